@@ -0,0 +1,23 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.graph.watch
+
+import (
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	GraphWatchNSID = "sh.tangled.graph.watch"
+)
+
+func init() {
+	util.RegisterType("sh.tangled.graph.watch", &GraphWatch{})
+} //
+// RECORDTYPE: GraphWatch
+type GraphWatch struct {
+	LexiconTypeID string `json:"$type,const=sh.tangled.graph.watch" cborgen:"$type,const=sh.tangled.graph.watch"`
+	CreatedAt     string `json:"createdAt" cborgen:"createdAt"`
+	Subject       string `json:"subject" cborgen:"subject"`
+}
@@ -0,0 +1,44 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.knot.health
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	KnotHealthNSID = "sh.tangled.knot.health"
+)
+
+// KnotHealth_Load is a "load" in the sh.tangled.knot.health schema.
+type KnotHealth_Load struct {
+	Cpus       int64 `json:"cpus" cborgen:"cpus"`
+	Goroutines int64 `json:"goroutines" cborgen:"goroutines"`
+	// heapBytes: Bytes of heap in use
+	HeapBytes int64 `json:"heapBytes" cborgen:"heapBytes"`
+}
+
+// KnotHealth_Output is the output of a sh.tangled.knot.health call.
+type KnotHealth_Output struct {
+	// capabilities: NSIDs of the XRPC methods the knot serves
+	Capabilities []string         `json:"capabilities" cborgen:"capabilities"`
+	Load         *KnotHealth_Load `json:"load" cborgen:"load"`
+	StartedAt    string           `json:"startedAt" cborgen:"startedAt"`
+	// uptime: Seconds since the knot started
+	Uptime  int64  `json:"uptime" cborgen:"uptime"`
+	Version string `json:"version" cborgen:"version"`
+}
+
+// KnotHealth calls the XRPC method "sh.tangled.knot.health".
+func KnotHealth(ctx context.Context, c util.LexClient) (*KnotHealth_Output, error) {
+	var out KnotHealth_Output
+	if err := c.LexDo(ctx, util.Query, "", "sh.tangled.knot.health", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,39 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.knot.usage
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	KnotUsageNSID = "sh.tangled.knot.usage"
+)
+
+// KnotUsage_Output is the output of a sh.tangled.knot.usage call.
+type KnotUsage_Output struct {
+	Did string `json:"did" cborgen:"did"`
+	// quota: Bytes the user's repositories may take up, absent when there is no quota
+	Quota *int64 `json:"quota,omitempty" cborgen:"quota,omitempty"`
+	// usage: Bytes the user's repositories take up on disk
+	Usage int64 `json:"usage" cborgen:"usage"`
+}
+
+// KnotUsage calls the XRPC method "sh.tangled.knot.usage".
+//
+// did: DID of the user
+func KnotUsage(ctx context.Context, c util.LexClient, did string) (*KnotUsage_Output, error) {
+	var out KnotUsage_Output
+
+	params := map[string]interface{}{}
+	params["did"] = did
+	if err := c.LexDo(ctx, util.Query, "", "sh.tangled.knot.usage", params, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
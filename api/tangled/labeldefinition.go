@@ -21,6 +21,8 @@ type LabelDefinition struct {
 	// color: The hex value for the background color for the label. Appviews may choose to respect this.
 	Color     *string `json:"color,omitempty" cborgen:"color,omitempty"`
 	CreatedAt string  `json:"createdAt" cborgen:"createdAt"`
+	// description: A short explanation of what this label is for.
+	Description *string `json:"description,omitempty" cborgen:"description,omitempty"`
 	// multiple: Whether this label can be repeated for a given entity, eg.: [reviewer:foo, reviewer:bar]
 	Multiple *bool `json:"multiple,omitempty" cborgen:"multiple,omitempty"`
 	// name: The display name of this label.
@@ -0,0 +1,30 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.pipeline.cancel
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	PipelineCancelNSID = "sh.tangled.pipeline.cancel"
+)
+
+// PipelineCancel_Input is the input argument to a sh.tangled.pipeline.cancel call.
+type PipelineCancel_Input struct {
+	// pipeline: ATURI of the pipeline to cancel
+	Pipeline string `json:"pipeline" cborgen:"pipeline"`
+}
+
+// PipelineCancel calls the XRPC method "sh.tangled.pipeline.cancel".
+func PipelineCancel(ctx context.Context, c util.LexClient, input *PipelineCancel_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.pipeline.cancel", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
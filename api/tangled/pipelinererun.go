@@ -0,0 +1,32 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.pipeline.rerun
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	PipelineRerunNSID = "sh.tangled.pipeline.rerun"
+)
+
+// PipelineRerun_Input is the input argument to a sh.tangled.pipeline.rerun call.
+type PipelineRerun_Input struct {
+	// pipeline: ATURI of the pipeline to run again
+	Pipeline string `json:"pipeline" cborgen:"pipeline"`
+	// rkey: record key of the new pipeline run
+	Rkey string `json:"rkey" cborgen:"rkey"`
+}
+
+// PipelineRerun calls the XRPC method "sh.tangled.pipeline.rerun".
+func PipelineRerun(ctx context.Context, c util.LexClient, input *PipelineRerun_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.pipeline.rerun", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
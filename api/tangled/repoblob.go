@@ -33,6 +33,8 @@ type RepoBlob_Output struct {
 	Content *string `json:"content,omitempty" cborgen:"content,omitempty"`
 	// encoding: Content encoding
 	Encoding *string `json:"encoding,omitempty" cborgen:"encoding,omitempty"`
+	// hash: Hash of the git blob of the file
+	Hash *string `json:"hash,omitempty" cborgen:"hash,omitempty"`
 	// isBinary: Whether the file is binary
 	IsBinary   *bool                `json:"isBinary,omitempty" cborgen:"isBinary,omitempty"`
 	LastCommit *RepoBlob_LastCommit `json:"lastCommit,omitempty" cborgen:"lastCommit,omitempty"`
@@ -20,6 +20,8 @@ type RepoCollaborator struct {
 	LexiconTypeID string `json:"$type,const=sh.tangled.repo.collaborator" cborgen:"$type,const=sh.tangled.repo.collaborator"`
 	CreatedAt     string `json:"createdAt" cborgen:"createdAt"`
 	// repo: repo to add this user to
-	Repo    string `json:"repo" cborgen:"repo"`
-	Subject string `json:"subject" cborgen:"subject"`
+	Repo string `json:"repo" cborgen:"repo"`
+	// role: role of this user in the repo, collaborator if not set
+	Role    *string `json:"role,omitempty" cborgen:"role,omitempty"`
+	Subject string  `json:"subject" cborgen:"subject"`
 }
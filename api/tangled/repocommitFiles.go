@@ -0,0 +1,66 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.commitFiles
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoCommitFilesNSID = "sh.tangled.repo.commitFiles"
+)
+
+// RepoCommitFiles_File is a "file" in the sh.tangled.repo.commitFiles schema.
+type RepoCommitFiles_File struct {
+	// content: Base64-encoded file content
+	Content string `json:"content" cborgen:"content"`
+	// delete: Remove the file instead of writing it; content is ignored
+	Delete *bool `json:"delete,omitempty" cborgen:"delete,omitempty"`
+	// executable: Whether a new file is executable; existing files keep their mode
+	Executable *bool `json:"executable,omitempty" cborgen:"executable,omitempty"`
+	// expectedBlob: Hash of the blob the file is expected to have; the commit is refused if the file has changed since
+	ExpectedBlob *string `json:"expectedBlob,omitempty" cborgen:"expectedBlob,omitempty"`
+	// from: Path the file is renamed from, which is removed
+	From *string `json:"from,omitempty" cborgen:"from,omitempty"`
+	// path: Path of the file within the repository
+	Path string `json:"path" cborgen:"path"`
+}
+
+// RepoCommitFiles_Input is the input argument to a sh.tangled.repo.commitFiles call.
+type RepoCommitFiles_Input struct {
+	// authorEmail: Author email for the commit
+	AuthorEmail *string `json:"authorEmail,omitempty" cborgen:"authorEmail,omitempty"`
+	// authorName: Author name for the commit
+	AuthorName *string `json:"authorName,omitempty" cborgen:"authorName,omitempty"`
+	// branch: Branch to commit to
+	Branch string                  `json:"branch" cborgen:"branch"`
+	Files  []*RepoCommitFiles_File `json:"files" cborgen:"files"`
+	// message: Commit message
+	Message string `json:"message" cborgen:"message"`
+	// patchOnly: Return the commit as a patch, and leave the repository as it is
+	PatchOnly *bool `json:"patchOnly,omitempty" cborgen:"patchOnly,omitempty"`
+	// repo: AT-URI of the repository
+	Repo string `json:"repo" cborgen:"repo"`
+}
+
+// RepoCommitFiles_Output is the output of a sh.tangled.repo.commitFiles call.
+type RepoCommitFiles_Output struct {
+	// commit: Hash of the commit, unless patchOnly was set
+	Commit *string `json:"commit,omitempty" cborgen:"commit,omitempty"`
+	// patch: The commit in git format-patch form, if patchOnly was set
+	Patch *string `json:"patch,omitempty" cborgen:"patch,omitempty"`
+}
+
+// RepoCommitFiles calls the XRPC method "sh.tangled.repo.commitFiles".
+func RepoCommitFiles(ctx context.Context, c util.LexClient, input *RepoCommitFiles_Input) (*RepoCommitFiles_Output, error) {
+	var out RepoCommitFiles_Output
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.commitFiles", nil, input, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
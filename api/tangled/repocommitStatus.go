@@ -0,0 +1,38 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.commitStatus
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoCommitStatusNSID = "sh.tangled.repo.commitStatus"
+)
+
+// RepoCommitStatus_Input is the input argument to a sh.tangled.repo.commitStatus call.
+type RepoCommitStatus_Input struct {
+	// context: Name of the check, unique per commit
+	Context     string  `json:"context" cborgen:"context"`
+	Description *string `json:"description,omitempty" cborgen:"description,omitempty"`
+	// repo: AT-URI of the repository
+	Repo string `json:"repo" cborgen:"repo"`
+	// sha: Full hash of the commit
+	Sha   string `json:"sha" cborgen:"sha"`
+	State string `json:"state" cborgen:"state"`
+	// targetUrl: Where the details of the check can be found
+	TargetUrl *string `json:"targetUrl,omitempty" cborgen:"targetUrl,omitempty"`
+}
+
+// RepoCommitStatus calls the XRPC method "sh.tangled.repo.commitStatus".
+func RepoCommitStatus(ctx context.Context, c util.LexClient, input *RepoCommitStatus_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.commitStatus", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.contributors
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoContributorsNSID = "sh.tangled.repo.contributors"
+)
+
+// RepoContributors_Contributor is a "contributor" in the sh.tangled.repo.contributors schema.
+type RepoContributors_Contributor struct {
+	// additions: Lines added across those commits
+	Additions int64 `json:"additions" cborgen:"additions"`
+	// commits: Number of non-merge commits authored
+	Commits int64 `json:"commits" cborgen:"commits"`
+	// deletions: Lines deleted across those commits
+	Deletions int64 `json:"deletions" cborgen:"deletions"`
+	// email: Author email
+	Email string `json:"email" cborgen:"email"`
+	// firstCommit: Author date of their first commit
+	FirstCommit string `json:"firstCommit" cborgen:"firstCommit"`
+	// lastCommit: Author date of their most recent commit
+	LastCommit string `json:"lastCommit" cborgen:"lastCommit"`
+	// name: Author name on their most recent commit
+	Name string `json:"name" cborgen:"name"`
+}
+
+// RepoContributors_Output is the output of a sh.tangled.repo.contributors call.
+type RepoContributors_Output struct {
+	// contributors: Commit authors, most commits first
+	Contributors []*RepoContributors_Contributor `json:"contributors" cborgen:"contributors"`
+	// ref: The git reference used
+	Ref string `json:"ref" cborgen:"ref"`
+}
+
+// RepoContributors calls the XRPC method "sh.tangled.repo.contributors".
+//
+// ref: Git reference (branch, tag, or commit SHA)
+// repo: Repository identifier in format 'did:plc:.../repoName'
+func RepoContributors(ctx context.Context, c util.LexClient, ref string, repo string) (*RepoContributors_Output, error) {
+	var out RepoContributors_Output
+
+	params := map[string]interface{}{}
+	if ref != "" {
+		params["ref"] = ref
+	}
+	params["repo"] = repo
+	if err := c.LexDo(ctx, util.Query, "", "sh.tangled.repo.contributors", params, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,40 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.createFromTemplate
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoCreateFromTemplateNSID = "sh.tangled.repo.createFromTemplate"
+)
+
+// RepoCreateFromTemplate_Input is the input argument to a sh.tangled.repo.createFromTemplate call.
+type RepoCreateFromTemplate_Input struct {
+	// authorEmail: Author email for the initial commit
+	AuthorEmail *string `json:"authorEmail,omitempty" cborgen:"authorEmail,omitempty"`
+	// authorName: Author name for the initial commit
+	AuthorName *string `json:"authorName,omitempty" cborgen:"authorName,omitempty"`
+	// defaultBranch: Branch that holds the initial commit
+	DefaultBranch *string `json:"defaultBranch,omitempty" cborgen:"defaultBranch,omitempty"`
+	// ref: Ref of the template to take the tree from, its default branch if unset
+	Ref *string `json:"ref,omitempty" cborgen:"ref,omitempty"`
+	// rkey: Rkey of the repository record
+	Rkey string `json:"rkey" cborgen:"rkey"`
+	// source: A source URL to fetch the template from
+	Source string `json:"source" cborgen:"source"`
+}
+
+// RepoCreateFromTemplate calls the XRPC method "sh.tangled.repo.createFromTemplate".
+func RepoCreateFromTemplate(ctx context.Context, c util.LexClient, input *RepoCreateFromTemplate_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.createFromTemplate", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.deleteHiddenRef
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoDeleteHiddenRefNSID = "sh.tangled.repo.deleteHiddenRef"
+)
+
+// RepoDeleteHiddenRef_Input is the input argument to a sh.tangled.repo.deleteHiddenRef call.
+type RepoDeleteHiddenRef_Input struct {
+	// forkRef: Fork reference name
+	ForkRef string `json:"forkRef" cborgen:"forkRef"`
+	// remoteRef: Remote reference name
+	RemoteRef string `json:"remoteRef" cborgen:"remoteRef"`
+	// repo: AT-URI of the repository
+	Repo string `json:"repo" cborgen:"repo"`
+}
+
+// RepoDeleteHiddenRef calls the XRPC method "sh.tangled.repo.deleteHiddenRef".
+func RepoDeleteHiddenRef(ctx context.Context, c util.LexClient, input *RepoDeleteHiddenRef_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.deleteHiddenRef", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
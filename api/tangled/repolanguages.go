@@ -14,6 +14,18 @@ const (
 	RepoLanguagesNSID = "sh.tangled.repo.languages"
 )
 
+// RepoLanguages_Excluded is a "excluded" in the sh.tangled.repo.languages schema.
+type RepoLanguages_Excluded struct {
+	// documentation: Number of documentation files, see linguist-documentation
+	Documentation int64 `json:"documentation" cborgen:"documentation"`
+	// generated: Number of generated files, see linguist-generated
+	Generated int64 `json:"generated" cborgen:"generated"`
+	// overridden: Number of files whose language was set by linguist-language
+	Overridden int64 `json:"overridden" cborgen:"overridden"`
+	// vendored: Number of vendored files, see linguist-vendored
+	Vendored int64 `json:"vendored" cborgen:"vendored"`
+}
+
 // RepoLanguages_Language is a "language" in the sh.tangled.repo.languages schema.
 type RepoLanguages_Language struct {
 	// color: Hex color code for this language
@@ -32,6 +44,8 @@ type RepoLanguages_Language struct {
 
 // RepoLanguages_Output is the output of a sh.tangled.repo.languages call.
 type RepoLanguages_Output struct {
+	// excluded: Files left out of the breakdown, or whose language was overridden, by .gitattributes or linguist's detection
+	Excluded  *RepoLanguages_Excluded   `json:"excluded,omitempty" cborgen:"excluded,omitempty"`
 	Languages []*RepoLanguages_Language `json:"languages" cborgen:"languages"`
 	// ref: The git reference used
 	Ref string `json:"ref" cborgen:"ref"`
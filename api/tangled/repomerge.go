@@ -28,6 +28,8 @@ type RepoMerge_Input struct {
 	CommitMessage *string `json:"commitMessage,omitempty" cborgen:"commitMessage,omitempty"`
 	// did: DID of the repository owner
 	Did string `json:"did" cborgen:"did"`
+	// fuzz: Lines of context around each change that may be ignored if the patch does not apply as is
+	Fuzz *int64 `json:"fuzz,omitempty" cborgen:"fuzz,omitempty"`
 	// name: Name of the repository
 	Name string `json:"name" cborgen:"name"`
 	// patch: Patch content to merge
@@ -14,10 +14,24 @@ const (
 	RepoMergeCheckNSID = "sh.tangled.repo.mergeCheck"
 )
 
+// RepoMergeCheck_ConflictHunk is a "conflictHunk" in the sh.tangled.repo.mergeCheck schema.
+type RepoMergeCheck_ConflictHunk struct {
+	// base: Lines the patch expected to find
+	Base string `json:"base" cborgen:"base"`
+	// line: Line in the target branch where the region starts
+	Line int64 `json:"line" cborgen:"line"`
+	// ours: Lines found on the target branch
+	Ours string `json:"ours" cborgen:"ours"`
+	// theirs: Lines the patch would have written
+	Theirs string `json:"theirs" cborgen:"theirs"`
+}
+
 // RepoMergeCheck_ConflictInfo is a "conflictInfo" in the sh.tangled.repo.mergeCheck schema.
 type RepoMergeCheck_ConflictInfo struct {
 	// filename: Name of the conflicted file
 	Filename string `json:"filename" cborgen:"filename"`
+	// hunks: Regions of the file that the patch conflicts with
+	Hunks []*RepoMergeCheck_ConflictHunk `json:"hunks,omitempty" cborgen:"hunks,omitempty"`
 	// reason: Reason for the conflict
 	Reason string `json:"reason" cborgen:"reason"`
 }
@@ -28,6 +42,8 @@ type RepoMergeCheck_Input struct {
 	Branch string `json:"branch" cborgen:"branch"`
 	// did: DID of the repository owner
 	Did string `json:"did" cborgen:"did"`
+	// fuzz: Lines of context around each change that may be ignored if the patch does not apply as is
+	Fuzz *int64 `json:"fuzz,omitempty" cborgen:"fuzz,omitempty"`
 	// name: Name of the repository
 	Name string `json:"name" cborgen:"name"`
 	// patch: Patch or pull request to check for merge conflicts
@@ -36,6 +52,8 @@ type RepoMergeCheck_Input struct {
 
 // RepoMergeCheck_Output is the output of a sh.tangled.repo.mergeCheck call.
 type RepoMergeCheck_Output struct {
+	// applied_with_fuzz: Whether the patch only applies after ignoring some of its context
+	Applied_with_fuzz *bool `json:"applied_with_fuzz,omitempty" cborgen:"applied_with_fuzz,omitempty"`
 	// conflicts: List of files with merge conflicts
 	Conflicts []*RepoMergeCheck_ConflictInfo `json:"conflicts,omitempty" cborgen:"conflicts,omitempty"`
 	// error: Error message if check failed
@@ -0,0 +1,39 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.pruneHiddenRefs
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoPruneHiddenRefsNSID = "sh.tangled.repo.pruneHiddenRefs"
+)
+
+// RepoPruneHiddenRefs_Input is the input argument to a sh.tangled.repo.pruneHiddenRefs call.
+type RepoPruneHiddenRefs_Input struct {
+	// keep: Hidden refs to keep, as forkRef/remoteRef
+	Keep []string `json:"keep" cborgen:"keep"`
+	// repo: AT-URI of the repository
+	Repo string `json:"repo" cborgen:"repo"`
+}
+
+// RepoPruneHiddenRefs_Output is the output of a sh.tangled.repo.pruneHiddenRefs call.
+type RepoPruneHiddenRefs_Output struct {
+	// pruned: Hidden refs that were deleted, as forkRef/remoteRef
+	Pruned []string `json:"pruned" cborgen:"pruned"`
+}
+
+// RepoPruneHiddenRefs calls the XRPC method "sh.tangled.repo.pruneHiddenRefs".
+func RepoPruneHiddenRefs(ctx context.Context, c util.LexClient, input *RepoPruneHiddenRefs_Input) (*RepoPruneHiddenRefs_Output, error) {
+	var out RepoPruneHiddenRefs_Output
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.pruneHiddenRefs", nil, input, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
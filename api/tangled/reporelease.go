@@ -0,0 +1,31 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.release
+
+import (
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoReleaseNSID = "sh.tangled.repo.release"
+)
+
+func init() {
+	util.RegisterType("sh.tangled.repo.release", &RepoRelease{})
+} //
+// RECORDTYPE: RepoRelease
+type RepoRelease struct {
+	LexiconTypeID string `json:"$type,const=sh.tangled.repo.release" cborgen:"$type,const=sh.tangled.repo.release"`
+	// body: release notes, in markdown
+	Body      *string `json:"body,omitempty" cborgen:"body,omitempty"`
+	CreatedAt string  `json:"createdAt" cborgen:"createdAt"`
+	// prerelease: whether this release is not meant for general use yet
+	Prerelease *bool `json:"prerelease,omitempty" cborgen:"prerelease,omitempty"`
+	// repo: repo that this release belongs to
+	Repo string `json:"repo" cborgen:"repo"`
+	// tag: name of the tag that this release is cut from
+	Tag   string `json:"tag" cborgen:"tag"`
+	Title string `json:"title" cborgen:"title"`
+}
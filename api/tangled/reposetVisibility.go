@@ -0,0 +1,29 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.setVisibility
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoSetVisibilityNSID = "sh.tangled.repo.setVisibility"
+)
+
+// RepoSetVisibility_Input is the input argument to a sh.tangled.repo.setVisibility call.
+type RepoSetVisibility_Input struct {
+	Repo string `json:"repo" cborgen:"repo"`
+}
+
+// RepoSetVisibility calls the XRPC method "sh.tangled.repo.setVisibility".
+func RepoSetVisibility(ctx context.Context, c util.LexClient, input *RepoSetVisibility_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.setVisibility", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.size
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoSizeNSID = "sh.tangled.repo.size"
+)
+
+// RepoSize_Output is the output of a sh.tangled.repo.size call.
+type RepoSize_Output struct {
+	// size: Bytes the repository takes up on disk
+	Size int64 `json:"size" cborgen:"size"`
+}
+
+// RepoSize calls the XRPC method "sh.tangled.repo.size".
+//
+// repo: Repository identifier in format 'did:plc:.../repoName'
+func RepoSize(ctx context.Context, c util.LexClient, repo string) (*RepoSize_Output, error) {
+	var out RepoSize_Output
+
+	params := map[string]interface{}{}
+	params["repo"] = repo
+	if err := c.LexDo(ctx, util.Query, "", "sh.tangled.repo.size", params, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
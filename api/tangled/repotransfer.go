@@ -0,0 +1,36 @@
+// Code generated by cmd/lexgen (see Makefile's lexgen); DO NOT EDIT.
+
+package tangled
+
+// schema: sh.tangled.repo.transfer
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+const (
+	RepoTransferNSID = "sh.tangled.repo.transfer"
+)
+
+// RepoTransfer_Input is the input argument to a sh.tangled.repo.transfer call.
+type RepoTransfer_Input struct {
+	// did: DID of the current repository owner
+	Did string `json:"did" cborgen:"did"`
+	// name: Name of the repository to transfer
+	Name string `json:"name" cborgen:"name"`
+	// newRkey: Rkey of the caller's new repository record
+	NewRkey string `json:"newRkey" cborgen:"newRkey"`
+	// rkey: Rkey of the current owner's repository record
+	Rkey string `json:"rkey" cborgen:"rkey"`
+}
+
+// RepoTransfer calls the XRPC method "sh.tangled.repo.transfer".
+func RepoTransfer(ctx context.Context, c util.LexClient, input *RepoTransfer_Input) error {
+	if err := c.LexDo(ctx, util.Procedure, "application/json", "sh.tangled.repo.transfer", nil, input, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
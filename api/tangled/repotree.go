@@ -45,6 +45,18 @@ type RepoTree_Readme struct {
 	Filename string `json:"filename" cborgen:"filename"`
 }
 
+// RepoTree_Submodule is a "submodule" in the sh.tangled.repo.tree schema.
+type RepoTree_Submodule struct {
+	// branch: Branch to track in the submodule
+	Branch *string `json:"branch,omitempty" cborgen:"branch,omitempty"`
+	// commit: Commit the submodule is pinned to
+	Commit string `json:"commit" cborgen:"commit"`
+	// name: Submodule name
+	Name string `json:"name" cborgen:"name"`
+	// url: Submodule repository URL, as written in .gitmodules
+	Url string `json:"url" cborgen:"url"`
+}
+
 // RepoTree_TreeEntry is a "treeEntry" in the sh.tangled.repo.tree schema.
 type RepoTree_TreeEntry struct {
 	Last_commit *RepoTree_LastCommit `json:"last_commit,omitempty" cborgen:"last_commit,omitempty"`
@@ -54,6 +66,8 @@ type RepoTree_TreeEntry struct {
 	Name string `json:"name" cborgen:"name"`
 	// size: File size in bytes
 	Size int64 `json:"size" cborgen:"size"`
+	// submodule: Submodule information if the entry is a gitlink
+	Submodule *RepoTree_Submodule `json:"submodule,omitempty" cborgen:"submodule,omitempty"`
 }
 
 // RepoTree calls the XRPC method "sh.tangled.repo.tree".
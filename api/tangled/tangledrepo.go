@@ -17,21 +17,29 @@ func init() {
 } //
 // RECORDTYPE: Repo
 type Repo struct {
-	LexiconTypeID string  `json:"$type,const=sh.tangled.repo" cborgen:"$type,const=sh.tangled.repo"`
-	CreatedAt     string  `json:"createdAt" cborgen:"createdAt"`
-	Description   *string `json:"description,omitempty" cborgen:"description,omitempty"`
+	LexiconTypeID string `json:"$type,const=sh.tangled.repo" cborgen:"$type,const=sh.tangled.repo"`
+	// cancelSupersededPipelines: Cancel queued pipelines of a branch once a newer one is triggered
+	CancelSupersededPipelines *bool   `json:"cancelSupersededPipelines,omitempty" cborgen:"cancelSupersededPipelines,omitempty"`
+	CreatedAt                 string  `json:"createdAt" cborgen:"createdAt"`
+	Description               *string `json:"description,omitempty" cborgen:"description,omitempty"`
 	// knot: knot where the repo was created
 	Knot string `json:"knot" cborgen:"knot"`
 	// labels: List of labels that this repo subscribes to
 	Labels []string `json:"labels,omitempty" cborgen:"labels,omitempty"`
 	// name: name of the repo
 	Name string `json:"name" cborgen:"name"`
+	// pipelineConcurrency: Maximum number of pipelines of this repo that the spindle runs at once, further pipelines are queued
+	PipelineConcurrency *int64 `json:"pipelineConcurrency,omitempty" cborgen:"pipelineConcurrency,omitempty"`
 	// source: source of the repo
 	Source *string `json:"source,omitempty" cborgen:"source,omitempty"`
 	// spindle: CI runner to send jobs to and receive results from
 	Spindle *string `json:"spindle,omitempty" cborgen:"spindle,omitempty"`
 	// topics: Topics related to the repo
 	Topics []string `json:"topics,omitempty" cborgen:"topics,omitempty"`
+	// transferTo: DID of the user this repo is being transferred to, once they accept, this record only marks where the repo went
+	TransferTo *string `json:"transferTo,omitempty" cborgen:"transferTo,omitempty"`
+	// visibility: Who can see the repo, private repos are only visible to its owner and collaborators
+	Visibility *string `json:"visibility,omitempty" cborgen:"visibility,omitempty"`
 	// website: Any URI related to the repo
 	Website *string `json:"website,omitempty" cborgen:"website,omitempty"`
 }
@@ -0,0 +1,564 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/moderation"
+	"tangled.org/core/appview/notify"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+	"tangled.org/core/appview/serververify"
+	"tangled.org/core/eventconsumer"
+	"tangled.org/core/idresolver"
+	"tangled.org/core/rbac"
+)
+
+// how much of each list the dashboard shows
+const dashboardLimit = 20
+
+type Admin struct {
+	db         *db.DB
+	oauth      *oauth.OAuth
+	pages      *pages.Pages
+	config     *config.Config
+	idResolver *idresolver.Resolver
+	enforcer   *rbac.Enforcer
+	knotstream *eventconsumer.Consumer
+	notifier   notify.Notifier
+	logger     *slog.Logger
+}
+
+func New(
+	database *db.DB,
+	oauthHandler *oauth.OAuth,
+	pagesHandler *pages.Pages,
+	config *config.Config,
+	idResolver *idresolver.Resolver,
+	enforcer *rbac.Enforcer,
+	knotstream *eventconsumer.Consumer,
+	notifier notify.Notifier,
+	logger *slog.Logger,
+) *Admin {
+	return &Admin{
+		db:         database,
+		oauth:      oauthHandler,
+		pages:      pagesHandler,
+		config:     config,
+		idResolver: idResolver,
+		enforcer:   enforcer,
+		knotstream: knotstream,
+		notifier:   notifier,
+		logger:     logger,
+	}
+}
+
+func (a *Admin) Router() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(middleware.AuthMiddleware(a.oauth))
+	r.Use(a.adminsOnly)
+
+	r.Get("/", a.dashboard)
+	r.Post("/suspend", a.suspend)
+	r.Post("/takedown", a.takedown)
+	r.Post("/restore", a.restore)
+	r.Post("/approve", a.approve)
+	r.Post("/reject", a.reject)
+
+	return r
+}
+
+// adminsOnly hides what it wraps from everyone but admins.
+func (a *Admin) adminsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := a.oauth.GetUser(r)
+		if user == nil || !a.config.Admin.IsAdmin(user.Did) {
+			a.pages.Error404(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Admin) dashboard(w http.ResponseWriter, r *http.Request) {
+	l := a.logger.With("handler", "dashboard")
+	user := a.oauth.GetUser(r)
+	ctx := r.Context()
+
+	emails, err := db.GetRecentSignups(a.db, dashboardLimit)
+	if err != nil {
+		l.Error("failed to get signups", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	dids := make([]string, 0, len(emails))
+	for _, email := range emails {
+		dids = append(dids, email.Did)
+	}
+	suspended, err := db.GetTakedowns(a.db, dids)
+	if err != nil {
+		l.Error("failed to get takedowns", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	signups := make([]pages.AdminSignup, 0, len(emails))
+	for _, email := range emails {
+		_, ok := suspended[email.Did]
+		signups = append(signups, pages.AdminSignup{
+			Email:     email,
+			Suspended: ok,
+		})
+	}
+
+	inflight, err := db.GetInflightSignups(a.db, dashboardLimit)
+	if err != nil {
+		l.Error("failed to get inflight signups", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	openReports, err := db.CountReports(a.db, db.FilterEq("status", models.ReportOpen))
+	if err != nil {
+		l.Error("failed to count reports", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	reports, err := db.GetReports(
+		a.db,
+		pagination.Page{Limit: dashboardLimit},
+		db.FilterEq("status", models.ReportOpen),
+	)
+	if err != nil {
+		l.Error("failed to get reports", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	items := make([]pages.ModerationQueueItem, 0, len(reports))
+	for _, report := range reports {
+		target, err := moderation.Describe(ctx, a.db, a.idResolver, report.Subject)
+		if err != nil {
+			target = models.ReportTarget{Kind: report.Kind, Subject: report.Subject}
+		}
+		items = append(items, pages.ModerationQueueItem{
+			Report: report,
+			Target: target,
+		})
+	}
+
+	knots, err := db.GetRegistrations(a.db)
+	if err != nil {
+		l.Error("failed to get knots", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+	slices.Reverse(knots)
+
+	spindles, err := db.GetSpindles(a.db)
+	if err != nil {
+		l.Error("failed to get spindles", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+	slices.Reverse(spindles)
+
+	recent, err := db.GetRecentTakedowns(a.db, dashboardLimit)
+	if err != nil {
+		l.Error("failed to get takedowns", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	takedowns := make([]pages.AdminTakedown, 0, len(recent))
+	for _, takedown := range recent {
+		target, err := moderation.Describe(ctx, a.db, a.idResolver, takedown.Subject)
+		if err != nil {
+			target = models.ReportTarget{Kind: models.ReportKindOf(takedown.Subject), Subject: takedown.Subject}
+		}
+		takedowns = append(takedowns, pages.AdminTakedown{
+			Takedown: takedown,
+			Target:   target,
+		})
+	}
+
+	actions, err := db.GetAdminActions(a.db, dashboardLimit)
+	if err != nil {
+		l.Error("failed to get admin actions", "err", err)
+		a.pages.Error500(w)
+		return
+	}
+
+	a.pages.AdminDashboard(w, pages.AdminDashboardParams{
+		LoggedInUser:    user,
+		IsModerator:     a.config.Moderation.IsModerator(user.Did),
+		Signups:         signups,
+		InflightSignups: inflight,
+		Reports:         items,
+		OpenReports:     openReports,
+		Knots:           knots,
+		Spindles:        spindles,
+		Takedowns:       takedowns,
+		Actions:         actions,
+	})
+}
+
+// suspend takes an account down: it is logged out, can no longer log in,
+// and its profile and repos are hidden.
+func (a *Admin) suspend(w http.ResponseWriter, r *http.Request) {
+	l := a.logger.With("handler", "suspend")
+	user := a.oauth.GetUser(r)
+	noticeId := "suspend"
+
+	account := strings.TrimPrefix(strings.TrimSpace(r.FormValue("account")), "@")
+	if account == "" {
+		a.pages.Notice(w, noticeId, "Enter the handle or DID of an account.")
+		return
+	}
+
+	id, err := a.idResolver.ResolveIdent(r.Context(), account)
+	if err != nil {
+		l.Error("failed to resolve account", "err", err, "account", account)
+		a.pages.Notice(w, noticeId, fmt.Sprintf("Could not find %s.", account))
+		return
+	}
+
+	did := id.DID.String()
+	if a.config.Admin.IsAdmin(did) {
+		a.pages.Notice(w, noticeId, "Admins can't be suspended.")
+		return
+	}
+
+	if err := a.act(user.Did, models.AdminSuspend, did, r.FormValue("reason")); err != nil {
+		l.Error("failed to suspend", "err", err, "did", did)
+		a.pages.Notice(w, noticeId, "Failed to suspend the account.")
+		return
+	}
+
+	l.Info("account suspended", "did", did, "admin", user.Did)
+	a.pages.HxRefresh(w)
+}
+
+func (a *Admin) takedown(w http.ResponseWriter, r *http.Request) {
+	l := a.logger.With("handler", "takedown")
+	user := a.oauth.GetUser(r)
+	noticeId := "takedown"
+
+	owner, name, ok := strings.Cut(strings.Trim(strings.TrimSpace(r.FormValue("repo")), "/"), "/")
+	if !ok || owner == "" || name == "" {
+		a.pages.Notice(w, noticeId, "Enter a repo as owner/name.")
+		return
+	}
+
+	id, err := a.idResolver.ResolveIdent(r.Context(), strings.TrimPrefix(owner, "@"))
+	if err != nil {
+		l.Error("failed to resolve owner", "err", err, "owner", owner)
+		a.pages.Notice(w, noticeId, fmt.Sprintf("Could not find %s.", owner))
+		return
+	}
+
+	repo, err := db.GetRepo(
+		a.db,
+		db.FilterEq("did", id.DID.String()),
+		db.FilterEq("name", name),
+	)
+	if err != nil {
+		l.Error("failed to get repo", "err", err, "owner", owner, "name", name)
+		a.pages.Notice(w, noticeId, fmt.Sprintf("Could not find %s/%s.", owner, name))
+		return
+	}
+
+	subject := repo.RepoAt().String()
+	if err := a.act(user.Did, models.AdminTakedown, subject, r.FormValue("reason")); err != nil {
+		l.Error("failed to take down", "err", err, "subject", subject)
+		a.pages.Notice(w, noticeId, "Failed to take the repo down.")
+		return
+	}
+
+	l.Info("repo taken down", "subject", subject, "admin", user.Did)
+	a.pages.HxRefresh(w)
+}
+
+// restore lifts a takedown, whether an admin or a moderator made it.
+func (a *Admin) restore(w http.ResponseWriter, r *http.Request) {
+	l := a.logger.With("handler", "restore")
+	user := a.oauth.GetUser(r)
+	noticeId := "takedowns"
+
+	subject := r.FormValue("subject")
+	if !db.IsTakenDown(a.db, subject) {
+		a.pages.Notice(w, noticeId, "That is not taken down.")
+		return
+	}
+
+	if err := a.act(user.Did, models.AdminRestore, subject, r.FormValue("reason")); err != nil {
+		l.Error("failed to restore", "err", err, "subject", subject)
+		a.pages.Notice(w, noticeId, "Failed to restore it.")
+		return
+	}
+
+	l.Info("takedown lifted", "subject", subject, "admin", user.Did)
+	a.pages.HxRefresh(w)
+}
+
+// approve lets a pending or rejected knot or spindle serve.
+func (a *Admin) approve(w http.ResponseWriter, r *http.Request) {
+	a.review(w, r, models.ApprovalApproved)
+}
+
+// reject turns a registration away. Only pending ones can be, approved
+// knots and spindles are taken down instead.
+func (a *Admin) reject(w http.ResponseWriter, r *http.Request) {
+	a.review(w, r, models.ApprovalRejected)
+}
+
+// review settles the registration of a knot or spindle and lets its owner
+// know. Those that already proved who runs them are set up right away, the
+// rest once they do.
+func (a *Admin) review(w http.ResponseWriter, r *http.Request, approval models.Approval) {
+	l := a.logger.With("handler", "review", "approval", approval)
+	user := a.oauth.GetUser(r)
+
+	kind := r.FormValue("kind")
+	owner := r.FormValue("owner")
+	domain := r.FormValue("domain")
+	l = l.With("kind", kind, "owner", owner, "domain", domain)
+
+	noticeId := kind + "s"
+	reason := strings.TrimSpace(r.FormValue("reason"))
+
+	var err error
+	switch kind {
+	case "knot":
+		err = a.reviewKnot(r.Context(), user.Did, owner, domain, approval, reason)
+	case "spindle":
+		err = a.reviewSpindle(r.Context(), user.Did, owner, domain, approval, reason)
+	default:
+		a.pages.Notice(w, "knots", "Unknown kind of registration.")
+		return
+	}
+
+	var reviewErr *reviewError
+	if errors.As(err, &reviewErr) {
+		a.pages.Notice(w, noticeId, reviewErr.msg)
+		return
+	}
+	if err != nil {
+		l.Error("failed to review registration", "err", err)
+		a.pages.Notice(w, noticeId, "Failed to review the registration.")
+		return
+	}
+
+	l.Info("registration reviewed", "admin", user.Did)
+	a.pages.HxRefresh(w)
+}
+
+// reviewError is a reason a registration can't be reviewed as asked.
+type reviewError struct {
+	msg string
+}
+
+func (e *reviewError) Error() string {
+	return e.msg
+}
+
+func checkReview(current, approval models.Approval) error {
+	if current == approval {
+		return &reviewError{fmt.Sprintf("That is already %s.", approval)}
+	}
+	if approval == models.ApprovalRejected && current == models.ApprovalApproved {
+		return &reviewError{"Approved registrations can't be rejected."}
+	}
+	return nil
+}
+
+func (a *Admin) reviewKnot(ctx context.Context, adminDid, owner, domain string, approval models.Approval, reason string) error {
+	registrations, err := db.GetRegistrations(
+		a.db,
+		db.FilterEq("did", owner),
+		db.FilterEq("domain", domain),
+	)
+	if err != nil {
+		return err
+	}
+	if len(registrations) != 1 {
+		return &reviewError{"Could not find that knot."}
+	}
+	registration := registrations[0]
+
+	if err := checkReview(registration.Approval, approval); err != nil {
+		return err
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+		a.enforcer.E.LoadPolicy()
+	}()
+
+	err = db.SetKnotApproval(
+		tx,
+		approval,
+		db.FilterEq("did", owner),
+		db.FilterEq("domain", domain),
+	)
+	if err != nil {
+		return err
+	}
+
+	if approval.IsApproved() && registration.IsRegistered() {
+		if err := serververify.EnableKnot(a.enforcer, domain, owner); err != nil {
+			return err
+		}
+	}
+
+	if err := addReview(tx, adminDid, approval, domain, reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := a.enforcer.E.SavePolicy(); err != nil {
+		return fmt.Errorf("failed to update ACLs: %w", err)
+	}
+
+	if approval.IsApproved() && registration.IsRegistered() {
+		go a.knotstream.AddSource(
+			context.Background(),
+			eventconsumer.NewKnotSource(domain),
+		)
+	}
+
+	registration.Approval = approval
+	a.notifier.NewKnotApproval(ctx, syntax.DID(adminDid), &registration)
+	return nil
+}
+
+func (a *Admin) reviewSpindle(ctx context.Context, adminDid, owner, instance string, approval models.Approval, reason string) error {
+	spindles, err := db.GetSpindles(
+		a.db,
+		db.FilterEq("owner", owner),
+		db.FilterEq("instance", instance),
+	)
+	if err != nil {
+		return err
+	}
+	if len(spindles) != 1 {
+		return &reviewError{"Could not find that spindle."}
+	}
+	spindle := spindles[0]
+
+	if err := checkReview(spindle.Approval, approval); err != nil {
+		return err
+	}
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+		a.enforcer.E.LoadPolicy()
+	}()
+
+	err = db.SetSpindleApproval(
+		tx,
+		approval,
+		db.FilterEq("owner", owner),
+		db.FilterEq("instance", instance),
+	)
+	if err != nil {
+		return err
+	}
+
+	if approval.IsApproved() && spindle.Verified != nil {
+		if err := serververify.EnableSpindle(a.enforcer, instance, owner); err != nil {
+			return err
+		}
+	}
+
+	if err := addReview(tx, adminDid, approval, instance, reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := a.enforcer.E.SavePolicy(); err != nil {
+		return fmt.Errorf("failed to update ACLs: %w", err)
+	}
+
+	spindle.Approval = approval
+	a.notifier.NewSpindleApproval(ctx, syntax.DID(adminDid), &spindle)
+	return nil
+}
+
+func addReview(e db.Execer, adminDid string, approval models.Approval, domain, reason string) error {
+	action := models.AdminApprove
+	if approval == models.ApprovalRejected {
+		action = models.AdminReject
+	}
+
+	return db.AddAdminAction(e, &models.AdminAction{
+		AdminDid: adminDid,
+		Action:   action,
+		Subject:  domain,
+		Reason:   reason,
+	})
+}
+
+// act applies an action to subject and records it in the audit log, all at
+// once. Taking something down also closes the open reports on it.
+func (a *Admin) act(adminDid string, action models.AdminActionKind, subject, reason string) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	switch action {
+	case models.AdminSuspend, models.AdminTakedown:
+		if err := db.AddTakedown(tx, &models.Takedown{Subject: subject, ByDid: adminDid}); err != nil {
+			return err
+		}
+		if err := db.ResolveReports(tx, subject, models.ReportTakenDown, adminDid); err != nil {
+			return err
+		}
+	case models.AdminRestore:
+		if err := db.DeleteTakedown(tx, subject); err != nil {
+			return err
+		}
+	}
+
+	if err := db.AddAdminAction(tx, &models.AdminAction{
+		AdminDid: adminDid,
+		Action:   action,
+		Subject:  subject,
+		Reason:   strings.TrimSpace(reason),
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,311 @@
+// Package attachments lets users upload files to link from issue, pull and
+// comment bodies. Files are content addressed, so the URL an upload gets
+// never changes.
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+)
+
+// the types uploads may have, as sniffed from their contents; anything a
+// browser could run, like html or svg, is left out
+var allowedTypes = map[string]bool{
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"video/mp4":                 true,
+	"video/webm":                true,
+	"application/pdf":           true,
+	"application/zip":           true,
+	"application/x-gzip":        true,
+	"text/plain; charset=utf-8": true,
+}
+
+// shown inline rather than downloaded
+var inlineTypes = []string{"image/", "video/", "application/pdf", "text/plain"}
+
+type Attachments struct {
+	db     *db.DB
+	oauth  *oauth.OAuth
+	config *config.Config
+	store  Store
+	logger *slog.Logger
+}
+
+func New(database *db.DB, oauthHandler *oauth.OAuth, config *config.Config, store Store, logger *slog.Logger) *Attachments {
+	return &Attachments{
+		db:     database,
+		oauth:  oauthHandler,
+		config: config,
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (a *Attachments) Router() http.Handler {
+	r := chi.NewRouter()
+	r.With(middleware.AuthMiddleware(a.oauth)).Post("/", a.upload)
+	r.Get("/{hash}/{name}", a.serve)
+	return r
+}
+
+type uploadResponse struct {
+	Url      string `json:"url"`
+	Markdown string `json:"markdown"`
+}
+
+func (a *Attachments) upload(w http.ResponseWriter, r *http.Request) {
+	l := a.logger.With("handler", "upload")
+	user := a.oauth.GetUser(r)
+	limits := a.config.Attachment
+
+	// leave some room for the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Attachments can be at most %s.", humanize.IBytes(uint64(limits.MaxBytes))), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "No file was uploaded.", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	contents, err := io.ReadAll(io.LimitReader(file, limits.MaxBytes+1))
+	if err != nil {
+		l.Error("failed to read upload", "err", err)
+		http.Error(w, "Failed to read the upload.", http.StatusBadRequest)
+		return
+	}
+	if int64(len(contents)) > limits.MaxBytes {
+		http.Error(w, fmt.Sprintf("Attachments can be at most %s.", humanize.IBytes(uint64(limits.MaxBytes))), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	mimeType := http.DetectContentType(contents)
+	if !allowedTypes[mimeType] {
+		http.Error(w, "This type of file can't be attached.", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	usage, err := db.AttachmentUsage(a.db, user.Did)
+	if err != nil {
+		l.Error("failed to get attachment usage", "err", err)
+		http.Error(w, "Failed to upload. Try again later.", http.StatusInternalServerError)
+		return
+	}
+	if usage+int64(len(contents)) > limits.QuotaBytes {
+		http.Error(w, fmt.Sprintf("You have used up your %s of attachments.", humanize.IBytes(uint64(limits.QuotaBytes))), http.StatusInsufficientStorage)
+		return
+	}
+
+	sum := sha256.Sum256(contents)
+	hash := hex.EncodeToString(sum[:])
+
+	if err := a.store.Put(r.Context(), hash, bytes.NewReader(contents)); err != nil {
+		l.Error("failed to store attachment", "err", err)
+		http.Error(w, "Failed to upload. Try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	name := cleanName(header.Filename)
+	err = db.AddAttachment(a.db, models.Attachment{
+		Did:      user.Did,
+		Hash:     hash,
+		Name:     name,
+		MimeType: mimeType,
+		Size:     int64(len(contents)),
+		Created:  time.Now().UTC(),
+	})
+	if err != nil {
+		l.Error("failed to add attachment", "err", err)
+		http.Error(w, "Failed to upload. Try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	link := fmt.Sprintf("%s/attachments/%s/%s", a.config.Core.AppviewHost, hash, url.PathEscape(name))
+	markdown := fmt.Sprintf("[%s](%s)", escapeLabel(name), link)
+	if strings.HasPrefix(mimeType, "image/") {
+		markdown = "!" + markdown
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uploadResponse{
+		Url:      link,
+		Markdown: markdown,
+	})
+}
+
+func (a *Attachments) serve(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	name := chi.URLParam(r, "name")
+	if !isHash(hash) {
+		http.NotFound(w, r)
+		return
+	}
+
+	attachments, err := db.GetAttachments(a.db, db.FilterEq("hash", hash))
+	if err != nil {
+		a.logger.Error("failed to get attachment", "hash", hash, "err", err)
+		http.Error(w, "failed to get attachment", http.StatusInternalServerError)
+		return
+	}
+	if len(attachments) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	attachment := attachments[0]
+
+	f, err := a.store.Open(r.Context(), hash)
+	if err != nil {
+		a.logger.Error("failed to open attachment", "hash", hash, "err", err)
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	disposition := "attachment"
+	if slices.ContainsFunc(inlineTypes, func(prefix string) bool {
+		return strings.HasPrefix(attachment.MimeType, prefix)
+	}) {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	http.ServeContent(w, r, "", attachment.Created, f)
+}
+
+// Cleanup removes uploads that no posted body links to once they are old
+// enough, every CleanupEvery until ctx is done.
+func (a *Attachments) Cleanup(ctx context.Context) {
+	every := a.config.Attachment.CleanupEvery
+	if every <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		a.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Attachments) cleanup(ctx context.Context) {
+	cutoff := time.Now().UTC().Add(-a.config.Attachment.CleanupAfter)
+	stale, err := db.GetAttachments(
+		a.db,
+		db.FilterEq("used", 0),
+		db.FilterLte("created", cutoff.Format(time.RFC3339)),
+	)
+	if err != nil {
+		a.logger.Error("failed to get stale attachments", "err", err)
+		return
+	}
+
+	removed := 0
+	for _, attachment := range stale {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := db.DeleteAttachments(a.db, db.FilterEq("id", attachment.Id)); err != nil {
+			a.logger.Error("failed to delete attachment", "id", attachment.Id, "err", err)
+			continue
+		}
+		removed++
+
+		// the file may still be used by someone else who uploaded it too
+		others, err := db.GetAttachments(a.db, db.FilterEq("hash", attachment.Hash))
+		if err != nil {
+			a.logger.Error("failed to get attachment", "hash", attachment.Hash, "err", err)
+			continue
+		}
+		if len(others) > 0 {
+			continue
+		}
+
+		if err := a.store.Delete(ctx, attachment.Hash); err != nil {
+			a.logger.Error("failed to delete attachment contents", "hash", attachment.Hash, "err", err)
+		}
+	}
+
+	if removed > 0 {
+		a.logger.Info("removed unused attachments", "count", removed)
+	}
+}
+
+var attachmentLink = regexp.MustCompile(`/attachments/([0-9a-f]{64})/`)
+
+// Find returns the hashes of the attachments linked from source.
+func Find(source string) []string {
+	var hashes []string
+	for _, match := range attachmentLink.FindAllStringSubmatch(source, -1) {
+		if !slices.Contains(hashes, match[1]) {
+			hashes = append(hashes, match[1])
+		}
+	}
+	return hashes
+}
+
+func isHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+func cleanName(name string) string {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
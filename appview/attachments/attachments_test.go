@@ -0,0 +1,38 @@
+package attachments
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("b", 64)
+
+	source := "![shot](https://tangled.org/attachments/" + a + "/shot.png)\n" +
+		"see [log](https://tangled.org/attachments/" + b + "/build.log) and " +
+		"![again](https://tangled.org/attachments/" + a + "/shot.png)\n" +
+		"not ours: https://tangled.org/attachments/" + strings.Repeat("z", 64) + "/x"
+
+	got := Find(source)
+	want := []string{a, b}
+	if !slices.Equal(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := map[string]string{
+		"shot.png":             "shot.png",
+		"../../etc/passwd":     "passwd",
+		`C:\Users\me\shot.png`: "shot.png",
+		"  ":                   "file",
+		"..":                   "file",
+	}
+	for in, want := range tests {
+		if got := cleanName(in); got != want {
+			t.Errorf("cleanName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package commitverify
 
 import (
 	"log"
+	"time"
 
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"tangled.org/core/appview/db"
@@ -10,20 +11,38 @@ import (
 	"tangled.org/core/types"
 )
 
+// Status tells a good signature apart from one made with a key that has
+// since gone bad.
+type Status string
+
+const (
+	StatusUnverified Status = ""
+	StatusVerified   Status = "verified"
+	StatusKeyExpired Status = "key expired"
+	StatusKeyRevoked Status = "key revoked"
+)
+
 type verifiedCommit struct {
 	fingerprint string
 	hash        string
+	status      Status
 }
 
 type VerifiedCommits map[verifiedCommit]struct{}
 
+// IsVerified reports whether hash was signed with a key that was valid at
+// the time.
 func (vcs VerifiedCommits) IsVerified(hash string) bool {
+	return vcs.Status(hash) == StatusVerified
+}
+
+func (vcs VerifiedCommits) Status(hash string) Status {
 	for vc := range vcs {
 		if vc.hash == hash {
-			return true
+			return vc.status
 		}
 	}
-	return false
+	return StatusUnverified
 }
 
 func (vcs VerifiedCommits) Fingerprint(hash string) string {
@@ -51,13 +70,18 @@ func GetVerifiedCommits(e db.Execer, emailToDid map[string]string, ndCommits []t
 	for _, commit := range ndCommits {
 		c := commit.Commit
 
+		// only ssh signatures can be checked against registered keys
+		if crypto.DetectSignatureType([]byte(c.PGPSignature)) != crypto.SignatureSSH {
+			continue
+		}
+
 		committerEmail := c.Committer.Email
 		if did, exists := emailToDid[committerEmail]; exists {
 			// check if we've already fetched public keys for this did
 			pubKeys, ok := didPubkeyCache[did]
 			if !ok {
-				// fetch and cache public keys
-				keys, err := db.GetPublicKeysForDid(e, did)
+				// fetch and cache public keys, revoked ones too
+				keys, err := db.GetPublicKeys(e, db.FilterEq("did", did))
 				if err != nil {
 					log.Printf("failed to fetch pubkey for %s: %v", committerEmail, err)
 					continue
@@ -67,26 +91,118 @@ func GetVerifiedCommits(e db.Execer, emailToDid map[string]string, ndCommits []t
 			}
 
 			// try to verify with any associated pubkeys
-			for _, pk := range pubKeys {
-				if _, ok := crypto.VerifyCommitSignature(pk.Key, commit); ok {
+			vc, ok := verify(pubKeys, c.This, c.Committer.When, func(key string) bool {
+				_, ok := crypto.VerifyCommitSignature(key, commit)
+				return ok
+			})
+			if ok {
+				vcs[vc] = struct{}{}
+			}
+		}
+	}
 
-					fp, err := crypto.SSHFingerprint(pk.Key)
-					if err != nil {
-						log.Println("error computing ssh fingerprint:", err)
-					}
+	return vcs, nil
+}
 
-					vc := verifiedCommit{fingerprint: fp, hash: c.This}
-					vcs[vc] = struct{}{}
-					break
-				}
+// GetVerifiedTags verifies signed annotated tags against the keys of their
+// taggers, the result is keyed by the hash of the tag object.
+func GetVerifiedTags(e db.Execer, emailToDid map[string]string, tags []*types.TagReference) (VerifiedCommits, error) {
+	vcs := VerifiedCommits{}
+
+	didPubkeyCache := make(map[string][]models.PublicKey)
+
+	for _, tag := range tags {
+		if tag.Tag == nil || tag.Payload == "" {
+			continue
+		}
+
+		// only ssh signatures can be checked against registered keys
+		if crypto.DetectSignatureType([]byte(tag.Tag.PGPSignature)) != crypto.SignatureSSH {
+			continue
+		}
+
+		taggerEmail := tag.Tag.Tagger.Email
+		did, exists := emailToDid[taggerEmail]
+		if !exists {
+			continue
+		}
+
+		pubKeys, ok := didPubkeyCache[did]
+		if !ok {
+			keys, err := db.GetPublicKeys(e, db.FilterEq("did", did))
+			if err != nil {
+				log.Printf("failed to fetch pubkey for %s: %v", taggerEmail, err)
+				continue
 			}
+			pubKeys = keys
+			didPubkeyCache[did] = pubKeys
+		}
 
+		vc, ok := verify(pubKeys, tag.Tag.Hash.String(), tag.Tag.Tagger.When, func(key string) bool {
+			_, ok := crypto.VerifyTagSignature(key, *tag)
+			return ok
+		})
+		if ok {
+			vcs[vc] = struct{}{}
 		}
 	}
 
 	return vcs, nil
 }
 
+// verify finds the key that signed hash at signedAt, preferring keys that
+// were valid then over expired ones, and expired ones over revoked ones.
+func verify(pubKeys []models.PublicKey, hash string, signedAt time.Time, check func(key string) bool) (verifiedCommit, bool) {
+	var found verifiedCommit
+	var ok bool
+
+	for _, pk := range pubKeys {
+		status := keyStatus(pk, signedAt)
+		if ok && rank(status) <= rank(found.status) {
+			continue
+		}
+		if !check(pk.Key) {
+			continue
+		}
+
+		fp, err := crypto.SSHFingerprint(pk.Key)
+		if err != nil {
+			log.Println("error computing ssh fingerprint:", err)
+		}
+
+		found, ok = verifiedCommit{fingerprint: fp, hash: hash, status: status}, true
+		if status == StatusVerified {
+			break
+		}
+	}
+
+	return found, ok
+}
+
+func keyStatus(pk models.PublicKey, signedAt time.Time) Status {
+	switch {
+	case pk.Revoked != nil:
+		return StatusKeyRevoked
+	case pk.ExpiredAt(signedAt):
+		return StatusKeyExpired
+	default:
+		return StatusVerified
+	}
+}
+
+func rank(s Status) int {
+	switch s {
+	case StatusVerified:
+		return 3
+	case StatusKeyExpired:
+		return 2
+	case StatusKeyRevoked:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // ObjectCommitToNiceDiff is a compatibility function to convert a
 // commit object into a NiceDiff structure.
 func ObjectCommitToNiceDiff(c *object.Commit) types.NiceDiff {
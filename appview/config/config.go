@@ -3,7 +3,9 @@ package config
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"slices"
 	"time"
 
 	"github.com/sethvargo/go-envconfig"
@@ -18,6 +20,13 @@ type CoreConfig struct {
 	Dev                     bool   `env:"DEV, default=false"`
 	DisallowedNicknamesFile string `env:"DISALLOWED_NICKNAMES_FILE"`
 
+	// where the key authenticator app secrets are sealed with is kept, it is
+	// generated on first start if missing
+	TotpKeyFile string `env:"TOTP_KEY_FILE, default=totp.key"`
+
+	// the branch new repos start with, unless their owner picked another
+	DefaultBranch string `env:"DEFAULT_BRANCH, default=main"`
+
 	// temporarily, to add users to default knot and spindle
 	AppPassword string `env:"APP_PASSWORD"`
 
@@ -25,9 +34,27 @@ type CoreConfig struct {
 	TmpAltAppPassword string `env:"ALT_APP_PASSWORD"`
 }
 
+// DbConfig tunes the connections to the sqlite database. Writers wait up to
+// BusyTimeout for a locked database, and some transactions are retried
+// BusyRetries times if it stays locked for longer.
+type DbConfig struct {
+	BusyTimeout  time.Duration `env:"BUSY_TIMEOUT, default=5s"`
+	MaxOpenConns int           `env:"MAX_OPEN_CONNS, default=32"`
+	MaxIdleConns int           `env:"MAX_IDLE_CONNS, default=8"`
+	BusyRetries  int           `env:"BUSY_RETRIES, default=3"`
+}
+
 type OAuthConfig struct {
 	ClientSecret string `env:"CLIENT_SECRET"`
 	ClientKid    string `env:"CLIENT_KID"`
+
+	// A login ends once it goes unused for SessionIdle, or SessionMax after
+	// it began, whichever comes first. Logins that ask to be remembered get
+	// RememberIdle and RememberMax instead, and outlive the browser session.
+	SessionIdle  time.Duration `env:"SESSION_IDLE, default=24h"`
+	SessionMax   time.Duration `env:"SESSION_MAX, default=168h"`
+	RememberIdle time.Duration `env:"REMEMBER_IDLE, default=336h"`
+	RememberMax  time.Duration `env:"REMEMBER_MAX, default=2160h"`
 }
 
 type PlcConfig struct {
@@ -44,6 +71,10 @@ type ConsumerConfig struct {
 	ConnectionTimeout time.Duration `env:"CONNECTION_TIMEOUT, default=5s"`
 	WorkerCount       int           `env:"WORKER_COUNT, default=64"`
 	QueueSize         int           `env:"QUEUE_SIZE, default=100"`
+
+	// ingesters are not all idempotent, so messages are not retried unless
+	// asked for; failed ones are kept in the dead_letters table either way
+	MaxAttempts int `env:"MAX_ATTEMPTS, default=1"`
 }
 
 type ResendConfig struct {
@@ -84,11 +115,168 @@ type Cloudflare struct {
 	TurnstileSecretKey string `env:"TURNSTILE_SECRET_KEY"`
 }
 
+// AntiAbuseConfig picks what keeps bots from signing up: Cloudflare
+// Turnstile, a proof-of-work that the browser solves, or nothing. With
+// proof-of-work, creating repos needs one too. Each bit of difficulty doubles
+// the work.
+type AntiAbuseConfig struct {
+	Challenge     string `env:"CHALLENGE, default=turnstile"`
+	PowDifficulty int    `env:"POW_DIFFICULTY, default=18"`
+}
+
+const (
+	ChallengeTurnstile = "turnstile"
+	ChallengePow       = "pow"
+	ChallengeNone      = "none"
+)
+
+func (c AntiAbuseConfig) UsesPow() bool {
+	return c.Challenge == ChallengePow
+}
+
 type LabelConfig struct {
 	DefaultLabelDefs []string `env:"DEFAULTS, default=at://did:plc:wshs7t2adsemcrrd4snkeqli/sh.tangled.label.definition/wontfix,at://did:plc:wshs7t2adsemcrrd4snkeqli/sh.tangled.label.definition/good-first-issue,at://did:plc:wshs7t2adsemcrrd4snkeqli/sh.tangled.label.definition/duplicate,at://did:plc:wshs7t2adsemcrrd4snkeqli/sh.tangled.label.definition/documentation,at://did:plc:wshs7t2adsemcrrd4snkeqli/sh.tangled.label.definition/assignee"` // delimiter=,
 	GoodFirstIssue   string   `env:"GFI, default=at://did:plc:wshs7t2adsemcrrd4snkeqli/sh.tangled.label.definition/good-first-issue"`
 }
 
+// KnotClientConfig tunes the clients used to call knots and spindles.
+// Transient failures are retried with exponential backoff, starting at
+// RetryWaitMin and doubling up to RetryWaitMax.
+type KnotClientConfig struct {
+	Timeout      time.Duration `env:"TIMEOUT, default=30s"`
+	MaxRetries   int           `env:"MAX_RETRIES, default=3"`
+	RetryWaitMin time.Duration `env:"RETRY_WAIT_MIN, default=250ms"`
+	RetryWaitMax time.Duration `env:"RETRY_WAIT_MAX, default=4s"`
+
+	// HealthTTL is how long what a knot says about its health is reused
+	// for before it is asked again.
+	HealthTTL time.Duration `env:"HEALTH_TTL, default=5m"`
+}
+
+// PatchConfig bounds the patches that pulls may be opened or resubmitted
+// with, so that knots are never asked to apply pathological diffs. A limit
+// of 0 turns it off.
+type PatchConfig struct {
+	MaxBytes int `env:"MAX_BYTES, default=10485760"`
+	MaxFiles int `env:"MAX_FILES, default=1000"`
+	MaxHunks int `env:"MAX_HUNKS, default=10000"`
+
+	// RenameThreshold is how similar, in percent, a deleted and an added
+	// file must be to be shown as a rename. 0 turns detection off.
+	RenameThreshold int `env:"RENAME_THRESHOLD, default=50"`
+
+	// MergeCheckTTL is how long the result of checking whether a pull
+	// merges cleanly is reused for. 0 checks with the knot every time.
+	MergeCheckTTL time.Duration `env:"MERGE_CHECK_TTL, default=5m"`
+
+	// HiddenRefSweepEvery is how often the hidden refs of forks that no
+	// open pull compares against are removed from knots. 0 turns it off.
+	HiddenRefSweepEvery time.Duration `env:"HIDDEN_REF_SWEEP_EVERY, default=24h"`
+}
+
+// AttachmentConfig bounds the files users may attach to issues, pulls and
+// comments. Uploads that no posted body links to are removed once they are
+// older than CleanupAfter.
+type AttachmentConfig struct {
+	Dir          string        `env:"DIR, default=attachments"`
+	MaxBytes     int64         `env:"MAX_BYTES, default=10485760"`
+	QuotaBytes   int64         `env:"QUOTA_BYTES, default=104857600"`
+	CleanupAfter time.Duration `env:"CLEANUP_AFTER, default=24h"`
+	CleanupEvery time.Duration `env:"CLEANUP_EVERY, default=1h"`
+}
+
+// UploadConfig bounds the files that may be uploaded to a repo from the
+// browser. All files of an upload go into a single commit, so MaxBytes
+// and MaxFiles apply to the upload as a whole.
+type UploadConfig struct {
+	MaxFileBytes int64 `env:"MAX_FILE_BYTES, default=10485760"`
+	MaxBytes     int64 `env:"MAX_BYTES, default=26214400"`
+	MaxFiles     int   `env:"MAX_FILES, default=100"`
+}
+
+// WebhookConfig bounds what is sent to webhooks and what is kept of each
+// delivery. Payloads larger than MaxPayloadBytes are not sent, and responses
+// are cut at MaxResponseBytes. Each webhook keeps its last KeepDeliveries
+// deliveries, for no longer than KeepFor.
+type WebhookConfig struct {
+	Timeout          time.Duration `env:"TIMEOUT, default=10s"`
+	MaxPayloadBytes  int           `env:"MAX_PAYLOAD_BYTES, default=262144"`
+	MaxResponseBytes int64         `env:"MAX_RESPONSE_BYTES, default=16384"`
+	KeepDeliveries   int           `env:"KEEP_DELIVERIES, default=100"`
+	KeepFor          time.Duration `env:"KEEP_FOR, default=720h"`
+}
+
+// MailConfig sets up taking patches over email. Mail sent to
+// <owner>/<repo>@Domain, or <owner>/<repo>+<branch>@Domain, is handed to the
+// appview by a relay that posts the raw message along with InboundSecret.
+// The relay must add its DKIM result as the topmost Authentication-Results
+// header, naming AuthServId. Without a secret and an authserv-id, no mail is
+// taken.
+type MailConfig struct {
+	Domain        string `env:"DOMAIN, default=patches.tangled.org"`
+	InboundSecret string `env:"INBOUND_SECRET"`
+	AuthServId    string `env:"AUTHSERV_ID"`
+	MaxBytes      int64  `env:"MAX_BYTES, default=12582912"`
+}
+
+// ExportConfig sets up the archives users can export their data to. An
+// archive is kept under Dir for KeepFor after it is built; expired ones are
+// looked for every SweepEvery. Archives of repos are only taken from knots
+// that answer within RepoTimeout.
+type ExportConfig struct {
+	Dir         string        `env:"DIR, default=exports"`
+	KeepFor     time.Duration `env:"KEEP_FOR, default=168h"`
+	SweepEvery  time.Duration `env:"SWEEP_EVERY, default=1h"`
+	RepoTimeout time.Duration `env:"REPO_TIMEOUT, default=2m"`
+}
+
+// DeletionConfig sets up account deletion. Accounts are deleted GracePeriod
+// after their owner asks for it, so that they can change their mind; a
+// deletion that got stuck, say on a knot that is down, is tried again every
+// RetryEvery. Knots are given KnotTimeout to remove a repo.
+type DeletionConfig struct {
+	GracePeriod time.Duration `env:"GRACE_PERIOD, default=168h"`
+	RetryEvery  time.Duration `env:"RETRY_EVERY, default=15m"`
+	KnotTimeout time.Duration `env:"KNOT_TIMEOUT, default=30s"`
+}
+
+// AccessLogConfig sets up the log line written for every request. Requests
+// are logged at Level, and only SampleRate of them are; requests that failed
+// with a server error are always logged, as errors.
+type AccessLogConfig struct {
+	Level      slog.Level `env:"LEVEL, default=info"`
+	SampleRate float64    `env:"SAMPLE_RATE, default=1"`
+}
+
+// ModerationConfig lists who reviews the reports users file, and how many
+// reports a user may file in a day.
+type ModerationConfig struct {
+	Moderators    []string `env:"MODERATORS"`
+	ReportsPerDay int      `env:"REPORTS_PER_DAY, default=20"`
+}
+
+func (c ModerationConfig) IsModerator(did string) bool {
+	return slices.Contains(c.Moderators, did)
+}
+
+// AdminConfig lists the admins of this instance. The admin dashboard is only
+// served when there are any. With ApproveRegistrations, new knots and
+// spindles only serve once an admin approved them.
+type AdminConfig struct {
+	Dids                 []string `env:"DIDS"`
+	ApproveRegistrations bool     `env:"APPROVE_REGISTRATIONS, default=false"`
+}
+
+func (c AdminConfig) IsAdmin(did string) bool {
+	return slices.Contains(c.Dids, did)
+}
+
+// ReviewsRegistrations says whether new knots and spindles wait for an
+// admin. It takes an admin to approve them, so without any they don't.
+func (c AdminConfig) ReviewsRegistrations() bool {
+	return c.ApproveRegistrations && len(c.Dids) > 0
+}
+
 func (cfg RedisConfig) ToURL() string {
 	u := &url.URL{
 		Scheme: "redis",
@@ -104,20 +292,33 @@ func (cfg RedisConfig) ToURL() string {
 }
 
 type Config struct {
-	Core          CoreConfig      `env:",prefix=TANGLED_"`
-	Jetstream     JetstreamConfig `env:",prefix=TANGLED_JETSTREAM_"`
-	Knotstream    ConsumerConfig  `env:",prefix=TANGLED_KNOTSTREAM_"`
-	Spindlestream ConsumerConfig  `env:",prefix=TANGLED_SPINDLESTREAM_"`
-	Resend        ResendConfig    `env:",prefix=TANGLED_RESEND_"`
-	Posthog       PosthogConfig   `env:",prefix=TANGLED_POSTHOG_"`
-	Camo          CamoConfig      `env:",prefix=TANGLED_CAMO_"`
-	Avatar        AvatarConfig    `env:",prefix=TANGLED_AVATAR_"`
-	OAuth         OAuthConfig     `env:",prefix=TANGLED_OAUTH_"`
-	Redis         RedisConfig     `env:",prefix=TANGLED_REDIS_"`
-	Plc           PlcConfig       `env:",prefix=TANGLED_PLC_"`
-	Pds           PdsConfig       `env:",prefix=TANGLED_PDS_"`
-	Cloudflare    Cloudflare      `env:",prefix=TANGLED_CLOUDFLARE_"`
-	Label         LabelConfig     `env:",prefix=TANGLED_LABEL_"`
+	Core          CoreConfig       `env:",prefix=TANGLED_"`
+	Db            DbConfig         `env:",prefix=TANGLED_DB_"`
+	Jetstream     JetstreamConfig  `env:",prefix=TANGLED_JETSTREAM_"`
+	Knotstream    ConsumerConfig   `env:",prefix=TANGLED_KNOTSTREAM_"`
+	Spindlestream ConsumerConfig   `env:",prefix=TANGLED_SPINDLESTREAM_"`
+	Resend        ResendConfig     `env:",prefix=TANGLED_RESEND_"`
+	Posthog       PosthogConfig    `env:",prefix=TANGLED_POSTHOG_"`
+	Camo          CamoConfig       `env:",prefix=TANGLED_CAMO_"`
+	Avatar        AvatarConfig     `env:",prefix=TANGLED_AVATAR_"`
+	OAuth         OAuthConfig      `env:",prefix=TANGLED_OAUTH_"`
+	Redis         RedisConfig      `env:",prefix=TANGLED_REDIS_"`
+	Plc           PlcConfig        `env:",prefix=TANGLED_PLC_"`
+	Pds           PdsConfig        `env:",prefix=TANGLED_PDS_"`
+	Cloudflare    Cloudflare       `env:",prefix=TANGLED_CLOUDFLARE_"`
+	AntiAbuse     AntiAbuseConfig  `env:",prefix=TANGLED_ANTI_ABUSE_"`
+	Label         LabelConfig      `env:",prefix=TANGLED_LABEL_"`
+	KnotClient    KnotClientConfig `env:",prefix=TANGLED_KNOT_CLIENT_"`
+	Patch         PatchConfig      `env:",prefix=TANGLED_PATCH_"`
+	Attachment    AttachmentConfig `env:",prefix=TANGLED_ATTACHMENT_"`
+	Upload        UploadConfig     `env:",prefix=TANGLED_UPLOAD_"`
+	Webhook       WebhookConfig    `env:",prefix=TANGLED_WEBHOOK_"`
+	Mail          MailConfig       `env:",prefix=TANGLED_MAIL_"`
+	Moderation    ModerationConfig `env:",prefix=TANGLED_MODERATION_"`
+	Admin         AdminConfig      `env:",prefix=TANGLED_ADMIN_"`
+	Export        ExportConfig     `env:",prefix=TANGLED_EXPORT_"`
+	Deletion      DeletionConfig   `env:",prefix=TANGLED_DELETION_"`
+	AccessLog     AccessLogConfig  `env:",prefix=TANGLED_ACCESS_LOG_"`
 }
 
 func LoadConfig(ctx context.Context) (*Config, error) {
@@ -127,5 +328,15 @@ func LoadConfig(ctx context.Context) (*Config, error) {
 		return nil, err
 	}
 
+	switch cfg.AntiAbuse.Challenge {
+	case ChallengeTurnstile, ChallengePow, ChallengeNone:
+	default:
+		return nil, fmt.Errorf("unknown anti-abuse challenge %q", cfg.AntiAbuse.Challenge)
+	}
+
+	if cfg.AccessLog.SampleRate < 0 || cfg.AccessLog.SampleRate > 1 {
+		return nil, fmt.Errorf("access log sample rate must be between 0 and 1, got %v", cfg.AccessLog.SampleRate)
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddAdminAction(e Execer, action *models.AdminAction) error {
+	result, err := e.Exec(
+		`insert into admin_actions (admin_did, action, subject, reason) values (?, ?, ?, ?)`,
+		action.AdminDid,
+		action.Action,
+		action.Subject,
+		action.Reason,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add admin action: %w", err)
+	}
+
+	action.Id, err = result.LastInsertId()
+	return err
+}
+
+// GetAdminActions returns the latest entries of the audit log, newest first.
+func GetAdminActions(e Execer, limit int) ([]models.AdminAction, error) {
+	rows, err := e.Query(
+		`select id, admin_did, action, subject, reason, created
+		from admin_actions
+		order by created desc, id desc
+		limit ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []models.AdminAction
+	for rows.Next() {
+		var action models.AdminAction
+		var created string
+		if err := rows.Scan(
+			&action.Id,
+			&action.AdminDid,
+			&action.Action,
+			&action.Subject,
+			&action.Reason,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+
+		action.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			action.Created = t
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestAdminActions(t *testing.T) {
+	d := createTestDB(t)
+
+	subject := "did:plc:spammer"
+	assert.NoError(t, AddTakedown(d, &models.Takedown{Subject: subject, ByDid: "did:plc:admin"}))
+	assert.NoError(t, AddAdminAction(d, &models.AdminAction{
+		AdminDid: "did:plc:admin",
+		Action:   models.AdminSuspend,
+		Subject:  subject,
+		Reason:   "spam",
+	}))
+
+	takedowns, err := GetRecentTakedowns(d, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(takedowns))
+	assert.Equal(t, subject, takedowns[0].Subject)
+
+	assert.NoError(t, DeleteTakedown(d, subject))
+	assert.NoError(t, AddAdminAction(d, &models.AdminAction{
+		AdminDid: "did:plc:admin",
+		Action:   models.AdminRestore,
+		Subject:  subject,
+	}))
+	assert.False(t, IsTakenDown(d, subject))
+
+	actions, err := GetAdminActions(d, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(actions))
+	assert.Equal(t, models.AdminRestore, actions[0].Action)
+	assert.Equal(t, models.AdminSuspend, actions[1].Action)
+	assert.Equal(t, "spam", actions[1].Reason)
+}
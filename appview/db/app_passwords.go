@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddAppPassword(e Execer, a *models.AppPassword) error {
+	result, err := e.Exec(
+		`insert into app_passwords (did, name, password_hash, scopes, knots) values (?, ?, ?, ?, ?)`,
+		a.Did, a.Name, a.PasswordHash, strings.Join(a.Scopes, " "), strings.Join(a.Knots, " "),
+	)
+	if err != nil {
+		return err
+	}
+
+	a.Id, err = result.LastInsertId()
+	return err
+}
+
+func DeleteAppPassword(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from app_passwords %s`, whereClause)
+
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+func TouchAppPassword(e Execer, id int64) error {
+	_, err := e.Exec(
+		`update app_passwords set last_used = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') where id = ?`,
+		id,
+	)
+	return err
+}
+
+func GetAppPasswords(e Execer, filters ...filter) ([]models.AppPassword, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, did, name, password_hash, scopes, knots, created, last_used
+		from app_passwords %s
+		order by created desc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var passwords []models.AppPassword
+	for rows.Next() {
+		var a models.AppPassword
+		var scopes, knots, createdAt string
+		var lastUsed sql.NullString
+		if err := rows.Scan(&a.Id, &a.Did, &a.Name, &a.PasswordHash, &scopes, &knots, &createdAt, &lastUsed); err != nil {
+			return nil, err
+		}
+
+		a.Scopes = strings.Fields(scopes)
+		a.Knots = strings.Fields(knots)
+
+		a.Created, err = time.Parse(time.RFC3339, createdAt)
+		if err != nil {
+			a.Created = time.Now()
+		}
+
+		if lastUsed.Valid {
+			if t, err := time.Parse(time.RFC3339, lastUsed.String); err == nil {
+				a.LastUsed = &t
+			}
+		}
+
+		passwords = append(passwords, a)
+	}
+
+	return passwords, rows.Err()
+}
+
+func GetAppPassword(e Execer, filters ...filter) (*models.AppPassword, error) {
+	passwords, err := GetAppPasswords(e, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	if passwords == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if len(passwords) != 1 {
+		return nil, fmt.Errorf("too many rows returned")
+	}
+
+	return &passwords[0], nil
+}
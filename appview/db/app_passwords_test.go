@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestAppPasswords(t *testing.T) {
+	d := createTestDB(t)
+
+	password := &models.AppPassword{
+		Did:          "did:plc:alice",
+		Name:         "laptop",
+		PasswordHash: models.HashAppPassword("tgl_secret"),
+		Scopes:       []string{"git:read", "git:write"},
+		Knots:        []string{"knot.example.com"},
+	}
+	assert.NoError(t, AddAppPassword(d, password))
+
+	found, err := GetAppPassword(d, FilterEq("password_hash", models.HashAppPassword("tgl_secret")))
+	assert.NoError(t, err)
+	assert.Equal(t, "did:plc:alice", found.Did)
+	assert.Equal(t, []string{"git:read", "git:write"}, found.Scopes)
+	assert.True(t, found.LastUsed == nil)
+	assert.True(t, found.AllowsKnot("did:web:knot.example.com"))
+	assert.False(t, found.AllowsKnot("did:web:evil.example.com"))
+
+	assert.NoError(t, TouchAppPassword(d, found.Id))
+	found, err = GetAppPassword(d, FilterEq("id", password.Id))
+	assert.NoError(t, err)
+	assert.True(t, found.LastUsed != nil)
+
+	// others can't revoke it
+	assert.NoError(t, DeleteAppPassword(d, FilterEq("did", "did:plc:bob"), FilterEq("id", password.Id)))
+	_, err = GetAppPassword(d, FilterEq("id", password.Id))
+	assert.NoError(t, err)
+
+	assert.NoError(t, DeleteAppPassword(d, FilterEq("did", "did:plc:alice"), FilterEq("id", password.Id)))
+	_, err = GetAppPassword(d, FilterEq("id", password.Id))
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func TestApprovals(t *testing.T) {
+	d := createTestDB(t)
+
+	assert.NoError(t, AddKnot(d, "knot.example.com", "did:plc:owner", models.ApprovalPending))
+	assert.NoError(t, SetKnotApproval(
+		d,
+		models.ApprovalApproved,
+		FilterEq("did", "did:plc:owner"),
+		FilterEq("domain", "knot.example.com"),
+	))
+
+	registrations, err := GetRegistrations(d, FilterEq("domain", "knot.example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(registrations))
+	assert.True(t, registrations[0].Approval.IsApproved())
+
+	assert.NoError(t, AddSpindle(d, models.Spindle{
+		Owner:    syntax.DID("did:plc:owner"),
+		Instance: "spindle.example.com",
+		Approval: models.ApprovalPending,
+	}))
+	assert.NoError(t, SetSpindleApproval(
+		d,
+		models.ApprovalRejected,
+		FilterEq("owner", "did:plc:owner"),
+		FilterEq("instance", "spindle.example.com"),
+	))
+
+	spindles, err := GetSpindles(d, FilterEq("instance", "spindle.example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(spindles))
+	assert.Equal(t, models.ApprovalRejected, spindles[0].Approval)
+
+	// the check constraint keeps out anything else
+	assert.Error(t, SetSpindleApproval(d, models.Approval("maybe")))
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddAttachment(e Execer, attachment models.Attachment) error {
+	_, err := e.Exec(
+		`insert or ignore into attachments (did, hash, name, mime_type, size, created)
+		values (?, ?, ?, ?, ?, ?)`,
+		attachment.Did,
+		attachment.Hash,
+		attachment.Name,
+		attachment.MimeType,
+		attachment.Size,
+		attachment.Created.Format(time.RFC3339),
+	)
+	return err
+}
+
+func GetAttachments(e Execer, filters ...filter) ([]models.Attachment, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, did, hash, name, mime_type, size, created, used
+		from attachments %s
+		order by id asc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var attachment models.Attachment
+		var created string
+		var used int
+		if err := rows.Scan(
+			&attachment.Id,
+			&attachment.Did,
+			&attachment.Hash,
+			&attachment.Name,
+			&attachment.MimeType,
+			&attachment.Size,
+			&created,
+			&used,
+		); err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			attachment.Created = t
+		}
+		attachment.Used = used != 0
+
+		attachments = append(attachments, attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// AttachmentUsage is the number of bytes did has uploaded and not yet had
+// cleaned up.
+func AttachmentUsage(e Execer, did string) (int64, error) {
+	var usage int64
+	err := e.QueryRow(
+		`select coalesce(sum(size), 0) from attachments where did = ?`,
+		did,
+	).Scan(&usage)
+	return usage, err
+}
+
+// MarkAttachmentsUsed marks the attachments did uploaded with the given
+// hashes as linked from a posted body, so cleanup leaves them be.
+func MarkAttachmentsUsed(e Execer, did string, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	filters := []filter{
+		FilterEq("did", did),
+		FilterIn("hash", hashes),
+	}
+
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	query := fmt.Sprintf(
+		`update attachments set used = 1 where %s`,
+		strings.Join(conditions, " and "),
+	)
+
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+func DeleteAttachments(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from attachments %s`, whereClause)
+
+	_, err := e.Exec(query, args...)
+	return err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddBranchDeletion(e Execer, deletion models.BranchDeletion) error {
+	created := deletion.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	_, err := e.Exec(
+		`insert into branch_deletions (pull_at, repo_at, branch, did, error, created)
+		values (?, ?, ?, ?, ?, ?)`,
+		deletion.PullAt,
+		deletion.RepoAt,
+		deletion.Branch,
+		deletion.Did,
+		deletion.Error,
+		created.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetBranchDeletions returns the matching outcomes, latest first.
+func GetBranchDeletions(e Execer, filters ...filter) ([]models.BranchDeletion, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, pull_at, repo_at, branch, did, error, created
+		from branch_deletions %s
+		order by created desc, id desc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deletions []models.BranchDeletion
+	for rows.Next() {
+		var deletion models.BranchDeletion
+		var created string
+		if err := rows.Scan(
+			&deletion.Id,
+			&deletion.PullAt,
+			&deletion.RepoAt,
+			&deletion.Branch,
+			&deletion.Did,
+			&deletion.Error,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			deletion.Created = t
+		}
+
+		deletions = append(deletions, deletion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deletions, nil
+}
@@ -10,12 +10,17 @@ import (
 
 func AddCollaborator(e Execer, c models.Collaborator) error {
 	_, err := e.Exec(
-		`insert into collaborators (did, rkey, subject_did, repo_at) values (?, ?, ?, ?);`,
-		c.Did, c.Rkey, c.SubjectDid, c.RepoAt,
+		`insert into collaborators (did, rkey, subject_did, repo_at, role) values (?, ?, ?, ?, ?);`,
+		c.Did, c.Rkey, c.SubjectDid, c.RepoAt, c.Role,
 	)
 	return err
 }
 
+func SetCollaboratorRole(e Execer, id int64, role string) error {
+	_, err := e.Exec(`update collaborators set role = ? where id = ?`, role, id)
+	return err
+}
+
 func DeleteCollaborator(e Execer, filters ...filter) error {
 	var conditions []string
 	var args []any
@@ -79,6 +84,7 @@ func GetCollaborators(e Execer, filters ...filter) ([]models.Collaborator, error
 			rkey,
 			subject_did,
 			repo_at,
+			role,
 			created
 		from collaborators %s`,
 		whereClause,
@@ -97,6 +103,7 @@ func GetCollaborators(e Execer, filters ...filter) ([]models.Collaborator, error
 			&collaborator.Rkey,
 			&collaborator.SubjectDid,
 			&collaborator.RepoAt,
+			&collaborator.Role,
 			&createdAt,
 		); err != nil {
 			return nil, err
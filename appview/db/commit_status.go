@@ -0,0 +1,179 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+// SetCommitStatus records status, replacing any earlier status of the same
+// repo, commit and context.
+func SetCommitStatus(e Execer, status models.CommitStatus) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := e.Exec(
+		`insert into commit_statuses (repo_at, sha, context, state, target_url, description, did, created, updated)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		on conflict(repo_at, sha, context) do update set
+			state = excluded.state,
+			target_url = excluded.target_url,
+			description = excluded.description,
+			did = excluded.did,
+			updated = excluded.updated`,
+		status.RepoAt,
+		status.Sha,
+		status.Context,
+		status.State,
+		status.TargetUrl,
+		status.Description,
+		status.Did,
+		now,
+		now,
+	)
+	return err
+}
+
+func GetCommitStatuses(e Execer, filters ...filter) ([]models.CommitStatus, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, repo_at, sha, context, state, target_url, description, did, created, updated
+		from commit_statuses %s
+		order by context asc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []models.CommitStatus
+	for rows.Next() {
+		var status models.CommitStatus
+		var created, updated string
+		if err := rows.Scan(
+			&status.Id,
+			&status.RepoAt,
+			&status.Sha,
+			&status.Context,
+			&status.State,
+			&status.TargetUrl,
+			&status.Description,
+			&status.Did,
+			&created,
+			&updated,
+		); err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			status.Created = t
+		}
+		if t, err := time.Parse(time.RFC3339, updated); err == nil {
+			status.Updated = t
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+func AddRequiredCheck(e Execer, check models.RequiredCheck) error {
+	_, err := e.Exec(
+		`insert or ignore into required_checks (repo_at, branch, context)
+		values (?, ?, ?)`,
+		check.RepoAt,
+		check.Branch,
+		check.Context,
+	)
+	return err
+}
+
+func GetRequiredChecks(e Execer, filters ...filter) ([]models.RequiredCheck, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, repo_at, branch, context, created
+		from required_checks %s
+		order by branch asc, context asc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []models.RequiredCheck
+	for rows.Next() {
+		var check models.RequiredCheck
+		var created string
+		if err := rows.Scan(
+			&check.Id,
+			&check.RepoAt,
+			&check.Branch,
+			&check.Context,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			check.Created = t
+		}
+
+		checks = append(checks, check)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
+
+func DeleteRequiredChecks(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	_, err := e.Exec(`delete from required_checks`+whereClause, args...)
+	return err
+}
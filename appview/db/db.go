@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"reflect"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"tangled.org/core/log"
@@ -14,7 +15,19 @@ import (
 
 type DB struct {
 	*sql.DB
-	logger *slog.Logger
+	logger      *slog.Logger
+	busyRetries int
+}
+
+// Options tunes the connections to the database. Writers that find it locked
+// wait up to BusyTimeout for it, and transactions run through Transact that
+// still find it busy are retried BusyRetries times. Zero values leave the
+// defaults of the sqlite driver and database/sql in place.
+type Options struct {
+	BusyTimeout  time.Duration
+	MaxOpenConns int
+	MaxIdleConns int
+	BusyRetries  int
 }
 
 type Execer interface {
@@ -28,13 +41,20 @@ type Execer interface {
 	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
 }
 
-func Make(ctx context.Context, dbPath string) (*DB, error) {
+// Open connects to the database at dbPath, leaving its schema as it is.
+func Open(ctx context.Context, dbPath string, options Options) (*DB, error) {
 	// https://github.com/mattn/go-sqlite3#connection-string
 	opts := []string{
 		"_foreign_keys=1",
 		"_journal_mode=WAL",
 		"_synchronous=NORMAL",
 		"_auto_vacuum=incremental",
+		// take the write lock when a transaction begins, where the busy
+		// timeout applies, rather than failing right away on its first write
+		"_txlock=immediate",
+	}
+	if options.BusyTimeout > 0 {
+		opts = append(opts, fmt.Sprintf("_busy_timeout=%d", options.BusyTimeout.Milliseconds()))
 	}
 
 	logger := log.FromContext(ctx)
@@ -44,14 +64,38 @@ func Make(ctx context.Context, dbPath string) (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if options.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(options.MaxOpenConns)
+	}
+	if options.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(options.MaxIdleConns)
+	}
 
-	conn, err := db.Conn(ctx)
+	return &DB{
+		db,
+		logger,
+		options.BusyRetries,
+	}, nil
+}
+
+// Make opens the database at dbPath and brings its schema up to date.
+func Make(ctx context.Context, dbPath string, options Options) (*DB, error) {
+	d, err := Open(ctx, dbPath, options)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
-	_, err = conn.ExecContext(ctx, `
+	if err := d.Migrate(ctx); err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// createTables creates the tables as they were before any migration.
+func createTables(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
 		create table if not exists registrations (
 			id integer primary key autoincrement,
 			domain text not null unique,
@@ -561,659 +605,26 @@ func Make(ctx context.Context, dbPath string) (*DB, error) {
 			email_notifications integer not null default 0
 		);
 
-		create table if not exists migrations (
+		create table if not exists reference_links (
 			id integer primary key autoincrement,
-			name text unique
+
+			-- issue, pull or comment that the reference is written in
+			from_at text not null,
+			-- issue or pull that from_at belongs to
+			thread_at text not null,
+			-- referenced issue or pull
+			to_at text not null,
+
+			created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+			unique (from_at, to_at)
 		);
 
 		-- indexes for better performance
 		create index if not exists idx_notifications_recipient_created on notifications(recipient_did, created desc);
 		create index if not exists idx_notifications_recipient_read on notifications(recipient_did, read);
+		create index if not exists idx_reference_links_to_at on reference_links(to_at);
 	`)
-	if err != nil {
-		return nil, err
-	}
-
-	// run migrations
-	runMigration(conn, logger, "add-description-to-repos", func(tx *sql.Tx) error {
-		tx.Exec(`
-			alter table repos add column description text check (length(description) <= 200);
-		`)
-		return nil
-	})
-
-	runMigration(conn, logger, "add-rkey-to-pubkeys", func(tx *sql.Tx) error {
-		// add unconstrained column
-		_, err := tx.Exec(`
-			alter table public_keys
-			add column rkey text;
-		`)
-		if err != nil {
-			return err
-		}
-
-		// backfill
-		_, err = tx.Exec(`
-			update public_keys
-			set rkey = ''
-			where rkey is null;
-		`)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
-
-	runMigration(conn, logger, "add-rkey-to-comments", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table comments drop column comment_at;
-			alter table comments add column rkey text;
-		`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-deleted-and-edited-to-issue-comments", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table comments add column deleted text; -- timestamp
-			alter table comments add column edited text; -- timestamp
-		`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-source-info-to-pulls-and-submissions", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table pulls add column source_branch text;
-			alter table pulls add column source_repo_at text;
-			alter table pull_submissions add column source_rev text;
-		`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-source-to-repos", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table repos add column source text;
-		`)
-		return err
-	})
-
-	// disable foreign-keys for the next migration
-	// NOTE: this cannot be done in a transaction, so it is run outside [0]
-	//
-	// [0]: https://sqlite.org/pragma.html#pragma_foreign_keys
-	conn.ExecContext(ctx, "pragma foreign_keys = off;")
-	runMigration(conn, logger, "recreate-pulls-column-for-stacking-support", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			create table pulls_new (
-				-- identifiers
-				id integer primary key autoincrement,
-				pull_id integer not null,
-
-				-- at identifiers
-				repo_at text not null,
-				owner_did text not null,
-				rkey text not null,
-
-				-- content
-				title text not null,
-				body text not null,
-				target_branch text not null,
-				state integer not null default 0 check (state in (0, 1, 2, 3)), -- closed, open, merged, deleted
-
-				-- source info
-				source_branch text,
-				source_repo_at text,
-
-				-- stacking
-				stack_id text,
-				change_id text,
-				parent_change_id text,
-
-				-- meta
-				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-
-				-- constraints
-				unique(repo_at, pull_id),
-				foreign key (repo_at) references repos(at_uri) on delete cascade
-			);
-
-			insert into pulls_new (
-				id, pull_id,
-				repo_at, owner_did, rkey,
-				title, body, target_branch, state,
-				source_branch, source_repo_at,
-				created
-			)
-			select
-				id, pull_id,
-				repo_at, owner_did, rkey,
-				title, body, target_branch, state,
-				source_branch, source_repo_at,
-				created
-			FROM pulls;
-
-			drop table pulls;
-			alter table pulls_new rename to pulls;
-		`)
-		return err
-	})
-	conn.ExecContext(ctx, "pragma foreign_keys = on;")
-
-	runMigration(conn, logger, "add-spindle-to-repos", func(tx *sql.Tx) error {
-		tx.Exec(`
-			alter table repos add column spindle text;
-		`)
-		return nil
-	})
-
-	// drop all knot secrets, add unique constraint to knots
-	//
-	// knots will henceforth use service auth for signed requests
-	runMigration(conn, logger, "no-more-secrets", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			create table registrations_new (
-				id integer primary key autoincrement,
-				domain text not null,
-				did text not null,
-				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-				registered text,
-				read_only integer not null default 0,
-				unique(domain, did)
-			);
-
-			insert into registrations_new (id, domain, did, created, registered, read_only)
-			select id, domain, did, created, registered, 1 from registrations
-			where registered is not null;
-
-			drop table registrations;
-			alter table registrations_new rename to registrations;
-		`)
-		return err
-	})
-
-	// recreate and add rkey + created columns with default constraint
-	runMigration(conn, logger, "rework-collaborators-table", func(tx *sql.Tx) error {
-		// create new table
-		// - repo_at instead of repo integer
-		// - rkey field
-		// - created field
-		_, err := tx.Exec(`
-			create table collaborators_new (
-				-- identifiers for the record
-				id integer primary key autoincrement,
-				did text not null,
-				rkey text,
-
-				-- content
-				subject_did text not null,
-				repo_at text not null,
-
-				-- meta
-				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-
-				-- constraints
-				foreign key (repo_at) references repos(at_uri) on delete cascade
-			)
-		`)
-		if err != nil {
-			return err
-		}
-
-		// copy data
-		_, err = tx.Exec(`
-			insert into collaborators_new (id, did, rkey, subject_did, repo_at)
-			select
-				c.id,
-				r.did,
-				'',
-				c.did,
-				r.at_uri
-			from collaborators c
-			join repos r on c.repo = r.id
-		`)
-		if err != nil {
-			return err
-		}
-
-		// drop old table
-		_, err = tx.Exec(`drop table collaborators`)
-		if err != nil {
-			return err
-		}
-
-		// rename new table
-		_, err = tx.Exec(`alter table collaborators_new rename to collaborators`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-rkey-to-issues", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table issues add column rkey text not null default '';
-
-			-- get last url section from issue_at and save to rkey column
-			update issues
-			set rkey = replace(issue_at, rtrim(issue_at, replace(issue_at, '/', '')), '');
-		`)
-		return err
-	})
-
-	// repurpose the read-only column to "needs-upgrade"
-	runMigration(conn, logger, "rename-registrations-read-only-to-needs-upgrade", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table registrations rename column read_only to needs_upgrade;
-		`)
-		return err
-	})
-
-	// require all knots to upgrade after the release of total xrpc
-	runMigration(conn, logger, "migrate-knots-to-total-xrpc", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			update registrations set needs_upgrade = 1;
-		`)
-		return err
-	})
-
-	// require all knots to upgrade after the release of total xrpc
-	runMigration(conn, logger, "migrate-spindles-to-xrpc-owner", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table spindles add column needs_upgrade integer not null default 0;
-		`)
-		return err
-	})
-
-	// remove issue_at from issues and replace with generated column
-	//
-	// this requires a full table recreation because stored columns
-	// cannot be added via alter
-	//
-	// couple other changes:
-	// - columns renamed to be more consistent
-	// - adds edited and deleted fields
-	//
-	// disable foreign-keys for the next migration
-	conn.ExecContext(ctx, "pragma foreign_keys = off;")
-	runMigration(conn, logger, "remove-issue-at-from-issues", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			create table if not exists issues_new (
-				-- identifiers
-				id integer primary key autoincrement,
-				did text not null,
-				rkey text not null,
-				at_uri text generated always as ('at://' || did || '/' || 'sh.tangled.repo.issue' || '/' || rkey) stored,
-
-				-- at identifiers
-				repo_at text not null,
-
-				-- content
-				issue_id integer not null,
-				title text not null,
-				body text not null,
-				open integer not null default 1,
-				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-				edited text,  -- timestamp
-				deleted text,  -- timestamp
-
-				unique(did, rkey),
-				unique(repo_at, issue_id),
-				unique(at_uri),
-				foreign key (repo_at) references repos(at_uri) on delete cascade
-			);
-		`)
-		if err != nil {
-			return err
-		}
-
-		// transfer data
-		_, err = tx.Exec(`
-			insert into issues_new (id, did, rkey, repo_at, issue_id, title, body, open, created)
-			select
-				i.id,
-				i.owner_did,
-				i.rkey,
-				i.repo_at,
-				i.issue_id,
-				i.title,
-				i.body,
-				i.open,
-				i.created
-			from issues i;
-		`)
-		if err != nil {
-			return err
-		}
-
-		// drop old table
-		_, err = tx.Exec(`drop table issues`)
-		if err != nil {
-			return err
-		}
-
-		// rename new table
-		_, err = tx.Exec(`alter table issues_new rename to issues`)
-		return err
-	})
-	conn.ExecContext(ctx, "pragma foreign_keys = on;")
-
-	// - renames the comments table to 'issue_comments'
-	// - rework issue comments to update constraints:
-	//   * unique(did, rkey)
-	//   * remove comment-id and just use the global ID
-	//   * foreign key (repo_at, issue_id)
-	// - new columns
-	//   * column "reply_to" which can be any other comment
-	//   * column "at-uri" which is a generated column
-	runMigration(conn, logger, "rework-issue-comments", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			create table if not exists issue_comments (
-				-- identifiers
-				id integer primary key autoincrement,
-				did text not null,
-				rkey text,
-				at_uri text generated always as ('at://' || did || '/' || 'sh.tangled.repo.issue.comment' || '/' || rkey) stored,
-
-				-- at identifiers
-				issue_at text not null,
-				reply_to text, -- at_uri of parent comment
-
-				-- content
-				body text not null,
-				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-				edited text,
-				deleted text,
-
-				-- constraints
-				unique(did, rkey),
-				unique(at_uri),
-				foreign key (issue_at) references issues(at_uri) on delete cascade
-			);
-		`)
-		if err != nil {
-			return err
-		}
-
-		// transfer data
-		_, err = tx.Exec(`
-			insert into issue_comments (id, did, rkey, issue_at, body, created, edited, deleted)
-			select
-				c.id,
-				c.owner_did,
-				c.rkey,
-				i.at_uri,  -- get at_uri from issues table
-				c.body,
-				c.created,
-				c.edited,
-				c.deleted
-			from comments c
-			join issues i on c.repo_at = i.repo_at and c.issue_id = i.issue_id;
-		`)
-		if err != nil {
-			return err
-		}
-
-		// drop old table
-		_, err = tx.Exec(`drop table comments`)
-		return err
-	})
-
-	// add generated at_uri column to pulls table
-	//
-	// this requires a full table recreation because stored columns
-	// cannot be added via alter
-	//
-	// disable foreign-keys for the next migration
-	conn.ExecContext(ctx, "pragma foreign_keys = off;")
-	runMigration(conn, logger, "add-at-uri-to-pulls", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-		create table if not exists pulls_new (
-			-- identifiers
-			id integer primary key autoincrement,
-			pull_id integer not null,
-			at_uri text generated always as ('at://' || owner_did || '/' || 'sh.tangled.repo.pull' || '/' || rkey) stored,
-
-			-- at identifiers
-			repo_at text not null,
-			owner_did text not null,
-			rkey text not null,
-
-			-- content
-			title text not null,
-			body text not null,
-			target_branch text not null,
-			state integer not null default 0 check (state in (0, 1, 2, 3)), -- closed, open, merged, deleted
-
-			-- source info
-			source_branch text,
-			source_repo_at text,
-
-			-- stacking
-			stack_id text,
-			change_id text,
-			parent_change_id text,
-
-			-- meta
-			created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-
-			-- constraints
-			unique(repo_at, pull_id),
-			unique(at_uri),
-			foreign key (repo_at) references repos(at_uri) on delete cascade
-		);
-		`)
-		if err != nil {
-			return err
-		}
-
-		// transfer data
-		_, err = tx.Exec(`
-		insert into pulls_new (
-			id, pull_id, repo_at, owner_did, rkey,
-			title, body, target_branch, state,
-			source_branch, source_repo_at,
-			stack_id, change_id, parent_change_id,
-			created
-		)
-		select
-			id, pull_id, repo_at, owner_did, rkey,
-			title, body, target_branch, state,
-			source_branch, source_repo_at,
-			stack_id, change_id, parent_change_id,
-			created
-			from pulls;
-		`)
-		if err != nil {
-			return err
-		}
-
-		// drop old table
-		_, err = tx.Exec(`drop table pulls`)
-		if err != nil {
-			return err
-		}
-
-		// rename new table
-		_, err = tx.Exec(`alter table pulls_new rename to pulls`)
-		return err
-	})
-	conn.ExecContext(ctx, "pragma foreign_keys = on;")
-
-	// remove repo_at and pull_id from pull_submissions and replace with pull_at
-	//
-	// this requires a full table recreation because stored columns
-	// cannot be added via alter
-	//
-	// disable foreign-keys for the next migration
-	conn.ExecContext(ctx, "pragma foreign_keys = off;")
-	runMigration(conn, logger, "remove-repo-at-pull-id-from-pull-submissions", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-		create table if not exists pull_submissions_new (
-			-- identifiers
-			id integer primary key autoincrement,
-			pull_at text not null,
-
-			-- content, these are immutable, and require a resubmission to update
-			round_number integer not null default 0,
-			patch text,
-			source_rev text,
-
-			-- meta
-			created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-
-			-- constraints
-			unique(pull_at, round_number),
-			foreign key (pull_at) references pulls(at_uri) on delete cascade
-		);
-		`)
-		if err != nil {
-			return err
-		}
-
-		// transfer data, constructing pull_at from pulls table
-		_, err = tx.Exec(`
-		insert into pull_submissions_new (id, pull_at, round_number, patch, created)
-		select 
-			ps.id,
-			'at://' || p.owner_did || '/sh.tangled.repo.pull/' || p.rkey,
-			ps.round_number,
-			ps.patch,
-			ps.created
-		from pull_submissions ps
-		join pulls p on ps.repo_at = p.repo_at and ps.pull_id = p.pull_id;
-		`)
-		if err != nil {
-			return err
-		}
-
-		// drop old table
-		_, err = tx.Exec(`drop table pull_submissions`)
-		if err != nil {
-			return err
-		}
-
-		// rename new table
-		_, err = tx.Exec(`alter table pull_submissions_new rename to pull_submissions`)
-		return err
-	})
-	conn.ExecContext(ctx, "pragma foreign_keys = on;")
-
-	// knots may report the combined patch for a comparison, we can store that on the appview side
-	// (but not on the pds record), because calculating the combined patch requires a git index
-	runMigration(conn, logger, "add-combined-column-submissions", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table pull_submissions add column combined text;
-		`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-pronouns-profile", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table profile add column pronouns text;
-		`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-meta-column-repos", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table repos add column website text;
-			alter table repos add column topics text;
-		`)
-		return err
-	})
-
-	runMigration(conn, logger, "add-usermentioned-preference", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			alter table notification_preferences add column user_mentioned integer not null default 1;
-		`)
-		return err
-	})
-
-	// remove the foreign key constraints from stars.
-	runMigration(conn, logger, "generalize-stars-subject", func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
-			create table stars_new (
-				id integer primary key autoincrement,
-				did text not null,
-				rkey text not null,
-
-				subject_at text not null,
-
-				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
-				unique(did, rkey),
-				unique(did, subject_at)
-			);
-
-			insert into stars_new (
-				id,
-				did,
-				rkey,
-				subject_at,
-				created
-			)
-			select
-				id,
-				starred_by_did,
-				rkey,
-				repo_at,
-				created
-			from stars;
-
-			drop table stars;
-			alter table stars_new rename to stars;
-
-			create index if not exists idx_stars_created on stars(created);
-			create index if not exists idx_stars_subject_at_created on stars(subject_at, created);
-		`)
-		return err
-	})
-
-	return &DB{
-		db,
-		logger,
-	}, nil
-}
-
-type migrationFn = func(*sql.Tx) error
-
-func runMigration(c *sql.Conn, logger *slog.Logger, name string, migrationFn migrationFn) error {
-	logger = logger.With("migration", name)
-
-	tx, err := c.BeginTx(context.Background(), nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	var exists bool
-	err = tx.QueryRow("select exists (select 1 from migrations where name = ?)", name).Scan(&exists)
-	if err != nil {
-		return err
-	}
-
-	if !exists {
-		// run migration
-		err = migrationFn(tx)
-		if err != nil {
-			logger.Error("failed to run migration", "err", err)
-			return err
-		}
-
-		// mark migration as complete
-		_, err = tx.Exec("insert into migrations (name) values (?)", name)
-		if err != nil {
-			logger.Error("failed to mark migration as complete", "err", err)
-			return err
-		}
-
-		// commit the transaction
-		if err := tx.Commit(); err != nil {
-			return err
-		}
-
-		logger.Info("migration applied successfully")
-	} else {
-		logger.Warn("skipped migration, already applied")
-	}
-
-	return nil
+	return err
 }
 
 func (d *DB) Close() error {
@@ -1238,6 +649,7 @@ func FilterEq(key string, arg any) filter      { return newFilter(key, "=", arg)
 func FilterNotEq(key string, arg any) filter   { return newFilter(key, "<>", arg) }
 func FilterGte(key string, arg any) filter     { return newFilter(key, ">=", arg) }
 func FilterLte(key string, arg any) filter     { return newFilter(key, "<=", arg) }
+func FilterLt(key string, arg any) filter      { return newFilter(key, "<", arg) }
 func FilterIs(key string, arg any) filter      { return newFilter(key, "is", arg) }
 func FilterIsNot(key string, arg any) filter   { return newFilter(key, "is not", arg) }
 func FilterIn(key string, arg any) filter      { return newFilter(key, "in", arg) }
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ec "tangled.org/core/eventconsumer"
+)
+
+// DeadLetterStore keeps the messages that a stream's consumer gave up on.
+type DeadLetterStore struct {
+	Execer
+	Stream string
+}
+
+func (s DeadLetterStore) AddDeadLetter(dl ec.DeadLetter) error {
+	_, err := s.Exec(
+		`insert into dead_letters (stream, source, nsid, rkey, event, error, attempts, created)
+		values (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.Stream,
+		dl.Source,
+		dl.Message.Nsid,
+		dl.Message.Rkey,
+		string(dl.Message.EventJson),
+		dl.Error,
+		dl.Attempts,
+		dl.Created.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func GetDeadLetters(e Execer, filters ...filter) ([]ec.DeadLetter, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, source, nsid, rkey, event, error, attempts, created
+		from dead_letters %s
+		order by id asc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deadLetters []ec.DeadLetter
+	for rows.Next() {
+		var dl ec.DeadLetter
+		var event, created string
+		err := rows.Scan(
+			&dl.Id,
+			&dl.Source,
+			&dl.Message.Nsid,
+			&dl.Message.Rkey,
+			&event,
+			&dl.Error,
+			&dl.Attempts,
+			&created,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		dl.Message.EventJson = []byte(event)
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			dl.Created = t
+		}
+
+		deadLetters = append(deadLetters, dl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deadLetters, nil
+}
+
+func DeleteDeadLetters(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from dead_letters %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
@@ -0,0 +1,262 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddAccountDeletion(e Execer, deletion *models.AccountDeletion) error {
+	result, err := e.Exec(
+		`insert into account_deletions (did, scheduled) values (?, ?)`,
+		deletion.Did,
+		deletion.Scheduled.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add account deletion: %w", err)
+	}
+
+	deletion.Id, err = result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	deletion.Status = models.DeletionScheduled
+	deletion.Step = models.DeletionSteps[0]
+	return nil
+}
+
+// GetAccountDeletions returns account deletions matching filters, newest
+// first.
+func GetAccountDeletions(e Execer, filters ...filter) ([]models.AccountDeletion, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, did, status, step, error, attempts, created, scheduled, finished
+		from account_deletions
+		%s
+		order by created desc, id desc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deletions []models.AccountDeletion
+	for rows.Next() {
+		var deletion models.AccountDeletion
+		var created, scheduled string
+		var finished sql.NullString
+		if err := rows.Scan(
+			&deletion.Id,
+			&deletion.Did,
+			&deletion.Status,
+			&deletion.Step,
+			&deletion.Error,
+			&deletion.Attempts,
+			&created,
+			&scheduled,
+			&finished,
+		); err != nil {
+			return nil, err
+		}
+
+		deletion.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			deletion.Created = t
+		}
+		if t, err := time.Parse(time.RFC3339, scheduled); err == nil {
+			deletion.Scheduled = t
+		}
+		if finished.Valid {
+			if t, err := time.Parse(time.RFC3339, finished.String); err == nil {
+				deletion.Finished = &t
+			}
+		}
+
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions, rows.Err()
+}
+
+// GetPendingAccountDeletion returns the deletion of the account of did that
+// is yet to be carried out, if there is one.
+func GetPendingAccountDeletion(e Execer, did string) (*models.AccountDeletion, error) {
+	deletions, err := GetAccountDeletions(
+		e,
+		FilterEq("did", did),
+		FilterIn("status", []models.DeletionStatus{models.DeletionScheduled, models.DeletionRunning}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(deletions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &deletions[0], nil
+}
+
+// CancelAccountDeletion cancels a deletion that has not started yet, and
+// reports whether there was one to cancel.
+func CancelAccountDeletion(e Execer, id int64) (bool, error) {
+	result, err := e.Exec(
+		`update account_deletions set status = ? where id = ? and status = ?`,
+		models.DeletionCancelled,
+		id,
+		models.DeletionScheduled,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func StartAccountDeletion(e Execer, id int64) error {
+	_, err := e.Exec(
+		`update account_deletions set status = ? where id = ? and status = ?`,
+		models.DeletionRunning,
+		id,
+		models.DeletionScheduled,
+	)
+	return err
+}
+
+// SetAccountDeletionStep records that a deletion is to go on from step.
+func SetAccountDeletionStep(e Execer, id int64, step models.DeletionStep) error {
+	_, err := e.Exec(
+		`update account_deletions set step = ?, error = '' where id = ?`,
+		step,
+		id,
+	)
+	return err
+}
+
+// FailAccountDeletionStep records why the current step of a deletion could
+// not be taken. The step is taken again on the next try.
+func FailAccountDeletionStep(e Execer, id int64, reason string) error {
+	_, err := e.Exec(
+		`update account_deletions set error = ?, attempts = attempts + 1 where id = ?`,
+		reason,
+		id,
+	)
+	return err
+}
+
+func FinishAccountDeletion(e Execer, id int64) error {
+	_, err := e.Exec(
+		`update account_deletions
+		set status = ?, error = '', finished = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		where id = ?`,
+		models.DeletionDone,
+		id,
+	)
+	return err
+}
+
+// AnonymizeAccountContent takes what did wrote out of the threads of
+// others. Their comments on issues are left as deleted placeholders, as
+// when a comment is deleted by hand, so that replies keep their place.
+// Issues that others commented on are closed and kept the same way, with
+// their body removed; other issues are removed. Pulls are closed but kept,
+// since they may have been reviewed or merged, and comments on pulls are
+// removed.
+func AnonymizeAccountContent(e Execer, did string) error {
+	if err := DeleteIssueComments(e, FilterEq("did", did)); err != nil {
+		return fmt.Errorf("failed to anonymize issue comments: %w", err)
+	}
+
+	_, err := e.Exec(
+		`update issues
+		set body = '', open = 0, deleted = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		where did = ?
+		and deleted is null
+		and exists (
+			select 1 from issue_comments c
+			where c.issue_at = issues.at_uri and c.did <> issues.did
+		)`,
+		did,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize issues: %w", err)
+	}
+
+	if err := DeleteIssues(e, FilterEq("did", did), FilterIs("deleted", nil)); err != nil {
+		return fmt.Errorf("failed to remove issues: %w", err)
+	}
+
+	_, err = e.Exec(
+		`update pulls set state = ? where owner_did = ? and state = ?`,
+		models.PullClosed,
+		did,
+		models.PullOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to close pulls: %w", err)
+	}
+
+	if _, err := e.Exec(`delete from pull_comments where owner_did = ?`, did); err != nil {
+		return fmt.Errorf("failed to remove pull comments: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveAccountRows removes what the appview keeps about did that nobody
+// else depends on. Repos and content are dealt with before, see
+// RemoveRepo and AnonymizeAccountContent. What others did, like following
+// did, is theirs and kept, and so are reports, takedowns and the history of
+// threads and labels.
+func RemoveAccountRows(e Execer, did string) error {
+	queries := []string{
+		`delete from public_keys where did = ?`,
+		`delete from emails where did = ?`,
+		`delete from profile where did = ?`,
+		`delete from profile_links where did = ?`,
+		`delete from profile_stats where did = ?`,
+		`delete from profile_pinned_repositories where did = ?`,
+		`delete from punchcard where did = ?`,
+		`delete from punchcard_events where did = ?`,
+		`delete from follows where user_did = ?`,
+		`delete from stars where did = ?`,
+		`delete from reactions where reacted_by_did = ?`,
+		`delete from watches where did = ?`,
+		`delete from strings where did = ?`,
+		`delete from collaborators where subject_did = ?`,
+		`delete from spindle_members where subject = ?`,
+		`delete from notifications where recipient_did = ?`,
+		`delete from notification_preferences where user_did = ?`,
+		`delete from user_settings where did = ?`,
+		`delete from app_passwords where did = ?`,
+		`delete from totp_secrets where did = ?`,
+		`delete from handle_history where did = ?`,
+		`delete from exports where did = ?`,
+	}
+
+	for _, query := range queries {
+		args := make([]any, strings.Count(query, "?"))
+		for i := range args {
+			args[i] = did
+		}
+		if _, err := e.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to remove account rows: %s: %w", query, err)
+		}
+	}
+
+	return nil
+}
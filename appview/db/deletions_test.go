@@ -0,0 +1,104 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestAccountDeletions(t *testing.T) {
+	d := createTestDB(t)
+	did := "did:plc:leaving"
+
+	deletion := &models.AccountDeletion{Did: did, Scheduled: time.Now().Add(time.Hour)}
+	assert.NoError(t, AddAccountDeletion(d, deletion))
+
+	pending, err := GetPendingAccountDeletion(d, did)
+	assert.NoError(t, err)
+	assert.Equal(t, deletion.Id, pending.Id)
+	assert.True(t, pending.IsPending())
+	assert.Equal(t, models.DeletionStepRepos, pending.Step)
+
+	// not due yet
+	due, err := GetAccountDeletions(d, FilterEq("status", models.DeletionScheduled), FilterLte("scheduled", time.Now().UTC().Format(time.RFC3339)))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(due))
+
+	assert.NoError(t, StartAccountDeletion(d, deletion.Id))
+	assert.NoError(t, FailAccountDeletionStep(d, deletion.Id, "knot is down"))
+	assert.NoError(t, SetAccountDeletionStep(d, deletion.Id, models.DeletionStepContent))
+
+	// too late to cancel once it started
+	cancelled, err := CancelAccountDeletion(d, deletion.Id)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+
+	running, err := GetPendingAccountDeletion(d, did)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeletionRunning, running.Status)
+	assert.Equal(t, models.DeletionStepContent, running.Step)
+	assert.Equal(t, "", running.Error)
+	assert.Equal(t, 1, running.Attempts)
+
+	assert.NoError(t, FinishAccountDeletion(d, deletion.Id))
+	_, err = GetPendingAccountDeletion(d, did)
+	assert.Error(t, err)
+
+	assert.NoError(t, AddEmail(d, models.Email{Did: did, Address: "leaving@example.com", Verified: true, Primary: true}))
+	assert.NoError(t, AddExport(d, &models.Export{Did: did}))
+	assert.NoError(t, RemoveAccountRows(d, did))
+	emails, err := GetAllEmails(d, did)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(emails))
+	exports, err := GetExports(d, FilterEq("did", did))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(exports))
+}
+
+func TestAnonymizeAccountContent(t *testing.T) {
+	d := createTestDB(t)
+	did := "did:plc:leaving"
+	other := "did:plc:staying"
+	repo := &models.Repo{Did: other, Name: "project", Knot: "knot.example.com", Rkey: "3lrepo"}
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+	assert.NoError(t, tx.Commit())
+	repoAt := repo.RepoAt().String()
+
+	for _, q := range []string{
+		`insert into issues (did, rkey, repo_at, issue_id, title, body) values ('did:plc:leaving', 'discussed', '` + repoAt + `', 1, 'discussed', 'body')`,
+		`insert into issues (did, rkey, repo_at, issue_id, title, body) values ('did:plc:leaving', 'lonely', '` + repoAt + `', 2, 'lonely', 'body')`,
+		`insert into issue_comments (did, rkey, issue_at, body) values ('did:plc:staying', 'c1', 'at://did:plc:leaving/sh.tangled.repo.issue/discussed', 'reply')`,
+		`insert into issue_comments (did, rkey, issue_at, body) values ('did:plc:leaving', 'c2', 'at://did:plc:leaving/sh.tangled.repo.issue/discussed', 'mine')`,
+	} {
+		_, err = d.Exec(q)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, AnonymizeAccountContent(d, did))
+
+	// a late delete event for the kept issue leaves it be, as the ingester
+	// does
+	assert.NoError(t, DeleteIssues(d, FilterEq("did", did), FilterEq("rkey", "discussed"), FilterIs("deleted", nil)))
+
+	issues, err := GetIssues(d, FilterEq("did", did))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "discussed", issues[0].Rkey)
+	assert.Equal(t, "", issues[0].Body)
+	assert.False(t, issues[0].Open)
+	assert.NotZero(t, issues[0].Deleted)
+
+	mine, err := GetIssueComments(d, FilterEq("did", did))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mine))
+	assert.Equal(t, "", mine[0].Body)
+	assert.NotZero(t, mine[0].Deleted)
+
+	theirs, err := GetIssueComments(d, FilterEq("did", other))
+	assert.NoError(t, err)
+	assert.Equal(t, "reply", theirs[0].Body)
+}
@@ -71,6 +71,9 @@ func GetDidForEmail(e Execer, em string) (string, error) {
 	return did, nil
 }
 
+// GetEmailToDid maps emails, like those of commit authors, to the dids that
+// registered them. Emails are matched case-insensitively, the keys of the
+// result are spelled as they were passed in.
 func GetEmailToDid(e Execer, emails []string, isVerifiedFilter bool) (map[string]string, error) {
 	if len(emails) == 0 {
 		return make(map[string]string), nil
@@ -83,6 +86,9 @@ func GetEmailToDid(e Execer, emails []string, isVerifiedFilter bool) (map[string
 
 	assoc := make(map[string]string)
 
+	// the same address may be spelled differently across commits
+	spellings := make(map[string][]string)
+
 	// Create placeholders for the IN clause
 	placeholders := make([]string, 0, len(emails))
 	args := make([]any, 1, len(emails)+1)
@@ -93,16 +99,25 @@ func GetEmailToDid(e Execer, emails []string, isVerifiedFilter bool) (map[string
 			assoc[email] = email
 			continue
 		}
-		placeholders = append(placeholders, "?")
-		args = append(args, email)
+
+		lower := strings.ToLower(email)
+		if _, ok := spellings[lower]; !ok {
+			placeholders = append(placeholders, "?")
+			args = append(args, lower)
+		}
+		spellings[lower] = append(spellings[lower], email)
+	}
+
+	if len(placeholders) == 0 {
+		return assoc, nil
 	}
 
 	query := `
-		select email, did
+		select lower(email), did
 		from emails
 		where
 			verified = ?
-			and email in (` + strings.Join(placeholders, ",") + `)
+			and lower(email) in (` + strings.Join(placeholders, ",") + `)
 	`
 
 	rows, err := e.Query(query, args...)
@@ -116,7 +131,9 @@ func GetEmailToDid(e Execer, emails []string, isVerifiedFilter bool) (map[string
 		if err := rows.Scan(&email, &did); err != nil {
 			return nil, err
 		}
-		assoc[email] = did
+		for _, spelling := range spellings[email] {
+			assoc[spelling] = did
+		}
 	}
 
 	if err := rows.Err(); err != nil {
@@ -126,6 +143,22 @@ func GetEmailToDid(e Execer, emails []string, isVerifiedFilter bool) (map[string
 	return assoc, nil
 }
 
+// GetVerifiedDidForEmail returns the did that has verified an email, if
+// any. Only one did can have verified a given address.
+func GetVerifiedDidForEmail(e Execer, em string) (string, error) {
+	query := `
+		select did
+		from emails
+		where lower(email) = lower(?) and verified = 1
+	`
+	var did string
+	err := e.QueryRow(query, em).Scan(&did)
+	if err != nil {
+		return "", err
+	}
+	return did, nil
+}
+
 func GetVerificationCodeForEmail(e Execer, did string, email string) (string, error) {
 	query := `
 		select verification_code
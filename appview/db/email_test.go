@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestEmailToDid(t *testing.T) {
+	d := createTestDB(t)
+
+	assert.NoError(t, AddEmail(d, models.Email{Did: "did:plc:alice", Address: "alice@example.com", VerificationCode: "a"}))
+	assert.NoError(t, AddEmail(d, models.Email{Did: "did:plc:alice", Address: "alice@work.example.com", VerificationCode: "b"}))
+	assert.NoError(t, AddEmail(d, models.Email{Did: "did:plc:mallory", Address: "Alice@Work.example.com", VerificationCode: "c"}))
+	assert.NoError(t, MarkEmailVerified(d, "did:plc:alice", "alice@example.com"))
+	assert.NoError(t, MarkEmailVerified(d, "did:plc:alice", "alice@work.example.com"))
+
+	t.Run("every verified email resolves, whatever its spelling", func(t *testing.T) {
+		emailToDid, err := GetEmailToDid(d, []string{"alice@example.com", "ALICE@work.example.com", "alice@work.example.com"}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"alice@example.com":      "did:plc:alice",
+			"ALICE@work.example.com": "did:plc:alice",
+			"alice@work.example.com": "did:plc:alice",
+		}, emailToDid)
+	})
+
+	t.Run("unverified claims don't resolve", func(t *testing.T) {
+		owner, err := GetVerifiedDidForEmail(d, "Alice@Work.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "did:plc:alice", owner)
+	})
+
+	t.Run("an address is verified by one did only", func(t *testing.T) {
+		assert.Error(t, MarkEmailVerified(d, "did:plc:mallory", "Alice@Work.example.com"))
+	})
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddExport(e Execer, export *models.Export) error {
+	result, err := e.Exec(
+		`insert into exports (did, include_repos) values (?, ?)`,
+		export.Did,
+		export.IncludeRepos,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add export: %w", err)
+	}
+
+	export.Id, err = result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	export.Status = models.ExportPending
+	return nil
+}
+
+// GetExports returns exports matching filters, newest first.
+func GetExports(e Execer, filters ...filter) ([]models.Export, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, did, include_repos, status, size, error, created, finished, expires
+		from exports
+		%s
+		order by created desc, id desc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []models.Export
+	for rows.Next() {
+		var export models.Export
+		var includeRepos int
+		var created string
+		var finished, expires sql.NullString
+		if err := rows.Scan(
+			&export.Id,
+			&export.Did,
+			&includeRepos,
+			&export.Status,
+			&export.Size,
+			&export.Error,
+			&created,
+			&finished,
+			&expires,
+		); err != nil {
+			return nil, err
+		}
+
+		export.IncludeRepos = includeRepos != 0
+		export.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			export.Created = t
+		}
+		if finished.Valid {
+			if t, err := time.Parse(time.RFC3339, finished.String); err == nil {
+				export.Finished = &t
+			}
+		}
+		if expires.Valid {
+			if t, err := time.Parse(time.RFC3339, expires.String); err == nil {
+				export.Expires = &t
+			}
+		}
+
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func SetExportRunning(e Execer, id int64) error {
+	_, err := e.Exec(`update exports set status = ? where id = ?`, models.ExportRunning, id)
+	return err
+}
+
+// FinishExport records that the archive of an export was built, and when it
+// is to be removed.
+func FinishExport(e Execer, id int64, size uint64, expires time.Time) error {
+	_, err := e.Exec(
+		`update exports
+		set status = ?, size = ?, finished = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'), expires = ?
+		where id = ?`,
+		models.ExportDone,
+		size,
+		expires.UTC().Format(time.RFC3339),
+		id,
+	)
+	return err
+}
+
+func FailExport(e Execer, id int64, reason string) error {
+	_, err := e.Exec(
+		`update exports
+		set status = ?, error = ?, finished = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		where id = ?`,
+		models.ExportFailed,
+		reason,
+		id,
+	)
+	return err
+}
+
+func ExpireExport(e Execer, id int64) error {
+	_, err := e.Exec(`update exports set status = ? where id = ?`, models.ExportExpired, id)
+	return err
+}
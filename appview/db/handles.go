@@ -0,0 +1,33 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// RecordHandle notes that handle belongs to did, replacing whoever held it
+// before.
+func RecordHandle(e Execer, handle, did string) error {
+	_, err := e.Exec(
+		`insert into handle_history (handle, did)
+		values (?, ?)
+		on conflict(handle) do update set
+			did = excluded.did,
+			seen = excluded.seen`,
+		strings.ToLower(handle),
+		did,
+	)
+	return err
+}
+
+// GetHandleDid returns the did that handle was last seen belonging to, or
+// an empty string if it never was.
+func GetHandleDid(e Execer, handle string) (string, error) {
+	var did string
+	err := e.QueryRow(`select did from handle_history where handle = ?`, strings.ToLower(handle)).Scan(&did)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return did, err
+}
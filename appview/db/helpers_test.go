@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func createTestDB(t *testing.T) *DB {
+	t.Helper()
+	d, err := Make(context.Background(), filepath.Join(t.TempDir(), "appview.db"), Options{})
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// addTestRepo stores repo, on a made up knot and with an rkey made from its
+// name unless it has them. AddRepo stores every repo as created now, so its
+// Created is set afterwards when it isn't zero.
+func addTestRepo(t *testing.T, d *DB, repo *models.Repo) *models.Repo {
+	t.Helper()
+	if repo.Knot == "" {
+		repo.Knot = "knot.example.com"
+	}
+	if repo.Rkey == "" {
+		repo.Rkey = "3l" + repo.Name
+	}
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+	assert.NoError(t, tx.Commit())
+
+	if !repo.Created.IsZero() {
+		_, err = d.Exec(`update repos set created = ? where at_uri = ?`, repo.Created.Format(time.RFC3339), repo.RepoAt().String())
+		assert.NoError(t, err)
+	}
+	return repo
+}
@@ -19,6 +19,7 @@ func AddLabelDefinition(e Execer, l *models.LabelDefinition) (int64, error) {
 			did,
 			rkey,
 			name,
+			description,
 			value_type,
 			value_format,
 			value_enum,
@@ -27,15 +28,17 @@ func AddLabelDefinition(e Execer, l *models.LabelDefinition) (int64, error) {
 			multiple,
 			created
 		)
-		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		on conflict(did, rkey) do update set
 			name = excluded.name,
+			description = excluded.description,
 			scope = excluded.scope,
 			color = excluded.color,
 			multiple = excluded.multiple`,
 		l.Did,
 		l.Rkey,
 		l.Name,
+		l.Description,
 		l.ValueType.Type,
 		l.ValueType.Format,
 		strings.Join(l.ValueType.Enum, ","),
@@ -97,6 +100,7 @@ func GetLabelDefinitions(e Execer, filters ...filter) ([]models.LabelDefinition,
 			did,
 			rkey,
 			name,
+			description,
 			value_type,
 			value_format,
 			value_enum,
@@ -120,7 +124,7 @@ func GetLabelDefinitions(e Execer, filters ...filter) ([]models.LabelDefinition,
 	for rows.Next() {
 		var labelDefinition models.LabelDefinition
 		var createdAt, enumVariants, scopes string
-		var color sql.Null[string]
+		var description, color sql.Null[string]
 		var multiple int
 
 		if err := rows.Scan(
@@ -128,6 +132,7 @@ func GetLabelDefinitions(e Execer, filters ...filter) ([]models.LabelDefinition,
 			&labelDefinition.Did,
 			&labelDefinition.Rkey,
 			&labelDefinition.Name,
+			&description,
 			&labelDefinition.ValueType.Type,
 			&labelDefinition.ValueType.Format,
 			&enumVariants,
@@ -144,6 +149,10 @@ func GetLabelDefinitions(e Execer, filters ...filter) ([]models.LabelDefinition,
 			labelDefinition.Created = time.Now()
 		}
 
+		if description.Valid {
+			labelDefinition.Description = &description.V
+		}
+
 		if color.Valid {
 			labelDefinition.Color = &color.V
 		}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+// AddMailThread records that the message with messageId belongs to the
+// given pull. Recording a message twice is not an error.
+func AddMailThread(e Execer, messageId string, repoAt syntax.ATURI, pullId int) error {
+	_, err := e.Exec(
+		`insert or ignore into mail_threads (message_id, repo_at, pull_id) values (?, ?, ?)`,
+		messageId,
+		repoAt,
+		pullId,
+	)
+	return err
+}
+
+// GetMailThread returns the pull that the first known message among
+// messageIds belongs to.
+func GetMailThread(e Execer, messageIds []string) (syntax.ATURI, int, error) {
+	var repoAt syntax.ATURI
+	var pullId int
+	for _, id := range messageIds {
+		err := e.QueryRow(
+			`select repo_at, pull_id from mail_threads where message_id = ?`,
+			id,
+		).Scan(&repoAt, &pullId)
+		if err == nil {
+			return repoAt, pullId, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", 0, err
+		}
+	}
+	return "", 0, sql.ErrNoRows
+}
+
+// AddMailPatchPart keeps a part of a patch series until the rest of it
+// arrives. A part sent again replaces the earlier one.
+func AddMailPatchPart(e Execer, part models.MailPatchPart) error {
+	_, err := e.Exec(
+		`insert or replace into mail_patch_parts (
+			series_id, part, total, did, repo_at, branch, message_id, subject, body
+		)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		part.SeriesId,
+		part.Part,
+		part.Total,
+		part.Did,
+		part.RepoAt,
+		part.Branch,
+		part.MessageId,
+		part.Subject,
+		part.Body,
+	)
+	return err
+}
+
+// GetMailPatchParts returns the parts of a series that have arrived so far,
+// in order.
+func GetMailPatchParts(e Execer, seriesId string) ([]models.MailPatchPart, error) {
+	rows, err := e.Query(
+		`select series_id, part, total, did, repo_at, branch, message_id, subject, body, created
+		from mail_patch_parts
+		where series_id = ?
+		order by part asc`,
+		seriesId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []models.MailPatchPart
+	for rows.Next() {
+		var part models.MailPatchPart
+		var created string
+		if err := rows.Scan(
+			&part.SeriesId,
+			&part.Part,
+			&part.Total,
+			&part.Did,
+			&part.RepoAt,
+			&part.Branch,
+			&part.MessageId,
+			&part.Subject,
+			&part.Body,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			part.Created = t
+		}
+		parts = append(parts, part)
+	}
+
+	return parts, rows.Err()
+}
+
+func DeleteMailPatchParts(e Execer, seriesId string) error {
+	_, err := e.Exec(`delete from mail_patch_parts where series_id = ?`, seriesId)
+	return err
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// GetMergeCheck returns the cached merge check of a pull, ok is false if
+// there is none for this target branch and patch, or it is older than
+// maxAge.
+func GetMergeCheck(e Execer, pullAt syntax.ATURI, targetBranch, patchHash string, maxAge time.Duration) (data []byte, ok bool, err error) {
+	var created string
+	err = e.QueryRow(
+		`select data, created from pull_merge_checks
+		where pull_at = ? and target_branch = ? and patch_hash = ?`,
+		pullAt,
+		targetBranch,
+		patchHash,
+	).Scan(&data, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get merge check: %w", err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil || time.Since(createdAt) > maxAge {
+		return nil, false, nil
+	}
+
+	return data, true, nil
+}
+
+// PutMergeCheck caches the merge check of a pull, replacing the one for any
+// previous target branch or patch.
+func PutMergeCheck(e Execer, pullAt, repoAt syntax.ATURI, targetBranch, patchHash string, data []byte) error {
+	_, err := e.Exec(
+		`insert or replace into pull_merge_checks (pull_at, repo_at, target_branch, patch_hash, data)
+		values (?, ?, ?, ?, ?)`,
+		pullAt,
+		repoAt,
+		targetBranch,
+		patchHash,
+		data,
+	)
+	return err
+}
+
+func DeleteMergeChecks(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from pull_merge_checks %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Migration changes the schema of the database, see migrations.
+type Migration struct {
+	Name string
+	Up   migrationFn
+	// Down undoes Up, for the migrations that can be undone
+	Down migrationFn
+	// NoForeignKeys turns foreign keys off while the migration runs, for
+	// migrations that recreate tables other tables refer to
+	NoForeignKeys bool
+}
+
+type migrationFn = func(*sql.Tx) error
+
+// MigrationStatus tells whether the migration of a version was applied to
+// the database, and when.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied *time.Time
+}
+
+var ErrNoMigration = errors.New("no migration to undo")
+
+// Migrate creates the tables of a new database, then applies every migration
+// that was not applied to it yet, in order.
+func (d *DB) Migrate(ctx context.Context) error {
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := createTables(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for i, m := range migrations {
+		version := i + 1
+		if _, ok := applied[version]; ok {
+			continue
+		}
+
+		if err := runMigration(ctx, conn, version, m, true); err != nil {
+			d.logger.Error("failed to run migration", "version", version, "migration", m.Name, "err", err)
+			return err
+		}
+		d.logger.Info("migration applied successfully", "version", version, "migration", m.Name)
+	}
+
+	return nil
+}
+
+// Migrations lists every migration along with whether it was applied.
+func (d *DB) Migrations(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: i + 1, Name: m.Name}
+		if t, ok := applied[i+1]; ok {
+			statuses[i].Applied = &t
+		}
+	}
+
+	return statuses, nil
+}
+
+// UndoMigration undoes the latest migration that was applied, and returns
+// its status from before it was undone.
+func (d *DB) UndoMigration(ctx context.Context) (MigrationStatus, error) {
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	defer conn.Close()
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	version := 0
+	for v := range applied {
+		version = max(version, v)
+	}
+	if version == 0 {
+		return MigrationStatus{}, ErrNoMigration
+	}
+
+	m := migrations[version-1]
+	if m.Down == nil {
+		return MigrationStatus{}, fmt.Errorf("migration %d (%s) cannot be undone", version, m.Name)
+	}
+
+	if err := runMigration(ctx, conn, version, m, false); err != nil {
+		return MigrationStatus{}, err
+	}
+
+	t := applied[version]
+	d.logger.Info("migration undone successfully", "version", version, "migration", m.Name)
+	return MigrationStatus{Version: version, Name: m.Name, Applied: &t}, nil
+}
+
+// runMigration applies m, or undoes it if up is false, and records that it
+// did in a single transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, version int, m Migration, up bool) error {
+	// foreign keys can't be turned off within a transaction
+	if m.NoForeignKeys {
+		if _, err := conn.ExecContext(ctx, "pragma foreign_keys = off;"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "pragma foreign_keys = on;")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	fn := m.Up
+	if !up {
+		fn = m.Down
+	}
+	if err := fn(tx); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", version, m.Name, err)
+	}
+
+	if up {
+		_, err = tx.Exec("insert into schema_migrations (version, name) values (?, ?)", version, m.Name)
+	} else {
+		_, err = tx.Exec("delete from schema_migrations where version = ?", version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// appliedMigrations returns when each of the applied migrations was applied,
+// by version. It fails if the database knows a migration under a different
+// name, or one this build does not know at all.
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	_, err := conn.ExecContext(ctx, `
+		create table if not exists schema_migrations (
+			version integer primary key,
+			name text not null unique,
+			applied text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+		);
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := adoptLegacyMigrations(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to adopt legacy migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "select version, name, applied from schema_migrations order by version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var name, appliedAt string
+		if err := rows.Scan(&version, &name, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		if version < 1 || version > len(migrations) {
+			return nil, fmt.Errorf("database has migration %d (%s), which is newer than this build", version, name)
+		}
+		if want := migrations[version-1].Name; name != want {
+			return nil, fmt.Errorf("database has migration %d as %s, expected %s", version, name, want)
+		}
+
+		t, err := time.Parse(time.RFC3339, appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = t
+	}
+
+	return applied, rows.Err()
+}
+
+// adoptLegacyMigrations records the versions of the migrations applied to
+// databases from before migrations had versions, which only kept their names
+// in the migrations table.
+func adoptLegacyMigrations(ctx context.Context, conn *sql.Conn) error {
+	var legacy, adopted bool
+	err := conn.QueryRowContext(ctx, `
+		select
+			exists (select 1 from sqlite_master where type = 'table' and name = 'migrations'),
+			exists (select 1 from schema_migrations)
+	`).Scan(&legacy, &adopted)
+	if err != nil || !legacy || adopted {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, m := range migrations {
+		_, err := tx.Exec(`
+			insert into schema_migrations (version, name)
+			select ?, name from migrations where name = ?
+		`, i+1, m.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	d := createTestDB(t)
+
+	hasColumn := func(table, name string) bool {
+		var exists bool
+		err := d.QueryRow("select exists (select 1 from pragma_table_info(?) where name = ?)", table, name).Scan(&exists)
+		assert.NoError(t, err)
+		return exists
+	}
+
+	statuses, err := d.Migrations(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), len(statuses))
+	for i, s := range statuses {
+		assert.Equal(t, i+1, s.Version)
+		assert.NotZero(t, s.Applied, "%s was not applied", s.Name)
+	}
+
+	// the latest migration can be undone, and is applied again
+	latest := statuses[len(statuses)-1]
+	undone, err := d.UndoMigration(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, latest.Name, undone.Name)
+	assert.False(t, hasColumn("app_passwords", "knots"))
+
+	statuses, err = d.Migrations(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, statuses[len(statuses)-1].Applied)
+
+	assert.NoError(t, d.Migrate(ctx))
+	assert.True(t, hasColumn("app_passwords", "knots"))
+
+	// applying twice changes nothing
+	assert.NoError(t, d.Migrate(ctx))
+}
+
+func TestMigrations_LegacyDatabase(t *testing.T) {
+	ctx := context.Background()
+	d := createTestDB(t)
+
+	// databases from before versions only know migrations by name
+	_, err := d.Exec(`
+		delete from schema_migrations;
+		create table migrations (id integer primary key autoincrement, name text unique);
+	`)
+	assert.NoError(t, err)
+	for _, m := range migrations {
+		_, err := d.Exec("insert into migrations (name) values (?)", m.Name)
+		assert.NoError(t, err)
+	}
+
+	// would fail if any migration ran again
+	assert.NoError(t, d.Migrate(ctx))
+
+	statuses, err := d.Migrations(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.NotZero(t, s.Applied, "%s was not adopted", s.Name)
+	}
+}
@@ -0,0 +1,1508 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"tangled.org/core/crypto"
+)
+
+// migrations change the tables created by createTables into the ones the
+// appview uses today. They are applied in order, and their position in this
+// list, counting from 1, is their version: only ever add migrations to its
+// end.
+var migrations = []Migration{
+	{
+		Name: "add-description-to-repos",
+		Up: func(tx *sql.Tx) error {
+			tx.Exec(`
+				alter table repos add column description text check (length(description) <= 200);
+			`)
+			return nil
+		},
+	},
+
+	{
+		Name: "add-rkey-to-pubkeys",
+		Up: func(tx *sql.Tx) error {
+			// add unconstrained column
+			_, err := tx.Exec(`
+				alter table public_keys
+				add column rkey text;
+			`)
+			if err != nil {
+				return err
+			}
+
+			// backfill
+			_, err = tx.Exec(`
+				update public_keys
+				set rkey = ''
+				where rkey is null;
+			`)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	},
+
+	{
+		Name: "add-rkey-to-comments",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table comments drop column comment_at;
+				alter table comments add column rkey text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-deleted-and-edited-to-issue-comments",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table comments add column deleted text; -- timestamp
+				alter table comments add column edited text; -- timestamp
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-source-info-to-pulls-and-submissions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table pulls add column source_branch text;
+				alter table pulls add column source_repo_at text;
+				alter table pull_submissions add column source_rev text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-source-to-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column source text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name:          "recreate-pulls-column-for-stacking-support",
+		NoForeignKeys: true,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table pulls_new (
+					-- identifiers
+					id integer primary key autoincrement,
+					pull_id integer not null,
+
+					-- at identifiers
+					repo_at text not null,
+					owner_did text not null,
+					rkey text not null,
+
+					-- content
+					title text not null,
+					body text not null,
+					target_branch text not null,
+					state integer not null default 0 check (state in (0, 1, 2, 3)), -- closed, open, merged, deleted
+
+					-- source info
+					source_branch text,
+					source_repo_at text,
+
+					-- stacking
+					stack_id text,
+					change_id text,
+					parent_change_id text,
+
+					-- meta
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					-- constraints
+					unique(repo_at, pull_id),
+					foreign key (repo_at) references repos(at_uri) on delete cascade
+				);
+
+				insert into pulls_new (
+					id, pull_id,
+					repo_at, owner_did, rkey,
+					title, body, target_branch, state,
+					source_branch, source_repo_at,
+					created
+				)
+				select
+					id, pull_id,
+					repo_at, owner_did, rkey,
+					title, body, target_branch, state,
+					source_branch, source_repo_at,
+					created
+				FROM pulls;
+
+				drop table pulls;
+				alter table pulls_new rename to pulls;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-spindle-to-repos",
+		Up: func(tx *sql.Tx) error {
+			tx.Exec(`
+				alter table repos add column spindle text;
+			`)
+			return nil
+		},
+	},
+
+	// drop all knot secrets, add unique constraint to knots
+	//
+	// knots will henceforth use service auth for signed requests
+	{
+		Name: "no-more-secrets",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table registrations_new (
+					id integer primary key autoincrement,
+					domain text not null,
+					did text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					registered text,
+					read_only integer not null default 0,
+					unique(domain, did)
+				);
+
+				insert into registrations_new (id, domain, did, created, registered, read_only)
+				select id, domain, did, created, registered, 1 from registrations
+				where registered is not null;
+
+				drop table registrations;
+				alter table registrations_new rename to registrations;
+			`)
+			return err
+		},
+	},
+
+	// recreate and add rkey + created columns with default constraint
+	{
+		Name: "rework-collaborators-table",
+		Up: func(tx *sql.Tx) error {
+			// create new table
+			// - repo_at instead of repo integer
+			// - rkey field
+			// - created field
+			_, err := tx.Exec(`
+				create table collaborators_new (
+					-- identifiers for the record
+					id integer primary key autoincrement,
+					did text not null,
+					rkey text,
+
+					-- content
+					subject_did text not null,
+					repo_at text not null,
+
+					-- meta
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					-- constraints
+					foreign key (repo_at) references repos(at_uri) on delete cascade
+				)
+			`)
+			if err != nil {
+				return err
+			}
+
+			// copy data
+			_, err = tx.Exec(`
+				insert into collaborators_new (id, did, rkey, subject_did, repo_at)
+				select
+					c.id,
+					r.did,
+					'',
+					c.did,
+					r.at_uri
+				from collaborators c
+				join repos r on c.repo = r.id
+			`)
+			if err != nil {
+				return err
+			}
+
+			// drop old table
+			_, err = tx.Exec(`drop table collaborators`)
+			if err != nil {
+				return err
+			}
+
+			// rename new table
+			_, err = tx.Exec(`alter table collaborators_new rename to collaborators`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-rkey-to-issues",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table issues add column rkey text not null default '';
+
+				-- get last url section from issue_at and save to rkey column
+				update issues
+				set rkey = replace(issue_at, rtrim(issue_at, replace(issue_at, '/', '')), '');
+			`)
+			return err
+		},
+	},
+
+	// repurpose the read-only column to "needs-upgrade"
+	{
+		Name: "rename-registrations-read-only-to-needs-upgrade",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table registrations rename column read_only to needs_upgrade;
+			`)
+			return err
+		},
+	},
+
+	// require all knots to upgrade after the release of total xrpc
+	{
+		Name: "migrate-knots-to-total-xrpc",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				update registrations set needs_upgrade = 1;
+			`)
+			return err
+		},
+	},
+
+	// require all knots to upgrade after the release of total xrpc
+	{
+		Name: "migrate-spindles-to-xrpc-owner",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table spindles add column needs_upgrade integer not null default 0;
+			`)
+			return err
+		},
+	},
+
+	// remove issue_at from issues and replace with generated column
+	//
+	// this requires a full table recreation because stored columns
+	// cannot be added via alter
+	//
+	// couple other changes:
+	// - columns renamed to be more consistent
+	// - adds edited and deleted fields
+	{
+		Name:          "remove-issue-at-from-issues",
+		NoForeignKeys: true,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists issues_new (
+					-- identifiers
+					id integer primary key autoincrement,
+					did text not null,
+					rkey text not null,
+					at_uri text generated always as ('at://' || did || '/' || 'sh.tangled.repo.issue' || '/' || rkey) stored,
+
+					-- at identifiers
+					repo_at text not null,
+
+					-- content
+					issue_id integer not null,
+					title text not null,
+					body text not null,
+					open integer not null default 1,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					edited text,  -- timestamp
+					deleted text,  -- timestamp
+
+					unique(did, rkey),
+					unique(repo_at, issue_id),
+					unique(at_uri),
+					foreign key (repo_at) references repos(at_uri) on delete cascade
+				);
+			`)
+			if err != nil {
+				return err
+			}
+
+			// transfer data
+			_, err = tx.Exec(`
+				insert into issues_new (id, did, rkey, repo_at, issue_id, title, body, open, created)
+				select
+					i.id,
+					i.owner_did,
+					i.rkey,
+					i.repo_at,
+					i.issue_id,
+					i.title,
+					i.body,
+					i.open,
+					i.created
+				from issues i;
+			`)
+			if err != nil {
+				return err
+			}
+
+			// drop old table
+			_, err = tx.Exec(`drop table issues`)
+			if err != nil {
+				return err
+			}
+
+			// rename new table
+			_, err = tx.Exec(`alter table issues_new rename to issues`)
+			return err
+		},
+	},
+
+	// - renames the comments table to 'issue_comments'
+	// - rework issue comments to update constraints:
+	//   * unique(did, rkey)
+	//   * remove comment-id and just use the global ID
+	//   * foreign key (repo_at, issue_id)
+	// - new columns
+	//   * column "reply_to" which can be any other comment
+	//   * column "at-uri" which is a generated column
+	{
+		Name: "rework-issue-comments",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists issue_comments (
+					-- identifiers
+					id integer primary key autoincrement,
+					did text not null,
+					rkey text,
+					at_uri text generated always as ('at://' || did || '/' || 'sh.tangled.repo.issue.comment' || '/' || rkey) stored,
+
+					-- at identifiers
+					issue_at text not null,
+					reply_to text, -- at_uri of parent comment
+
+					-- content
+					body text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					edited text,
+					deleted text,
+
+					-- constraints
+					unique(did, rkey),
+					unique(at_uri),
+					foreign key (issue_at) references issues(at_uri) on delete cascade
+				);
+			`)
+			if err != nil {
+				return err
+			}
+
+			// transfer data
+			_, err = tx.Exec(`
+				insert into issue_comments (id, did, rkey, issue_at, body, created, edited, deleted)
+				select
+					c.id,
+					c.owner_did,
+					c.rkey,
+					i.at_uri,  -- get at_uri from issues table
+					c.body,
+					c.created,
+					c.edited,
+					c.deleted
+				from comments c
+				join issues i on c.repo_at = i.repo_at and c.issue_id = i.issue_id;
+			`)
+			if err != nil {
+				return err
+			}
+
+			// drop old table
+			_, err = tx.Exec(`drop table comments`)
+			return err
+		},
+	},
+
+	// add generated at_uri column to pulls table
+	//
+	// this requires a full table recreation because stored columns
+	// cannot be added via alter
+	{
+		Name:          "add-at-uri-to-pulls",
+		NoForeignKeys: true,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			create table if not exists pulls_new (
+				-- identifiers
+				id integer primary key autoincrement,
+				pull_id integer not null,
+				at_uri text generated always as ('at://' || owner_did || '/' || 'sh.tangled.repo.pull' || '/' || rkey) stored,
+
+				-- at identifiers
+				repo_at text not null,
+				owner_did text not null,
+				rkey text not null,
+
+				-- content
+				title text not null,
+				body text not null,
+				target_branch text not null,
+				state integer not null default 0 check (state in (0, 1, 2, 3)), -- closed, open, merged, deleted
+
+				-- source info
+				source_branch text,
+				source_repo_at text,
+
+				-- stacking
+				stack_id text,
+				change_id text,
+				parent_change_id text,
+
+				-- meta
+				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+				-- constraints
+				unique(repo_at, pull_id),
+				unique(at_uri),
+				foreign key (repo_at) references repos(at_uri) on delete cascade
+			);
+			`)
+			if err != nil {
+				return err
+			}
+
+			// transfer data
+			_, err = tx.Exec(`
+			insert into pulls_new (
+				id, pull_id, repo_at, owner_did, rkey,
+				title, body, target_branch, state,
+				source_branch, source_repo_at,
+				stack_id, change_id, parent_change_id,
+				created
+			)
+			select
+				id, pull_id, repo_at, owner_did, rkey,
+				title, body, target_branch, state,
+				source_branch, source_repo_at,
+				stack_id, change_id, parent_change_id,
+				created
+				from pulls;
+			`)
+			if err != nil {
+				return err
+			}
+
+			// drop old table
+			_, err = tx.Exec(`drop table pulls`)
+			if err != nil {
+				return err
+			}
+
+			// rename new table
+			_, err = tx.Exec(`alter table pulls_new rename to pulls`)
+			return err
+		},
+	},
+
+	// remove repo_at and pull_id from pull_submissions and replace with pull_at
+	//
+	// this requires a full table recreation because stored columns
+	// cannot be added via alter
+	{
+		Name:          "remove-repo-at-pull-id-from-pull-submissions",
+		NoForeignKeys: true,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			create table if not exists pull_submissions_new (
+				-- identifiers
+				id integer primary key autoincrement,
+				pull_at text not null,
+
+				-- content, these are immutable, and require a resubmission to update
+				round_number integer not null default 0,
+				patch text,
+				source_rev text,
+
+				-- meta
+				created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+				-- constraints
+				unique(pull_at, round_number),
+				foreign key (pull_at) references pulls(at_uri) on delete cascade
+			);
+			`)
+			if err != nil {
+				return err
+			}
+
+			// transfer data, constructing pull_at from pulls table
+			_, err = tx.Exec(`
+			insert into pull_submissions_new (id, pull_at, round_number, patch, created)
+			select 
+				ps.id,
+				'at://' || p.owner_did || '/sh.tangled.repo.pull/' || p.rkey,
+				ps.round_number,
+				ps.patch,
+				ps.created
+			from pull_submissions ps
+			join pulls p on ps.repo_at = p.repo_at and ps.pull_id = p.pull_id;
+			`)
+			if err != nil {
+				return err
+			}
+
+			// drop old table
+			_, err = tx.Exec(`drop table pull_submissions`)
+			if err != nil {
+				return err
+			}
+
+			// rename new table
+			_, err = tx.Exec(`alter table pull_submissions_new rename to pull_submissions`)
+			return err
+		},
+	},
+
+	// knots may report the combined patch for a comparison, we can store that on the appview side
+	// (but not on the pds record), because calculating the combined patch requires a git index
+	{
+		Name: "add-combined-column-submissions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table pull_submissions add column combined text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-pronouns-profile",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table profile add column pronouns text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-meta-column-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column website text;
+				alter table repos add column topics text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-usermentioned-preference",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table notification_preferences add column user_mentioned integer not null default 1;
+			`)
+			return err
+		},
+	},
+
+	// remove the foreign key constraints from stars.
+	{
+		Name: "generalize-stars-subject",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table stars_new (
+					id integer primary key autoincrement,
+					did text not null,
+					rkey text not null,
+
+					subject_at text not null,
+
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					unique(did, rkey),
+					unique(did, subject_at)
+				);
+
+				insert into stars_new (
+					id,
+					did,
+					rkey,
+					subject_at,
+					created
+				)
+				select
+					id,
+					starred_by_did,
+					rkey,
+					repo_at,
+					created
+				from stars;
+
+				drop table stars;
+				alter table stars_new rename to stars;
+
+				create index if not exists idx_stars_created on stars(created);
+				create index if not exists idx_stars_subject_at_created on stars(subject_at, created);
+			`)
+			return err
+		},
+	},
+
+	// reactions are looked up by subject for threads and all of their comments
+	{
+		Name: "add-reactions-thread-at-index",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create index if not exists idx_reactions_thread_at_kind on reactions(thread_at, kind);
+			`)
+			return err
+		},
+	},
+
+	// pipelines that were run again point back to the run they were started from
+	{
+		Name: "add-rerun-of-pipelines",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table pipelines add column rerun_of integer references pipelines(id) on delete set null;
+			`)
+			return err
+		},
+	},
+
+	// files kept from successful workflows, served by the spindle that ran them
+	{
+		Name: "add-pipeline-artifacts-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists pipeline_artifacts (
+					id integer primary key autoincrement,
+					spindle text not null,
+
+					-- referenced pipeline. these form the (did, rkey) pair
+					pipeline_knot text not null,
+					pipeline_rkey text not null,
+
+					workflow text not null,
+					name text not null,
+					size integer not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					unique (pipeline_knot, pipeline_rkey, workflow, name),
+					foreign key (pipeline_knot, pipeline_rkey)
+						references pipelines (knot, rkey)
+						on delete cascade
+				);
+			`)
+			return err
+		},
+	},
+
+	// branches and tags as last listed by the knot, dropped whenever the
+	// knot reports a ref update
+	{
+		Name: "add-repo-ref-cache-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists repo_ref_cache (
+					repo_at text not null,
+					kind text not null check (kind in ('branches', 'tags')),
+					data text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					primary key (repo_at, kind)
+				);
+			`)
+			return err
+		},
+	},
+
+	// event stream messages that could not be processed, kept for
+	// inspection and replay
+	{
+		Name: "add-dead-letters-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists dead_letters (
+					id integer primary key autoincrement,
+					stream text not null,
+					source text not null,
+					nsid text not null,
+					rkey text not null,
+					event text not null,
+					error text not null,
+					attempts integer not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+			`)
+			return err
+		},
+	},
+
+	// keys are revoked instead of deleted so that signatures made with them
+	// can still be recognised, expiry comes from the key's expiry-time option
+	{
+		Name: "add-validity-to-pubkeys",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table public_keys add column expires text;
+				alter table public_keys add column revoked text;
+			`)
+			if err != nil {
+				return err
+			}
+
+			// backfill
+			rows, err := tx.Query(`select id, key from public_keys`)
+			if err != nil {
+				return err
+			}
+			expiries := make(map[int64]string)
+			for rows.Next() {
+				var id int64
+				var key string
+				if err := rows.Scan(&id, &key); err != nil {
+					rows.Close()
+					return err
+				}
+				if t, _ := crypto.SSHKeyExpiry(key); t != nil {
+					expiries[id] = t.Format(time.RFC3339)
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for id, expires := range expiries {
+				if _, err := tx.Exec(`update public_keys set expires = ? where id = ?`, expires, id); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+
+	// an address can be verified by one did only, otherwise commits by it
+	// would be attributed to whichever did the query happened to return
+	{
+		Name: "add-verified-emails-unique-index",
+		Up: func(tx *sql.Tx) error {
+			// the earliest verification wins, later ones have to verify again
+			_, err := tx.Exec(`
+				update emails
+				set verified = 0
+				where verified = 1 and id not in (
+					select min(id) from emails where verified = 1 group by lower(email)
+				);
+			`)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(`
+				create unique index if not exists idx_emails_verified_email
+				on emails (lower(email)) where verified = 1;
+			`)
+			return err
+		},
+	},
+
+	// collaborators were all given the same role before roles existed
+	{
+		Name: "add-role-to-collaborators",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table collaborators add column role text not null default 'collaborator';
+			`)
+			return err
+		},
+	},
+
+	// repos offered to another user by their owner, awaiting an answer
+	{
+		Name: "add-repo-transfers-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists repo_transfers (
+					id integer primary key autoincrement,
+					repo_at text not null unique,
+					from_did text not null,
+					to_did text not null,
+					status text not null default 'pending' check (status in ('pending', 'declined')),
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					foreign key (repo_at) references repos(at_uri) on delete cascade
+				);
+			`)
+			return err
+		},
+	},
+
+	// private repos are hidden from everyone but their owner and collaborators
+	{
+		Name: "add-visibility-to-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column visibility text not null default 'public' check (visibility in ('public', 'private'));
+			`)
+			return err
+		},
+	},
+
+	// tokens for git over HTTPS and knot api calls, only their hashes are kept
+	{
+		Name: "add-app-passwords-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists app_passwords (
+					id integer primary key autoincrement,
+					did text not null,
+					name text not null,
+					password_hash text not null unique,
+					scopes text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					last_used text,
+
+					unique(did, name)
+				);
+			`)
+			return err
+		},
+	},
+
+	// authenticator apps, asked for before destructive actions
+	{
+		Name: "add-totp-secrets-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists totp_secrets (
+					did text primary key,
+					secret text not null,
+					confirmed integer not null default 0,
+					last_step integer not null default 0,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+			`)
+			return err
+		},
+	},
+
+	// watchers hear about every new issue and pull in a repo
+	{
+		Name: "add-watches-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists watches (
+					id integer primary key autoincrement,
+					did text not null,
+					rkey text not null,
+
+					subject_at text not null,
+
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					unique(did, rkey),
+					unique(did, subject_at)
+				);
+
+				create index if not exists idx_watches_subject_at on watches(subject_at);
+			`)
+			return err
+		},
+	},
+
+	// a tag has at most one release, its artifacts stay keyed by tag hash
+	{
+		Name: "add-releases-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists releases (
+					id integer primary key autoincrement,
+					did text not null,
+					rkey text not null,
+					repo_at text not null,
+					tag text not null,
+					title text not null,
+					body text not null default '',
+					prerelease integer not null default 0,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					edited text,
+
+					unique(did, rkey),
+					unique(repo_at, tag),
+					foreign key (repo_at) references repos(at_uri) on delete cascade
+				);
+			`)
+			return err
+		},
+	},
+
+	// contributor statistics of the default branch are cached alongside
+	// refs, sqlite can't alter the check so the cache is rebuilt
+	{
+		Name: "add-contributors-ref-cache-kind",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				drop table if exists repo_ref_cache;
+
+				create table repo_ref_cache (
+					repo_at text not null,
+					kind text not null check (kind in ('branches', 'tags', 'contributors')),
+					data text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					primary key (repo_at, kind)
+				);
+			`)
+			return err
+		},
+	},
+
+	// records that count towards a punchcard, keyed by the record so that
+	// seeing one again does not count it twice
+	{
+		Name: "add-punchcard-events-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists punchcard_events (
+					subject_at text primary key,
+					did text not null,
+					kind text not null check (kind in ('issue', 'issue_comment', 'pull', 'pull_comment')),
+					date text not null -- yyyy-mm-dd, in UTC
+				);
+
+				create index if not exists idx_punchcard_events_did_date on punchcard_events(did, date);
+
+				insert or ignore into punchcard_events (subject_at, did, kind, date)
+				select at_uri, did, 'issue', date(created) from issues;
+
+				insert or ignore into punchcard_events (subject_at, did, kind, date)
+				select at_uri, did, 'issue_comment', date(created) from issue_comments;
+
+				insert or ignore into punchcard_events (subject_at, did, kind, date)
+				select 'at://' || owner_did || '/sh.tangled.repo.pull/' || rkey, owner_did, 'pull', date(created) from pulls;
+
+				insert or ignore into punchcard_events (subject_at, did, kind, date)
+				select comment_at, owner_did, 'pull_comment', date(created) from pull_comments;
+			`)
+			return err
+		},
+	},
+
+	// handles as seen on the event stream, so that links using a handle
+	// someone has since changed away from can be redirected
+	{
+		Name: "add-handle-history-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists handle_history (
+					handle text primary key,
+					did text not null,
+					seen text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+			`)
+			return err
+		},
+	},
+
+	// merge checks are cached per pull, for the patch and target branch they
+	// were made against
+	{
+		Name: "add-pull-merge-checks-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists pull_merge_checks (
+					pull_at text primary key,
+					repo_at text not null,
+					target_branch text not null,
+					patch_hash text not null,
+					data text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+				create index if not exists idx_pull_merge_checks_target on pull_merge_checks(repo_at, target_branch);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-attachments-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists attachments (
+					id integer primary key autoincrement,
+					did text not null,
+					hash text not null,
+					name text not null,
+					mime_type text not null,
+					size integer not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					used integer not null default 0,
+
+					unique (did, hash)
+				);
+				create index if not exists idx_attachments_hash on attachments(hash);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-commit-statuses-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists commit_statuses (
+					id integer primary key autoincrement,
+					repo_at text not null,
+					sha text not null,
+					context text not null,
+					state text not null check (state in ('pending', 'success', 'failure', 'error')),
+					target_url text not null default '',
+					description text not null default '',
+					did text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					updated text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					unique (repo_at, sha, context)
+				);
+				create index if not exists idx_commit_statuses_sha on commit_statuses(repo_at, sha);
+
+				create table if not exists required_checks (
+					id integer primary key autoincrement,
+					repo_at text not null,
+					branch text not null,
+					context text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					unique (repo_at, branch, context)
+				);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-webhooks-tables",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists webhooks (
+					id integer primary key autoincrement,
+					repo_at text not null,
+					url text not null,
+					secret text not null default '',
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					foreign key (repo_at) references repos(at_uri) on delete cascade
+				);
+
+				create table if not exists webhook_deliveries (
+					id integer primary key autoincrement,
+					webhook_id integer not null,
+					event text not null,
+					guid text not null,
+					request_headers text not null default '',
+					payload text not null,
+					response_status integer not null default 0,
+					response_headers text not null default '',
+					response_body text not null default '',
+					error text not null default '',
+					duration_ms integer not null default 0,
+					redelivery_of integer,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+					foreign key (webhook_id) references webhooks(id) on delete cascade
+				);
+				create index if not exists idx_webhook_deliveries_webhook on webhook_deliveries(webhook_id, id);
+			`)
+			return err
+		},
+	},
+
+	// only changes of state are kept here, label and reference events are
+	// read off label_ops and reference_links
+	{
+		Name: "add-thread-events-table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists thread_events (
+					id integer primary key autoincrement,
+					thread_at text not null,
+					kind text not null check (kind in ('closed', 'reopened', 'merged')),
+					did text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+				create index if not exists idx_thread_events_thread_at on thread_events(thread_at);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-description-to-label-definitions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table label_definitions add column description text;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-branch-deletions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column delete_branch_on_merge integer not null default 0;
+
+				create table if not exists branch_deletions (
+					id integer primary key autoincrement,
+					pull_at text not null,
+					repo_at text not null,
+					branch text not null,
+					did text not null,
+					error text not null default '',
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+				create index if not exists idx_branch_deletions_pull_at on branch_deletions(pull_at);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-is-template-to-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column is_template integer not null default 0;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-pull-dependencies",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists pull_dependencies (
+					id integer primary key autoincrement,
+					pull_at text not null,
+					depends_on text not null,
+					did text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					unique(pull_at, depends_on)
+				);
+				create index if not exists idx_pull_dependencies_depends_on on pull_dependencies(depends_on);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-mail-tables",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				-- message ids of the mail that opened or commented on a pull, so
+				-- that replies land on the same pull
+				create table if not exists mail_threads (
+					message_id text primary key,
+					repo_at text not null,
+					pull_id integer not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+
+				-- parts of a patch series that is still arriving
+				create table if not exists mail_patch_parts (
+					series_id text not null,
+					part integer not null,
+					total integer not null,
+					did text not null,
+					repo_at text not null,
+					branch text not null,
+					message_id text not null,
+					subject text not null,
+					body text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					primary key (series_id, part)
+				);
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-user-settings",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				-- settings of a user that only concern this appview; an empty
+				-- default branch means the instance default
+				create table if not exists user_settings (
+					did text primary key,
+					default_branch text not null default ''
+				);
+			`)
+			return err
+		},
+	},
+
+	// pins used to be repos only, and went with the repo; now strings,
+	// issues and pulls can be pinned too, and pins say which they are
+	{
+		Name: "generalize-profile-pins",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table profile_pinned_repositories_new (
+					-- id
+					id integer primary key autoincrement,
+					did text not null,
+
+					-- data
+					kind text not null default 'repo' check (kind in ('repo', 'string', 'issue', 'pull')),
+					at_uri text not null,
+					position integer not null default 0,
+
+					-- constraints
+					unique(did, at_uri),
+					foreign key (did) references profile(did) on delete cascade
+				);
+
+				insert into profile_pinned_repositories_new (id, did, kind, at_uri, position)
+				select
+					id,
+					did,
+					'repo',
+					at_uri,
+					row_number() over (partition by did order by id) - 1
+				from profile_pinned_repositories;
+
+				drop table profile_pinned_repositories;
+				alter table profile_pinned_repositories_new rename to profile_pinned_repositories;
+			`)
+			return err
+		},
+	},
+
+	// reports are for moderators only; someone can have one open report on
+	// a subject at a time, and a takedown hides its subject for good
+	{
+		Name: "add-reports-and-takedowns",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists reports (
+					id integer primary key autoincrement,
+					reporter_did text not null,
+
+					-- an at-uri, or a did for users
+					subject text not null,
+					kind text not null check (kind in ('repo', 'issue', 'comment', 'user')),
+					reason text not null,
+					details text not null default '',
+
+					status text not null default 'open' check (status in ('open', 'dismissed', 'takendown')),
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					resolved_by text,
+					resolved text
+				);
+
+				create unique index if not exists idx_reports_open_reporter_subject
+					on reports(reporter_did, subject) where status = 'open';
+				create index if not exists idx_reports_status on reports(status);
+				create index if not exists idx_reports_subject on reports(subject);
+
+				create table if not exists takedowns (
+					subject text primary key,
+					by_did text not null,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+			`)
+			return err
+		},
+	},
+
+	// every action taken from the admin dashboard is kept, for the record
+	{
+		Name: "add-admin-actions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists admin_actions (
+					id integer primary key autoincrement,
+					admin_did text not null,
+					action text not null check (action in ('suspend', 'takedown', 'restore')),
+
+					-- an at-uri, or a did for accounts
+					subject text not null,
+					reason text not null default '',
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+			`)
+			return err
+		},
+	},
+
+	// exports are built in the background; the archive is kept on disk
+	// until expires, and the row stays around so users see what they asked
+	// for
+	{
+		Name: "add-exports",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists exports (
+					id integer primary key autoincrement,
+					did text not null,
+					include_repos integer not null default 0,
+					status text not null default 'pending' check (status in ('pending', 'running', 'done', 'failed', 'expired')),
+					size integer not null default 0,
+					error text not null default '',
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					finished text,
+					expires text
+				);
+
+				create index if not exists idx_exports_did on exports(did);
+				create index if not exists idx_exports_status on exports(status);
+			`)
+			return err
+		},
+	},
+
+	// accounts are deleted in steps, after a grace period; step is the next
+	// one to take, so that a deletion cut short picks up where it stopped
+	{
+		Name: "add-account-deletions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table if not exists account_deletions (
+					id integer primary key autoincrement,
+					did text not null,
+					status text not null default 'scheduled' check (status in ('scheduled', 'running', 'done', 'cancelled')),
+					step text not null default 'repos',
+					error text not null default '',
+					attempts integer not null default 0,
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+					scheduled text not null,
+					finished text
+				);
+
+				create index if not exists idx_account_deletions_did on account_deletions(did);
+				create index if not exists idx_account_deletions_status on account_deletions(status);
+			`)
+			return err
+		},
+	},
+
+	// knots and spindles registered before approvals existed are approved
+	{
+		Name: "add-approval-to-registrations-and-spindles",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table registrations add column approval text not null default 'approved' check (approval in ('pending', 'approved', 'rejected'));
+				alter table spindles add column approval text not null default 'approved' check (approval in ('pending', 'approved', 'rejected'));
+			`)
+			return err
+		},
+	},
+
+	// admins approve and reject registrations too
+	{
+		Name: "add-approvals-to-admin-actions",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				create table admin_actions_new (
+					id integer primary key autoincrement,
+					admin_did text not null,
+					action text not null check (action in ('suspend', 'takedown', 'restore', 'approve', 'reject')),
+
+					-- an at-uri, or a did for accounts, or the domain of a knot or spindle
+					subject text not null,
+					reason text not null default '',
+					created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+				);
+
+				insert into admin_actions_new (id, admin_did, action, subject, reason, created)
+				select id, admin_did, action, subject, reason, created
+				from admin_actions;
+
+				drop table admin_actions;
+				alter table admin_actions_new rename to admin_actions;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-pipelines-disabled-to-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column pipelines_disabled integer not null default 0;
+			`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos drop column pipelines_disabled;
+			`)
+			return err
+		},
+	},
+
+	// 0 leaves the limit to the spindle
+	{
+		Name: "add-pipeline-queue-settings-to-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column pipeline_concurrency integer not null default 0;
+				alter table repos add column cancel_superseded_pipelines integer not null default 0;
+			`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos drop column pipeline_concurrency;
+				alter table repos drop column cancel_superseded_pipelines;
+			`)
+			return err
+		},
+	},
+
+	{
+		Name: "add-require-passing-pipeline-to-repos",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos add column require_passing_pipeline integer not null default 0;
+			`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table repos drop column require_passing_pipeline;
+			`)
+			return err
+		},
+	},
+
+	// app passwords only work on the knots they were made for, so that a knot
+	// can't replay them elsewhere. Older ones have none.
+	{
+		Name: "add-knots-to-app-passwords",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table app_passwords add column knots text not null default '';
+			`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				alter table app_passwords drop column knots;
+			`)
+			return err
+		},
+	},
+}
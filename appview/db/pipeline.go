@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"tangled.org/core/appview/models"
+	"tangled.org/core/workflow"
 )
 
 func GetPipelines(e Execer, filters ...filter) ([]models.Pipeline, error) {
@@ -24,7 +25,7 @@ func GetPipelines(e Execer, filters ...filter) ([]models.Pipeline, error) {
 		whereClause = " where " + strings.Join(conditions, " and ")
 	}
 
-	query := fmt.Sprintf(`select id, rkey, knot, repo_owner, repo_name, sha, created from pipelines %s`, whereClause)
+	query := fmt.Sprintf(`select id, rkey, knot, repo_owner, repo_name, trigger_id, sha, created, rerun_of from pipelines %s`, whereClause)
 
 	rows, err := e.Query(query, args...)
 
@@ -42,8 +43,10 @@ func GetPipelines(e Execer, filters ...filter) ([]models.Pipeline, error) {
 			&pipeline.Knot,
 			&pipeline.RepoOwner,
 			&pipeline.RepoName,
+			&pipeline.TriggerId,
 			&pipeline.Sha,
 			&createdAt,
+			&pipeline.RerunOf,
 		)
 		if err != nil {
 			return nil, err
@@ -71,6 +74,7 @@ func AddPipeline(e Execer, pipeline models.Pipeline) error {
 		pipeline.RepoName,
 		pipeline.TriggerId,
 		pipeline.Sha,
+		pipeline.RerunOf,
 	}
 
 	placeholders := make([]string, len(args))
@@ -85,7 +89,8 @@ func AddPipeline(e Execer, pipeline models.Pipeline) error {
 		repo_owner,
 		repo_name,
 		trigger_id,
-		sha
+		sha,
+		rerun_of
 	) values (%s)
 	`, strings.Join(placeholders, ","))
 
@@ -94,6 +99,24 @@ func AddPipeline(e Execer, pipeline models.Pipeline) error {
 	return err
 }
 
+func DeletePipelines(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from pipelines %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
+
 func AddTrigger(e Execer, trigger models.Trigger) (int64, error) {
 	args := []any{
 		trigger.Kind,
@@ -169,19 +192,57 @@ func AddPipelineStatus(e Execer, status models.PipelineStatus) error {
 // this is a mega query, but the most useful one:
 // get N pipelines, for each one get the latest status of its N workflows
 func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipeline, error) {
+	return getPipelineStatuses(e, limit, false, filters...)
+}
+
+// GetLatestPipelineStatuses is GetPipelineStatuses for the newest pipeline of
+// each commit, leaving out the runs it was the rerun of.
+func GetLatestPipelineStatuses(e Execer, filters ...filter) ([]models.Pipeline, error) {
+	return getPipelineStatuses(e, 0, true, filters...)
+}
+
+func getPipelineStatuses(e Execer, limit int, latestPerSha bool, filters ...filter) ([]models.Pipeline, error) {
 	var conditions []string
 	var args []any
 	for _, filter := range filters {
-		filter.key = "p." + filter.key // the table is aliased in the query to `p`
+		// the tables are aliased in the query to `p` and `t`, filters on
+		// pipelines may leave the alias out
+		if !strings.Contains(filter.key, ".") {
+			filter.key = "p." + filter.key
+		}
 		conditions = append(conditions, filter.Condition())
 		args = append(args, filter.Arg()...)
 	}
 
+	if latestPerSha {
+		filterClause := ""
+		if conditions != nil {
+			filterClause = " where " + strings.Join(conditions, " and ")
+		}
+		conditions = append(conditions, fmt.Sprintf(`p.id in (
+			select id from (
+				select
+					p.id,
+					row_number() over (partition by p.sha order by p.created desc, p.id desc) as n
+				from pipelines p
+				join triggers t on p.trigger_id = t.id
+				%s
+			)
+			where n = 1
+		)`, filterClause))
+		args = append(args, args...)
+	}
+
 	whereClause := ""
 	if conditions != nil {
 		whereClause = " where " + strings.Join(conditions, " and ")
 	}
 
+	limitClause := ""
+	if limit > 0 {
+		limitClause = fmt.Sprintf("limit %d", limit)
+	}
+
 	query := fmt.Sprintf(`
 		select
 			p.id,
@@ -191,6 +252,7 @@ func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipel
 			p.repo_name,
 			p.sha,
 			p.created,
+			p.rerun_of,
 			t.id,
 			t.kind,
 			t.push_ref,
@@ -206,8 +268,8 @@ func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipel
 			triggers t ON p.trigger_id = t.id
 		%s
 		order by p.created desc
-		limit %d
-	`, whereClause, limit)
+		%s
+	`, whereClause, limitClause)
 
 	rows, err := e.Query(query, args...)
 	if err != nil {
@@ -229,6 +291,7 @@ func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipel
 			&p.RepoName,
 			&p.Sha,
 			&created,
+			&p.RerunOf,
 			&p.TriggerId,
 			&t.Kind,
 			&t.PushRef,
@@ -251,6 +314,7 @@ func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipel
 		t.Id = p.TriggerId
 		p.Trigger = &t
 		p.Statuses = make(map[string]models.WorkflowStatus)
+		p.MatrixJobs = make(map[string]models.WorkflowStatus)
 
 		k := fmt.Sprintf("%s/%s", p.Knot, p.Rkey)
 		pipelines[k] = p
@@ -320,16 +384,19 @@ func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipel
 		if !ok {
 			continue
 		}
-		statuses, _ := pipeline.Statuses[ps.Workflow]
-		if !ok {
-			pipeline.Statuses[ps.Workflow] = models.WorkflowStatus{}
+		// matrix jobs are kept apart from the workflows they belong to
+		all := pipeline.Statuses
+		if _, _, isJob := workflow.SplitMatrixJobName(ps.Workflow); isJob {
+			all = pipeline.MatrixJobs
 		}
 
+		statuses := all[ps.Workflow]
+
 		// append
 		statuses.Data = append(statuses.Data, ps)
 
 		// reassign
-		pipeline.Statuses[ps.Workflow] = statuses
+		all[ps.Workflow] = statuses
 		pipelines[key] = pipeline
 	}
 
@@ -365,3 +432,83 @@ func GetPipelineStatuses(e Execer, limit int, filters ...filter) ([]models.Pipel
 
 	return all, nil
 }
+
+func AddPipelineArtifact(e Execer, artifact models.PipelineArtifact) error {
+	_, err := e.Exec(
+		`insert or replace into pipeline_artifacts (
+			spindle,
+			pipeline_knot,
+			pipeline_rkey,
+			workflow,
+			name,
+			size,
+			created
+		) values (?, ?, ?, ?, ?, ?, ?)`,
+		artifact.Spindle,
+		artifact.PipelineKnot,
+		artifact.PipelineRkey,
+		artifact.Workflow,
+		artifact.Name,
+		artifact.Size,
+		artifact.Created.Format(time.RFC3339),
+	)
+	return err
+}
+
+func GetPipelineArtifacts(e Execer, filters ...filter) ([]models.PipelineArtifact, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`
+		select id, spindle, pipeline_knot, pipeline_rkey, workflow, name, size, created
+		from pipeline_artifacts
+		%s
+		order by name asc
+	`, whereClause)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artifacts []models.PipelineArtifact
+	for rows.Next() {
+		var a models.PipelineArtifact
+		var created string
+		err := rows.Scan(
+			&a.Id,
+			&a.Spindle,
+			&a.PipelineKnot,
+			&a.PipelineRkey,
+			&a.Workflow,
+			&a.Name,
+			&a.Size,
+			&created,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			a.Created = t
+		}
+
+		artifacts = append(artifacts, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
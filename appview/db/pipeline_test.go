@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/workflow"
+)
+
+func TestGetLatestPipelineStatuses(t *testing.T) {
+	d := createTestDB(t)
+
+	shaA, shaB := strings.Repeat("a", 40), strings.Repeat("b", 40)
+
+	// the pipelines of a commit, with the time each was run at
+	runs := []struct {
+		sha     string
+		created string
+	}{
+		{shaA, "2025-01-01T00:00:00Z"},
+		{shaA, "2025-01-03T00:00:00Z"},
+		{shaA, "2025-01-02T00:00:00Z"},
+		{shaB, "2024-12-01T00:00:00Z"},
+	}
+	for i, run := range runs {
+		triggerId, err := AddTrigger(d, models.Trigger{Kind: workflow.TriggerKindPush})
+		assert.NoError(t, err)
+
+		rkey := fmt.Sprintf("3lpipeline%d", i)
+		assert.NoError(t, AddPipeline(d, models.Pipeline{
+			Rkey:      rkey,
+			Knot:      "knot.example.com",
+			RepoOwner: "did:plc:alice",
+			RepoName:  "repo",
+			TriggerId: int(triggerId),
+			Sha:       run.sha,
+		}))
+		_, err = d.Exec(`update pipelines set created = ? where rkey = ?`, run.created, rkey)
+		assert.NoError(t, err)
+	}
+
+	// reruns of one commit do not push out the others
+	ps, err := GetLatestPipelineStatuses(
+		d,
+		FilterEq("repo_owner", "did:plc:alice"),
+		FilterIn("sha", []string{shaA, shaB}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ps))
+
+	latest := make(map[string]string)
+	for _, p := range ps {
+		latest[p.Sha] = p.Rkey
+	}
+	assert.Equal(t, map[string]string{shaA: "3lpipeline1", shaB: "3lpipeline3"}, latest)
+}
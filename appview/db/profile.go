@@ -29,6 +29,10 @@ func MakeProfileTimeline(e Execer, forDid string) (*models.ProfileTimeline, erro
 
 	// group pulls by month
 	for _, pull := range pulls {
+		if pull.Repo.IsPrivate() {
+			continue
+		}
+
 		pullMonth := pull.Created.Month()
 
 		if currentMonth-pullMonth >= TimeframeMonths {
@@ -52,6 +56,10 @@ func MakeProfileTimeline(e Execer, forDid string) (*models.ProfileTimeline, erro
 	}
 
 	for _, issue := range issues {
+		if issue.Repo.IsPrivate() {
+			continue
+		}
+
 		issueMonth := issue.Created.Month()
 
 		if currentMonth-issueMonth >= TimeframeMonths {
@@ -65,7 +73,12 @@ func MakeProfileTimeline(e Execer, forDid string) (*models.ProfileTimeline, erro
 		*items = append(*items, &issue)
 	}
 
-	repos, err := GetRepos(e, 0, FilterEq("did", forDid))
+	repos, err := GetRepos(
+		e,
+		0,
+		FilterEq("did", forDid),
+		FilterEq("visibility", models.RepoVisibilityPublic),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting all repos by did: %w", err)
 	}
@@ -113,7 +126,7 @@ func UpsertProfile(tx *sql.Tx, profile *models.Profile) error {
 		return err
 	}
 
-	// update pinned repos
+	// update pins
 	_, err = tx.Exec(`delete from profile_pinned_repositories where did = ?`, profile.Did)
 	if err != nil {
 		return err
@@ -179,16 +192,21 @@ func UpsertProfile(tx *sql.Tx, profile *models.Profile) error {
 		}
 	}
 
-	for _, pin := range profile.PinnedRepos {
-		if pin == "" {
+	position := 0
+	for _, pin := range profile.Pins {
+		kind := models.PinKindOf(pin)
+		if kind == "" {
 			continue
 		}
 
 		_, err := tx.Exec(
-			`insert into profile_pinned_repositories (did, at_uri) values (?, ?)`,
+			`insert into profile_pinned_repositories (did, kind, at_uri, position) values (?, ?, ?, ?)`,
 			profile.Did,
+			kind,
 			pin,
+			position,
 		)
+		position++
 
 		if err != nil {
 			log.Println("profile_pinned_repositories", "err", err)
@@ -284,7 +302,7 @@ func GetProfiles(e Execer, filters ...filter) (map[string]*models.Profile, error
 		idxs[did] = idx + 1
 	}
 
-	pinsQuery := fmt.Sprintf("select at_uri, did from profile_pinned_repositories where did in (%s)", inClause)
+	pinsQuery := fmt.Sprintf("select at_uri, did from profile_pinned_repositories where did in (%s) order by position", inClause)
 	rows, err = e.Query(pinsQuery, args...)
 	if err != nil {
 		return nil, err
@@ -301,7 +319,7 @@ func GetProfiles(e Execer, filters ...filter) (map[string]*models.Profile, error
 		}
 
 		idx := idxs[did]
-		profileMap[did].PinnedRepos[idx] = link
+		profileMap[did].Pins[idx] = link
 		idxs[did] = idx + 1
 	}
 
@@ -369,14 +387,14 @@ func GetProfile(e Execer, did string) (*models.Profile, error) {
 		i++
 	}
 
-	rows, err = e.Query(`select at_uri from profile_pinned_repositories where did = ?`, did)
+	rows, err = e.Query(`select at_uri from profile_pinned_repositories where did = ? order by position`, did)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	i = 0
 	for rows.Next() {
-		if err := rows.Scan(&profile.PinnedRepos[i]); err != nil {
+		if err := rows.Scan(&profile.Pins[i]); err != nil {
 			return nil, err
 		}
 		i++
@@ -440,7 +458,8 @@ func ValidateProfile(e Execer, profile *models.Profile) error {
 		return err
 	}
 
-	// ensure all pinned repos are either own repos or collaborating repos
+	// ensure all pinned repos are either own repos or collaborating repos,
+	// and that everything else pinned was made by the user
 	repos, err := GetRepos(e, 0, FilterEq("did", profile.Did))
 	if err != nil {
 		log.Printf("getting repos for %s: %s", profile.Did, err)
@@ -459,12 +478,21 @@ func ValidateProfile(e Execer, profile *models.Profile) error {
 		validRepos = append(validRepos, r.RepoAt())
 	}
 
-	for _, pinned := range profile.PinnedRepos {
+	for _, pinned := range profile.Pins {
 		if pinned == "" {
 			continue
 		}
-		if !slices.Contains(validRepos, pinned) {
-			return fmt.Errorf("Invalid pinned repo: `%s, does not belong to own or collaborating repos", pinned)
+		switch models.PinKindOf(pinned) {
+		case models.PinKindRepo:
+			if !slices.Contains(validRepos, pinned) {
+				return fmt.Errorf("Invalid pinned repo: `%s, does not belong to own or collaborating repos", pinned)
+			}
+		case models.PinKindString, models.PinKindIssue, models.PinKindPull:
+			if pinned.Authority().String() != profile.Did {
+				return fmt.Errorf("Invalid pin: `%s`, was not made by this user", pinned)
+			}
+		default:
+			return fmt.Errorf("Invalid pin: `%s`, only repos, strings, issues and pulls can be pinned", pinned)
 		}
 	}
 
@@ -500,3 +528,94 @@ func validateLinks(profile *models.Profile) error {
 	}
 	return nil
 }
+
+// GetPins looks up what uris, pinned or pinnable by did, point at, keeping
+// their order. Ones that are gone, or that are in repos with a visibility not
+// in visible, are left out.
+func GetPins(e Execer, did string, uris []syntax.ATURI, visible []models.RepoVisibility) ([]models.Pin, error) {
+	byKind := make(map[models.PinKind][]syntax.ATURI)
+	for _, pin := range uris {
+		if kind := models.PinKindOf(pin); kind != "" {
+			byKind[kind] = append(byKind[kind], pin)
+		}
+	}
+
+	resolved := make(map[syntax.ATURI]models.Pin)
+	visibleRepo := func(r *models.Repo) bool {
+		return r != nil && slices.Contains(visible, r.Visibility)
+	}
+
+	if uris := byKind[models.PinKindRepo]; len(uris) > 0 {
+		repos, err := GetRepos(e, 0, FilterIn("at_uri", uris))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pinned repos: %w", err)
+		}
+		for _, r := range repos {
+			if visibleRepo(&r) {
+				resolved[r.RepoAt()] = models.Pin{Kind: models.PinKindRepo, Uri: r.RepoAt(), Repo: &r}
+			}
+		}
+	}
+
+	if uris := byKind[models.PinKindString]; len(uris) > 0 {
+		var rkeys []string
+		for _, uri := range uris {
+			rkeys = append(rkeys, uri.RecordKey().String())
+		}
+		strs, err := GetStrings(e, 0, FilterEq("did", did), FilterIn("rkey", rkeys))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pinned strings: %w", err)
+		}
+		for _, s := range strs {
+			resolved[s.AtUri()] = models.Pin{Kind: models.PinKindString, Uri: s.AtUri(), String: &s}
+		}
+	}
+
+	if uris := byKind[models.PinKindIssue]; len(uris) > 0 {
+		issues, err := GetIssues(e, FilterIn("at_uri", uris))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pinned issues: %w", err)
+		}
+		for _, i := range issues {
+			if i.Deleted == nil && visibleRepo(i.Repo) {
+				resolved[i.AtUri()] = models.Pin{Kind: models.PinKindIssue, Uri: i.AtUri(), Issue: &i}
+			}
+		}
+	}
+
+	if uris := byKind[models.PinKindPull]; len(uris) > 0 {
+		pulls, err := GetPulls(e, FilterIn("at_uri", uris))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pinned pulls: %w", err)
+		}
+
+		var repoAts []syntax.ATURI
+		for _, p := range pulls {
+			repoAts = append(repoAts, p.RepoAt)
+		}
+		repos, err := GetRepos(e, 0, FilterIn("at_uri", repoAts))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repos of pinned pulls: %w", err)
+		}
+		repoMap := make(map[syntax.ATURI]*models.Repo)
+		for _, r := range repos {
+			repoMap[r.RepoAt()] = &r
+		}
+
+		for _, p := range pulls {
+			p.Repo = repoMap[p.RepoAt]
+			if visibleRepo(p.Repo) {
+				resolved[p.AtUri()] = models.Pin{Kind: models.PinKindPull, Uri: p.AtUri(), Pull: p}
+			}
+		}
+	}
+
+	var pins []models.Pin
+	for _, uri := range uris {
+		if pin, ok := resolved[uri]; ok {
+			pins = append(pins, pin)
+		}
+	}
+
+	return pins, nil
+}
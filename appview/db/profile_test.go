@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func TestGetPins(t *testing.T) {
+	d := createTestDB(t)
+	did := "did:plc:alice"
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	public := addTestRepo(t, d, &models.Repo{Did: did, Name: "public", Visibility: models.RepoVisibilityPublic})
+	private := addTestRepo(t, d, &models.Repo{Did: did, Name: "private", Visibility: models.RepoVisibilityPrivate})
+
+	str := models.String{Did: syntax.DID(did), Rkey: "3lstring", Filename: "notes.md", Created: created}
+	assert.NoError(t, AddString(d, str))
+
+	profile := &models.Profile{Did: did}
+	profile.Pins[0] = str.AtUri()
+	profile.Pins[1] = private.RepoAt()
+	profile.Pins[2] = public.RepoAt()
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, UpsertProfile(tx, profile))
+
+	// pins keep the order they were given in
+	got, err := GetProfile(d, did)
+	assert.NoError(t, err)
+	assert.Equal(t, profile.Pins, got.Pins)
+
+	all := []models.RepoVisibility{models.RepoVisibilityPublic, models.RepoVisibilityPrivate}
+	pins, err := GetPins(d, did, got.Pins[:], all)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(pins))
+	assert.Equal(t, models.PinKindString, pins[0].Kind)
+	assert.Equal(t, "notes.md", pins[0].String.Filename)
+	assert.Equal(t, "private", pins[1].Repo.Name)
+	assert.Equal(t, "public", pins[2].Repo.Name)
+
+	// private repos are left out for everyone else
+	pins, err = GetPins(d, did, got.Pins[:], []models.RepoVisibility{models.RepoVisibilityPublic})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(pins))
+	assert.Equal(t, models.PinKindString, pins[0].Kind)
+	assert.Equal(t, "public", pins[1].Repo.Name)
+}
@@ -1,78 +1,95 @@
 package db
 
 import (
-	"tangled.org/core/appview/models"
+	"database/sql"
+	"fmt"
+	"strings"
 	"time"
+
+	"tangled.org/core/appview/models"
+	"tangled.org/core/crypto"
 )
 
+// AddPublicKey adds a key, or brings back one that was revoked.
 func AddPublicKey(e Execer, did, name, key, rkey string) error {
+	var expires *string
+	if t, _ := crypto.SSHKeyExpiry(key); t != nil {
+		s := t.Format(time.RFC3339)
+		expires = &s
+	}
+
 	_, err := e.Exec(
-		`insert or ignore into public_keys (did, name, key, rkey)
-		 values (?, ?, ?, ?)`,
-		did, name, key, rkey)
+		`insert into public_keys (did, name, key, rkey, expires)
+		 values (?, ?, ?, ?, ?)
+		 on conflict(did, name, key) do update set
+			rkey = excluded.rkey,
+			expires = excluded.expires,
+			revoked = null`,
+		did, name, key, rkey, expires)
 	return err
 }
 
-func DeletePublicKey(e Execer, did, name, key string) error {
+// RevokePublicKey marks a key as deleted by its owner. It is kept so that
+// commits signed with it can be told apart from unsigned ones.
+func RevokePublicKey(e Execer, did, name, key string) error {
 	_, err := e.Exec(`
-		delete from public_keys
-		where did = ? and name = ? and key = ?`,
+		update public_keys
+		set revoked = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		where did = ? and name = ? and key = ? and revoked is null`,
 		did, name, key)
 	return err
 }
 
-func DeletePublicKeyByRkey(e Execer, did, rkey string) error {
+func RevokePublicKeyByRkey(e Execer, did, rkey string) error {
 	_, err := e.Exec(`
-		delete from public_keys
-		where did = ? and rkey = ?`,
+		update public_keys
+		set revoked = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		where did = ? and rkey = ? and revoked is null`,
 		did, rkey)
 	return err
 }
 
-func GetAllPublicKeys(e Execer) ([]models.PublicKey, error) {
-	var keys []models.PublicKey
-
-	rows, err := e.Query(`select key, name, did, rkey, created from public_keys`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var publicKey models.PublicKey
-		var createdAt string
-		if err := rows.Scan(&publicKey.Key, &publicKey.Name, &publicKey.Did, &publicKey.Rkey, &createdAt); err != nil {
-			return nil, err
-		}
-		createdAtTime, _ := time.Parse(time.RFC3339, createdAt)
-		publicKey.Created = &createdAtTime
-		keys = append(keys, publicKey)
+// GetPublicKeys returns keys including revoked ones.
+func GetPublicKeys(e Execer, filters ...filter) ([]models.PublicKey, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
 	}
 
-	return keys, nil
-}
-
-func GetPublicKeysForDid(e Execer, did string) ([]models.PublicKey, error) {
-	var keys []models.PublicKey
-
-	rows, err := e.Query(`select did, key, name, rkey, created from public_keys where did = ?`, did)
+	query := fmt.Sprintf(`select did, key, name, rkey, created, expires, revoked from public_keys %s`, whereClause)
+	rows, err := e.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var keys []models.PublicKey
 	for rows.Next() {
 		var publicKey models.PublicKey
 		var createdAt string
-		if err := rows.Scan(&publicKey.Did, &publicKey.Key, &publicKey.Name, &publicKey.Rkey, &createdAt); err != nil {
+		var expires, revoked sql.NullString
+		if err := rows.Scan(&publicKey.Did, &publicKey.Key, &publicKey.Name, &publicKey.Rkey, &createdAt, &expires, &revoked); err != nil {
 			return nil, err
 		}
 		createdAtTime, _ := time.Parse(time.RFC3339, createdAt)
 		publicKey.Created = &createdAtTime
+		if expires.Valid {
+			if t, err := time.Parse(time.RFC3339, expires.String); err == nil {
+				publicKey.Expires = &t
+			}
+		}
+		if revoked.Valid {
+			if t, err := time.Parse(time.RFC3339, revoked.String); err == nil {
+				publicKey.Revoked = &t
+			}
+		}
 		keys = append(keys, publicKey)
 	}
 
@@ -82,3 +99,11 @@ func GetPublicKeysForDid(e Execer, did string) ([]models.PublicKey, error) {
 
 	return keys, nil
 }
+
+func GetAllPublicKeys(e Execer) ([]models.PublicKey, error) {
+	return GetPublicKeys(e, FilterIs("revoked", nil))
+}
+
+func GetPublicKeysForDid(e Execer, did string) ([]models.PublicKey, error) {
+	return GetPublicKeys(e, FilterEq("did", did), FilterIs("revoked", nil))
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func AddPullDependency(e Execer, pullAt, dependsOn syntax.ATURI, did string) error {
+	_, err := e.Exec(
+		`insert into pull_dependencies (pull_at, depends_on, did, created)
+		values (?, ?, ?, ?)`,
+		pullAt,
+		dependsOn,
+		did,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func DeletePullDependency(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from pull_dependencies %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+// GetPullDependencies returns the matching dependencies along with the
+// pulls they are on, oldest first. Dependencies on pulls that no longer
+// exist are left out. Filters apply to the pull_dependencies table as d.
+func GetPullDependencies(e Execer, filters ...filter) ([]models.PullDependency, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select d.id, d.pull_at, d.did, d.created, p.at_uri, p.pull_id, p.title, p.state, r.did, r.name
+		from pull_dependencies d
+		join pulls p on p.at_uri = d.depends_on
+		join repos r on r.at_uri = p.repo_at
+		%s
+		order by d.created asc, d.id asc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []models.PullDependency
+	for rows.Next() {
+		var dep models.PullDependency
+		var created string
+		if err := rows.Scan(
+			&dep.Id,
+			&dep.PullAt,
+			&dep.Did,
+			&created,
+			&dep.On.AtUri,
+			&dep.On.Number,
+			&dep.On.Title,
+			&dep.OnState,
+			&dep.On.RepoDid,
+			&dep.On.RepoName,
+		); err != nil {
+			return nil, err
+		}
+		dep.On.Kind = models.ReferenceKindPull
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			dep.Created = t
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return deps, rows.Err()
+}
+
+// GetPullDependents returns the pulls that depend on pullAt.
+func GetPullDependents(e Execer, pullAt syntax.ATURI) ([]models.Reference, error) {
+	rows, err := e.Query(`
+		select p.at_uri, p.pull_id, p.title, r.did, r.name
+		from pull_dependencies d
+		join pulls p on p.at_uri = d.pull_at
+		join repos r on r.at_uri = p.repo_at
+		where d.depends_on = ?
+		order by d.created asc, d.id asc
+	`, pullAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dependents []models.Reference
+	for rows.Next() {
+		ref := models.Reference{Kind: models.ReferenceKindPull}
+		if err := rows.Scan(&ref.AtUri, &ref.Number, &ref.Title, &ref.RepoDid, &ref.RepoName); err != nil {
+			return nil, err
+		}
+		dependents = append(dependents, ref)
+	}
+
+	return dependents, rows.Err()
+}
+
+// PullDependsOn reports whether pullAt depends on dependsOn, directly or
+// through other pulls.
+func PullDependsOn(e Execer, pullAt, dependsOn syntax.ATURI) (bool, error) {
+	var exists bool
+	err := e.QueryRow(`
+		with recursive reachable(at) as (
+			select depends_on from pull_dependencies where pull_at = ?
+			union
+			select d.depends_on
+			from pull_dependencies d
+			join reachable on d.pull_at = reachable.at
+		)
+		select exists (select 1 from reachable where at = ?)
+	`, pullAt, dependsOn).Scan(&exists)
+	return exists, err
+}
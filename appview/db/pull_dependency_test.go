@@ -0,0 +1,78 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestPullDependencies(t *testing.T) {
+	d := createTestDB(t)
+
+	repo := &models.Repo{
+		Did:  "did:plc:alice",
+		Name: "project",
+		Knot: "knot.example.com",
+		Rkey: "3lrepo",
+	}
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+
+	var pulls []*models.Pull
+	for _, rkey := range []string{"3lpulla", "3lpullb", "3lpullc"} {
+		pull := &models.Pull{
+			RepoAt:       repo.RepoAt(),
+			OwnerDid:     "did:plc:bob",
+			Rkey:         rkey,
+			Title:        rkey,
+			TargetBranch: "main",
+			Submissions:  []*models.PullSubmission{{Patch: "diff"}},
+		}
+		assert.NoError(t, NewPull(tx, pull))
+		pulls = append(pulls, pull)
+	}
+	assert.NoError(t, tx.Commit())
+
+	a, b, c := pulls[0].AtUri(), pulls[1].AtUri(), pulls[2].AtUri()
+
+	// a -> b -> c
+	assert.NoError(t, AddPullDependency(d, a, b, "did:plc:bob"))
+	assert.NoError(t, AddPullDependency(d, b, c, "did:plc:bob"))
+	assert.Error(t, AddPullDependency(d, a, b, "did:plc:bob"))
+
+	deps, err := GetPullDependencies(d, FilterEq("d.pull_at", a))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(deps))
+	assert.Equal(t, b, deps[0].On.AtUri)
+	assert.Equal(t, 2, deps[0].On.Number)
+	assert.Equal(t, "project", deps[0].On.RepoName)
+	assert.True(t, deps[0].IsBlocking())
+
+	assert.NoError(t, MergePull(d, repo.RepoAt(), pulls[1].PullId))
+	deps, err = GetPullDependencies(d, FilterEq("d.pull_at", a))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(models.BlockingDependencies(deps)))
+
+	dependents, err := GetPullDependents(d, c)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(dependents))
+	assert.Equal(t, b, dependents[0].AtUri)
+
+	// c -> a would close the loop
+	cycle, err := PullDependsOn(d, a, c)
+	assert.NoError(t, err)
+	assert.True(t, cycle)
+	cycle, err = PullDependsOn(d, c, a)
+	assert.NoError(t, err)
+	assert.False(t, cycle)
+
+	deps, err = GetPullDependencies(d, FilterEq("d.pull_at", b))
+	assert.NoError(t, err)
+	assert.NoError(t, DeletePullDependency(d, FilterEq("pull_at", b), FilterEq("id", deps[0].Id)))
+	cycle, err = PullDependsOn(d, a, c)
+	assert.NoError(t, err)
+	assert.False(t, cycle)
+}
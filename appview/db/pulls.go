@@ -304,11 +304,8 @@ func GetPullIDs(e Execer, opts models.PullSearchOptions) ([]int64, error) {
 	}
 	pageClause := ""
 	if opts.Page.Limit != 0 {
-		pageClause = fmt.Sprintf(
-			" limit %d offset %d ",
-			opts.Page.Limit,
-			opts.Page.Offset,
-		)
+		pageClause = " limit ? offset ? "
+		args = append(args, opts.Page.Limit, opts.Page.Offset)
 	}
 
 	query := fmt.Sprintf(
@@ -318,11 +315,12 @@ func GetPullIDs(e Execer, opts models.PullSearchOptions) ([]int64, error) {
 		from
 			pulls
 		%s
+		order by
+			created desc, id desc
 		%s`,
 		whereClause,
 		pageClause,
 	)
-	args = append(args, opts.Page.Limit, opts.Page.Offset)
 	rows, err := e.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -342,6 +340,49 @@ func GetPullIDs(e Execer, opts models.PullSearchOptions) ([]int64, error) {
 	return ids, nil
 }
 
+// GetForkPullRefs returns the forks that pulls were opened from, each with
+// the hidden refs that its open pulls compare against, as
+// <source branch>/<target branch>. Forks with no open pulls have none.
+func GetForkPullRefs(e Execer, filters ...filter) (map[syntax.ATURI][]string, error) {
+	conditions := []string{"source_repo_at is not null", "source_repo_at != ''"}
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	query := fmt.Sprintf(`
+		select source_repo_at, source_branch, target_branch, state
+		from pulls
+		where %s
+	`, strings.Join(conditions, " and "))
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[syntax.ATURI][]string)
+	for rows.Next() {
+		var fork, sourceBranch, targetBranch string
+		var state models.PullState
+		if err := rows.Scan(&fork, &sourceBranch, &targetBranch, &state); err != nil {
+			return nil, err
+		}
+
+		forkAt := syntax.ATURI(fork)
+		ref := sourceBranch + "/" + targetBranch
+		if state.IsOpen() && !slices.Contains(refs[forkAt], ref) {
+			refs[forkAt] = append(refs[forkAt], ref)
+		} else if _, ok := refs[forkAt]; !ok {
+			refs[forkAt] = nil
+		}
+	}
+
+	return refs, rows.Err()
+}
+
 func GetPull(e Execer, repoAt syntax.ATURI, pullId int) (*models.Pull, error) {
 	pulls, err := GetPullsWithLimit(e, 1, FilterEq("repo_at", repoAt), FilterEq("pull_id", pullId))
 	if err != nil {
@@ -541,7 +582,8 @@ func GetPullsByOwnerDid(e Execer, did, timeframe string) ([]models.Pull, error)
 				r.name,
 				r.knot,
 				r.rkey,
-				r.created
+				r.created,
+				r.visibility
 			from
 				pulls p
 			join
@@ -571,6 +613,7 @@ func GetPullsByOwnerDid(e Execer, did, timeframe string) ([]models.Pull, error)
 			&repo.Knot,
 			&repo.Rkey,
 			&repoCreatedAt,
+			&repo.Visibility,
 		)
 		if err != nil {
 			return nil, err
@@ -635,6 +678,12 @@ func SetPullState(e Execer, repoAt syntax.ATURI, pullId int, pullState models.Pu
 	return err
 }
 
+// SetPullBody replaces the description of a pull.
+func SetPullBody(e Execer, repoAt syntax.ATURI, pullId int, body string) error {
+	_, err := e.Exec(`update pulls set body = ? where repo_at = ? and pull_id = ?`, body, repoAt, pullId)
+	return err
+}
+
 func ClosePull(e Execer, repoAt syntax.ATURI, pullId int) error {
 	err := SetPullState(e, repoAt, pullId, models.PullClosed)
 	return err
@@ -752,6 +801,48 @@ func GetStack(e Execer, stackId string) (models.Stack, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	return orderStack(unorderedPulls)
+}
+
+// GetStacks fetches several stacks at once, keyed by stack-id.
+func GetStacks(e Execer, stackIds []string) (map[string]models.Stack, error) {
+	stacks := make(map[string]models.Stack)
+	if len(stackIds) == 0 {
+		return stacks, nil
+	}
+
+	unorderedPulls, err := GetPulls(
+		e,
+		FilterIn("stack_id", stackIds),
+		FilterNotEq("state", models.PullDeleted),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	byStackId := make(map[string][]*models.Pull)
+	for _, p := range unorderedPulls {
+		byStackId[p.StackId] = append(byStackId[p.StackId], p)
+	}
+
+	for stackId, pulls := range byStackId {
+		stack, err := orderStack(pulls)
+		if err != nil {
+			return nil, fmt.Errorf("stack %s: %w", stackId, err)
+		}
+		stacks[stackId] = stack
+	}
+
+	return stacks, nil
+}
+
+// orderStack walks parent-change-ids from the top of the stack down.
+func orderStack(unorderedPulls []*models.Pull) (models.Stack, error) {
+	if len(unorderedPulls) == 0 {
+		return models.Stack{}, nil
+	}
+
 	// map of parent-change-id to pull
 	changeIdMap := make(map[string]*models.Pull, len(unorderedPulls))
 	parentMap := make(map[string]*models.Pull, len(unorderedPulls))
@@ -770,6 +861,9 @@ func GetStack(e Execer, stackId string) (models.Stack, error) {
 			break
 		}
 	}
+	if topPull == nil {
+		return nil, fmt.Errorf("failed to find top of stack, stack is malformed")
+	}
 
 	pulls := []*models.Pull{}
 	for {
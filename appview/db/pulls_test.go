@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func TestGetForkPullRefs(t *testing.T) {
+	d := createTestDB(t)
+
+	repo := &models.Repo{Did: "did:plc:alice", Name: "project", Knot: "knot.example.com", Rkey: "3lrepo"}
+	fork := syntax.ATURI("at://did:plc:bob/sh.tangled.repo/3lfork")
+	other := syntax.ATURI("at://did:plc:carol/sh.tangled.repo/3lfork")
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+
+	newPull := func(rkey, branch string, source *syntax.ATURI) *models.Pull {
+		pull := &models.Pull{
+			RepoAt:       repo.RepoAt(),
+			OwnerDid:     "did:plc:bob",
+			Rkey:         rkey,
+			Title:        rkey,
+			TargetBranch: "main",
+			Submissions:  []*models.PullSubmission{{Patch: "diff"}},
+		}
+		if branch != "" {
+			pull.PullSource = &models.PullSource{Branch: branch, RepoAt: source}
+		}
+		assert.NoError(t, NewPull(tx, pull))
+		return pull
+	}
+
+	newPull("3lpulla", "feature", &fork)
+	newPull("3lpullb", "feature", &fork)
+	merged := newPull("3lpullc", "fix", &fork)
+	closed := newPull("3lpulld", "old", &other)
+	newPull("3lpulle", "", nil)
+	newPull("3lpullf", "topic", nil)
+	assert.NoError(t, tx.Commit())
+
+	assert.NoError(t, MergePull(d, repo.RepoAt(), merged.PullId))
+	assert.NoError(t, ClosePull(d, repo.RepoAt(), closed.PullId))
+
+	refs, err := GetForkPullRefs(d)
+	assert.NoError(t, err)
+	assert.Equal(t, map[syntax.ATURI][]string{
+		fork:  {"feature/main"},
+		other: nil,
+	}, refs)
+
+	refs, err = GetForkPullRefs(d, FilterEq("source_repo_at", other))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(refs))
+}
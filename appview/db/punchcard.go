@@ -20,18 +20,53 @@ func AddPunch(e Execer, punch models.Punch) error {
 	return err
 }
 
-func MakePunchcard(e Execer, filters ...filter) (*models.Punchcard, error) {
+// AddPunchEvent counts a record towards its author's punchcard, on the UTC
+// day it was created. Records that were counted already are ignored.
+func AddPunchEvent(e Execer, event models.PunchEvent) error {
+	_, err := e.Exec(`
+		insert or ignore into punchcard_events (subject_at, did, kind, date)
+		values (?, ?, ?, ?)
+	`, event.SubjectAt, event.Did, event.Kind, event.Created.UTC().Format(time.DateOnly))
+	return err
+}
+
+func DeletePunchEvents(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from punchcard_events %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+// MakePunchcard counts commits and records per UTC day from start to end,
+// both inclusive. Every day in between has a punch, even if it is empty.
+func MakePunchcard(e Execer, start, end time.Time, filters ...filter) (*models.Punchcard, error) {
 	punchcard := &models.Punchcard{}
-	now := time.Now()
-	startOfYear := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
-	endOfYear := time.Date(now.Year(), 12, 31, 0, 0, 0, 0, time.UTC)
-	for d := startOfYear; d.Before(endOfYear) || d.Equal(endOfYear); d = d.AddDate(0, 0, 1) {
+	start = truncateDay(start)
+	end = truncateDay(end)
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
 		punchcard.Punches = append(punchcard.Punches, models.Punch{
 			Date:  d,
 			Count: 0,
 		})
 	}
 
+	filters = append(
+		filters,
+		FilterGte("date", start.Format(time.DateOnly)),
+		FilterLte("date", end.Format(time.DateOnly)),
+	)
+
 	var conditions []string
 	var args []any
 	for _, filter := range filters {
@@ -39,14 +74,15 @@ func MakePunchcard(e Execer, filters ...filter) (*models.Punchcard, error) {
 		args = append(args, filter.Arg()...)
 	}
 
-	whereClause := ""
-	if conditions != nil {
-		whereClause = " where " + strings.Join(conditions, " and ")
-	}
+	whereClause := " where " + strings.Join(conditions, " and ")
 
 	query := fmt.Sprintf(`
 		select date, sum(count) as total_count
-		from punchcard
+		from (
+			select did, date, count from punchcard
+			union all
+			select did, date, 1 as count from punchcard_events
+		)
 		%s
 		group by date
 		order by date
@@ -77,9 +113,20 @@ func MakePunchcard(e Execer, filters ...filter) (*models.Punchcard, error) {
 			punch.Count = int(count.Int64)
 		}
 
-		punchcard.Punches[punch.Date.YearDay()] = punch
+		day := int(punch.Date.Sub(start).Hours() / 24)
+		if day < 0 || day >= len(punchcard.Punches) {
+			continue
+		}
+
+		punchcard.Punches[day] = punch
 		punchcard.Total += punch.Count
 	}
 
-	return punchcard, nil
+	return punchcard, rows.Err()
+}
+
+// truncateDay is the start of the UTC day t falls on.
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
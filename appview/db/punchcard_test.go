@@ -0,0 +1,35 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestMakePunchcard(t *testing.T) {
+	d := createTestDB(t)
+
+	did := "did:plc:alice"
+	end := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	start := end.AddDate(-1, 0, 1)
+
+	assert.NoError(t, AddPunch(d, models.Punch{Did: did, Date: end, Count: 3}))
+	assert.NoError(t, AddPunch(d, models.Punch{Did: did, Date: end.AddDate(-2, 0, 0), Count: 5}))
+
+	// late on the 9th in UTC-5 is the 10th in UTC
+	late := time.Date(2025, 3, 9, 22, 0, 0, 0, time.FixedZone("", -5*60*60))
+	issue := models.PunchEvent{Did: did, SubjectAt: "at://did:plc:alice/sh.tangled.repo.issue/1", Kind: models.PunchIssue, Created: late}
+	assert.NoError(t, AddPunchEvent(d, issue))
+	assert.NoError(t, AddPunchEvent(d, issue))
+	assert.NoError(t, AddPunchEvent(d, models.PunchEvent{Did: did, SubjectAt: "at://did:plc:alice/sh.tangled.repo.pull/1", Kind: models.PunchPull, Created: start}))
+
+	punchcard, err := MakePunchcard(d, start, end, FilterEq("did", did))
+	assert.NoError(t, err)
+	assert.Equal(t, 365, len(punchcard.Punches))
+	assert.Equal(t, 5, punchcard.Total)
+	assert.Equal(t, 1, punchcard.Punches[0].Count)
+	assert.Equal(t, 4, punchcard.Punches[364].Count)
+	assert.Equal(t, "2025-03-10", punchcard.Punches[364].Date.Format(time.DateOnly))
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"log"
 	"time"
 
@@ -63,33 +64,60 @@ func GetReactionCount(e Execer, threadAt syntax.ATURI, kind models.ReactionKind)
 }
 
 func GetReactionMap(e Execer, userLimit int, threadAt syntax.ATURI) (map[models.ReactionKind]models.ReactionDisplayData, error) {
-	query := `
-	select kind, reacted_by_did,
-	       row_number() over (partition by kind order by created asc) as rn,
-	       count(*) over (partition by kind) as total
+	reactionMaps, err := GetReactionMaps(e, userLimit, []syntax.ATURI{threadAt})
+	if err != nil {
+		return nil, err
+	}
+
+	return reactionMaps[threadAt], nil
+}
+
+// GetReactionMaps fetches reactions for several subjects in one query, keyed
+// on the subject's AT-URI. Subjects can be threads (issues, pulls) or their
+// comments; every requested subject is present in the returned map.
+func GetReactionMaps(e Execer, userLimit int, subjects []syntax.ATURI) (map[syntax.ATURI]map[models.ReactionKind]models.ReactionDisplayData, error) {
+	reactionMaps := make(map[syntax.ATURI]map[models.ReactionKind]models.ReactionDisplayData, len(subjects))
+	for _, subject := range subjects {
+		reactionMap := map[models.ReactionKind]models.ReactionDisplayData{}
+		for _, kind := range models.OrderedReactionKinds {
+			reactionMap[kind] = models.ReactionDisplayData{Count: 0, Users: []string{}}
+		}
+		reactionMaps[subject] = reactionMap
+	}
+
+	if len(subjects) == 0 {
+		return reactionMaps, nil
+	}
+
+	filter := FilterIn("thread_at", subjects)
+	query := fmt.Sprintf(`
+	select thread_at, kind, reacted_by_did,
+	       row_number() over (partition by thread_at, kind order by created asc, id asc) as rn,
+	       count(*) over (partition by thread_at, kind) as total
 	from reactions
-	where thread_at = ?
-	order by kind, created asc`
+	where %s
+	order by thread_at, kind, created asc, id asc`, filter.Condition())
 
-	rows, err := e.Query(query, threadAt)
+	rows, err := e.Query(query, filter.Arg()...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	reactionMap := map[models.ReactionKind]models.ReactionDisplayData{}
-	for _, kind := range models.OrderedReactionKinds {
-		reactionMap[kind] = models.ReactionDisplayData{Count: 0, Users: []string{}}
-	}
-
 	for rows.Next() {
+		var subject syntax.ATURI
 		var kind models.ReactionKind
 		var did string
 		var rn, total int
-		if err := rows.Scan(&kind, &did, &rn, &total); err != nil {
+		if err := rows.Scan(&subject, &kind, &did, &rn, &total); err != nil {
 			return nil, err
 		}
 
+		reactionMap, ok := reactionMaps[subject]
+		if !ok {
+			continue
+		}
+
 		data := reactionMap[kind]
 		data.Count = total
 		if userLimit > 0 && rn <= userLimit {
@@ -98,7 +126,7 @@ func GetReactionMap(e Execer, userLimit int, threadAt syntax.ATURI) (map[models.
 		reactionMap[kind] = data
 	}
 
-	return reactionMap, rows.Err()
+	return reactionMaps, rows.Err()
 }
 
 func GetReactionStatus(e Execer, userDid string, threadAt syntax.ATURI, kind models.ReactionKind) bool {
@@ -110,10 +138,55 @@ func GetReactionStatus(e Execer, userDid string, threadAt syntax.ATURI, kind mod
 }
 
 func GetReactionStatusMap(e Execer, userDid string, threadAt syntax.ATURI) map[models.ReactionKind]bool {
-	statusMap := map[models.ReactionKind]bool{}
-	for _, kind := range models.OrderedReactionKinds {
-		count := GetReactionStatus(e, userDid, threadAt, kind)
-		statusMap[kind] = count
+	statusMaps, err := GetReactionStatusMaps(e, userDid, []syntax.ATURI{threadAt})
+	if err != nil {
+		log.Println("failed to get reaction status for", threadAt, err)
+		return map[models.ReactionKind]bool{}
 	}
-	return statusMap
+
+	return statusMaps[threadAt]
+}
+
+// GetReactionStatusMaps reports which kinds the user has reacted with, for
+// each of the given subjects.
+func GetReactionStatusMaps(e Execer, userDid string, subjects []syntax.ATURI) (map[syntax.ATURI]map[models.ReactionKind]bool, error) {
+	statusMaps := make(map[syntax.ATURI]map[models.ReactionKind]bool, len(subjects))
+	for _, subject := range subjects {
+		statusMap := map[models.ReactionKind]bool{}
+		for _, kind := range models.OrderedReactionKinds {
+			statusMap[kind] = false
+		}
+		statusMaps[subject] = statusMap
+	}
+
+	if len(subjects) == 0 {
+		return statusMaps, nil
+	}
+
+	filter := FilterIn("thread_at", subjects)
+	query := fmt.Sprintf(
+		`select thread_at, kind from reactions where reacted_by_did = ? and %s`,
+		filter.Condition(),
+	)
+
+	args := append([]any{userDid}, filter.Arg()...)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subject syntax.ATURI
+		var kind models.ReactionKind
+		if err := rows.Scan(&subject, &kind); err != nil {
+			return nil, err
+		}
+
+		if statusMap, ok := statusMaps[subject]; ok {
+			statusMap[kind] = true
+		}
+	}
+
+	return statusMaps, rows.Err()
 }
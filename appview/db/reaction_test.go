@@ -0,0 +1,74 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func TestReactionsOnThreadAndComments(t *testing.T) {
+	d := createTestDB(t)
+
+	issueAt := syntax.ATURI("at://did:plc:alice/sh.tangled.repo.issue/3lissue")
+	commentAt := syntax.ATURI("at://did:plc:bob/sh.tangled.repo.issue.comment/3lcomment")
+
+	// three likes on the issue, one like and one rocket on its comment
+	for i, did := range []string{"did:plc:alice", "did:plc:bob", "did:plc:carol"} {
+		err := AddReaction(d, did, issueAt, models.Like, "issue-like-"+string(rune('a'+i)))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, AddReaction(d, "did:plc:carol", commentAt, models.Like, "comment-like"))
+	assert.NoError(t, AddReaction(d, "did:plc:carol", commentAt, models.Rocket, "comment-rocket"))
+
+	t.Run("counts are kept per subject", func(t *testing.T) {
+		maps, err := GetReactionMaps(d, 20, []syntax.ATURI{issueAt, commentAt})
+		assert.NoError(t, err)
+
+		assert.Equal(t, 3, maps[issueAt][models.Like].Count)
+		assert.Equal(t, 0, maps[issueAt][models.Rocket].Count)
+		assert.Equal(t, 1, maps[commentAt][models.Like].Count)
+		assert.Equal(t, 1, maps[commentAt][models.Rocket].Count)
+	})
+
+	t.Run("user limit applies per subject", func(t *testing.T) {
+		maps, err := GetReactionMaps(d, 2, []syntax.ATURI{issueAt, commentAt})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"did:plc:alice", "did:plc:bob"}, maps[issueAt][models.Like].Users)
+		assert.Equal(t, 3, maps[issueAt][models.Like].Count)
+		assert.Equal(t, []string{"did:plc:carol"}, maps[commentAt][models.Like].Users)
+	})
+
+	t.Run("single subject matches batch", func(t *testing.T) {
+		single, err := GetReactionMap(d, 20, commentAt)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, single[models.Like].Count)
+		assert.Equal(t, 1, single[models.Rocket].Count)
+	})
+
+	t.Run("status maps are kept per subject", func(t *testing.T) {
+		statuses, err := GetReactionStatusMaps(d, "did:plc:alice", []syntax.ATURI{issueAt, commentAt})
+		assert.NoError(t, err)
+		assert.True(t, statuses[issueAt][models.Like])
+		assert.False(t, statuses[commentAt][models.Like])
+
+		statuses, err = GetReactionStatusMaps(d, "did:plc:carol", []syntax.ATURI{issueAt, commentAt})
+		assert.NoError(t, err)
+		assert.True(t, statuses[issueAt][models.Like])
+		assert.False(t, statuses[issueAt][models.Rocket])
+		assert.True(t, statuses[commentAt][models.Like])
+		assert.True(t, statuses[commentAt][models.Rocket])
+
+		single := GetReactionStatusMap(d, "did:plc:carol", commentAt)
+		assert.Equal(t, statuses[commentAt], single)
+	})
+
+	t.Run("unknown subjects are empty", func(t *testing.T) {
+		other := syntax.ATURI("at://did:plc:dave/sh.tangled.repo.pull/3lpull")
+		maps, err := GetReactionMaps(d, 20, []syntax.ATURI{other})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, maps[other][models.Like].Count)
+	})
+}
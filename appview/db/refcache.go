@@ -0,0 +1,64 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+// GetRefCache returns the cached listing of kind for a repo, ok is false if
+// there is none or it is older than maxAge.
+func GetRefCache(e Execer, repoAt syntax.ATURI, kind models.RefCacheKind, maxAge time.Duration) (data []byte, ok bool, err error) {
+	var created string
+	err = e.QueryRow(
+		`select data, created from repo_ref_cache where repo_at = ? and kind = ?`,
+		repoAt,
+		kind,
+	).Scan(&data, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get ref cache: %w", err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil || time.Since(createdAt) > maxAge {
+		return nil, false, nil
+	}
+
+	return data, true, nil
+}
+
+func PutRefCache(e Execer, repoAt syntax.ATURI, kind models.RefCacheKind, data []byte) error {
+	_, err := e.Exec(
+		`insert or replace into repo_ref_cache (repo_at, kind, data) values (?, ?, ?)`,
+		repoAt,
+		kind,
+		data,
+	)
+	return err
+}
+
+func DeleteRefCache(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from repo_ref_cache %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
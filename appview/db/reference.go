@@ -0,0 +1,221 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+// ResolveReferences looks up issue and pull references, as written, and
+// returns the ones that exist keyed by how they were written. Numbered
+// references ('#12') are resolved within repoAt, issues are preferred over
+// pulls when both exist. Only references to public repos, and to the private
+// ones in readable, are resolved.
+func ResolveReferences(e Execer, repoAt syntax.ATURI, refs []string, readable []string) (map[string]models.Reference, error) {
+	cond, args := repoReadable(readable)
+	return resolveReferences(e, repoAt, refs, cond, args)
+}
+
+// repoReadable is the condition that the repo r is public or in readable.
+func repoReadable(readable []string) (string, []any) {
+	private := FilterIn("r.at_uri", readable)
+	return fmt.Sprintf("(r.visibility = 'public' or %s)", private.Condition()), private.Arg()
+}
+
+func resolveReferences(e Execer, repoAt syntax.ATURI, refs []string, visibility string, visibilityArgs []any) (map[string]models.Reference, error) {
+	resolved := make(map[string]models.Reference)
+
+	var numbers []int
+	var uris []string
+	for _, ref := range refs {
+		if n, ok := strings.CutPrefix(ref, "#"); ok {
+			if number, err := strconv.Atoi(n); err == nil {
+				numbers = append(numbers, number)
+			}
+			continue
+		}
+		if _, err := syntax.ParseATURI(ref); err == nil {
+			uris = append(uris, ref)
+		}
+	}
+
+	if len(numbers) == 0 && len(uris) == 0 {
+		return resolved, nil
+	}
+
+	issueNumbers := FilterIn("i.issue_id", numbers)
+	issueUris := FilterIn("i.at_uri", uris)
+	pullNumbers := FilterIn("p.pull_id", numbers)
+	pullUris := FilterIn("p.at_uri", uris)
+
+	query := fmt.Sprintf(`
+		select 'issue', i.at_uri, i.issue_id, i.title, i.repo_at, r.did, r.name
+		from issues i
+		join repos r on r.at_uri = i.repo_at
+		where ((i.repo_at = ? and %s) or %s) and %s
+
+		union all
+
+		select 'pull', p.at_uri, p.pull_id, p.title, p.repo_at, r.did, r.name
+		from pulls p
+		join repos r on r.at_uri = p.repo_at
+		where ((p.repo_at = ? and %s) or %s) and %s
+	`,
+		issueNumbers.Condition(), issueUris.Condition(), visibility,
+		pullNumbers.Condition(), pullUris.Condition(), visibility,
+	)
+
+	var args []any
+	args = append(args, repoAt)
+	args = append(args, issueNumbers.Arg()...)
+	args = append(args, issueUris.Arg()...)
+	args = append(args, visibilityArgs...)
+	args = append(args, repoAt)
+	args = append(args, pullNumbers.Arg()...)
+	args = append(args, pullUris.Arg()...)
+	args = append(args, visibilityArgs...)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query references: %w", err)
+	}
+	defer rows.Close()
+
+	numbered := make(map[string]models.Reference)
+	for rows.Next() {
+		var ref models.Reference
+		var refRepoAt string
+		if err := rows.Scan(&ref.Kind, &ref.AtUri, &ref.Number, &ref.Title, &refRepoAt, &ref.RepoDid, &ref.RepoName); err != nil {
+			return nil, fmt.Errorf("failed to scan reference: %w", err)
+		}
+
+		resolved[ref.AtUri.String()] = ref
+
+		if refRepoAt == repoAt.String() {
+			key := fmt.Sprintf("#%d", ref.Number)
+			if existing, ok := numbered[key]; !ok || existing.Kind != models.ReferenceKindIssue {
+				numbered[key] = ref
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// key the results by how they were written
+	out := make(map[string]models.Reference)
+	for _, ref := range refs {
+		if r, ok := numbered[ref]; ok {
+			out[ref] = r
+		} else if r, ok := resolved[ref]; ok {
+			out[ref] = r
+		}
+	}
+
+	return out, nil
+}
+
+// PutReferenceLinks resolves the references written in fromAt, which belongs
+// to the issue or pull threadAt in repoAt, and replaces the ones previously
+// recorded for it. References back to threadAt itself are not recorded.
+// References to private repos are recorded too, GetBacklinks leaves out
+// those the viewer can't read.
+func PutReferenceLinks(e Execer, repoAt, fromAt, threadAt syntax.ATURI, refs []string) error {
+	resolved, err := resolveReferences(e, repoAt, refs, "1 = 1", nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.Exec(`delete from reference_links where from_at = ?`, fromAt)
+	if err != nil {
+		return fmt.Errorf("failed to clear reference links: %w", err)
+	}
+
+	for _, ref := range resolved {
+		if ref.AtUri == threadAt {
+			continue
+		}
+
+		_, err := e.Exec(
+			`insert or ignore into reference_links (from_at, thread_at, to_at) values (?, ?, ?)`,
+			fromAt,
+			threadAt,
+			ref.AtUri,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert reference link: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func DeleteReferenceLinks(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from reference_links %s`, whereClause)
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+// GetBacklinks returns the issues and pulls that reference toAt, from their
+// body or any of their comments, in the order they first did so. Those in
+// private repos are left out unless they are in readable.
+func GetBacklinks(e Execer, toAt syntax.ATURI, readable []string) ([]models.Reference, error) {
+	visibility, visibilityArgs := repoReadable(readable)
+
+	var args []any
+	args = append(args, toAt)
+	args = append(args, visibilityArgs...)
+	args = append(args, toAt)
+	args = append(args, visibilityArgs...)
+
+	rows, err := e.Query(fmt.Sprintf(`
+		select kind, at_uri, number, title, repo_did, repo_name
+		from (
+			select 'issue' as kind, i.at_uri, i.issue_id as number, i.title, r.did as repo_did, r.name as repo_name, l.created
+			from reference_links l
+			join issues i on i.at_uri = l.thread_at
+			join repos r on r.at_uri = i.repo_at
+			where l.to_at = ? and %s
+
+			union all
+
+			select 'pull' as kind, p.at_uri, p.pull_id as number, p.title, r.did as repo_did, r.name as repo_name, l.created
+			from reference_links l
+			join pulls p on p.at_uri = l.thread_at
+			join repos r on r.at_uri = p.repo_at
+			where l.to_at = ? and %s
+		)
+		group by at_uri
+		order by min(created) asc
+	`, visibility, visibility), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backlinks: %w", err)
+	}
+	defer rows.Close()
+
+	var backlinks []models.Reference
+	for rows.Next() {
+		var ref models.Reference
+		if err := rows.Scan(&ref.Kind, &ref.AtUri, &ref.Number, &ref.Title, &ref.RepoDid, &ref.RepoName); err != nil {
+			return nil, fmt.Errorf("failed to scan backlink: %w", err)
+		}
+		backlinks = append(backlinks, ref)
+	}
+
+	return backlinks, rows.Err()
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func TestReferences(t *testing.T) {
+	d := createTestDB(t)
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+
+	repo := &models.Repo{Did: "did:plc:alice", Name: "core", Knot: "knot.example.com", Rkey: "3lrepo"}
+	assert.NoError(t, AddRepo(tx, repo))
+	other := &models.Repo{Did: "did:plc:bob", Name: "other", Knot: "knot.example.com", Rkey: "3lother"}
+	assert.NoError(t, AddRepo(tx, other))
+
+	// issue #1 and pull #1 share a number within the repo
+	issue := &models.Issue{RepoAt: repo.RepoAt(), Did: "did:plc:alice", Rkey: "3lissue", Title: "bug", Body: "see #1"}
+	assert.NoError(t, PutIssue(tx, issue))
+	pull := &models.Pull{
+		RepoAt:      repo.RepoAt(),
+		OwnerDid:    "did:plc:bob",
+		Rkey:        "3lpull",
+		Title:       "fix",
+		Body:        "fixes #1",
+		Submissions: []*models.PullSubmission{{}},
+	}
+	assert.NoError(t, NewPull(tx, pull))
+	second := &models.Pull{
+		RepoAt:      repo.RepoAt(),
+		OwnerDid:    "did:plc:bob",
+		Rkey:        "3lpull2",
+		Title:       "another fix",
+		Submissions: []*models.PullSubmission{{}},
+	}
+	assert.NoError(t, NewPull(tx, second))
+	elsewhere := &models.Issue{RepoAt: other.RepoAt(), Did: "did:plc:bob", Rkey: "3lelsewhere", Title: "elsewhere"}
+	assert.NoError(t, PutIssue(tx, elsewhere))
+	private := &models.Repo{Did: "did:plc:bob", Name: "private", Knot: "knot.example.com", Rkey: "3lprivate", Visibility: models.RepoVisibilityPrivate}
+	assert.NoError(t, AddRepo(tx, private))
+	secret := &models.Issue{RepoAt: private.RepoAt(), Did: "did:plc:bob", Rkey: "3lsecret", Title: "secret"}
+	assert.NoError(t, PutIssue(tx, secret))
+	assert.NoError(t, tx.Commit())
+
+	t.Run("numbers resolve within the repo", func(t *testing.T) {
+		refs, err := ResolveReferences(d, repo.RepoAt(), []string{"#1", "#2", "#3"}, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, models.ReferenceKindIssue, refs["#1"].Kind)
+		assert.Equal(t, issue.AtUri(), refs["#1"].AtUri)
+		assert.Equal(t, models.ReferenceKindPull, refs["#2"].Kind)
+		assert.Equal(t, "/did:plc:alice/core/pulls/2", refs["#2"].Href())
+		_, ok := refs["#3"]
+		assert.False(t, ok)
+	})
+
+	t.Run("at-uris resolve across repos", func(t *testing.T) {
+		uri := elsewhere.AtUri().String()
+		refs, err := ResolveReferences(d, repo.RepoAt(), []string{uri}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "/did:plc:bob/other/issues/1", refs[uri].Href())
+	})
+
+	t.Run("backlinks skip self references", func(t *testing.T) {
+		assert.NoError(t, PutReferenceLinks(d, repo.RepoAt(), issue.AtUri(), issue.AtUri(), []string{"#1"}))
+		assert.NoError(t, PutReferenceLinks(d, repo.RepoAt(), pull.AtUri(), pull.AtUri(), []string{"#1"}))
+
+		backlinks, err := GetBacklinks(d, issue.AtUri(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(backlinks))
+		assert.Equal(t, pull.AtUri(), backlinks[0].AtUri)
+		assert.Equal(t, models.ReferenceKindPull, backlinks[0].Kind)
+	})
+
+	t.Run("edits replace earlier references", func(t *testing.T) {
+		commentAt := syntax.ATURI("at://did:plc:carol/sh.tangled.repo.issue.comment/3lcomment")
+		assert.NoError(t, PutReferenceLinks(d, repo.RepoAt(), commentAt, issue.AtUri(), []string{"#2"}))
+
+		backlinks, err := GetBacklinks(d, second.AtUri(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(backlinks))
+		assert.Equal(t, issue.AtUri(), backlinks[0].AtUri)
+
+		assert.NoError(t, PutReferenceLinks(d, repo.RepoAt(), commentAt, issue.AtUri(), nil))
+		backlinks, err = GetBacklinks(d, second.AtUri(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(backlinks))
+	})
+
+	t.Run("private repos are left out unless readable", func(t *testing.T) {
+		uri := secret.AtUri().String()
+		refs, err := ResolveReferences(d, repo.RepoAt(), []string{uri}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(refs))
+
+		refs, err = ResolveReferences(d, repo.RepoAt(), []string{uri}, []string{private.RepoAt().String()})
+		assert.NoError(t, err)
+		assert.Equal(t, "/did:plc:bob/private/issues/1", refs[uri].Href())
+
+		assert.NoError(t, PutReferenceLinks(d, private.RepoAt(), secret.AtUri(), secret.AtUri(), []string{issue.AtUri().String()}))
+
+		backlinks, err := GetBacklinks(d, issue.AtUri(), nil)
+		assert.NoError(t, err)
+		for _, b := range backlinks {
+			assert.NotEqual(t, secret.AtUri(), b.AtUri)
+		}
+
+		backlinks, err = GetBacklinks(d, issue.AtUri(), []string{private.RepoAt().String()})
+		assert.NoError(t, err)
+		assert.True(t, slices.ContainsFunc(backlinks, func(b models.Reference) bool {
+			return b.AtUri == secret.AtUri()
+		}))
+	})
+}
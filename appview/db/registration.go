@@ -25,7 +25,7 @@ func GetRegistrations(e Execer, filters ...filter) ([]models.Registration, error
 	}
 
 	query := fmt.Sprintf(`
-		select id, domain, did, created, registered, needs_upgrade
+		select id, domain, did, created, registered, needs_upgrade, approval
 		from registrations
 		%s
 		order by created
@@ -44,7 +44,7 @@ func GetRegistrations(e Execer, filters ...filter) ([]models.Registration, error
 		var needsUpgrade int
 		var reg models.Registration
 
-		err = rows.Scan(&reg.Id, &reg.Domain, &reg.ByDid, &createdAt, &registeredAt, &needsUpgrade)
+		err = rows.Scan(&reg.Id, &reg.Domain, &reg.ByDid, &createdAt, &registeredAt, &needsUpgrade, &reg.Approval)
 		if err != nil {
 			return nil, err
 		}
@@ -86,11 +86,28 @@ func MarkRegistered(e Execer, filters ...filter) error {
 	return err
 }
 
-func AddKnot(e Execer, domain, did string) error {
+func AddKnot(e Execer, domain, did string, approval models.Approval) error {
 	_, err := e.Exec(`
-		insert into registrations (domain, did)
-		values (?, ?)
-	`, domain, did)
+		insert into registrations (domain, did, approval)
+		values (?, ?, ?)
+	`, domain, did, approval)
+	return err
+}
+
+func SetKnotApproval(e Execer, approval models.Approval, filters ...filter) error {
+	var conditions []string
+	args := []any{approval}
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	query := "update registrations set approval = ?"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+
+	_, err := e.Exec(query, args...)
 	return err
 }
 
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+// PutRelease creates a release, or updates it when the record already exists.
+func PutRelease(e Execer, release *models.Release) error {
+	var edited *string
+	if release.Edited != nil {
+		t := release.Edited.Format(time.RFC3339)
+		edited = &t
+	}
+
+	_, err := e.Exec(
+		`insert into releases (did, rkey, repo_at, tag, title, body, prerelease, created, edited)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		on conflict(did, rkey) do update set
+			tag = excluded.tag,
+			title = excluded.title,
+			body = excluded.body,
+			prerelease = excluded.prerelease,
+			edited = excluded.edited`,
+		release.Did,
+		release.Rkey,
+		release.RepoAt,
+		release.Tag,
+		release.Title,
+		release.Body,
+		release.Prerelease,
+		release.Created.Format(time.RFC3339),
+		edited,
+	)
+	return err
+}
+
+func DeleteRelease(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from releases %s`, whereClause)
+
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+// GetReleases returns releases matching filters, newest first.
+func GetReleases(e Execer, filters ...filter) ([]models.Release, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, did, rkey, repo_at, tag, title, body, prerelease, created, edited
+		from releases
+		%s
+		order by created desc, id desc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releases []models.Release
+	for rows.Next() {
+		var release models.Release
+		var created string
+		var edited sql.NullString
+		if err := rows.Scan(
+			&release.Id,
+			&release.Did,
+			&release.Rkey,
+			&release.RepoAt,
+			&release.Tag,
+			&release.Title,
+			&release.Body,
+			&release.Prerelease,
+			&created,
+			&edited,
+		); err != nil {
+			return nil, err
+		}
+
+		release.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			release.Created = t
+		}
+
+		if edited.Valid {
+			if t, err := time.Parse(time.RFC3339, edited.String); err == nil {
+				release.Edited = &t
+			}
+		}
+
+		releases = append(releases, release)
+	}
+
+	return releases, rows.Err()
+}
+
+func GetRelease(e Execer, filters ...filter) (*models.Release, error) {
+	releases, err := GetReleases(e, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	if releases == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if len(releases) != 1 {
+		return nil, fmt.Errorf("too many rows returned")
+	}
+
+	return &releases[0], nil
+}
+
+// GetLatestRelease returns the newest release of repoAt that is not a
+// pre-release.
+func GetLatestRelease(e Execer, repoAt syntax.ATURI) (*models.Release, error) {
+	releases, err := GetReleases(e, FilterEq("repo_at", repoAt), FilterEq("prerelease", 0))
+	if err != nil {
+		return nil, err
+	}
+
+	if releases == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return &releases[0], nil
+}
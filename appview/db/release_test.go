@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestLatestRelease(t *testing.T) {
+	d := createTestDB(t)
+
+	repo := &models.Repo{
+		Did:  "did:plc:alice",
+		Name: "project",
+		Knot: "knot.example.com",
+		Rkey: "3lrepo",
+	}
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+	assert.NoError(t, tx.Commit())
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, tag := range []string{"v1.0.0", "v1.1.0", "v2.0.0-rc1"} {
+		assert.NoError(t, PutRelease(d, &models.Release{
+			Did:        "did:plc:alice",
+			Rkey:       "3lrelease" + tag,
+			RepoAt:     repo.RepoAt(),
+			Tag:        tag,
+			Title:      tag,
+			Prerelease: tag == "v2.0.0-rc1",
+			Created:    created.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	latest, err := GetLatestRelease(d, repo.RepoAt())
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", latest.Tag)
+
+	// editing keeps a single release for the tag
+	latest.Title = "second release"
+	latest.Prerelease = true
+	assert.NoError(t, PutRelease(d, latest))
+
+	releases, err := GetReleases(d, FilterEq("repo_at", repo.RepoAt()))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(releases))
+
+	latest, err = GetLatestRelease(d, repo.RepoAt())
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", latest.Tag)
+}
@@ -0,0 +1,250 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pagination"
+)
+
+// AddReport files a report, unless its reporter already has an open one on
+// the same subject. It says whether the report was filed.
+func AddReport(e Execer, report *models.Report) (bool, error) {
+	result, err := e.Exec(
+		`insert or ignore into reports (reporter_did, subject, kind, reason, details)
+		values (?, ?, ?, ?, ?)`,
+		report.ReporterDid,
+		report.Subject,
+		report.Kind,
+		report.Reason,
+		report.Details,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add report: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	report.Id, err = result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetReports returns reports matching filters, newest first.
+func GetReports(e Execer, page pagination.Page, filters ...filter) ([]models.Report, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	limitClause := ""
+	if page.Limit > 0 {
+		limitClause = " limit ? offset ?"
+		args = append(args, page.Limit, page.Offset)
+	}
+
+	query := fmt.Sprintf(
+		`select id, reporter_did, subject, kind, reason, details, status, created, resolved_by, resolved
+		from reports
+		%s
+		order by created desc, id desc
+		%s`,
+		whereClause,
+		limitClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.Report
+	for rows.Next() {
+		var report models.Report
+		var created string
+		var resolvedBy, resolved sql.NullString
+		if err := rows.Scan(
+			&report.Id,
+			&report.ReporterDid,
+			&report.Subject,
+			&report.Kind,
+			&report.Reason,
+			&report.Details,
+			&report.Status,
+			&created,
+			&resolvedBy,
+			&resolved,
+		); err != nil {
+			return nil, err
+		}
+
+		report.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			report.Created = t
+		}
+		if resolvedBy.Valid {
+			report.ResolvedBy = &resolvedBy.String
+		}
+		if resolved.Valid {
+			if t, err := time.Parse(time.RFC3339, resolved.String); err == nil {
+				report.Resolved = &t
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+func GetReport(e Execer, id int64) (*models.Report, error) {
+	reports, err := GetReports(e, pagination.Page{}, FilterEq("id", id))
+	if err != nil {
+		return nil, err
+	}
+	if reports == nil {
+		return nil, sql.ErrNoRows
+	}
+	return &reports[0], nil
+}
+
+func CountReports(e Execer, filters ...filter) (int64, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`select count(1) from reports %s`, whereClause)
+	var count int64
+	err := e.QueryRow(query, args...).Scan(&count)
+	if !errors.Is(err, sql.ErrNoRows) && err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ResolveReports closes every open report on subject with status.
+func ResolveReports(e Execer, subject string, status models.ReportStatus, byDid string) error {
+	_, err := e.Exec(
+		`update reports
+		set status = ?, resolved_by = ?, resolved = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		where subject = ? and status = ?`,
+		status,
+		byDid,
+		subject,
+		models.ReportOpen,
+	)
+	return err
+}
+
+func AddTakedown(e Execer, takedown *models.Takedown) error {
+	_, err := e.Exec(
+		`insert or ignore into takedowns (subject, by_did) values (?, ?)`,
+		takedown.Subject,
+		takedown.ByDid,
+	)
+	return err
+}
+
+func DeleteTakedown(e Execer, subject string) error {
+	_, err := e.Exec(`delete from takedowns where subject = ?`, subject)
+	return err
+}
+
+// GetTakedowns returns the takedowns of subjects, keyed by subject.
+func GetTakedowns(e Execer, subjects []string) (map[string]models.Takedown, error) {
+	takedowns := make(map[string]models.Takedown)
+	if len(subjects) == 0 {
+		return takedowns, nil
+	}
+
+	filter := FilterIn("subject", subjects)
+	rows, err := e.Query(
+		fmt.Sprintf(`select subject, by_did, created from takedowns where %s`, filter.Condition()),
+		filter.Arg()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list, err := scanTakedowns(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, takedown := range list {
+		takedowns[takedown.Subject] = takedown
+	}
+
+	return takedowns, nil
+}
+
+// GetRecentTakedowns returns the latest takedowns, newest first.
+func GetRecentTakedowns(e Execer, limit int) ([]models.Takedown, error) {
+	rows, err := e.Query(
+		`select subject, by_did, created from takedowns order by created desc limit ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTakedowns(rows)
+}
+
+func scanTakedowns(rows *sql.Rows) ([]models.Takedown, error) {
+	var takedowns []models.Takedown
+	for rows.Next() {
+		var takedown models.Takedown
+		var created string
+		if err := rows.Scan(&takedown.Subject, &takedown.ByDid, &created); err != nil {
+			return nil, err
+		}
+
+		takedown.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			takedown.Created = t
+		}
+
+		takedowns = append(takedowns, takedown)
+	}
+
+	return takedowns, rows.Err()
+}
+
+func IsTakenDown(e Execer, subject string) bool {
+	takedowns, err := GetTakedowns(e, []string{subject})
+	if err != nil {
+		return false
+	}
+	_, ok := takedowns[subject]
+	return ok
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pagination"
+)
+
+func TestReports(t *testing.T) {
+	d := createTestDB(t)
+
+	subject := "at://did:plc:bob/sh.tangled.repo/3lrepo"
+	report := func(reporter string) bool {
+		filed, err := AddReport(d, &models.Report{
+			ReporterDid: reporter,
+			Subject:     subject,
+			Kind:        models.ReportKindOf(subject),
+			Reason:      models.ReportReason("spam"),
+		})
+		assert.NoError(t, err)
+		return filed
+	}
+
+	assert.True(t, report("did:plc:alice"))
+	assert.False(t, report("did:plc:alice"), "an open report is not filed twice")
+	assert.True(t, report("did:plc:carol"))
+
+	open, err := CountReports(d, FilterEq("status", models.ReportOpen))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), open)
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddTakedown(tx, &models.Takedown{Subject: subject, ByDid: "did:plc:mod"}))
+	assert.NoError(t, ResolveReports(tx, subject, models.ReportTakenDown, "did:plc:mod"))
+	assert.NoError(t, tx.Commit())
+
+	reports, err := GetReports(d, pagination.Page{}, FilterEq("subject", subject))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reports))
+	for _, r := range reports {
+		assert.Equal(t, models.ReportTakenDown, r.Status)
+		assert.NotZero(t, r.ResolvedBy)
+		assert.Equal(t, "did:plc:mod", *r.ResolvedBy)
+	}
+
+	// once the earlier report is closed, the same reporter may file again
+	assert.True(t, report("did:plc:alice"))
+
+	assert.True(t, IsTakenDown(d, subject))
+	assert.False(t, IsTakenDown(d, "did:plc:bob"))
+}
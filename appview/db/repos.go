@@ -1,6 +1,7 @@
 package db
 
 import (
+	"cmp"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -73,7 +74,15 @@ func GetRepos(e Execer, limit int, filters ...filter) ([]models.Repo, error) {
 			website,
 			topics,
 			source,
-			spindle
+			spindle,
+			visibility,
+			delete_branch_on_merge,
+			is_template,
+			pipelines_disabled,
+			pipeline_concurrency,
+			cancel_superseded_pipelines,
+			require_passing_pipeline,
+			(select to_did from repo_transfers t where t.repo_at = r.at_uri and t.status = 'pending')
 		from
 			repos r
 		%s
@@ -91,7 +100,7 @@ func GetRepos(e Execer, limit int, filters ...filter) ([]models.Repo, error) {
 	for rows.Next() {
 		var repo models.Repo
 		var createdAt string
-		var description, website, topicStr, source, spindle sql.NullString
+		var description, website, topicStr, source, spindle, transferTo sql.NullString
 
 		err := rows.Scan(
 			&repo.Id,
@@ -105,6 +114,14 @@ func GetRepos(e Execer, limit int, filters ...filter) ([]models.Repo, error) {
 			&topicStr,
 			&source,
 			&spindle,
+			&repo.Visibility,
+			&repo.DeleteBranchOnMerge,
+			&repo.IsTemplate,
+			&repo.PipelinesDisabled,
+			&repo.PipelineConcurrency,
+			&repo.CancelSupersededPipelines,
+			&repo.RequirePassingPipeline,
+			&transferTo,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute repo query: %w ", err)
@@ -128,6 +145,9 @@ func GetRepos(e Execer, limit int, filters ...filter) ([]models.Repo, error) {
 		if spindle.Valid {
 			repo.Spindle = spindle.String
 		}
+		if transferTo.Valid {
+			repo.TransferTo = transferTo.String
+		}
 
 		repo.RepoStats = &models.RepoStats{}
 		repoMap[repo.RepoAt()] = &repo
@@ -394,20 +414,25 @@ func GetRepoByAtUri(e Execer, atUri string) (*models.Repo, error) {
 func PutRepo(tx *sql.Tx, repo models.Repo) error {
 	_, err := tx.Exec(
 		`update repos
-		set knot = ?, description = ?, website = ?, topics = ?
+		set knot = ?, description = ?, website = ?, topics = ?, visibility = ?
 		where did = ? and rkey = ?
 		`,
-		repo.Knot, repo.Description, repo.Website, repo.TopicStr(), repo.Did, repo.Rkey,
+		repo.Knot, repo.Description, repo.Website, repo.TopicStr(), repo.Visibility, repo.Did, repo.Rkey,
 	)
 	return err
 }
 
 func AddRepo(tx *sql.Tx, repo *models.Repo) error {
+	visibility := repo.Visibility
+	if visibility == "" {
+		visibility = models.RepoVisibilityPublic
+	}
+
 	_, err := tx.Exec(
 		`insert into repos
-		(did, name, knot, rkey, at_uri, description, website, topics, source)
-		values (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		repo.Did, repo.Name, repo.Knot, repo.Rkey, repo.RepoAt().String(), repo.Description, repo.Website, repo.TopicStr(), repo.Source,
+		(did, name, knot, rkey, at_uri, description, website, topics, source, visibility)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		repo.Did, repo.Name, repo.Knot, repo.Rkey, repo.RepoAt().String(), repo.Description, repo.Website, repo.TopicStr(), repo.Source, visibility,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert repo: %w", err)
@@ -439,6 +464,84 @@ func GetRepoSource(e Execer, repoAt syntax.ATURI) (string, error) {
 	return nullableSource.String, nil
 }
 
+// GetForkCount counts the public forks of repoAt.
+func GetForkCount(e Execer, repoAt syntax.ATURI) (int, error) {
+	forks := 0
+	err := e.QueryRow(
+		`select count(1) from repos where source = ? and visibility = ?`,
+		repoAt.String(),
+		models.RepoVisibilityPublic,
+	).Scan(&forks)
+	if err != nil {
+		return 0, err
+	}
+	return forks, nil
+}
+
+// GetForkTree returns the forks of root and their forks, down to maxDepth
+// levels and at most limit repos, shallowest and then oldest first. Each
+// returned node is a direct fork of root.
+func GetForkTree(e Execer, root syntax.ATURI, maxDepth, limit int) ([]*models.ForkNode, error) {
+	rows, err := e.Query(
+		`with recursive tree(at_uri, depth) as (
+			select at_uri, 1 from repos where source = ?
+			union all
+			select r.at_uri, t.depth + 1
+			from repos r
+			join tree t on r.source = t.at_uri
+			where t.depth < ?
+		)
+		select at_uri from tree
+		order by depth
+		limit ?`,
+		root.String(),
+		maxDepth,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ats []string
+	for rows.Next() {
+		var at string
+		if err := rows.Scan(&at); err != nil {
+			return nil, err
+		}
+		ats = append(ats, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	repos, err := GetRepos(e, 0, FilterIn("at_uri", ats))
+	if err != nil {
+		return nil, err
+	}
+	// repos created in the same second keep the order they were added in
+	slices.SortFunc(repos, func(a, b models.Repo) int {
+		return cmp.Or(a.Created.Compare(b.Created), cmp.Compare(a.Id, b.Id))
+	})
+
+	nodes := make(map[string]*models.ForkNode, len(repos))
+	for _, repo := range repos {
+		nodes[repo.RepoAt().String()] = &models.ForkNode{Repo: repo}
+	}
+
+	var roots []*models.ForkNode
+	for _, repo := range repos {
+		node := nodes[repo.RepoAt().String()]
+		if repo.Source == root.String() {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[repo.Source]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots, nil
+}
+
 func GetForksByDid(e Execer, did string) ([]models.Repo, error) {
 	var repos []models.Repo
 
@@ -552,6 +655,37 @@ func UpdateSpindle(e Execer, repoAt string, spindle *string) error {
 	return err
 }
 
+func UpdateDeleteBranchOnMerge(e Execer, repoAt string, enabled bool) error {
+	_, err := e.Exec(
+		`update repos set delete_branch_on_merge = ? where at_uri = ?`, enabled, repoAt)
+	return err
+}
+
+func UpdatePipelinesDisabled(e Execer, repoAt string, disabled bool) error {
+	_, err := e.Exec(
+		`update repos set pipelines_disabled = ? where at_uri = ?`, disabled, repoAt)
+	return err
+}
+
+func UpdatePipelineQueue(e Execer, repoAt string, concurrency int, cancelSuperseded bool) error {
+	_, err := e.Exec(
+		`update repos set pipeline_concurrency = ?, cancel_superseded_pipelines = ? where at_uri = ?`,
+		concurrency, cancelSuperseded, repoAt)
+	return err
+}
+
+func UpdateRequirePassingPipeline(e Execer, repoAt string, required bool) error {
+	_, err := e.Exec(
+		`update repos set require_passing_pipeline = ? where at_uri = ?`, required, repoAt)
+	return err
+}
+
+func UpdateIsTemplate(e Execer, repoAt string, isTemplate bool) error {
+	_, err := e.Exec(
+		`update repos set is_template = ? where at_uri = ?`, isTemplate, repoAt)
+	return err
+}
+
 func SubscribeLabel(e Execer, rl *models.RepoLabel) error {
 	query := `insert or ignore into repo_labels (repo_at, label_at) values (?, ?)`
 
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestGetForkTree(t *testing.T) {
+	d := createTestDB(t)
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// forks are listed oldest first
+	add := func(did, name, source string) *models.Repo {
+		repo := addTestRepo(t, d, &models.Repo{Did: did, Name: name, Source: source, Created: created})
+		created = created.Add(time.Hour)
+		return repo
+	}
+
+	root := add("did:plc:alice", "project", "")
+	bob := add("did:plc:bob", "project-bob", root.RepoAt().String())
+	add("did:plc:carol", "project-carol", root.RepoAt().String())
+	dave := add("did:plc:dave", "project-dave", bob.RepoAt().String())
+	add("did:plc:erin", "project-erin", dave.RepoAt().String())
+
+	tree, err := GetForkTree(d, root.RepoAt(), 2, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tree))
+	assert.Equal(t, "project-bob", tree[0].Repo.Name)
+	assert.Equal(t, "project-carol", tree[1].Repo.Name)
+	assert.Equal(t, 1, len(tree[0].Children))
+	assert.Equal(t, "project-dave", tree[0].Children[0].Repo.Name)
+
+	// erin is past the depth limit
+	assert.Equal(t, 0, len(tree[0].Children[0].Children))
+}
+
+func TestPipelinesDisabled(t *testing.T) {
+	d := createTestDB(t)
+
+	repo := &models.Repo{
+		Did:     "did:plc:alice",
+		Name:    "project",
+		Knot:    "knot.example.com",
+		Rkey:    "3lproject",
+		Spindle: "spindle.example.com",
+		Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, UpdateSpindle(d, repo.RepoAt().String(), &repo.Spindle))
+
+	assert.NoError(t, UpdatePipelinesDisabled(d, repo.RepoAt().String(), true))
+
+	got, err := GetRepo(d, FilterEq("at_uri", repo.RepoAt().String()))
+	assert.NoError(t, err)
+	assert.True(t, got.PipelinesDisabled)
+	assert.Equal(t, "spindle.example.com", got.Spindle)
+
+	// the spindle is kept, but left out of the record
+	assert.Zero(t, got.AsRecord().Spindle)
+}
+
+func TestPipelineQueue(t *testing.T) {
+	d := createTestDB(t)
+
+	repo := &models.Repo{
+		Did:     "did:plc:alice",
+		Name:    "project",
+		Knot:    "knot.example.com",
+		Rkey:    "3lproject",
+		Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+	assert.NoError(t, tx.Commit())
+
+	// the spindle picks the limit until the repo sets one
+	got, err := GetRepo(d, FilterEq("at_uri", repo.RepoAt().String()))
+	assert.NoError(t, err)
+	assert.Zero(t, got.AsRecord().PipelineConcurrency)
+	assert.Zero(t, got.AsRecord().CancelSupersededPipelines)
+
+	assert.NoError(t, UpdatePipelineQueue(d, repo.RepoAt().String(), 3, true))
+
+	got, err = GetRepo(d, FilterEq("at_uri", repo.RepoAt().String()))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got.PipelineConcurrency)
+	assert.True(t, got.CancelSupersededPipelines)
+
+	record := got.AsRecord()
+	assert.Equal(t, int64(3), *record.PipelineConcurrency)
+	assert.True(t, *record.CancelSupersededPipelines)
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pagination"
+)
+
+// likeEscaper escapes the wildcards of a like pattern, for use with
+// `escape '\'`.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchRepos finds public repos, and the private ones in readable, whose
+// name, description or topics contain keyword. Name matches rank first, then
+// newer repos. It also returns the total number of matches.
+func SearchRepos(e Execer, keyword string, readable []string, page pagination.Page) ([]models.Repo, int, error) {
+	escaped := likeEscaper.Replace(keyword)
+	contains := "%" + escaped + "%"
+
+	visibility := FilterIn("at_uri", readable)
+	whereClause := fmt.Sprintf(
+		`where (name like ? escape '\' or description like ? escape '\' or topics like ? escape '\')
+		and (visibility = 'public' or %s)`,
+		visibility.Condition(),
+	)
+	whereArgs := append([]any{contains, contains, contains}, visibility.Arg()...)
+
+	var total int
+	err := e.QueryRow(`select count(1) from repos `+whereClause, whereArgs...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(
+		`select at_uri from repos
+		%s
+		order by
+			case
+				when name = ? collate nocase then 0
+				when name like ? escape '\' then 1
+				when name like ? escape '\' then 2
+				else 3
+			end,
+			created desc
+		limit ? offset ?`,
+		whereClause,
+	)
+	args := append(whereArgs, keyword, escaped+"%", contains, page.Limit, page.Offset)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var repoAts []string
+	for rows.Next() {
+		var repoAt string
+		if err := rows.Scan(&repoAt); err != nil {
+			return nil, 0, err
+		}
+		repoAts = append(repoAts, repoAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	repos, err := GetRepos(e, 0, FilterIn("at_uri", repoAts))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// back into rank order
+	byAt := make(map[string]models.Repo, len(repos))
+	for _, repo := range repos {
+		byAt[repo.RepoAt().String()] = repo
+	}
+	ranked := make([]models.Repo, 0, len(repos))
+	for _, repoAt := range repoAts {
+		if repo, ok := byAt[repoAt]; ok {
+			ranked = append(ranked, repo)
+		}
+	}
+
+	return ranked, total, nil
+}
+
+// SearchHandles finds the dids of recorded handles that contain keyword.
+// Exact and prefix matches rank first, then handles seen more recently. It
+// also returns the total number of matching dids.
+func SearchHandles(e Execer, keyword string, page pagination.Page) ([]string, int, error) {
+	escaped := likeEscaper.Replace(strings.ToLower(keyword))
+	contains := "%" + escaped + "%"
+
+	var total int
+	err := e.QueryRow(
+		`select count(distinct did) from handle_history where handle like ? escape '\'`,
+		contains,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := e.Query(
+		`select did from handle_history
+		where handle like ? escape '\'
+		group by did
+		order by
+			min(case
+				when handle = ? then 0
+				when handle like ? escape '\' then 1
+				else 2
+			end),
+			max(seen) desc
+		limit ? offset ?`,
+		contains, strings.ToLower(keyword), escaped+"%", page.Limit, page.Offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var dids []string
+	for rows.Next() {
+		var did string
+		if err := rows.Scan(&did); err != nil {
+			return nil, 0, err
+		}
+		dids = append(dids, did)
+	}
+
+	return dids, total, rows.Err()
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pagination"
+)
+
+func TestSearchRepos(t *testing.T) {
+	d := createTestDB(t)
+
+	add := func(did, name, description string, visibility models.RepoVisibility) *models.Repo {
+		return addTestRepo(t, d, &models.Repo{Did: did, Name: name, Description: description, Visibility: visibility})
+	}
+
+	add("did:plc:alice", "tools", "a parser for bleve queries", models.RepoVisibilityPublic)
+	add("did:plc:bob", "parser-utils", "", models.RepoVisibilityPublic)
+	add("did:plc:carol", "Parser", "", models.RepoVisibilityPublic)
+	secret := add("did:plc:dave", "secret-parser", "", models.RepoVisibilityPrivate)
+	add("did:plc:erin", "100%_done", "", models.RepoVisibilityPublic)
+
+	repos, total, err := SearchRepos(d, "parser", nil, pagination.FirstPage())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, "Parser", repos[0].Name)
+	assert.Equal(t, "parser-utils", repos[1].Name)
+	assert.Equal(t, "tools", repos[2].Name)
+
+	// private repos only show up for those who can read them
+	repos, total, err = SearchRepos(d, "parser", []string{secret.RepoAt().String()}, pagination.Page{Limit: 1, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, "secret-parser", repos[0].Name)
+
+	// wildcards are literal
+	_, total, err = SearchRepos(d, "%_", nil, pagination.FirstPage())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
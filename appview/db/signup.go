@@ -1,6 +1,8 @@
 package db
 
 import (
+	"time"
+
 	"tangled.org/core/appview/models"
 )
 
@@ -22,3 +24,70 @@ func GetEmailForCode(e Execer, inviteCode string) (string, error) {
 	err := e.QueryRow(query, inviteCode).Scan(&email)
 	return email, err
 }
+
+// GetInflightSignups returns the latest signups that are waiting on their
+// email to be verified, newest first.
+func GetInflightSignups(e Execer, limit int) ([]models.InflightSignup, error) {
+	rows, err := e.Query(
+		`select id, email, invite_code, created from signups_inflight order by created desc limit ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var signups []models.InflightSignup
+	for rows.Next() {
+		var signup models.InflightSignup
+		var created string
+		if err := rows.Scan(&signup.Id, &signup.Email, &signup.InviteCode, &created); err != nil {
+			return nil, err
+		}
+
+		signup.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			signup.Created = t
+		}
+
+		signups = append(signups, signup)
+	}
+
+	return signups, rows.Err()
+}
+
+// GetRecentSignups returns the primary emails most recently added to
+// accounts, newest first. Every signup through this instance adds one, so
+// these are the accounts that joined last.
+func GetRecentSignups(e Execer, limit int) ([]models.Email, error) {
+	rows, err := e.Query(
+		`select id, did, email, verified, is_primary, created
+		from emails
+		where is_primary = 1
+		order by created desc, id desc
+		limit ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []models.Email
+	for rows.Next() {
+		var email models.Email
+		var created string
+		if err := rows.Scan(&email.ID, &email.Did, &email.Address, &email.Verified, &email.Primary, &created); err != nil {
+			return nil, err
+		}
+
+		email.CreatedAt = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			email.CreatedAt = t
+		}
+
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -25,7 +26,7 @@ func GetSpindles(e Execer, filters ...filter) ([]models.Spindle, error) {
 	}
 
 	query := fmt.Sprintf(
-		`select id, owner, instance, verified, created, needs_upgrade
+		`select id, owner, instance, verified, created, needs_upgrade, approval
 		from spindles
 		%s
 		order by created
@@ -53,6 +54,7 @@ func GetSpindles(e Execer, filters ...filter) ([]models.Spindle, error) {
 			&verified,
 			&createdAt,
 			&needsUpgrade,
+			&spindle.Approval,
 		); err != nil {
 			return nil, err
 		}
@@ -84,13 +86,57 @@ func GetSpindles(e Execer, filters ...filter) ([]models.Spindle, error) {
 // if there is an existing spindle with the same instance, this returns an error
 func AddSpindle(e Execer, spindle models.Spindle) error {
 	_, err := e.Exec(
-		`insert into spindles (owner, instance) values (?, ?)`,
+		`insert into spindles (owner, instance, approval) values (?, ?, ?)`,
 		spindle.Owner,
 		spindle.Instance,
+		spindle.Approval,
 	)
 	return err
 }
 
+func SetSpindleApproval(e Execer, approval models.Approval, filters ...filter) error {
+	var conditions []string
+	args := []any{approval}
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	query := "update spindles set approval = ?"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+// ApprovedSpindles returns those of instances that are verified and
+// approved, the ones repos may run their pipelines on.
+func ApprovedSpindles(e Execer, instances []string) ([]string, error) {
+	if len(instances) == 0 {
+		return nil, nil
+	}
+
+	spindles, err := GetSpindles(
+		e,
+		FilterIn("instance", instances),
+		FilterIsNot("verified", nil),
+		FilterEq("approval", models.ApprovalApproved),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var approved []string
+	for _, s := range spindles {
+		if !slices.Contains(approved, s.Instance) {
+			approved = append(approved, s.Instance)
+		}
+	}
+	return approved, nil
+}
+
 func VerifySpindle(e Execer, filters ...filter) (int64, error) {
 	var conditions []string
 	var args []any
@@ -11,6 +11,7 @@ import (
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pagination"
 )
 
 func AddStar(e Execer, star *models.Star) error {
@@ -62,16 +63,73 @@ func DeleteStarByRkey(e Execer, did string, rkey string) error {
 	return err
 }
 
+// GetStarCount counts who starred subjectAt. Someone starring from two tabs
+// at once can end up with two star records, they count once.
 func GetStarCount(e Execer, subjectAt syntax.ATURI) (int, error) {
 	stars := 0
 	err := e.QueryRow(
-		`select count(did) from stars where subject_at = ?`, subjectAt).Scan(&stars)
+		`select count(distinct did) from stars where subject_at = ?`, subjectAt).Scan(&stars)
 	if err != nil {
 		return 0, err
 	}
 	return stars, nil
 }
 
+// GetStars returns stars matching filters, newest first. A zero page
+// returns all of them.
+func GetStars(e Execer, page pagination.Page, filters ...filter) ([]models.Star, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	pageClause := ""
+	if page.Limit > 0 {
+		pageClause = " limit ? offset ?"
+		args = append(args, page.Limit, page.Offset)
+	}
+
+	query := fmt.Sprintf(
+		`select did, subject_at, created, rkey
+		from stars
+		%s
+		order by created desc, id desc
+		%s`,
+		whereClause,
+		pageClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stars []models.Star
+	for rows.Next() {
+		var star models.Star
+		var created string
+		if err := rows.Scan(&star.Did, &star.RepoAt, &created, &star.Rkey); err != nil {
+			return nil, err
+		}
+
+		star.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			star.Created = t
+		}
+
+		stars = append(stars, star)
+	}
+
+	return stars, rows.Err()
+}
+
 // getStarStatuses returns a map of repo URIs to star status for a given user
 // This is an internal helper function to avoid N+1 queries
 func getStarStatuses(e Execer, userDid string, repoAts []syntax.ATURI) (map[string]bool, error) {
@@ -257,6 +315,7 @@ func GetTopStarredReposLastWeek(e Execer) ([]models.Repo, error) {
 			select distinct subject_at
 			from stars
 			where created >= datetime('now', '-7 days')
+				and subject_at in (select at_uri from repos where visibility = 'public')
 		),
 		repo_star_counts as (
 			select
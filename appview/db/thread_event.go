@@ -0,0 +1,177 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+// AddThreadEvent records a change of state of an issue or pull. Label and
+// reference events are not stored, see models.ThreadEvent.
+func AddThreadEvent(e Execer, event models.ThreadEvent) error {
+	created := event.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	_, err := e.Exec(
+		`insert into thread_events (thread_at, kind, did, created) values (?, ?, ?, ?)`,
+		event.ThreadAt,
+		event.Kind,
+		event.Did,
+		created.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func GetThreadEvents(e Execer, filters ...filter) ([]models.ThreadEvent, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, thread_at, kind, did, created
+		from thread_events %s
+		order by created asc, id asc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ThreadEvent
+	for rows.Next() {
+		var event models.ThreadEvent
+		var created string
+		if err := rows.Scan(
+			&event.Id,
+			&event.ThreadAt,
+			&event.Kind,
+			&event.Did,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			event.Created = t
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func DeleteThreadEvents(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	_, err := e.Exec(`delete from thread_events`+whereClause, args...)
+	return err
+}
+
+// GetReferenceEvents returns an event for each issue or pull that references
+// toAt, from when and by whom it first did so.
+func GetReferenceEvents(e Execer, toAt syntax.ATURI) ([]models.ThreadEvent, error) {
+	rows, err := e.Query(`
+		select kind, at_uri, number, title, repo_did, repo_name, from_at, min(created)
+		from (
+			select 'issue' as kind, i.at_uri, i.issue_id as number, i.title, r.did as repo_did, r.name as repo_name, l.from_at, l.created
+			from reference_links l
+			join issues i on i.at_uri = l.thread_at
+			join repos r on r.at_uri = i.repo_at
+			where l.to_at = ?
+
+			union all
+
+			select 'pull' as kind, p.at_uri, p.pull_id as number, p.title, r.did as repo_did, r.name as repo_name, l.from_at, l.created
+			from reference_links l
+			join pulls p on p.at_uri = l.thread_at
+			join repos r on r.at_uri = p.repo_at
+			where l.to_at = ?
+		)
+		group by at_uri
+		order by min(created) asc
+	`, toAt, toAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reference events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.ThreadEvent
+	for rows.Next() {
+		var ref models.Reference
+		var fromAt, created string
+		if err := rows.Scan(&ref.Kind, &ref.AtUri, &ref.Number, &ref.Title, &ref.RepoDid, &ref.RepoName, &fromAt, &created); err != nil {
+			return nil, fmt.Errorf("failed to scan reference event: %w", err)
+		}
+
+		event := models.ThreadEvent{
+			ThreadAt:  toAt,
+			Kind:      models.ThreadEventReferenced,
+			Reference: &ref,
+		}
+		// whoever wrote the body or comment the reference is in
+		if aturi, err := syntax.ParseATURI(fromAt); err == nil {
+			event.Did = aturi.Authority().String()
+		}
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			event.Created = t
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetTimelineEvents gathers all events on the issue or pull threadAt: its
+// changes of state, the label ops on it, and the references to it.
+func GetTimelineEvents(e Execer, threadAt syntax.ATURI, defs map[string]*models.LabelDefinition) ([]models.ThreadEvent, error) {
+	events, err := GetThreadEvents(e, FilterEq("thread_at", threadAt))
+	if err != nil {
+		return nil, err
+	}
+
+	ops, err := GetLabelOps(e, FilterEq("subject", threadAt))
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, models.LabelEvents(threadAt, ops, defs)...)
+
+	references, err := GetReferenceEvents(e, threadAt)
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, references...)
+
+	return events, nil
+}
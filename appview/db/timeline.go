@@ -1,41 +1,88 @@
 package db
 
 import (
+	"slices"
 	"sort"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"tangled.org/core/appview/models"
 )
 
+// MakeTimeline gives the newest limit events of feed that happened before
+// before, or the newest overall if before is zero. Each source is asked for
+// at most limit events, so paging by the time of the last event is exact.
+//
 // TODO: this gathers heterogenous events from different sources and aggregates
 // them in code; if we did this entirely in sql, we could order and limit and paginate easily
-func MakeTimeline(e Execer, limit int, loggedInUserDid string, limitToUsersIsFollowing bool) ([]models.TimelineEvent, error) {
+func MakeTimeline(e Execer, limit int, loggedInUserDid string, feed models.TimelineFeed, before time.Time) ([]models.TimelineEvent, error) {
 	var events []models.TimelineEvent
 
-	var userIsFollowing []string
-	if limitToUsersIsFollowing {
+	// each source is queried once per set of filters, and the results are
+	// merged; the global feed has a single, empty set
+	repoSets := [][]filter{nil}
+	starSets := [][]filter{nil}
+	followSets := [][]filter{nil}
+
+	if feed == models.TimelineFollowing {
 		following, err := GetFollowing(e, loggedInUserDid)
 		if err != nil {
 			return nil, err
 		}
-
-		userIsFollowing = make([]string, 0, len(following))
+		followingDids := make([]string, 0, len(following))
 		for _, follow := range following {
-			userIsFollowing = append(userIsFollowing, follow.SubjectDid)
+			followingDids = append(followingDids, follow.SubjectDid)
+		}
+
+		watches, err := GetWatches(e, FilterEq("did", loggedInUserDid))
+		if err != nil {
+			return nil, err
+		}
+		watching := make([]string, 0, len(watches))
+		for _, watch := range watches {
+			watching = append(watching, watch.RepoAt.String())
+		}
+
+		// what the people followed did, and what others did on watched
+		// repos; what the user did themselves is left out
+		notMine := FilterNotEq("did", loggedInUserDid)
+		repoSets = [][]filter{
+			{FilterIn("did", followingDids)},
+			{FilterIn("source", watching), notMine},
+		}
+		starSets = [][]filter{
+			{FilterIn("did", followingDids)},
+			{FilterIn("subject_at", watching), notMine},
+		}
+		followSets = [][]filter{
+			{FilterIn("user_did", followingDids)},
 		}
 	}
 
-	repos, err := getTimelineRepos(e, limit, loggedInUserDid, userIsFollowing)
+	if !before.IsZero() {
+		cursor := before.UTC().Format(time.RFC3339)
+		for i := range repoSets {
+			repoSets[i] = append(repoSets[i], FilterLt("created", cursor))
+		}
+		for i := range starSets {
+			starSets[i] = append(starSets[i], FilterLt("created", cursor))
+		}
+		for i := range followSets {
+			followSets[i] = append(followSets[i], FilterLt("followed_at", cursor))
+		}
+	}
+
+	repos, err := getTimelineRepos(e, limit, loggedInUserDid, repoSets)
 	if err != nil {
 		return nil, err
 	}
 
-	stars, err := getTimelineStars(e, limit, loggedInUserDid, userIsFollowing)
+	stars, err := getTimelineStars(e, limit, loggedInUserDid, starSets)
 	if err != nil {
 		return nil, err
 	}
 
-	follows, err := getTimelineFollows(e, limit, loggedInUserDid, userIsFollowing)
+	follows, err := getTimelineFollows(e, limit, loggedInUserDid, followSets)
 	if err != nil {
 		return nil, err
 	}
@@ -83,15 +130,21 @@ func getRepoStarInfo(repo *models.Repo, starStatuses map[string]bool) (bool, int
 	return isStarred, starCount
 }
 
-func getTimelineRepos(e Execer, limit int, loggedInUserDid string, userIsFollowing []string) ([]models.TimelineEvent, error) {
-	filters := make([]filter, 0)
-	if userIsFollowing != nil {
-		filters = append(filters, FilterIn("did", userIsFollowing))
-	}
-
-	repos, err := GetRepos(e, limit, filters...)
-	if err != nil {
-		return nil, err
+func getTimelineRepos(e Execer, limit int, loggedInUserDid string, filterSets [][]filter) ([]models.TimelineEvent, error) {
+	var repos []models.Repo
+	seen := make(map[syntax.ATURI]bool)
+	for _, filters := range filterSets {
+		filters = append(filters, FilterEq("visibility", models.RepoVisibilityPublic))
+		set, err := GetRepos(e, limit, filters...)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range set {
+			if !seen[r.RepoAt()] {
+				seen[r.RepoAt()] = true
+				repos = append(repos, r)
+			}
+		}
 	}
 
 	// fetch all source repos
@@ -103,6 +156,7 @@ func getTimelineRepos(e Execer, limit int, loggedInUserDid string, userIsFollowi
 	}
 
 	var origRepos []models.Repo
+	var err error
 	if args != nil {
 		origRepos, err = GetRepos(e, 0, FilterIn("at_uri", args))
 	}
@@ -143,16 +197,27 @@ func getTimelineRepos(e Execer, limit int, loggedInUserDid string, userIsFollowi
 	return events, nil
 }
 
-func getTimelineStars(e Execer, limit int, loggedInUserDid string, userIsFollowing []string) ([]models.TimelineEvent, error) {
-	filters := make([]filter, 0)
-	if userIsFollowing != nil {
-		filters = append(filters, FilterIn("did", userIsFollowing))
+func getTimelineStars(e Execer, limit int, loggedInUserDid string, filterSets [][]filter) ([]models.TimelineEvent, error) {
+	var stars []models.RepoStar
+	seen := make(map[string]bool)
+	for _, filters := range filterSets {
+		set, err := GetRepoStars(e, limit, filters...)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range set {
+			key := s.Did + " " + s.RepoAt.String()
+			if !seen[key] {
+				seen[key] = true
+				stars = append(stars, s)
+			}
+		}
 	}
 
-	stars, err := GetRepoStars(e, limit, filters...)
-	if err != nil {
-		return nil, err
-	}
+	// stars give away private repos
+	stars = slices.DeleteFunc(stars, func(s models.RepoStar) bool {
+		return s.Repo.IsPrivate()
+	})
 
 	var repos []models.Repo
 	for _, s := range stars {
@@ -179,15 +244,14 @@ func getTimelineStars(e Execer, limit int, loggedInUserDid string, userIsFollowi
 	return events, nil
 }
 
-func getTimelineFollows(e Execer, limit int, loggedInUserDid string, userIsFollowing []string) ([]models.TimelineEvent, error) {
-	filters := make([]filter, 0)
-	if userIsFollowing != nil {
-		filters = append(filters, FilterIn("user_did", userIsFollowing))
-	}
-
-	follows, err := GetFollows(e, limit, filters...)
-	if err != nil {
-		return nil, err
+func getTimelineFollows(e Execer, limit int, loggedInUserDid string, filterSets [][]filter) ([]models.TimelineEvent, error) {
+	var follows []models.Follow
+	for _, filters := range filterSets {
+		set, err := GetFollows(e, limit, filters...)
+		if err != nil {
+			return nil, err
+		}
+		follows = append(follows, set...)
 	}
 
 	var subjects []string
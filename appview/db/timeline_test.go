@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestFollowingTimeline(t *testing.T) {
+	d := createTestDB(t)
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(did, name, source string) *models.Repo {
+		repo := addTestRepo(t, d, &models.Repo{Did: did, Name: name, Source: source, Created: created, Visibility: models.RepoVisibilityPublic})
+		created = created.Add(time.Hour)
+		return repo
+	}
+
+	watched := add("did:plc:carol", "watched", "")
+	add("did:plc:bob", "first", "")
+	add("did:plc:dave", "fork", watched.RepoAt().String())
+	add("did:plc:erin", "unrelated", "")
+	add("did:plc:alice", "own-fork", watched.RepoAt().String())
+	add("did:plc:bob", "second", "")
+
+	assert.NoError(t, AddFollow(d, &models.Follow{UserDid: "did:plc:alice", SubjectDid: "did:plc:bob", Rkey: "3lfollow"}))
+	assert.NoError(t, AddWatch(d, &models.Watch{Did: "did:plc:alice", RepoAt: watched.RepoAt(), Rkey: "3lwatch"}))
+
+	names := func(events []models.TimelineEvent) []string {
+		var names []string
+		for _, e := range events {
+			if e.Repo != nil {
+				names = append(names, e.Repo.Name)
+			}
+		}
+		return names
+	}
+
+	// repos of bob, and forks of the watched repo by anyone but alice
+	events, err := MakeTimeline(d, 10, "did:plc:alice", models.TimelineFollowing, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "fork", "first"}, names(events))
+
+	// the next page starts after the last event of this one
+	events, err = MakeTimeline(d, 2, "did:plc:alice", models.TimelineFollowing, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "fork"}, names(events))
+	events, err = MakeTimeline(d, 2, "did:plc:alice", models.TimelineFollowing, events[1].EventAt)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first"}, names(events))
+
+	events, err = MakeTimeline(d, 10, "did:plc:alice", models.TimelineGlobal, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, len(events))
+}
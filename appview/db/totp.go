@@ -0,0 +1,70 @@
+package db
+
+import (
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+// SetTotpSecret starts an enrollment for did, replacing any earlier one.
+func SetTotpSecret(e Execer, did, secret string) error {
+	_, err := e.Exec(
+		`insert or replace into totp_secrets (did, secret) values (?, ?)`,
+		did, secret,
+	)
+	return err
+}
+
+// UpdateTotpSecret replaces the stored secret of did, leaving the rest of
+// its enrollment as it is.
+func UpdateTotpSecret(e Execer, did, secret string) error {
+	_, err := e.Exec(`update totp_secrets set secret = ? where did = ?`, secret, did)
+	return err
+}
+
+func ConfirmTotpSecret(e Execer, did string) error {
+	_, err := e.Exec(`update totp_secrets set confirmed = 1 where did = ?`, did)
+	return err
+}
+
+func DeleteTotpSecret(e Execer, did string) error {
+	_, err := e.Exec(`delete from totp_secrets where did = ?`, did)
+	return err
+}
+
+func GetTotpSecret(e Execer, did string) (*models.TotpSecret, error) {
+	var t models.TotpSecret
+	var confirmed int
+	var createdAt string
+
+	err := e.QueryRow(
+		`select did, secret, confirmed, last_step, created from totp_secrets where did = ?`,
+		did,
+	).Scan(&t.Did, &t.Secret, &confirmed, &t.LastStep, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Confirmed = confirmed != 0
+	t.Created, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		t.Created = time.Now()
+	}
+
+	return &t, nil
+}
+
+// UseTotpStep records that a code for step was accepted. It reports false
+// when a code for that step, or a later one, was accepted before.
+func UseTotpStep(e Execer, did string, step int64) (bool, error) {
+	result, err := e.Exec(
+		`update totp_secrets set last_step = ? where did = ? and last_step < ?`,
+		step, did, step,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Transact runs fn in a transaction and commits it. Transactions that fail
+// because the database stayed busy past the busy timeout are rolled back and
+// run again, so fn may run more than once and must not have effects outside
+// of tx.
+func (d *DB) Transact(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := d.transact(ctx, fn)
+		if err == nil || !IsBusy(err) || attempt >= d.busyRetries {
+			return err
+		}
+
+		d.logger.Warn("database is busy, retrying transaction", "attempt", attempt+1, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 50 * time.Millisecond):
+		}
+	}
+}
+
+func (d *DB) transact(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsBusy reports whether err is sqlite giving up on a locked database.
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
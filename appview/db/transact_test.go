@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestTransact_ConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	d, err := Make(ctx, filepath.Join(t.TempDir(), "appview.db"), Options{
+		BusyTimeout:  5 * time.Second,
+		MaxOpenConns: 8,
+		MaxIdleConns: 8,
+		BusyRetries:  3,
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	_, err = d.Exec("create table counter (n integer not null)")
+	assert.NoError(t, err)
+
+	// every writer reads before it writes, which is where deferred
+	// transactions used to fail as busy
+	const writers, writes = 16, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes)
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range writes {
+				errs <- d.Transact(ctx, func(tx *sql.Tx) error {
+					var n int
+					if err := tx.QueryRow("select count(*) from counter").Scan(&n); err != nil {
+						return err
+					}
+					_, err := tx.Exec("insert into counter (n) values (?)", n+1)
+					return err
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	// writes were serialized, so every count was seen exactly once
+	var count, distinct int
+	err = d.QueryRow("select count(*), count(distinct n) from counter").Scan(&count, &distinct)
+	assert.NoError(t, err)
+	assert.Equal(t, writers*writes, count)
+	assert.Equal(t, writers*writes, distinct)
+}
+
+func TestTransact_RetriesBusy(t *testing.T) {
+	d := createTestDB(t)
+	d.busyRetries = 2
+
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+
+	calls := 0
+	err := d.Transact(context.Background(), func(tx *sql.Tx) error {
+		calls++
+		if calls < 3 {
+			return busy
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = d.Transact(context.Background(), func(tx *sql.Tx) error {
+		calls++
+		return busy
+	})
+	assert.True(t, IsBusy(err))
+	assert.Equal(t, 3, calls)
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddRepoTransfer(e Execer, t *models.RepoTransfer) error {
+	result, err := e.Exec(
+		`insert into repo_transfers (repo_at, from_did, to_did) values (?, ?, ?)`,
+		t.RepoAt, t.FromDid, t.ToDid,
+	)
+	if err != nil {
+		return err
+	}
+
+	t.Id, err = result.LastInsertId()
+	t.Status = models.RepoTransferPending
+	return err
+}
+
+func SetRepoTransferStatus(e Execer, id int64, status models.RepoTransferStatus) error {
+	_, err := e.Exec(`update repo_transfers set status = ? where id = ?`, status, id)
+	return err
+}
+
+func DeleteRepoTransfer(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(`delete from repo_transfers %s`, whereClause)
+
+	_, err := e.Exec(query, args...)
+	return err
+}
+
+func GetRepoTransfers(e Execer, filters ...filter) ([]models.RepoTransfer, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, repo_at, from_did, to_did, status, created
+		from repo_transfers %s
+		order by created desc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.RepoTransfer
+	for rows.Next() {
+		var t models.RepoTransfer
+		var createdAt string
+		if err := rows.Scan(&t.Id, &t.RepoAt, &t.FromDid, &t.ToDid, &t.Status, &createdAt); err != nil {
+			return nil, err
+		}
+		t.Created, err = time.Parse(time.RFC3339, createdAt)
+		if err != nil {
+			t.Created = time.Now()
+		}
+		transfers = append(transfers, t)
+	}
+
+	return transfers, rows.Err()
+}
+
+func GetRepoTransfer(e Execer, filters ...filter) (*models.RepoTransfer, error) {
+	transfers, err := GetRepoTransfers(e, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	if transfers == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if len(transfers) != 1 {
+		return nil, fmt.Errorf("too many rows returned")
+	}
+
+	return &transfers[0], nil
+}
+
+// TransferRepo moves repo over to the record rkey of did, and points
+// everything that referred to the old record at the new one. Collaborators
+// are dropped, their records belong to the previous owner.
+func TransferRepo(tx *sql.Tx, repo *models.Repo, did, rkey string) error {
+	oldAt := repo.RepoAt()
+
+	moved := *repo
+	moved.Did = did
+	moved.Rkey = rkey
+	newAt := moved.RepoAt()
+
+	// references are re-pointed one table at a time, only check them once
+	// all of them are done
+	if _, err := tx.Exec(`pragma defer_foreign_keys = on`); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`delete from collaborators where repo_at = ?`, oldAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`delete from repo_transfers where repo_at = ?`, oldAt); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		`update repos set did = ?, rkey = ?, at_uri = ? where at_uri = ?`,
+		did, rkey, newAt, oldAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	references := []string{
+		`update issues set repo_at = ? where repo_at = ?`,
+		`update pulls set repo_at = ? where repo_at = ?`,
+		`update pulls set source_repo_at = ? where source_repo_at = ?`,
+		`update pull_comments set repo_at = ? where repo_at = ?`,
+		`update repo_issue_seqs set repo_at = ? where repo_at = ?`,
+		`update repo_pull_seqs set repo_at = ? where repo_at = ?`,
+		`update artifacts set repo_at = ? where repo_at = ?`,
+		`update releases set repo_at = ? where repo_at = ?`,
+		`update repo_languages set repo_at = ? where repo_at = ?`,
+		`update repo_labels set repo_at = ? where repo_at = ?`,
+		`update repo_ref_cache set repo_at = ? where repo_at = ?`,
+		`update profile_pinned_repositories set at_uri = ? where at_uri = ?`,
+		`update stars set subject_at = ? where subject_at = ?`,
+		`update watches set subject_at = ? where subject_at = ?`,
+		`update repos set source = ? where source = ?`,
+	}
+	for _, query := range references {
+		if _, err := tx.Exec(query, newAt, oldAt); err != nil {
+			return err
+		}
+	}
+
+	// pipelines refer to repos by owner and name
+	_, err = tx.Exec(
+		`update pipelines set repo_owner = ? where knot = ? and repo_owner = ? and repo_name = ?`,
+		did, repo.Knot, repo.Did, repo.Name,
+	)
+	return err
+}
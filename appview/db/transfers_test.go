@@ -0,0 +1,71 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/models"
+)
+
+func TestTransferRepo(t *testing.T) {
+	d := createTestDB(t)
+
+	repo := &models.Repo{
+		Did:  "did:plc:alice",
+		Name: "project",
+		Knot: "knot.example.com",
+		Rkey: "3lrepo",
+	}
+	oldAt := repo.RepoAt()
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, AddRepo(tx, repo))
+	assert.NoError(t, PutIssue(tx, &models.Issue{
+		Did:    "did:plc:carol",
+		Rkey:   "3lissue",
+		RepoAt: oldAt,
+		Title:  "bug",
+	}))
+	assert.NoError(t, AddCollaborator(tx, models.Collaborator{
+		Did:        "did:plc:alice",
+		Rkey:       "3lcollab",
+		SubjectDid: "did:plc:carol",
+		RepoAt:     oldAt,
+		Role:       "collaborator",
+	}))
+	assert.NoError(t, AddStar(tx, &models.Star{Did: "did:plc:carol", RepoAt: oldAt, Rkey: "3lstar"}))
+	assert.NoError(t, AddRepoTransfer(tx, &models.RepoTransfer{
+		RepoAt:  oldAt,
+		FromDid: "did:plc:alice",
+		ToDid:   "did:plc:bob",
+	}))
+	assert.NoError(t, tx.Commit())
+
+	tx, err = d.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, TransferRepo(tx, repo, "did:plc:bob", "3lnewrepo"))
+	assert.NoError(t, tx.Commit())
+
+	moved, err := GetRepo(d, FilterEq("did", "did:plc:bob"), FilterEq("name", "project"))
+	assert.NoError(t, err)
+	assert.Equal(t, "3lnewrepo", moved.Rkey)
+	newAt := moved.RepoAt()
+
+	issues, err := GetIssues(d, FilterEq("repo_at", newAt))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, 1, issues[0].IssueId)
+
+	stars, err := GetStarCount(d, newAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stars)
+
+	collaborators, err := GetCollaborators(d, FilterEq("repo_at", newAt))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(collaborators))
+
+	transfers, err := GetRepoTransfers(d)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(transfers))
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetDefaultBranch returns the branch that did wants new repos to start
+// with, or "" if they left it to the instance.
+func GetDefaultBranch(e Execer, did string) (string, error) {
+	var branch string
+	err := e.QueryRow(`select default_branch from user_settings where did = ?`, did).Scan(&branch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return branch, err
+}
+
+func SetDefaultBranch(e Execer, did, branch string) error {
+	_, err := e.Exec(`
+		insert into user_settings (did, default_branch)
+		values (?, ?)
+		on conflict(did) do update set default_branch = excluded.default_branch
+	`, did, branch)
+	return err
+}
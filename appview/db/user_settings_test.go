@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDefaultBranch(t *testing.T) {
+	d := createTestDB(t)
+
+	branch, err := GetDefaultBranch(d, "did:plc:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "", branch)
+
+	assert.NoError(t, SetDefaultBranch(d, "did:plc:alice", "trunk"))
+	assert.NoError(t, SetDefaultBranch(d, "did:plc:alice", "develop"))
+
+	branch, err = GetDefaultBranch(d, "did:plc:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "develop", branch)
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/models"
+)
+
+func AddWatch(e Execer, watch *models.Watch) error {
+	_, err := e.Exec(
+		`insert or ignore into watches (did, subject_at, rkey) values (?, ?, ?)`,
+		watch.Did,
+		watch.RepoAt.String(),
+		watch.Rkey,
+	)
+	return err
+}
+
+func DeleteWatch(e Execer, did string, subjectAt syntax.ATURI) error {
+	_, err := e.Exec(`delete from watches where did = ? and subject_at = ?`, did, subjectAt)
+	return err
+}
+
+func DeleteWatchByRkey(e Execer, did string, rkey string) error {
+	_, err := e.Exec(`delete from watches where did = ? and rkey = ?`, did, rkey)
+	return err
+}
+
+// GetWatches returns watches matching filters, newest first.
+func GetWatches(e Execer, filters ...filter) ([]models.Watch, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select did, subject_at, created, rkey
+		from watches
+		%s
+		order by created desc, id desc`,
+		whereClause,
+	)
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []models.Watch
+	for rows.Next() {
+		var watch models.Watch
+		var created string
+		if err := rows.Scan(&watch.Did, &watch.RepoAt, &created, &watch.Rkey); err != nil {
+			return nil, err
+		}
+
+		watch.Created = time.Now()
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			watch.Created = t
+		}
+
+		watches = append(watches, watch)
+	}
+
+	return watches, rows.Err()
+}
+
+func GetWatch(e Execer, did string, subjectAt syntax.ATURI) (*models.Watch, error) {
+	watches, err := GetWatches(e, FilterEq("did", did), FilterEq("subject_at", subjectAt))
+	if err != nil {
+		return nil, err
+	}
+
+	if watches == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return &watches[0], nil
+}
+
+func GetWatchStatus(e Execer, did string, subjectAt syntax.ATURI) bool {
+	_, err := GetWatch(e, did, subjectAt)
+	return err == nil
+}
+
+// GetWatchers returns everyone watching subjectAt.
+func GetWatchers(e Execer, subjectAt syntax.ATURI) ([]syntax.DID, error) {
+	watches, err := GetWatches(e, FilterEq("subject_at", subjectAt))
+	if err != nil {
+		return nil, err
+	}
+
+	var watchers []syntax.DID
+	for _, w := range watches {
+		watchers = append(watchers, syntax.DID(w.Did))
+	}
+	return watchers, nil
+}
+
+// GetRepoWatches returns watches matching filters along with the repo each
+// of them is on. Watches of repos that are not known are left out.
+func GetRepoWatches(e Execer, filters ...filter) ([]models.RepoWatch, error) {
+	watches, err := GetWatches(e, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(watches) == 0 {
+		return nil, nil
+	}
+
+	var repoAts []string
+	for _, w := range watches {
+		repoAts = append(repoAts, w.RepoAt.String())
+	}
+
+	repos, err := GetRepos(e, 0, FilterIn("at_uri", repoAts))
+	if err != nil {
+		return nil, err
+	}
+
+	repoMap := make(map[syntax.ATURI]*models.Repo)
+	for i := range repos {
+		repoMap[repos[i].RepoAt()] = &repos[i]
+	}
+
+	var repoWatches []models.RepoWatch
+	for _, w := range watches {
+		if repo, ok := repoMap[w.RepoAt]; ok {
+			repoWatches = append(repoWatches, models.RepoWatch{
+				Watch: w,
+				Repo:  repo,
+			})
+		}
+	}
+
+	return repoWatches, nil
+}
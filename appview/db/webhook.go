@@ -0,0 +1,237 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/models"
+)
+
+func AddWebhook(e Execer, hook *models.Webhook) error {
+	result, err := e.Exec(
+		`insert into webhooks (repo_at, url, secret) values (?, ?, ?)`,
+		hook.RepoAt,
+		hook.Url,
+		hook.Secret,
+	)
+	if err != nil {
+		return err
+	}
+
+	hook.Id, err = result.LastInsertId()
+	return err
+}
+
+func GetWebhooks(e Execer, filters ...filter) ([]models.Webhook, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	query := fmt.Sprintf(
+		`select id, repo_at, url, secret, created
+		from webhooks %s
+		order by id asc`,
+		whereClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []models.Webhook
+	for rows.Next() {
+		var hook models.Webhook
+		var created string
+		if err := rows.Scan(
+			&hook.Id,
+			&hook.RepoAt,
+			&hook.Url,
+			&hook.Secret,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			hook.Created = t
+		}
+
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func DeleteWebhooks(e Execer, filters ...filter) error {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	_, err := e.Exec(`delete from webhooks`+whereClause, args...)
+	return err
+}
+
+func AddWebhookDelivery(e Execer, delivery *models.WebhookDelivery) error {
+	result, err := e.Exec(
+		`insert into webhook_deliveries (
+			webhook_id,
+			event,
+			guid,
+			request_headers,
+			payload,
+			response_status,
+			response_headers,
+			response_body,
+			error,
+			duration_ms,
+			redelivery_of
+		)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.WebhookId,
+		delivery.Event,
+		delivery.Guid,
+		delivery.RequestHeaders,
+		delivery.Payload,
+		delivery.ResponseStatus,
+		delivery.ResponseHeaders,
+		delivery.ResponseBody,
+		delivery.Error,
+		delivery.Duration.Milliseconds(),
+		delivery.RedeliveryOf,
+	)
+	if err != nil {
+		return err
+	}
+
+	delivery.Id, err = result.LastInsertId()
+	return err
+}
+
+func GetWebhookDeliveries(e Execer, limit int, filters ...filter) ([]models.WebhookDelivery, error) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		conditions = append(conditions, filter.Condition())
+		args = append(args, filter.Arg()...)
+	}
+
+	whereClause := ""
+	if conditions != nil {
+		whereClause = " where " + strings.Join(conditions, " and ")
+	}
+
+	limitClause := ""
+	if limit != 0 {
+		limitClause = fmt.Sprintf(" limit %d", limit)
+	}
+
+	query := fmt.Sprintf(
+		`select
+			id,
+			webhook_id,
+			event,
+			guid,
+			request_headers,
+			payload,
+			response_status,
+			response_headers,
+			response_body,
+			error,
+			duration_ms,
+			redelivery_of,
+			created
+		from webhook_deliveries %s
+		order by id desc %s`,
+		whereClause,
+		limitClause,
+	)
+
+	rows, err := e.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var durationMs int64
+		var created string
+		if err := rows.Scan(
+			&delivery.Id,
+			&delivery.WebhookId,
+			&delivery.Event,
+			&delivery.Guid,
+			&delivery.RequestHeaders,
+			&delivery.Payload,
+			&delivery.ResponseStatus,
+			&delivery.ResponseHeaders,
+			&delivery.ResponseBody,
+			&delivery.Error,
+			&durationMs,
+			&delivery.RedeliveryOf,
+			&created,
+		); err != nil {
+			return nil, err
+		}
+
+		delivery.Duration = time.Duration(durationMs) * time.Millisecond
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			delivery.Created = t
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// PruneWebhookDeliveries keeps only the latest keep deliveries of a webhook,
+// and drops those of them made before the given time.
+func PruneWebhookDeliveries(e Execer, webhookId int64, keep int, before time.Time) error {
+	_, err := e.Exec(
+		`delete from webhook_deliveries
+		where webhook_id = ?
+		and (
+			created < ?
+			or id not in (
+				select id from webhook_deliveries
+				where webhook_id = ?
+				order by id desc
+				limit ?
+			)
+		)`,
+		webhookId,
+		before.UTC().Format(time.RFC3339),
+		webhookId,
+		keep,
+	)
+	return err
+}
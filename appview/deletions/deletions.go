@@ -0,0 +1,248 @@
+// Package deletions deletes accounts, once their owner asks for it and the
+// grace period they have to change their mind is over.
+//
+// A deletion is carried out in steps, see models.DeletionSteps: the repos
+// of the account are removed from their knots, the enforcer and the
+// appview; what they wrote in the threads of others is anonymized (see
+// db.AnonymizeAccountContent); their records are deleted from their PDS;
+// everything else the appview keeps about them is removed; and finally
+// their sessions are revoked. Knots and spindles they run are left alone,
+// since others may depend on them.
+//
+// Every step can be taken again, and the next step is recorded once one is
+// done, so a deletion that got stuck, say on a knot that is down, is picked
+// up where it stopped every RetryEvery.
+package deletions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/email"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/rbac"
+)
+
+var ErrAlreadyScheduled = errors.New("the account is already being deleted")
+
+type Deleter struct {
+	db       *db.DB
+	oauth    *oauth.OAuth
+	enforcer *rbac.Enforcer
+	config   *config.Config
+	logger   *slog.Logger
+}
+
+func New(database *db.DB, oauthHandler *oauth.OAuth, enforcer *rbac.Enforcer, config *config.Config, logger *slog.Logger) *Deleter {
+	return &Deleter{
+		db:       database,
+		oauth:    oauthHandler,
+		enforcer: enforcer,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// Schedule asks for the account of did to be deleted once the grace period
+// is over, and lets them know by mail.
+func (d *Deleter) Schedule(did string) (*models.AccountDeletion, error) {
+	_, err := db.GetPendingAccountDeletion(d.db, did)
+	if err == nil {
+		return nil, ErrAlreadyScheduled
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	deletion := &models.AccountDeletion{
+		Did:       did,
+		Scheduled: time.Now().Add(d.config.Deletion.GracePeriod),
+	}
+	if err := db.AddAccountDeletion(d.db, deletion); err != nil {
+		return nil, err
+	}
+
+	d.notify(deletion)
+	return deletion, nil
+}
+
+// Cancel calls off the deletion of the account of did, as long as it has
+// not started.
+func (d *Deleter) Cancel(did string) error {
+	deletion, err := db.GetPendingAccountDeletion(d.db, did)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := db.CancelAccountDeletion(d.db, deletion.Id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrAlreadyScheduled
+	}
+	return nil
+}
+
+// Run carries out deletions once they are due, and every RetryEvery goes
+// on with the ones that got stuck, until ctx is done.
+func (d *Deleter) Run(ctx context.Context) {
+	every := d.config.Deletion.RetryEvery
+	if every <= 0 {
+		every = 15 * time.Minute
+	}
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		d.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Deleter) runDue(ctx context.Context) {
+	due, err := db.GetAccountDeletions(
+		d.db,
+		db.FilterEq("status", models.DeletionScheduled),
+		db.FilterLte("scheduled", time.Now().UTC().Format(time.RFC3339)),
+	)
+	if err != nil {
+		d.logger.Error("failed to get due deletions", "err", err)
+		return
+	}
+	for _, deletion := range due {
+		if err := db.StartAccountDeletion(d.db, deletion.Id); err != nil {
+			d.logger.Error("failed to start deletion", "deletion", deletion.Id, "err", err)
+		}
+	}
+
+	running, err := db.GetAccountDeletions(d.db, db.FilterEq("status", models.DeletionRunning))
+	if err != nil {
+		d.logger.Error("failed to get running deletions", "err", err)
+		return
+	}
+
+	// oldest first
+	for i := len(running) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			return
+		}
+		d.carryOut(ctx, running[i])
+	}
+}
+
+// carryOut takes the steps of deletion from the one it stopped at, and
+// stops again at the first one that fails.
+func (d *Deleter) carryOut(ctx context.Context, deletion models.AccountDeletion) {
+	l := d.logger.With("deletion", deletion.Id, "did", deletion.Did)
+
+	start := 0
+	for i, step := range models.DeletionSteps {
+		if step == deletion.Step {
+			start = i
+		}
+	}
+
+	for i := start; i < len(models.DeletionSteps); i++ {
+		step := models.DeletionSteps[i]
+
+		if err := d.take(ctx, deletion.Did, step); err != nil {
+			l.Error("failed to take deletion step", "step", step, "attempts", deletion.Attempts+1, "err", err)
+			if err := db.FailAccountDeletionStep(d.db, deletion.Id, reason(step)); err != nil {
+				l.Error("failed to record deletion failure", "err", err)
+			}
+			return
+		}
+		l.Info("took deletion step", "step", step)
+
+		if i+1 < len(models.DeletionSteps) {
+			if err := db.SetAccountDeletionStep(d.db, deletion.Id, models.DeletionSteps[i+1]); err != nil {
+				l.Error("failed to record deletion step", "err", err)
+				return
+			}
+		}
+	}
+
+	if err := db.FinishAccountDeletion(d.db, deletion.Id); err != nil {
+		l.Error("failed to finish deletion", "err", err)
+		return
+	}
+	l.Info("account deleted")
+}
+
+func (d *Deleter) take(ctx context.Context, did string, step models.DeletionStep) error {
+	switch step {
+	case models.DeletionStepRepos:
+		return d.removeRepos(ctx, did)
+	case models.DeletionStepContent:
+		return db.AnonymizeAccountContent(d.db, did)
+	case models.DeletionStepRecords:
+		return d.deleteRecords(ctx, did)
+	case models.DeletionStepRows:
+		return d.removeRows(ctx, did)
+	case models.DeletionStepSessions:
+		return d.revokeSessions(ctx, did)
+	default:
+		return fmt.Errorf("unknown deletion step %q", step)
+	}
+}
+
+// reason is what the owner of an account is told when step could not be
+// taken.
+func reason(step models.DeletionStep) string {
+	switch step {
+	case models.DeletionStepRepos:
+		return "Some of your repos could not be removed from their knots yet."
+	case models.DeletionStepRecords:
+		return "Your records could not be deleted from your PDS yet."
+	default:
+		return "Your account could not be deleted yet."
+	}
+}
+
+// notify mails the primary address of the user, if they have one, when
+// their account is to be deleted and how to stop it.
+func (d *Deleter) notify(deletion *models.AccountDeletion) {
+	if d.config.Resend.ApiKey == "" {
+		return
+	}
+
+	address, err := db.GetPrimaryEmail(d.db, deletion.Did)
+	if err != nil || !address.Verified {
+		return
+	}
+
+	appUrl := d.config.Core.AppviewHost
+	if d.config.Core.Dev {
+		appUrl = "http://" + d.config.Core.ListenAddr
+	}
+	link := appUrl + "/settings/account"
+	on := deletion.Scheduled.UTC().Format("January 2, 2006")
+
+	err = email.SendEmail(email.Email{
+		APIKey:  d.config.Resend.ApiKey,
+		From:    d.config.Resend.SentFrom,
+		To:      address.Address,
+		Subject: "Your Tangled account is to be deleted",
+		Text: `Your account is to be deleted on ` + on + `. If you did not mean to, or changed your mind, cancel it from the link below before then.
+` + link,
+		Html: `<p>Your account is to be deleted on ` + on + `. If you did not mean to, or changed your mind, cancel it before then.</p>
+<p><a href="` + link + `">` + link + `</a></p>`,
+	})
+	if err != nil {
+		d.logger.Error("failed to mail about deletion", "deletion", deletion.Id, "err", err)
+	}
+}
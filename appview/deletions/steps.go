@@ -0,0 +1,282 @@
+package deletions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	atpclient "github.com/bluesky-social/indigo/atproto/client"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/exports"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+)
+
+// keptCollections are records that knots and spindles others depend on
+// are set up with, which are left on the PDS.
+var keptCollections = []string{
+	tangled.KnotNSID,
+	tangled.KnotMemberNSID,
+	tangled.SpindleNSID,
+	tangled.SpindleMemberNSID,
+}
+
+// pdsClient resumes a session of did, to act on their PDS with.
+func (d *Deleter) pdsClient(ctx context.Context, did string) (*atpclient.APIClient, error) {
+	sess, err := d.oauth.ResumeSessionFor(ctx, syntax.DID(did))
+	if err != nil {
+		return nil, err
+	}
+	return sess.APIClient(), nil
+}
+
+// removeRepos removes every repo of did the way DeleteRepo does: the
+// record goes first, as knots only remove repos whose record is gone. Each
+// repo is removed from the appview as soon as its knot removed it, so a
+// retry only deals with those that are left.
+func (d *Deleter) removeRepos(ctx context.Context, did string) error {
+	repos, err := db.GetRepos(d.db, 0, db.FilterEq("did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get repos: %w", err)
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+
+	client, err := d.pdsClient(ctx, did)
+	if err != nil {
+		return err
+	}
+
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := d.removeRepo(ctx, client, &repo); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", repo.DidSlashRepo(), err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Deleter) removeRepo(ctx context.Context, client *atpclient.APIClient, repo *models.Repo) error {
+	_, err := comatproto.RepoDeleteRecord(ctx, client, &comatproto.RepoDeleteRecord_Input{
+		Collection: tangled.RepoNSID,
+		Repo:       repo.Did,
+		Rkey:       repo.Rkey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete record: %w", err)
+	}
+
+	knot, err := d.oauth.ServiceClientFor(
+		ctx,
+		syntax.DID(repo.Did),
+		oauth.WithService(repo.Knot),
+		oauth.WithLxm(tangled.RepoDeleteNSID),
+		oauth.WithoutRetry(),
+		oauth.WithDev(d.config.Core.Dev),
+		oauth.WithTimeout(d.config.Deletion.KnotTimeout),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to knot: %w", err)
+	}
+
+	err = tangled.RepoDelete(ctx, knot, &tangled.RepoDelete_Input{
+		Did:  repo.Did,
+		Name: repo.Name,
+		Rkey: repo.Rkey,
+	})
+	if err := xrpcclient.HandleXrpcErr(err); err != nil {
+		return err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+		if err := d.enforcer.E.LoadPolicy(); err != nil {
+			d.logger.Error("failed to rollback policies", "err", err)
+		}
+	}()
+
+	collaborators, err := d.enforcer.E.GetImplicitUsersForResourceByDomain(repo.DidSlashRepo(), repo.Knot)
+	if err != nil {
+		return fmt.Errorf("failed to get collaborators: %w", err)
+	}
+	for _, c := range collaborators {
+		d.enforcer.RemoveCollaborator(c[0], repo.Knot, repo.DidSlashRepo())
+	}
+
+	if err := d.enforcer.RemoveRepo(repo.Did, repo.Knot, repo.DidSlashRepo()); err != nil {
+		return fmt.Errorf("failed to update RBAC rules: %w", err)
+	}
+
+	if err := db.RemoveRepo(tx, repo.Did, repo.Name); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return d.enforcer.E.SavePolicy()
+}
+
+// deleteRecords deletes every record did has on their PDS that was made
+// here, except for keptCollections.
+func (d *Deleter) deleteRecords(ctx context.Context, did string) error {
+	client, err := d.pdsClient(ctx, did)
+	if err != nil {
+		return err
+	}
+
+	repo, err := comatproto.RepoDescribeRepo(ctx, client, did)
+	if err != nil {
+		return fmt.Errorf("failed to describe repo: %w", err)
+	}
+
+	for _, collection := range repo.Collections {
+		if !strings.HasPrefix(collection, "sh.tangled.") || slices.Contains(keptCollections, collection) {
+			continue
+		}
+
+		cursor := ""
+		for {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			out, err := comatproto.RepoListRecords(ctx, client, collection, cursor, 100, did, false)
+			if err != nil {
+				return fmt.Errorf("failed to list %s: %w", collection, err)
+			}
+
+			for _, record := range out.Records {
+				uri, err := syntax.ParseATURI(record.Uri)
+				if err != nil {
+					continue
+				}
+				_, err = comatproto.RepoDeleteRecord(ctx, client, &comatproto.RepoDeleteRecord_Input{
+					Collection: collection,
+					Repo:       did,
+					Rkey:       uri.RecordKey().String(),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to delete %s: %w", uri, err)
+				}
+			}
+
+			if out.Cursor == nil || *out.Cursor == "" || len(out.Records) == 0 {
+				break
+			}
+			cursor = *out.Cursor
+		}
+	}
+
+	return nil
+}
+
+// removeRows takes did out of the repos, knots and spindles of others, and
+// removes what the appview keeps about them, down to the archives of their
+// exports.
+func (d *Deleter) removeRows(ctx context.Context, did string) error {
+	collaboratingIn, err := db.CollaboratingIn(d.db, did)
+	if err != nil {
+		return fmt.Errorf("failed to get collaborations: %w", err)
+	}
+
+	// the archives go first, a retry would no longer find them once their
+	// rows are gone
+	archives, err := db.GetExports(d.db, db.FilterEq("did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get exports: %w", err)
+	}
+	for _, export := range archives {
+		path := exports.ArchivePath(d.config.Export.Dir, export.Id)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove export %d: %w", export.Id, err)
+		}
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+		if err := d.enforcer.E.LoadPolicy(); err != nil {
+			d.logger.Error("failed to rollback policies", "err", err)
+		}
+	}()
+
+	for _, repo := range collaboratingIn {
+		if err := d.enforcer.RemoveCollaborator(did, repo.Knot, repo.DidSlashRepo()); err != nil {
+			return fmt.Errorf("failed to remove collaborator: %w", err)
+		}
+	}
+
+	knots, err := d.enforcer.GetKnotsForUser(did)
+	if err != nil {
+		return err
+	}
+	for _, knot := range knots {
+		if isOwner, _ := d.enforcer.IsKnotOwner(did, knot); isOwner {
+			continue
+		}
+		if err := d.enforcer.RemoveKnotMember(knot, did); err != nil {
+			return fmt.Errorf("failed to remove knot member: %w", err)
+		}
+	}
+
+	spindles, err := d.enforcer.GetSpindlesForUser(did)
+	if err != nil {
+		return err
+	}
+	for _, spindle := range spindles {
+		if isOwner, _ := d.enforcer.IsSpindleOwner(did, spindle); isOwner {
+			continue
+		}
+		if err := d.enforcer.RemoveSpindleMember(spindle, did); err != nil {
+			return fmt.Errorf("failed to remove spindle member: %w", err)
+		}
+	}
+
+	if err := db.RemoveAccountRows(tx, did); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return d.enforcer.E.SavePolicy()
+}
+
+// revokeSessions logs did out everywhere. Sessions the PDS can't be asked
+// to revoke are dropped all the same.
+func (d *Deleter) revokeSessions(ctx context.Context, did string) error {
+	ids, err := d.oauth.AuthStore.SessionIds(ctx, syntax.DID(did))
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := d.oauth.ClientApp.Logout(ctx, syntax.DID(did), id); err == nil {
+			continue
+		}
+		if err := d.oauth.AuthStore.DeleteSession(ctx, syntax.DID(did), id); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+
+	return nil
+}
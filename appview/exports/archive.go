@@ -0,0 +1,319 @@
+package exports
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pagination"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+)
+
+// record is a record of the user as it would be on their PDS.
+type record struct {
+	Uri   string `json:"uri"`
+	Value any    `json:"value"`
+}
+
+type manifest struct {
+	Did      string    `json:"did"`
+	Exported time.Time `json:"exported"`
+	Files    []string  `json:"files"`
+
+	// repos whose contents could not be fetched from their knot
+	SkippedRepos []string `json:"skippedRepos,omitempty"`
+}
+
+type account struct {
+	Did                     string                          `json:"did"`
+	Profile                 *models.Profile                 `json:"profile,omitempty"`
+	Emails                  []accountEmail                  `json:"emails"`
+	PublicKeys              []accountKey                    `json:"publicKeys"`
+	DefaultBranch           string                          `json:"defaultBranch,omitempty"`
+	NotificationPreferences *models.NotificationPreferences `json:"notificationPreferences,omitempty"`
+	Following               []string                        `json:"following"`
+	Stars                   []string                        `json:"stars"`
+	Watching                []string                        `json:"watching"`
+}
+
+type accountEmail struct {
+	Address  string `json:"address"`
+	Verified bool   `json:"verified"`
+	Primary  bool   `json:"primary"`
+}
+
+type accountKey struct {
+	Name    string     `json:"name"`
+	Key     string     `json:"key"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+// writeArchive writes everything the user has on the appview to zw: their
+// account and settings, and the records of their repos, issues, pulls,
+// comments and strings. The contents of their repos are added when asked
+// for.
+func (x *Exporter) writeArchive(ctx context.Context, zw *zip.Writer, export *models.Export) error {
+	did := export.Did
+	m := manifest{
+		Did:      did,
+		Exported: time.Now().UTC(),
+	}
+
+	write := func(name string, v any) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		m.Files = append(m.Files, name)
+		return nil
+	}
+
+	acc, err := x.account(did)
+	if err != nil {
+		return err
+	}
+	if err := write("account.json", acc); err != nil {
+		return err
+	}
+
+	repos, err := db.GetRepos(x.db, 0, db.FilterEq("did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get repos: %w", err)
+	}
+	repoRecords := make([]record, 0, len(repos))
+	for _, repo := range repos {
+		repoRecords = append(repoRecords, record{repo.RepoAt().String(), repo.AsRecord()})
+	}
+	if err := write("repos.json", repoRecords); err != nil {
+		return err
+	}
+
+	issues, err := db.GetIssues(x.db, db.FilterEq("did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get issues: %w", err)
+	}
+	issueRecords := make([]record, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Deleted != nil {
+			continue
+		}
+		issueRecords = append(issueRecords, record{issue.AtUri().String(), issue.AsRecord()})
+	}
+	if err := write("issues.json", issueRecords); err != nil {
+		return err
+	}
+
+	issueComments, err := db.GetIssueComments(x.db, db.FilterEq("did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get issue comments: %w", err)
+	}
+	issueCommentRecords := make([]record, 0, len(issueComments))
+	for _, comment := range issueComments {
+		if comment.Deleted != nil {
+			continue
+		}
+		issueCommentRecords = append(issueCommentRecords, record{comment.AtUri().String(), comment.AsRecord()})
+	}
+	if err := write("issue-comments.json", issueCommentRecords); err != nil {
+		return err
+	}
+
+	pulls, err := db.GetPulls(x.db, db.FilterEq("owner_did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get pulls: %w", err)
+	}
+	pullRecords := make([]record, 0, len(pulls))
+	for _, pull := range pulls {
+		pullRecords = append(pullRecords, record{pull.AtUri().String(), pull.AsRecord()})
+	}
+	if err := write("pulls.json", pullRecords); err != nil {
+		return err
+	}
+
+	pullComments, err := db.GetPullComments(x.db, db.FilterEq("owner_did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get pull comments: %w", err)
+	}
+	pullAts := make(map[string]syntax.ATURI)
+	pullCommentRecords := make([]record, 0, len(pullComments))
+	for _, comment := range pullComments {
+		key := fmt.Sprintf("%s/%d", comment.RepoAt, comment.PullId)
+		pullAt, ok := pullAts[key]
+		if !ok {
+			pullAt, err = db.GetPullAt(x.db, syntax.ATURI(comment.RepoAt), comment.PullId)
+			if err != nil {
+				// the pull is gone, and the comment with it
+				continue
+			}
+			pullAts[key] = pullAt
+		}
+		pullCommentRecords = append(pullCommentRecords, record{comment.CommentAt, tangled.RepoPullComment{
+			LexiconTypeID: tangled.RepoPullCommentNSID,
+			Body:          comment.Body,
+			CreatedAt:     comment.Created.Format(time.RFC3339),
+			Pull:          pullAt.String(),
+		}})
+	}
+	if err := write("pull-comments.json", pullCommentRecords); err != nil {
+		return err
+	}
+
+	strs, err := db.GetStrings(x.db, 0, db.FilterEq("did", did))
+	if err != nil {
+		return fmt.Errorf("failed to get strings: %w", err)
+	}
+	stringRecords := make([]record, 0, len(strs))
+	for _, s := range strs {
+		stringRecords = append(stringRecords, record{s.AtUri().String(), s.AsRecord()})
+	}
+	if err := write("strings.json", stringRecords); err != nil {
+		return err
+	}
+
+	if export.IncludeRepos {
+		for _, repo := range repos {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			contents, err := x.repoArchive(ctx, &repo)
+			if err != nil {
+				x.logger.Info("skipping repo contents", "export", export.Id, "repo", repo.DidSlashRepo(), "err", err)
+				m.SkippedRepos = append(m.SkippedRepos, repo.Name)
+				continue
+			}
+
+			name := fmt.Sprintf("repos/%s.tar.gz", repo.Name)
+			f, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     name,
+				Method:   zip.Store, // already compressed
+				Modified: m.Exported,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(contents); err != nil {
+				return err
+			}
+			m.Files = append(m.Files, name)
+		}
+	}
+
+	return write("manifest.json", m)
+}
+
+func (x *Exporter) account(did string) (*account, error) {
+	acc := &account{Did: did}
+
+	profile, err := db.GetProfile(x.db, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	acc.Profile = profile
+
+	emails, err := db.GetAllEmails(x.db, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emails: %w", err)
+	}
+	acc.Emails = make([]accountEmail, 0, len(emails))
+	for _, e := range emails {
+		acc.Emails = append(acc.Emails, accountEmail{e.Address, e.Verified, e.Primary})
+	}
+
+	keys, err := db.GetPublicKeysForDid(x.db, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys: %w", err)
+	}
+	acc.PublicKeys = make([]accountKey, 0, len(keys))
+	for _, k := range keys {
+		acc.PublicKeys = append(acc.PublicKeys, accountKey{k.Name, k.Key, k.Created})
+	}
+
+	// most users never set a default branch
+	acc.DefaultBranch, _ = db.GetDefaultBranch(x.db, did)
+
+	acc.NotificationPreferences, err = db.GetNotificationPreference(x.db, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	following, err := db.GetFollowing(x.db, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follows: %w", err)
+	}
+	acc.Following = make([]string, 0, len(following))
+	for _, f := range following {
+		acc.Following = append(acc.Following, f.SubjectDid)
+	}
+
+	stars, err := db.GetStars(x.db, pagination.Page{}, db.FilterEq("did", did))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stars: %w", err)
+	}
+	acc.Stars = make([]string, 0, len(stars))
+	for _, s := range stars {
+		acc.Stars = append(acc.Stars, s.RepoAt.String())
+	}
+
+	watches, err := db.GetWatches(x.db, db.FilterEq("did", did))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watches: %w", err)
+	}
+	acc.Watching = make([]string, 0, len(watches))
+	for _, w := range watches {
+		acc.Watching = append(acc.Watching, w.RepoAt.String())
+	}
+
+	return acc, nil
+}
+
+// repoArchive fetches the contents of the default branch of repo from its
+// knot. Private repos are fetched as their owner.
+func (x *Exporter) repoArchive(ctx context.Context, repo *models.Repo) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, x.config.Export.RepoTimeout)
+	defer cancel()
+
+	var client *indigoxrpc.Client
+	if repo.IsPrivate() {
+		var err error
+		client, err = x.oauth.ServiceClientFor(
+			ctx,
+			syntax.DID(repo.Did),
+			oauth.WithService(repo.Knot),
+			oauth.WithLxm(tangled.RepoArchiveNSID),
+			oauth.WithDev(x.config.Core.Dev),
+			oauth.WithTimeout(x.config.Export.RepoTimeout),
+		)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		scheme := "http"
+		if !x.config.Core.Dev {
+			scheme = "https"
+		}
+		client = &indigoxrpc.Client{
+			Host:   fmt.Sprintf("%s://%s", scheme, repo.Knot),
+			Client: xrpcclient.HTTPClient(x.config.KnotClient, x.config.Export.RepoTimeout, true),
+		}
+	}
+
+	contents, err := tangled.RepoArchive(ctx, client, "tar.gz", repo.Name, "", repo.DidSlashRepo())
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return nil, xrpcerr
+	}
+	return contents, nil
+}
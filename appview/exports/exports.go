@@ -0,0 +1,275 @@
+// Package exports builds archives of everything a user has on the appview,
+// so that they can take their data elsewhere. Archives are built in the
+// background, one at a time, and removed once they expire.
+package exports
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/email"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+)
+
+var ErrInProgress = errors.New("an export is already in progress")
+
+type Exporter struct {
+	db     *db.DB
+	oauth  *oauth.OAuth
+	config *config.Config
+	logger *slog.Logger
+
+	// ids of exports that were just asked for
+	queue chan int64
+}
+
+func New(database *db.DB, oauthHandler *oauth.OAuth, config *config.Config, logger *slog.Logger) (*Exporter, error) {
+	if err := os.MkdirAll(config.Export.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export dir: %w", err)
+	}
+
+	return &Exporter{
+		db:     database,
+		oauth:  oauthHandler,
+		config: config,
+		logger: logger,
+		queue:  make(chan int64, 64),
+	}, nil
+}
+
+// Request asks for an archive of the data of did, unless one is already
+// being built.
+func (x *Exporter) Request(did string, includeRepos bool) (*models.Export, error) {
+	inProgress, err := db.GetExports(
+		x.db,
+		db.FilterEq("did", did),
+		db.FilterIn("status", []models.ExportStatus{models.ExportPending, models.ExportRunning}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(inProgress) > 0 {
+		return nil, ErrInProgress
+	}
+
+	export := &models.Export{
+		Did:          did,
+		IncludeRepos: includeRepos,
+	}
+	if err := db.AddExport(x.db, export); err != nil {
+		return nil, err
+	}
+
+	// with the queue full, the export is picked up on the next sweep
+	select {
+	case x.queue <- export.Id:
+	default:
+	}
+
+	return export, nil
+}
+
+// Path is where the archive of an export is kept.
+func (x *Exporter) Path(export *models.Export) string {
+	return ArchivePath(x.config.Export.Dir, export.Id)
+}
+
+// ArchivePath is where the archive of the export with the given id is kept
+// in dir.
+func ArchivePath(dir string, id int64) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.zip", id))
+}
+
+// Run builds the exports that are asked for, and every SweepEvery removes
+// the archives that expired, until ctx is done.
+func (x *Exporter) Run(ctx context.Context) {
+	every := x.config.Export.SweepEvery
+	if every <= 0 {
+		every = time.Hour
+	}
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	// exports cut short by a restart are built again
+	x.buildWaiting(ctx, models.ExportPending, models.ExportRunning)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-x.queue:
+			exports, err := db.GetExports(x.db, db.FilterEq("id", id), db.FilterEq("status", models.ExportPending))
+			if err != nil {
+				x.logger.Error("failed to get export", "id", id, "err", err)
+				continue
+			}
+			for _, export := range exports {
+				x.build(ctx, export)
+			}
+		case <-ticker.C:
+			x.sweep(ctx)
+			x.buildWaiting(ctx, models.ExportPending)
+		}
+	}
+}
+
+func (x *Exporter) buildWaiting(ctx context.Context, statuses ...models.ExportStatus) {
+	exports, err := db.GetExports(x.db, db.FilterIn("status", statuses))
+	if err != nil {
+		x.logger.Error("failed to get waiting exports", "err", err)
+		return
+	}
+
+	// oldest first
+	for i := len(exports) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			return
+		}
+		x.build(ctx, exports[i])
+	}
+}
+
+func (x *Exporter) build(ctx context.Context, export models.Export) {
+	l := x.logger.With("export", export.Id, "did", export.Did)
+
+	if err := db.SetExportRunning(x.db, export.Id); err != nil {
+		l.Error("failed to start export", "err", err)
+		return
+	}
+
+	start := time.Now()
+	size, err := x.write(ctx, &export)
+	if err != nil {
+		l.Error("failed to build export", "err", err)
+		if err := db.FailExport(x.db, export.Id, "The archive could not be built, try again later."); err != nil {
+			l.Error("failed to mark export as failed", "err", err)
+		}
+		return
+	}
+
+	expires := time.Now().Add(x.config.Export.KeepFor)
+	if err := db.FinishExport(x.db, export.Id, size, expires); err != nil {
+		l.Error("failed to finish export", "err", err)
+		return
+	}
+
+	// the account may have been deleted while the archive was built, along
+	// with the row of its export
+	if left, err := db.GetExports(x.db, db.FilterEq("id", export.Id)); err == nil && len(left) == 0 {
+		l.Info("export is gone, removing its archive")
+		os.Remove(x.Path(&export))
+		return
+	}
+
+	l.Info("export built", "size", size, "took", time.Since(start))
+	x.notify(&export, expires)
+}
+
+// write builds the archive of export next to where it is kept, and moves it
+// there once it is complete.
+func (x *Exporter) write(ctx context.Context, export *models.Export) (uint64, error) {
+	tmp, err := os.CreateTemp(x.config.Export.Dir, fmt.Sprintf("%d.zip.tmp*", export.Id))
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := zip.NewWriter(tmp)
+	if err := x.writeArchive(ctx, zw, export); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), x.Path(export)); err != nil {
+		return 0, err
+	}
+	return uint64(info.Size()), nil
+}
+
+// notify mails the primary address of the user, if they have one, that
+// their archive is ready.
+func (x *Exporter) notify(export *models.Export, expires time.Time) {
+	if x.config.Resend.ApiKey == "" {
+		return
+	}
+
+	address, err := db.GetPrimaryEmail(x.db, export.Did)
+	if err != nil || !address.Verified {
+		return
+	}
+
+	appUrl := x.config.Core.AppviewHost
+	if x.config.Core.Dev {
+		appUrl = "http://" + x.config.Core.ListenAddr
+	}
+	link := appUrl + "/settings/export"
+	until := expires.UTC().Format("January 2, 2006")
+
+	err = email.SendEmail(email.Email{
+		APIKey:  x.config.Resend.ApiKey,
+		From:    x.config.Resend.SentFrom,
+		To:      address.Address,
+		Subject: "Your Tangled export is ready",
+		Text: `The archive of your data you asked for is ready. Download it from the link below before ` + until + `, when it is removed.
+` + link,
+		Html: `<p>The archive of your data you asked for is ready. Download it before ` + until + `, when it is removed.</p>
+<p><a href="` + link + `">` + link + `</a></p>`,
+	})
+	if err != nil {
+		x.logger.Error("failed to mail about export", "export", export.Id, "err", err)
+	}
+}
+
+// sweep removes the archives that expired.
+func (x *Exporter) sweep(ctx context.Context) {
+	expired, err := db.GetExports(
+		x.db,
+		db.FilterEq("status", models.ExportDone),
+		db.FilterLte("expires", time.Now().UTC().Format(time.RFC3339)),
+	)
+	if err != nil {
+		x.logger.Error("failed to get expired exports", "err", err)
+		return
+	}
+
+	for _, export := range expired {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := os.Remove(x.Path(&export)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			x.logger.Error("failed to remove export", "export", export.Id, "err", err)
+			continue
+		}
+		if err := db.ExpireExport(x.db, export.Id); err != nil {
+			x.logger.Error("failed to expire export", "export", export.Id, "err", err)
+		}
+	}
+
+	if len(expired) > 0 {
+		x.logger.Info("removed expired exports", "count", len(expired))
+	}
+}
@@ -0,0 +1,73 @@
+package exports
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+)
+
+func TestWriteArchive(t *testing.T) {
+	d, err := db.Make(context.Background(), filepath.Join(t.TempDir(), "appview.db"), db.Options{})
+	assert.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	cfg := &config.Config{}
+	cfg.Export.Dir = t.TempDir()
+	x, err := New(d, nil, cfg, slog.Default())
+	assert.NoError(t, err)
+
+	tx, err := d.Begin()
+	assert.NoError(t, err)
+	mine := &models.Repo{Did: "did:plc:alice", Name: "core", Knot: "knot.example.com", Rkey: "3lrepo"}
+	assert.NoError(t, db.AddRepo(tx, mine))
+	theirs := &models.Repo{Did: "did:plc:bob", Name: "other", Knot: "knot.example.com", Rkey: "3lother"}
+	assert.NoError(t, db.AddRepo(tx, theirs))
+	assert.NoError(t, db.PutIssue(tx, &models.Issue{RepoAt: theirs.RepoAt(), Did: "did:plc:alice", Rkey: "3lissue", Title: "bug"}))
+	assert.NoError(t, db.PutIssue(tx, &models.Issue{RepoAt: mine.RepoAt(), Did: "did:plc:bob", Rkey: "3lbobs", Title: "not alice's"}))
+	assert.NoError(t, tx.Commit())
+
+	export, err := x.Request("did:plc:alice", false)
+	assert.NoError(t, err)
+	_, err = x.Request("did:plc:alice", false)
+	assert.IsError(t, err, ErrInProgress)
+
+	size, err := x.write(context.Background(), export)
+	assert.NoError(t, err)
+	assert.NotZero(t, size)
+
+	zr, err := zip.OpenReader(x.Path(export))
+	assert.NoError(t, err)
+	defer zr.Close()
+
+	read := func(name string, v any) {
+		t.Helper()
+		f, err := zr.Open(name)
+		assert.NoError(t, err)
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(data, v))
+	}
+
+	var m manifest
+	read("manifest.json", &m)
+	assert.Equal(t, "did:plc:alice", m.Did)
+	assert.SliceContains(t, m.Files, "strings.json")
+
+	var repos, issues []record
+	read("repos.json", &repos)
+	read("issues.json", &issues)
+	assert.Equal(t, 1, len(repos))
+	assert.Equal(t, mine.RepoAt().String(), repos[0].Uri)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "at://did:plc:alice/sh.tangled.repo.issue/3lissue", issues[0].Uri)
+}
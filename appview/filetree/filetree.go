@@ -4,6 +4,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"tangled.org/core/types"
 )
 
 type FileTreeNode struct {
@@ -11,6 +13,11 @@ type FileTreeNode struct {
 	Path        string
 	IsDirectory bool
 	Children    map[string]*FileTreeNode
+
+	// set by DiffFileTree only, summed up for directories
+	Insertions int64
+	Deletions  int64
+	IsBinary   bool
 }
 
 // NewNode creates a new node
@@ -29,34 +36,66 @@ func FileTree(files []string) *FileTreeNode {
 	sort.Strings(files)
 
 	for _, file := range files {
-		if file == "" {
+		rootNode.insert(file)
+	}
+
+	return rootNode
+}
+
+// DiffFileTree is a FileTree of the files in a diff, where every node also
+// counts the lines inserted and deleted under it.
+func DiffFileTree(files []types.FileStat) *FileTreeNode {
+	rootNode := newNode("", "", true)
+
+	for _, f := range files {
+		path := rootNode.insert(f.Name)
+		if len(path) == 0 {
 			continue
 		}
 
-		parts := strings.Split(filepath.Clean(file), "/")
-		if len(parts) == 0 {
-			continue
+		rootNode.Insertions += f.Insertions
+		rootNode.Deletions += f.Deletions
+		for _, node := range path {
+			node.Insertions += f.Insertions
+			node.Deletions += f.Deletions
 		}
+		path[len(path)-1].IsBinary = f.IsBinary
+	}
+
+	return rootNode
+}
+
+// insert adds file under n, and returns the nodes on the way to it.
+func (n *FileTreeNode) insert(file string) []*FileTreeNode {
+	if file == "" {
+		return nil
+	}
 
-		currentNode := rootNode
-		currentPath := ""
+	parts := strings.Split(filepath.Clean(file), "/")
+	if len(parts) == 0 {
+		return nil
+	}
 
-		for i, part := range parts {
-			if currentPath == "" {
-				currentPath = part
-			} else {
-				currentPath = filepath.Join(currentPath, part)
-			}
+	currentNode := n
+	currentPath := ""
+	path := make([]*FileTreeNode, 0, len(parts))
 
-			isDir := i < len(parts)-1
+	for i, part := range parts {
+		if currentPath == "" {
+			currentPath = part
+		} else {
+			currentPath = filepath.Join(currentPath, part)
+		}
 
-			if _, exists := currentNode.Children[part]; !exists {
-				currentNode.Children[part] = newNode(part, currentPath, isDir)
-			}
+		isDir := i < len(parts)-1
 
-			currentNode = currentNode.Children[part]
+		if _, exists := currentNode.Children[part]; !exists {
+			currentNode.Children[part] = newNode(part, currentPath, isDir)
 		}
+
+		currentNode = currentNode.Children[part]
+		path = append(path, currentNode)
 	}
 
-	return rootNode
+	return path
 }
@@ -24,3 +24,14 @@ func KeywordFieldQuery(field, keyword string) query.Query {
 	q.FieldVal = field
 	return q
 }
+
+// ExcludeKeywordsQuery matches what q matches, except documents whose field
+// is one of keywords.
+func ExcludeKeywordsQuery(q query.Query, field string, keywords []string) query.Query {
+	bq := bleve.NewBooleanQuery()
+	bq.AddMust(q)
+	for _, keyword := range keywords {
+		bq.AddMustNot(KeywordFieldQuery(field, keyword))
+	}
+	return bq
+}
@@ -0,0 +1,38 @@
+package bleveutil
+
+import (
+	"os"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// Rebuild creates a new index with the given mapping next to path, fills it
+// with populate and then moves it over the index at path. The index at path
+// is left untouched if populate fails, and a rebuild that was interrupted is
+// started over the next time.
+func Rebuild(path string, mapping mapping.IndexMapping, populate func(bleve.Index) error) error {
+	tmp := path + ".rebuild"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+
+	index, err := bleve.New(tmp, mapping)
+	if err != nil {
+		return err
+	}
+
+	if err := populate(index); err != nil {
+		index.Close()
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := index.Close(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,71 @@
+package bleveutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func docCount(t *testing.T, path string) uint64 {
+	t.Helper()
+	index, err := bleve.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	count, err := index.DocCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestRebuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bleve")
+
+	populate := func(ids ...string) func(bleve.Index) error {
+		return func(index bleve.Index) error {
+			for _, id := range ids {
+				if err := index.Index(id, map[string]any{"title": id}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if err := Rebuild(path, bleve.NewIndexMapping(), populate("a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	if n := docCount(t, path); n != 2 {
+		t.Fatalf("expected 2 documents, got %d", n)
+	}
+
+	// documents that are gone from the source are gone from the index
+	if err := Rebuild(path, bleve.NewIndexMapping(), populate("a")); err != nil {
+		t.Fatal(err)
+	}
+	if n := docCount(t, path); n != 1 {
+		t.Fatalf("expected 1 document, got %d", n)
+	}
+
+	// a failed rebuild keeps the previous index
+	failed := errors.New("failed")
+	err := Rebuild(path, bleve.NewIndexMapping(), func(index bleve.Index) error {
+		populate("a", "b", "c")(index)
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the populate error, got %v", err)
+	}
+	if n := docCount(t, path); n != 1 {
+		t.Fatalf("expected 1 document, got %d", n)
+	}
+	if _, err := os.Stat(path + ".rebuild"); !os.IsNotExist(err) {
+		t.Fatalf("expected the partial index to be removed, got %v", err)
+	}
+}
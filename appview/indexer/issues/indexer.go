@@ -160,14 +160,40 @@ func PopulateIndexer(ctx context.Context, ix *Indexer, e db.Execer) error {
 			return db.GetIssuesPaginated(e, page)
 		},
 		func(issues []models.Issue) error {
+			if err := ix.Index(ctx, issues...); err != nil {
+				return err
+			}
+			if (count+len(issues))/progressInterval > count/progressInterval {
+				l.Info("indexing issues", "count", count+len(issues))
+			}
 			count += len(issues)
-			return ix.Index(ctx, issues...)
+			return nil
 		},
 	)
 	l.Info("issues indexed", "count", count)
 	return err
 }
 
+// Rebuild indexes every issue into a new index that then replaces the one at
+// the indexer's path, see bleveutil.Rebuild. It is meant for an indexer that
+// was not initialized, with no appview running on the same index.
+func (ix *Indexer) Rebuild(ctx context.Context, e db.Execer) error {
+	if ix.indexer != nil {
+		return errors.New("indexer is already initialized")
+	}
+
+	mapping, err := generateIssueIndexMapping()
+	if err != nil {
+		return err
+	}
+
+	defer func() { ix.indexer = nil }()
+	return bleveutil.Rebuild(ix.path, mapping, func(index bleve.Index) error {
+		ix.indexer = index
+		return PopulateIndexer(ctx, ix, e)
+	})
+}
+
 // issueData data stored and will be indexed
 type issueData struct {
 	ID      int64  `json:"id"`
@@ -207,6 +233,9 @@ type SearchResult struct {
 
 const maxBatchSize = 20
 
+// progressInterval is how many issues are indexed between progress logs
+const progressInterval = 1000
+
 func (ix *Indexer) Index(ctx context.Context, issues ...models.Issue) error {
 	batch := bleveutil.NewFlushingBatch(ix.indexer, maxBatchSize)
 	for _, issue := range issues {
@@ -232,11 +261,18 @@ func (ix *Indexer) Search(ctx context.Context, opts models.IssueSearchOptions) (
 			bleveutil.MatchAndQuery("body", opts.Keyword, issueIndexerAnalyzer, 0),
 		))
 	}
-	queries = append(queries, bleveutil.KeywordFieldQuery("repo_at", opts.RepoAt))
-	queries = append(queries, bleveutil.BoolFieldQuery("is_open", opts.IsOpen))
+	if opts.RepoAt != "" {
+		queries = append(queries, bleveutil.KeywordFieldQuery("repo_at", opts.RepoAt))
+	}
+	if !opts.AnyState {
+		queries = append(queries, bleveutil.BoolFieldQuery("is_open", opts.IsOpen))
+	}
 	// TODO: append more queries
 
 	var indexerQuery query.Query = bleve.NewConjunctionQuery(queries...)
+	if len(opts.ExcludeRepoAts) > 0 {
+		indexerQuery = bleveutil.ExcludeKeywordsQuery(indexerQuery, "repo_at", opts.ExcludeRepoAts)
+	}
 	searchReq := bleve.NewSearchRequestOptions(indexerQuery, opts.Page.Limit, opts.Page.Offset, false)
 	res, err := ix.indexer.SearchInContext(ctx, searchReq)
 	if err != nil {
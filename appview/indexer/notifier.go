@@ -29,6 +29,15 @@ func (ix *Indexer) NewIssueState(ctx context.Context, actor syntax.DID, issue *m
 	}
 }
 
+func (ix *Indexer) EditIssue(ctx context.Context, issue *models.Issue) {
+	l := log.FromContext(ctx).With("notifier", "indexer", "issue", issue)
+	l.Debug("reindexing an edited issue")
+	err := ix.Issues.Index(ctx, *issue)
+	if err != nil {
+		l.Error("failed to index an issue", "err", err)
+	}
+}
+
 func (ix *Indexer) DeleteIssue(ctx context.Context, issue *models.Issue) {
 	l := log.FromContext(ctx).With("notifier", "indexer", "issue", issue)
 	l.Debug("deleting an issue")
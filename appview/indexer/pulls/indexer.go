@@ -155,12 +155,37 @@ func PopulateIndexer(ctx context.Context, ix *Indexer, e db.Execer) error {
 		return err
 	}
 	count := len(pulls)
-	err = ix.Index(ctx, pulls...)
+	for i := 0; i < count; i += progressInterval {
+		end := min(i+progressInterval, count)
+		if err := ix.Index(ctx, pulls[i:end]...); err != nil {
+			return err
+		}
+		if end < count {
+			l.Info("indexing pulls", "count", end, "total", count)
+		}
+	}
+	l.Info("pulls indexed", "count", count)
+	return nil
+}
+
+// Rebuild indexes every pull into a new index that then replaces the one at
+// the indexer's path, see bleveutil.Rebuild. It is meant for an indexer that
+// was not initialized, with no appview running on the same index.
+func (ix *Indexer) Rebuild(ctx context.Context, e db.Execer) error {
+	if ix.indexer != nil {
+		return errors.New("indexer is already initialized")
+	}
+
+	mapping, err := generatePullIndexMapping()
 	if err != nil {
 		return err
 	}
-	l.Info("pulls indexed", "count", count)
-	return err
+
+	defer func() { ix.indexer = nil }()
+	return bleveutil.Rebuild(ix.path, mapping, func(index bleve.Index) error {
+		ix.indexer = index
+		return PopulateIndexer(ctx, ix, e)
+	})
 }
 
 // pullData data stored and will be indexed
@@ -202,6 +227,9 @@ type searchResult struct {
 
 const maxBatchSize = 20
 
+// progressInterval is how many pulls are indexed between progress logs
+const progressInterval = 1000
+
 func (ix *Indexer) Index(ctx context.Context, pulls ...*models.Pull) error {
 	batch := bleveutil.NewFlushingBatch(ix.indexer, maxBatchSize)
 	for _, pull := range pulls {
@@ -233,10 +261,17 @@ func (ix *Indexer) Search(ctx context.Context, opts models.PullSearchOptions) (*
 			bleveutil.MatchAndQuery("body", opts.Keyword, pullIndexerAnalyzer, 0),
 		))
 	}
-	queries = append(queries, bleveutil.KeywordFieldQuery("repo_at", opts.RepoAt))
-	queries = append(queries, bleveutil.KeywordFieldQuery("state", opts.State.String()))
+	if opts.RepoAt != "" {
+		queries = append(queries, bleveutil.KeywordFieldQuery("repo_at", opts.RepoAt))
+	}
+	if !opts.AnyState {
+		queries = append(queries, bleveutil.KeywordFieldQuery("state", opts.State.String()))
+	}
 
 	var indexerQuery query.Query = bleve.NewConjunctionQuery(queries...)
+	if len(opts.ExcludeRepoAts) > 0 {
+		indexerQuery = bleveutil.ExcludeKeywordsQuery(indexerQuery, "repo_at", opts.ExcludeRepoAts)
+	}
 	searchReq := bleve.NewSearchRequestOptions(indexerQuery, limit, opts.Page.Offset, false)
 	res, err := ix.indexer.SearchInContext(ctx, searchReq)
 	if err != nil {
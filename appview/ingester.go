@@ -2,6 +2,7 @@ package appview
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -15,9 +16,12 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/ipfs/go-cid"
 	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/attachments"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/indexer"
 	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pages/markup"
 	"tangled.org/core/appview/serververify"
 	"tangled.org/core/appview/validator"
 	"tangled.org/core/idresolver"
@@ -31,6 +35,7 @@ type Ingester struct {
 	Config     *config.Config
 	Logger     *slog.Logger
 	Validator  *validator.Validator
+	Indexer    *indexer.Indexer
 }
 
 type processFunc func(ctx context.Context, e *jmodels.Event) error
@@ -53,17 +58,21 @@ func (i *Ingester) Ingest() processFunc {
 				err = i.IdResolver.InvalidateIdent(ctx, e.Account.Did)
 			}
 		case jmodels.EventKindIdentity:
-			err = i.IdResolver.InvalidateIdent(ctx, e.Identity.Did)
+			err = i.ingestIdentity(ctx, e)
 		case jmodels.EventKindCommit:
 			switch e.Commit.Collection {
 			case tangled.GraphFollowNSID:
 				err = i.ingestFollow(e)
 			case tangled.FeedStarNSID:
 				err = i.ingestStar(e)
+			case tangled.GraphWatchNSID:
+				err = i.ingestWatch(e)
 			case tangled.PublicKeyNSID:
 				err = i.ingestPublicKey(e)
 			case tangled.RepoArtifactNSID:
 				err = i.ingestArtifact(e)
+			case tangled.RepoReleaseNSID:
+				err = i.ingestRelease(e)
 			case tangled.ActorProfileNSID:
 				err = i.ingestProfile(e)
 			case tangled.SpindleMemberNSID:
@@ -83,7 +92,7 @@ func (i *Ingester) Ingest() processFunc {
 			case tangled.LabelDefinitionNSID:
 				err = i.ingestLabelDefinition(e)
 			case tangled.LabelOpNSID:
-				err = i.ingestLabelOp(e)
+				err = i.ingestLabelOp(ctx, e)
 			}
 			l = i.Logger.With("nsid", e.Commit.Collection)
 		}
@@ -96,6 +105,36 @@ func (i *Ingester) Ingest() processFunc {
 	}
 }
 
+// ingestIdentity records the handles of tangled users before and after a
+// handle change, and drops the cached identity.
+func (i *Ingester) ingestIdentity(ctx context.Context, e *jmodels.Event) error {
+	did := e.Identity.Did
+
+	// the event stream covers every account, only users with repos have
+	// links worth redirecting
+	repos, err := db.CountRepos(i.Db, db.FilterEq("did", did))
+	if err != nil {
+		return err
+	}
+
+	if repos > 0 {
+		// still cached with the previous handle
+		if ident, err := i.IdResolver.ResolveIdent(ctx, did); err == nil && !ident.Handle.IsInvalidHandle() {
+			if err := db.RecordHandle(i.Db, ident.Handle.String(), did); err != nil {
+				return err
+			}
+		}
+
+		if e.Identity.Handle != nil {
+			if err := db.RecordHandle(i.Db, *e.Identity.Handle, did); err != nil {
+				return err
+			}
+		}
+	}
+
+	return i.IdResolver.InvalidateIdent(ctx, did)
+}
+
 func (i *Ingester) ingestStar(e *jmodels.Event) error {
 	var err error
 	did := e.Did
@@ -136,6 +175,46 @@ func (i *Ingester) ingestStar(e *jmodels.Event) error {
 	return nil
 }
 
+func (i *Ingester) ingestWatch(e *jmodels.Event) error {
+	var err error
+	did := e.Did
+
+	l := i.Logger.With("handler", "ingestWatch")
+	l = l.With("nsid", e.Commit.Collection)
+
+	switch e.Commit.Operation {
+	case jmodels.CommitOperationCreate, jmodels.CommitOperationUpdate:
+		raw := json.RawMessage(e.Commit.Record)
+		record := tangled.GraphWatch{}
+		err = json.Unmarshal(raw, &record)
+		if err != nil {
+			l.Error("invalid record", "err", err)
+			return err
+		}
+
+		var subjectUri syntax.ATURI
+		subjectUri, err = syntax.ParseATURI(record.Subject)
+		if err != nil {
+			l.Error("invalid record", "err", err)
+			return err
+		}
+
+		err = db.AddWatch(i.Db, &models.Watch{
+			Did:    did,
+			RepoAt: subjectUri,
+			Rkey:   e.Commit.RKey,
+		})
+	case jmodels.CommitOperationDelete:
+		err = db.DeleteWatchByRkey(i.Db, did, e.Commit.RKey)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to %s watch record: %w", e.Commit.Operation, err)
+	}
+
+	return nil
+}
+
 func (i *Ingester) ingestFollow(e *jmodels.Event) error {
 	var err error
 	did := e.Did
@@ -192,7 +271,7 @@ func (i *Ingester) ingestPublicKey(e *jmodels.Event) error {
 		err = db.AddPublicKey(i.Db, did, name, key, e.Commit.RKey)
 	case jmodels.CommitOperationDelete:
 		l.Debug("processing delete of pubkey")
-		err = db.DeletePublicKeyByRkey(i.Db, did, e.Commit.RKey)
+		err = db.RevokePublicKeyByRkey(i.Db, did, e.Commit.RKey)
 	}
 
 	if err != nil {
@@ -263,6 +342,58 @@ func (i *Ingester) ingestArtifact(e *jmodels.Event) error {
 	return nil
 }
 
+func (i *Ingester) ingestRelease(e *jmodels.Event) error {
+	did := e.Did
+	var err error
+
+	l := i.Logger.With("handler", "ingestRelease")
+	l = l.With("nsid", e.Commit.Collection)
+
+	switch e.Commit.Operation {
+	case jmodels.CommitOperationCreate, jmodels.CommitOperationUpdate:
+		raw := json.RawMessage(e.Commit.Record)
+		record := tangled.RepoRelease{}
+		err = json.Unmarshal(raw, &record)
+		if err != nil {
+			l.Error("invalid record", "err", err)
+			return err
+		}
+
+		release, err := models.ReleaseFromRecord(did, e.Commit.RKey, record)
+		if err != nil {
+			return err
+		}
+
+		repo, err := db.GetRepoByAtUri(i.Db, release.RepoAt.String())
+		if err != nil {
+			return err
+		}
+
+		ok, err := i.Enforcer.E.Enforce(did, repo.Knot, repo.DidSlashRepo(), "repo:push")
+		if err != nil || !ok {
+			return err
+		}
+
+		if e.Commit.Operation == jmodels.CommitOperationUpdate {
+			now := time.Now()
+			release.Edited = &now
+		}
+
+		err = db.PutRelease(i.Db, release)
+		if err != nil {
+			return fmt.Errorf("failed to %s release record: %w", e.Commit.Operation, err)
+		}
+	case jmodels.CommitOperationDelete:
+		err = db.DeleteRelease(i.Db, db.FilterEq("did", did), db.FilterEq("rkey", e.Commit.RKey))
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to %s release record: %w", e.Commit.Operation, err)
+	}
+
+	return nil
+}
+
 func (i *Ingester) ingestProfile(e *jmodels.Event) error {
 	did := e.Did
 	var err error
@@ -329,7 +460,7 @@ func (i *Ingester) ingestProfile(e *jmodels.Event) error {
 			Location:       location,
 			Links:          links,
 			Stats:          stats,
-			PinnedRepos:    pinned,
+			Pins:           pinned,
 			Pronouns:       pronouns,
 		}
 
@@ -490,9 +621,15 @@ func (i *Ingester) ingestSpindle(ctx context.Context, e *jmodels.Event) error {
 			return fmt.Errorf("failed to index profile record, invalid db cast")
 		}
 
+		approval := models.ApprovalApproved
+		if i.Config.Admin.ReviewsRegistrations() {
+			approval = models.ApprovalPending
+		}
+
 		err := db.AddSpindle(ddb, models.Spindle{
 			Owner:    syntax.DID(did),
 			Instance: instance,
+			Approval: approval,
 		})
 		if err != nil {
 			l.Error("failed to add spindle to db", "err", err, "instance", instance)
@@ -710,7 +847,12 @@ func (i *Ingester) ingestKnot(e *jmodels.Event) error {
 			return fmt.Errorf("failed to index profile record, invalid db cast")
 		}
 
-		err := db.AddKnot(ddb, domain, did)
+		approval := models.ApprovalApproved
+		if i.Config.Admin.ReviewsRegistrations() {
+			approval = models.ApprovalPending
+		}
+
+		err := db.AddKnot(ddb, domain, did, approval)
 		if err != nil {
 			l.Error("failed to add knot to db", "err", err, "domain", domain)
 			return err
@@ -819,37 +961,86 @@ func (i *Ingester) ingestIssue(ctx context.Context, e *jmodels.Event) error {
 			return fmt.Errorf("failed to validate issue: %w", err)
 		}
 
-		tx, err := ddb.BeginTx(ctx, nil)
-		if err != nil {
-			l.Error("failed to begin transaction", "err", err)
-			return err
-		}
-		defer tx.Rollback()
+		err = ddb.Transact(ctx, func(tx *sql.Tx) error {
+			err := db.PutIssue(tx, &issue)
+			if err != nil {
+				l.Error("failed to create issue", "err", err)
+				return err
+			}
+
+			err = db.PutReferenceLinks(tx, issue.RepoAt, issue.AtUri(), issue.AtUri(), markup.FindReferences(issue.Body))
+			if err != nil {
+				l.Error("failed to record references", "err", err)
+			}
+
+			if err := db.MarkAttachmentsUsed(tx, did, attachments.Find(issue.Body)); err != nil {
+				l.Error("failed to mark attachments used", "err", err)
+			}
 
-		err = db.PutIssue(tx, &issue)
+			err = db.AddPunchEvent(tx, models.PunchEvent{
+				Did:       did,
+				SubjectAt: issue.AtUri(),
+				Kind:      models.PunchIssue,
+				Created:   issue.Created,
+			})
+			if err != nil {
+				l.Error("failed to add punch event", "err", err)
+			}
+
+			return nil
+		})
 		if err != nil {
-			l.Error("failed to create issue", "err", err)
+			l.Error("failed to commit txn", "err", err)
 			return err
 		}
 
-		err = tx.Commit()
+		// reread to index the issue as stored, the record has no state
+		issues, err := db.GetIssues(ddb, db.FilterEq("at_uri", issue.AtUri()))
+		if err == nil && len(issues) == 1 {
+			err = i.Indexer.Issues.Index(ctx, issues[0])
+		}
 		if err != nil {
-			l.Error("failed to commit txn", "err", err)
-			return err
+			l.Error("failed to index issue", "err", err)
 		}
 
 		return nil
 
 	case jmodels.CommitOperationDelete:
+		issues, err := db.GetIssues(
+			ddb,
+			db.FilterEq("did", did),
+			db.FilterEq("rkey", rkey),
+		)
+		if err != nil {
+			l.Error("failed to get issue", "err", err)
+		}
+		for _, issue := range issues {
+			if err := i.Indexer.Issues.Delete(ctx, issue.Id); err != nil {
+				l.Error("failed to unindex issue", "err", err)
+			}
+		}
+
+		// issues kept as placeholders when their author deleted their
+		// account stay, see db.AnonymizeAccountContent
 		if err := db.DeleteIssues(
 			ddb,
 			db.FilterEq("did", did),
 			db.FilterEq("rkey", rkey),
+			db.FilterIs("deleted", nil),
 		); err != nil {
 			l.Error("failed to delete", "err", err)
 			return fmt.Errorf("failed to delete issue record: %w", err)
 		}
 
+		issueAt := syntax.ATURI(fmt.Sprintf("at://%s/%s/%s", did, tangled.RepoIssueNSID, rkey))
+		if err := db.DeleteReferenceLinks(ddb, db.FilterEq("thread_at", issueAt)); err != nil {
+			l.Error("failed to delete references", "err", err)
+		}
+
+		if err := db.DeletePunchEvents(ddb, db.FilterEq("subject_at", issueAt)); err != nil {
+			l.Error("failed to delete punch event", "err", err)
+		}
+
 		return nil
 	}
 
@@ -893,6 +1084,29 @@ func (i *Ingester) ingestIssueComment(e *jmodels.Event) error {
 			return fmt.Errorf("failed to create issue comment: %w", err)
 		}
 
+		err = db.AddPunchEvent(ddb, models.PunchEvent{
+			Did:       did,
+			SubjectAt: comment.AtUri(),
+			Kind:      models.PunchIssueComment,
+			Created:   comment.Created,
+		})
+		if err != nil {
+			l.Error("failed to add punch event", "err", err)
+		}
+
+		issues, err := db.GetIssues(ddb, db.FilterEq("at_uri", comment.IssueAt))
+		if err == nil && len(issues) == 1 {
+			issue := issues[0]
+			err = db.PutReferenceLinks(ddb, issue.RepoAt, comment.AtUri(), issue.AtUri(), markup.FindReferences(comment.Body))
+		}
+		if err != nil {
+			l.Error("failed to record references", "err", err)
+		}
+
+		if err := db.MarkAttachmentsUsed(ddb, did, attachments.Find(comment.Body)); err != nil {
+			l.Error("failed to mark attachments used", "err", err)
+		}
+
 		return nil
 
 	case jmodels.CommitOperationDelete:
@@ -904,6 +1118,15 @@ func (i *Ingester) ingestIssueComment(e *jmodels.Event) error {
 			return fmt.Errorf("failed to delete issue comment record: %w", err)
 		}
 
+		commentAt := syntax.ATURI(fmt.Sprintf("at://%s/%s/%s", did, tangled.RepoIssueCommentNSID, rkey))
+		if err := db.DeleteReferenceLinks(ddb, db.FilterEq("from_at", commentAt)); err != nil {
+			l.Error("failed to delete references", "err", err)
+		}
+
+		if err := db.DeletePunchEvents(ddb, db.FilterEq("subject_at", commentAt)); err != nil {
+			l.Error("failed to delete punch event", "err", err)
+		}
+
 		return nil
 	}
 
@@ -964,7 +1187,7 @@ func (i *Ingester) ingestLabelDefinition(e *jmodels.Event) error {
 	return nil
 }
 
-func (i *Ingester) ingestLabelOp(e *jmodels.Event) error {
+func (i *Ingester) ingestLabelOp(ctx context.Context, e *jmodels.Event) error {
 	did := e.Did
 	rkey := e.Commit.RKey
 
@@ -1019,20 +1242,15 @@ func (i *Ingester) ingestLabelOp(e *jmodels.Event) error {
 			}
 		}
 
-		tx, err := ddb.Begin()
-		if err != nil {
-			return err
-		}
-		defer tx.Rollback()
-
-		for _, o := range ops {
-			_, err = db.AddLabelOp(tx, &o)
-			if err != nil {
-				return fmt.Errorf("failed to add labelop: %w", err)
+		err = ddb.Transact(ctx, func(tx *sql.Tx) error {
+			for _, o := range ops {
+				if _, err := db.AddLabelOp(tx, &o); err != nil {
+					return fmt.Errorf("failed to add labelop: %w", err)
+				}
 			}
-		}
-
-		if err = tx.Commit(); err != nil {
+			return nil
+		})
+		if err != nil {
 			return err
 		}
 	}
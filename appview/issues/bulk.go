@@ -0,0 +1,383 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	atpclient "github.com/bluesky-social/indigo/atproto/client"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/tid"
+)
+
+// maxBulkIssues keeps the records of one bulk action within a single
+// applyWrites call.
+const maxBulkIssues = 100
+
+type bulkAction string
+
+const (
+	bulkClose  bulkAction = "close"
+	bulkReopen bulkAction = "reopen"
+	bulkLabel  bulkAction = "label"
+	bulkAssign bulkAction = "assign"
+)
+
+// bulkChange is what a bulk action does to one issue. Closing and reopening
+// write no record, as with a single issue.
+type bulkChange struct {
+	issue   *models.Issue
+	labelOp *models.LabelOp
+	write   *comatproto.RepoApplyWrites_Input_Writes_Elem
+	rkey    string
+	nsid    string
+}
+
+// BulkIssues closes, reopens, labels or assigns several issues at once.
+// Issues that the action does not apply to are skipped and reported back,
+// the rest are changed together.
+func (rp *Issues) BulkIssues(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "BulkIssues")
+	noticeId := "bulk-issues-result"
+	user := rp.oauth.GetUser(r)
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	if !f.RolesInRepo(user).IsTriageAllowed() {
+		rp.pages.Notice(w, noticeId, "You are not allowed to triage issues in this repository.")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		rp.pages.Notice(w, noticeId, "Invalid form.")
+		return
+	}
+
+	var numbers []int
+	for _, v := range r.Form["issue"] {
+		if n, err := strconv.Atoi(v); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	if len(numbers) == 0 {
+		rp.pages.Notice(w, noticeId, "Select some issues first.")
+		return
+	}
+	if len(numbers) > maxBulkIssues {
+		rp.pages.Notice(w, noticeId, fmt.Sprintf("Select at most %d issues at a time.", maxBulkIssues))
+		return
+	}
+
+	issues, err := db.GetIssues(
+		rp.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterIn("issue_id", numbers),
+	)
+	if err != nil {
+		l.Error("failed to get issues", "err", err)
+		rp.pages.Notice(w, noticeId, "Failed to load issues. Try again later.")
+		return
+	}
+
+	action := bulkAction(r.FormValue("action"))
+	var skipped []string
+	var changes []bulkChange
+
+	switch action {
+	case bulkClose, bulkReopen:
+		changes, skipped = stateChanges(action, issues)
+
+	case bulkLabel, bulkAssign:
+		// labels picked for bulk labelling have no value
+		key := r.FormValue("label")
+		value := "null"
+		if action == bulkAssign {
+			key = r.FormValue("assignLabel")
+			assignee := strings.TrimPrefix(strings.TrimSpace(r.FormValue("assignee")), "@")
+			ident, err := rp.idResolver.ResolveIdent(r.Context(), assignee)
+			if err != nil {
+				rp.pages.Notice(w, noticeId, fmt.Sprintf("Could not find user %q.", assignee))
+				return
+			}
+			value = ident.DID.String()
+		}
+
+		actx, err := db.NewLabelApplicationCtx(rp.db, db.FilterIn("at_uri", f.Repo.Labels))
+		if err != nil {
+			l.Error("failed to get labels", "err", err)
+			rp.pages.Notice(w, noticeId, "Failed to load labels. Try again later.")
+			return
+		}
+		def, ok := actx.Defs[key]
+		if !ok {
+			rp.pages.Notice(w, noticeId, "Pick a label of this repository.")
+			return
+		}
+
+		var subjects []string
+		for _, issue := range issues {
+			subjects = append(subjects, issue.AtUri().String())
+		}
+		existing, err := db.GetLabelOps(rp.db, db.FilterIn("subject", subjects))
+		if err != nil {
+			l.Error("failed to get label ops", "err", err)
+			rp.pages.Notice(w, noticeId, "Failed to load labels. Try again later.")
+			return
+		}
+
+		changes, skipped = rp.labelChanges(actx, def, &f.Repo, user.Did, value, issues, existing)
+
+	default:
+		rp.pages.Notice(w, noticeId, "Unknown action.")
+		return
+	}
+
+	skipped = append(skipped, missingIssues(numbers, issues)...)
+
+	if len(changes) > 0 {
+		if err := rp.applyBulkChanges(r, action, changes); err != nil {
+			l.Error("failed to apply bulk changes", "action", action, "err", err)
+			rp.pages.Notice(w, noticeId, "Failed to update issues, none were changed. Try again later.")
+			return
+		}
+	}
+
+	if len(skipped) == 0 {
+		rp.pages.HxRefresh(w)
+		return
+	}
+
+	rp.pages.Notice(w, noticeId, fmt.Sprintf(
+		"Updated %d of %d issues, reload to see them. Skipped %s.",
+		len(changes),
+		len(numbers),
+		strings.Join(skipped, "; "),
+	))
+}
+
+// stateChanges closes or reopens issues, skipping those that already are.
+func stateChanges(action bulkAction, issues []models.Issue) ([]bulkChange, []string) {
+	var changes []bulkChange
+	var skipped []string
+	for i := range issues {
+		issue := &issues[i]
+		if issue.Open == (action == bulkReopen) {
+			skipped = append(skipped, fmt.Sprintf("#%d is already %s", issue.IssueId, issue.State()))
+			continue
+		}
+		changes = append(changes, bulkChange{issue: issue})
+	}
+	return changes, skipped
+}
+
+// labelChanges adds the label def with value to issues, skipping those that
+// the label can't be added to or that already have it. existing are the label
+// ops of all issues.
+func (rp *Issues) labelChanges(
+	actx *models.LabelApplicationCtx,
+	def *models.LabelDefinition,
+	repo *models.Repo,
+	did, value string,
+	issues []models.Issue,
+	existing []models.LabelOp,
+) ([]bulkChange, []string) {
+	var changes []bulkChange
+	var skipped []string
+
+	now := time.Now()
+	for i := range issues {
+		issue := &issues[i]
+
+		var ops []models.LabelOp
+		for _, op := range existing {
+			if op.Subject == issue.AtUri() {
+				ops = append(ops, op)
+			}
+		}
+		state := models.NewLabelState()
+		actx.ApplyLabelOps(state, ops)
+
+		op := models.LabelOp{
+			Did:          did,
+			Rkey:         tid.TID(),
+			Subject:      issue.AtUri(),
+			Operation:    models.LabelOperationAdd,
+			OperandKey:   def.AtUri().String(),
+			OperandValue: value,
+			PerformedAt:  now,
+			IndexedAt:    now,
+		}
+		if err := rp.validator.ValidateLabelOp(def, repo, &op); err != nil {
+			skipped = append(skipped, fmt.Sprintf("#%d: %s", issue.IssueId, err))
+			continue
+		}
+		if err := actx.ApplyLabelOp(state, op); err == models.LabelNoOpError {
+			skipped = append(skipped, fmt.Sprintf("#%d already has it", issue.IssueId))
+			continue
+		}
+
+		record := models.LabelOpsAsRecord([]models.LabelOp{op})
+		changes = append(changes, bulkChange{
+			issue:   issue,
+			labelOp: &op,
+			rkey:    op.Rkey,
+			nsid:    tangled.LabelOpNSID,
+			write: &comatproto.RepoApplyWrites_Input_Writes_Elem{
+				RepoApplyWrites_Create: &comatproto.RepoApplyWrites_Create{
+					Collection: tangled.LabelOpNSID,
+					Rkey:       &op.Rkey,
+					Value: &lexutil.LexiconTypeDecoder{
+						Val: &record,
+					},
+				},
+			},
+		})
+	}
+	return changes, skipped
+}
+
+// missingIssues lists the issues that were picked but no longer exist in
+// the repo.
+func missingIssues(numbers []int, issues []models.Issue) []string {
+	var skipped []string
+	for _, n := range numbers {
+		found := false
+		for _, issue := range issues {
+			found = found || issue.IssueId == n
+		}
+		if !found {
+			skipped = append(skipped, fmt.Sprintf("#%d does not exist", n))
+		}
+	}
+	return skipped
+}
+
+// applyBulkChanges applies changes for the user of r, and lets others know
+// of the issues that were closed or reopened.
+func (rp *Issues) applyBulkChanges(r *http.Request, action bulkAction, changes []bulkChange) error {
+	user := rp.oauth.GetUser(r)
+
+	client, err := rp.oauth.AuthorizedClient(r)
+	if err != nil {
+		return fmt.Errorf("failed to get authorized client: %w", err)
+	}
+
+	if err := rp.writeBulkChanges(r.Context(), client, user.Did, action, changes); err != nil {
+		return err
+	}
+
+	if action == bulkClose || action == bulkReopen {
+		for _, c := range changes {
+			c.issue.Open = action == bulkReopen
+			rp.notifier.NewIssueState(r.Context(), syntax.DID(user.Did), c.issue)
+		}
+	}
+
+	return nil
+}
+
+// writeBulkChanges writes the records of all changes in one go, and then
+// applies them to the database in one transaction. The records are removed
+// again if the transaction fails.
+func (rp *Issues) writeBulkChanges(ctx context.Context, client *atpclient.APIClient, did string, action bulkAction, changes []bulkChange) error {
+	var writes []*comatproto.RepoApplyWrites_Input_Writes_Elem
+	for _, c := range changes {
+		if c.write != nil {
+			writes = append(writes, c.write)
+		}
+	}
+
+	if len(writes) > 0 {
+		_, err := comatproto.RepoApplyWrites(ctx, client, &comatproto.RepoApplyWrites_Input{
+			Repo:   did,
+			Writes: writes,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write records: %w", err)
+		}
+	}
+
+	if err := rp.applyBulkChangesToDb(did, action, changes); err != nil {
+		if len(writes) > 0 {
+			if err := deleteBulkRecords(context.WithoutCancel(ctx), client, did, changes); err != nil {
+				rp.logger.Error("failed to delete records of failed bulk change", "err", err)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (rp *Issues) applyBulkChangesToDb(did string, action bulkAction, changes []bulkChange) error {
+	tx, err := rp.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range changes {
+		switch action {
+		case bulkClose, bulkReopen:
+			kind := models.ThreadEventClosed
+			update := db.CloseIssues
+			if action == bulkReopen {
+				kind = models.ThreadEventReopened
+				update = db.ReopenIssues
+			}
+
+			if err := update(tx, db.FilterEq("id", c.issue.Id)); err != nil {
+				return err
+			}
+			err := db.AddThreadEvent(tx, models.ThreadEvent{
+				ThreadAt: c.issue.AtUri(),
+				Kind:     kind,
+				Did:      did,
+			})
+			if err != nil {
+				return err
+			}
+
+		case bulkLabel, bulkAssign:
+			if _, err := db.AddLabelOp(tx, c.labelOp); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func deleteBulkRecords(ctx context.Context, client *atpclient.APIClient, did string, changes []bulkChange) error {
+	var writes []*comatproto.RepoApplyWrites_Input_Writes_Elem
+	for _, c := range changes {
+		if c.write == nil {
+			continue
+		}
+		writes = append(writes, &comatproto.RepoApplyWrites_Input_Writes_Elem{
+			RepoApplyWrites_Delete: &comatproto.RepoApplyWrites_Delete{
+				Collection: c.nsid,
+				Rkey:       c.rkey,
+			},
+		})
+	}
+
+	_, err := comatproto.RepoApplyWrites(ctx, client, &comatproto.RepoApplyWrites_Input{
+		Repo:   did,
+		Writes: writes,
+	})
+	return err
+}
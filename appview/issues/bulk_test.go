@@ -0,0 +1,260 @@
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	atpclient "github.com/bluesky-social/indigo/atproto/client"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/validator"
+	"tangled.org/core/rbac"
+)
+
+const (
+	owner = "did:plc:alice"
+	other = "did:plc:mallory"
+	knot  = "knot.example.com"
+)
+
+func testIssues(t *testing.T) (*Issues, *db.DB) {
+	t.Helper()
+	dir := t.TempDir()
+
+	d, err := db.Make(context.Background(), filepath.Join(dir, "appview.db"), db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+
+	enforcer, err := rbac.NewEnforcer(filepath.Join(dir, "acl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enforcer.AddKnot(knot); err != nil {
+		t.Fatal(err)
+	}
+	if err := enforcer.AddRepo(owner, knot, owner+"/project"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Issues{
+		db:        d,
+		logger:    slog.Default(),
+		validator: validator.New(d, nil, enforcer, config.PatchConfig{}),
+	}, d
+}
+
+func testIssueList() []models.Issue {
+	return []models.Issue{
+		{Did: owner, Rkey: "3lone", IssueId: 1, Open: true},
+		{Did: owner, Rkey: "3ltwo", IssueId: 2, Open: false},
+		{Did: owner, Rkey: "3lthree", IssueId: 3, Open: true},
+	}
+}
+
+func issueIds(changes []bulkChange) []int {
+	var ids []int
+	for _, c := range changes {
+		ids = append(ids, c.issue.IssueId)
+	}
+	return ids
+}
+
+func TestStateChanges(t *testing.T) {
+	changes, skipped := stateChanges(bulkClose, testIssueList())
+	if got := issueIds(changes); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("expected to close 1 and 3, got %v", got)
+	}
+	if want := []string{"#2 is already closed"}; !slices.Equal(skipped, want) {
+		t.Errorf("expected %v, got %v", want, skipped)
+	}
+	for _, c := range changes {
+		if c.write != nil {
+			t.Errorf("expected no record for #%d", c.issue.IssueId)
+		}
+	}
+
+	changes, skipped = stateChanges(bulkReopen, testIssueList())
+	if got := issueIds(changes); !slices.Equal(got, []int{2}) {
+		t.Errorf("expected to reopen 2, got %v", got)
+	}
+	if want := []string{"#1 is already open", "#3 is already open"}; !slices.Equal(skipped, want) {
+		t.Errorf("expected %v, got %v", want, skipped)
+	}
+}
+
+func TestLabelChanges(t *testing.T) {
+	rp, _ := testIssues(t)
+	repo := &models.Repo{Did: owner, Name: "project", Knot: knot}
+	def := &models.LabelDefinition{
+		Did:       owner,
+		Rkey:      "3lbug",
+		Name:      "bug",
+		ValueType: models.ValueType{Type: models.ConcreteTypeNull},
+	}
+	actx := &models.LabelApplicationCtx{Defs: map[string]*models.LabelDefinition{def.AtUri().String(): def}}
+
+	issues := testIssueList()
+	existing := []models.LabelOp{{
+		Did:          owner,
+		Subject:      issues[1].AtUri(),
+		Operation:    models.LabelOperationAdd,
+		OperandKey:   def.AtUri().String(),
+		OperandValue: "null",
+	}}
+
+	changes, skipped := rp.labelChanges(actx, def, repo, owner, "null", issues, existing)
+	if got := issueIds(changes); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("expected to label 1 and 3, got %v", got)
+	}
+	if want := []string{"#2 already has it"}; !slices.Equal(skipped, want) {
+		t.Errorf("expected %v, got %v", want, skipped)
+	}
+	for _, c := range changes {
+		if c.write == nil || c.labelOp.Operation != models.LabelOperationAdd {
+			t.Errorf("expected a record adding the label to #%d", c.issue.IssueId)
+		}
+	}
+
+	// those who can't triage are turned away on every issue
+	changes, skipped = rp.labelChanges(actx, def, repo, other, "null", issues, nil)
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", issueIds(changes))
+	}
+	if len(skipped) != 3 {
+		t.Errorf("expected every issue to be skipped, got %v", skipped)
+	}
+}
+
+func TestMissingIssues(t *testing.T) {
+	skipped := missingIssues([]int{1, 4, 2}, testIssueList())
+	if want := []string{"#4 does not exist"}; !slices.Equal(skipped, want) {
+		t.Errorf("expected %v, got %v", want, skipped)
+	}
+}
+
+// fakePds records the writes of every applyWrites call made to it.
+type fakePds struct {
+	mu    sync.Mutex
+	calls [][]map[string]any
+}
+
+func (p *fakePds) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/xrpc/com.atproto.repo.applyWrites" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var input struct {
+		Writes []map[string]any `json:"writes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.calls = append(p.calls, input.Writes)
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func TestWriteBulkChanges(t *testing.T) {
+	def := &models.LabelDefinition{
+		Did:       owner,
+		Rkey:      "3lbug",
+		Name:      "bug",
+		ValueType: models.ValueType{Type: models.ConcreteTypeNull},
+	}
+	actx := &models.LabelApplicationCtx{Defs: map[string]*models.LabelDefinition{def.AtUri().String(): def}}
+	repo := &models.Repo{Did: owner, Name: "project", Knot: knot}
+
+	t.Run("records and rows are added together", func(t *testing.T) {
+		rp, d := testIssues(t)
+		pds := &fakePds{}
+		srv := httptest.NewServer(pds)
+		defer srv.Close()
+
+		if _, err := db.AddLabelDefinition(d, def); err != nil {
+			t.Fatal(err)
+		}
+
+		changes, _ := rp.labelChanges(actx, def, repo, owner, "null", testIssueList(), nil)
+		err := rp.writeBulkChanges(context.Background(), atpclient.NewAPIClient(srv.URL), owner, bulkLabel, changes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pds.calls) != 1 || len(pds.calls[0]) != 3 {
+			t.Fatalf("expected one call creating 3 records, got %v", pds.calls)
+		}
+		ops, err := db.GetLabelOps(d, db.FilterEq("did", owner))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ops) != 3 {
+			t.Errorf("expected 3 label ops, got %d", len(ops))
+		}
+	})
+
+	t.Run("records are deleted when the transaction fails", func(t *testing.T) {
+		rp, d := testIssues(t)
+		pds := &fakePds{}
+		srv := httptest.NewServer(pds)
+		defer srv.Close()
+
+		changes, _ := rp.labelChanges(actx, def, repo, owner, "null", testIssueList(), nil)
+		d.Close()
+
+		err := rp.writeBulkChanges(context.Background(), atpclient.NewAPIClient(srv.URL), owner, bulkLabel, changes)
+		if err == nil {
+			t.Fatal("expected the transaction to fail")
+		}
+
+		if len(pds.calls) != 2 {
+			t.Fatalf("expected the records to be created and deleted, got %v", pds.calls)
+		}
+		var created, deleted []string
+		for _, w := range pds.calls[0] {
+			created = append(created, w["rkey"].(string))
+		}
+		for _, w := range pds.calls[1] {
+			if w["$type"] != "com.atproto.repo.applyWrites#delete" {
+				t.Errorf("expected a delete, got %v", w["$type"])
+			}
+			deleted = append(deleted, w["rkey"].(string))
+		}
+		if !slices.Equal(created, deleted) {
+			t.Errorf("expected %v to be deleted, got %v", created, deleted)
+		}
+	})
+
+	t.Run("closing writes no records", func(t *testing.T) {
+		rp, d := testIssues(t)
+		pds := &fakePds{}
+		srv := httptest.NewServer(pds)
+		defer srv.Close()
+
+		changes, _ := stateChanges(bulkClose, testIssueList())
+		d.Close()
+
+		err := rp.writeBulkChanges(context.Background(), atpclient.NewAPIClient(srv.URL), owner, bulkClose, changes)
+		if err == nil {
+			t.Fatal("expected the transaction to fail")
+		}
+		if len(pds.calls) != 0 {
+			t.Errorf("expected no calls to the PDS, got %v", pds.calls)
+		}
+	})
+}
@@ -7,7 +7,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"slices"
+	"net/url"
+	"strconv"
 	"time"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
@@ -17,9 +18,11 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/attachments"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
 	issues_indexer "tangled.org/core/appview/indexer/issues"
+	"tangled.org/core/appview/labels"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/notify"
 	"tangled.org/core/appview/oauth"
@@ -97,6 +100,51 @@ func (rp *Issues) RepoSingleIssue(w http.ResponseWriter, r *http.Request) {
 		userReactions = db.GetReactionStatusMap(rp.db, user.Did, issue.AtUri())
 	}
 
+	var commentAts []syntax.ATURI
+	var commentSubjects []string
+	for _, c := range issue.Comments {
+		commentAts = append(commentAts, c.AtUri())
+		commentSubjects = append(commentSubjects, c.AtUri().String())
+	}
+
+	// comments that were taken down are shown as deleted
+	takedowns, err := db.GetTakedowns(rp.db, commentSubjects)
+	if err != nil {
+		l.Error("failed to get comment takedowns", "err", err)
+	}
+	for i, c := range issue.Comments {
+		if takedown, ok := takedowns[c.AtUri().String()]; ok {
+			issue.Comments[i].Body = ""
+			issue.Comments[i].Deleted = &takedown.Created
+			issue.Comments[i].TakenDown = true
+		}
+	}
+
+	commentReactions, err := db.GetReactionMaps(rp.db, 20, commentAts)
+	if err != nil {
+		l.Error("failed to get comment reactions", "err", err)
+	}
+
+	commentUserReactions := map[syntax.ATURI]map[models.ReactionKind]bool{}
+	if user != nil {
+		commentUserReactions, err = db.GetReactionStatusMaps(rp.db, user.Did, commentAts)
+		if err != nil {
+			l.Error("failed to get comment reaction statuses", "err", err)
+		}
+	}
+
+	sources := []string{issue.Body}
+	for _, c := range issue.Comments {
+		sources = append(sources, c.Body)
+	}
+	readable := rp.readableRepos(user)
+	rp.resolveReferences(issue, readable, sources...)
+
+	backlinks, err := db.GetBacklinks(rp.db, issue.AtUri(), readable)
+	if err != nil {
+		l.Error("failed to get backlinks", "err", err)
+	}
+
 	labelDefs, err := db.GetLabelDefinitions(
 		rp.db,
 		db.FilterIn("at_uri", f.Repo.Labels),
@@ -113,14 +161,31 @@ func (rp *Issues) RepoSingleIssue(w http.ResponseWriter, r *http.Request) {
 		defs[l.AtUri().String()] = &l
 	}
 
+	events, err := db.GetTimelineEvents(rp.db, issue.AtUri(), defs)
+	if err != nil {
+		l.Error("failed to get issue events", "err", err)
+	}
+	events = append(events, models.ThreadEvent{
+		ThreadAt: issue.AtUri(),
+		Kind:     models.ThreadEventOpened,
+		Did:      issue.Did,
+		Created:  issue.Created,
+	})
+
+	commentList := issue.CommentList()
+
 	rp.pages.RepoSingleIssue(w, pages.RepoSingleIssueParams{
 		LoggedInUser:         user,
 		RepoInfo:             f.RepoInfo(user),
 		Issue:                issue,
-		CommentList:          issue.CommentList(),
+		CommentList:          commentList,
+		Timeline:             models.IssueTimeline(commentList, events),
 		OrderedReactionKinds: models.OrderedReactionKinds,
 		Reactions:            reactionMap,
 		UserReacted:          userReactions,
+		CommentReactions:     commentReactions,
+		CommentUserReacted:   commentUserReactions,
+		Backlinks:            backlinks,
 		LabelDefs:            defs,
 	})
 }
@@ -208,12 +273,22 @@ func (rp *Issues) EditIssue(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		err = db.PutReferenceLinks(tx, newIssue.RepoAt, newIssue.AtUri(), newIssue.AtUri(), markup.FindReferences(newIssue.Body))
+		if err != nil {
+			l.Error("failed to record references", "err", err)
+		}
+		if err := db.MarkAttachmentsUsed(tx, newIssue.Did, attachments.Find(newIssue.Body)); err != nil {
+			l.Error("failed to mark attachments used", "err", err)
+		}
+
 		if err = tx.Commit(); err != nil {
 			l.Error("failed to edit issue", "err", err)
 			rp.pages.Notice(w, "issues", "Failed to cedit issue.")
 			return
 		}
 
+		rp.notifier.EditIssue(r.Context(), newIssue)
+
 		rp.pages.HxRefresh(w)
 	}
 }
@@ -264,6 +339,13 @@ func (rp *Issues) DeleteIssue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := db.DeleteReferenceLinks(rp.db, db.FilterEq("thread_at", issue.AtUri())); err != nil {
+		l.Error("failed to delete references", "err", err)
+	}
+	if err := db.DeleteThreadEvents(rp.db, db.FilterEq("thread_at", issue.AtUri())); err != nil {
+		l.Error("failed to delete issue events", "err", err)
+	}
+
 	rp.notifier.DeleteIssue(r.Context(), issue)
 
 	// return to all issues page
@@ -286,17 +368,10 @@ func (rp *Issues) CloseIssue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	collaborators, err := f.Collaborators(r.Context())
-	if err != nil {
-		l.Error("failed to fetch repo collaborators", "err", err)
-	}
-	isCollaborator := slices.ContainsFunc(collaborators, func(collab pages.Collaborator) bool {
-		return user.Did == collab.Did
-	})
+	isTriager := f.RolesInRepo(user).IsTriageAllowed()
 	isIssueOwner := user.Did == issue.Did
 
-	// TODO: make this more granular
-	if isIssueOwner || isCollaborator {
+	if isIssueOwner || isTriager {
 		err = db.CloseIssues(
 			rp.db,
 			db.FilterEq("id", issue.Id),
@@ -306,6 +381,15 @@ func (rp *Issues) CloseIssue(w http.ResponseWriter, r *http.Request) {
 			rp.pages.Notice(w, "issue-action", "Failed to close issue. Try again later.")
 			return
 		}
+		err = db.AddThreadEvent(rp.db, models.ThreadEvent{
+			ThreadAt: issue.AtUri(),
+			Kind:     models.ThreadEventClosed,
+			Did:      user.Did,
+		})
+		if err != nil {
+			l.Error("failed to record issue event", "err", err)
+		}
+
 		// change the issue state (this will pass down to the notifiers)
 		issue.Open = false
 
@@ -337,16 +421,10 @@ func (rp *Issues) ReopenIssue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	collaborators, err := f.Collaborators(r.Context())
-	if err != nil {
-		l.Error("failed to fetch repo collaborators", "err", err)
-	}
-	isCollaborator := slices.ContainsFunc(collaborators, func(collab pages.Collaborator) bool {
-		return user.Did == collab.Did
-	})
+	isTriager := f.RolesInRepo(user).IsTriageAllowed()
 	isIssueOwner := user.Did == issue.Did
 
-	if isCollaborator || isIssueOwner {
+	if isTriager || isIssueOwner {
 		err := db.ReopenIssues(
 			rp.db,
 			db.FilterEq("id", issue.Id),
@@ -356,6 +434,15 @@ func (rp *Issues) ReopenIssue(w http.ResponseWriter, r *http.Request) {
 			rp.pages.Notice(w, "issue-action", "Failed to reopen issue. Try again later.")
 			return
 		}
+		err = db.AddThreadEvent(rp.db, models.ThreadEvent{
+			ThreadAt: issue.AtUri(),
+			Kind:     models.ThreadEventReopened,
+			Did:      user.Did,
+		})
+		if err != nil {
+			l.Error("failed to record issue event", "err", err)
+		}
+
 		// change the issue state (this will pass down to the notifiers)
 		issue.Open = true
 
@@ -371,6 +458,37 @@ func (rp *Issues) ReopenIssue(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// NewPullFromIssue sends the user to the new pull form, filled in from the
+// issue. The body refers back to the issue with a closing keyword, so that
+// merging the pull closes it.
+func (rp *Issues) NewPullFromIssue(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "NewPullFromIssue")
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	issue, ok := r.Context().Value("issue").(*models.Issue)
+	if !ok {
+		l.Error("failed to get issue")
+		rp.pages.Error404(w)
+		return
+	}
+
+	body := fmt.Sprintf("Closes #%d", issue.IssueId)
+	if issue.Body != "" {
+		body = issue.Body + "\n\n" + body
+	}
+
+	query := url.Values{}
+	query.Set("title", issue.Title)
+	query.Set("body", body)
+	query.Set("strategy", "branch")
+
+	http.Redirect(w, r, fmt.Sprintf("/%s/pulls/new?%s", f.OwnerSlashRepo(), query.Encode()), http.StatusFound)
+}
+
 func (rp *Issues) NewIssueComment(w http.ResponseWriter, r *http.Request) {
 	l := rp.logger.With("handler", "NewIssueComment")
 	user := rp.oauth.GetUser(r)
@@ -452,6 +570,14 @@ func (rp *Issues) NewIssueComment(w http.ResponseWriter, r *http.Request) {
 	// reset atUri to make rollback a no-op
 	atUri = ""
 
+	err = db.PutReferenceLinks(rp.db, issue.RepoAt, comment.AtUri(), issue.AtUri(), markup.FindReferences(comment.Body))
+	if err != nil {
+		l.Error("failed to record references", "err", err)
+	}
+	if err := db.MarkAttachmentsUsed(rp.db, comment.Did, attachments.Find(comment.Body)); err != nil {
+		l.Error("failed to mark attachments used", "err", err)
+	}
+
 	// notify about the new comment
 	comment.Id = commentId
 
@@ -501,6 +627,7 @@ func (rp *Issues) IssueComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	comment := comments[0]
+	rp.resolveReferences(issue, rp.readableRepos(user), comment.Body)
 
 	rp.pages.IssueCommentBodyFragment(w, pages.IssueCommentBodyParams{
 		LoggedInUser: user,
@@ -510,6 +637,119 @@ func (rp *Issues) IssueComment(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ToggleIssueTask checks or unchecks a single task list item in the issue
+// body, and writes the body back to the record on the author's PDS. Only the
+// author can do so, as the record is theirs.
+func (rp *Issues) ToggleIssueTask(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "ToggleIssueTask")
+	user := rp.oauth.GetUser(r)
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	issue, ok := r.Context().Value("issue").(*models.Issue)
+	if !ok {
+		l.Error("failed to get issue")
+		rp.pages.Error404(w)
+		return
+	}
+
+	// the body lives in the record of the author, which nobody else can
+	// write to
+	if user.Did != issue.Did {
+		l.Error("unauthorized task toggle", "issueDid", issue.Did, "gotDid", user.Did)
+		http.Error(w, "you cannot edit this issue", http.StatusForbidden)
+		return
+	}
+
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		http.Error(w, "invalid task index", http.StatusBadRequest)
+		return
+	}
+	checked := r.FormValue("checked") == "true"
+
+	// the body was edited since it was rendered, the index may now point
+	// at a different task; reload to show the latest body instead
+	if r.FormValue("hash") != markup.ContentHash(issue.Body) {
+		l.Info("stale task toggle", "issue", issue.AtUri())
+		rp.pages.HxRefresh(w)
+		return
+	}
+
+	newBody, err := markup.ToggleTaskListItem(issue.Body, index, checked)
+	if err != nil {
+		l.Error("failed to toggle task", "err", err)
+		http.Error(w, "invalid task index", http.StatusBadRequest)
+		return
+	}
+
+	newIssue := *issue
+	newIssue.Body = newBody
+
+	newRecord := newIssue.AsRecord()
+
+	client, err := rp.oauth.AuthorizedClient(r)
+	if err != nil {
+		l.Error("failed to get authorized client", "err", err)
+		http.Error(w, "failed to update issue", http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := comatproto.RepoGetRecord(r.Context(), client, "", tangled.RepoIssueNSID, user.Did, issue.Rkey)
+	if err != nil {
+		l.Error("failed to get record", "err", err)
+		http.Error(w, "failed to update issue, no record found on PDS", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoIssueNSID,
+		Repo:       user.Did,
+		Rkey:       issue.Rkey,
+		SwapRecord: ex.Cid,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &newRecord,
+		},
+	})
+	if err != nil {
+		l.Error("failed to edit record on PDS", "err", err)
+		http.Error(w, "failed to update issue on PDS", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := rp.db.Begin()
+	if err != nil {
+		l.Error("failed to start transaction", "err", err)
+		http.Error(w, "failed to update issue", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := db.PutIssue(tx, &newIssue); err != nil {
+		l.Error("failed to edit issue", "err", err)
+		http.Error(w, "failed to update issue", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Error("failed to edit issue", "err", err)
+		http.Error(w, "failed to update issue", http.StatusInternalServerError)
+		return
+	}
+
+	rp.notifier.EditIssue(r.Context(), &newIssue)
+
+	rp.resolveReferences(&newIssue, rp.readableRepos(user), newIssue.Body)
+	rp.pages.IssueBodyFragment(w, pages.IssueBodyParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Issue:        &newIssue,
+	})
+}
+
 func (rp *Issues) EditIssueComment(w http.ResponseWriter, r *http.Request) {
 	l := rp.logger.With("handler", "EditIssueComment")
 	user := rp.oauth.GetUser(r)
@@ -580,6 +820,17 @@ func (rp *Issues) EditIssueComment(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// comments without an rkey have no at-uri to record references under
+		if newComment.Rkey != "" {
+			err = db.PutReferenceLinks(rp.db, issue.RepoAt, newComment.AtUri(), issue.AtUri(), markup.FindReferences(newComment.Body))
+			if err != nil {
+				l.Error("failed to record references", "err", err)
+			}
+		}
+		if err := db.MarkAttachmentsUsed(rp.db, newComment.Did, attachments.Find(newComment.Body)); err != nil {
+			l.Error("failed to mark attachments used", "err", err)
+		}
+
 		// rkey is optional, it was introduced later
 		if newComment.Rkey != "" {
 			// update the record on pds
@@ -605,6 +856,7 @@ func (rp *Issues) EditIssueComment(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// return new comment body with htmx
+		rp.resolveReferences(issue, rp.readableRepos(user), newComment.Body)
 		rp.pages.IssueCommentBodyFragment(w, pages.IssueCommentBodyParams{
 			LoggedInUser: user,
 			RepoInfo:     f.RepoInfo(user),
@@ -749,6 +1001,12 @@ func (rp *Issues) DeleteIssueComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if comment.Rkey != "" {
+		if err := db.DeleteReferenceLinks(rp.db, db.FilterEq("from_at", comment.AtUri())); err != nil {
+			l.Error("failed to delete references", "err", err)
+		}
+	}
+
 	// delete from pds
 	if comment.Rkey != "" {
 		client, err := rp.oauth.AuthorizedClient(r)
@@ -897,9 +1155,15 @@ func (rp *Issues) NewIssue(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
+		labelPanel, err := labels.NewSubjectPanel(rp.db, &f.Repo, f.RepoInfo(user), tangled.RepoIssueNSID)
+		if err != nil {
+			l.Error("failed to fetch labels", "err", err)
+		}
+
 		rp.pages.RepoNewIssue(w, pages.RepoNewIssueParams{
 			LoggedInUser: user,
 			RepoInfo:     f.RepoInfo(user),
+			LabelPanel:   labelPanel,
 		})
 	case http.MethodPost:
 		issue := &models.Issue{
@@ -919,29 +1183,46 @@ func (rp *Issues) NewIssue(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		labelOps, err := labels.NewSubjectOps(rp.db, rp.validator, &f.Repo, user.Did, issue.AtUri(), r.Form)
+		if err != nil {
+			l.Error("invalid labels", "err", err)
+			rp.pages.Notice(w, "issues", fmt.Sprintf("Failed to create issue: %s", err))
+			return
+		}
+
 		record := issue.AsRecord()
 
-		// create an atproto record
+		// create an atproto record, along with one for the picked labels
 		client, err := rp.oauth.AuthorizedClient(r)
 		if err != nil {
 			l.Error("failed to get authorized client", "err", err)
 			rp.pages.Notice(w, "issues", "Failed to create issue.")
 			return
 		}
-		resp, err := comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
-			Collection: tangled.RepoIssueNSID,
-			Repo:       user.Did,
-			Rkey:       issue.Rkey,
-			Record: &lexutil.LexiconTypeDecoder{
-				Val: &record,
+		writes := []*comatproto.RepoApplyWrites_Input_Writes_Elem{{
+			RepoApplyWrites_Create: &comatproto.RepoApplyWrites_Create{
+				Collection: tangled.RepoIssueNSID,
+				Rkey:       &issue.Rkey,
+				Value: &lexutil.LexiconTypeDecoder{
+					Val: &record,
+				},
 			},
+		}}
+		var labelAtUri string
+		if len(labelOps) > 0 {
+			writes = append(writes, labels.SubjectOpsWrite(labelOps))
+			labelAtUri = fmt.Sprintf("at://%s/%s/%s", user.Did, tangled.LabelOpNSID, labelOps[0].Rkey)
+		}
+		_, err = comatproto.RepoApplyWrites(r.Context(), client, &comatproto.RepoApplyWrites_Input{
+			Repo:   user.Did,
+			Writes: writes,
 		})
 		if err != nil {
 			l.Error("failed to create issue", "err", err)
 			rp.pages.Notice(w, "issues", "Failed to create issue.")
 			return
 		}
-		atUri := resp.Uri
+		atUri := issue.AtUri().String()
 
 		tx, err := rp.db.BeginTx(r.Context(), nil)
 		if err != nil {
@@ -951,12 +1232,13 @@ func (rp *Issues) NewIssue(w http.ResponseWriter, r *http.Request) {
 		rollback := func() {
 			err1 := tx.Rollback()
 			err2 := rollbackRecord(context.Background(), atUri, client)
+			err3 := rollbackRecord(context.Background(), labelAtUri, client)
 
 			if errors.Is(err1, sql.ErrTxDone) {
 				err1 = nil
 			}
 
-			if err := errors.Join(err1, err2); err != nil {
+			if err := errors.Join(err1, err2, err3); err != nil {
 				l.Error("failed to rollback txn", "err", err)
 			}
 		}
@@ -969,14 +1251,31 @@ func (rp *Issues) NewIssue(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		for _, op := range labelOps {
+			if _, err := db.AddLabelOp(tx, &op); err != nil {
+				l.Error("failed to add label", "err", err)
+				rp.pages.Notice(w, "issues", "Failed to create issue.")
+				return
+			}
+		}
+
+		err = db.PutReferenceLinks(tx, issue.RepoAt, issue.AtUri(), issue.AtUri(), markup.FindReferences(issue.Body))
+		if err != nil {
+			l.Error("failed to record references", "err", err)
+		}
+		if err := db.MarkAttachmentsUsed(tx, issue.Did, attachments.Find(issue.Body)); err != nil {
+			l.Error("failed to mark attachments used", "err", err)
+		}
+
 		if err = tx.Commit(); err != nil {
 			l.Error("failed to create issue", "err", err)
 			rp.pages.Notice(w, "issues", "Failed to create issue.")
 			return
 		}
 
-		// everything is successful, do not rollback the atproto record
+		// everything is successful, do not rollback the atproto records
 		atUri = ""
+		labelAtUri = ""
 
 		rawMentions := markup.FindUserMentions(issue.Body)
 		idents := rp.idResolver.ResolveIdents(r.Context(), rawMentions)
@@ -1014,3 +1313,30 @@ func rollbackRecord(ctx context.Context, aturi string, client *atpclient.APIClie
 	})
 	return err
 }
+
+// readableRepos returns the private repos user may read, for leaving the
+// others out of references. None are when they can't be listed.
+func (rp *Issues) readableRepos(user *oauth.User) []string {
+	readable, err := rp.repoResolver.ReadableRepos(user)
+	if err != nil {
+		rp.logger.Error("failed to list private repos", "err", err)
+	}
+	return readable
+}
+
+// resolveReferences links up the issue and pull references written in
+// sources, which belong to the given issue, for display. Only those to
+// public repos and the private ones in readable are.
+func (rp *Issues) resolveReferences(issue *models.Issue, readable []string, sources ...string) {
+	var refs []string
+	for _, source := range sources {
+		refs = append(refs, markup.FindReferences(source)...)
+	}
+
+	resolved, err := db.ResolveReferences(rp.db, issue.RepoAt, refs, readable)
+	if err != nil {
+		rp.logger.Error("failed to resolve references", "err", err)
+		return
+	}
+	issue.References = resolved
+}
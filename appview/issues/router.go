@@ -32,9 +32,11 @@ func (i *Issues) Router(mw *middleware.Middleware) http.Handler {
 				})
 				r.Get("/edit", i.EditIssue)
 				r.Post("/edit", i.EditIssue)
+				r.Post("/tasks", i.ToggleIssueTask)
 				r.Delete("/", i.DeleteIssue)
 				r.Post("/close", i.CloseIssue)
 				r.Post("/reopen", i.ReopenIssue)
+				r.Get("/pull", i.NewPullFromIssue)
 			})
 		})
 
@@ -42,6 +44,7 @@ func (i *Issues) Router(mw *middleware.Middleware) http.Handler {
 			r.Use(middleware.AuthMiddleware(i.oauth))
 			r.Get("/new", i.NewIssue)
 			r.Post("/new", i.NewIssue)
+			r.Post("/bulk", i.BulkIssues)
 		})
 	})
 
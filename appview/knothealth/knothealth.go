@@ -0,0 +1,168 @@
+// Package knothealth asks knots how they are doing, and whether they serve
+// everything the appview needs of them. Answers are kept for a while, since
+// they are shown on every page that carries the upgrade banner.
+package knothealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/xrpcclient"
+)
+
+// RequiredMethods are the knot methods the appview calls. A knot that lacks
+// any of them needs an upgrade.
+var RequiredMethods = []string{
+	tangled.KnotHealthNSID,
+	tangled.KnotListKeysNSID,
+	tangled.KnotVersionNSID,
+	tangled.OwnerNSID,
+	tangled.RepoArchiveNSID,
+	tangled.RepoBlobNSID,
+	tangled.RepoBranchNSID,
+	tangled.RepoBranchesNSID,
+	tangled.RepoCompareNSID,
+	tangled.RepoContributorsNSID,
+	tangled.RepoCreateNSID,
+	tangled.RepoCreateFromTemplateNSID,
+	tangled.RepoDeleteNSID,
+	tangled.RepoDeleteBranchNSID,
+	tangled.RepoDiffNSID,
+	tangled.RepoForkStatusNSID,
+	tangled.RepoForkSyncNSID,
+	tangled.RepoGetDefaultBranchNSID,
+	tangled.RepoHiddenRefNSID,
+	tangled.RepoLanguagesNSID,
+	tangled.RepoLogNSID,
+	tangled.RepoMergeNSID,
+	tangled.RepoMergeCheckNSID,
+	tangled.RepoSetDefaultBranchNSID,
+	tangled.RepoSetVisibilityNSID,
+	tangled.RepoTagsNSID,
+	tangled.RepoTransferNSID,
+	tangled.RepoTreeNSID,
+}
+
+type Checker struct {
+	config *config.Config
+
+	mu      sync.Mutex
+	results map[string]models.KnotHealth
+}
+
+func New(config *config.Config) *Checker {
+	return &Checker{
+		config:  config,
+		results: make(map[string]models.KnotHealth),
+	}
+}
+
+// Check returns the health of the knot at domain, asking it again if the
+// last answer is older than the configured TTL.
+func (c *Checker) Check(ctx context.Context, domain string) models.KnotHealth {
+	c.mu.Lock()
+	h, ok := c.results[domain]
+	c.mu.Unlock()
+	if ok && time.Since(h.CheckedAt) < c.config.KnotClient.HealthTTL {
+		return h
+	}
+
+	h = c.check(ctx, domain)
+
+	c.mu.Lock()
+	c.results[domain] = h
+	c.mu.Unlock()
+
+	return h
+}
+
+// CheckAll checks each knot in domains at once.
+func (c *Checker) CheckAll(ctx context.Context, domains []string) map[string]models.KnotHealth {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]models.KnotHealth, len(domains))
+
+	for _, domain := range domains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := c.Check(ctx, domain)
+			mu.Lock()
+			results[domain] = h
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *Checker) check(ctx context.Context, domain string) models.KnotHealth {
+	h := models.KnotHealth{
+		Domain:    domain,
+		CheckedAt: time.Now(),
+	}
+
+	scheme := "https"
+	if c.config.Core.Dev {
+		scheme = "http"
+	}
+	cfg := c.config.KnotClient
+	// a knot that is down should not hold up the page for long
+	cfg.MaxRetries = 0
+	cfg.Timeout = min(cfg.Timeout, 5*time.Second)
+	xrpcc := xrpcclient.NewClient(cfg, fmt.Sprintf("%s://%s", scheme, domain))
+
+	out, err := tangled.KnotHealth(ctx, xrpcc)
+	if err := xrpcclient.HandleXrpcErr(err); err != nil {
+		if !errors.Is(err, xrpcclient.ErrXrpcUnsupported) {
+			h.Status = models.KnotHealthDown
+			return h
+		}
+
+		// knots from before the health endpoint can still say which
+		// version they are
+		h.Status = models.KnotHealthOutdated
+		h.Missing = []string{tangled.KnotHealthNSID}
+		if v, err := tangled.KnotVersion(ctx, xrpcc); err == nil {
+			h.Version = v.Version
+		}
+		return h
+	}
+
+	h.Version = out.Version
+	h.Uptime = time.Duration(out.Uptime) * time.Second
+	if t, err := time.Parse(time.RFC3339, out.StartedAt); err == nil {
+		h.StartedAt = t
+	}
+	if out.Load != nil {
+		h.Goroutines = out.Load.Goroutines
+		h.HeapBytes = uint64(out.Load.HeapBytes)
+		h.Cpus = out.Load.Cpus
+	}
+
+	h.Missing = missing(out.Capabilities)
+	h.Status = models.KnotHealthUp
+	if len(h.Missing) > 0 {
+		h.Status = models.KnotHealthOutdated
+	}
+
+	return h
+}
+
+func missing(capabilities []string) []string {
+	var m []string
+	for _, nsid := range RequiredMethods {
+		if !slices.Contains(capabilities, nsid) {
+			m = append(m, nsid)
+		}
+	}
+	return m
+}
@@ -0,0 +1,100 @@
+package knothealth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/models"
+)
+
+func newChecker() *Checker {
+	return New(&config.Config{
+		Core: config.CoreConfig{Dev: true},
+		KnotClient: config.KnotClientConfig{
+			Timeout:   time.Second,
+			HealthTTL: time.Minute,
+		},
+	})
+}
+
+func knot(t *testing.T, handler http.HandlerFunc) (string, *atomic.Int32) {
+	var calls atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return strings.TrimPrefix(s.URL, "http://"), &calls
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("up", func(t *testing.T) {
+		domain, calls := knot(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(tangled.KnotHealth_Output{
+				Version:      "v1.10.0",
+				StartedAt:    "2026-10-01T00:00:00Z",
+				Uptime:       90,
+				Capabilities: RequiredMethods,
+				Load:         &tangled.KnotHealth_Load{Goroutines: 12, HeapBytes: 1024, Cpus: 4},
+			})
+		})
+
+		c := newChecker()
+		h := c.Check(context.Background(), domain)
+		assert.Equal(t, models.KnotHealthUp, h.Status)
+		assert.Equal(t, "v1.10.0", h.Version)
+		assert.Equal(t, 90*time.Second, h.Uptime)
+		assert.Equal(t, int64(12), h.Goroutines)
+		assert.Zero(t, h.Missing)
+
+		// answers are reused
+		c.Check(context.Background(), domain)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("missing methods", func(t *testing.T) {
+		domain, _ := knot(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(tangled.KnotHealth_Output{
+				Version:      "v1.9.0",
+				Capabilities: RequiredMethods[1:],
+				Load:         &tangled.KnotHealth_Load{},
+			})
+		})
+
+		h := newChecker().Check(context.Background(), domain)
+		assert.True(t, h.NeedsUpgrade())
+		assert.Equal(t, RequiredMethods[:1], h.Missing)
+	})
+
+	t.Run("before the health endpoint", func(t *testing.T) {
+		domain, _ := knot(t, func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, tangled.KnotVersionNSID) {
+				json.NewEncoder(w).Encode(tangled.KnotVersion_Output{Version: "v1.8.0"})
+				return
+			}
+			http.NotFound(w, r)
+		})
+
+		h := newChecker().Check(context.Background(), domain)
+		assert.True(t, h.NeedsUpgrade())
+		assert.Equal(t, "v1.8.0", h.Version)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		domain, _ := knot(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		h := newChecker().Check(context.Background(), domain)
+		assert.True(t, h.IsDown())
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/knothealth"
 	"tangled.org/core/appview/middleware"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/oauth"
@@ -37,6 +38,7 @@ type Knots struct {
 	IdResolver *idresolver.Resolver
 	Logger     *slog.Logger
 	Knotstream *eventconsumer.Consumer
+	Health     *knothealth.Checker
 }
 
 func (k *Knots) Router() http.Handler {
@@ -48,6 +50,7 @@ func (k *Knots) Router() http.Handler {
 	r.With(middleware.AuthMiddleware(k.OAuth)).Get("/{domain}", k.dashboard)
 	r.With(middleware.AuthMiddleware(k.OAuth)).Delete("/{domain}", k.delete)
 
+	r.With(middleware.AuthMiddleware(k.OAuth)).Get("/{domain}/health", k.health)
 	r.With(middleware.AuthMiddleware(k.OAuth)).Post("/{domain}/retry", k.retry)
 	r.With(middleware.AuthMiddleware(k.OAuth)).Post("/{domain}/add", k.addMember)
 	r.With(middleware.AuthMiddleware(k.OAuth)).Post("/{domain}/remove", k.removeMember)
@@ -135,6 +138,28 @@ func (k *Knots) dashboard(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// htmx fragment
+func (k *Knots) health(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+	if domain == "" {
+		return
+	}
+
+	// only knots that proved who runs them are asked
+	registrations, err := db.GetRegistrations(
+		k.Db,
+		db.FilterEq("domain", domain),
+	)
+	if err != nil || !slices.ContainsFunc(registrations, func(reg models.Registration) bool {
+		return reg.Registered != nil
+	}) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	k.Pages.KnotHealth(w, k.Health.Check(r.Context(), domain))
+}
+
 func (k *Knots) register(w http.ResponseWriter, r *http.Request) {
 	user := k.OAuth.GetUser(r)
 	l := k.Logger.With("handler", "register")
@@ -170,7 +195,12 @@ func (k *Knots) register(w http.ResponseWriter, r *http.Request) {
 		k.Enforcer.E.LoadPolicy()
 	}()
 
-	err = db.AddKnot(tx, domain, user.Did)
+	approval := models.ApprovalApproved
+	if k.Config.Admin.ReviewsRegistrations() {
+		approval = models.ApprovalPending
+	}
+
+	err = db.AddKnot(tx, domain, user.Did, approval)
 	if err != nil {
 		l.Error("failed to insert", "err", err)
 		fail()
@@ -246,11 +276,13 @@ func (k *Knots) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// add this knot to knotstream
-	go k.Knotstream.AddSource(
-		r.Context(),
-		eventconsumer.NewKnotSource(domain),
-	)
+	// add this knot to knotstream, unless it waits on an admin
+	if approval.IsApproved() {
+		go k.Knotstream.AddSource(
+			r.Context(),
+			eventconsumer.NewKnotSource(domain),
+		)
+	}
 
 	// ok
 	k.Pages.HxRefresh(w)
@@ -461,11 +493,13 @@ func (k *Knots) retry(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// add this knot to knotstream
-	go k.Knotstream.AddSource(
-		r.Context(),
-		eventconsumer.NewKnotSource(domain),
-	)
+	// add this knot to knotstream, unless it waits on an admin
+	if registration.Approval.IsApproved() {
+		go k.Knotstream.AddSource(
+			r.Context(),
+			eventconsumer.NewKnotSource(domain),
+		)
+	}
 
 	shouldRefresh := r.Header.Get("shouldRefresh")
 	if shouldRefresh == "true" {
@@ -0,0 +1,143 @@
+package labels
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pages/repoinfo"
+	"tangled.org/core/appview/validator"
+	"tangled.org/core/tid"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+)
+
+// NewSubjectPanel prepares the label panel shown on the forms that create
+// issues and pulls, it is nil if there is nothing to pick from.
+func NewSubjectPanel(e db.Execer, repo *models.Repo, repoInfo repoinfo.RepoInfo, collection string) (*pages.EditLabelPanelParams, error) {
+	if !repoInfo.Roles.IsTriageAllowed() {
+		return nil, nil
+	}
+
+	labelDefs, err := db.GetLabelDefinitions(
+		e,
+		db.FilterIn("at_uri", repo.Labels),
+		db.FilterContains("scope", collection),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(labelDefs) == 0 {
+		return nil, nil
+	}
+
+	defs := make(map[string]*models.LabelDefinition)
+	for _, l := range labelDefs {
+		defs[l.AtUri().String()] = &l
+	}
+
+	return &pages.EditLabelPanelParams{
+		RepoInfo: repoInfo,
+		Defs:     defs,
+		State:    models.NewLabelState(),
+	}, nil
+}
+
+// NewSubjectOps turns the labels picked on the form that creates subject
+// into label ops. Fields are keyed by label definition, the same way the
+// label panel submits them. Every picked label has to be one of the repo's
+// labels and has to apply to the subject's collection.
+//
+// All ops share one rkey, so that they end up in a single record.
+func NewSubjectOps(e db.Execer, v *validator.Validator, repo *models.Repo, did string, subject syntax.ATURI, form url.Values) ([]models.LabelOp, error) {
+	var keys []string
+	for key := range form {
+		if uri, err := syntax.ParseATURI(key); err == nil && uri.Collection().String() == tangled.LabelDefinitionNSID {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	slices.Sort(keys)
+
+	actx, err := db.NewLabelApplicationCtx(e, db.FilterIn("at_uri", repo.Labels))
+	if err != nil {
+		return nil, err
+	}
+
+	rkey := tid.TID()
+	now := time.Now()
+	collection := subject.Collection().String()
+
+	var ops []models.LabelOp
+	for _, key := range keys {
+		def, ok := actx.Defs[key]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a label of this repository", key)
+		}
+		if !slices.Contains(def.Scope, collection) {
+			return nil, fmt.Errorf("label %q does not apply to %s", def.Name, collection)
+		}
+
+		vals := form[key]
+		if !def.Multiple {
+			vals = vals[len(vals)-1:]
+		}
+
+		for _, val := range vals {
+			// the label panel submits empty values for labels left unset
+			if val == "" {
+				continue
+			}
+
+			op := models.LabelOp{
+				Did:          did,
+				Rkey:         rkey,
+				Subject:      subject,
+				Operation:    models.LabelOperationAdd,
+				OperandKey:   key,
+				OperandValue: val,
+				PerformedAt:  now,
+				IndexedAt:    now,
+			}
+			if err := v.ValidateLabelOp(def, repo, &op); err != nil {
+				return nil, fmt.Errorf("label %q: %w", def.Name, err)
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	// drop values that were picked twice
+	state := models.NewLabelState()
+	valid := ops[:0]
+	for _, op := range ops {
+		if err := actx.ApplyLabelOp(state, op); err != models.LabelNoOpError {
+			valid = append(valid, op)
+		}
+	}
+
+	return valid, nil
+}
+
+// SubjectOpsWrite is the write that creates the record of ops, to go
+// along with the write that creates their subject.
+func SubjectOpsWrite(ops []models.LabelOp) *comatproto.RepoApplyWrites_Input_Writes_Elem {
+	record := models.LabelOpsAsRecord(ops)
+	return &comatproto.RepoApplyWrites_Input_Writes_Elem{
+		RepoApplyWrites_Create: &comatproto.RepoApplyWrites_Create{
+			Collection: tangled.LabelOpNSID,
+			Rkey:       &ops[0].Rkey,
+			Value: &lexutil.LexiconTypeDecoder{
+				Val: &record,
+			},
+		},
+	}
+}
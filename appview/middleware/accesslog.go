@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"tangled.org/core/appview/config"
+)
+
+// AccessLog writes a structured log line for every request once it has been
+// served, sampled as cfg asks.
+func (mw Middleware) AccessLog(logger *slog.Logger, cfg config.AccessLogConfig) middlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			// handlers that never write still answer with a 200
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := cfg.Level
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			} else if cfg.SampleRate < 1 && rand.Float64() >= cfg.SampleRate {
+				return
+			}
+
+			ctx := r.Context()
+			if !logger.Enabled(ctx, level) {
+				return
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int("bytes", ww.BytesWritten()),
+			}
+			if did := mw.oauth.GetDid(r); did != "" {
+				attrs = append(attrs, slog.String("did", did))
+			}
+			if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+				attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+			}
+
+			logger.LogAttrs(ctx, level, "request", attrs...)
+		})
+	}
+}
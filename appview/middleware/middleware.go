@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"slices"
@@ -17,7 +18,9 @@ import (
 	"tangled.org/core/appview/pages"
 	"tangled.org/core/appview/pagination"
 	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/idresolver"
+	tlog "tangled.org/core/log"
 	"tangled.org/core/rbac"
 )
 
@@ -43,6 +46,21 @@ func New(oauth *oauth.OAuth, db *db.DB, enforcer *rbac.Enforcer, repoResolver *r
 
 type middlewareFunc func(http.Handler) http.Handler
 
+// WithLogger makes l the logger of every request, see tlog.FromContext. The
+// middlewares that resolve a user, repo or pull add them to it as fields.
+func (mw Middleware) WithLogger(l *slog.Logger) middlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tlog.IntoContext(r.Context(), l)))
+		})
+	}
+}
+
+// withLogFields adds args to the logger of the request context ctx.
+func withLogFields(ctx context.Context, args ...any) context.Context {
+	return tlog.IntoContext(ctx, tlog.FromContext(ctx).With(args...))
+}
+
 func AuthMiddleware(o *oauth.OAuth) middlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +94,8 @@ func AuthMiddleware(o *oauth.OAuth) middlewareFunc {
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := withLogFields(r.Context(), "did", sess.Data.AccountDID.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -189,12 +208,23 @@ func (mw Middleware) ResolveIdent() middlewareFunc {
 
 			id, err := mw.idResolver.ResolveIdent(req.Context(), didOrHandle)
 			if err != nil {
+				// the handle may have been changed since it was linked
+				if mw.redirectStaleHandle(w, req, didOrHandle, "", "") {
+					return
+				}
+
 				// invalid did or handle
 				log.Printf("failed to resolve did/handle '%s': %s\n", didOrHandle, err)
 				mw.pages.Error404(w)
 				return
 			}
 
+			// users that were taken down are gone, along with their repos
+			if db.IsTakenDown(mw.db, id.DID.String()) {
+				mw.pages.Error404(w)
+				return
+			}
+
 			ctx := context.WithValue(req.Context(), "resolvedId", *id)
 
 			next.ServeHTTP(w, req.WithContext(ctx))
@@ -221,17 +251,114 @@ func (mw Middleware) ResolveRepo() middlewareFunc {
 				db.FilterEq("name", repoName),
 			)
 			if err != nil {
+				// the handle may have been passed on to someone else since
+				// it was linked
+				handle := strings.TrimPrefix(chi.URLParam(req, "user"), "@")
+				if mw.redirectStaleHandle(w, req, handle, repoName, id.DID.String()) {
+					return
+				}
+
 				log.Println("failed to resolve repo", "err", err)
 				mw.pages.ErrorKnot404(w)
 				return
 			}
 
+			if db.IsTakenDown(mw.db, repo.RepoAt().String()) {
+				mw.pages.Error404(w)
+				return
+			}
+
 			ctx := context.WithValue(req.Context(), "repo", repo)
+			ctx = withLogFields(ctx, "repo", repo.DidSlashRepo())
+
+			// private repos don't exist for those without access to them, and
+			// the knot wants to know who is looking. Git authenticates with
+			// the knot itself, through the credentials we pass along.
+			if repo.IsPrivate() && !isGitRequest(req) {
+				user := mw.oauth.GetUser(req)
+				if user == nil {
+					mw.pages.Error404(w)
+					return
+				}
+
+				ok, err := mw.enforcer.IsRepoReadAllowed(user.Did, repo.Knot, repo.DidSlashRepo())
+				if err != nil || !ok {
+					mw.pages.Error404(w)
+					return
+				}
+
+				token, err := mw.oauth.ServiceToken(req, oauth.WithService(repo.Knot))
+				if err != nil {
+					tlog.FromContext(ctx).Error("failed to get service token", "err", err)
+					mw.pages.Error503(w)
+					return
+				}
+				ctx = xrpcclient.WithViewer(ctx, repo.Knot, token)
+			}
+
 			next.ServeHTTP(w, req.WithContext(ctx))
 		})
 	}
 }
 
+// redirectStaleHandle permanently redirects a request whose path starts
+// with a handle that last belonged to someone other than currentDid, to the
+// same path under their current handle. With a repoName, that someone must
+// have the repo. It reports whether it redirected.
+//
+// The redirect is only made to a different handle that resolves to the
+// same did, so following it can't lead back here.
+func (mw Middleware) redirectStaleHandle(w http.ResponseWriter, req *http.Request, handle, repoName, currentDid string) bool {
+	if strings.HasPrefix(handle, "did:") {
+		return false
+	}
+
+	did, err := db.GetHandleDid(mw.db, handle)
+	if err != nil || did == "" || did == currentDid {
+		return false
+	}
+
+	id, err := mw.idResolver.ResolveIdent(req.Context(), did)
+	if err != nil || id.Handle.IsInvalidHandle() {
+		return false
+	}
+
+	current := id.Handle.String()
+	if strings.EqualFold(current, handle) {
+		return false
+	}
+
+	if repoName != "" {
+		if _, err := db.GetRepo(mw.db, db.FilterEq("did", did), db.FilterEq("name", repoName)); err != nil {
+			return false
+		}
+	}
+
+	user := chi.URLParam(req, "user")
+	newUser := current
+	if strings.HasPrefix(user, "@") {
+		newUser = "@" + current
+	}
+
+	u := *req.URL
+	u.Path = "/" + newUser + strings.TrimPrefix(req.URL.Path, "/"+user)
+	u.RawPath = ""
+
+	http.Redirect(w, req, u.RequestURI(), http.StatusMovedPermanently)
+	return true
+}
+
+// isGitRequest reports whether req is one of the git smart HTTP requests that
+// are proxied to the knot.
+func isGitRequest(req *http.Request) bool {
+	for _, suffix := range []string{"/info/refs", "/git-upload-pack", "/git-receive-pack"} {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 // middleware that is tacked on top of /{user}/{repo}/pulls/{pull}
 func (mw Middleware) ResolvePull() middlewareFunc {
 	return func(next http.Handler) http.Handler {
@@ -259,6 +386,7 @@ func (mw Middleware) ResolvePull() middlewareFunc {
 			}
 
 			ctx := context.WithValue(r.Context(), "pull", pr)
+			ctx = withLogFields(ctx, "pull_id", pr.PullId)
 
 			if pr.IsStacked() {
 				stack, err := db.GetStack(mw.db, pr.StackId)
@@ -306,6 +434,11 @@ func (mw Middleware) ResolveIssue(next http.Handler) http.Handler {
 			return
 		}
 
+		if db.IsTakenDown(mw.db, issue.AtUri().String()) {
+			mw.pages.Error404(w)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), "issue", issue)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type AdminActionKind string
+
+const (
+	AdminSuspend  AdminActionKind = "suspend"
+	AdminTakedown AdminActionKind = "takedown"
+	AdminRestore  AdminActionKind = "restore"
+	AdminApprove  AdminActionKind = "approve"
+	AdminReject   AdminActionKind = "reject"
+)
+
+// AdminAction is an entry in the audit log of the admin dashboard.
+type AdminAction struct {
+	Id       int64
+	AdminDid string
+	Action   AdminActionKind
+	Subject  string
+	Reason   string
+	Created  time.Time
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AppPassword is a token that stands in for its owner where oauth doesn't
+// reach, like git over HTTPS. Only a hash of the token is kept.
+type AppPassword struct {
+	Id           int64
+	Did          string
+	Name         string
+	PasswordHash string
+	Scopes       []string
+	// Knots are the hostnames of the knots the password may be used on
+	Knots    []string
+	Created  time.Time
+	LastUsed *time.Time
+}
+
+func (a AppPassword) HasScope(scope string) bool {
+	return slices.Contains(a.Scopes, scope)
+}
+
+// AllowsKnot reports whether the password may be used on the knot with the
+// given did, which is a did:web of its hostname.
+func (a AppPassword) AllowsKnot(did string) bool {
+	host, ok := strings.CutPrefix(did, "did:web:")
+	return ok && slices.Contains(a.Knots, host)
+}
+
+// HashAppPassword is what is stored in place of password. App passwords are
+// random enough that a plain hash is as good as a slow one.
+func HashAppPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,16 @@
+package models
+
+// Approval is what an admin made of the registration of a knot or spindle.
+// Where registrations are reviewed, only approved knots and spindles serve;
+// elsewhere every registration is approved as it is made.
+type Approval string
+
+const (
+	ApprovalPending  Approval = "pending"
+	ApprovalApproved Approval = "approved"
+	ApprovalRejected Approval = "rejected"
+)
+
+func (a Approval) IsApproved() bool {
+	return a == ApprovalApproved
+}
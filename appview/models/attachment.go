@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment is a file uploaded to be linked from an issue, pull or
+// comment body. Files are stored once per hash; each uploader gets their
+// own row, so quotas and cleanup are tracked per user.
+type Attachment struct {
+	Id       int64
+	Did      string
+	Hash     string
+	Name     string
+	MimeType string
+	Size     int64
+	Created  time.Time
+
+	// Used is set once a posted body links to the attachment. Attachments
+	// that are never used are removed after a while.
+	Used bool
+}
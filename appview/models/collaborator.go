@@ -15,6 +15,7 @@ type Collaborator struct {
 	// content
 	SubjectDid syntax.DID
 	RepoAt     syntax.ATURI
+	Role       string
 
 	// meta
 	Created time.Time
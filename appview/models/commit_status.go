@@ -0,0 +1,84 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	spindle "tangled.org/core/spindle/models"
+)
+
+type CommitStatusState string
+
+const (
+	CommitStatusPending CommitStatusState = "pending"
+	CommitStatusSuccess CommitStatusState = "success"
+	CommitStatusFailure CommitStatusState = "failure"
+	CommitStatusError   CommitStatusState = "error"
+)
+
+var CommitStatusStates = []CommitStatusState{
+	CommitStatusPending,
+	CommitStatusSuccess,
+	CommitStatusFailure,
+	CommitStatusError,
+}
+
+func (s CommitStatusState) IsValid() bool {
+	return slices.Contains(CommitStatusStates, s)
+}
+
+// CommitStatus is the outcome of a check run on a commit by something other
+// than spindle, like an external CI system. There is one per repo, commit
+// and context.
+type CommitStatus struct {
+	Id          int64
+	RepoAt      syntax.ATURI
+	Sha         string
+	Context     string
+	State       CommitStatusState
+	TargetUrl   string
+	Description string
+
+	// who posted the status
+	Did     string
+	Created time.Time
+	Updated time.Time
+}
+
+// RequiredCheck is a context that must pass on the head of a pull before it
+// can be merged into branch.
+type RequiredCheck struct {
+	Id      int64
+	RepoAt  syntax.ATURI
+	Branch  string
+	Context string
+	Created time.Time
+}
+
+// MissingChecks returns the required contexts that have not passed, either
+// as a successful commit status or as a successful workflow of pipeline.
+// pipeline may be nil.
+func MissingChecks(required []RequiredCheck, statuses []CommitStatus, pipeline *Pipeline) []string {
+	passed := make(map[string]bool)
+	for _, s := range statuses {
+		if s.State == CommitStatusSuccess {
+			passed[s.Context] = true
+		}
+	}
+	if pipeline != nil {
+		for name, w := range pipeline.Statuses {
+			if len(w.Data) > 0 && w.Latest().Status == spindle.StatusKindSuccess {
+				passed[name] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, r := range required {
+		if !passed[r.Context] && !slices.Contains(missing, r.Context) {
+			missing = append(missing, r.Context)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type DeletionStatus string
+
+const (
+	DeletionScheduled DeletionStatus = "scheduled"
+	DeletionRunning   DeletionStatus = "running"
+	DeletionDone      DeletionStatus = "done"
+	DeletionCancelled DeletionStatus = "cancelled"
+)
+
+// DeletionStep is a part of deleting an account. Steps are taken in the
+// order of DeletionSteps, and each can be taken again if it was cut short.
+type DeletionStep string
+
+const (
+	// repos are removed from their knots, the enforcer and the appview
+	DeletionStepRepos DeletionStep = "repos"
+	// content others depend on is anonymized, the rest removed
+	DeletionStepContent DeletionStep = "content"
+	// records are deleted from the PDS of the account
+	DeletionStepRecords DeletionStep = "records"
+	// everything else the appview keeps about the account is removed
+	DeletionStepRows DeletionStep = "rows"
+	// sessions are revoked
+	DeletionStepSessions DeletionStep = "sessions"
+)
+
+var DeletionSteps = []DeletionStep{
+	DeletionStepRepos,
+	DeletionStepContent,
+	DeletionStepRecords,
+	DeletionStepRows,
+	DeletionStepSessions,
+}
+
+// AccountDeletion is a request of a user to have their account deleted,
+// which is carried out once Scheduled has passed.
+type AccountDeletion struct {
+	Id        int64
+	Did       string
+	Status    DeletionStatus
+	Step      DeletionStep
+	Error     string
+	Attempts  int
+	Created   time.Time
+	Scheduled time.Time
+	Finished  *time.Time
+}
+
+// IsPending says whether the deletion can still be cancelled.
+func (d *AccountDeletion) IsPending() bool {
+	return d.Status == DeletionScheduled
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportDone    ExportStatus = "done"
+	ExportFailed  ExportStatus = "failed"
+	ExportExpired ExportStatus = "expired"
+)
+
+// Export is an archive of everything a user has on the appview, built in
+// the background when they ask for it.
+type Export struct {
+	Id           int64
+	Did          string
+	IncludeRepos bool
+	Status       ExportStatus
+	Size         uint64
+	Error        string
+	Created      time.Time
+	Finished     *time.Time
+	Expires      *time.Time
+}
+
+// IsInProgress says whether the archive is still being built.
+func (e *Export) IsInProgress() bool {
+	return e.Status == ExportPending || e.Status == ExportRunning
+}
+
+func (e *Export) IsDownloadable() bool {
+	return e.Status == ExportDone && e.Expires != nil && time.Now().Before(*e.Expires)
+}
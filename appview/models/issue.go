@@ -27,6 +27,10 @@ type Issue struct {
 	Comments []IssueComment
 	Labels   LabelState
 	Repo     *Repo
+
+	// issues and pulls referenced from the body and comments, keyed by
+	// how they were written
+	References map[string]Reference
 }
 
 func (i *Issue) AtUri() syntax.ATURI {
@@ -170,6 +174,9 @@ type IssueComment struct {
 	Created time.Time
 	Edited  *time.Time
 	Deleted *time.Time
+
+	// set when moderators have taken it down; it is then shown as deleted
+	TakenDown bool
 }
 
 func (i *IssueComment) AtUri() syntax.ATURI {
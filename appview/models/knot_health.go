@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type KnotHealthStatus string
+
+const (
+	KnotHealthUp KnotHealthStatus = "up"
+	// the knot could not be reached
+	KnotHealthDown KnotHealthStatus = "down"
+	// the knot is up, but lacks methods the appview calls
+	KnotHealthOutdated KnotHealthStatus = "outdated"
+)
+
+// KnotHealth is what a knot last said about itself, as of CheckedAt.
+type KnotHealth struct {
+	Domain    string
+	Status    KnotHealthStatus
+	CheckedAt time.Time
+
+	// unset when the knot is down, or too old to report them
+	Version    string
+	StartedAt  time.Time
+	Uptime     time.Duration
+	Goroutines int64
+	HeapBytes  uint64
+	Cpus       int64
+
+	// Missing lists the methods the appview needs that the knot does not
+	// serve.
+	Missing []string
+}
+
+func (h KnotHealth) IsUp() bool {
+	return h.Status == KnotHealthUp
+}
+
+func (h KnotHealth) IsDown() bool {
+	return h.Status == KnotHealthDown
+}
+
+func (h KnotHealth) NeedsUpgrade() bool {
+	return h.Status == KnotHealthOutdated
+}
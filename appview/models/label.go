@@ -96,12 +96,13 @@ type LabelDefinition struct {
 	Did  string
 	Rkey string
 
-	Name      string
-	ValueType ValueType
-	Scope     []string
-	Color     *string
-	Multiple  bool
-	Created   time.Time
+	Name        string
+	Description *string
+	ValueType   ValueType
+	Scope       []string
+	Color       *string
+	Multiple    bool
+	Created     time.Time
 }
 
 func (l *LabelDefinition) AtUri() syntax.ATURI {
@@ -111,12 +112,13 @@ func (l *LabelDefinition) AtUri() syntax.ATURI {
 func (l *LabelDefinition) AsRecord() tangled.LabelDefinition {
 	vt := l.ValueType.AsRecord()
 	return tangled.LabelDefinition{
-		Name:      l.Name,
-		Color:     l.Color,
-		CreatedAt: l.Created.Format(time.RFC3339),
-		Multiple:  &l.Multiple,
-		Scope:     l.Scope,
-		ValueType: &vt,
+		Name:        l.Name,
+		Description: l.Description,
+		Color:       l.Color,
+		CreatedAt:   l.Created.Format(time.RFC3339),
+		Multiple:    &l.Multiple,
+		Scope:       l.Scope,
+		ValueType:   &vt,
 	}
 }
 
@@ -161,12 +163,13 @@ func LabelDefinitionFromRecord(did, rkey string, record tangled.LabelDefinition)
 		Did:  did,
 		Rkey: rkey,
 
-		Name:      record.Name,
-		ValueType: vt,
-		Scope:     record.Scope,
-		Color:     record.Color,
-		Multiple:  multiple,
-		Created:   created,
+		Name:        record.Name,
+		Description: record.Description,
+		ValueType:   vt,
+		Scope:       record.Scope,
+		Color:       record.Color,
+		Multiple:    multiple,
+		Created:     created,
 	}, nil
 }
 
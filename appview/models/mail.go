@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// MailPatchPart is one message of a patch series sent over email. Parts are
+// held on to until the whole series has arrived, and then opened as a single
+// pull.
+type MailPatchPart struct {
+	SeriesId  string
+	Part      int
+	Total     int
+	Did       string
+	RepoAt    syntax.ATURI
+	Branch    string
+	MessageId string
+
+	// Subject and Body are as sent; for every part but the cover letter,
+	// Body is the patch itself.
+	Subject string
+	Body    string
+
+	Created time.Time
+}
+
+// IsCoverLetter reports whether this is the "[PATCH 0/n]" message that
+// introduces the series.
+func (p MailPatchPart) IsCoverLetter() bool {
+	return p.Part == 0
+}
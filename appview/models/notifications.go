@@ -21,6 +21,10 @@ const (
 	NotificationTypePullClosed     NotificationType = "pull_closed"
 	NotificationTypePullReopen     NotificationType = "pull_reopen"
 	NotificationTypeUserMentioned  NotificationType = "user_mentioned"
+	NotificationTypeRepoTransfer   NotificationType = "repo_transfer"
+
+	NotificationTypeRegistrationApproved NotificationType = "registration_approved"
+	NotificationTypeRegistrationRejected NotificationType = "registration_rejected"
 )
 
 type Notification struct {
@@ -66,6 +70,12 @@ func (n *Notification) Icon() string {
 		return "user-plus"
 	case NotificationTypeUserMentioned:
 		return "at-sign"
+	case NotificationTypeRepoTransfer:
+		return "arrow-right-left"
+	case NotificationTypeRegistrationApproved:
+		return "shield-check"
+	case NotificationTypeRegistrationRejected:
+		return "shield-off"
 	default:
 		return ""
 	}
@@ -119,6 +129,10 @@ func (prefs *NotificationPreferences) ShouldNotify(t NotificationType) bool {
 		return prefs.Followed
 	case NotificationTypeUserMentioned:
 		return prefs.UserMentioned
+	case NotificationTypeRepoTransfer:
+		return true // transfers wait on the recipient
+	case NotificationTypeRegistrationApproved, NotificationTypeRegistrationRejected:
+		return true // registrants wait on the admin
 	default:
 		return false
 	}
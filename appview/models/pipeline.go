@@ -1,11 +1,14 @@
 package models
 
 import (
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/go-git/go-git/v5/plumbing"
+	"tangled.org/core/api/tangled"
 	spindle "tangled.org/core/spindle/models"
 	"tangled.org/core/workflow"
 )
@@ -20,9 +23,17 @@ type Pipeline struct {
 	Sha       string
 	Created   time.Time
 
+	// id of the pipeline this one was run again from, if any
+	RerunOf *int
+
 	// populate when querying for reverse mappings
 	Trigger  *Trigger
 	Statuses map[string]WorkflowStatus
+
+	// statuses of the jobs matrix workflows fan out into, keyed by their
+	// full name, the workflows themselves in Statuses carry the overall
+	// status of their jobs
+	MatrixJobs map[string]WorkflowStatus
 }
 
 type WorkflowStatus struct {
@@ -77,11 +88,69 @@ func (p Pipeline) Workflows() []string {
 	return ws
 }
 
+// MatrixJob is one combination of values a matrix workflow fanned out
+// into.
+type MatrixJob struct {
+	Name   string
+	Values string
+	Status WorkflowStatus
+}
+
+// Jobs returns the matrix jobs of a workflow, if it has any.
+func (p Pipeline) Jobs(workflowName string) []MatrixJob {
+	var jobs []MatrixJob
+	for name, status := range p.MatrixJobs {
+		if parent, values, ok := workflow.SplitMatrixJobName(name); ok && parent == workflowName {
+			jobs = append(jobs, MatrixJob{name, values, status})
+		}
+	}
+	slices.SortFunc(jobs, func(a, b MatrixJob) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return jobs
+}
+
 // if we know that a spindle has picked up this pipeline, then it is Responding
 func (p Pipeline) IsResponding() bool {
 	return len(p.Statuses) != 0
 }
 
+// a pipeline is finished once every one of its workflows reached an "end state"
+func (p Pipeline) IsFinished() bool {
+	if !p.IsResponding() {
+		return false
+	}
+	for _, w := range p.Statuses {
+		if !w.Latest().Status.IsFinish() {
+			return false
+		}
+	}
+	return true
+}
+
+// PipelineBlocker explains why p keeps a pull from merging in a repo that
+// requires passing pipelines, or returns "" if every workflow of p passed.
+// p may be nil.
+func PipelineBlocker(p *Pipeline) string {
+	switch {
+	case p == nil || !p.IsResponding():
+		return "has no pipeline yet"
+	case !p.IsFinished():
+		return "has a pipeline that is still running"
+	}
+
+	for _, w := range p.Statuses {
+		if w.Latest().Status != spindle.StatusKindSuccess {
+			return "has a pipeline that did not pass"
+		}
+	}
+	return ""
+}
+
+func (p Pipeline) AtUri() syntax.ATURI {
+	return syntax.ATURI(fmt.Sprintf("at://did:web:%s/%s/%s", p.Knot, tangled.PipelineNSID, p.Rkey))
+}
+
 type Trigger struct {
 	Id   int
 	Kind workflow.TriggerKind
@@ -128,3 +197,16 @@ type PipelineStatus struct {
 	Error        *string
 	ExitCode     int
 }
+
+// PipelineArtifact is a file kept from a successful workflow, stored on the
+// spindle that ran it. These are unrelated to the artifacts attached to tags.
+type PipelineArtifact struct {
+	Id           int
+	Spindle      string
+	PipelineKnot string
+	PipelineRkey string
+	Workflow     string
+	Name         string
+	Size         uint64
+	Created      time.Time
+}
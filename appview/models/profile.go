@@ -18,7 +18,7 @@ type Profile struct {
 	Location       string
 	Links          [5]string
 	Stats          [2]VanityStat
-	PinnedRepos    [6]syntax.ATURI
+	Pins           [6]syntax.ATURI // repos, strings, issues and pulls, in order
 	Pronouns       string
 }
 
@@ -40,8 +40,8 @@ func (p Profile) IsStatsEmpty() bool {
 	return true
 }
 
-func (p Profile) IsPinnedReposEmpty() bool {
-	for _, r := range p.PinnedRepos {
+func (p Profile) IsPinsEmpty() bool {
+	for _, r := range p.Pins {
 		if r != "" {
 			return false
 		}
@@ -49,6 +49,43 @@ func (p Profile) IsPinnedReposEmpty() bool {
 	return true
 }
 
+type PinKind string
+
+const (
+	PinKindRepo   PinKind = "repo"
+	PinKindString PinKind = "string"
+	PinKindIssue  PinKind = "issue"
+	PinKindPull   PinKind = "pull"
+)
+
+// PinKindOf is the kind of what uri points at, going by its collection, or
+// "" if it isn't something that can be pinned.
+func PinKindOf(uri syntax.ATURI) PinKind {
+	switch uri.Collection().String() {
+	case tangled.RepoNSID:
+		return PinKindRepo
+	case tangled.StringNSID:
+		return PinKindString
+	case tangled.RepoIssueNSID:
+		return PinKindIssue
+	case tangled.RepoPullNSID:
+		return PinKindPull
+	}
+	return ""
+}
+
+// Pin is something pinned to a profile, along with what it points at; only
+// the field of its kind is set.
+type Pin struct {
+	Kind PinKind
+	Uri  syntax.ATURI
+
+	Repo   *Repo
+	String *String
+	Issue  *Issue
+	Pull   *Pull
+}
+
 type VanityStatKind string
 
 const (
@@ -11,6 +11,17 @@ type PublicKey struct {
 	Name    string `json:"name"`
 	Rkey    string `json:"rkey"`
 	Created *time.Time
+
+	// set by the key's expiry-time option, if any
+	Expires *time.Time `json:"-"`
+	// when the key was deleted by its owner, signatures made with it are
+	// no longer trusted
+	Revoked *time.Time `json:"-"`
+}
+
+// ExpiredAt reports whether the key had expired by t.
+func (p PublicKey) ExpiredAt(t time.Time) bool {
+	return p.Expires != nil && t.After(*p.Expires)
 }
 
 func (p PublicKey) MarshalJSON() ([]byte, error) {
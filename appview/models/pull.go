@@ -79,6 +79,10 @@ type Pull struct {
 	// optionally, populate this when querying for reverse mappings
 	Labels LabelState
 	Repo   *Repo
+
+	// issues and pulls referenced from the body and comments, keyed by
+	// how they were written
+	References map[string]Reference
 }
 
 func (p Pull) AsRecord() tangled.RepoPull {
@@ -151,6 +155,16 @@ type PullComment struct {
 	Created time.Time
 }
 
+func (c *PullComment) AtUri() syntax.ATURI {
+	return syntax.ATURI(c.CommentAt)
+}
+
+// Suggestions are the changes proposed in the comment, see
+// patchutil.ParseSuggestions.
+func (c *PullComment) Suggestions() []patchutil.Suggestion {
+	return patchutil.ParseSuggestions(c.Body)
+}
+
 func (p *Pull) LastRoundNumber() int {
 	return len(p.Submissions) - 1
 }
@@ -257,6 +271,13 @@ func (s *PullSubmission) Participants() []string {
 	return participants
 }
 
+// HasSuggestions reports whether any comment on the round proposes a change.
+func (s *PullSubmission) HasSuggestions() bool {
+	return slices.ContainsFunc(s.Comments, func(c PullComment) bool {
+		return len(c.Suggestions()) > 0
+	})
+}
+
 func (s PullSubmission) CombinedPatch() string {
 	if s.Combined == "" {
 		return s.Patch
@@ -332,6 +353,48 @@ func (stack Stack) CombinedPatch() string {
 	return combined.String()
 }
 
+// Reorder arranges the stack by the given change-ids, top of the stack
+// first, and rewrites parent-change-ids to match the new order. The pulls
+// are copied, the original stack is left untouched.
+//
+// Pulls that are no longer open stay where they are.
+func (stack Stack) Reorder(changeIds []string) (Stack, error) {
+	if len(changeIds) != len(stack) {
+		return nil, fmt.Errorf("expected %d pulls in the new order, got %d", len(stack), len(changeIds))
+	}
+
+	byChangeId := make(map[string]*Pull, len(stack))
+	for _, p := range stack {
+		byChangeId[p.ChangeId] = p
+	}
+
+	reordered := make(Stack, len(changeIds))
+	for i, id := range changeIds {
+		p, ok := byChangeId[id]
+		if !ok {
+			return nil, fmt.Errorf("change %s is not part of this stack, or is listed twice", id)
+		}
+		delete(byChangeId, id)
+
+		if p.State != PullOpen && stack[i].ChangeId != id {
+			return nil, fmt.Errorf("#%d is %s and cannot be moved", p.PullId, p.State.String())
+		}
+
+		np := *p
+		reordered[i] = &np
+	}
+
+	for i, p := range reordered {
+		if i+1 < len(reordered) {
+			p.ParentChangeId = reordered[i+1].ChangeId
+		} else {
+			p.ParentChangeId = ""
+		}
+	}
+
+	return reordered, nil
+}
+
 // filter out PRs that are "active"
 //
 // PRs that are still open are active
@@ -356,4 +419,52 @@ func (stack Stack) Mergeable() Stack {
 type BranchDeleteStatus struct {
 	Repo   *Repo
 	Branch string
+	// open pulls that target the branch, deleting it needs confirmation then
+	TargetOf []int
+}
+
+// BranchDeletion is the outcome of deleting the source branch of a merged
+// pull, either from the pull page or automatically on merge.
+type BranchDeletion struct {
+	Id      int64
+	PullAt  syntax.ATURI
+	RepoAt  syntax.ATURI
+	Branch  string
+	Did     string
+	Error   string
+	Created time.Time
+}
+
+func (d BranchDeletion) Succeeded() bool {
+	return d.Error == ""
+}
+
+// PullDependency is a pull that another one has been declared to depend
+// on, in the same repo or not. The dependent pull can't be merged until its
+// dependencies are.
+type PullDependency struct {
+	Id int64
+	// the dependent pull
+	PullAt  syntax.ATURI
+	Did     string
+	Created time.Time
+
+	// the pull depended on, and its state
+	On      Reference
+	OnState PullState
+}
+
+func (d PullDependency) IsBlocking() bool {
+	return d.OnState != PullMerged
+}
+
+// BlockingDependencies are the dependencies that are not merged yet.
+func BlockingDependencies(deps []PullDependency) []PullDependency {
+	var blocking []PullDependency
+	for _, d := range deps {
+		if d.IsBlocking() {
+			blocking = append(blocking, d)
+		}
+	}
+	return blocking
 }
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
 
 type Punch struct {
 	Did   string
@@ -12,3 +16,23 @@ type Punchcard struct {
 	Total   int
 	Punches []Punch
 }
+
+// PunchKind is what kind of record a punch was made for, commits are
+// counted separately as they don't have one.
+type PunchKind string
+
+const (
+	PunchIssue        PunchKind = "issue"
+	PunchIssueComment PunchKind = "issue_comment"
+	PunchPull         PunchKind = "pull"
+	PunchPullComment  PunchKind = "pull_comment"
+)
+
+// PunchEvent is a single contribution that is a record, counted at most once
+// however many times it is seen.
+type PunchEvent struct {
+	Did       string
+	SubjectAt syntax.ATURI
+	Kind      PunchKind
+	Created   time.Time
+}
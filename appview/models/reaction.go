@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
 )
 
 type ReactionKind string
@@ -60,3 +61,17 @@ type ReactionDisplayData struct {
 	Count int
 	Users []string
 }
+
+// reactions can be attached to threads (issues and pulls) as well as
+// individual comments on those threads
+var reactableCollections = map[syntax.NSID]struct{}{
+	tangled.RepoIssueNSID:        {},
+	tangled.RepoIssueCommentNSID: {},
+	tangled.RepoPullNSID:         {},
+	tangled.RepoPullCommentNSID:  {},
+}
+
+func IsReactable(subject syntax.ATURI) bool {
+	_, ok := reactableCollections[subject.Collection()]
+	return ok
+}
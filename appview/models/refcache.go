@@ -0,0 +1,11 @@
+package models
+
+// RefCacheKind is which listing of a repo's refs is cached. Contributor
+// statistics are cached the same way, since a push invalidates them too.
+type RefCacheKind string
+
+const (
+	RefCacheBranches     RefCacheKind = "branches"
+	RefCacheTags         RefCacheKind = "tags"
+	RefCacheContributors RefCacheKind = "contributors"
+)
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+type ReferenceKind string
+
+const (
+	ReferenceKindIssue ReferenceKind = "issue"
+	ReferenceKindPull  ReferenceKind = "pull"
+)
+
+// Reference is an issue or a pull, as linked to from the body of another
+// issue, pull or comment.
+type Reference struct {
+	Kind     ReferenceKind
+	AtUri    syntax.ATURI
+	Number   int
+	Title    string
+	RepoDid  string
+	RepoName string
+}
+
+func (r Reference) Href() string {
+	switch r.Kind {
+	case ReferenceKindPull:
+		return fmt.Sprintf("/%s/%s/pulls/%d", r.RepoDid, r.RepoName, r.Number)
+	default:
+		return fmt.Sprintf("/%s/%s/issues/%d", r.RepoDid, r.RepoName, r.Number)
+	}
+}
+
+// ReferenceHrefs maps references, as written, to the page they link to.
+func ReferenceHrefs(refs map[string]Reference) map[string]string {
+	hrefs := make(map[string]string, len(refs))
+	for text, ref := range refs {
+		hrefs[text] = ref.Href()
+	}
+	return hrefs
+}
@@ -11,6 +11,7 @@ type Registration struct {
 	Created      *time.Time
 	Registered   *time.Time
 	NeedsUpgrade bool
+	Approval     Approval
 }
 
 func (r *Registration) Status() Status {
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+)
+
+// Release dresses up a tag with a title and notes. Artifacts stay attached
+// to the tag itself, so a release shows whatever was uploaded to its tag.
+type Release struct {
+	Id         int64
+	Did        string
+	Rkey       string
+	RepoAt     syntax.ATURI
+	Tag        string
+	Title      string
+	Body       string
+	Prerelease bool
+	Created    time.Time
+	Edited     *time.Time
+
+	// optionally, populate this when querying for reverse mappings
+	Artifacts []Artifact
+	// set when the tag was deleted from the repo after the release was cut
+	TagMissing bool
+}
+
+func (r *Release) AtUri() syntax.ATURI {
+	return syntax.ATURI(fmt.Sprintf("at://%s/%s/%s", r.Did, tangled.RepoReleaseNSID, r.Rkey))
+}
+
+func (r *Release) AsRecord() tangled.RepoRelease {
+	return tangled.RepoRelease{
+		Repo:       r.RepoAt.String(),
+		Tag:        r.Tag,
+		Title:      r.Title,
+		Body:       &r.Body,
+		Prerelease: &r.Prerelease,
+		CreatedAt:  r.Created.Format(time.RFC3339),
+	}
+}
+
+func ReleaseFromRecord(did, rkey string, record tangled.RepoRelease) (*Release, error) {
+	repoAt, err := syntax.ParseATURI(record.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repo: %w", err)
+	}
+
+	created, err := time.Parse(time.RFC3339, record.CreatedAt)
+	if err != nil {
+		created = time.Now()
+	}
+
+	release := Release{
+		Did:     did,
+		Rkey:    rkey,
+		RepoAt:  repoAt,
+		Tag:     record.Tag,
+		Title:   record.Title,
+		Created: created,
+	}
+	if record.Body != nil {
+		release.Body = *record.Body
+	}
+	if record.Prerelease != nil {
+		release.Prerelease = *record.Prerelease
+	}
+
+	return &release, nil
+}
@@ -22,22 +22,45 @@ type Repo struct {
 	Topics      []string
 	Spindle     string
 	Labels      []string
+	Visibility  RepoVisibility
 
 	// optionally, populate this when querying for reverse mappings
 	RepoStats *RepoStats
 
 	// optional
 	Source string
+
+	// set while the repo is offered to another user
+	TransferTo string
+
+	// appview setting, the source branches of merged pulls are deleted
+	DeleteBranchOnMerge bool
+
+	// appview setting, others can start new repos off this one's tree
+	IsTemplate bool
+
+	// appview setting, pushes don't run pipelines on the spindle
+	PipelinesDisabled bool
+
+	// how many pipelines the spindle runs at once, 0 leaves it to the spindle
+	PipelineConcurrency int
+
+	// queued pipelines of a branch are cancelled when a newer one comes in
+	CancelSupersededPipelines bool
+
+	// appview setting, pulls only merge once the pipeline on their head passed
+	RequirePassingPipeline bool
 }
 
 func (r *Repo) AsRecord() tangled.Repo {
-	var source, spindle, description, website *string
+	var source, spindle, description, website, transferTo, visibility *string
 
 	if r.Source != "" {
 		source = &r.Source
 	}
 
-	if r.Spindle != "" {
+	// spindles only watch repos whose record names them
+	if r.Spindle != "" && !r.PipelinesDisabled {
 		spindle = &r.Spindle
 	}
 
@@ -49,6 +72,26 @@ func (r *Repo) AsRecord() tangled.Repo {
 		website = &r.Website
 	}
 
+	if r.TransferTo != "" {
+		transferTo = &r.TransferTo
+	}
+
+	if r.IsPrivate() {
+		v := string(r.Visibility)
+		visibility = &v
+	}
+
+	var concurrency *int64
+	if r.PipelineConcurrency > 0 {
+		c := int64(r.PipelineConcurrency)
+		concurrency = &c
+	}
+
+	var cancelSuperseded *bool
+	if r.CancelSupersededPipelines {
+		cancelSuperseded = &r.CancelSupersededPipelines
+	}
+
 	return tangled.Repo{
 		Knot:        r.Knot,
 		Name:        r.Name,
@@ -59,9 +102,24 @@ func (r *Repo) AsRecord() tangled.Repo {
 		Source:      source,
 		Spindle:     spindle,
 		Labels:      r.Labels,
+		TransferTo:  transferTo,
+		Visibility:  visibility,
+
+		PipelineConcurrency:       concurrency,
+		CancelSupersededPipelines: cancelSuperseded,
 	}
 }
 
+func (r Repo) IsPrivate() bool {
+	return r.Visibility == RepoVisibilityPrivate
+}
+
+// RequiresPassingPipeline reports whether pulls must wait for a passing
+// pipeline, which only makes sense while pipelines can run at all.
+func (r Repo) RequiresPassingPipeline() bool {
+	return r.RequirePassingPipeline && r.Spindle != "" && !r.PipelinesDisabled
+}
+
 func (r Repo) RepoAt() syntax.ATURI {
 	return syntax.ATURI(fmt.Sprintf("at://%s/%s/%s", r.Did, tangled.RepoNSID, r.Rkey))
 }
@@ -75,9 +133,26 @@ func (r Repo) TopicStr() string {
 	return strings.Join(r.Topics, " ")
 }
 
+type RepoVisibility string
+
+const (
+	RepoVisibilityPublic  RepoVisibility = "public"
+	RepoVisibilityPrivate RepoVisibility = "private"
+)
+
+// RepoVisibilityFromRecord reads the visibility of a repo record, records
+// without one are public.
+func RepoVisibilityFromRecord(record *tangled.Repo) RepoVisibility {
+	if record.Visibility != nil && *record.Visibility == string(RepoVisibilityPrivate) {
+		return RepoVisibilityPrivate
+	}
+	return RepoVisibilityPublic
+}
+
 type RepoStats struct {
 	Language   string
 	StarCount  int
+	ForkCount  int
 	IssueCount IssueCount
 	PullCount  PullCount
 }
@@ -114,6 +189,9 @@ const (
 	BlobContentTypeSvg
 	BlobContentTypeVideo
 	BlobContentTypeSubmodule
+	BlobContentTypeNotebook
+	BlobContentTypeTable
+	BlobContentTypePdf
 )
 
 func (ty BlobContentType) IsCode() bool      { return ty == BlobContentTypeCode }
@@ -122,6 +200,9 @@ func (ty BlobContentType) IsImage() bool     { return ty == BlobContentTypeImage
 func (ty BlobContentType) IsSvg() bool       { return ty == BlobContentTypeSvg }
 func (ty BlobContentType) IsVideo() bool     { return ty == BlobContentTypeVideo }
 func (ty BlobContentType) IsSubmodule() bool { return ty == BlobContentTypeSubmodule }
+func (ty BlobContentType) IsNotebook() bool  { return ty == BlobContentTypeNotebook }
+func (ty BlobContentType) IsTable() bool     { return ty == BlobContentTypeTable }
+func (ty BlobContentType) IsPdf() bool       { return ty == BlobContentTypePdf }
 
 type BlobView struct {
 	HasTextView     bool // can show as code/text
@@ -151,3 +232,9 @@ func (b BlobView) IsUnsupported() bool {
 	// no view available, only raw
 	return !(b.HasRenderedView || b.HasTextView)
 }
+
+// ForkNode is a fork in a repo's fork network, along with its own forks.
+type ForkNode struct {
+	Repo     Repo
+	Children []*ForkNode
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+)
+
+type ReportKind string
+
+const (
+	ReportKindRepo    ReportKind = "repo"
+	ReportKindIssue   ReportKind = "issue"
+	ReportKindComment ReportKind = "comment"
+	ReportKindUser    ReportKind = "user"
+)
+
+// ReportKindOf is the kind of what subject is, a DID for users and an AT-URI
+// for everything else, or "" if it can't be reported.
+func ReportKindOf(subject string) ReportKind {
+	if strings.HasPrefix(subject, "did:") {
+		if _, err := syntax.ParseDID(subject); err == nil {
+			return ReportKindUser
+		}
+		return ""
+	}
+
+	uri, err := syntax.ParseATURI(subject)
+	if err != nil {
+		return ""
+	}
+	switch uri.Collection().String() {
+	case tangled.RepoNSID:
+		return ReportKindRepo
+	case tangled.RepoIssueNSID:
+		return ReportKindIssue
+	case tangled.RepoIssueCommentNSID:
+		return ReportKindComment
+	}
+	return ""
+}
+
+type ReportReason string
+
+const (
+	ReportReasonSpam       ReportReason = "spam"
+	ReportReasonHarassment ReportReason = "harassment"
+	ReportReasonIllegal    ReportReason = "illegal"
+	ReportReasonMalware    ReportReason = "malware"
+	ReportReasonOther      ReportReason = "other"
+)
+
+var ReportReasons = []ReportReason{
+	ReportReasonSpam,
+	ReportReasonHarassment,
+	ReportReasonIllegal,
+	ReportReasonMalware,
+	ReportReasonOther,
+}
+
+func (r ReportReason) IsValid() bool {
+	for _, reason := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportDismissed ReportStatus = "dismissed"
+	ReportTakenDown ReportStatus = "takendown"
+)
+
+// Report flags something for the moderators of this appview. Who reported
+// it is only ever shown to them.
+type Report struct {
+	Id          int64
+	ReporterDid string
+	Subject     string
+	Kind        ReportKind
+	Reason      ReportReason
+	Details     string
+	Status      ReportStatus
+	Created     time.Time
+
+	// set once a moderator has dealt with it
+	ResolvedBy *string
+	Resolved   *time.Time
+}
+
+// ReportTarget is what a report is about, as shown to whoever is reporting
+// it or reviewing the report.
+type ReportTarget struct {
+	Kind     ReportKind
+	Subject  string
+	OwnerDid string
+	Title    string
+	Link     string
+	Excerpt  string
+}
+
+// Takedown hides a repo, issue, comment or user from everyone.
+type Takedown struct {
+	Subject string
+	ByDid   string
+	Created time.Time
+}
@@ -7,6 +7,11 @@ type IssueSearchOptions struct {
 	RepoAt  string
 	IsOpen  bool
 
+	// match issues in any state, ignoring IsOpen
+	AnyState bool
+	// issues in these repos never match
+	ExcludeRepoAts []string
+
 	Page pagination.Page
 }
 
@@ -15,6 +20,11 @@ type PullSearchOptions struct {
 	RepoAt  string
 	State   PullState
 
+	// match pulls in any state, ignoring State
+	AnyState bool
+	// pulls in these repos never match
+	ExcludeRepoAts []string
+
 	Page pagination.Page
 }
 
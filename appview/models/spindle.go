@@ -13,6 +13,7 @@ type Spindle struct {
 	Verified     *time.Time
 	Created      time.Time
 	NeedsUpgrade bool
+	Approval     Approval
 }
 
 type SpindleMember struct {
@@ -0,0 +1,39 @@
+package models
+
+import "tangled.org/core/api/tangled"
+
+// StorageUsage is the disk taken up by a user's repos on a knot.
+type StorageUsage struct {
+	Knot  string
+	Usage uint64
+	// 0 when the knot has no quota
+	Quota uint64
+}
+
+func StorageUsageFromKnot(knot string, out *tangled.KnotUsage_Output) StorageUsage {
+	s := StorageUsage{Knot: knot}
+	if out.Usage > 0 {
+		s.Usage = uint64(out.Usage)
+	}
+	if out.Quota != nil && *out.Quota > 0 {
+		s.Quota = uint64(*out.Quota)
+	}
+	return s
+}
+
+func (s StorageUsage) HasQuota() bool {
+	return s.Quota > 0
+}
+
+// Percent is how much of the quota is used, at most 100.
+func (s StorageUsage) Percent() int {
+	if !s.HasQuota() {
+		return 0
+	}
+	return int(min(s.Usage*100/s.Quota, 100))
+}
+
+// IsNearQuota reports whether pushes are about to be refused.
+func (s StorageUsage) IsNearQuota() bool {
+	return s.HasQuota() && s.Usage*10 >= s.Quota*9
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+type ThreadEventKind string
+
+const (
+	ThreadEventOpened     ThreadEventKind = "opened"
+	ThreadEventClosed     ThreadEventKind = "closed"
+	ThreadEventReopened   ThreadEventKind = "reopened"
+	ThreadEventMerged     ThreadEventKind = "merged"
+	ThreadEventLabeled    ThreadEventKind = "labeled"
+	ThreadEventUnlabeled  ThreadEventKind = "unlabeled"
+	ThreadEventAssigned   ThreadEventKind = "assigned"
+	ThreadEventUnassigned ThreadEventKind = "unassigned"
+	ThreadEventReferenced ThreadEventKind = "referenced"
+)
+
+// ThreadEvent is something that happened to an issue or pull, other than a
+// comment. Only state changes are stored as events of their own; the others
+// are read off the records they come from, label ops and references, so
+// that they stay as portable as those are.
+type ThreadEvent struct {
+	Id       int64
+	ThreadAt syntax.ATURI
+	Kind     ThreadEventKind
+	Did      string
+	Created  time.Time
+
+	// set on label events: the definition, and the value added or removed
+	LabelKey   string
+	LabelValue string
+
+	// set on referenced events: the issue or pull the reference is in
+	Reference *Reference
+}
+
+// LabelEvents turns the label ops on a thread into events. Ops on labels
+// whose values are users are told apart as assignments.
+func LabelEvents(threadAt syntax.ATURI, ops []LabelOp, defs map[string]*LabelDefinition) []ThreadEvent {
+	var events []ThreadEvent
+	for _, op := range ops {
+		def, ok := defs[op.OperandKey]
+		if !ok {
+			continue
+		}
+
+		assign := def.ValueType.IsDidFormat()
+		var kind ThreadEventKind
+		switch {
+		case op.Operation == LabelOperationAdd && assign:
+			kind = ThreadEventAssigned
+		case op.Operation == LabelOperationAdd:
+			kind = ThreadEventLabeled
+		case op.Operation == LabelOperationDel && assign:
+			kind = ThreadEventUnassigned
+		case op.Operation == LabelOperationDel:
+			kind = ThreadEventUnlabeled
+		default:
+			continue
+		}
+
+		events = append(events, ThreadEvent{
+			Id:         op.Id,
+			ThreadAt:   threadAt,
+			Kind:       kind,
+			Did:        op.Did,
+			Created:    op.SortAt(),
+			LabelKey:   op.OperandKey,
+			LabelValue: op.OperandValue,
+		})
+	}
+	return events
+}
+
+// TimelineItem is one entry of the timeline of an issue or pull: either a
+// comment, with its replies on issues, or an event.
+type TimelineItem struct {
+	Created     time.Time
+	Comment     *CommentListItem
+	PullComment *PullComment
+	Event       *ThreadEvent
+}
+
+// IssueTimeline interleaves the comment threads of an issue with its events,
+// oldest first.
+func IssueTimeline(comments []CommentListItem, events []ThreadEvent) []TimelineItem {
+	var items []TimelineItem
+	for i := range comments {
+		items = append(items, TimelineItem{Created: comments[i].Self.Created, Comment: &comments[i]})
+	}
+	return withEvents(items, events)
+}
+
+// PullTimelines splits the comments and events of a pull by the round they
+// happened in, and interleaves them within each round, oldest first. Events
+// from before the first round go with it.
+func PullTimelines(pull *Pull, events []ThreadEvent) [][]TimelineItem {
+	timelines := make([][]TimelineItem, len(pull.Submissions))
+	if len(timelines) == 0 {
+		return timelines
+	}
+
+	eventsByRound := make([][]ThreadEvent, len(timelines))
+	for _, e := range events {
+		round := 0
+		for i, s := range pull.Submissions {
+			if !e.Created.Before(s.Created) {
+				round = i
+			}
+		}
+		eventsByRound[round] = append(eventsByRound[round], e)
+	}
+
+	for i, s := range pull.Submissions {
+		var items []TimelineItem
+		for _, c := range s.Comments {
+			items = append(items, TimelineItem{Created: c.Created, PullComment: &c})
+		}
+		timelines[i] = withEvents(items, eventsByRound[i])
+	}
+	return timelines
+}
+
+func withEvents(items []TimelineItem, events []ThreadEvent) []TimelineItem {
+	for i := range events {
+		items = append(items, TimelineItem{Created: events[i].Created, Event: &events[i]})
+	}
+	slices.SortStableFunc(items, func(a, b TimelineItem) int {
+		return a.Created.Compare(b.Created)
+	})
+	return items
+}
@@ -21,3 +21,14 @@ type TimelineEvent struct {
 	IsStarred bool
 	StarCount int64
 }
+
+// TimelineFeed is what a timeline is made of.
+type TimelineFeed string
+
+const (
+	// TimelineGlobal is everything that happened in public.
+	TimelineGlobal TimelineFeed = "global"
+	// TimelineFollowing is what the people a user follows did, and what
+	// happened on the repos they watch.
+	TimelineFollowing TimelineFeed = "following"
+)
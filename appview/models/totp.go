@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TotpSecret is the authenticator app a user enrolled, it counts once they
+// confirmed it with a code.
+type TotpSecret struct {
+	Did       string
+	Secret    string
+	Confirmed bool
+	// the last time step a code was accepted for, codes can't be reused
+	LastStep int64
+	Created  time.Time
+}
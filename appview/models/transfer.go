@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+type RepoTransferStatus string
+
+const (
+	RepoTransferPending  RepoTransferStatus = "pending"
+	RepoTransferDeclined RepoTransferStatus = "declined"
+)
+
+// RepoTransfer is a repo that its owner has offered to another user.
+type RepoTransfer struct {
+	Id      int64
+	RepoAt  syntax.ATURI
+	FromDid syntax.DID
+	ToDid   syntax.DID
+	Status  RepoTransferStatus
+	Created time.Time
+}
+
+func (t RepoTransfer) IsPending() bool {
+	return t.Status == RepoTransferPending
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// Watch subscribes a user to every new issue and pull in a repo, unlike a
+// star which only bookmarks it.
+type Watch struct {
+	Did     string
+	RepoAt  syntax.ATURI
+	Created time.Time
+	Rkey    string
+}
+
+// RepoWatch is used for reverse mapping to repos
+type RepoWatch struct {
+	Watch
+	Repo *Repo
+}
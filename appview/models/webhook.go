@@ -0,0 +1,50 @@
+package models
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// Webhook is an endpoint that is sent a signed request for each event on a
+// repo.
+type Webhook struct {
+	Id      int64
+	RepoAt  syntax.ATURI
+	Url     string
+	Secret  string
+	Created time.Time
+}
+
+// DisplayUrl is the url of the webhook with any credentials in it masked.
+func (h Webhook) DisplayUrl() string {
+	u, err := url.Parse(h.Url)
+	if err != nil {
+		return h.Url
+	}
+	return u.Redacted()
+}
+
+// WebhookDelivery is one attempt at sending an event to a webhook.
+// Redeliveries are attempts of their own, pointing back at the delivery they
+// were made from.
+type WebhookDelivery struct {
+	Id              int64
+	WebhookId       int64
+	Event           string
+	Guid            string
+	RequestHeaders  string
+	Payload         string
+	ResponseStatus  int
+	ResponseHeaders string
+	ResponseBody    string
+	Error           string
+	Duration        time.Duration
+	RedeliveryOf    *int64
+	Created         time.Time
+}
+
+func (d WebhookDelivery) Succeeded() bool {
+	return d.Error == "" && d.ResponseStatus >= 200 && d.ResponseStatus < 300
+}
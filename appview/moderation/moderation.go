@@ -0,0 +1,340 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+	"tangled.org/core/idresolver"
+)
+
+var errUnknownSubject = errors.New("unknown subject")
+
+type Moderation struct {
+	db         *db.DB
+	oauth      *oauth.OAuth
+	pages      *pages.Pages
+	config     *config.Config
+	idResolver *idresolver.Resolver
+	logger     *slog.Logger
+}
+
+func New(
+	database *db.DB,
+	oauthHandler *oauth.OAuth,
+	pagesHandler *pages.Pages,
+	config *config.Config,
+	idResolver *idresolver.Resolver,
+	logger *slog.Logger,
+) *Moderation {
+	return &Moderation{
+		db:         database,
+		oauth:      oauthHandler,
+		pages:      pagesHandler,
+		config:     config,
+		idResolver: idResolver,
+		logger:     logger,
+	}
+}
+
+func (m *Moderation) Router(mw *middleware.Middleware) http.Handler {
+	r := chi.NewRouter()
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(m.oauth))
+		r.Get("/report", m.reportPage)
+		r.Post("/report", m.submitReport)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(m.oauth))
+		r.Use(m.moderatorsOnly)
+		r.With(middleware.Paginate).Get("/", m.queuePage)
+		r.Post("/{id}/dismiss", m.resolve(models.ReportDismissed))
+		r.Post("/{id}/takedown", m.resolve(models.ReportTakenDown))
+	})
+
+	return r
+}
+
+// moderatorsOnly hides what it wraps from everyone but moderators.
+func (m *Moderation) moderatorsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := m.oauth.GetUser(r)
+		if user == nil || !m.config.Moderation.IsModerator(user.Did) {
+			m.pages.Error404(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Moderation) reportPage(w http.ResponseWriter, r *http.Request) {
+	l := m.logger.With("handler", "reportPage")
+	user := m.oauth.GetUser(r)
+
+	target, err := Describe(r.Context(), m.db, m.idResolver, r.URL.Query().Get("subject"))
+	if err != nil {
+		l.Error("failed to describe report subject", "err", err)
+		m.pages.Error404(w)
+		return
+	}
+
+	m.pages.ReportPage(w, pages.ReportParams{
+		LoggedInUser: user,
+		Target:       target,
+		Reasons:      models.ReportReasons,
+	})
+}
+
+func (m *Moderation) submitReport(w http.ResponseWriter, r *http.Request) {
+	l := m.logger.With("handler", "submitReport")
+	user := m.oauth.GetUser(r)
+	noticeId := "report"
+
+	target, err := Describe(r.Context(), m.db, m.idResolver, r.FormValue("subject"))
+	if err != nil {
+		l.Error("failed to describe report subject", "err", err)
+		m.pages.Notice(w, noticeId, "There is nothing to report here.")
+		return
+	}
+
+	reason := models.ReportReason(r.FormValue("reason"))
+	if !reason.IsValid() {
+		m.pages.Notice(w, noticeId, "Pick a reason for the report.")
+		return
+	}
+
+	details := strings.TrimSpace(r.FormValue("details"))
+	if len(details) > 2000 {
+		m.pages.Notice(w, noticeId, "Details can be at most 2000 characters long.")
+		return
+	}
+
+	if limit := m.config.Moderation.ReportsPerDay; limit > 0 {
+		since := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+		count, err := db.CountReports(
+			m.db,
+			db.FilterEq("reporter_did", user.Did),
+			db.FilterGte("created", since),
+		)
+		if err != nil {
+			l.Error("failed to count reports", "err", err)
+			m.pages.Notice(w, noticeId, "Failed to file the report, try again later.")
+			return
+		}
+		if count >= int64(limit) {
+			m.pages.Notice(w, noticeId, "You have filed too many reports today, try again tomorrow.")
+			return
+		}
+	}
+
+	filed, err := db.AddReport(m.db, &models.Report{
+		ReporterDid: user.Did,
+		Subject:     target.Subject,
+		Kind:        target.Kind,
+		Reason:      reason,
+		Details:     details,
+	})
+	if err != nil {
+		l.Error("failed to add report", "err", err)
+		m.pages.Notice(w, noticeId, "Failed to file the report, try again later.")
+		return
+	}
+	if !filed {
+		m.pages.Notice(w, noticeId, "You have already reported this; a moderator will look at it soon.")
+		return
+	}
+
+	l.Info("report filed", "subject", target.Subject, "reason", reason)
+	m.pages.Notice(w, noticeId, "Thanks, a moderator will look at your report soon.")
+}
+
+func (m *Moderation) queuePage(w http.ResponseWriter, r *http.Request) {
+	l := m.logger.With("handler", "queuePage")
+	user := m.oauth.GetUser(r)
+	page := pagination.FromContext(r.Context())
+
+	status := models.ReportStatus(r.URL.Query().Get("status"))
+	switch status {
+	case models.ReportDismissed, models.ReportTakenDown:
+	default:
+		status = models.ReportOpen
+	}
+
+	total, err := db.CountReports(m.db, db.FilterEq("status", status))
+	if err != nil {
+		l.Error("failed to count reports", "err", err)
+		m.pages.Error500(w)
+		return
+	}
+
+	reports, err := db.GetReports(m.db, page, db.FilterEq("status", status))
+	if err != nil {
+		l.Error("failed to get reports", "err", err)
+		m.pages.Error500(w)
+		return
+	}
+
+	items := make([]pages.ModerationQueueItem, 0, len(reports))
+	for _, report := range reports {
+		target, err := Describe(r.Context(), m.db, m.idResolver, report.Subject)
+		if err != nil {
+			// what was reported may be gone since
+			target = models.ReportTarget{Kind: report.Kind, Subject: report.Subject}
+		}
+		items = append(items, pages.ModerationQueueItem{
+			Report: report,
+			Target: target,
+		})
+	}
+
+	m.pages.ModerationQueue(w, pages.ModerationQueueParams{
+		LoggedInUser: user,
+		Items:        items,
+		Status:       status,
+		Page:         page,
+		Total:        total,
+	})
+}
+
+// resolve closes a report, along with every other open report on the same
+// subject, taking the subject down if asked to.
+func (m *Moderation) resolve(status models.ReportStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := m.logger.With("handler", "resolve", "status", status)
+		user := m.oauth.GetUser(r)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			m.pages.Error404(w)
+			return
+		}
+
+		report, err := db.GetReport(m.db, id)
+		if err != nil {
+			l.Error("failed to get report", "err", err, "id", id)
+			m.pages.Error404(w)
+			return
+		}
+
+		tx, err := m.db.Begin()
+		if err != nil {
+			l.Error("failed to start transaction", "err", err)
+			m.pages.Notice(w, fmt.Sprintf("report-%d", id), "Failed to resolve the report.")
+			return
+		}
+		defer tx.Rollback()
+
+		if status == models.ReportTakenDown {
+			if err := db.AddTakedown(tx, &models.Takedown{Subject: report.Subject, ByDid: user.Did}); err != nil {
+				l.Error("failed to take down", "err", err, "subject", report.Subject)
+				m.pages.Notice(w, fmt.Sprintf("report-%d", id), "Failed to take it down.")
+				return
+			}
+		}
+
+		if err := db.ResolveReports(tx, report.Subject, status, user.Did); err != nil {
+			l.Error("failed to resolve reports", "err", err, "subject", report.Subject)
+			m.pages.Notice(w, fmt.Sprintf("report-%d", id), "Failed to resolve the report.")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			l.Error("failed to commit", "err", err)
+			m.pages.Notice(w, fmt.Sprintf("report-%d", id), "Failed to resolve the report.")
+			return
+		}
+
+		l.Info("report resolved", "subject", report.Subject, "moderator", user.Did)
+		m.pages.HxRefresh(w)
+	}
+}
+
+// Describe looks up what subject is, so it can be shown to whoever reports
+// it or reviews the report.
+func Describe(ctx context.Context, d *db.DB, idResolver *idresolver.Resolver, subject string) (models.ReportTarget, error) {
+	target := models.ReportTarget{
+		Kind:    models.ReportKindOf(subject),
+		Subject: subject,
+	}
+
+	switch target.Kind {
+	case models.ReportKindUser:
+		id, err := idResolver.ResolveIdent(ctx, subject)
+		if err != nil {
+			return target, err
+		}
+		target.OwnerDid = id.DID.String()
+		target.Link = "/" + id.DID.String()
+
+	case models.ReportKindRepo:
+		repo, err := db.GetRepo(d, db.FilterEq("at_uri", subject))
+		if err != nil {
+			return target, err
+		}
+		target.OwnerDid = repo.Did
+		target.Title = repo.Name
+		target.Link = fmt.Sprintf("/%s/%s", repo.Did, repo.Name)
+
+	case models.ReportKindIssue:
+		issues, err := db.GetIssues(d, db.FilterEq("at_uri", subject))
+		if err != nil {
+			return target, err
+		}
+		if len(issues) == 0 || issues[0].Repo == nil {
+			return target, errUnknownSubject
+		}
+		issue := issues[0]
+		target.OwnerDid = issue.Did
+		target.Title = fmt.Sprintf("#%d %s", issue.IssueId, issue.Title)
+		target.Link = fmt.Sprintf("/%s/%s/issues/%d", issue.Repo.Did, issue.Repo.Name, issue.IssueId)
+
+	case models.ReportKindComment:
+		uri := syntax.ATURI(subject)
+		comments, err := db.GetIssueComments(
+			d,
+			db.FilterEq("did", uri.Authority().String()),
+			db.FilterEq("rkey", uri.RecordKey().String()),
+		)
+		if err != nil {
+			return target, err
+		}
+		if len(comments) == 0 {
+			return target, errUnknownSubject
+		}
+		comment := comments[0]
+
+		issues, err := db.GetIssues(d, db.FilterEq("at_uri", comment.IssueAt))
+		if err != nil {
+			return target, err
+		}
+		if len(issues) == 0 || issues[0].Repo == nil {
+			return target, errUnknownSubject
+		}
+		issue := issues[0]
+		target.OwnerDid = comment.Did
+		target.Title = fmt.Sprintf("comment on #%d %s", issue.IssueId, issue.Title)
+		target.Link = fmt.Sprintf("/%s/%s/issues/%d#%d", issue.Repo.Did, issue.Repo.Name, issue.IssueId, comment.Id)
+		target.Excerpt = comment.Body
+
+	default:
+		return target, errUnknownSubject
+	}
+
+	return target, nil
+}
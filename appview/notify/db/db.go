@@ -36,6 +36,22 @@ func (n *databaseNotifier) NewRepo(ctx context.Context, repo *models.Repo) {
 	// no-op for now
 }
 
+func (n *databaseNotifier) NewRepoTransfer(ctx context.Context, repo *models.Repo, transfer *models.RepoTransfer) {
+	var issueId *int64
+	var pullId *int64
+
+	n.notifyEvent(
+		transfer.FromDid,
+		[]syntax.DID{transfer.ToDid},
+		models.NotificationTypeRepoTransfer,
+		"repo",
+		repo.RepoAt().String(),
+		&repo.Id,
+		issueId,
+		pullId,
+	)
+}
+
 func (n *databaseNotifier) NewStar(ctx context.Context, star *models.Star) {
 	if star.RepoAt.Collection().String() != tangled.RepoNSID {
 		// skip string stars for now
@@ -78,6 +94,7 @@ func (n *databaseNotifier) NewIssue(ctx context.Context, issue *models.Issue, me
 	// build the recipients list
 	// - owner of the repo
 	// - collaborators in the repo
+	// - watchers of the repo
 	var recipients []syntax.DID
 	recipients = append(recipients, syntax.DID(issue.Repo.Did))
 	collaborators, err := db.GetCollaborators(n.db, db.FilterEq("repo_at", issue.Repo.RepoAt()))
@@ -88,6 +105,7 @@ func (n *databaseNotifier) NewIssue(ctx context.Context, issue *models.Issue, me
 	for _, c := range collaborators {
 		recipients = append(recipients, c.SubjectDid)
 	}
+	recipients = append(recipients, n.watchers(issue.Repo)...)
 
 	actorDid := syntax.DID(issue.Did)
 	entityType := "issue"
@@ -178,6 +196,10 @@ func (n *databaseNotifier) NewIssueComment(ctx context.Context, comment *models.
 	)
 }
 
+func (n *databaseNotifier) EditIssue(ctx context.Context, issue *models.Issue) {
+	// no-op for now
+}
+
 func (n *databaseNotifier) DeleteIssue(ctx context.Context, issue *models.Issue) {
 	// no-op for now
 }
@@ -216,6 +238,7 @@ func (n *databaseNotifier) NewPull(ctx context.Context, pull *models.Pull) {
 	// build the recipients list
 	// - owner of the repo
 	// - collaborators in the repo
+	// - watchers of the repo
 	var recipients []syntax.DID
 	recipients = append(recipients, syntax.DID(repo.Did))
 	collaborators, err := db.GetCollaborators(n.db, db.FilterEq("repo_at", repo.RepoAt()))
@@ -226,6 +249,7 @@ func (n *databaseNotifier) NewPull(ctx context.Context, pull *models.Pull) {
 	for _, c := range collaborators {
 		recipients = append(recipients, c.SubjectDid)
 	}
+	recipients = append(recipients, n.watchers(repo)...)
 
 	actorDid := syntax.DID(pull.OwnerDid)
 	eventType := models.NotificationTypePullCreated
@@ -308,6 +332,41 @@ func (n *databaseNotifier) UpdateProfile(ctx context.Context, profile *models.Pr
 	// no-op
 }
 
+func (n *databaseNotifier) NewKnotApproval(ctx context.Context, actor syntax.DID, registration *models.Registration) {
+	n.notifyApproval(actor, syntax.DID(registration.ByDid), registration.Approval, "knot", registration.Domain)
+}
+
+func (n *databaseNotifier) NewSpindleApproval(ctx context.Context, actor syntax.DID, spindle *models.Spindle) {
+	n.notifyApproval(actor, spindle.Owner, spindle.Approval, "spindle", spindle.Instance)
+}
+
+func (n *databaseNotifier) notifyApproval(actor, owner syntax.DID, approval models.Approval, entityType, domain string) {
+	var eventType models.NotificationType
+	switch approval {
+	case models.ApprovalApproved:
+		eventType = models.NotificationTypeRegistrationApproved
+	case models.ApprovalRejected:
+		eventType = models.NotificationTypeRegistrationRejected
+	default:
+		return
+	}
+
+	var repoId *int64
+	var issueId *int64
+	var pullId *int64
+
+	n.notifyEvent(
+		actor,
+		[]syntax.DID{owner},
+		eventType,
+		entityType,
+		domain,
+		repoId,
+		issueId,
+		pullId,
+	)
+}
+
 func (n *databaseNotifier) DeleteString(ctx context.Context, did, rkey string) {
 	// no-op
 }
@@ -420,6 +479,21 @@ func (n *databaseNotifier) NewPullState(ctx context.Context, actor syntax.DID, p
 	)
 }
 
+// watchers returns who watches repo. Private repos only notify their owner
+// and collaborators, who are the only ones that can see them.
+func (n *databaseNotifier) watchers(repo *models.Repo) []syntax.DID {
+	if repo == nil || repo.IsPrivate() {
+		return nil
+	}
+
+	watchers, err := db.GetWatchers(n.db, repo.RepoAt())
+	if err != nil {
+		log.Printf("failed to fetch watchers: %v", err)
+		return nil
+	}
+	return watchers
+}
+
 func (n *databaseNotifier) notifyEvent(
 	actorDid syntax.DID,
 	recipients []syntax.DID,
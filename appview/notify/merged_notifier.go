@@ -46,6 +46,10 @@ func (m *mergedNotifier) NewRepo(ctx context.Context, repo *models.Repo) {
 	m.fanout("NewRepo", ctx, repo)
 }
 
+func (m *mergedNotifier) NewRepoTransfer(ctx context.Context, repo *models.Repo, transfer *models.RepoTransfer) {
+	m.fanout("NewRepoTransfer", ctx, repo, transfer)
+}
+
 func (m *mergedNotifier) NewStar(ctx context.Context, star *models.Star) {
 	m.fanout("NewStar", ctx, star)
 }
@@ -66,6 +70,10 @@ func (m *mergedNotifier) NewIssueState(ctx context.Context, actor syntax.DID, is
 	m.fanout("NewIssueState", ctx, actor, issue)
 }
 
+func (m *mergedNotifier) EditIssue(ctx context.Context, issue *models.Issue) {
+	m.fanout("EditIssue", ctx, issue)
+}
+
 func (m *mergedNotifier) DeleteIssue(ctx context.Context, issue *models.Issue) {
 	m.fanout("DeleteIssue", ctx, issue)
 }
@@ -94,6 +102,14 @@ func (m *mergedNotifier) UpdateProfile(ctx context.Context, profile *models.Prof
 	m.fanout("UpdateProfile", ctx, profile)
 }
 
+func (m *mergedNotifier) NewKnotApproval(ctx context.Context, actor syntax.DID, registration *models.Registration) {
+	m.fanout("NewKnotApproval", ctx, actor, registration)
+}
+
+func (m *mergedNotifier) NewSpindleApproval(ctx context.Context, actor syntax.DID, spindle *models.Spindle) {
+	m.fanout("NewSpindleApproval", ctx, actor, spindle)
+}
+
 func (m *mergedNotifier) NewString(ctx context.Context, s *models.String) {
 	m.fanout("NewString", ctx, s)
 }
@@ -9,6 +9,7 @@ import (
 
 type Notifier interface {
 	NewRepo(ctx context.Context, repo *models.Repo)
+	NewRepoTransfer(ctx context.Context, repo *models.Repo, transfer *models.RepoTransfer)
 
 	NewStar(ctx context.Context, star *models.Star)
 	DeleteStar(ctx context.Context, star *models.Star)
@@ -16,6 +17,7 @@ type Notifier interface {
 	NewIssue(ctx context.Context, issue *models.Issue, mentions []syntax.DID)
 	NewIssueComment(ctx context.Context, comment *models.IssueComment, mentions []syntax.DID)
 	NewIssueState(ctx context.Context, actor syntax.DID, issue *models.Issue)
+	EditIssue(ctx context.Context, issue *models.Issue)
 	DeleteIssue(ctx context.Context, issue *models.Issue)
 
 	NewFollow(ctx context.Context, follow *models.Follow)
@@ -27,6 +29,9 @@ type Notifier interface {
 
 	UpdateProfile(ctx context.Context, profile *models.Profile)
 
+	NewKnotApproval(ctx context.Context, actor syntax.DID, registration *models.Registration)
+	NewSpindleApproval(ctx context.Context, actor syntax.DID, spindle *models.Spindle)
+
 	NewString(ctx context.Context, s *models.String)
 	EditString(ctx context.Context, s *models.String)
 	DeleteString(ctx context.Context, did, rkey string)
@@ -38,6 +43,8 @@ type BaseNotifier struct{}
 var _ Notifier = &BaseNotifier{}
 
 func (m *BaseNotifier) NewRepo(ctx context.Context, repo *models.Repo) {}
+func (m *BaseNotifier) NewRepoTransfer(ctx context.Context, repo *models.Repo, transfer *models.RepoTransfer) {
+}
 
 func (m *BaseNotifier) NewStar(ctx context.Context, star *models.Star)    {}
 func (m *BaseNotifier) DeleteStar(ctx context.Context, star *models.Star) {}
@@ -46,6 +53,7 @@ func (m *BaseNotifier) NewIssue(ctx context.Context, issue *models.Issue, mentio
 func (m *BaseNotifier) NewIssueComment(ctx context.Context, comment *models.IssueComment, mentions []syntax.DID) {
 }
 func (m *BaseNotifier) NewIssueState(ctx context.Context, actor syntax.DID, issue *models.Issue) {}
+func (m *BaseNotifier) EditIssue(ctx context.Context, issue *models.Issue)                       {}
 func (m *BaseNotifier) DeleteIssue(ctx context.Context, issue *models.Issue)                     {}
 
 func (m *BaseNotifier) NewFollow(ctx context.Context, follow *models.Follow)    {}
@@ -58,6 +66,11 @@ func (m *BaseNotifier) NewPullState(ctx context.Context, actor syntax.DID, pull
 
 func (m *BaseNotifier) UpdateProfile(ctx context.Context, profile *models.Profile) {}
 
+func (m *BaseNotifier) NewKnotApproval(ctx context.Context, actor syntax.DID, registration *models.Registration) {
+}
+func (m *BaseNotifier) NewSpindleApproval(ctx context.Context, actor syntax.DID, spindle *models.Spindle) {
+}
+
 func (m *BaseNotifier) NewString(ctx context.Context, s *models.String)    {}
 func (m *BaseNotifier) EditString(ctx context.Context, s *models.String)   {}
 func (m *BaseNotifier) DeleteString(ctx context.Context, did, rkey string) {}
@@ -0,0 +1,58 @@
+// Package punchcard counts pulls and pull comments towards their author's
+// punchcard. They are not ingested from the firehose, unlike issues and
+// issue comments, which the ingester counts.
+package punchcard
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/notify"
+)
+
+type punchcardNotifier struct {
+	db *db.DB
+	notify.BaseNotifier
+}
+
+func NewPunchcardNotifier(database *db.DB) notify.Notifier {
+	return &punchcardNotifier{
+		database,
+		notify.BaseNotifier{},
+	}
+}
+
+var _ notify.Notifier = &punchcardNotifier{}
+
+func (n *punchcardNotifier) NewPull(ctx context.Context, pull *models.Pull) {
+	n.punch(models.PunchEvent{
+		Did:       pull.OwnerDid,
+		SubjectAt: pull.AtUri(),
+		Kind:      models.PunchPull,
+		Created:   pull.Created,
+	})
+}
+
+func (n *punchcardNotifier) NewPullComment(ctx context.Context, comment *models.PullComment, mentions []syntax.DID) {
+	n.punch(models.PunchEvent{
+		Did:       comment.OwnerDid,
+		SubjectAt: comment.AtUri(),
+		Kind:      models.PunchPullComment,
+		Created:   comment.Created,
+	})
+}
+
+func (n *punchcardNotifier) punch(event models.PunchEvent) {
+	// not read back from the database after being created
+	if event.Created.IsZero() {
+		event.Created = time.Now()
+	}
+
+	if err := db.AddPunchEvent(n.db, event); err != nil {
+		log.Println("failed to add punch event:", err)
+	}
+}
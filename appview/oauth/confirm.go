@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/totp"
+)
+
+// TotpEnrolled reports whether did confirmed an authenticator app, which is
+// then asked for before destructive actions.
+func (o *OAuth) TotpEnrolled(did string) bool {
+	secret, err := db.GetTotpSecret(o.Db, did)
+	return err == nil && secret.Confirmed
+}
+
+// TotpSecret returns the authenticator app of did with its secret opened.
+// Secrets stored before they were sealed are sealed on the way.
+func (o *OAuth) TotpSecret(did string) (*models.TotpSecret, error) {
+	secret, err := db.GetTotpSecret(o.Db, did)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := secret.Secret
+	secret.Secret, err = o.totpSealer.Open(did, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	if !totp.IsSealed(stored) {
+		if err := o.storeTotpSecret(did, secret.Secret, db.UpdateTotpSecret); err != nil {
+			o.Logger.Error("failed to seal totp secret", "did", did, "err", err)
+		}
+	}
+
+	return secret, nil
+}
+
+// SetTotpSecret starts an enrollment of did in an authenticator app with
+// secret, replacing any earlier one.
+func (o *OAuth) SetTotpSecret(did, secret string) error {
+	return o.storeTotpSecret(did, secret, db.SetTotpSecret)
+}
+
+func (o *OAuth) storeTotpSecret(did, secret string, store func(db.Execer, string, string) error) error {
+	sealed, err := o.totpSealer.Seal(did, secret)
+	if err != nil {
+		return err
+	}
+	return store(o.Db, did, sealed)
+}
+
+// CheckTotp checks a code from the authenticator app of secret, and records
+// its step so that it can't be used again. After a few invalid codes, all
+// codes are refused for a while, so that they can't be guessed. The
+// returned error is meant for the user.
+func (o *OAuth) CheckTotp(secret *models.TotpSecret, code string) error {
+	did, now := secret.Did, time.Now()
+
+	if o.totpFailures.Exhausted(did, now) {
+		return fmt.Errorf("Too many invalid codes, try again in a few minutes.")
+	}
+
+	step, ok := totp.Validate(secret.Secret, code, now)
+	if !ok {
+		o.totpFailures.Allow(did, now)
+		return fmt.Errorf("Invalid authentication code.")
+	}
+
+	fresh, err := db.UseTotpStep(o.Db, did, step)
+	if err != nil {
+		o.Logger.Error("failed to record totp step", "did", did, "err", err)
+		return fmt.Errorf("Unable to check the code right now, try again later.")
+	}
+	if !fresh {
+		o.totpFailures.Allow(did, now)
+		return fmt.Errorf("That code was already used, wait for the next one.")
+	}
+
+	return nil
+}
+
+// ConfirmAction checks that the user making r means to go through with a
+// destructive action, so that a slip or a stolen session can't do much
+// damage. Users with an authenticator app send a fresh code in the "totp"
+// form value, everyone else types the name of what they act on, expected,
+// in the "confirm" form value. The returned error is meant for the user.
+func (o *OAuth) ConfirmAction(r *http.Request, expected string) error {
+	did := o.GetDid(r)
+
+	secret, err := o.TotpSecret(did)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		o.Logger.Error("failed to get totp secret", "did", did, "err", err)
+		return fmt.Errorf("Unable to confirm this action right now, try again later.")
+	}
+
+	if secret == nil || !secret.Confirmed {
+		if r.FormValue("confirm") != expected {
+			return fmt.Errorf("Type %s to confirm.", expected)
+		}
+		return nil
+	}
+
+	return o.CheckTotp(secret, r.FormValue("totp"))
+}
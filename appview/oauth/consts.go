@@ -10,6 +10,7 @@ const (
 	SessionRefreshJwt    = "refreshJwt"
 	SessionExpiry        = "expiry"
 	SessionAuthenticated = "authenticated"
+	SessionRemember      = "remember"
 
 	SessionDpopPrivateJwk      = "dpopPrivateJwk"
 	SessionDpopAuthServerNonce = "dpopAuthServerNonce"
@@ -67,6 +67,12 @@ func (o *OAuth) callback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if db.IsTakenDown(o.Db, sessData.AccountDID.String()) {
+		l.Info("refusing login of suspended account", "did", sessData.AccountDID)
+		http.Redirect(w, r, "/login?error=suspended", http.StatusFound)
+		return
+	}
+
 	if err := o.SaveSession(w, r, sessData); err != nil {
 		l.Error("failed to save session", "data", sessData, "err", err)
 		http.Redirect(w, r, "/login?error=session", http.StatusFound)
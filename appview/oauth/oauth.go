@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -17,12 +18,16 @@ import (
 	"github.com/posthog/posthog-go"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/ratelimit"
+	"tangled.org/core/appview/totp"
+	"tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/idresolver"
 	"tangled.org/core/rbac"
 )
 
 type OAuth struct {
 	ClientApp  *oauth.ClientApp
+	AuthStore  *RedisStore
 	SessStore  *sessions.CookieStore
 	Config     *config.Config
 	JwksUri    string
@@ -33,6 +38,11 @@ type OAuth struct {
 	Enforcer   *rbac.Enforcer
 	IdResolver *idresolver.Resolver
 	Logger     *slog.Logger
+
+	// seals the secrets of authenticator apps before they are stored
+	totpSealer *totp.Sealer
+	// failed authenticator app codes, by did
+	totpFailures *ratelimit.Limiter
 }
 
 func New(config *config.Config, ph posthog.Client, db *db.DB, enforcer *rbac.Enforcer, res *idresolver.Resolver, logger *slog.Logger) (*OAuth, error) {
@@ -62,15 +72,18 @@ func New(config *config.Config, ph posthog.Client, db *db.DB, enforcer *rbac.Enf
 
 	authStore, err := NewRedisStore(&RedisStoreConfig{
 		RedisURL:                  config.Redis.ToURL(),
-		SessionExpiryDuration:     time.Hour * 24 * 90,
-		SessionInactivityDuration: time.Hour * 24 * 14,
-		AuthRequestExpiryDuration: time.Minute * 30,
+		SessionExpiryDuration:     config.OAuth.SessionMax,
+		SessionInactivityDuration: config.OAuth.SessionIdle,
+		AuthRequestExpiryDuration: authRequestExpiry,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// cookies are refused once older than this, so it has to allow for the
+	// longest of logins; each cookie is given its own max age when saved
 	sessStore := sessions.NewCookieStore([]byte(config.Core.CookieSecret))
+	sessStore.MaxAge(int(max(config.OAuth.SessionMax, config.OAuth.RememberMax).Seconds()))
 
 	clientApp := oauth.NewClientApp(&oauthConfig, authStore)
 	clientApp.Dir = res.Directory()
@@ -79,11 +92,21 @@ func New(config *config.Config, ph posthog.Client, db *db.DB, enforcer *rbac.Enf
 		clientApp.Resolver.Client.Transport = http.DefaultTransport
 	}
 
+	totpKey, err := totp.LoadOrCreateKey(config.Core.TotpKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	totpSealer, err := totp.NewSealer(totpKey)
+	if err != nil {
+		return nil, err
+	}
+
 	clientName := config.Core.AppviewName
 
 	logger.Info("oauth setup successfully", "IsConfidential", clientApp.Config.IsConfidential())
 	return &OAuth{
 		ClientApp:  clientApp,
+		AuthStore:  authStore,
 		Config:     config,
 		SessStore:  sessStore,
 		JwksUri:    jwksUri,
@@ -94,13 +117,46 @@ func New(config *config.Config, ph posthog.Client, db *db.DB, enforcer *rbac.Enf
 		Enforcer:   enforcer,
 		IdResolver: res,
 		Logger:     logger,
+		totpSealer: totpSealer,
+		// a few codes may be mistyped, then one more every few minutes
+		totpFailures: ratelimit.New(5*time.Minute, 5),
 	}, nil
 }
 
+// how long a login may take, from being started to the callback
+const authRequestExpiry = 30 * time.Minute
+
+// StartLogin keeps what was asked for at login until the callback, where
+// the session is saved.
+func (o *OAuth) StartLogin(w http.ResponseWriter, r *http.Request, remember bool) error {
+	userSession, err := o.SessStore.Get(r, SessionName)
+	if err != nil && userSession == nil {
+		return err
+	}
+
+	userSession.Values[SessionRemember] = remember
+	userSession.Options.MaxAge = int(authRequestExpiry.Seconds())
+	return userSession.Save(r, w)
+}
+
 func (o *OAuth) SaveSession(w http.ResponseWriter, r *http.Request, sessData *oauth.ClientSessionData) error {
 	// first we save the did in the user session
 	userSession, err := o.SessStore.Get(r, SessionName)
-	if err != nil {
+	if err != nil && userSession == nil {
+		return err
+	}
+
+	// remembered logins last longer, and their cookie outlives the browser
+	// session; others go when the browser is closed
+	lifetime := Lifetime{Idle: o.Config.OAuth.SessionIdle, Max: o.Config.OAuth.SessionMax}
+	userSession.Options.MaxAge = 0
+	if remember, _ := userSession.Values[SessionRemember].(bool); remember {
+		lifetime = Lifetime{Idle: o.Config.OAuth.RememberIdle, Max: o.Config.OAuth.RememberMax}
+		userSession.Options.MaxAge = int(lifetime.Max.Seconds())
+	}
+	delete(userSession.Values, SessionRemember)
+
+	if err := o.AuthStore.SetLifetime(r.Context(), sessData.AccountDID, sessData.SessionID, lifetime); err != nil {
 		return err
 	}
 
@@ -111,6 +167,25 @@ func (o *OAuth) SaveSession(w http.ResponseWriter, r *http.Request, sessData *oa
 	return userSession.Save(r, w)
 }
 
+// sessionIds reads which session the cookie of a request is for.
+func sessionIds(userSession *sessions.Session) (syntax.DID, string, error) {
+	d, ok := userSession.Values[SessionDid].(string)
+	if !ok {
+		return "", "", fmt.Errorf("no session available for user")
+	}
+	sessDid, err := syntax.ParseDID(d)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed DID in session cookie '%s': %w", d, err)
+	}
+
+	sessId, ok := userSession.Values[SessionId].(string)
+	if !ok {
+		return "", "", fmt.Errorf("no session available for user")
+	}
+
+	return sessDid, sessId, nil
+}
+
 func (o *OAuth) ResumeSession(r *http.Request) (*oauth.ClientSession, error) {
 	userSession, err := o.SessStore.Get(r, SessionName)
 	if err != nil {
@@ -120,13 +195,15 @@ func (o *OAuth) ResumeSession(r *http.Request) (*oauth.ClientSession, error) {
 		return nil, fmt.Errorf("no session available for user")
 	}
 
-	d := userSession.Values[SessionDid].(string)
-	sessDid, err := syntax.ParseDID(d)
+	sessDid, sessId, err := sessionIds(userSession)
 	if err != nil {
-		return nil, fmt.Errorf("malformed DID in session cookie '%s': %w", d, err)
+		return nil, err
 	}
 
-	sessId := userSession.Values[SessionId].(string)
+	// suspended accounts are logged out wherever they were logged in
+	if db.IsTakenDown(o.Db, sessDid.String()) {
+		return nil, fmt.Errorf("account %s is suspended", sessDid)
+	}
 
 	clientSess, err := o.ClientApp.ResumeSession(r.Context(), sessDid, sessId)
 	if err != nil {
@@ -136,6 +213,26 @@ func (o *OAuth) ResumeSession(r *http.Request) (*oauth.ClientSession, error) {
 	return clientSess, nil
 }
 
+// ResumeSessionFor resumes any session that did has open, for acting on
+// their behalf outside of a request they made, like when they send mail.
+func (o *OAuth) ResumeSessionFor(ctx context.Context, did syntax.DID) (*oauth.ClientSession, error) {
+	ids, err := o.AuthStore.SessionIds(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		sess, err := o.ClientApp.ResumeSession(ctx, did, id)
+		if err == nil {
+			return sess, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, fmt.Errorf("no session available for %s: %w", did, errors.Join(errs...))
+}
+
 func (o *OAuth) DeleteSession(w http.ResponseWriter, r *http.Request) error {
 	userSession, err := o.SessStore.Get(r, SessionName)
 	if err != nil {
@@ -145,14 +242,11 @@ func (o *OAuth) DeleteSession(w http.ResponseWriter, r *http.Request) error {
 		return fmt.Errorf("no session available for user")
 	}
 
-	d := userSession.Values[SessionDid].(string)
-	sessDid, err := syntax.ParseDID(d)
+	sessDid, sessId, err := sessionIds(userSession)
 	if err != nil {
-		return fmt.Errorf("malformed DID in session cookie '%s': %w", d, err)
+		return err
 	}
 
-	sessId := userSession.Values[SessionId].(string)
-
 	// delete the session
 	err1 := o.ClientApp.Logout(r.Context(), sessDid, sessId)
 
@@ -203,6 +297,7 @@ type ServiceClientOpts struct {
 	lxm     string
 	dev     bool
 	timeout time.Duration
+	retry   bool
 }
 
 type ServiceClientOpt func(*ServiceClientOpts)
@@ -210,6 +305,7 @@ type ServiceClientOpt func(*ServiceClientOpts)
 func DefaultServiceClientOpts() ServiceClientOpts {
 	return ServiceClientOpts{
 		timeout: time.Second * 5,
+		retry:   true,
 	}
 }
 
@@ -246,6 +342,14 @@ func WithTimeout(timeout time.Duration) ServiceClientOpt {
 	}
 }
 
+// WithoutRetry is for calls that must not be repeated, like merges, where a
+// retry after a lost response could apply them twice.
+func WithoutRetry() ServiceClientOpt {
+	return func(s *ServiceClientOpts) {
+		s.retry = false
+	}
+}
+
 func (s *ServiceClientOpts) Audience() string {
 	return fmt.Sprintf("did:web:%s", s.service)
 }
@@ -259,35 +363,73 @@ func (s *ServiceClientOpts) Host() string {
 	return scheme + s.service
 }
 
-func (o *OAuth) ServiceClient(r *http.Request, os ...ServiceClientOpt) (*xrpc.Client, error) {
+// ServiceToken asks the PDS of the user for a service auth token for the
+// configured service.
+func (o *OAuth) ServiceToken(r *http.Request, os ...ServiceClientOpt) (string, error) {
+	client, err := o.AuthorizedClient(r)
+	if err != nil {
+		return "", err
+	}
+
+	return serviceToken(r.Context(), client, os...)
+}
+
+func serviceToken(ctx context.Context, client *atpclient.APIClient, os ...ServiceClientOpt) (string, error) {
 	opts := DefaultServiceClientOpts()
 	for _, o := range os {
 		o(&opts)
 	}
 
-	client, err := o.AuthorizedClient(r)
-	if err != nil {
-		return nil, err
-	}
-
 	// force expiry to atleast 60 seconds in the future
 	sixty := time.Now().Unix() + 60
 	if opts.exp < sixty {
 		opts.exp = sixty
 	}
 
-	resp, err := comatproto.ServerGetServiceAuth(r.Context(), client, opts.Audience(), opts.exp, opts.lxm)
+	resp, err := comatproto.ServerGetServiceAuth(ctx, client, opts.Audience(), opts.exp, opts.lxm)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Token, nil
+}
+
+func (o *OAuth) ServiceClient(r *http.Request, os ...ServiceClientOpt) (*xrpc.Client, error) {
+	token, err := o.ServiceToken(r, os...)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.serviceClient(token, os...), nil
+}
+
+// ServiceClientFor is ServiceClient for calls made on behalf of did outside
+// of a request they made, with a session they left open.
+func (o *OAuth) ServiceClientFor(ctx context.Context, did syntax.DID, os ...ServiceClientOpt) (*xrpc.Client, error) {
+	sess, err := o.ResumeSessionFor(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := serviceToken(ctx, sess.APIClient(), os...)
 	if err != nil {
 		return nil, err
 	}
 
+	return o.serviceClient(token, os...), nil
+}
+
+func (o *OAuth) serviceClient(token string, os ...ServiceClientOpt) *xrpc.Client {
+	opts := DefaultServiceClientOpts()
+	for _, o := range os {
+		o(&opts)
+	}
+
 	return &xrpc.Client{
 		Auth: &xrpc.AuthInfo{
-			AccessJwt: resp.Token,
-		},
-		Host: opts.Host(),
-		Client: &http.Client{
-			Timeout: opts.timeout,
+			AccessJwt: token,
 		},
-	}, nil
+		Host:   opts.Host(),
+		Client: xrpcclient.HTTPClient(o.Config.KnotClient, opts.timeout, opts.retry),
+	}
 }
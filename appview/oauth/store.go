@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
@@ -16,11 +17,23 @@ type RedisStoreConfig struct {
 
 	// The purpose of these limits is to avoid dead sessions hanging around in the db indefinitely.
 	// The durations here should be *at least as long as* the expected duration of the oauth session itself.
+	// They are the lifetime of sessions that weren't given one with SetLifetime.
 	SessionExpiryDuration     time.Duration // duration since session creation (max TTL)
-	SessionInactivityDuration time.Duration // duration since last session update
+	SessionInactivityDuration time.Duration // duration since last use of the session
 	AuthRequestExpiryDuration time.Duration // duration since auth request creation
 }
 
+// Lifetime bounds how long a session lasts: it ends once it goes unused for
+// Idle, or Max after it was created, whichever comes first.
+type Lifetime struct {
+	Idle time.Duration
+	Max  time.Duration
+}
+
+// how long a session may go without being marked as used, so that busy
+// sessions aren't written back on every request
+const sessionTouchInterval = time.Minute
+
 // redis-backed implementation of ClientAuthStore.
 type RedisStore struct {
 	client *redis.Client
@@ -32,6 +45,35 @@ var _ oauth.ClientAuthStore = &RedisStore{}
 type sessionMetadata struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// the lifetime the session was given, if not the default one
+	Idle time.Duration `json:"idle,omitempty"`
+	Max  time.Duration `json:"max,omitempty"`
+}
+
+func (r *RedisStore) lifetime(meta sessionMetadata) Lifetime {
+	lt := Lifetime{
+		Idle: r.cfg.SessionInactivityDuration,
+		Max:  r.cfg.SessionExpiryDuration,
+	}
+	if meta.Idle > 0 {
+		lt.Idle = meta.Idle
+	}
+	if meta.Max > 0 {
+		lt.Max = meta.Max
+	}
+	return lt
+}
+
+// ttl is how much longer the session lasts if it isn't used again, or 0
+// if it has expired.
+func (r *RedisStore) ttl(meta sessionMetadata) time.Duration {
+	lt := r.lifetime(meta)
+	remaining := min(
+		lt.Idle-time.Since(meta.UpdatedAt),
+		lt.Max-time.Since(meta.CreatedAt),
+	)
+	return max(remaining, 0)
 }
 
 func NewRedisStore(cfg *RedisStoreConfig) (*RedisStore, error) {
@@ -106,12 +148,10 @@ func (r *RedisStore) GetSession(ctx context.Context, did syntax.DID, sessionID s
 		return nil, fmt.Errorf("failed to unmarshal session metadata: %w", err)
 	}
 
-	// Check if session has been inactive for too long
-	inactiveThreshold := time.Now().Add(-r.cfg.SessionInactivityDuration)
-	if meta.UpdatedAt.Before(inactiveThreshold) {
-		// Session is inactive, delete it
+	// Check if session has been inactive for too long, or is too old
+	if r.ttl(meta) == 0 {
 		r.client.Del(ctx, key, metaKey)
-		return nil, fmt.Errorf("session expired due to inactivity: %s", did)
+		return nil, fmt.Errorf("session expired: %s", did)
 	}
 
 	// Get the actual session data
@@ -128,9 +168,64 @@ func (r *RedisStore) GetSession(ctx context.Context, did syntax.DID, sessionID s
 		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
 	}
 
+	// using a session keeps it from going idle
+	if time.Since(meta.UpdatedAt) > sessionTouchInterval {
+		meta.UpdatedAt = time.Now()
+		if err := r.saveMetadata(ctx, did, sessionID, meta); err != nil {
+			return nil, err
+		}
+	}
+
 	return &sess, nil
 }
 
+// SetLifetime changes how long a session lasts, counting from when it was
+// created.
+func (r *RedisStore) SetLifetime(ctx context.Context, did syntax.DID, sessionID string, lt Lifetime) error {
+	metaData, err := r.client.Get(ctx, sessionMetadataKey(did, sessionID)).Bytes()
+	if err == redis.Nil {
+		return fmt.Errorf("session not found: %s", did)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session metadata: %w", err)
+	}
+
+	var meta sessionMetadata
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return fmt.Errorf("failed to unmarshal session metadata: %w", err)
+	}
+
+	meta.Idle = lt.Idle
+	meta.Max = lt.Max
+	return r.saveMetadata(ctx, did, sessionID, meta)
+}
+
+// saveMetadata writes the metadata of a session, and has the session expire
+// along with it.
+func (r *RedisStore) saveMetadata(ctx context.Context, did syntax.DID, sessionID string, meta sessionMetadata) error {
+	key := sessionKey(did, sessionID)
+	metaKey := sessionMetadataKey(did, sessionID)
+
+	ttl := r.ttl(meta)
+	if ttl == 0 {
+		r.client.Del(ctx, key, metaKey)
+		return fmt.Errorf("session has expired")
+	}
+
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session metadata: %w", err)
+	}
+	if err := r.client.Set(ctx, metaKey, metaData, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save session metadata: %w", err)
+	}
+	if err := r.client.Expire(ctx, key, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to extend session: %w", err)
+	}
+
+	return nil
+}
+
 func (r *RedisStore) SaveSession(ctx context.Context, sess oauth.ClientSessionData) error {
 	key := sessionKey(sess.AccountDID, sess.SessionID)
 	metaKey := sessionMetadataKey(sess.AccountDID, sess.SessionID)
@@ -152,37 +247,26 @@ func (r *RedisStore) SaveSession(ctx context.Context, sess oauth.ClientSessionDa
 	} else if err != nil {
 		return fmt.Errorf("failed to check existing session metadata: %w", err)
 	} else {
-		// Existing session - preserve CreatedAt, update UpdatedAt
+		// Existing session - preserve CreatedAt and lifetime, update UpdatedAt;
+		// tokens are refreshed with the session, so refreshes can't outlive it
 		if err := json.Unmarshal(existingMetaData, &meta); err != nil {
 			return fmt.Errorf("failed to unmarshal existing session metadata: %w", err)
 		}
 		meta.UpdatedAt = time.Now()
 	}
 
-	// Calculate remaining TTL based on creation time
-	remainingTTL := r.cfg.SessionExpiryDuration - time.Since(meta.CreatedAt)
-	if remainingTTL <= 0 {
+	// The session lasts until it goes idle or reaches its max age
+	ttl := r.ttl(meta)
+	if ttl == 0 {
 		return fmt.Errorf("session has expired")
 	}
 
-	// Use the shorter of: remaining TTL or inactivity duration
-	ttl := min(r.cfg.SessionInactivityDuration, remainingTTL)
-
 	// Save session data
 	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
 	}
 
-	// Save metadata
-	metaData, err := json.Marshal(meta)
-	if err != nil {
-		return fmt.Errorf("failed to marshal session metadata: %w", err)
-	}
-	if err := r.client.Set(ctx, metaKey, metaData, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to save session metadata: %w", err)
-	}
-
-	return nil
+	return r.saveMetadata(ctx, sess.AccountDID, sess.SessionID, meta)
 }
 
 func (r *RedisStore) DeleteSession(ctx context.Context, did syntax.DID, sessionID string) error {
@@ -195,6 +279,22 @@ func (r *RedisStore) DeleteSession(ctx context.Context, did syntax.DID, sessionI
 	return nil
 }
 
+// SessionIds returns the ids of the sessions that did has open.
+func (r *RedisStore) SessionIds(ctx context.Context, did syntax.DID) ([]string, error) {
+	prefix := sessionMetadataKey(did, "")
+
+	var ids []string
+	iter := r.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		ids = append(ids, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (r *RedisStore) GetAuthRequestInfo(ctx context.Context, state string) (*oauth.AuthRequestData, error) {
 	key := authRequestKey(state)
 	data, err := r.client.Get(ctx, key).Bytes()
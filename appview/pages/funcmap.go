@@ -18,15 +18,14 @@ import (
 	"strings"
 	"time"
 
-	"github.com/alecthomas/chroma/v2"
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/alecthomas/chroma/v2/lexers"
-	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/dustin/go-humanize"
 	"github.com/go-enry/go-enry/v2"
 	"github.com/yuin/goldmark"
 	"tangled.org/core/appview/filetree"
+	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages/markup"
 	"tangled.org/core/crypto"
 )
@@ -255,12 +254,22 @@ func (p *Pages) funcMap() template.FuncMap {
 			sanitized := p.rctx.SanitizeDefault(htmlString)
 			return template.HTML(sanitized)
 		},
+		// like markdown, but links the given issue and pull references
+		"markdownWithRefs": func(text string, refs map[string]models.Reference) template.HTML {
+			rctx := *p.rctx
+			rctx.RendererType = markup.RendererTypeDefault
+			rctx.References = models.ReferenceHrefs(refs)
+			htmlString := rctx.RenderMarkdown(text)
+			sanitized := rctx.SanitizeDefault(htmlString)
+			return template.HTML(sanitized)
+		},
 		"description": func(text string) template.HTML {
 			p.rctx.RendererType = markup.RendererTypeDefault
 			htmlString := p.rctx.RenderMarkdownWith(text, goldmark.New())
 			sanitized := p.rctx.SanitizeDescription(htmlString)
 			return template.HTML(sanitized)
 		},
+		"contentHash": markup.ContentHash,
 		"readme": func(text string) template.HTML {
 			p.rctx.RendererType = markup.RendererTypeRepoMarkdown
 			htmlString := p.rctx.RenderMarkdown(text)
@@ -268,7 +277,6 @@ func (p *Pages) funcMap() template.FuncMap {
 			return template.HTML(sanitized)
 		},
 		"code": func(content, path string) string {
-			var style *chroma.Style = styles.Get("catpuccin-latte")
 			formatter := chromahtml.New(
 				chromahtml.InlineCode(false),
 				chromahtml.WithLineNumbers(true),
@@ -289,7 +297,7 @@ func (p *Pages) funcMap() template.FuncMap {
 			}
 
 			var code bytes.Buffer
-			err = formatter.Format(&code, style, iterator)
+			err = formatter.Format(&code, markup.CodeStyle, iterator)
 			if err != nil {
 				p.logger.Error("chroma format", "err", "err")
 				return ""
@@ -297,6 +305,46 @@ func (p *Pages) funcMap() template.FuncMap {
 
 			return code.String()
 		},
+		// highlight is like code, without line numbers, for snippets that
+		// share a page
+		"highlight": func(content, language string) template.HTML {
+			formatter := chromahtml.New(
+				chromahtml.Standalone(false),
+				chromahtml.WithClasses(true),
+			)
+
+			lexer := lexers.Get(language)
+			if lexer == nil {
+				lexer = lexers.Fallback
+			}
+
+			iterator, err := lexer.Tokenise(nil, content)
+			if err != nil {
+				p.logger.Error("chroma tokenize", "err", err)
+				return ""
+			}
+
+			var code bytes.Buffer
+			err = formatter.Format(&code, markup.CodeStyle, iterator)
+			if err != nil {
+				p.logger.Error("chroma format", "err", err)
+				return ""
+			}
+
+			return template.HTML(code.String())
+		},
+		// notebook parses a Jupyter notebook, nil if it can't be
+		"notebook": func(source string) *markup.Notebook {
+			nb, err := markup.ParseNotebook(source)
+			if err != nil {
+				p.logger.Warn("failed to parse notebook", "err", err)
+				return nil
+			}
+			return nb
+		},
+		"sanitize": func(html string) template.HTML {
+			return template.HTML(p.rctx.SanitizeDefault(html))
+		},
 		"trimUriScheme": func(text string) string {
 			text = strings.TrimPrefix(text, "https://")
 			text = strings.TrimPrefix(text, "http://")
@@ -340,6 +388,7 @@ func (p *Pages) funcMap() template.FuncMap {
 		},
 		"cssContentHash": p.CssContentHash,
 		"fileTree":       filetree.FileTree,
+		"diffFileTree":   filetree.DiffFileTree,
 		"pathEscape": func(s string) string {
 			return url.PathEscape(s)
 		},
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/yuin/goldmark/ast"
 )
@@ -23,6 +24,11 @@ func (rctx *RenderContext) camoImageLinkTransformer(dst string) string {
 		return dst
 	}
 
+	// attachments are served by the appview itself
+	if rctx.AppviewHost != "" && strings.HasPrefix(dst, rctx.AppviewHost+"/attachments/") {
+		return dst
+	}
+
 	if rctx.CamoUrl != "" && rctx.CamoSecret != "" {
 		return GenerateCamoURL(rctx.CamoUrl, rctx.CamoSecret, dst)
 	}
@@ -0,0 +1,34 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderedEmoji(t *testing.T) {
+	rctx := &RenderContext{
+		Sanitizer:    NewSanitizer(),
+		RendererType: RendererTypeDefault,
+	}
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{":rocket:", "<p>🚀</p>"},
+		{"shipped (:rocket:)!", "<p>shipped (🚀)!</p>"},
+		{"done:tada:", "<p>done:tada:</p>"},
+		{":+1::tada:, thanks", "<p>👍🎉, thanks</p>"},
+		{"meet at 10:30:00", "<p>meet at 10:30:00</p>"},
+		{":not_an_emoji: stays", "<p>:not_an_emoji: stays</p>"},
+		{"`:rocket:` in code", "<p><code>:rocket:</code> in code</p>"},
+		{"```\n:rocket:\n```", "<pre><code>:rocket:\n</code></pre>"},
+	}
+
+	for _, tt := range tests {
+		got := strings.TrimSpace(rctx.SanitizeDefault(rctx.RenderMarkdown(tt.source)))
+		if got != tt.want {
+			t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
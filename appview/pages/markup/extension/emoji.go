@@ -0,0 +1,118 @@
+package extension
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// An EmojiNode represents a known :shortcode: and the emoji it stands for.
+type EmojiNode struct {
+	ast.BaseInline
+	Shortcode string
+	Emoji     string
+}
+
+var _ ast.Node = &EmojiNode{}
+
+// Dump implements Node.Dump.
+func (n *EmojiNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Shortcode": n.Shortcode}, nil)
+}
+
+// KindEmoji is a NodeKind of the Emoji node.
+var KindEmoji = ast.NewNodeKind("Emoji")
+
+// Kind implements Node.Kind.
+func (n *EmojiNode) Kind() ast.NodeKind {
+	return KindEmoji
+}
+
+var emojiRegexp = regexp.MustCompile(`^:([a-z0-9_+-]+):`)
+
+type emojiParser struct{}
+
+// NewEmojiParser return a new InlineParser that parses :shortcode:
+// expressions. Unknown shortcodes are left as they are.
+func NewEmojiParser() parser.InlineParser {
+	return &emojiParser{}
+}
+
+func (s *emojiParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (s *emojiParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	// not in the middle of a word, like the times in 10:30:00
+	if prev := block.PrecendingCharacter(); prev < utf8.RuneSelf && util.IsAlphaNumeric(byte(prev)) {
+		return nil
+	}
+
+	line, _ := block.PeekLine()
+	m := emojiRegexp.FindSubmatch(line)
+	if m == nil {
+		return nil
+	}
+
+	emoji, ok := emojis[string(m[1])]
+	if !ok {
+		return nil
+	}
+
+	block.Advance(len(m[0]))
+	return &EmojiNode{
+		Shortcode: string(m[1]),
+		Emoji:     emoji,
+	}
+}
+
+// emojiHtmlRenderer is a renderer.NodeRenderer implementation that
+// renders Emoji nodes.
+type emojiHtmlRenderer struct {
+	html.Config
+}
+
+// NewEmojiHTMLRenderer returns a new EmojiHTMLRenderer.
+func NewEmojiHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &emojiHtmlRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *emojiHtmlRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindEmoji, r.renderEmoji)
+}
+
+func (r *emojiHtmlRenderer) renderEmoji(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.Write(util.EscapeHTML([]byte(n.(*EmojiNode).Emoji)))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+type emojiExt struct{}
+
+// EmojiExt is an extension that turns GitHub's :shortcode: emoji, like
+// ':rocket:', into the emoji themselves.
+var EmojiExt = &emojiExt{}
+
+func (e *emojiExt) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewEmojiParser(), 500),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewEmojiHTMLRenderer(), 500),
+	))
+}
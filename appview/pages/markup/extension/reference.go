@@ -0,0 +1,160 @@
+package extension
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// A RefNode represents a reference to an issue or a pull, either by number
+// within the current repo ('#12') or by AT-URI.
+type RefNode struct {
+	ast.BaseInline
+
+	// Ref is the reference as written in the source
+	Ref string
+
+	// Href is set once the reference is resolved, unresolved references
+	// are rendered as plain text
+	Href string
+}
+
+var _ ast.Node = &RefNode{}
+
+// Dump implements Node.Dump.
+func (n *RefNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Ref": n.Ref, "Href": n.Href}, nil)
+}
+
+// KindRef is a NodeKind of the Ref node.
+var KindRef = ast.NewNodeKind("Ref")
+
+// Kind implements Node.Kind.
+func (n *RefNode) Kind() ast.NodeKind {
+	return KindRef
+}
+
+var (
+	numberRefRegexp = regexp.MustCompile(`^#([0-9]+)\b`)
+	atUriRefRegexp  = regexp.MustCompile(`^at://did:[a-z]+:[a-zA-Z0-9._:%-]+/sh\.tangled\.repo\.(issue|pull)/[a-zA-Z0-9._~:-]+`)
+)
+
+type refParser struct{}
+
+// NewRefParser returns a new InlineParser that parses issue and pull
+// references.
+func NewRefParser() parser.InlineParser {
+	return &refParser{}
+}
+
+func (s *refParser) Trigger() []byte {
+	// ' ' indicates any white spaces and a line head, at-uris have no
+	// punctuation to trigger on
+	return []byte{'#', ' ', '('}
+}
+
+func (s *refParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if pc.IsInLinkLabel() {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	var m []int
+	switch line[0] {
+	case '#':
+		// references must not be glued to a preceding word, 'foo#12' is not one
+		if prev := block.PrecendingCharacter(); prev != '\n' && prev != '(' && !unicode.IsSpace(prev) {
+			return nil
+		}
+		m = numberRefRegexp.FindIndex(line)
+	default:
+		// advance if current position is not a line head
+		consumes := 0
+		if line[0] == ' ' || line[0] == '(' {
+			consumes = 1
+		}
+		m = atUriRefRegexp.FindIndex(line[consumes:])
+		if m == nil {
+			return nil
+		}
+		// trailing punctuation ends the sentence, not the uri
+		for m[1] > 0 && strings.ContainsRune(".,:~", rune(line[consumes+m[1]-1])) {
+			m[1]--
+		}
+		if consumes != 0 {
+			ast.MergeOrAppendTextSegment(parent, segment.WithStop(segment.Start+consumes))
+			block.Advance(consumes)
+			segment = segment.WithStart(segment.Start + consumes)
+		}
+	}
+	if m == nil {
+		return nil
+	}
+
+	refSegment := text.NewSegment(segment.Start, segment.Start+m[1])
+	block.Advance(m[1])
+	node := &RefNode{Ref: string(refSegment.Value(block.Source()))}
+	node.AppendChild(node, ast.NewTextSegment(refSegment))
+	return node
+}
+
+// refHtmlRenderer is a renderer.NodeRenderer implementation that renders
+// Ref nodes.
+type refHtmlRenderer struct {
+	html.Config
+}
+
+// NewRefHTMLRenderer returns a new RefHTMLRenderer.
+func NewRefHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &refHtmlRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *refHtmlRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindRef, r.renderRef)
+}
+
+func (r *refHtmlRenderer) renderRef(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	href := n.(*RefNode).Href
+	if href == "" {
+		return ast.WalkContinue, nil
+	}
+
+	if entering {
+		w.WriteString(`<a href="`)
+		w.Write(util.EscapeHTML(util.URLEscape([]byte(href), false)))
+		w.WriteString(`" class="reference">`)
+	} else {
+		w.WriteString("</a>")
+	}
+	return ast.WalkContinue, nil
+}
+
+type refExt struct{}
+
+// RefExt is an extension that parses references to issues and pulls, like
+// '#12' or 'at://did:plc:foo/sh.tangled.repo.issue/3lbar'.
+var RefExt = &refExt{}
+
+func (e *refExt) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewRefParser(), 500),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewRefHTMLRenderer(), 500),
+	))
+}
@@ -0,0 +1,73 @@
+package extension
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// TaskIndexAttr holds the position of a task list checkbox within its
+// document, in source order, starting at 0.
+const TaskIndexAttr = "data-task-index"
+
+// taskIndexTransformer numbers every task list checkbox in the document.
+type taskIndexTransformer struct{}
+
+func (t *taskIndexTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	index := 0
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == east.KindTaskCheckBox {
+			n.SetAttributeString(TaskIndexAttr, fmt.Appendf(nil, "%d", index))
+			index++
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// taskCheckBoxRenderer renders checkboxes like the GFM renderer does, but
+// carries over the task index so that a checkbox can be mapped back to its
+// source line.
+type taskCheckBoxRenderer struct{}
+
+func (r *taskCheckBoxRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(east.KindTaskCheckBox, r.render)
+}
+
+func (r *taskCheckBoxRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*east.TaskCheckBox)
+
+	_, _ = w.WriteString(`<input type="checkbox" disabled=""`)
+	if n.IsChecked {
+		_, _ = w.WriteString(` checked=""`)
+	}
+	html.RenderAttributes(w, n, nil)
+	_, _ = w.WriteString("> ")
+
+	return ast.WalkContinue, nil
+}
+
+type taskListExt struct{}
+
+// TaskListExt numbers GFM task list checkboxes, it must be used alongside
+// the GFM task list extension.
+var TaskListExt = &taskListExt{}
+
+func (e *taskListExt) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&taskIndexTransformer{}, 100),
+	))
+	// takes precedence over the GFM checkbox renderer, registered at 500
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&taskCheckBoxRenderer{}, 100),
+	))
+}
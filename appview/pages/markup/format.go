@@ -8,15 +8,21 @@ type Format string
 
 const (
 	FormatMarkdown Format = "markdown"
+	FormatNotebook Format = "notebook"
+	FormatTable    Format = "table"
 	FormatText     Format = "text"
 )
 
 var FileTypes map[Format][]string = map[Format][]string{
 	FormatMarkdown: {".md", ".markdown", ".mdown", ".mkdn", ".mkd"},
+	FormatNotebook: {".ipynb"},
+	FormatTable:    {".csv", ".tsv"},
 }
 
 var FileTypePatterns = map[Format]*regexp.Regexp{
 	FormatMarkdown: regexp.MustCompile(`(?i)\.(md|markdown|mdown|mkdn|mkd)$`),
+	FormatNotebook: regexp.MustCompile(`(?i)\.ipynb$`),
+	FormatTable:    regexp.MustCompile(`(?i)\.(csv|tsv)$`),
 }
 
 var ReadmePattern = regexp.MustCompile(`(?i)^readme(\.(md|markdown|txt))?$`)
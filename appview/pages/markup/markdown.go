@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"net/url"
 	"path"
+	"regexp"
 	"strings"
 
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
@@ -44,14 +45,32 @@ const (
 type RenderContext struct {
 	CamoUrl    string
 	CamoSecret string
+	// AppviewHost serves attachments, which are linked to directly rather
+	// than through camo.
+	AppviewHost string
 	repoinfo.RepoInfo
 	IsDev        bool
 	RendererType RendererType
 	Sanitizer    Sanitizer
 	Files        fs.FS
+
+	// References maps issue and pull references, as written in the
+	// source, to the page they link to. Unknown references are left as-is.
+	References map[string]string
 }
 
+// CodeStyle is the chroma style of highlighted code, both in markdown and in
+// blobs. Code is highlighted with classes, so the stylesheet has the final
+// say on colors.
+var CodeStyle = styles.Get("catppuccin-latte")
+
 func NewMarkdown() goldmark.Markdown {
+	return newMarkdown("footnote")
+}
+
+// newMarkdown is NewMarkdown with footnote ids starting with
+// footnotePrefix.
+func newMarkdown(footnotePrefix string) goldmark.Markdown {
 	md := goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
@@ -60,14 +79,18 @@ func NewMarkdown() goldmark.Markdown {
 					chromahtml.Standalone(false),
 					chromahtml.WithClasses(true),
 				),
-				highlighting.WithCustomStyle(styles.Get("catppuccin-latte")),
+				highlighting.WithCustomStyle(CodeStyle),
 			),
 			extension.NewFootnote(
-				extension.WithFootnoteIDPrefix([]byte("footnote")),
+				extension.WithFootnoteIDPrefix(footnotePrefix),
 			),
+			extension.DefinitionList,
 			treeblood.MathML(),
 			callout.CalloutExtention,
 			textension.AtExt,
+			textension.RefExt,
+			textension.TaskListExt,
+			textension.EmojiExt,
 		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
@@ -77,8 +100,15 @@ func NewMarkdown() goldmark.Markdown {
 	return md
 }
 
+// RenderMarkdown renders source with footnote ids namespaced by its
+// content, so that several renders on one page (like comments) don't link
+// to each other's footnotes.
 func (rctx *RenderContext) RenderMarkdown(source string) string {
-	return rctx.RenderMarkdownWith(source, NewMarkdown())
+	return rctx.RenderMarkdownWith(source, newMarkdown(footnoteIDPrefix(source)))
+}
+
+func footnoteIDPrefix(source string) string {
+	return fmt.Sprintf("footnote-%s-", ContentHash(source)[:8])
 }
 
 func (rctx *RenderContext) RenderMarkdownWith(source string, md goldmark.Markdown) string {
@@ -213,6 +243,8 @@ func (a *MarkdownTransformer) Transform(node *ast.Document, reader text.Reader,
 			case *ast.Image:
 				a.rctx.imageFromKnotAstTransformer(n)
 				a.rctx.camoImageLinkAstTransformer(n)
+			case *textension.RefNode:
+				n.Href = a.rctx.References[n.Ref]
 			}
 		}
 
@@ -228,9 +260,30 @@ func (rctx *RenderContext) relativeLinkTransformer(link *ast.Link) {
 		return
 	}
 
-	actualPath := rctx.actualPath(dst)
+	parsed, err := url.Parse(dst)
+	if err != nil || parsed.Path == "" {
+		return
+	}
+
+	actualPath := rctx.actualPath(parsed.Path)
 
-	newPath := path.Join("/", rctx.RepoInfo.FullName(), "tree", rctx.RepoInfo.Ref, actualPath)
+	// files open as blobs. anything else, like a directory, goes to the
+	// tree, which redirects files it gets to their blob anyway.
+	view := "tree"
+	if !strings.HasSuffix(parsed.Path, "/") && path.Ext(actualPath) != "" {
+		view = "blob"
+	}
+
+	newPath := path.Join("/", rctx.RepoInfo.FullName(), view, url.PathEscape(rctx.RepoInfo.Ref))
+	if actualPath != "" {
+		newPath += "/" + escapePath(actualPath)
+	}
+	if parsed.RawQuery != "" {
+		newPath += "?" + parsed.RawQuery
+	}
+	if parsed.Fragment != "" {
+		newPath += "#" + parsed.EscapedFragment()
+	}
 	link.Destination = []byte(newPath)
 }
 
@@ -249,7 +302,7 @@ func (rctx *RenderContext) imageFromKnotTransformer(dst string) string {
 	repoName := fmt.Sprintf("%s/%s", rctx.RepoInfo.OwnerDid, rctx.RepoInfo.Name)
 
 	query := fmt.Sprintf("repo=%s&ref=%s&path=%s&raw=true",
-		url.QueryEscape(repoName), url.QueryEscape(rctx.RepoInfo.Ref), actualPath)
+		url.QueryEscape(repoName), url.QueryEscape(rctx.RepoInfo.Ref), url.QueryEscape(actualPath))
 
 	parsedURL := &url.URL{
 		Scheme:   scheme,
@@ -294,14 +347,29 @@ func (rctx *RenderContext) anchorHeadingTransformer(h *ast.Heading) {
 
 // actualPath decides when to join the file path with the
 // current repository directory (essentially only when the link
-// destination is relative. if it's absolute then we assume the
-// user knows what they're doing.)
+// destination is relative. if it's absolute then it's taken from the
+// repository root.) ".." never leads out of the repository, and the
+// result has no leading slash.
 func (rctx *RenderContext) actualPath(dst string) string {
-	if path.IsAbs(dst) {
-		return dst
+	if !path.IsAbs(dst) {
+		// the current directory comes from the escaped request path
+		dir, err := url.PathUnescape(rctx.CurrentDir)
+		if err != nil {
+			dir = rctx.CurrentDir
+		}
+		dst = path.Join(dir, dst)
 	}
 
-	return path.Join(rctx.CurrentDir, dst)
+	return strings.TrimPrefix(path.Join("/", dst), "/")
+}
+
+// escapePath escapes each element of a slash separated path.
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		elems[i] = url.PathEscape(elem)
+	}
+	return strings.Join(elems, "/")
 }
 
 // FindUserMentions returns Set of user handles from given markup soruce.
@@ -328,6 +396,63 @@ func FindUserMentions(source string) []string {
 	return mentions
 }
 
+// FindReferences returns the set of issue and pull references, as written,
+// from given markup source. These are either '#12' or full AT-URIs.
+func FindReferences(source string) []string {
+	var (
+		refs        []string
+		refsSet     = make(map[string]struct{})
+		md          = NewMarkdown()
+		sourceBytes = []byte(source)
+		root        = md.Parser().Parse(text.NewReader(sourceBytes))
+	)
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == textension.KindRef {
+			ref := n.(*textension.RefNode).Ref
+			if _, ok := refsSet[ref]; !ok {
+				refsSet[ref] = struct{}{}
+				refs = append(refs, ref)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return refs
+}
+
+// closingKeywordRegexp matches text that ends in a closing keyword, like
+// 'fixes ' or 'Closes: '.
+var closingKeywordRegexp = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+$`)
+
+// FindClosingReferences returns the references, as written, that directly
+// follow a closing keyword in given markup source, as in 'fixes #12'.
+func FindClosingReferences(source string) []string {
+	var (
+		refs        []string
+		refsSet     = make(map[string]struct{})
+		md          = NewMarkdown()
+		sourceBytes = []byte(source)
+		root        = md.Parser().Parse(text.NewReader(sourceBytes))
+	)
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == textension.KindRef {
+			prev, ok := n.PreviousSibling().(*ast.Text)
+			if !ok || !closingKeywordRegexp.Match(prev.Segment.Value(sourceBytes)) {
+				return ast.WalkSkipChildren, nil
+			}
+
+			ref := n.(*textension.RefNode).Ref
+			if _, ok := refsSet[ref]; !ok {
+				refsSet[ref] = struct{}{}
+				refs = append(refs, ref)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return refs
+}
+
 func isAbsoluteUrl(link string) bool {
 	parsed, err := url.Parse(link)
 	if err != nil {
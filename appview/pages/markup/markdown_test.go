@@ -0,0 +1,110 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"tangled.org/core/appview/pages/repoinfo"
+)
+
+func TestRepoMarkdownLinks(t *testing.T) {
+	rctx := &RenderContext{
+		CamoUrl:    "https://camo.example.com",
+		CamoSecret: "secret",
+		RepoInfo: repoinfo.RepoInfo{
+			OwnerDid:    "did:plc:alice",
+			OwnerHandle: "alice.test",
+			Name:        "project",
+			Knot:        "knot.example.com",
+			Ref:         "feature/docs",
+			CurrentDir:  "docs/guide",
+		},
+		RendererType: RendererTypeRepoMarkdown,
+		Sanitizer:    NewSanitizer(),
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"nested file", "[x](./setup/x.md)", `href="/alice.test/project/blob/feature%2Fdocs/docs/guide/setup/x.md"`},
+		{"directory", "[dir](sub/)", `href="/alice.test/project/tree/feature%2Fdocs/docs/guide/sub"`},
+		{"parent", "[up](../README.md#usage)", `href="/alice.test/project/blob/feature%2Fdocs/docs/README.md#usage"`},
+		{"clamped traversal", "[out](../../../../etc/passwd.txt)", `href="/alice.test/project/blob/feature%2Fdocs/etc/passwd.txt"`},
+		{"from the root", "[root](/LICENSE.md)", `href="/alice.test/project/blob/feature%2Fdocs/LICENSE.md"`},
+		{"spaces", "[notes](my%20notes.md)", `href="/alice.test/project/blob/feature%2Fdocs/docs/guide/my%20notes.md"`},
+		{"absolute url", "[site](https://example.com/a.md)", `href="https://example.com/a.md"`},
+		{"anchor", "[top](#top)", `href="#top"`},
+		{"relative image", "![logo](img/logo.png)", GenerateCamoURL(
+			"https://camo.example.com",
+			"secret",
+			"https://knot.example.com/xrpc/sh.tangled.repo.blob?repo=did%3Aplc%3Aalice%2Fproject&ref=feature%2Fdocs&path=docs%2Fguide%2Fimg%2Flogo.png&raw=true",
+		)},
+		{"remote image", "![badge](https://example.com/badge.svg)", GenerateCamoURL(
+			"https://camo.example.com",
+			"secret",
+			"https://example.com/badge.svg",
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := rctx.SanitizeDefault(rctx.RenderMarkdown(tt.source))
+			if !strings.Contains(html, tt.want) {
+				t.Errorf("expected %s in:\n%s", tt.want, html)
+			}
+		})
+	}
+}
+
+func TestFootnotes(t *testing.T) {
+	rctx := &RenderContext{
+		Sanitizer:    NewSanitizer(),
+		RendererType: RendererTypeDefault,
+	}
+
+	first := rctx.SanitizeDefault(rctx.RenderMarkdown("first[^1]\n\n[^1]: a note\n"))
+	second := rctx.SanitizeDefault(rctx.RenderMarkdown("second[^1]\n\n[^1]: another note\n"))
+
+	firstPrefix := footnoteIDPrefix("first[^1]\n\n[^1]: a note\n")
+	for _, want := range []string{
+		`href="#` + firstPrefix + `fn:1"`,
+		`id="` + firstPrefix + `fn:1"`,
+		`href="#` + firstPrefix + `fnref:1"`,
+		`class="footnote-backref"`,
+	} {
+		if !strings.Contains(first, want) {
+			t.Errorf("expected %s in:\n%s", want, first)
+		}
+	}
+
+	if strings.Contains(second, firstPrefix) {
+		t.Errorf("expected footnote ids of separate renders to differ:\n%s", second)
+	}
+
+	list := rctx.SanitizeDefault(rctx.RenderMarkdown("term\n: definition\n"))
+	if !strings.Contains(list, "<dl>\n<dt>term</dt>\n<dd>definition</dd>\n</dl>") {
+		t.Errorf("expected a definition list:\n%s", list)
+	}
+}
+
+func TestHighlightedCodeBlocks(t *testing.T) {
+	rctx := &RenderContext{
+		Sanitizer:    NewSanitizer(),
+		RendererType: RendererTypeDefault,
+	}
+
+	html := rctx.SanitizeDefault(rctx.RenderMarkdown("```go\nfunc main() {}\n```"))
+	for _, want := range []string{`<pre class="chroma">`, `<span class="kd">func</span>`, `<span class="nf">main</span>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %s in:\n%s", want, html)
+		}
+	}
+
+	// unknown languages stay plain, and escaped
+	html = rctx.SanitizeDefault(rctx.RenderMarkdown("```nosuchlang\n<b>bold</b>\n```"))
+	if html != "<pre><code>&lt;b&gt;bold&lt;/b&gt;\n</code></pre>\n" {
+		t.Errorf("expected plain preformatted text, got:\n%s", html)
+	}
+}
@@ -0,0 +1,215 @@
+package markup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// Notebooks can carry megabytes of outputs, plots and dataframes mostly.
+// Outputs past these limits are truncated or left out, so that a single
+// notebook doesn't make for a huge page.
+const (
+	// NotebookOutputLimit caps the text of a single output
+	NotebookOutputLimit = 64 << 10
+	// NotebookImageLimit caps the size of a single image, base64 encoded
+	NotebookImageLimit = 2 << 20
+	// NotebookOutputsLimit caps the outputs of the whole notebook
+	NotebookOutputsLimit = 8 << 20
+)
+
+type NotebookCellType string
+
+const (
+	NotebookCellMarkdown NotebookCellType = "markdown"
+	NotebookCellCode     NotebookCellType = "code"
+	NotebookCellRaw      NotebookCellType = "raw"
+)
+
+// Notebook is a Jupyter notebook, as much of it as is needed to render it.
+type Notebook struct {
+	// Language of the code cells, as named by the kernel
+	Language string
+	Cells    []NotebookCell
+}
+
+type NotebookCell struct {
+	Type           NotebookCellType
+	Source         string
+	ExecutionCount *int
+	Outputs        []NotebookOutput
+}
+
+func (c NotebookCell) IsMarkdown() bool { return c.Type == NotebookCellMarkdown }
+func (c NotebookCell) IsCode() bool     { return c.Type == NotebookCellCode }
+
+// NotebookOutput is one output of a code cell, in the richest format that
+// can be shown. At most one of its contents is set.
+type NotebookOutput struct {
+	Text     string
+	Markdown string
+	// HTML is unsanitized
+	HTML  string
+	Image template.URL
+
+	IsError bool
+	// the output was cut short
+	Truncated bool
+	// the output was left out altogether
+	Omitted bool
+}
+
+var ErrUnsupportedNotebook = errors.New("unsupported notebook format")
+
+// ParseNotebook parses a notebook in the nbformat 4 JSON format.
+func ParseNotebook(source string) (*Notebook, error) {
+	var raw rawNotebook
+	if err := json.Unmarshal([]byte(source), &raw); err != nil {
+		return nil, err
+	}
+	if raw.Nbformat != 4 {
+		return nil, ErrUnsupportedNotebook
+	}
+
+	nb := &Notebook{
+		Language: raw.Metadata.LanguageInfo.Name,
+	}
+	if nb.Language == "" {
+		nb.Language = raw.Metadata.Kernelspec.Language
+	}
+
+	budget := NotebookOutputsLimit
+	for _, rc := range raw.Cells {
+		cell := NotebookCell{
+			Type:           NotebookCellType(rc.CellType),
+			Source:         string(rc.Source),
+			ExecutionCount: rc.ExecutionCount,
+		}
+
+		for _, ro := range rc.Outputs {
+			out := ro.output()
+
+			size := len(out.Text) + len(out.Markdown) + len(out.HTML) + len(out.Image)
+			if size > budget {
+				out = NotebookOutput{Omitted: true}
+			}
+			budget -= size
+
+			cell.Outputs = append(cell.Outputs, out)
+		}
+
+		nb.Cells = append(nb.Cells, cell)
+	}
+
+	return nb, nil
+}
+
+type rawNotebook struct {
+	Nbformat int `json:"nbformat"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType       string      `json:"cell_type"`
+		Source         multiline   `json:"source"`
+		ExecutionCount *int        `json:"execution_count"`
+		Outputs        []rawOutput `json:"outputs"`
+	} `json:"cells"`
+}
+
+type rawOutput struct {
+	OutputType string               `json:"output_type"`
+	Text       multiline            `json:"text"`
+	Data       map[string]multiline `json:"data"`
+	Ename      string               `json:"ename"`
+	Evalue     string               `json:"evalue"`
+	Traceback  []string             `json:"traceback"`
+}
+
+// notebookImageTypes are the image types shown inline, in order of
+// preference. SVGs are left out, they are scripts as much as images.
+var notebookImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+func (o rawOutput) output() NotebookOutput {
+	switch o.OutputType {
+	case "stream":
+		return textOutput(string(o.Text), false)
+
+	case "error":
+		text := strings.Join(o.Traceback, "\n")
+		if text == "" {
+			text = o.Ename + ": " + o.Evalue
+		}
+		return textOutput(text, true)
+
+	case "execute_result", "display_data":
+		// roughly the order Jupyter itself prefers; cutting markup short
+		// would mangle it, so large html and markdown give way instead
+		if html, ok := o.Data["text/html"]; ok && len(html) <= NotebookOutputLimit {
+			return NotebookOutput{HTML: string(html)}
+		}
+		if md, ok := o.Data["text/markdown"]; ok && len(md) <= NotebookOutputLimit {
+			return NotebookOutput{Markdown: string(md)}
+		}
+		for _, mime := range notebookImageTypes {
+			if data, ok := o.Data[mime]; ok {
+				return imageOutput(mime, string(data))
+			}
+		}
+		if text, ok := o.Data["text/plain"]; ok {
+			return textOutput(string(text), false)
+		}
+	}
+
+	return NotebookOutput{Omitted: true}
+}
+
+func textOutput(text string, isError bool) NotebookOutput {
+	out := NotebookOutput{IsError: isError}
+	out.Text = ansiEscape.ReplaceAllString(text, "")
+	if len(out.Text) > NotebookOutputLimit {
+		out.Text = strings.ToValidUTF8(out.Text[:NotebookOutputLimit], "")
+		out.Truncated = true
+	}
+	return out
+}
+
+func imageOutput(mime, data string) NotebookOutput {
+	// base64 in notebooks is wrapped over several lines
+	data = strings.Join(strings.Fields(data), "")
+	if len(data) > NotebookImageLimit {
+		return NotebookOutput{Omitted: true}
+	}
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return NotebookOutput{Omitted: true}
+	}
+	return NotebookOutput{Image: template.URL("data:" + mime + ";base64," + data)}
+}
+
+// multiline is a string that nbformat may also split into a list of lines.
+type multiline string
+
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = multiline(s)
+		return nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*m = multiline(strings.Join(lines, ""))
+	return nil
+}
@@ -0,0 +1,115 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNotebook(t *testing.T) {
+	bigText := `"` + strings.Repeat("x", NotebookOutputLimit+10) + `"`
+	source := `{
+  "nbformat": 4,
+  "nbformat_minor": 5,
+  "metadata": {"kernelspec": {"language": "python"}},
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Title\n", "some text"]},
+    {
+      "cell_type": "code",
+      "execution_count": 3,
+      "source": "print('hi')",
+      "outputs": [
+        {"output_type": "stream", "name": "stdout", "text": ["hi\n"]},
+        {"output_type": "execute_result", "data": {"text/plain": ["df"], "text/html": ["<table></table>"]}},
+        {"output_type": "display_data", "data": {"image/png": "aGVs\nbG8=\n", "text/plain": "<Figure>"}},
+        {"output_type": "error", "ename": "ValueError", "evalue": "bad", "traceback": ["\u001b[0;31mValueError\u001b[0m: bad"]},
+        {"output_type": "stream", "name": "stdout", "text": ` + bigText + `},
+        {"output_type": "display_data", "data": {"application/pdf": "..."}}
+      ]
+    },
+    {"cell_type": "code", "execution_count": null, "source": "", "outputs": []}
+  ]
+}`
+
+	nb, err := ParseNotebook(source)
+	if err != nil {
+		t.Fatalf("ParseNotebook: %v", err)
+	}
+
+	if nb.Language != "python" {
+		t.Errorf("language = %q, want python", nb.Language)
+	}
+	if len(nb.Cells) != 3 {
+		t.Fatalf("got %d cells, want 3", len(nb.Cells))
+	}
+
+	if md := nb.Cells[0]; !md.IsMarkdown() || md.Source != "# Title\nsome text" {
+		t.Errorf("markdown cell = %+v", md)
+	}
+
+	code := nb.Cells[1]
+	if !code.IsCode() || code.ExecutionCount == nil || *code.ExecutionCount != 3 {
+		t.Errorf("code cell = %+v", code)
+	}
+	if nb.Cells[2].ExecutionCount != nil {
+		t.Errorf("unexecuted cell has an execution count")
+	}
+
+	outs := code.Outputs
+	if len(outs) != 6 {
+		t.Fatalf("got %d outputs, want 6", len(outs))
+	}
+	if outs[0].Text != "hi\n" {
+		t.Errorf("stream output = %+v", outs[0])
+	}
+	if outs[1].HTML != "<table></table>" || outs[1].Text != "" {
+		t.Errorf("html is preferred over text, got %+v", outs[1])
+	}
+	if outs[2].Image != "data:image/png;base64,aGVsbG8=" {
+		t.Errorf("image output = %+v", outs[2])
+	}
+	if !outs[3].IsError || outs[3].Text != "ValueError: bad" {
+		t.Errorf("error output = %+v", outs[3])
+	}
+	if !outs[4].Truncated || len(outs[4].Text) != NotebookOutputLimit {
+		t.Errorf("large output was not truncated, got %d bytes", len(outs[4].Text))
+	}
+	if !outs[5].Omitted {
+		t.Errorf("unsupported output = %+v", outs[5])
+	}
+}
+
+func TestParseNotebookOutputsLimit(t *testing.T) {
+	// each image is under the per-image limit, but together they are over
+	// the limit of the notebook
+	image := strings.Repeat("AAAA", NotebookImageLimit/4)
+	n := NotebookOutputsLimit/len(image) + 1
+
+	var outputs []string
+	for range n {
+		outputs = append(outputs, `{"output_type": "display_data", "data": {"image/png": "`+image+`"}}`)
+	}
+	source := `{"nbformat": 4, "metadata": {}, "cells": [{"cell_type": "code", "source": "", "outputs": [` +
+		strings.Join(outputs, ",") + `]}]}`
+
+	nb, err := ParseNotebook(source)
+	if err != nil {
+		t.Fatalf("ParseNotebook: %v", err)
+	}
+
+	outs := nb.Cells[0].Outputs
+	if outs[0].Omitted {
+		t.Errorf("first output should fit")
+	}
+	if !outs[len(outs)-1].Omitted {
+		t.Errorf("last output should be left out")
+	}
+}
+
+func TestParseNotebookUnsupported(t *testing.T) {
+	if _, err := ParseNotebook(`{"nbformat": 3, "worksheets": []}`); err != ErrUnsupportedNotebook {
+		t.Errorf("err = %v, want ErrUnsupportedNotebook", err)
+	}
+	if _, err := ParseNotebook(`not json`); err == nil {
+		t.Errorf("expected an error for invalid json")
+	}
+}
@@ -0,0 +1,60 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindReferences(t *testing.T) {
+	source := strings.Join([]string{
+		"fixes #12 and (#3), see at://did:plc:foo/sh.tangled.repo.pull/3lbar",
+		"",
+		"not a reference: foo#4, `#5`",
+		"",
+		"```",
+		"#6",
+		"```",
+		"",
+		"again #12",
+	}, "\n")
+
+	got := FindReferences(source)
+	want := []string{"#12", "#3", "at://did:plc:foo/sh.tangled.repo.pull/3lbar"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFindClosingReferences(t *testing.T) {
+	source := strings.Join([]string{
+		"Fixes #12 and #3, closes: #4",
+		"",
+		"resolved at://did:plc:foo/sh.tangled.repo.issue/3lbar",
+		"",
+		"see #5, prefixes #6",
+	}, "\n")
+
+	got := FindClosingReferences(source)
+	want := []string{"#12", "#4", "at://did:plc:foo/sh.tangled.repo.issue/3lbar"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRenderedReferences(t *testing.T) {
+	rctx := &RenderContext{
+		Sanitizer:    NewSanitizer(),
+		RendererType: RendererTypeDefault,
+		References: map[string]string{
+			"#12": "/did:plc:foo/core/pulls/12",
+		},
+	}
+	html := rctx.SanitizeDefault(rctx.RenderMarkdown("fixes #12, not #13"))
+
+	if !strings.Contains(html, `<a href="/did:plc:foo/core/pulls/12" class="reference"`) {
+		t.Errorf("expected #12 to be linked:\n%s", html)
+	}
+	if strings.Contains(html, `#13</a>`) {
+		t.Errorf("expected #13 to be left as text:\n%s", html)
+	}
+}
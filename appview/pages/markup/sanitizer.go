@@ -70,6 +70,7 @@ func defaultPolicy() *bluemonday.Policy {
 	// checkboxes
 	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
 	policy.AllowAttrs("checked", "disabled", "data-source-position").OnElements("input")
+	policy.AllowAttrs("data-task-index").Matching(regexp.MustCompile(`^[0-9]+$`)).OnElements("input")
 
 	// for code blocks
 	policy.AllowAttrs("class").Matching(regexp.MustCompile(`chroma`)).OnElements("pre")
@@ -77,9 +78,16 @@ func defaultPolicy() *bluemonday.Policy {
 	policy.AllowAttrs("class").Matching(regexp.MustCompile(`heading`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "h7", "h8")
 	policy.AllowAttrs("class").Matching(regexp.MustCompile(strings.Join(slices.Collect(maps.Values(chroma.StandardTypes)), "|"))).OnElements("span")
 
+	// footnotes
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes$`)).OnElements("div")
+	policy.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|backlink|endnotes)$`)).OnElements("a", "div")
+
 	// at-mentions
 	policy.AllowAttrs("class").Matching(regexp.MustCompile(`mention`)).OnElements("a")
 
+	// issue and pull references
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`reference`)).OnElements("a")
+
 	// centering content
 	policy.AllowElements("center")
 
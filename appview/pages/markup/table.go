@@ -0,0 +1,111 @@
+package markup
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// TableRowLimit caps the rows parsed out of a delimited file, the rest is
+// left to the raw view.
+const TableRowLimit = 5000
+
+// Table is a delimited file, like a CSV or a TSV, parsed for display.
+type Table struct {
+	Header []string
+	// Rows are padded to the width of the widest row
+	Rows [][]string
+	// the file has more rows than TableRowLimit
+	Truncated bool
+}
+
+// tableDelimiters are the delimiters DetectDelimiter picks from, in order
+// of preference.
+var tableDelimiters = []rune{',', '\t', ';', '|'}
+
+// DetectDelimiter guesses the delimiter of a delimited file from its first
+// lines. It picks the delimiter that splits them into the most fields,
+// as long as every line has the same number of them, and falls back to a
+// comma.
+func DetectDelimiter(source string) rune {
+	best, bestFields := ',', 1
+
+	for _, d := range tableDelimiters {
+		r := newTableReader(source, d)
+		// every record must have as many fields as the first one
+		r.FieldsPerRecord = 0
+
+		fields := 0
+		for range 10 {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				fields = 0
+				break
+			}
+			fields = len(record)
+		}
+
+		if fields > bestFields {
+			best, bestFields = d, fields
+		}
+	}
+
+	return best
+}
+
+// ParseTable parses delimited text, taking its first row as the header.
+// Quoted fields may hold delimiters, newlines and doubled quotes; stray
+// quotes are taken literally, and rows may have any number of fields.
+func ParseTable(source string, delimiter rune) (*Table, error) {
+	r := newTableReader(source, delimiter)
+	r.FieldsPerRecord = -1
+
+	t := &Table{}
+	width := 0
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(t.Rows) == TableRowLimit {
+			t.Truncated = true
+			break
+		}
+
+		width = max(width, len(record))
+		if t.Header == nil {
+			t.Header = record
+		} else {
+			t.Rows = append(t.Rows, record)
+		}
+	}
+
+	t.Header = padRow(t.Header, width)
+	for i, row := range t.Rows {
+		t.Rows[i] = padRow(row, width)
+	}
+
+	return t, nil
+}
+
+func newTableReader(source string, delimiter rune) *csv.Reader {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(source, "\uFEFF")))
+	r.Comma = delimiter
+	r.LazyQuotes = true
+	return r
+}
+
+func padRow(row []string, width int) []string {
+	for len(row) < width {
+		row = append(row, "")
+	}
+	return row
+}
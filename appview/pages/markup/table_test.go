@@ -0,0 +1,141 @@
+package markup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		delimiter rune
+		header    []string
+		rows      [][]string
+	}{
+		{
+			name:      "plain",
+			source:    "a,b\n1,2\n3,4\n",
+			delimiter: ',',
+			header:    []string{"a", "b"},
+			rows:      [][]string{{"1", "2"}, {"3", "4"}},
+		},
+		{
+			name:      "quoted delimiter",
+			source:    "name,city\n\"Doe, Jane\",Paris\n",
+			delimiter: ',',
+			header:    []string{"name", "city"},
+			rows:      [][]string{{"Doe, Jane", "Paris"}},
+		},
+		{
+			name:      "escaped quotes",
+			source:    "quote\n\"she said \"\"hi\"\"\"\n",
+			delimiter: ',',
+			header:    []string{"quote"},
+			rows:      [][]string{{`she said "hi"`}},
+		},
+		{
+			name:      "embedded newline",
+			source:    "id,note\n1,\"first line\nsecond line\"\n2,short\n",
+			delimiter: ',',
+			header:    []string{"id", "note"},
+			rows:      [][]string{{"1", "first line\nsecond line"}, {"2", "short"}},
+		},
+		{
+			name:      "crlf line endings",
+			source:    "a,b\r\n1,2\r\n",
+			delimiter: ',',
+			header:    []string{"a", "b"},
+			rows:      [][]string{{"1", "2"}},
+		},
+		{
+			name:      "stray quote",
+			source:    "size\n5\" floppy\n",
+			delimiter: ',',
+			header:    []string{"size"},
+			rows:      [][]string{{`5" floppy`}},
+		},
+		{
+			name:      "ragged rows are padded",
+			source:    "a,b,c\n1\n1,2,3,4\n",
+			delimiter: ',',
+			header:    []string{"a", "b", "c", ""},
+			rows:      [][]string{{"1", "", "", ""}, {"1", "2", "3", "4"}},
+		},
+		{
+			name:      "byte order mark",
+			source:    "\uFEFFa,b\n1,2\n",
+			delimiter: ',',
+			header:    []string{"a", "b"},
+			rows:      [][]string{{"1", "2"}},
+		},
+		{
+			name:      "tabs",
+			source:    "a\tb\n1,5\t2\n",
+			delimiter: '\t',
+			header:    []string{"a", "b"},
+			rows:      [][]string{{"1,5", "2"}},
+		},
+		{
+			name:      "header only",
+			source:    "a,b\n",
+			delimiter: ',',
+			header:    []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseTable(tt.source, tt.delimiter)
+			if err != nil {
+				t.Fatalf("ParseTable: %v", err)
+			}
+			if !reflect.DeepEqual(table.Header, tt.header) {
+				t.Errorf("header = %q, want %q", table.Header, tt.header)
+			}
+			if !reflect.DeepEqual(table.Rows, tt.rows) {
+				t.Errorf("rows = %q, want %q", table.Rows, tt.rows)
+			}
+			if table.Truncated {
+				t.Errorf("table should not be truncated")
+			}
+		})
+	}
+}
+
+func TestParseTableRowLimit(t *testing.T) {
+	source := "n\n" + strings.Repeat("1\n", TableRowLimit+1)
+
+	table, err := ParseTable(source, ',')
+	if err != nil {
+		t.Fatalf("ParseTable: %v", err)
+	}
+	if len(table.Rows) != TableRowLimit || !table.Truncated {
+		t.Errorf("got %d rows, truncated %v; want %d rows, truncated", len(table.Rows), table.Truncated, TableRowLimit)
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   rune
+	}{
+		{"comma", "a,b,c\n1,2,3\n", ','},
+		{"semicolon", "a;b;c\n1,5;2,5;3\n", ';'},
+		{"tab", "a\tb\n1\t2\n", '\t'},
+		{"pipe", "a|b|c\n1|2|3\n", '|'},
+		{"quoted commas", "a;b\n\"x,y,z\";2\n", ';'},
+		{"single column", "a\n1\n", ','},
+		{"inconsistent", "a;b\n1;2;3;4\n", ','},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectDelimiter(tt.source); got != tt.want {
+				t.Errorf("DetectDelimiter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package markup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// ContentHash identifies a revision of some markdown source, it is handed
+// out alongside rendered task lists so that toggles against stale content
+// can be detected.
+func ContentHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// ToggleTaskListItem sets the state of the task list item at index (as
+// numbered by the renderer) and returns the rewritten source. Only the
+// checkbox marker is touched, the rest of the source is left as-is.
+func ToggleTaskListItem(source string, index int, checked bool) (string, error) {
+	src := []byte(source)
+	root := NewMarkdown().Parser().Parse(text.NewReader(src))
+
+	// position of the "[ ]" marker in the source
+	marker := -1
+	current := 0
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != east.KindTaskCheckBox {
+			return ast.WalkContinue, nil
+		}
+
+		if current == index {
+			// the checkbox is always the first inline of its list item's
+			// text block, which starts at the marker
+			if lines := n.Parent().Lines(); lines.Len() > 0 {
+				marker = lines.At(0).Start
+			}
+			return ast.WalkStop, nil
+		}
+
+		current++
+		return ast.WalkContinue, nil
+	})
+
+	if marker < 0 || marker+2 >= len(src) || src[marker] != '[' || src[marker+2] != ']' {
+		return "", fmt.Errorf("no task list item at index %d", index)
+	}
+
+	if checked {
+		src[marker+1] = 'x'
+	} else {
+		src[marker+1] = ' '
+	}
+
+	return string(src), nil
+}
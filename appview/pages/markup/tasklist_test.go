@@ -0,0 +1,76 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToggleTaskListItem(t *testing.T) {
+	source := strings.Join([]string{
+		"intro paragraph with [brackets]",
+		"",
+		"- [ ] first",
+		"- [x] second",
+		"  - [ ] nested",
+		"- not a task",
+		"",
+		"```",
+		"- [ ] inside a code block",
+		"```",
+		"",
+		"1. [X] ordered",
+	}, "\n")
+
+	tests := []struct {
+		name    string
+		index   int
+		checked bool
+		want    string // line expected after the toggle
+		wantErr bool
+	}{
+		{name: "check first", index: 0, checked: true, want: "- [x] first"},
+		{name: "uncheck second", index: 1, checked: false, want: "- [ ] second"},
+		{name: "check nested", index: 2, checked: true, want: "  - [x] nested"},
+		{name: "uncheck ordered", index: 3, checked: false, want: "1. [ ] ordered"},
+		{name: "already checked", index: 1, checked: true, want: "- [x] second"},
+		{name: "out of range", index: 4, wantErr: true},
+		{name: "negative", index: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToggleTaskListItem(source, tt.index, tt.checked)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected %q in:\n%s", tt.want, got)
+			}
+			if len(got) != len(source) {
+				t.Errorf("toggle should only rewrite the marker")
+			}
+			if !strings.Contains(got, "- [ ] inside a code block") {
+				t.Errorf("code block was modified:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestRenderedTaskIndices(t *testing.T) {
+	source := "- [ ] first\n- [x] second\n"
+
+	rctx := &RenderContext{Sanitizer: NewSanitizer()}
+	html := rctx.SanitizeDefault(rctx.RenderMarkdown(source))
+
+	for _, want := range []string{`data-task-index="0"`, `data-task-index="1"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %s in rendered output:\n%s", want, html)
+		}
+	}
+}
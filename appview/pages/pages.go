@@ -24,6 +24,7 @@ import (
 	"tangled.org/core/appview/pages/markup"
 	"tangled.org/core/appview/pages/repoinfo"
 	"tangled.org/core/appview/pagination"
+	"tangled.org/core/appview/pow"
 	"tangled.org/core/idresolver"
 	"tangled.org/core/patchutil"
 	"tangled.org/core/types"
@@ -53,11 +54,12 @@ type Pages struct {
 func NewPages(config *config.Config, res *idresolver.Resolver, logger *slog.Logger) *Pages {
 	// initialized with safe defaults, can be overriden per use
 	rctx := &markup.RenderContext{
-		IsDev:      config.Core.Dev,
-		CamoUrl:    config.Camo.Host,
-		CamoSecret: config.Camo.SharedSecret,
-		Sanitizer:  markup.NewSanitizer(),
-		Files:      Files,
+		IsDev:       config.Core.Dev,
+		CamoUrl:     config.Camo.Host,
+		CamoSecret:  config.Camo.SharedSecret,
+		AppviewHost: config.Core.AppviewHost,
+		Sanitizer:   markup.NewSanitizer(),
+		Files:       Files,
 	}
 
 	p := &Pages{
@@ -226,6 +228,7 @@ func (p *Pages) Login(w io.Writer, params LoginParams) error {
 
 type SignupParams struct {
 	CloudflareSiteKey string
+	Pow               *pow.Challenge
 }
 
 func (p *Pages) Signup(w io.Writer, params SignupParams) error {
@@ -264,6 +267,39 @@ func (p *Pages) TermsOfService(w io.Writer, params TermsOfServiceParams) error {
 	return p.execute("legal/terms", w, params)
 }
 
+type MarkupPreviewParams struct {
+	Source string
+
+	// RepoInfo is set when the draft belongs to a repo
+	RepoInfo *repoinfo.RepoInfo
+
+	// RepoMarkup renders the draft as a file in the repo, rather than as
+	// an issue, pull or comment body
+	RepoMarkup bool
+	References map[string]models.Reference
+}
+
+// MarkupPreview renders a markdown draft exactly as it is rendered once
+// posted.
+func (p *Pages) MarkupPreview(w io.Writer, params MarkupPreviewParams) error {
+	rctx := *p.rctx
+	rctx.RepoInfo = repoinfo.RepoInfo{}
+	if params.RepoInfo != nil {
+		rctx.RepoInfo = *params.RepoInfo
+	}
+
+	rctx.RendererType = markup.RendererTypeDefault
+	if params.RepoMarkup {
+		rctx.RendererType = markup.RendererTypeRepoMarkdown
+	}
+	rctx.References = models.ReferenceHrefs(params.References)
+
+	htmlString := rctx.RenderMarkdown(params.Source)
+	sanitized := rctx.SanitizeDefault(htmlString)
+
+	return p.executePlain("fragments/markupPreview", w, template.HTML(sanitized))
+}
+
 type PrivacyPolicyParams struct {
 	LoggedInUser *oauth.User
 	Content      template.HTML
@@ -303,6 +339,9 @@ func (p *Pages) Brand(w io.Writer, params BrandParams) error {
 type TimelineParams struct {
 	LoggedInUser *oauth.User
 	Timeline     []models.TimelineEvent
+	Feed         models.TimelineFeed
+	Before       string // cursor of this page, empty on the first
+	Next         string // cursor of the next page, empty on the last
 	Repos        []models.Repo
 	GfiLabel     *models.LabelDefinition
 }
@@ -324,10 +363,82 @@ func (p *Pages) GoodFirstIssues(w io.Writer, params GoodFirstIssuesParams) error
 	return p.execute("goodfirstissues/index", w, params)
 }
 
+type SearchKind string
+
+const (
+	SearchRepos  SearchKind = "repos"
+	SearchIssues SearchKind = "issues"
+	SearchPulls  SearchKind = "pulls"
+	SearchUsers  SearchKind = "users"
+)
+
+// SearchKinds in the order their results are listed in
+var SearchKinds = []SearchKind{SearchRepos, SearchIssues, SearchPulls, SearchUsers}
+
+type SearchParams struct {
+	LoggedInUser *oauth.User
+	Query        string
+	// empty when searching every kind
+	Kind SearchKind
+	Page pagination.Page
+
+	Repos      []models.Repo
+	RepoCount  int
+	Issues     []models.Issue
+	IssueCount int
+	Pulls      []*models.Pull
+	PullCount  int
+	// dids
+	Users     []string
+	UserCount int
+}
+
+// Count is the number of results of kind.
+func (s SearchParams) Count(kind SearchKind) int {
+	switch kind {
+	case SearchRepos:
+		return s.RepoCount
+	case SearchIssues:
+		return s.IssueCount
+	case SearchPulls:
+		return s.PullCount
+	case SearchUsers:
+		return s.UserCount
+	}
+	return 0
+}
+
+// Shown is the number of results of kind on this page.
+func (s SearchParams) Shown(kind SearchKind) int {
+	switch kind {
+	case SearchRepos:
+		return len(s.Repos)
+	case SearchIssues:
+		return len(s.Issues)
+	case SearchPulls:
+		return len(s.Pulls)
+	case SearchUsers:
+		return len(s.Users)
+	}
+	return 0
+}
+
+func (s SearchParams) Kinds() []SearchKind {
+	return SearchKinds
+}
+
+func (p *Pages) Search(w io.Writer, params SearchParams) error {
+	return p.execute("search/index", w, params)
+}
+
 type UserProfileSettingsParams struct {
 	LoggedInUser *oauth.User
 	Tabs         []map[string]any
 	Tab          string
+
+	// "" when new repos start with InstanceDefaultBranch
+	DefaultBranch         string
+	InstanceDefaultBranch string
 }
 
 func (p *Pages) UserProfileSettings(w io.Writer, params UserProfileSettingsParams) error {
@@ -346,6 +457,60 @@ func (p *Pages) Notifications(w io.Writer, params NotificationsParams) error {
 	return p.execute("notifications/list", w, params)
 }
 
+type ReportParams struct {
+	LoggedInUser *oauth.User
+	Target       models.ReportTarget
+	Reasons      []models.ReportReason
+}
+
+func (p *Pages) ReportPage(w io.Writer, params ReportParams) error {
+	return p.execute("moderation/report", w, params)
+}
+
+type ModerationQueueItem struct {
+	models.Report
+	Target models.ReportTarget
+}
+
+type ModerationQueueParams struct {
+	LoggedInUser *oauth.User
+	Items        []ModerationQueueItem
+	Status       models.ReportStatus
+	Page         pagination.Page
+	Total        int64
+}
+
+func (p *Pages) ModerationQueue(w io.Writer, params ModerationQueueParams) error {
+	return p.execute("moderation/queue", w, params)
+}
+
+type AdminSignup struct {
+	models.Email
+	Suspended bool
+}
+
+type AdminTakedown struct {
+	models.Takedown
+	Target models.ReportTarget
+}
+
+type AdminDashboardParams struct {
+	LoggedInUser    *oauth.User
+	IsModerator     bool
+	Signups         []AdminSignup
+	InflightSignups []models.InflightSignup
+	Reports         []ModerationQueueItem
+	OpenReports     int64
+	Knots           []models.Registration
+	Spindles        []models.Spindle
+	Takedowns       []AdminTakedown
+	Actions         []models.AdminAction
+}
+
+func (p *Pages) AdminDashboard(w io.Writer, params AdminDashboardParams) error {
+	return p.execute("admin/dashboard", w, params)
+}
+
 type NotificationItemParams struct {
 	Notification *models.Notification
 }
@@ -373,6 +538,66 @@ func (p *Pages) UserKeysSettings(w io.Writer, params UserKeysSettingsParams) err
 	return p.execute("user/settings/keys", w, params)
 }
 
+type UserAppPasswordsSettingsParams struct {
+	LoggedInUser *oauth.User
+	AppPasswords []models.AppPassword
+	Scopes       []string
+	Tabs         []map[string]any
+	Tab          string
+}
+
+func (p *Pages) UserAppPasswordsSettings(w io.Writer, params UserAppPasswordsSettingsParams) error {
+	return p.execute("user/settings/appPasswords", w, params)
+}
+
+type UserSecuritySettingsParams struct {
+	LoggedInUser *oauth.User
+	Totp         *models.TotpSecret
+	// set while an enrollment awaits confirmation
+	TotpUri string
+	Tabs    []map[string]any
+	Tab     string
+}
+
+func (p *Pages) UserSecuritySettings(w io.Writer, params UserSecuritySettingsParams) error {
+	return p.execute("user/settings/security", w, params)
+}
+
+type UserWatchingSettingsParams struct {
+	LoggedInUser *oauth.User
+	Watches      []models.RepoWatch
+	Tabs         []map[string]any
+	Tab          string
+}
+
+func (p *Pages) UserWatchingSettings(w io.Writer, params UserWatchingSettingsParams) error {
+	return p.execute("user/settings/watching", w, params)
+}
+
+type UserExportSettingsParams struct {
+	LoggedInUser *oauth.User
+	Exports      []models.Export
+	Tabs         []map[string]any
+	Tab          string
+}
+
+func (p *Pages) UserExportSettings(w io.Writer, params UserExportSettingsParams) error {
+	return p.execute("user/settings/export", w, params)
+}
+
+type UserAccountSettingsParams struct {
+	LoggedInUser   *oauth.User
+	Deletion       *models.AccountDeletion
+	DeletionPhrase string
+	TotpEnrolled   bool
+	Tabs           []map[string]any
+	Tab            string
+}
+
+func (p *Pages) UserAccountSettings(w io.Writer, params UserAccountSettingsParams) error {
+	return p.execute("user/settings/account", w, params)
+}
+
 type UserEmailsSettingsParams struct {
 	LoggedInUser *oauth.User
 	Emails       []models.Email
@@ -398,6 +623,8 @@ func (p *Pages) UserNotificationSettings(w io.Writer, params UserNotificationSet
 type UpgradeBannerParams struct {
 	Registrations []models.Registration
 	Spindles      []models.Spindle
+	// verified knots whose health check says they are out of date
+	OutdatedKnots []models.KnotHealth
 }
 
 func (p *Pages) UpgradeBanner(w io.Writer, params UpgradeBannerParams) error {
@@ -433,6 +660,10 @@ func (p *Pages) KnotListing(w io.Writer, params KnotListingParams) error {
 	return p.executePlain("knots/fragments/knotListing", w, params)
 }
 
+func (p *Pages) KnotHealth(w io.Writer, health models.KnotHealth) error {
+	return p.executePlain("knots/fragments/knotHealth", w, health)
+}
+
 type SpindlesParams struct {
 	LoggedInUser *oauth.User
 	Spindles     []models.Spindle
@@ -462,8 +693,10 @@ func (p *Pages) SpindleDashboard(w io.Writer, params SpindleDashboardParams) err
 }
 
 type NewRepoParams struct {
-	LoggedInUser *oauth.User
-	Knots        []string
+	LoggedInUser  *oauth.User
+	Knots         []string
+	Pow           *pow.Challenge
+	DefaultBranch string
 }
 
 func (p *Pages) NewRepo(w io.Writer, params NewRepoParams) error {
@@ -480,6 +713,17 @@ func (p *Pages) ForkRepo(w io.Writer, params ForkRepoParams) error {
 	return p.execute("repo/fork", w, params)
 }
 
+type RepoTemplateParams struct {
+	LoggedInUser  *oauth.User
+	Knots         []string
+	RepoInfo      repoinfo.RepoInfo
+	DefaultBranch string
+}
+
+func (p *Pages) RepoTemplate(w io.Writer, params RepoTemplateParams) error {
+	return p.execute("repo/template", w, params)
+}
+
 type ProfileCard struct {
 	UserDid      string
 	UserHandle   string
@@ -510,12 +754,11 @@ func (p *ProfileCard) GetTabs() [][]any {
 }
 
 type ProfileOverviewParams struct {
-	LoggedInUser       *oauth.User
-	Repos              []models.Repo
-	CollaboratingRepos []models.Repo
-	ProfileTimeline    *models.ProfileTimeline
-	Card               *ProfileCard
-	Active             string
+	LoggedInUser    *oauth.User
+	Pins            []models.Pin
+	ProfileTimeline *models.ProfileTimeline
+	Card            *ProfileCard
+	Active          string
 }
 
 func (p *Pages) ProfileOverview(w io.Writer, params ProfileOverviewParams) error {
@@ -601,6 +844,10 @@ func (p *Pages) FollowFragment(w io.Writer, params FollowFragmentParams) error {
 	return p.executePlain("user/fragments/follow", w, params)
 }
 
+func (p *Pages) StorageFragment(w io.Writer, usage []models.StorageUsage) error {
+	return p.executePlain("user/fragments/storage", w, usage)
+}
+
 type EditBioParams struct {
 	LoggedInUser *oauth.User
 	Profile      *models.Profile
@@ -613,12 +860,12 @@ func (p *Pages) EditBioFragment(w io.Writer, params EditBioParams) error {
 type EditPinsParams struct {
 	LoggedInUser *oauth.User
 	Profile      *models.Profile
-	AllRepos     []PinnedRepo
+	Candidates   []PinCandidate
 }
 
-type PinnedRepo struct {
+type PinCandidate struct {
 	IsPinned bool
-	models.Repo
+	models.Pin
 }
 
 func (p *Pages) EditPinsFragment(w io.Writer, params EditPinsParams) error {
@@ -635,6 +882,15 @@ func (p *Pages) StarBtnFragment(w io.Writer, params StarBtnFragmentParams) error
 	return p.executePlain("fragments/starBtn", w, params)
 }
 
+type WatchBtnFragmentParams struct {
+	IsWatching bool
+	SubjectAt  syntax.ATURI
+}
+
+func (p *Pages) WatchBtnFragment(w io.Writer, params WatchBtnFragmentParams) error {
+	return p.executePlain("fragments/watchBtn", w, params)
+}
+
 type RepoIndexParams struct {
 	LoggedInUser  *oauth.User
 	RepoInfo      repoinfo.RepoInfo
@@ -650,10 +906,24 @@ type RepoIndexParams struct {
 	VerifiedCommits  commitverify.VerifiedCommits
 	Languages        []types.RepoLanguageDetails
 	Pipelines        map[string]models.Pipeline
+	LatestRelease    *models.Release
 	NeedsKnotUpgrade bool
+	// set for empty repos the viewer can push to
+	Setup *RepoSetup
+	// whether files can be added in the browser
+	CanAddFiles bool
 	types.RepoIndexResponse
 }
 
+// RepoSetup is how the first commits get pushed to an empty repo.
+type RepoSetup struct {
+	Branch   string
+	HTTPSUrl string
+	SSHUrl   string
+	// set for forks, to fetch the upstream from
+	UpstreamUrl string
+}
+
 func (p *Pages) RepoIndexPage(w io.Writer, params RepoIndexParams) error {
 	params.Active = "overview"
 	if params.IsEmpty {
@@ -709,6 +979,9 @@ type RepoCommitParams struct {
 	Pipeline     *models.Pipeline
 	DiffOpts     types.DiffOpts
 
+	// reported by CI systems other than spindle
+	CommitStatuses []models.CommitStatus
+
 	// singular because it's always going to be just one
 	VerifiedCommit commitverify.VerifiedCommits
 
@@ -728,6 +1001,8 @@ type RepoTreeParams struct {
 	TreePath     string
 	Raw          bool
 	HTMLReadme   template.HTML
+	// whether files can be added to the tree in the browser
+	CanAddFiles bool
 	types.RepoTreeResponse
 }
 
@@ -757,6 +1032,8 @@ func (p *Pages) RepoTree(w io.Writer, params RepoTreeParams) error {
 
 	p.rctx.RepoInfo = params.RepoInfo
 	p.rctx.RepoInfo.Ref = params.Ref
+	// the readme lives in the tree being shown, not next to it
+	p.rctx.RepoInfo.CurrentDir = params.TreePath
 	p.rctx.RendererType = markup.RendererTypeRepoMarkdown
 
 	if params.ReadmeFileName != "" {
@@ -792,8 +1069,9 @@ type RepoTagsParams struct {
 	RepoInfo     repoinfo.RepoInfo
 	Active       string
 	types.RepoTagsResponse
-	ArtifactMap       map[plumbing.Hash][]models.Artifact
-	DanglingArtifacts []models.Artifact
+	ArtifactMap  map[plumbing.Hash][]models.Artifact
+	ReleaseMap   map[string]models.Release
+	VerifiedTags commitverify.VerifiedCommits
 }
 
 func (p *Pages) RepoTags(w io.Writer, params RepoTagsParams) error {
@@ -801,6 +1079,161 @@ func (p *Pages) RepoTags(w io.Writer, params RepoTagsParams) error {
 	return p.executeRepo("repo/tags", w, params)
 }
 
+type RepoReleasesParams struct {
+	LoggedInUser      *oauth.User
+	RepoInfo          repoinfo.RepoInfo
+	Active            string
+	Releases          []models.Release
+	Latest            *models.Release
+	DanglingArtifacts []models.Artifact
+}
+
+func (p *Pages) RepoReleases(w io.Writer, params RepoReleasesParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/releases/releases", w, params)
+}
+
+type RepoReleaseParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Release      models.Release
+	Latest       *models.Release
+}
+
+func (p *Pages) RepoRelease(w io.Writer, params RepoReleaseParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/releases/release", w, params)
+}
+
+type RepoPutReleaseParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Action       string
+
+	// tags that can be released, when creating
+	Tags        []string
+	SelectedTag string
+
+	// the release being edited
+	Release *models.Release
+}
+
+func (p *Pages) RepoPutRelease(w io.Writer, params RepoPutReleaseParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/releases/put", w, params)
+}
+
+type ReleaseNotesParams struct {
+	Body string
+}
+
+func (p *Pages) ReleaseNotesFragment(w io.Writer, params ReleaseNotesParams) error {
+	return p.executePlain("repo/releases/fragments/notes", w, params)
+}
+
+type Stargazer struct {
+	Did     string
+	Handle  string
+	Starred time.Time
+}
+
+type RepoStargazersParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Stargazers   []Stargazer
+	Page         pagination.Page
+	Total        int
+}
+
+func (p *Pages) RepoStargazers(w io.Writer, params RepoStargazersParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/stargazers", w, params)
+}
+
+// ForkStatus is how the default branch of a fork compares to its source's,
+// empty when they couldn't be compared.
+type ForkStatus string
+
+const (
+	ForkUpToDate ForkStatus = "up-to-date"
+	ForkAhead    ForkStatus = "ahead"
+	ForkBehind   ForkStatus = "behind"
+	ForkDiverged ForkStatus = "diverged"
+)
+
+type Fork struct {
+	Repo   models.Repo
+	Handle string
+	Status ForkStatus
+	Ahead  int
+	Behind int
+}
+
+type RepoForksParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Forks        []Fork
+	Page         pagination.Page
+	Total        int
+}
+
+func (p *Pages) RepoForks(w io.Writer, params RepoForksParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/forks", w, params)
+}
+
+// ForkNode is a fork in the fork network, Hidden counts its forks that
+// were left out to keep the network small.
+type ForkNode struct {
+	Fork
+	Children []*ForkNode
+	Hidden   int
+}
+
+type RepoForkNetworkParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Network      []*ForkNode
+	Hidden       int
+	Total        int
+}
+
+func (p *Pages) RepoForkNetwork(w io.Writer, params RepoForkNetworkParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/forknetwork", w, params)
+}
+
+// Contributor is a commit author, Did is empty when their email isn't
+// verified by anyone.
+type Contributor struct {
+	Name        string
+	Did         string
+	Commits     int64
+	Additions   int64
+	Deletions   int64
+	FirstCommit time.Time
+	LastCommit  time.Time
+}
+
+type RepoContributorsParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Contributors []Contributor
+	Page         pagination.Page
+	Total        int
+}
+
+func (p *Pages) RepoContributors(w io.Writer, params RepoContributorsParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/contributors", w, params)
+}
+
 type RepoArtifactParams struct {
 	LoggedInUser *oauth.User
 	RepoInfo     repoinfo.RepoInfo
@@ -818,11 +1251,20 @@ type RepoBlobParams struct {
 	BreadCrumbs  [][]string
 	BlobView     models.BlobView
 	*tangled.RepoBlob_Output
+
+	// for tables, the whole table and the rows on this page
+	Table     *markup.Table
+	TableRows [][]string
+	Page      pagination.Page
+
+	// whether the file can be edited or deleted in the browser
+	CanEdit   bool
+	CanDelete bool
 }
 
 func (p *Pages) RepoBlob(w io.Writer, params RepoBlobParams) error {
 	switch params.BlobView.ContentType {
-	case models.BlobContentTypeMarkup:
+	case models.BlobContentTypeMarkup, models.BlobContentTypeNotebook:
 		p.rctx.RepoInfo = params.RepoInfo
 	}
 
@@ -830,6 +1272,57 @@ func (p *Pages) RepoBlob(w io.Writer, params RepoBlobParams) error {
 	return p.executeRepo("repo/blob", w, params)
 }
 
+type RepoEditFileParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Ref          string
+	Path         string
+	Content      string
+	// the blob being edited, so that edits don't overwrite newer changes
+	Blob  string
+	IsNew bool
+	// false for users whose changes are proposed in a pull instead
+	CanCommit bool
+}
+
+func (p *Pages) RepoEditFile(w io.Writer, params RepoEditFileParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/editFile", w, params)
+}
+
+type RepoDeleteFileParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Ref          string
+	Path         string
+	Blob         string
+	CanCommit    bool
+}
+
+func (p *Pages) RepoDeleteFile(w io.Writer, params RepoDeleteFileParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/deleteFile", w, params)
+}
+
+type RepoUploadFilesParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Ref          string
+	Dir          string
+	CanCommit    bool
+	MaxFileBytes uint64
+	MaxBytes     uint64
+	MaxFiles     int
+}
+
+func (p *Pages) RepoUploadFiles(w io.Writer, params RepoUploadFilesParams) error {
+	params.Active = "overview"
+	return p.executeRepo("repo/uploadFiles", w, params)
+}
+
 type Collaborator struct {
 	Did    string
 	Handle string
@@ -856,6 +1349,30 @@ func (p *Pages) RepoSettings(w io.Writer, params RepoSettingsParams) error {
 }
 
 type RepoGeneralSettingsParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Tabs         []map[string]any
+	Tab          string
+	Branches     []types.Branch
+	Transfer     *models.RepoTransfer
+	// destructive actions ask for a code instead of the repo name
+	TotpEnrolled bool
+
+	DeleteBranchOnMerge bool
+
+	// bytes the repo takes up on its knot, 0 when the knot can't say
+	RepoSize uint64
+	// the owner's usage of the knot, only shown to them
+	Storage *models.StorageUsage
+}
+
+func (p *Pages) RepoGeneralSettings(w io.Writer, params RepoGeneralSettingsParams) error {
+	params.Active = "settings"
+	return p.executeRepo("repo/settings/general", w, params)
+}
+
+type RepoLabelSettingsParams struct {
 	LoggedInUser       *oauth.User
 	RepoInfo           repoinfo.RepoInfo
 	Labels             []models.LabelDefinition
@@ -865,12 +1382,21 @@ type RepoGeneralSettingsParams struct {
 	Active             string
 	Tabs               []map[string]any
 	Tab                string
-	Branches           []types.Branch
 }
 
-func (p *Pages) RepoGeneralSettings(w io.Writer, params RepoGeneralSettingsParams) error {
+func (p *Pages) RepoLabelSettings(w io.Writer, params RepoLabelSettingsParams) error {
 	params.Active = "settings"
-	return p.executeRepo("repo/settings/general", w, params)
+	return p.executeRepo("repo/settings/labels", w, params)
+}
+
+type RepoTransferParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Transfer     *models.RepoTransfer
+}
+
+func (p *Pages) RepoTransfer(w io.Writer, params RepoTransferParams) error {
+	return p.execute("repo/transfer", w, params)
 }
 
 type RepoAccessSettingsParams struct {
@@ -888,14 +1414,19 @@ func (p *Pages) RepoAccessSettings(w io.Writer, params RepoAccessSettingsParams)
 }
 
 type RepoPipelineSettingsParams struct {
-	LoggedInUser   *oauth.User
-	RepoInfo       repoinfo.RepoInfo
-	Active         string
-	Tabs           []map[string]any
-	Tab            string
-	Spindles       []string
-	CurrentSpindle string
-	Secrets        []map[string]any
+	LoggedInUser     *oauth.User
+	RepoInfo         repoinfo.RepoInfo
+	Active           string
+	Tabs             []map[string]any
+	Tab              string
+	Spindles         []string
+	CurrentSpindle   string
+	PipelinesOn      bool
+	Concurrency      int // 0 when the spindle picks the limit
+	CancelSuperseded bool
+	RequirePassing   bool
+	Secrets          []map[string]any
+	RequiredChecks   []models.RequiredCheck
 }
 
 func (p *Pages) RepoPipelineSettings(w io.Writer, params RepoPipelineSettingsParams) error {
@@ -903,6 +1434,22 @@ func (p *Pages) RepoPipelineSettings(w io.Writer, params RepoPipelineSettingsPar
 	return p.executeRepo("repo/settings/pipelines", w, params)
 }
 
+type RepoWebhookSettingsParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Active       string
+	Tabs         []map[string]any
+	Tab          string
+	Webhooks     []models.Webhook
+	Webhook      *models.Webhook
+	Deliveries   []models.WebhookDelivery
+}
+
+func (p *Pages) RepoWebhookSettings(w io.Writer, params RepoWebhookSettingsParams) error {
+	params.Active = "settings"
+	return p.executeRepo("repo/settings/webhooks", w, params)
+}
+
 type RepoIssuesParams struct {
 	LoggedInUser    *oauth.User
 	RepoInfo        repoinfo.RepoInfo
@@ -926,11 +1473,17 @@ type RepoSingleIssueParams struct {
 	Active       string
 	Issue        *models.Issue
 	CommentList  []models.CommentListItem
+	Timeline     []models.TimelineItem
 	LabelDefs    map[string]*models.LabelDefinition
 
 	OrderedReactionKinds []models.ReactionKind
 	Reactions            map[models.ReactionKind]models.ReactionDisplayData
 	UserReacted          map[models.ReactionKind]bool
+	CommentReactions     map[syntax.ATURI]map[models.ReactionKind]models.ReactionDisplayData
+	CommentUserReacted   map[syntax.ATURI]map[models.ReactionKind]bool
+
+	// issues and pulls that mention this one
+	Backlinks []models.Reference
 }
 
 func (p *Pages) RepoSingleIssue(w io.Writer, params RepoSingleIssueParams) error {
@@ -962,10 +1515,21 @@ func (p *Pages) ThreadReactionFragment(w io.Writer, params ThreadReactionFragmen
 	return p.executePlain("repo/fragments/reaction", w, params)
 }
 
+type IssueBodyParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Issue        *models.Issue
+}
+
+func (p *Pages) IssueBodyFragment(w io.Writer, params IssueBodyParams) error {
+	return p.executePlain("repo/issues/fragments/issueBody", w, params)
+}
+
 type RepoNewIssueParams struct {
 	LoggedInUser *oauth.User
 	RepoInfo     repoinfo.RepoInfo
 	Issue        *models.Issue // existing issue if any -- passed when editing
+	LabelPanel   *EditLabelPanelParams
 	Active       string
 	Action       string
 }
@@ -1029,6 +1593,7 @@ type RepoNewPullParams struct {
 	TargetBranch string
 	Title        string
 	Body         string
+	LabelPanel   *EditLabelPanelParams
 	Active       string
 }
 
@@ -1041,12 +1606,16 @@ type RepoPullsParams struct {
 	LoggedInUser *oauth.User
 	RepoInfo     repoinfo.RepoInfo
 	Pulls        []*models.Pull
+	PullCount    int
+	Page         pagination.Page
 	Active       string
 	FilteringBy  models.PullState
 	FilterQuery  string
 	Stacks       map[string]models.Stack
 	Pipelines    map[string]models.Pipeline
 	LabelDefs    map[string]*models.LabelDefinition
+	// stats of the latest round of each pull, by pull id
+	Stats map[int]types.PatchStat
 }
 
 func (p *Pages) RepoPulls(w io.Writer, params RepoPullsParams) error {
@@ -1072,6 +1641,16 @@ func (r ResubmitResult) Unknown() bool {
 	return r == Unknown
 }
 
+type PullBodyParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Pull         *models.Pull
+}
+
+func (p *Pages) PullBodyFragment(w io.Writer, params PullBodyParams) error {
+	return p.executePlain("repo/pulls/fragments/pullBody", w, params)
+}
+
 type RepoSinglePullParams struct {
 	LoggedInUser       *oauth.User
 	RepoInfo           repoinfo.RepoInfo
@@ -1080,15 +1659,33 @@ type RepoSinglePullParams struct {
 	Stack              models.Stack
 	AbandonedPulls     []*models.Pull
 	BranchDeleteStatus *models.BranchDeleteStatus
+	BranchDeletion     *models.BranchDeletion
 	MergeCheck         types.MergeCheckResponse
 	ResubmitCheck      ResubmitResult
-	Pipelines          map[string]models.Pipeline
+	// why the pull waits for its pipelines before it can merge
+	PipelineBlockers []string
+	Pipelines        map[string]models.Pipeline
+	CommitStatuses   map[string][]models.CommitStatus
+	// stats of the latest round
+	Stats types.PatchStat
 
 	OrderedReactionKinds []models.ReactionKind
 	Reactions            map[models.ReactionKind]models.ReactionDisplayData
 	UserReacted          map[models.ReactionKind]bool
+	CommentReactions     map[syntax.ATURI]map[models.ReactionKind]models.ReactionDisplayData
+	CommentUserReacted   map[syntax.ATURI]map[models.ReactionKind]bool
+
+	// issues and pulls that mention this one
+	Backlinks []models.Reference
+
+	// pulls this one depends on, and the ones that depend on it
+	Dependencies []models.PullDependency
+	Dependents   []models.Reference
 
 	LabelDefs map[string]*models.LabelDefinition
+
+	// comments and events of each round
+	Timelines [][]models.TimelineItem
 }
 
 func (p *Pages) RepoSinglePull(w io.Writer, params RepoSinglePullParams) error {
@@ -1118,6 +1715,7 @@ type RepoPullInterdiffParams struct {
 	RepoInfo             repoinfo.RepoInfo
 	Pull                 *models.Pull
 	Round                int
+	From                 int
 	Interdiff            *patchutil.InterdiffResult
 	OrderedReactionKinds []models.ReactionKind
 	DiffOpts             types.DiffOpts
@@ -1146,6 +1744,15 @@ func (p *Pages) PullCompareBranchesFragment(w io.Writer, params PullCompareBranc
 	return p.executePlain("repo/pulls/fragments/pullCompareBranches", w, params)
 }
 
+type PullCommitPreviewParams struct {
+	RepoInfo repoinfo.RepoInfo
+	Commits  []types.CompareCommit
+}
+
+func (p *Pages) PullCommitPreviewFragment(w io.Writer, params PullCommitPreviewParams) error {
+	return p.executePlain("repo/pulls/fragments/pullCommitPreview", w, params)
+}
+
 type PullCompareForkParams struct {
 	RepoInfo repoinfo.RepoInfo
 	Forks    []models.Repo
@@ -1177,6 +1784,17 @@ func (p *Pages) PullResubmitFragment(w io.Writer, params PullResubmitParams) err
 	return p.executePlain("repo/pulls/fragments/pullResubmit", w, params)
 }
 
+type PullStackReorderParams struct {
+	LoggedInUser *oauth.User
+	RepoInfo     repoinfo.RepoInfo
+	Pull         *models.Pull
+	Stack        models.Stack
+}
+
+func (p *Pages) PullStackReorderFragment(w io.Writer, params PullStackReorderParams) error {
+	return p.executePlain("repo/pulls/fragments/pullStackReorder", w, params)
+}
+
 type PullActionsParams struct {
 	LoggedInUser       *oauth.User
 	RepoInfo           repoinfo.RepoInfo
@@ -1185,6 +1803,7 @@ type PullActionsParams struct {
 	MergeCheck         types.MergeCheckResponse
 	ResubmitCheck      ResubmitResult
 	BranchDeleteStatus *models.BranchDeleteStatus
+	PipelineBlockers   []string
 	Stack              models.Stack
 }
 
@@ -1213,6 +1832,7 @@ type RepoCompareParams struct {
 	Head         string
 	Diff         *types.NiceDiff
 	DiffOpts     types.DiffOpts
+	Commits      []types.CompareCommit
 
 	Active string
 }
@@ -1334,6 +1954,7 @@ type WorkflowParams struct {
 	Workflow     string
 	LogUrl       string
 	Active       string
+	Artifacts    []models.PipelineArtifact
 }
 
 func (p *Pages) Workflow(w io.Writer, params WorkflowParams) error {
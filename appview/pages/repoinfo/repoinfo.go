@@ -56,10 +56,13 @@ type RepoInfo struct {
 	Description  string
 	Website      string
 	Topics       []string
+	IsPrivate    bool
+	IsTemplate   bool
 	Knot         string
 	Spindle      string
 	RepoAt       syntax.ATURI
 	IsStarred    bool
+	IsWatching   bool
 	Stats        models.RepoStats
 	Roles        RolesInRepo
 	Source       *models.Repo
@@ -114,3 +117,13 @@ func (r RolesInRepo) IsCollaborator() bool {
 func (r RolesInRepo) IsPushAllowed() bool {
 	return slices.Contains(r.Roles, "repo:push")
 }
+
+func (r RolesInRepo) IsMergeAllowed() bool {
+	return slices.Contains(r.Roles, "repo:merge")
+}
+
+// IsTriageAllowed reports whether issues and pulls can be labelled, closed
+// and reopened.
+func (r RolesInRepo) IsTriageAllowed() bool {
+	return slices.Contains(r.Roles, "repo:triage")
+}
@@ -0,0 +1,91 @@
+package pipelines
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"tangled.org/core/appview/models"
+)
+
+type badgeStatus struct {
+	message string
+	color   string
+}
+
+var (
+	badgePassing = badgeStatus{"passing", "#16a34a"}
+	badgeFailing = badgeStatus{"failing", "#dc2626"}
+	badgeRunning = badgeStatus{"running", "#ca8a04"}
+	// a pipeline stopped before it could pass or fail
+	badgeCancelled = badgeStatus{"cancelled", "#9ca3af"}
+	badgeUnknown   = badgeStatus{"unknown", "#6b7280"}
+)
+
+// statusOf summarizes the latest status of every workflow in a pipeline.
+func statusOf(p *models.Pipeline) badgeStatus {
+	if p == nil || !p.IsResponding() {
+		return badgeUnknown
+	}
+
+	counts := p.Counts()
+	switch {
+	case counts["failed"] > 0 || counts["timeout"] > 0:
+		return badgeFailing
+	case counts["pending"] > 0 || counts["queued"] > 0 || counts["running"] > 0:
+		return badgeRunning
+	case counts["cancelled"] > 0:
+		return badgeCancelled
+	case counts["success"] == len(p.Statuses):
+		return badgePassing
+	default:
+		return badgeUnknown
+	}
+}
+
+// textWidth roughly approximates the width of s in 11px Verdana, which is
+// close enough to size the badge without shipping font metrics.
+func textWidth(s string) int {
+	return len(s)*7 + 10
+}
+
+// writeBadge renders a shields.io-like badge. style is either "flat" (the
+// default) or "flat-square".
+func writeBadge(w io.Writer, label string, status badgeStatus, style string) error {
+	labelWidth := textWidth(label)
+	messageWidth := textWidth(status.message)
+	width := labelWidth + messageWidth
+
+	radius := 3
+	if style == "flat-square" {
+		radius = 0
+	}
+
+	label = html.EscapeString(label)
+	message := html.EscapeString(status.message)
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+  <title>%[4]s: %[5]s</title>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="%[6]d" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[3]d" height="20" fill="%[7]s"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[8]d" y="14">%[4]s</text>
+    <text x="%[9]d" y="14">%[5]s</text>
+  </g>
+</svg>
+`,
+		width,
+		labelWidth,
+		messageWidth,
+		label,
+		message,
+		radius,
+		status.color,
+		labelWidth/2,
+		labelWidth+messageWidth/2,
+	)
+	return err
+}
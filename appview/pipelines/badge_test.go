@@ -0,0 +1,41 @@
+package pipelines
+
+import (
+	"testing"
+
+	"tangled.org/core/appview/models"
+	spindle "tangled.org/core/spindle/models"
+)
+
+func TestStatusOf(t *testing.T) {
+	pipeline := func(statuses ...spindle.StatusKind) *models.Pipeline {
+		p := &models.Pipeline{Statuses: map[string]models.WorkflowStatus{}}
+		for i, s := range statuses {
+			p.Statuses[string(rune('a'+i))] = models.WorkflowStatus{
+				Data: []models.PipelineStatus{{Status: s}},
+			}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		pipeline *models.Pipeline
+		want     badgeStatus
+	}{
+		{"none", nil, badgeUnknown},
+		{"no workflows", pipeline(), badgeUnknown},
+		{"passing", pipeline(spindle.StatusKindSuccess, spindle.StatusKindSuccess), badgePassing},
+		{"failing", pipeline(spindle.StatusKindSuccess, spindle.StatusKindFailed), badgeFailing},
+		{"timed out", pipeline(spindle.StatusKindTimeout, spindle.StatusKindCancelled), badgeFailing},
+		{"running", pipeline(spindle.StatusKindSuccess, spindle.StatusKindRunning), badgeRunning},
+		{"cancelled", pipeline(spindle.StatusKindSuccess, spindle.StatusKindCancelled), badgeCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusOf(tt.pipeline); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
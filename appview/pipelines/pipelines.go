@@ -4,22 +4,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
 	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/eventconsumer"
 	"tangled.org/core/idresolver"
 	"tangled.org/core/rbac"
 	spindlemodel "tangled.org/core/spindle/models"
+	"tangled.org/core/tid"
+	"tangled.org/core/workflow"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/gorilla/websocket"
 )
 
@@ -35,11 +46,20 @@ type Pipelines struct {
 	logger        *slog.Logger
 }
 
-func (p *Pipelines) Router() http.Handler {
+func (p *Pipelines) Router(mw *middleware.Middleware) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", p.Index)
+	r.Get("/badge.svg", p.Badge)
 	r.Get("/{pipeline}/workflow/{workflow}", p.Workflow)
 	r.Get("/{pipeline}/workflow/{workflow}/logs", p.Logs)
+	r.Get("/{pipeline}/workflow/{workflow}/artifacts/*", p.DownloadArtifact)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(p.oauth))
+		r.Use(mw.RepoPermissionMiddleware("repo:push"))
+		r.Post("/{pipeline}/rerun", p.Rerun)
+		r.Post("/{pipeline}/cancel", p.Cancel)
+	})
 
 	return r
 }
@@ -143,35 +163,407 @@ func (p *Pipelines) Workflow(w http.ResponseWriter, r *http.Request) {
 
 	singlePipeline := ps[0]
 
+	// a matrix workflow has no logs of its own, show its first job instead
+	if jobs := singlePipeline.Jobs(workflow); len(jobs) > 0 {
+		http.Redirect(w, r, fmt.Sprintf("/%s/pipelines/%s/workflow/%s", repoInfo.FullName(), pipelineId, url.PathEscape(jobs[0].Name)), http.StatusFound)
+		return
+	}
+
+	artifacts, err := db.GetPipelineArtifacts(
+		p.db,
+		db.FilterEq("pipeline_knot", singlePipeline.Knot),
+		db.FilterEq("pipeline_rkey", singlePipeline.Rkey),
+		db.FilterEq("workflow", workflow),
+	)
+	if err != nil {
+		l.Error("failed to get artifacts", "err", err)
+		// non-fatal
+	}
+
 	p.pages.Workflow(w, pages.WorkflowParams{
 		LoggedInUser: user,
 		RepoInfo:     repoInfo,
 		Pipeline:     singlePipeline,
 		Workflow:     workflow,
+		Artifacts:    artifacts,
 	})
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+// DownloadArtifact passes through an artifact from the spindle that kept it.
+// Artifacts are as public as the repo they were built from, those of private
+// repos are fetched with a service auth token of the viewer.
+func (p *Pipelines) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	user := p.oauth.GetUser(r)
+	l := p.logger.With("handler", "DownloadArtifact")
+
+	f, err := p.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		http.Error(w, "repo not found", http.StatusNotFound)
+		return
+	}
+
+	repoInfo := f.RepoInfo(user)
+
+	pipelineId := chi.URLParam(r, "pipeline")
+	workflow := chi.URLParam(r, "workflow")
+	name, err := url.PathUnescape(chi.URLParam(r, "*"))
+	if err != nil || name == "" {
+		http.Error(w, "invalid artifact name", http.StatusBadRequest)
+		return
+	}
+
+	ps, err := db.GetPipelines(
+		p.db,
+		db.FilterEq("repo_owner", repoInfo.OwnerDid),
+		db.FilterEq("repo_name", repoInfo.Name),
+		db.FilterEq("knot", repoInfo.Knot),
+		db.FilterEq("id", pipelineId),
+	)
+	if err != nil || len(ps) != 1 {
+		l.Error("pipeline query failed", "err", err, "count", len(ps))
+		http.Error(w, "pipeline not found", http.StatusNotFound)
+		return
+	}
+
+	artifacts, err := db.GetPipelineArtifacts(
+		p.db,
+		db.FilterEq("pipeline_knot", ps[0].Knot),
+		db.FilterEq("pipeline_rkey", ps[0].Rkey),
+		db.FilterEq("workflow", workflow),
+		db.FilterEq("name", name),
+	)
+	if err != nil || len(artifacts) != 1 {
+		l.Error("artifact query failed", "err", err, "count", len(artifacts))
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	artifact := artifacts[0]
+
+	scheme := "https"
+	if p.config.Core.Dev {
+		scheme = "http"
+	}
+	wid := spindlemodel.WorkflowId{
+		PipelineId: spindlemodel.PipelineId{
+			Knot: artifact.PipelineKnot,
+			Rkey: artifact.PipelineRkey,
+		},
+		Name: artifact.Workflow,
+	}
+	artifactUrl := scheme + "://" + artifact.Spindle + spindlemodel.ArtifactPath(wid, artifact.Name)
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, artifactUrl, nil)
+	if err != nil {
+		l.Error("failed to create request", "err", err)
+		http.Error(w, "failed to fetch artifact", http.StatusInternalServerError)
+		return
+	}
+	req.Header, err = p.spindleHeader(r, f, artifact.Spindle)
+	if err != nil {
+		l.Error("failed to get service token", "err", err)
+		http.Error(w, "failed to authorize with the spindle", http.StatusBadGateway)
+		return
+	}
+
+	// no timeout, artifacts may take long to pass through, and the request
+	// ends with the one of the viewer anyway
+	client := xrpcclient.HTTPClient(p.config.KnotClient, 0, true)
+	resp, err := client.Do(req)
+	if err != nil {
+		l.Error("failed to fetch artifact", "url", artifactUrl, "err", err)
+		http.Error(w, "failed to reach spindle", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		l.Error("spindle returned an error", "url", artifactUrl, "status", resp.StatusCode)
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	for _, h := range []string{"Content-Type", "Content-Length", "Content-Disposition", "Last-Modified"} {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		l.Error("failed to stream artifact", "err", err)
+	}
 }
 
-func (p *Pipelines) Logs(w http.ResponseWriter, r *http.Request) {
-	l := p.logger.With("handler", "logs")
+// spindleHeader returns the headers to reach the spindle of f with. Logs and
+// artifacts of private repos are only given out for a service auth token of
+// the viewer.
+func (p *Pipelines) spindleHeader(r *http.Request, f *reporesolver.ResolvedRepo, spindle string) (http.Header, error) {
+	header := http.Header{}
+	if !f.IsPrivate() {
+		return header, nil
+	}
+
+	token, err := p.oauth.ServiceToken(r, oauth.WithService(spindle), oauth.WithDev(p.config.Core.Dev))
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return header, nil
+}
+
+// Badge renders the status of the latest pipeline run by a push to a branch,
+// the default branch unless ?branch= is given, as an SVG to embed in READMEs.
+func (p *Pipelines) Badge(w http.ResponseWriter, r *http.Request) {
+	l := p.logger.With("handler", "Badge")
 
-	clientConn, err := upgrader.Upgrade(w, r, nil)
+	f, err := p.repoResolver.Resolve(r)
 	if err != nil {
-		l.Error("websocket upgrade failed", "err", err)
+		l.Error("failed to get repo and knot", "err", err)
+		http.Error(w, "repo not found", http.StatusNotFound)
 		return
 	}
-	defer func() {
-		_ = clientConn.WriteControl(
-			websocket.CloseMessage,
-			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "log stream complete"),
-			time.Now().Add(time.Second),
+
+	status := badgeUnknown
+
+	branch := r.URL.Query().Get("branch")
+	if branch == "" {
+		scheme := "http"
+		if !p.config.Core.Dev {
+			scheme = "https"
+		}
+		xrpcc := xrpcclient.NewClient(p.config.KnotClient, fmt.Sprintf("%s://%s", scheme, f.Knot))
+
+		repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+		out, err := tangled.RepoGetDefaultBranch(r.Context(), xrpcc, repo)
+		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+			l.Error("failed to call XRPC repo.getDefaultBranch", "err", xrpcerr)
+		} else {
+			branch = out.Name
+		}
+	}
+
+	if branch != "" {
+		ps, err := db.GetPipelineStatuses(
+			p.db,
+			1,
+			db.FilterEq("repo_owner", f.Did),
+			db.FilterEq("repo_name", f.Name),
+			db.FilterEq("knot", f.Knot),
+			db.FilterEq("t.kind", workflow.TriggerKindPush),
+			db.FilterEq("t.push_ref", plumbing.NewBranchReferenceName(branch).String()),
 		)
-		clientConn.Close()
-	}()
+		if err != nil {
+			l.Error("failed to query db", "err", err)
+		} else if len(ps) == 1 {
+			status = statusOf(&ps[0])
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if f.IsPrivate() {
+		// only those who can read the repo may see its badge, so it must
+		// not be kept where others could get it
+		w.Header().Set("Cache-Control", "private, no-store")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=60") // 1 minute
+	}
+	if err := writeBadge(w, "pipeline", status, r.URL.Query().Get("style")); err != nil {
+		l.Error("failed to write badge", "err", err)
+	}
+}
+
+// Rerun asks the spindle to run the workflows of a finished pipeline again.
+// The new run is recorded as a pipeline of its own, pointing back to the
+// original, so that earlier runs stay around.
+func (p *Pipelines) Rerun(w http.ResponseWriter, r *http.Request) {
+	user := p.oauth.GetUser(r)
+	l := p.logger.With("handler", "Rerun")
+	noticeId := "pipeline-rerun-error"
+
+	f, err := p.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	repoInfo := f.RepoInfo(user)
+
+	pipelineId := chi.URLParam(r, "pipeline")
+	ps, err := db.GetPipelineStatuses(
+		p.db,
+		1,
+		db.FilterEq("repo_owner", repoInfo.OwnerDid),
+		db.FilterEq("repo_name", repoInfo.Name),
+		db.FilterEq("knot", repoInfo.Knot),
+		db.FilterEq("id", pipelineId),
+	)
+	if err != nil || len(ps) != 1 {
+		l.Error("pipeline query failed", "err", err, "count", len(ps))
+		p.pages.Notice(w, noticeId, "Failed to find this pipeline.")
+		return
+	}
+	original := ps[0]
+
+	if !original.IsFinished() {
+		p.pages.Notice(w, noticeId, "This pipeline is still running.")
+		return
+	}
+
+	if f.Spindle == "" {
+		p.pages.Notice(w, noticeId, "No spindle is configured for this repository.")
+		return
+	}
+
+	spindleClient, err := p.oauth.ServiceClient(
+		r,
+		oauth.WithService(f.Spindle),
+		oauth.WithLxm(tangled.PipelineRerunNSID),
+		oauth.WithoutRetry(),
+		oauth.WithExp(60),
+		oauth.WithDev(p.config.Core.Dev),
+	)
+	if err != nil {
+		l.Error("failed to create spindle client", "err", err)
+		p.pages.Notice(w, noticeId, "Failed to authorize with the spindle, try again later.")
+		return
+	}
+
+	// the rerun is recorded before the spindle is asked to run it, so that
+	// its statuses have a pipeline to belong to
+	rerun := models.Pipeline{
+		Rkey:      tid.TID(),
+		Knot:      original.Knot,
+		RepoOwner: original.RepoOwner,
+		RepoName:  original.RepoName,
+		TriggerId: original.TriggerId,
+		Sha:       original.Sha,
+		RerunOf:   &original.Id,
+	}
+	err = db.AddPipeline(p.db, rerun)
+	if err != nil {
+		l.Error("failed to add pipeline", "err", err)
+		p.pages.Notice(w, noticeId, "Failed to run pipeline again, try again later.")
+		return
+	}
+
+	err = tangled.PipelineRerun(
+		r.Context(),
+		spindleClient,
+		&tangled.PipelineRerun_Input{
+			Pipeline: original.AtUri().String(),
+			Rkey:     rerun.Rkey,
+		},
+	)
+	if err != nil {
+		l.Error("failed to run pipeline again", "spindle", f.Spindle, "err", err)
+		if err := db.DeletePipelines(
+			p.db,
+			db.FilterEq("knot", rerun.Knot),
+			db.FilterEq("rkey", rerun.Rkey),
+		); err != nil {
+			l.Error("failed to clean up pipeline", "err", err)
+		}
+		p.pages.Notice(w, noticeId, fmt.Sprintf("Failed to reach %s, try again later.", f.Spindle))
+		return
+	}
+
+	created, err := db.GetPipelines(
+		p.db,
+		db.FilterEq("knot", rerun.Knot),
+		db.FilterEq("rkey", rerun.Rkey),
+	)
+	if err != nil || len(created) != 1 {
+		l.Error("failed to get pipeline", "err", err)
+		p.pages.HxLocation(w, fmt.Sprintf("/%s/pipelines", repoInfo.FullName()))
+		return
+	}
+
+	workflows := original.Workflows()
+	p.pages.HxLocation(w, fmt.Sprintf("/%s/pipelines/%d/workflow/%s", repoInfo.FullName(), created[0].Id, workflows[0]))
+}
+
+// Cancel asks the spindle to stop the workflows of a pipeline that is still
+// queued or running. The cancelled statuses arrive through the spindle event
+// stream like any other.
+func (p *Pipelines) Cancel(w http.ResponseWriter, r *http.Request) {
+	user := p.oauth.GetUser(r)
+	l := p.logger.With("handler", "Cancel")
+	noticeId := "pipeline-cancel-error"
+
+	f, err := p.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	repoInfo := f.RepoInfo(user)
+
+	pipelineId := chi.URLParam(r, "pipeline")
+	ps, err := db.GetPipelineStatuses(
+		p.db,
+		1,
+		db.FilterEq("repo_owner", repoInfo.OwnerDid),
+		db.FilterEq("repo_name", repoInfo.Name),
+		db.FilterEq("knot", repoInfo.Knot),
+		db.FilterEq("id", pipelineId),
+	)
+	if err != nil || len(ps) != 1 {
+		l.Error("pipeline query failed", "err", err, "count", len(ps))
+		p.pages.Notice(w, noticeId, "Failed to find this pipeline.")
+		return
+	}
+	pipeline := ps[0]
+
+	if pipeline.IsFinished() {
+		p.pages.Notice(w, noticeId, "This pipeline has already finished.")
+		return
+	}
+
+	if f.Spindle == "" {
+		p.pages.Notice(w, noticeId, "No spindle is configured for this repository.")
+		return
+	}
+
+	spindleClient, err := p.oauth.ServiceClient(
+		r,
+		oauth.WithService(f.Spindle),
+		oauth.WithLxm(tangled.PipelineCancelNSID),
+		oauth.WithExp(60),
+		oauth.WithDev(p.config.Core.Dev),
+	)
+	if err != nil {
+		l.Error("failed to create spindle client", "err", err)
+		p.pages.Notice(w, noticeId, "Failed to authorize with the spindle, try again later.")
+		return
+	}
+
+	err = tangled.PipelineCancel(
+		r.Context(),
+		spindleClient,
+		&tangled.PipelineCancel_Input{
+			Pipeline: pipeline.AtUri().String(),
+		},
+	)
+	if err != nil {
+		l.Error("failed to cancel pipeline", "spindle", f.Spindle, "err", err)
+		p.pages.Notice(w, noticeId, fmt.Sprintf("Failed to cancel pipeline on %s, try again later.", f.Spindle))
+		return
+	}
+
+	p.pages.HxRefresh(w)
+}
+
+// Logs streams the rendered logs of a workflow as server-sent events, each
+// event carrying an htmx out-of-band fragment. Events are numbered so that a
+// dropped stream can be resumed through Last-Event-ID, or ?after=.
+//
+// With ?poll, the lines available within a couple of seconds are returned
+// as a single response instead, for clients that can't keep a stream open.
+// X-Log-Next holds the number to continue from and X-Log-Done is set once
+// the workflow is finished.
+func (p *Pipelines) Logs(w http.ResponseWriter, r *http.Request) {
+	l := p.logger.With("handler", "logs")
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
@@ -217,16 +609,41 @@ func (p *Pipelines) Logs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// lines up to and including this one were already sent
+	after := r.URL.Query().Get("after")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		after = id
+	}
+	skip, _ := strconv.Atoi(after)
+
+	_, poll := r.URL.Query()["poll"]
+
+	var flusher http.Flusher
+	if !poll {
+		var ok bool
+		if flusher, ok = w.(http.Flusher); !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	scheme := "wss"
 	if p.config.Core.Dev {
 		scheme = "ws"
 	}
 
-	url := scheme + "://" + strings.Join([]string{spindle, "logs", knot, rkey, workflow}, "/")
+	url := scheme + "://" + strings.Join([]string{spindle, "logs", knot, rkey, url.PathEscape(workflow)}, "/")
 	l = l.With("url", url)
 	l.Info("logs endpoint hit")
 
-	spindleConn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	header, err := p.spindleHeader(r, f, spindle)
+	if err != nil {
+		l.Error("failed to get service token", "err", err)
+		http.Error(w, "failed to authorize with the spindle", http.StatusBadGateway)
+		return
+	}
+
+	spindleConn, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
 	if err != nil {
 		l.Error("websocket dial failed", "err", err)
 		http.Error(w, "failed to connect to log stream", http.StatusBadGateway)
@@ -239,8 +656,20 @@ func (p *Pipelines) Logs(w http.ResponseWriter, r *http.Request) {
 	// start a goroutine to read from spindle
 	go readLogs(spindleConn, evChan)
 
+	if poll {
+		p.pollLogs(ctx, l, w, evChan, skip)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
 	stepStartTimes := make(map[int]time.Time)
 	var fragment bytes.Buffer
+	n := 0
 	for {
 		select {
 		case <-ctx.Done():
@@ -249,79 +678,157 @@ func (p *Pipelines) Logs(w http.ResponseWriter, r *http.Request) {
 
 		case ev, ok := <-evChan:
 			if !ok {
-				continue
+				return
 			}
 
 			if ev.err != nil && ev.isCloseError() {
-				l.Debug("graceful shutdown, tail complete", "err", err)
+				l.Debug("graceful shutdown, tail complete", "err", ev.err)
+				// the workflow is finished, tell the client not to reconnect
+				fmt.Fprint(w, "event: done\ndata:\n\n")
+				flusher.Flush()
 				return
 			}
 			if ev.err != nil {
-				l.Error("error reading from spindle", "err", err)
+				l.Error("error reading from spindle", "err", ev.err)
 				return
 			}
 
-			var logLine spindlemodel.LogLine
-			if err = json.Unmarshal(ev.msg, &logLine); err != nil {
-				l.Error("failed to parse logline", "err", err)
+			fragment.Reset()
+			if err := p.renderLogLine(&fragment, ev.msg, stepStartTimes); err != nil {
+				l.Error("failed to render log line", "err", err)
 				continue
 			}
 
-			fragment.Reset()
-
-			switch logLine.Kind {
-			case spindlemodel.LogKindControl:
-				switch logLine.StepStatus {
-				case spindlemodel.StepStatusStart:
-					stepStartTimes[logLine.StepId] = logLine.Time
-					collapsed := false
-					if logLine.StepKind == spindlemodel.StepKindSystem {
-						collapsed = true
-					}
-					err = p.pages.LogBlock(&fragment, pages.LogBlockParams{
-						Id:        logLine.StepId,
-						Name:      logLine.Content,
-						Command:   logLine.StepCommand,
-						Collapsed: collapsed,
-						StartTime: logLine.Time,
-					})
-				case spindlemodel.StepStatusEnd:
-					startTime := stepStartTimes[logLine.StepId]
-					endTime := logLine.Time
-					err = p.pages.LogBlockEnd(&fragment, pages.LogBlockEndParams{
-						Id:        logLine.StepId,
-						StartTime: startTime,
-						EndTime:   endTime,
-					})
-				}
-
-			case spindlemodel.LogKindData:
-				// data messages simply insert new log lines into current step
-				err = p.pages.LogLine(&fragment, pages.LogLineParams{
-					Id:      logLine.StepId,
-					Content: logLine.Content,
-				})
-			}
-			if err != nil {
-				l.Error("failed to render log line", "err", err)
-				return
+			// every line is replayed, so that step timings are known
+			n++
+			if n <= skip {
+				continue
 			}
 
-			if err = clientConn.WriteMessage(websocket.TextMessage, fragment.Bytes()); err != nil {
+			if err := writeEvent(w, n, fragment.Bytes()); err != nil {
 				l.Error("error writing to client", "err", err)
 				return
 			}
+			flusher.Flush()
 
 		case <-time.After(30 * time.Second):
 			l.Debug("sent keepalive")
-			if err = clientConn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(time.Second)); err != nil {
-				l.Error("failed to write control", "err", err)
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				l.Error("failed to write keepalive", "err", err)
 				return
 			}
+			flusher.Flush()
 		}
 	}
 }
 
+// pollLogs collects the rendered lines after skip that arrive within a short
+// window, and writes them out as one response.
+func (p *Pipelines) pollLogs(ctx context.Context, l *slog.Logger, w http.ResponseWriter, evChan chan logEvent, skip int) {
+	stepStartTimes := make(map[int]time.Time)
+	var out bytes.Buffer
+	n := 0
+	done := false
+
+	// once caught up, wait a little while for more lines
+	timeout := time.After(2 * time.Second)
+collect:
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case ev, ok := <-evChan:
+			if !ok {
+				break collect
+			}
+			if ev.err != nil {
+				done = ev.isCloseError()
+				break collect
+			}
+
+			if err := p.renderLogLine(&out, ev.msg, stepStartTimes); err != nil {
+				l.Error("failed to render log line", "err", err)
+				continue
+			}
+			n++
+			if n <= skip {
+				out.Reset()
+			}
+
+		case <-timeout:
+			break collect
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Log-Next", strconv.Itoa(max(n, skip)))
+	if done {
+		w.Header().Set("X-Log-Done", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+}
+
+// renderLogLine renders a log line sent by the spindle as an out-of-band
+// fragment. stepStartTimes keeps track of when steps started, to time them.
+func (p *Pipelines) renderLogLine(w io.Writer, msg []byte, stepStartTimes map[int]time.Time) error {
+	var logLine spindlemodel.LogLine
+	if err := json.Unmarshal(msg, &logLine); err != nil {
+		return fmt.Errorf("failed to parse logline: %w", err)
+	}
+
+	switch logLine.Kind {
+	case spindlemodel.LogKindControl:
+		switch logLine.StepStatus {
+		case spindlemodel.StepStatusStart:
+			stepStartTimes[logLine.StepId] = logLine.Time
+			collapsed := false
+			if logLine.StepKind == spindlemodel.StepKindSystem {
+				collapsed = true
+			}
+			return p.pages.LogBlock(w, pages.LogBlockParams{
+				Id:        logLine.StepId,
+				Name:      logLine.Content,
+				Command:   logLine.StepCommand,
+				Collapsed: collapsed,
+				StartTime: logLine.Time,
+			})
+		case spindlemodel.StepStatusEnd:
+			startTime := stepStartTimes[logLine.StepId]
+			endTime := logLine.Time
+			return p.pages.LogBlockEnd(w, pages.LogBlockEndParams{
+				Id:        logLine.StepId,
+				StartTime: startTime,
+				EndTime:   endTime,
+			})
+		}
+
+	case spindlemodel.LogKindData:
+		// data messages simply insert new log lines into current step
+		return p.pages.LogLine(w, pages.LogLineParams{
+			Id:      logLine.StepId,
+			Content: logLine.Content,
+		})
+	}
+
+	return nil
+}
+
+// writeEvent writes a server-sent event, data spanning several lines is
+// split over several data fields.
+func writeEvent(w io.Writer, id int, data []byte) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "id: %d\nevent: log\n", id)
+	for line := range strings.SplitSeq(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
 // either a message or an error
 type logEvent struct {
 	msg []byte
@@ -0,0 +1,53 @@
+package pow
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func solve(c *Challenge) string {
+	for nonce := 0; ; nonce++ {
+		if Solves(c.Value, strconv.Itoa(nonce), c.Difficulty) {
+			return strconv.Itoa(nonce)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p := New("secret", 8)
+
+	c, err := p.Challenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := solve(c)
+
+	wrong := "x"
+	for Solves(c.Value, wrong, c.Difficulty) {
+		wrong += "x"
+	}
+	if err := p.Verify(c.Value, wrong); !errors.Is(err, ErrWrongNonce) {
+		t.Errorf("wrong nonce: got %v", err)
+	}
+
+	if err := p.Verify(c.Value, nonce); err != nil {
+		t.Errorf("solved challenge was refused: %v", err)
+	}
+
+	if err := p.Verify(c.Value, nonce); !errors.Is(err, ErrUsedChallenge) {
+		t.Errorf("challenge was reused: got %v", err)
+	}
+
+	// challenges from elsewhere, or made easier, are refused
+	other, _ := New("other", 8).Challenge()
+	if err := New("secret", 8).Verify(other.Value, solve(other)); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("foreign challenge: got %v", err)
+	}
+
+	easy, _ := p.Challenge()
+	tampered := "0" + easy.Value[1:]
+	if err := p.Verify(tampered, "0"); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("tampered challenge: got %v", err)
+	}
+}
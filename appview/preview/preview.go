@@ -0,0 +1,151 @@
+// Package preview renders markdown drafts the same way they will be
+// rendered once posted, so forms can offer a preview tab.
+package preview
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pages/markup"
+	"tangled.org/core/appview/pages/repoinfo"
+	"tangled.org/core/appview/ratelimit"
+	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/idresolver"
+	"tangled.org/core/rbac"
+)
+
+const (
+	// drafts larger than this are refused outright
+	maxSourceSize = 64 << 10
+
+	// each user gets a burst of previews, refilled at this rate
+	previewEvery = time.Second
+	previewBurst = 10
+)
+
+type Preview struct {
+	db         *db.DB
+	oauth      *oauth.OAuth
+	enforcer   *rbac.Enforcer
+	idResolver *idresolver.Resolver
+	pages      *pages.Pages
+	limiter    *ratelimit.Limiter
+	logger     *slog.Logger
+}
+
+func New(
+	database *db.DB,
+	oauthHandler *oauth.OAuth,
+	enforcer *rbac.Enforcer,
+	idResolver *idresolver.Resolver,
+	pagesHandler *pages.Pages,
+	logger *slog.Logger,
+) *Preview {
+	return &Preview{
+		db:         database,
+		oauth:      oauthHandler,
+		enforcer:   enforcer,
+		idResolver: idResolver,
+		pages:      pagesHandler,
+		limiter:    ratelimit.New(previewEvery, previewBurst),
+		logger:     logger,
+	}
+}
+
+func (p *Preview) Router() http.Handler {
+	r := chi.NewRouter()
+	r.With(middleware.AuthMiddleware(p.oauth)).Post("/preview", p.preview)
+	return r
+}
+
+// preview renders the "markdown" form value. With a "repo" AT-URI, issue
+// and pull references resolve against that repo; "context=repo" renders
+// it as a file in the repo instead, with links relative to "ref".
+func (p *Preview) preview(w http.ResponseWriter, r *http.Request) {
+	l := p.logger.With("handler", "preview")
+	user := p.oauth.GetUser(r)
+
+	if !p.limiter.Allow(user.Did, time.Now()) {
+		http.Error(w, "too many previews, try again in a moment", http.StatusTooManyRequests)
+		return
+	}
+
+	// leave some room for the other form values
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxSourceSize)
+	if err := r.ParseForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "draft is too large to preview", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	source := r.FormValue("markdown")
+	if len(source) > maxSourceSize {
+		http.Error(w, "draft is too large to preview", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	params := pages.MarkupPreviewParams{
+		Source:     source,
+		RepoMarkup: r.FormValue("context") == "repo",
+	}
+
+	if repoAt := r.FormValue("repo"); repoAt != "" {
+		if _, err := syntax.ParseATURI(repoAt); err != nil {
+			http.Error(w, "invalid repo", http.StatusBadRequest)
+			return
+		}
+
+		repo, err := db.GetRepoByAtUri(p.db, repoAt)
+		if err != nil {
+			http.Error(w, "repo not found", http.StatusNotFound)
+			return
+		}
+
+		if repo.IsPrivate() {
+			ok, err := p.enforcer.IsRepoReadAllowed(user.Did, repo.Knot, repo.DidSlashRepo())
+			if err != nil || !ok {
+				http.Error(w, "repo not found", http.StatusNotFound)
+				return
+			}
+		}
+
+		info := repoinfo.RepoInfo{
+			Name:     repo.Name,
+			Rkey:     repo.Rkey,
+			OwnerDid: repo.Did,
+			Knot:     repo.Knot,
+			RepoAt:   repo.RepoAt(),
+			Ref:      r.FormValue("ref"),
+		}
+		if id, err := p.idResolver.ResolveIdent(r.Context(), repo.Did); err == nil && !id.Handle.IsInvalidHandle() {
+			info.OwnerHandle = id.Handle.String()
+		}
+		params.RepoInfo = &info
+
+		readable, _, err := reporesolver.PrivateRepos(p.db, p.enforcer, user.Did)
+		if err != nil {
+			l.Error("failed to list private repos", "err", err)
+		}
+		refs, err := db.ResolveReferences(p.db, repo.RepoAt(), markup.FindReferences(source), readable)
+		if err != nil {
+			l.Error("failed to resolve references", "err", err)
+		}
+		params.References = refs
+	}
+
+	if err := p.pages.MarkupPreview(w, params); err != nil {
+		l.Error("failed to render preview", "err", err)
+	}
+}
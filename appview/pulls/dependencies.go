@@ -0,0 +1,178 @@
+package pulls
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/reporesolver"
+	tlog "tangled.org/core/log"
+)
+
+// AddPullDependency declares that the pull depends on another one, given
+// as '#12' for a pull of the same repo or as the AT-URI of any pull.
+func (s *Pulls) AddPullDependency(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "AddPullDependency")
+
+	user := s.oauth.GetUser(r)
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Notice(w, "pull-dependencies-error", "Failed to add dependency. Try again later.")
+		return
+	}
+
+	if user.Did != pull.OwnerDid && !f.RolesInRepo(user).IsTriageAllowed() {
+		s.pages.Notice(w, "pull-dependencies-error", "You are unauthorized to edit the dependencies of this pull.")
+		return
+	}
+
+	dependency, err := s.findDependency(f, strings.TrimSpace(r.FormValue("pull")))
+	if err != nil {
+		s.pages.Notice(w, "pull-dependencies-error", err.Error())
+		return
+	}
+
+	if dependency.AtUri() == pull.AtUri() {
+		s.pages.Notice(w, "pull-dependencies-error", "A pull can't depend on itself.")
+		return
+	}
+	// stacks already merge from the bottom up
+	if pull.IsStacked() && dependency.StackId == pull.StackId {
+		s.pages.Notice(w, "pull-dependencies-error", "Pulls of the same stack already depend on the ones below them.")
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		l.Error("failed to start transaction", "err", err)
+		s.pages.Notice(w, "pull-dependencies-error", "Failed to add dependency. Try again later.")
+		return
+	}
+	defer tx.Rollback()
+
+	cycle, err := db.PullDependsOn(tx, dependency.AtUri(), pull.AtUri())
+	if err != nil {
+		l.Error("failed to check for dependency cycles", "err", err)
+		s.pages.Notice(w, "pull-dependencies-error", "Failed to add dependency. Try again later.")
+		return
+	}
+	if cycle {
+		s.pages.Notice(w, "pull-dependencies-error", "That pull already depends on this one, directly or through other pulls.")
+		return
+	}
+
+	if err := db.AddPullDependency(tx, pull.AtUri(), dependency.AtUri(), user.Did); err != nil {
+		l.Error("failed to add dependency", "err", err)
+		s.pages.Notice(w, "pull-dependencies-error", "This pull already depends on that one.")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		l.Error("failed to commit transaction", "err", err)
+		s.pages.Notice(w, "pull-dependencies-error", "Failed to add dependency. Try again later.")
+		return
+	}
+
+	s.pages.HxRefresh(w)
+}
+
+func (s *Pulls) RemovePullDependency(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "RemovePullDependency")
+
+	user := s.oauth.GetUser(r)
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Notice(w, "pull-dependencies-error", "Failed to remove dependency. Try again later.")
+		return
+	}
+
+	if user.Did != pull.OwnerDid && !f.RolesInRepo(user).IsTriageAllowed() {
+		s.pages.Notice(w, "pull-dependencies-error", "You are unauthorized to edit the dependencies of this pull.")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad dependency id", http.StatusBadRequest)
+		return
+	}
+
+	err = db.DeletePullDependency(s.db, db.FilterEq("id", id), db.FilterEq("pull_at", pull.AtUri()))
+	if err != nil {
+		l.Error("failed to remove dependency", "err", err)
+		s.pages.Notice(w, "pull-dependencies-error", "Failed to remove dependency. Try again later.")
+		return
+	}
+
+	s.pages.HxRefresh(w)
+}
+
+// findDependency looks up a pull as written by the user.
+func (s *Pulls) findDependency(f *reporesolver.ResolvedRepo, ref string) (*models.Pull, error) {
+	if n, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err == nil {
+		pull, err := db.GetPull(s.db, f.RepoAt(), n)
+		if err != nil {
+			return nil, fmt.Errorf("Pull #%d does not exist.", n)
+		}
+		return pull, nil
+	}
+
+	uri, err := syntax.ParseATURI(ref)
+	if err != nil || uri.Collection().String() != tangled.RepoPullNSID {
+		return nil, fmt.Errorf("Enter a pull number, like #12, or the AT-URI of a pull.")
+	}
+
+	pulls, err := db.GetPulls(s.db, db.FilterEq("at_uri", uri))
+	if err != nil || len(pulls) == 0 {
+		return nil, fmt.Errorf("That pull does not exist.")
+	}
+	return pulls[0], nil
+}
+
+// blockedBy explains which dependencies keep pulls from being merged, or
+// returns an empty string if none do.
+func (s *Pulls) blockedBy(f *reporesolver.ResolvedRepo, pulls models.Stack) (string, error) {
+	var ats []syntax.ATURI
+	for _, p := range pulls {
+		ats = append(ats, p.AtUri())
+	}
+
+	deps, err := db.GetPullDependencies(s.db, db.FilterIn("d.pull_at", ats))
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, d := range models.BlockingDependencies(deps) {
+		name := fmt.Sprintf("#%d", d.On.Number)
+		if d.On.RepoDid != f.OwnerDid() || d.On.RepoName != f.Name {
+			name = fmt.Sprintf("%s/%s#%d", d.On.RepoDid, d.On.RepoName, d.On.Number)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprintf("blocked by %s, which must be merged first", strings.Join(names, ", ")), nil
+}
@@ -0,0 +1,138 @@
+package pulls
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/xrpcclient"
+)
+
+// Pulls from forks compare against a hidden ref on the fork, one per source
+// and target branch, that tracks the target branch. Those refs are removed
+// once no open pull needs them: straight away when a pull is merged or
+// closed, and by a periodic sweep for the ones that was not possible for.
+//
+// Both happen on behalf of the fork's owner, who is rarely the one merging,
+// so they need to have a session open.
+
+// deleteHiddenRefs removes the hidden refs of the fork based pulls among
+// pulls that no other open pull shares. It does not wait for the knots.
+func (s *Pulls) deleteHiddenRefs(pulls []*models.Pull) {
+	for _, p := range pulls {
+		if !p.IsForkBased() {
+			continue
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			l := s.logger.With("pull", p.AtUri(), "fork", p.PullSource.RepoAt)
+
+			fork := *p.PullSource.RepoAt
+			refs, err := db.GetForkPullRefs(s.db, db.FilterEq("source_repo_at", fork))
+			if err != nil {
+				l.Error("failed to get open pulls of fork", "err", err)
+				return
+			}
+			if slices.Contains(refs[fork], p.PullSource.Branch+"/"+p.TargetBranch) {
+				return
+			}
+
+			client, err := s.forkClient(ctx, fork, tangled.RepoDeleteHiddenRefNSID)
+			if err != nil {
+				l.Info("leaving hidden ref to the sweep", "err", err)
+				return
+			}
+
+			err = tangled.RepoDeleteHiddenRef(ctx, client, &tangled.RepoDeleteHiddenRef_Input{
+				Repo:      fork.String(),
+				ForkRef:   p.PullSource.Branch,
+				RemoteRef: p.TargetBranch,
+			})
+			if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+				l.Info("leaving hidden ref to the sweep", "err", xrpcerr)
+			}
+		}()
+	}
+}
+
+// SweepHiddenRefs removes the hidden refs of every fork that no open pull
+// compares against, every HiddenRefSweepEvery until ctx is done.
+func (s *Pulls) SweepHiddenRefs(ctx context.Context) {
+	every := s.config.Patch.HiddenRefSweepEvery
+	if every <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.sweepHiddenRefs(ctx)
+	}
+}
+
+func (s *Pulls) sweepHiddenRefs(ctx context.Context) {
+	forks, err := db.GetForkPullRefs(s.db)
+	if err != nil {
+		s.logger.Error("failed to get forks with pulls", "err", err)
+		return
+	}
+
+	pruned := 0
+	for fork, keep := range forks {
+		if ctx.Err() != nil {
+			return
+		}
+
+		l := s.logger.With("fork", fork)
+
+		client, err := s.forkClient(ctx, fork, tangled.RepoPruneHiddenRefsNSID)
+		if err != nil {
+			l.Debug("skipping fork", "err", err)
+			continue
+		}
+
+		out, err := tangled.RepoPruneHiddenRefs(ctx, client, &tangled.RepoPruneHiddenRefs_Input{
+			Repo: fork.String(),
+			Keep: append([]string{}, keep...),
+		})
+		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+			l.Warn("failed to prune hidden refs", "err", xrpcerr)
+			continue
+		}
+		pruned += len(out.Pruned)
+	}
+
+	s.logger.Info("swept hidden refs", "forks", len(forks), "pruned", pruned)
+}
+
+// forkClient calls the knot of fork as its owner.
+func (s *Pulls) forkClient(ctx context.Context, fork syntax.ATURI, lxm string) (*indigoxrpc.Client, error) {
+	repo, err := db.GetRepoByAtUri(s.db, fork.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.oauth.ServiceClientFor(
+		ctx,
+		syntax.DID(repo.Did),
+		oauth.WithService(repo.Knot),
+		oauth.WithLxm(lxm),
+		oauth.WithDev(s.config.Core.Dev),
+	)
+}
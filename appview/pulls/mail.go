@@ -0,0 +1,468 @@
+package pulls
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	indigooauth "github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/email"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages/markup"
+	"tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/patchutil"
+	"tangled.org/core/tid"
+)
+
+// MailRouter takes mail handed over by the relay for the patches domain.
+func (s *Pulls) MailRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/inbound", s.InboundMail)
+	return r
+}
+
+// mailRejection is mail that will never be taken, however often it is
+// sent. The sender is told why.
+type mailRejection struct {
+	reason string
+}
+
+func (e *mailRejection) Error() string {
+	return e.reason
+}
+
+func reject(format string, args ...any) error {
+	return &mailRejection{reason: fmt.Sprintf(format, args...)}
+}
+
+// InboundMail takes one raw message. Patches, sent with git send-email to
+// <owner>/<repo>@ the patches domain, open a pull on behalf of the sender;
+// replies to them become comments on it. Senders are matched to an account
+// by a verified email address, and the relay must have found a DKIM
+// signature from their domain.
+func (s *Pulls) InboundMail(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "InboundMail")
+
+	secret := s.config.Mail.InboundSecret
+	if secret == "" || s.config.Mail.AuthServId == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	msg, err := parseMailMessage(http.MaxBytesReader(w, r.Body, s.config.Mail.MaxBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	l = l.With("from", msg.From, "message_id", msg.MessageId)
+
+	err = s.takeMail(r.Context(), msg)
+	var rejection *mailRejection
+	if errors.As(err, &rejection) {
+		l.Info("rejected mail", "reason", rejection.reason)
+		s.replyToMail(msg, rejection.reason)
+		// the relay should not retry
+		http.Error(w, rejection.reason, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		l.Error("failed to take mail", "err", err)
+		http.Error(w, "failed to take mail", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Pulls) takeMail(ctx context.Context, msg *mailMessage) error {
+	_, domain, _ := strings.Cut(msg.From, "@")
+	if !dkimAligned(msg.AuthResults, s.config.Mail.AuthServId, domain) {
+		return reject("Your mail was not signed by %s, so it cannot be told apart from a forgery.", domain)
+	}
+
+	did, err := db.GetVerifiedDidForEmail(s.db, msg.From)
+	if errors.Is(err, sql.ErrNoRows) {
+		return reject("%s is not a verified email address of any account. Add it in your settings and try again.", msg.From)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up sender: %w", err)
+	}
+
+	subject, isPatch := parsePatchSubject(msg.Subject)
+	if !isPatch {
+		repoAt, pullId, err := db.GetMailThread(s.db, msg.References)
+		if errors.Is(err, sql.ErrNoRows) {
+			return reject("This is neither a patch nor a reply to one. Patches are sent with git send-email.")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up thread: %w", err)
+		}
+		return s.commentFromMail(ctx, msg, did, repoAt, pullId)
+	}
+
+	var target mailTarget
+	var ok bool
+	for _, to := range msg.To {
+		if target, ok = parseMailTarget(to, s.config.Mail.Domain); ok {
+			break
+		}
+	}
+	if !ok {
+		return reject("Patches are sent to <owner>/<repo>@%s, or <owner>/<repo>+<branch>@%s for a branch other than the default.", s.config.Mail.Domain, s.config.Mail.Domain)
+	}
+
+	ident, err := s.idResolver.ResolveIdent(ctx, target.Owner)
+	if err != nil {
+		return reject("There is no user called %s.", target.Owner)
+	}
+	repo, err := db.GetRepo(s.db, db.FilterEq("did", ident.DID.String()), db.FilterEq("name", target.Repo))
+	if err != nil || !s.canSeeRepo(did, repo) {
+		return reject("There is no repository called %s/%s.", target.Owner, target.Repo)
+	}
+
+	if target.Branch == "" {
+		target.Branch, err = s.defaultBranch(ctx, did, repo)
+		if err != nil {
+			return fmt.Errorf("failed to get default branch: %w", err)
+		}
+	}
+
+	// the thread the series hangs off, or this message if it starts one
+	seriesId := msg.MessageId
+	if len(msg.References) > 0 {
+		seriesId = msg.References[len(msg.References)-1]
+	}
+
+	// a cover letter that arrives after the patches it introduces
+	if subject.Part == 0 {
+		if repoAt, pullId, err := db.GetMailThread(s.db, []string{seriesId}); err == nil {
+			return db.AddMailThread(s.db, msg.MessageId, repoAt, pullId)
+		}
+	}
+
+	part := models.MailPatchPart{
+		SeriesId:  seriesId,
+		Part:      subject.Part,
+		Total:     subject.Total,
+		Did:       did,
+		RepoAt:    repo.RepoAt(),
+		Branch:    target.Branch,
+		MessageId: msg.MessageId,
+		Subject:   subject.Title,
+		Body:      msg.Body,
+	}
+	if part.Part > 0 {
+		part.Body = msg.formatPatch()
+	}
+
+	parts := []models.MailPatchPart{part}
+	if part.Total > 1 {
+		if err := db.AddMailPatchPart(s.db, part); err != nil {
+			return fmt.Errorf("failed to keep patch: %w", err)
+		}
+
+		parts, err = db.GetMailPatchParts(s.db, seriesId)
+		if err != nil {
+			return fmt.Errorf("failed to get series: %w", err)
+		}
+
+		// wait for the rest of the series
+		patches := 0
+		for _, p := range parts {
+			if !p.IsCoverLetter() {
+				patches++
+			}
+		}
+		if patches < part.Total {
+			return nil
+		}
+	}
+
+	if err := s.pullFromMail(ctx, repo, parts); err != nil {
+		return err
+	}
+
+	return db.DeleteMailPatchParts(s.db, seriesId)
+}
+
+// canSeeRepo reports whether did may send mail to repo. Private repos don't
+// exist for those without access to them, like on the web.
+func (s *Pulls) canSeeRepo(did string, repo *models.Repo) bool {
+	if !repo.IsPrivate() {
+		return true
+	}
+	ok, err := s.enforcer.IsRepoReadAllowed(did, repo.Knot, repo.DidSlashRepo())
+	return err == nil && ok
+}
+
+// defaultBranch asks the knot for the default branch of repo, as did when
+// the repo is private.
+func (s *Pulls) defaultBranch(ctx context.Context, did string, repo *models.Repo) (string, error) {
+	scheme := "http"
+	if !s.config.Core.Dev {
+		scheme = "https"
+	}
+	var xrpcc lexutil.LexClient = xrpcclient.NewClient(s.config.KnotClient, fmt.Sprintf("%s://%s", scheme, repo.Knot))
+	if repo.IsPrivate() {
+		var err error
+		xrpcc, err = s.oauth.ServiceClientFor(
+			ctx,
+			syntax.DID(did),
+			oauth.WithService(repo.Knot),
+			oauth.WithLxm(tangled.RepoGetDefaultBranchNSID),
+			oauth.WithDev(s.config.Core.Dev),
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	out, err := tangled.RepoGetDefaultBranch(ctx, xrpcc, repo.DidSlashRepo())
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return "", xrpcerr
+	}
+	return out.Name, nil
+}
+
+// senderSession resumes a session of the sender, since records can only be
+// written to their PDS with one.
+func (s *Pulls) senderSession(ctx context.Context, did string) (*indigooauth.ClientSession, error) {
+	sess, err := s.oauth.ResumeSessionFor(ctx, syntax.DID(did))
+	if err != nil {
+		s.logger.Info("no session for mail sender", "did", did, "err", err)
+		return nil, reject("You need to be signed in to %s for mail to be posted on your behalf. Sign in and send it again.", s.config.Core.AppviewName)
+	}
+	return sess, nil
+}
+
+// pullFromMail opens a pull with a complete series. Its title and body come
+// from the cover letter if there is one, and from the first patch if not.
+func (s *Pulls) pullFromMail(ctx context.Context, repo *models.Repo, parts []models.MailPatchPart) error {
+	first := parts[0]
+
+	var title, body string
+	var patches []string
+	var messageIds []string
+	for _, p := range parts {
+		if p.Did != first.Did {
+			return reject("Parts of this series were sent by more than one account.")
+		}
+		messageIds = append(messageIds, p.MessageId)
+		if p.IsCoverLetter() {
+			// git leaves these in when the cover letter is not written
+			if !strings.Contains(p.Subject, "*** SUBJECT HERE ***") {
+				title = p.Subject
+			}
+			if !strings.Contains(p.Body, "*** BLURB HERE ***") {
+				body, _, _ = strings.Cut(p.Body, "\n-- \n")
+				body = strings.TrimSpace(body)
+			}
+			continue
+		}
+		patches = append(patches, p.Body)
+	}
+
+	patch := strings.Join(patches, "")
+	if err := s.validator.ValidatePatch(&patch); err != nil {
+		return reject("The patch could not be read: %v", err)
+	}
+
+	if title == "" || body == "" {
+		formatPatches, err := patchutil.ExtractPatches(patch)
+		if err != nil || len(formatPatches) == 0 {
+			return reject("The patch could not be read: %v", err)
+		}
+		if title == "" {
+			title = formatPatches[0].Title
+		}
+		if body == "" {
+			body = formatPatches[0].Body
+		}
+	}
+
+	sess, err := s.senderSession(ctx, first.Did)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rkey := tid.TID()
+	pull := &models.Pull{
+		Title:        title,
+		Body:         body,
+		TargetBranch: first.Branch,
+		OwnerDid:     first.Did,
+		RepoAt:       repo.RepoAt(),
+		Rkey:         rkey,
+		Submissions: []*models.PullSubmission{
+			{Patch: patch},
+		},
+	}
+	if err := db.NewPull(tx, pull); err != nil {
+		return fmt.Errorf("failed to create pull: %w", err)
+	}
+	if err := db.PutReferenceLinks(tx, pull.RepoAt, pull.AtUri(), pull.AtUri(), markup.FindReferences(pull.Body)); err != nil {
+		s.logger.Error("failed to record references", "err", err)
+	}
+	for _, id := range messageIds {
+		if err := db.AddMailThread(tx, id, pull.RepoAt, pull.PullId); err != nil {
+			return fmt.Errorf("failed to record thread: %w", err)
+		}
+	}
+
+	_, err = comatproto.RepoPutRecord(ctx, sess.APIClient(), &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoPullNSID,
+		Repo:       first.Did,
+		Rkey:       rkey,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &tangled.RepoPull{
+				Title: title,
+				Body:  &body,
+				Target: &tangled.RepoPull_Target{
+					Repo:   repo.RepoAt().String(),
+					Branch: first.Branch,
+				},
+				Patch:     patch,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write pull record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.notifier.NewPull(ctx, pull)
+	return nil
+}
+
+func (s *Pulls) commentFromMail(ctx context.Context, msg *mailMessage, did string, repoAt syntax.ATURI, pullId int) error {
+	body := replyBody(msg.Body)
+	if body == "" {
+		return reject("Your reply was empty once the quoted message was left out.")
+	}
+
+	pull, err := db.GetPull(s.db, repoAt, pullId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return reject("The pull this thread belongs to no longer exists.")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get pull: %w", err)
+	}
+	repo, err := db.GetRepoByAtUri(s.db, repoAt.String())
+	if err != nil || !s.canSeeRepo(did, repo) {
+		return reject("The pull this thread belongs to no longer exists.")
+	}
+
+	sess, err := s.senderSession(ctx, did)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	atResp, err := comatproto.RepoPutRecord(ctx, sess.APIClient(), &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoPullCommentNSID,
+		Repo:       did,
+		Rkey:       tid.TID(),
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &tangled.RepoPullComment{
+				Pull:      pull.AtUri().String(),
+				Body:      body,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write comment record: %w", err)
+	}
+
+	comment := &models.PullComment{
+		OwnerDid:     did,
+		RepoAt:       repoAt.String(),
+		PullId:       pull.PullId,
+		Body:         body,
+		CommentAt:    atResp.Uri,
+		SubmissionId: pull.LatestSubmission().ID,
+	}
+	if _, err := db.NewPullComment(tx, comment); err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	if err := db.PutReferenceLinks(tx, pull.RepoAt, comment.AtUri(), pull.AtUri(), markup.FindReferences(comment.Body)); err != nil {
+		s.logger.Error("failed to record references", "err", err)
+	}
+	if err := db.AddMailThread(tx, msg.MessageId, repoAt, pullId); err != nil {
+		return fmt.Errorf("failed to record thread: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	var mentions []syntax.DID
+	for _, ident := range s.idResolver.ResolveIdents(ctx, markup.FindUserMentions(comment.Body)) {
+		if ident != nil && !ident.Handle.IsInvalidHandle() {
+			mentions = append(mentions, ident.DID)
+		}
+	}
+	s.notifier.NewPullComment(ctx, comment, mentions)
+	return nil
+}
+
+// replyToMail tells the sender why their mail was not taken. Only mail that
+// passed DKIM gets here, so this never answers a forged address.
+func (s *Pulls) replyToMail(msg *mailMessage, reason string) {
+	if s.config.Resend.ApiKey == "" {
+		return
+	}
+
+	_, domain, _ := strings.Cut(msg.From, "@")
+	if !dkimAligned(msg.AuthResults, s.config.Mail.AuthServId, domain) {
+		return
+	}
+
+	subject := msg.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+
+	err := email.SendEmail(email.Email{
+		APIKey:  s.config.Resend.ApiKey,
+		From:    s.config.Resend.SentFrom,
+		To:      msg.From,
+		Subject: subject,
+		Text:    reason + "\n",
+	})
+	if err != nil {
+		s.logger.Error("failed to reply to mail", "err", err)
+	}
+}
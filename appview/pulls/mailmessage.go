@@ -0,0 +1,290 @@
+package pulls
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mailMessage is the part of an email that matters for taking patches and
+// replies over mail.
+type mailMessage struct {
+	From    string
+	RawFrom string
+	To      []string
+	Subject string
+	Date    string
+
+	MessageId string
+	// the messages this one replies to, nearest first
+	References []string
+
+	// Authentication-Results headers, topmost first
+	AuthResults []string
+	Body        string
+}
+
+func parseMailMessage(r io.Reader) (*mailMessage, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	decode := func(s string) string {
+		if d, err := dec.DecodeHeader(s); err == nil {
+			return d
+		}
+		return s
+	}
+
+	from, err := m.Header.AddressList("From")
+	if err != nil || len(from) != 1 {
+		return nil, fmt.Errorf("message must be from exactly one address")
+	}
+
+	msg := &mailMessage{
+		From:        strings.ToLower(from[0].Address),
+		RawFrom:     decode(m.Header.Get("From")),
+		Subject:     decode(m.Header.Get("Subject")),
+		Date:        m.Header.Get("Date"),
+		AuthResults: m.Header["Authentication-Results"],
+	}
+
+	ids := messageIds(m.Header.Get("Message-Id"))
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("message has no Message-ID")
+	}
+	msg.MessageId = ids[0]
+
+	for _, h := range []string{"To", "Cc"} {
+		addrs, err := m.Header.AddressList(h)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			msg.To = append(msg.To, a.Address)
+		}
+	}
+
+	// In-Reply-To names the parent; References lists the thread oldest
+	// first, so it is walked backwards
+	refs := messageIds(m.Header.Get("In-Reply-To"))
+	older := messageIds(m.Header.Get("References"))
+	slices.Reverse(older)
+	for _, id := range append(refs, older...) {
+		if !slices.Contains(msg.References, id) {
+			msg.References = append(msg.References, id)
+		}
+	}
+
+	body, err := textBody(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body)
+	if err != nil {
+		return nil, err
+	}
+	msg.Body = strings.ReplaceAll(body, "\r\n", "\n")
+
+	return msg, nil
+}
+
+var messageIdRe = regexp.MustCompile(`<[^<>\s]+>`)
+
+func messageIds(h string) []string {
+	return messageIdRe.FindAllString(h, -1)
+}
+
+// textBody returns the first text/plain part of a body.
+func textBody(contentType, encoding string, r io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return "", fmt.Errorf("message has no text/plain part")
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to read message part: %w", err)
+			}
+			body, err := textBody(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)
+			if err == nil {
+				return body, nil
+			}
+		}
+	}
+
+	if mediaType != "text/plain" {
+		return "", fmt.Errorf("message has no text/plain part")
+	}
+
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return "", fmt.Errorf("failed to read message body: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// mailTarget is where a patch was sent: <owner>/<repo>@domain, optionally
+// with +<branch> to pick the branch it is for.
+type mailTarget struct {
+	Owner  string
+	Repo   string
+	Branch string
+}
+
+func parseMailTarget(addr, domain string) (mailTarget, bool) {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 || !strings.EqualFold(addr[at+1:], domain) {
+		return mailTarget{}, false
+	}
+
+	var t mailTarget
+	local := addr[:at]
+	local, t.Branch, _ = strings.Cut(local, "+")
+	t.Owner, t.Repo, _ = strings.Cut(local, "/")
+	if t.Owner == "" || t.Repo == "" {
+		return mailTarget{}, false
+	}
+
+	return t, true
+}
+
+// patchSubject is what the "[PATCH v2 1/3]" prefix of a subject says.
+type patchSubject struct {
+	Title string
+	// Part and Total are 1 and 1 for a lone patch; Part is 0 for a cover
+	// letter
+	Part  int
+	Total int
+}
+
+var (
+	patchTagRe  = regexp.MustCompile(`^\[([^\]]*\bPATCH\b[^\]]*)\]\s*(.*)$`)
+	patchPartRe = regexp.MustCompile(`\b(\d+)/(\d+)\b`)
+)
+
+func parsePatchSubject(subject string) (patchSubject, bool) {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(subject)), "re:") {
+		return patchSubject{}, false
+	}
+
+	m := patchTagRe.FindStringSubmatch(strings.TrimSpace(subject))
+	if m == nil {
+		return patchSubject{}, false
+	}
+
+	s := patchSubject{Title: m[2], Part: 1, Total: 1}
+	if pm := patchPartRe.FindStringSubmatch(m[1]); pm != nil {
+		s.Part, _ = strconv.Atoi(pm[1])
+		s.Total, _ = strconv.Atoi(pm[2])
+		if s.Total < 1 || s.Part > s.Total {
+			return patchSubject{}, false
+		}
+	}
+
+	return s, true
+}
+
+// dkimAligned reports whether the relay that took the message found a
+// passing DKIM signature from the sender's domain. Anyone can write an
+// Authentication-Results header into their own mail, so only the topmost
+// one is looked at, which the relay adds, and only if it names the relay's
+// authserv-id (RFC 8601).
+func dkimAligned(authResults []string, authServId, fromDomain string) bool {
+	if len(authResults) == 0 || authServId == "" {
+		return false
+	}
+
+	methods := strings.Split(authResults[0], ";")
+	if id := strings.Fields(methods[0]); len(id) == 0 || !strings.EqualFold(id[0], authServId) {
+		return false
+	}
+
+	fromDomain = strings.ToLower(fromDomain)
+	for _, method := range methods[1:] {
+		fields := strings.Fields(strings.ToLower(method))
+		if len(fields) == 0 || fields[0] != "dkim=pass" {
+			continue
+		}
+		for _, f := range fields[1:] {
+			var d string
+			if v, ok := strings.CutPrefix(f, "header.d="); ok {
+				d = v
+			} else if v, ok := strings.CutPrefix(f, "header.i="); ok {
+				_, d, _ = strings.Cut(v, "@")
+			}
+			d = strings.Trim(d, `"`)
+			if d != "" && (fromDomain == d || strings.HasSuffix(fromDomain, "."+d)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatPatch turns a patch sent with git send-email back into what git
+// format-patch wrote before it was sent.
+func (m *mailMessage) formatPatch() string {
+	var b strings.Builder
+	b.WriteString("From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001\n")
+	fmt.Fprintf(&b, "From: %s\n", m.RawFrom)
+	if m.Date != "" {
+		fmt.Fprintf(&b, "Date: %s\n", m.Date)
+	}
+	fmt.Fprintf(&b, "Subject: %s\n\n", m.Subject)
+	b.WriteString(m.Body)
+	if !strings.HasSuffix(m.Body, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var attributionRe = regexp.MustCompile(`^On .*wrote:$`)
+
+// replyBody drops the quoted message that replies usually end with, along
+// with the signature. Quotes in between the reply are kept, since inline
+// review needs them.
+func replyBody(body string) string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if line == "-- " {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line == "" || strings.HasPrefix(line, ">") {
+			end--
+			continue
+		}
+		if attributionRe.MatchString(line) {
+			end--
+		}
+		break
+	}
+
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
@@ -0,0 +1,112 @@
+package pulls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+const sentPatch = "From: Bob <bob@example.com>\r\n" +
+	"To: alice.example.com/project+dev@patches.tangled.org\r\n" +
+	"Subject: [PATCH v2 2/3] fix the thing\r\n" +
+	"Date: Mon, 12 Oct 2026 10:00:00 +0000\r\n" +
+	"Message-Id: <2@example.com>\r\n" +
+	"In-Reply-To: <1@example.com>\r\n" +
+	"References: <0@example.com> <1@example.com>\r\n" +
+	"Authentication-Results: mx.tangled.org; spf=pass smtp.mailfrom=example.com; dkim=pass header.d=example.com\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: 8bit\r\n" +
+	"\r\n" +
+	"it was broken\r\n" +
+	"---\r\n" +
+	" a.txt | 2 +-\r\n"
+
+func TestParseMailMessage(t *testing.T) {
+	msg, err := parseMailMessage(strings.NewReader(sentPatch))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "bob@example.com", msg.From)
+	assert.Equal(t, []string{"alice.example.com/project+dev@patches.tangled.org"}, msg.To)
+	assert.Equal(t, "<2@example.com>", msg.MessageId)
+	assert.Equal(t, []string{"<1@example.com>", "<0@example.com>"}, msg.References)
+	assert.Equal(t, "it was broken\n---\n a.txt | 2 +-\n", msg.Body)
+	assert.True(t, dkimAligned(msg.AuthResults, "mx.tangled.org", "example.com"))
+	assert.False(t, dkimAligned(msg.AuthResults, "mx.tangled.org", "example.org"))
+
+	patch := msg.formatPatch()
+	assert.True(t, strings.HasPrefix(patch, "From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001\nFrom: Bob <bob@example.com>\n"))
+	assert.Contains(t, patch, "Subject: [PATCH v2 2/3] fix the thing\n\nit was broken\n")
+}
+
+func TestParseMailMessageNeedsMessageId(t *testing.T) {
+	_, err := parseMailMessage(strings.NewReader("From: bob@example.com\r\nSubject: hi\r\n\r\nhi\r\n"))
+	assert.Error(t, err)
+}
+
+func TestDkimAligned(t *testing.T) {
+	tests := []struct {
+		results []string
+		domain  string
+		want    bool
+	}{
+		{[]string{"mx; dkim=pass header.d=example.com"}, "example.com", true},
+		{[]string{"mx; dkim=pass header.d=example.com"}, "mail.example.com", true},
+		{[]string{"mx; dkim=pass header.i=@example.com"}, "example.com", true},
+		{[]string{"mx; dkim=fail header.d=example.com"}, "example.com", false},
+		{[]string{"mx; dkim=pass header.d=evil.com"}, "example.com", false},
+		{[]string{"mx; dkim=pass header.d=ample.com"}, "example.com", false},
+		{nil, "example.com", false},
+		// results the relay did not write
+		{[]string{"evil; dkim=pass header.d=example.com"}, "example.com", false},
+		{[]string{"mx; dkim=fail header.d=example.com", "mx; dkim=pass header.d=example.com"}, "example.com", false},
+		{[]string{"mx 1; dkim=pass header.d=example.com"}, "example.com", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, dkimAligned(tt.results, "mx", tt.domain), "%v %s", tt.results, tt.domain)
+	}
+}
+
+func TestParseMailTarget(t *testing.T) {
+	target, ok := parseMailTarget("alice.example.com/project+feature/x@Patches.Tangled.org", "patches.tangled.org")
+	assert.True(t, ok)
+	assert.Equal(t, mailTarget{Owner: "alice.example.com", Repo: "project", Branch: "feature/x"}, target)
+
+	target, ok = parseMailTarget("alice.example.com/project@patches.tangled.org", "patches.tangled.org")
+	assert.True(t, ok)
+	assert.Equal(t, "", target.Branch)
+
+	_, ok = parseMailTarget("alice.example.com/project@example.com", "patches.tangled.org")
+	assert.False(t, ok)
+
+	_, ok = parseMailTarget("alice@patches.tangled.org", "patches.tangled.org")
+	assert.False(t, ok)
+}
+
+func TestParsePatchSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    patchSubject
+		ok      bool
+	}{
+		{"[PATCH] fix it", patchSubject{Title: "fix it", Part: 1, Total: 1}, true},
+		{"[PATCH v3 2/5] fix it", patchSubject{Title: "fix it", Part: 2, Total: 5}, true},
+		{"[PATCH 0/2] cover", patchSubject{Title: "cover", Part: 0, Total: 2}, true},
+		{"[RFC PATCH core] try it", patchSubject{Title: "try it", Part: 1, Total: 1}, true},
+		{"Re: [PATCH] fix it", patchSubject{}, false},
+		{"[PATCH 3/2] too many", patchSubject{}, false},
+		{"fix it", patchSubject{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parsePatchSubject(tt.subject)
+		assert.Equal(t, tt.ok, ok, tt.subject)
+		assert.Equal(t, tt.want, got, tt.subject)
+	}
+}
+
+func TestReplyBody(t *testing.T) {
+	body := "Looks good, but:\n\n> +foo\n\nfoo is not a word.\n\nOn Mon, Bob wrote:\n> the patch\n> more patch\n\n-- \nAlice\n"
+	assert.Equal(t, "Looks good, but:\n\n> +foo\n\nfoo is not a word.", replyBody(body))
+}
@@ -7,12 +7,12 @@ import (
 	"image"
 	"image/color"
 	"image/png"
-	"log"
 	"net/http"
 
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/ogcard"
+	tlog "tangled.org/core/log"
 	"tangled.org/core/patchutil"
 	"tangled.org/core/types"
 )
@@ -123,7 +123,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	avatarURL := s.pages.AvatarUrl(authorHandle, "256")
 	err = avatarArea.DrawCircularExternalImage(avatarURL, avatarX, avatarY, avatarSize)
 	if err != nil {
-		log.Printf("failed to draw avatar (non-fatal): %v", err)
+		s.logger.Warn("failed to draw avatar (non-fatal)", "err", err)
 	}
 
 	// Split stats area: left side for status/stats (80%), right side for dolly (20%)
@@ -164,7 +164,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	// Draw icon with status color
 	err = statusStatsArea.DrawLucideIcon(statusIcon, statsX, statsY+iconBaselineOffset-statusIconSize/2+5, statusIconSize, statusColor)
 	if err != nil {
-		log.Printf("failed to draw status icon: %v", err)
+		s.logger.Error("failed to draw status icon", "err", err)
 	}
 
 	// Draw text with status color
@@ -172,7 +172,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	statusTextSize := 32.0
 	err = statusStatsArea.DrawTextAt(statusText, textX, statsY+iconBaselineOffset, statusColor, statusTextSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw status text: %v", err)
+		s.logger.Error("failed to draw status text", "err", err)
 	}
 
 	statusTextWidth := len(statusText) * 20
@@ -181,7 +181,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	// Draw comment count
 	err = statusStatsArea.DrawLucideIcon("message-square", currentX, statsY+iconBaselineOffset-iconSize/2+5, iconSize, iconColor)
 	if err != nil {
-		log.Printf("failed to draw comment icon: %v", err)
+		s.logger.Error("failed to draw comment icon", "err", err)
 	}
 
 	currentX += iconSize + 15
@@ -191,7 +191,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	}
 	err = statusStatsArea.DrawTextAt(commentText, currentX, statsY+iconBaselineOffset, iconColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw comment text: %v", err)
+		s.logger.Error("failed to draw comment text", "err", err)
 	}
 
 	commentTextWidth := len(commentText) * 20
@@ -200,7 +200,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	// Draw files changed
 	err = statusStatsArea.DrawLucideIcon("static/icons/file-diff", currentX, statsY+iconBaselineOffset-iconSize/2+5, iconSize, iconColor)
 	if err != nil {
-		log.Printf("failed to draw file diff icon: %v", err)
+		s.logger.Error("failed to draw file diff icon", "err", err)
 	}
 
 	currentX += iconSize + 15
@@ -210,7 +210,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	}
 	err = statusStatsArea.DrawTextAt(filesText, currentX, statsY+iconBaselineOffset, iconColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw files text: %v", err)
+		s.logger.Error("failed to draw files text", "err", err)
 	}
 
 	filesTextWidth := len(filesText) * 20
@@ -221,7 +221,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	additionsText := fmt.Sprintf("+%d", diffStats.Insertions)
 	err = statusStatsArea.DrawTextAt(additionsText, currentX, statsY+iconBaselineOffset, greenColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw additions text: %v", err)
+		s.logger.Error("failed to draw additions text", "err", err)
 	}
 
 	additionsTextWidth := len(additionsText) * 20
@@ -232,7 +232,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	deletionsText := fmt.Sprintf("-%d", diffStats.Deletions)
 	err = statusStatsArea.DrawTextAt(deletionsText, currentX, statsY+iconBaselineOffset, redColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw deletions text: %v", err)
+		s.logger.Error("failed to draw deletions text", "err", err)
 	}
 
 	// Draw dolly logo on the right side
@@ -243,7 +243,7 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 	dollyColor := color.RGBA{180, 180, 180, 255} // light gray
 	err = dollyArea.DrawDollySilhouette(dollyX, dollyY, dollySize, dollyColor)
 	if err != nil {
-		log.Printf("dolly silhouette not available (this is ok): %v", err)
+		s.logger.Debug("dolly silhouette not available (this is ok)", "err", err)
 	}
 
 	// Draw "opened by @author" and date at the bottom with more spacing
@@ -255,22 +255,24 @@ func (s *Pulls) drawPullSummaryCard(pull *models.Pull, repo *models.Repo, commen
 
 	err = statusStatsArea.DrawTextAt(metaText, statsX, labelY, iconColor, labelSize, ogcard.Top, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw metadata: %v", err)
+		s.logger.Error("failed to draw metadata", "err", err)
 	}
 
 	return mainCard, nil
 }
 
 func (s *Pulls) PullOpenGraphSummary(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "PullOpenGraphSummary")
+
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("pull not found in context")
+		l.Error("pull not found in context")
 		http.Error(w, "pull not found", http.StatusNotFound)
 		return
 	}
@@ -278,7 +280,7 @@ func (s *Pulls) PullOpenGraphSummary(w http.ResponseWriter, r *http.Request) {
 	// Get comment count from database
 	comments, err := db.GetPullComments(s.db, db.FilterEq("pull_id", pull.ID))
 	if err != nil {
-		log.Printf("failed to get pull comments: %v", err)
+		l.Error("failed to get pull comments", "err", err)
 	}
 	commentCount := len(comments)
 
@@ -295,7 +297,7 @@ func (s *Pulls) PullOpenGraphSummary(w http.ResponseWriter, r *http.Request) {
 
 	card, err := s.drawPullSummaryCard(pull, &f.Repo, commentCount, diffStats, filesChanged)
 	if err != nil {
-		log.Println("failed to draw pull summary card", err)
+		l.Error("failed to draw pull summary card", "err", err)
 		http.Error(w, "failed to draw pull summary card", http.StatusInternalServerError)
 		return
 	}
@@ -303,7 +305,7 @@ func (s *Pulls) PullOpenGraphSummary(w http.ResponseWriter, r *http.Request) {
 	var imageBuffer bytes.Buffer
 	err = png.Encode(&imageBuffer, card.Img)
 	if err != nil {
-		log.Println("failed to encode pull summary card", err)
+		l.Error("failed to encode pull summary card", "err", err)
 		http.Error(w, "failed to encode pull summary card", http.StatusInternalServerError)
 		return
 	}
@@ -315,7 +317,7 @@ func (s *Pulls) PullOpenGraphSummary(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write(imageBytes)
 	if err != nil {
-		log.Println("failed to write pull summary card", err)
+		l.Error("failed to write pull summary card", "err", err)
 		return
 	}
 }
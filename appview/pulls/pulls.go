@@ -1,11 +1,11 @@
 package pulls
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"net/http"
 	"slices"
@@ -15,14 +15,18 @@ import (
 	"time"
 
 	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/attachments"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
 	pulls_indexer "tangled.org/core/appview/indexer/pulls"
+	"tangled.org/core/appview/labels"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/notify"
 	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
 	"tangled.org/core/appview/pages/markup"
+	"tangled.org/core/appview/pagination"
+	"tangled.org/core/appview/refcache"
 	"tangled.org/core/appview/reporesolver"
 	"tangled.org/core/appview/validator"
 	"tangled.org/core/appview/xrpcclient"
@@ -35,11 +39,16 @@ import (
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	lexutil "github.com/bluesky-social/indigo/lex/util"
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/google/uuid"
+	tlog "tangled.org/core/log"
 )
 
+// mergeFuzz is how many lines of context a merge may ignore when the merger
+// opts into it, the same as GNU patch's default fuzz factor.
+var mergeFuzz int64 = 2
+
 type Pulls struct {
 	oauth        *oauth.OAuth
 	repoResolver *reporesolver.RepoResolver
@@ -84,18 +93,20 @@ func New(
 
 // htmx fragment
 func (s *Pulls) PullActions(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "PullActions")
+
 	switch r.Method {
 	case http.MethodGet:
 		user := s.oauth.GetUser(r)
 		f, err := s.repoResolver.Resolve(r)
 		if err != nil {
-			log.Println("failed to get repo and knot", err)
+			l.Error("failed to get repo and knot", "err", err)
 			return
 		}
 
 		pull, ok := r.Context().Value("pull").(*models.Pull)
 		if !ok {
-			log.Println("failed to get pull")
+			l.Error("failed to get pull")
 			s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 			return
 		}
@@ -110,16 +121,11 @@ func (s *Pulls) PullActions(w http.ResponseWriter, r *http.Request) {
 		}
 		if roundNumber >= len(pull.Submissions) {
 			http.Error(w, "bad round id", http.StatusBadRequest)
-			log.Println("failed to parse round id", err)
+			l.Error("failed to parse round id", "err", err)
 			return
 		}
 
-		mergeCheckResponse := s.mergeCheck(r, f, pull, stack)
-		branchDeleteStatus := s.branchDeleteStatus(r, f, pull)
-		resubmitResult := pages.Unknown
-		if user.Did == pull.OwnerDid {
-			resubmitResult = s.resubmitCheck(r, f, pull, stack)
-		}
+		mergeCheckResponse, resubmitResult, branchDeleteStatus := s.pullStatus(r, f, pull, stack)
 
 		s.pages.PullActionsFragment(w, pages.PullActionsParams{
 			LoggedInUser:       user,
@@ -129,6 +135,7 @@ func (s *Pulls) PullActions(w http.ResponseWriter, r *http.Request) {
 			MergeCheck:         mergeCheckResponse,
 			ResubmitCheck:      resubmitResult,
 			BranchDeleteStatus: branchDeleteStatus,
+			PipelineBlockers:   s.pipelineStatus(f, pull, stack),
 			Stack:              stack,
 		})
 		return
@@ -136,16 +143,18 @@ func (s *Pulls) PullActions(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "RepoSinglePull")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
@@ -154,12 +163,8 @@ func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
 	stack, _ := r.Context().Value("stack").(models.Stack)
 	abandonedPulls, _ := r.Context().Value("abandonedPulls").([]*models.Pull)
 
-	mergeCheckResponse := s.mergeCheck(r, f, pull, stack)
-	branchDeleteStatus := s.branchDeleteStatus(r, f, pull)
-	resubmitResult := pages.Unknown
-	if user != nil && user.Did == pull.OwnerDid {
-		resubmitResult = s.resubmitCheck(r, f, pull, stack)
-	}
+	mergeCheckResponse, resubmitResult, branchDeleteStatus := s.pullStatus(r, f, pull, stack)
+	pipelineBlockers := s.pipelineStatus(f, pull, stack)
 
 	repoInfo := f.RepoInfo(user)
 
@@ -176,16 +181,15 @@ func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
 		shas = append(shas, p.LatestSha())
 	}
 
-	ps, err := db.GetPipelineStatuses(
+	ps, err := db.GetLatestPipelineStatuses(
 		s.db,
-		len(shas),
 		db.FilterEq("repo_owner", repoInfo.OwnerDid),
 		db.FilterEq("repo_name", repoInfo.Name),
 		db.FilterEq("knot", repoInfo.Knot),
 		db.FilterIn("sha", shas),
 	)
 	if err != nil {
-		log.Printf("failed to fetch pipeline statuses: %s", err)
+		l.Error("failed to fetch pipeline statuses", "err", err)
 		// non-fatal
 	}
 
@@ -193,9 +197,23 @@ func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
 		m[p.Sha] = p
 	}
 
+	statuses, err := db.GetCommitStatuses(
+		s.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterIn("sha", shas),
+	)
+	if err != nil {
+		l.Error("failed to fetch commit statuses", "err", err)
+		// non-fatal
+	}
+	statusesBySha := make(map[string][]models.CommitStatus)
+	for _, status := range statuses {
+		statusesBySha[status.Sha] = append(statusesBySha[status.Sha], status)
+	}
+
 	reactionMap, err := db.GetReactionMap(s.db, 20, pull.AtUri())
 	if err != nil {
-		log.Println("failed to get pull reactions")
+		l.Error("failed to get pull reactions")
 		s.pages.Notice(w, "pulls", "Failed to load pull. Try again later.")
 	}
 
@@ -204,13 +222,50 @@ func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
 		userReactions = db.GetReactionStatusMap(s.db, user.Did, pull.AtUri())
 	}
 
+	var commentAts []syntax.ATURI
+	for _, submission := range pull.Submissions {
+		for _, c := range submission.Comments {
+			commentAts = append(commentAts, c.AtUri())
+		}
+	}
+
+	commentReactions, err := db.GetReactionMaps(s.db, 20, commentAts)
+	if err != nil {
+		l.Error("failed to get pull comment reactions", "err", err)
+	}
+
+	commentUserReactions := map[syntax.ATURI]map[models.ReactionKind]bool{}
+	if user != nil {
+		commentUserReactions, err = db.GetReactionStatusMaps(s.db, user.Did, commentAts)
+		if err != nil {
+			l.Error("failed to get pull comment reaction statuses", "err", err)
+		}
+	}
+
+	readable := s.readableRepos(user)
+	s.resolveReferences(pull, readable)
+
+	backlinks, err := db.GetBacklinks(s.db, pull.AtUri(), readable)
+	if err != nil {
+		l.Error("failed to get backlinks", "err", err)
+	}
+
+	dependencies, err := db.GetPullDependencies(s.db, db.FilterEq("d.pull_at", pull.AtUri()))
+	if err != nil {
+		l.Error("failed to get pull dependencies", "err", err)
+	}
+	dependents, err := db.GetPullDependents(s.db, pull.AtUri())
+	if err != nil {
+		l.Error("failed to get pull dependents", "err", err)
+	}
+
 	labelDefs, err := db.GetLabelDefinitions(
 		s.db,
 		db.FilterIn("at_uri", f.Repo.Labels),
 		db.FilterContains("scope", tangled.RepoPullNSID),
 	)
 	if err != nil {
-		log.Println("failed to fetch labels", err)
+		l.Error("failed to fetch labels", "err", err)
 		s.pages.Error503(w)
 		return
 	}
@@ -220,6 +275,28 @@ func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
 		defs[l.AtUri().String()] = &l
 	}
 
+	stats, err := patchutil.Stats(pull.LatestPatch())
+	if err != nil {
+		l.Error("failed to compute patch stats", "err", err)
+	}
+
+	// the first round already marks when the pull was opened
+	events, err := db.GetTimelineEvents(s.db, pull.AtUri(), defs)
+	if err != nil {
+		l.Error("failed to get pull events", "err", err)
+	}
+
+	var branchDeletion *models.BranchDeletion
+	if pull.State == models.PullMerged {
+		deletions, err := db.GetBranchDeletions(s.db, db.FilterEq("pull_at", pull.AtUri()))
+		if err != nil {
+			l.Error("failed to get branch deletions", "err", err)
+		}
+		if len(deletions) > 0 {
+			branchDeletion = &deletions[0]
+		}
+	}
+
 	s.pages.RepoSinglePull(w, pages.RepoSinglePullParams{
 		LoggedInUser:       user,
 		RepoInfo:           repoInfo,
@@ -227,34 +304,60 @@ func (s *Pulls) RepoSinglePull(w http.ResponseWriter, r *http.Request) {
 		Stack:              stack,
 		AbandonedPulls:     abandonedPulls,
 		BranchDeleteStatus: branchDeleteStatus,
+		BranchDeletion:     branchDeletion,
 		MergeCheck:         mergeCheckResponse,
 		ResubmitCheck:      resubmitResult,
+		PipelineBlockers:   pipelineBlockers,
 		Pipelines:          m,
+		CommitStatuses:     statusesBySha,
+		Stats:              stats,
 
 		OrderedReactionKinds: models.OrderedReactionKinds,
 		Reactions:            reactionMap,
 		UserReacted:          userReactions,
+		CommentReactions:     commentReactions,
+		CommentUserReacted:   commentUserReactions,
+
+		Backlinks: backlinks,
+
+		Dependencies: dependencies,
+		Dependents:   dependents,
 
 		LabelDefs: defs,
+		Timelines: models.PullTimelines(pull, events),
 	})
 }
 
-func (s *Pulls) mergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull, stack models.Stack) types.MergeCheckResponse {
-	if pull.State == models.PullMerged {
-		return types.MergeCheckResponse{}
-	}
+// pullStatus runs the knot checks that make sense for the state pull is in.
+// Open pulls are checked for whether they merge cleanly and, for their
+// author, whether their source has moved on. Merged pulls are checked for
+// whether their branch can be deleted. Nothing else asks the knot.
+func (s *Pulls) pullStatus(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull, stack models.Stack) (types.MergeCheckResponse, pages.ResubmitResult, *models.BranchDeleteStatus) {
+	var mergeCheck types.MergeCheckResponse
+	resubmit := pages.Unknown
+	var branchDelete *models.BranchDeleteStatus
 
-	scheme := "https"
-	if s.config.Core.Dev {
-		scheme = "http"
+	switch pull.State {
+	case models.PullOpen:
+		mergeCheck = s.mergeCheck(r, f, pull, stack)
+		if user := s.oauth.GetUser(r); user != nil && user.Did == pull.OwnerDid {
+			resubmit = s.resubmitCheck(r, f, pull, stack)
+		}
+	case models.PullMerged:
+		branchDelete = s.branchDeleteStatus(r, f, pull)
 	}
-	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
 
-	xrpcc := indigoxrpc.Client{
-		Host: host,
-	}
+	return mergeCheck, resubmit, branchDelete
+}
+
+// mergeCheck asks the knot whether pull merges cleanly, reusing a recent
+// answer for the same patch and target branch. Answers are dropped when the
+// target branch moves.
+func (s *Pulls) mergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull, stack models.Stack) types.MergeCheckResponse {
+	l := tlog.FromContext(r.Context())
 
 	patch := pull.LatestPatch()
+	pulls := models.Stack{pull}
 	if pull.IsStacked() {
 		// combine patches of substack
 		subStack := stack.Below(pull)
@@ -262,20 +365,74 @@ func (s *Pulls) mergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *
 		mergeable := subStack.Mergeable()
 		// combine each patch
 		patch = mergeable.CombinedPatch()
+		pulls = mergeable
+	}
+
+	// no need to ask the knot about pulls that can't be merged yet
+	blocked, err := s.blockedBy(f, pulls)
+	if err != nil {
+		l.Error("failed to get pull dependencies", "err", err)
+	}
+	if blocked != "" {
+		return types.MergeCheckResponse{Error: blocked}
+	}
+
+	ttl := s.config.Patch.MergeCheckTTL
+	if ttl <= 0 {
+		return s.knotMergeCheck(r, f, pull, patch)
+	}
+
+	patchHash := markup.ContentHash(patch)
+	data, ok, err := db.GetMergeCheck(s.db, pull.AtUri(), pull.TargetBranch, patchHash, ttl)
+	if err != nil {
+		l.Error("failed to get cached merge check", "err", err)
+	}
+	if ok {
+		var result types.MergeCheckResponse
+		if err := json.Unmarshal(data, &result); err == nil {
+			return result
+		}
+	}
+
+	result := s.knotMergeCheck(r, f, pull, patch)
+
+	// errors are worth retrying on the next render
+	if result.Error == "" {
+		if data, err := json.Marshal(result); err == nil {
+			err = db.PutMergeCheck(s.db, pull.AtUri(), f.RepoAt(), pull.TargetBranch, patchHash, data)
+			if err != nil {
+				l.Error("failed to cache merge check", "err", err)
+			}
+		}
+	}
+
+	return result
+}
+
+func (s *Pulls) knotMergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull, patch string) types.MergeCheckResponse {
+	l := tlog.FromContext(r.Context())
+
+	scheme := "https"
+	if s.config.Core.Dev {
+		scheme = "http"
 	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
 	resp, xe := tangled.RepoMergeCheck(
 		r.Context(),
-		&xrpcc,
+		xrpcc,
 		&tangled.RepoMergeCheck_Input{
 			Did:    f.OwnerDid(),
 			Name:   f.Name,
 			Branch: pull.TargetBranch,
 			Patch:  patch,
+			Fuzz:   &mergeFuzz,
 		},
 	)
 	if err := xrpcclient.HandleXrpcErr(xe); err != nil {
-		log.Println("failed to check for mergeability", "err", err)
+		l.Error("failed to check for mergeability", "err", err)
 		return types.MergeCheckResponse{
 			Error: fmt.Sprintf("failed to check merge status: %s", err.Error()),
 		}
@@ -288,6 +445,14 @@ func (s *Pulls) mergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *
 			Filename: conflict.Filename,
 			Reason:   conflict.Reason,
 		}
+		for _, h := range conflict.Hunks {
+			conflicts[i].Hunks = append(conflicts[i].Hunks, types.ConflictHunk{
+				Line:   h.Line,
+				Base:   h.Base,
+				Ours:   h.Ours,
+				Theirs: h.Theirs,
+			})
+		}
 	}
 
 	result := types.MergeCheckResponse{
@@ -295,6 +460,10 @@ func (s *Pulls) mergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *
 		Conflicts:    conflicts,
 	}
 
+	if resp.Applied_with_fuzz != nil {
+		result.AppliedWithFuzz = *resp.Applied_with_fuzz
+	}
+
 	if resp.Message != nil {
 		result.Message = *resp.Message
 	}
@@ -306,11 +475,28 @@ func (s *Pulls) mergeCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *
 	return result
 }
 
-func (s *Pulls) branchDeleteStatus(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull) *models.BranchDeleteStatus {
-	if pull.State != models.PullMerged {
-		return nil
+// RecheckMerge forgets the cached merge check of a pull, so that the next
+// render asks the knot again.
+func (s *Pulls) RecheckMerge(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "RecheckMerge")
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Notice(w, "pull-recheck", "Failed to re-check pull. Try again later.")
+		return
 	}
 
+	if err := db.DeleteMergeChecks(s.db, db.FilterEq("pull_at", pull.AtUri())); err != nil {
+		l.Error("failed to delete merge check", "err", err)
+		s.pages.Notice(w, "pull-recheck", "Failed to re-check pull. Try again later.")
+		return
+	}
+
+	s.pages.HxRefresh(w)
+}
+
+func (s *Pulls) branchDeleteStatus(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull) *models.BranchDeleteStatus {
 	user := s.oauth.GetUser(r)
 	if user == nil {
 		return nil
@@ -319,7 +505,6 @@ func (s *Pulls) branchDeleteStatus(r *http.Request, f *reporesolver.ResolvedRepo
 	var branch string
 	var repo *models.Repo
 	// check if the branch exists
-	// NOTE: appview could cache branches/tags etc. for every repo by listening for gitRefUpdates
 	if pull.IsBranchBased() {
 		branch = pull.PullSource.Branch
 		repo = &f.Repo
@@ -346,23 +531,168 @@ func (s *Pulls) branchDeleteStatus(r *http.Request, f *reporesolver.ResolvedRepo
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, repo.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
-	resp, err := tangled.RepoBranch(r.Context(), xrpcc, branch, fmt.Sprintf("%s/%s", repo.Did, repo.Name))
+	xrpcBytes, err := refcache.Branches(r.Context(), s.db, xrpcc, repo.RepoAt(), fmt.Sprintf("%s/%s", repo.Did, repo.Name))
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		return nil
 	}
 
-	return &models.BranchDeleteStatus{
-		Repo:   repo,
-		Branch: resp.Name,
+	var result types.RepoBranchesResponse
+	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
+		return nil
+	}
+
+	for _, b := range result.Branches {
+		if b.Name != branch {
+			continue
+		}
+
+		// the default branch can't be deleted
+		if b.IsDefault {
+			return nil
+		}
+
+		// nor can branches that are protected by required checks
+		protected, err := db.GetRequiredChecks(
+			s.db,
+			db.FilterEq("repo_at", repo.RepoAt()),
+			db.FilterEq("branch", branch),
+		)
+		if err != nil || len(protected) > 0 {
+			return nil
+		}
+
+		targetOf, err := db.GetPulls(
+			s.db,
+			db.FilterEq("repo_at", repo.RepoAt()),
+			db.FilterEq("target_branch", branch),
+			db.FilterEq("state", models.PullOpen),
+		)
+		if err != nil {
+			return nil
+		}
+
+		status := &models.BranchDeleteStatus{
+			Repo:   repo,
+			Branch: b.Name,
+		}
+		for _, p := range targetOf {
+			status.TargetOf = append(status.TargetOf, p.PullId)
+		}
+		return status
+	}
+
+	return nil
+}
+
+// DeletePullBranch deletes the source branch of a merged pull, from the
+// repo it was pushed to, which may be a fork.
+func (s *Pulls) DeletePullBranch(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "DeletePullBranch")
+
+	noticeId := "delete-branch-error"
+	user := s.oauth.GetUser(r)
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to delete branch. Try again later.")
+		return
+	}
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Notice(w, noticeId, "Failed to delete branch. Try again later.")
+		return
+	}
+
+	if pull.State != models.PullMerged {
+		s.pages.Notice(w, noticeId, "Only the branches of merged pulls can be deleted.")
+		return
+	}
+
+	// also checks that the user can push to the source repo
+	status := s.branchDeleteStatus(r, f, pull)
+	if status == nil {
+		s.pages.Notice(w, noticeId, "This branch cannot be deleted.")
+		return
+	}
+
+	if len(status.TargetOf) > 0 && r.FormValue("confirm") != "true" {
+		var ids []string
+		for _, id := range status.TargetOf {
+			ids = append(ids, fmt.Sprintf("#%d", id))
+		}
+		s.pages.Notice(w, noticeId, fmt.Sprintf("%s is the target of open pulls %s, confirm to delete it anyway.", status.Branch, strings.Join(ids, ", ")))
+		return
+	}
+
+	deletion := s.deleteSourceBranch(r, user, pull, status)
+	if !deletion.Succeeded() {
+		s.pages.Notice(w, noticeId, fmt.Sprintf("Failed to delete branch: %s", deletion.Error))
+		return
+	}
+
+	s.pages.HxRefresh(w)
+}
+
+// deleteSourceBranch asks the knot of the source repo to delete the branch
+// of status, and records the outcome against pull. Callers are expected to
+// have checked status with branchDeleteStatus.
+func (s *Pulls) deleteSourceBranch(r *http.Request, user *oauth.User, pull *models.Pull, status *models.BranchDeleteStatus) models.BranchDeletion {
+	l := tlog.FromContext(r.Context())
+
+	deletion := models.BranchDeletion{
+		PullAt: pull.AtUri(),
+		RepoAt: status.Repo.RepoAt(),
+		Branch: status.Branch,
+		Did:    user.Did,
+	}
+
+	client, err := s.oauth.ServiceClient(
+		r,
+		oauth.WithService(status.Repo.Knot),
+		oauth.WithLxm(tangled.RepoDeleteBranchNSID),
+		oauth.WithoutRetry(),
+		oauth.WithDev(s.config.Core.Dev),
+	)
+	if err != nil {
+		l.Error("failed to connect to knot server", "err", err)
+		deletion.Error = "failed to connect to knotserver"
+	} else {
+		err = tangled.RepoDeleteBranch(
+			r.Context(),
+			client,
+			&tangled.RepoDeleteBranch_Input{
+				Branch: status.Branch,
+				Repo:   status.Repo.RepoAt().String(),
+			},
+		)
+		if err := xrpcclient.HandleXrpcErr(err); err != nil {
+			l.Error("failed to delete branch", "err", err)
+			deletion.Error = err.Error()
+		}
+	}
+
+	if deletion.Succeeded() {
+		// don't wait for the knot to report the deletion
+		if err := refcache.Invalidate(s.db, status.Repo.RepoAt(), plumbing.NewBranchReferenceName(status.Branch).String()); err != nil {
+			l.Error("failed to invalidate ref cache", "err", err)
+		}
+	}
+
+	if err := db.AddBranchDeletion(s.db, deletion); err != nil {
+		l.Error("failed to record branch deletion", "err", err)
 	}
+
+	return deletion
 }
 
 func (s *Pulls) resubmitCheck(r *http.Request, f *reporesolver.ResolvedRepo, pull *models.Pull, stack models.Stack) pages.ResubmitResult {
-	if pull.State == models.PullMerged || pull.State == models.PullDeleted || pull.PullSource == nil {
+	l := tlog.FromContext(r.Context())
+
+	if pull.PullSource == nil {
 		return pages.Unknown
 	}
 
@@ -372,7 +702,7 @@ func (s *Pulls) resubmitCheck(r *http.Request, f *reporesolver.ResolvedRepo, pul
 		// fork-based pulls
 		sourceRepo, err := db.GetRepoByAtUri(s.db, pull.PullSource.RepoAt.String())
 		if err != nil {
-			log.Println("failed to get source repo", err)
+			l.Error("failed to get source repo", "err", err)
 			return pages.Unknown
 		}
 
@@ -391,18 +721,16 @@ func (s *Pulls) resubmitCheck(r *http.Request, f *reporesolver.ResolvedRepo, pul
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", ownerDid, repoName)
 	branchResp, err := tangled.RepoBranch(r.Context(), xrpcc, pull.PullSource.Branch, repo)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.branches", xrpcerr)
+			l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 			return pages.Unknown
 		}
-		log.Println("failed to reach knotserver", err)
+		l.Error("failed to reach knotserver", "err", err)
 		return pages.Unknown
 	}
 
@@ -423,10 +751,12 @@ func (s *Pulls) resubmitCheck(r *http.Request, f *reporesolver.ResolvedRepo, pul
 }
 
 func (s *Pulls) RepoPullPatch(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "RepoPullPatch")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -437,7 +767,7 @@ func (s *Pulls) RepoPullPatch(w http.ResponseWriter, r *http.Request) {
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
@@ -448,13 +778,15 @@ func (s *Pulls) RepoPullPatch(w http.ResponseWriter, r *http.Request) {
 	roundIdInt, err := strconv.Atoi(roundId)
 	if err != nil || roundIdInt >= len(pull.Submissions) {
 		http.Error(w, "bad round id", http.StatusBadRequest)
-		log.Println("failed to parse round id", err)
+		l.Error("failed to parse round id", "err", err)
 		return
 	}
 
 	patch := pull.Submissions[roundIdInt].CombinedPatch()
 	diff := patchutil.AsNiceDiff(patch, pull.TargetBranch)
 
+	s.resolveReferences(pull, s.readableRepos(user))
+
 	s.pages.RepoPullPatchPage(w, pages.RepoPullPatchParams{
 		LoggedInUser: user,
 		RepoInfo:     f.RepoInfo(user),
@@ -469,11 +801,13 @@ func (s *Pulls) RepoPullPatch(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) RepoPullInterdiff(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "RepoPullInterdiff")
+
 	user := s.oauth.GetUser(r)
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -484,55 +818,87 @@ func (s *Pulls) RepoPullInterdiff(w http.ResponseWriter, r *http.Request) {
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to get pull.")
 		return
 	}
 
+	// the round in the path is compared against the one before it, unless
+	// other rounds are picked with ?from= and ?to=
 	roundId := chi.URLParam(r, "round")
 	roundIdInt, err := strconv.Atoi(roundId)
-	if err != nil || roundIdInt >= len(pull.Submissions) {
+	if err != nil || roundIdInt < 0 || roundIdInt >= len(pull.Submissions) {
 		http.Error(w, "bad round id", http.StatusBadRequest)
-		log.Println("failed to parse round id", err)
+		l.Error("failed to parse round id", "err", err)
 		return
 	}
 
-	if roundIdInt == 0 {
+	to := roundIdInt
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = strconv.Atoi(v)
+		if err != nil || to < 0 || to >= len(pull.Submissions) {
+			http.Error(w, "bad round id", http.StatusBadRequest)
+			l.Error("failed to parse to round", "err", err)
+			return
+		}
+	}
+
+	from := to - 1
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err = strconv.Atoi(v)
+		if err != nil || from < 0 || from >= len(pull.Submissions) {
+			http.Error(w, "bad round id", http.StatusBadRequest)
+			l.Error("failed to parse from round", "err", err)
+			return
+		}
+	}
+
+	if from < 0 {
 		http.Error(w, "bad round id", http.StatusBadRequest)
-		log.Println("cannot interdiff initial submission")
+		l.Warn("cannot interdiff initial submission")
+		return
+	}
+
+	if from >= to {
+		http.Error(w, "the round to compare from must come before the round to compare to", http.StatusBadRequest)
 		return
 	}
 
-	currentPatch, err := patchutil.AsDiff(pull.Submissions[roundIdInt].CombinedPatch())
+	currentPatch, err := patchutil.AsDiff(pull.Submissions[to].CombinedPatch())
 	if err != nil {
-		log.Println("failed to interdiff; current patch malformed")
+		l.Error("failed to interdiff; current patch malformed")
 		s.pages.Notice(w, fmt.Sprintf("interdiff-error-%d", roundIdInt), "Failed to calculate interdiff; current patch is invalid.")
 		return
 	}
 
-	previousPatch, err := patchutil.AsDiff(pull.Submissions[roundIdInt-1].CombinedPatch())
+	previousPatch, err := patchutil.AsDiff(pull.Submissions[from].CombinedPatch())
 	if err != nil {
-		log.Println("failed to interdiff; previous patch malformed")
+		l.Error("failed to interdiff; previous patch malformed")
 		s.pages.Notice(w, fmt.Sprintf("interdiff-error-%d", roundIdInt), "Failed to calculate interdiff; previous patch is invalid.")
 		return
 	}
 
 	interdiff := patchutil.Interdiff(previousPatch, currentPatch)
 
+	s.resolveReferences(pull, s.readableRepos(user))
+
 	s.pages.RepoPullInterdiffPage(w, pages.RepoPullInterdiffParams{
 		LoggedInUser: s.oauth.GetUser(r),
 		RepoInfo:     f.RepoInfo(user),
 		Pull:         pull,
-		Round:        roundIdInt,
+		Round:        to,
+		From:         from,
 		Interdiff:    interdiff,
 		DiffOpts:     diffOpts,
 	})
 }
 
 func (s *Pulls) RepoPullPatchRaw(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "RepoPullPatchRaw")
+
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
@@ -541,7 +907,7 @@ func (s *Pulls) RepoPullPatchRaw(w http.ResponseWriter, r *http.Request) {
 	roundIdInt, err := strconv.Atoi(roundId)
 	if err != nil || roundIdInt >= len(pull.Submissions) {
 		http.Error(w, "bad round id", http.StatusBadRequest)
-		log.Println("failed to parse round id", err)
+		l.Error("failed to parse round id", "err", err)
 		return
 	}
 
@@ -550,7 +916,7 @@ func (s *Pulls) RepoPullPatchRaw(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
-	l := s.logger.With("handler", "RepoPulls")
+	l := tlog.FromContext(r.Context()).With("handler", "RepoPulls")
 
 	user := s.oauth.GetUser(r)
 	params := r.URL.Query()
@@ -565,10 +931,22 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
+	page := pagination.FromContext(r.Context())
+
+	totalPulls := 0
+	switch state {
+	case models.PullOpen:
+		totalPulls = f.RepoStats.PullCount.Open
+	case models.PullMerged:
+		totalPulls = f.RepoStats.PullCount.Merged
+	case models.PullClosed:
+		totalPulls = f.RepoStats.PullCount.Closed
+	}
+
 	keyword := params.Get("q")
 
 	var ids []int64
@@ -576,7 +954,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 		Keyword: keyword,
 		RepoAt:  f.RepoAt().String(),
 		State:   state,
-		// Page: page,
+		Page:    page,
 	}
 	l.Debug("searching with", "searchOpts", searchOpts)
 	if keyword != "" {
@@ -586,6 +964,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		ids = res.Hits
+		totalPulls = int(res.Total)
 		l.Debug("searched pulls with indexer", "count", len(ids))
 	} else {
 		ids, err = db.GetPullIDs(s.db, searchOpts)
@@ -601,7 +980,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 		db.FilterIn("id", ids),
 	)
 	if err != nil {
-		log.Println("failed to get pulls", err)
+		l.Error("failed to get pulls", "err", err)
 		s.pages.Notice(w, "pulls", "Failed to load pulls. Try again later.")
 		return
 	}
@@ -612,7 +991,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 			if p.PullSource.RepoAt != nil {
 				pullSourceRepo, err = db.GetRepoByAtUri(s.db, p.PullSource.RepoAt.String())
 				if err != nil {
-					log.Printf("failed to get repo by at uri: %v", err)
+					l.Error("failed to get repo by at uri", "err", err)
 					continue
 				} else {
 					p.PullSource.Repo = pullSourceRepo
@@ -623,6 +1002,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 
 	// we want to group all stacked PRs into just one list
 	stacks := make(map[string]models.Stack)
+	var stackIds []string
 	var shas []string
 	n := 0
 	for _, p := range pulls {
@@ -636,6 +1016,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 				// skip this PR
 			} else {
 				stacks[p.StackId] = nil
+				stackIds = append(stackIds, p.StackId)
 				pulls[n] = p
 				n++
 			}
@@ -646,17 +1027,40 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 	}
 	pulls = pulls[:n]
 
+	// list every rung of each stack in stack order, including the ones that
+	// were filtered out above, so that their pipelines can be shown as well
+	fullStacks, err := db.GetStacks(s.db, stackIds)
+	if err != nil {
+		l.Error("failed to fetch stacks", "err", err)
+		// non-fatal, fall back to the pulls in this listing
+	}
+	for _, p := range pulls {
+		stack, ok := fullStacks[p.StackId]
+		if !ok {
+			continue
+		}
+
+		var others models.Stack
+		for _, sp := range stack {
+			if sp.ChangeId == p.ChangeId {
+				continue
+			}
+			others = append(others, sp)
+			shas = append(shas, sp.LatestSha())
+		}
+		stacks[p.StackId] = others
+	}
+
 	repoInfo := f.RepoInfo(user)
-	ps, err := db.GetPipelineStatuses(
+	ps, err := db.GetLatestPipelineStatuses(
 		s.db,
-		len(shas),
 		db.FilterEq("repo_owner", repoInfo.OwnerDid),
 		db.FilterEq("repo_name", repoInfo.Name),
 		db.FilterEq("knot", repoInfo.Knot),
 		db.FilterIn("sha", shas),
 	)
 	if err != nil {
-		log.Printf("failed to fetch pipeline statuses: %s", err)
+		l.Error("failed to fetch pipeline statuses", "err", err)
 		// non-fatal
 	}
 	m := make(map[string]models.Pipeline)
@@ -670,7 +1074,7 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 		db.FilterContains("scope", tangled.RepoPullNSID),
 	)
 	if err != nil {
-		log.Println("failed to fetch labels", err)
+		l.Error("failed to fetch labels", "err", err)
 		s.pages.Error503(w)
 		return
 	}
@@ -680,30 +1084,40 @@ func (s *Pulls) RepoPulls(w http.ResponseWriter, r *http.Request) {
 		defs[l.AtUri().String()] = &l
 	}
 
+	stats := make(map[int]types.PatchStat)
+	for _, p := range pulls {
+		if st, err := patchutil.Stats(p.LatestPatch()); err == nil {
+			stats[p.PullId] = st
+		}
+	}
+
 	s.pages.RepoPulls(w, pages.RepoPullsParams{
 		LoggedInUser: s.oauth.GetUser(r),
 		RepoInfo:     f.RepoInfo(user),
 		Pulls:        pulls,
+		PullCount:    totalPulls,
+		Page:         page,
 		LabelDefs:    defs,
 		FilteringBy:  state,
 		FilterQuery:  keyword,
 		Stacks:       stacks,
 		Pipelines:    m,
+		Stats:        stats,
 	})
 }
 
 func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
-	l := s.logger.With("handler", "PullComment")
+	l := tlog.FromContext(r.Context()).With("handler", "PullComment")
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
@@ -712,7 +1126,7 @@ func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
 	roundNumber, err := strconv.Atoi(roundNumberStr)
 	if err != nil || roundNumber >= len(pull.Submissions) {
 		http.Error(w, "bad round id", http.StatusBadRequest)
-		log.Println("failed to parse round id", err)
+		l.Error("failed to parse round id", "err", err)
 		return
 	}
 
@@ -735,7 +1149,7 @@ func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
 		// Start a transaction
 		tx, err := s.db.BeginTx(r.Context(), nil)
 		if err != nil {
-			log.Println("failed to start transaction", err)
+			l.Error("failed to start transaction", "err", err)
 			s.pages.Notice(w, "pull-comment", "Failed to create comment.")
 			return
 		}
@@ -745,7 +1159,7 @@ func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
 
 		client, err := s.oauth.AuthorizedClient(r)
 		if err != nil {
-			log.Println("failed to get authorized client", err)
+			l.Error("failed to get authorized client", "err", err)
 			s.pages.Notice(w, "pull-comment", "Failed to create comment.")
 			return
 		}
@@ -762,7 +1176,7 @@ func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 		if err != nil {
-			log.Println("failed to create pull comment", err)
+			l.Error("failed to create pull comment", "err", err)
 			s.pages.Notice(w, "pull-comment", "Failed to create comment.")
 			return
 		}
@@ -779,14 +1193,22 @@ func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
 		// Create the pull comment in the database with the commentAt field
 		commentId, err := db.NewPullComment(tx, comment)
 		if err != nil {
-			log.Println("failed to create pull comment", err)
+			l.Error("failed to create pull comment", "err", err)
 			s.pages.Notice(w, "pull-comment", "Failed to create comment.")
 			return
 		}
 
+		err = db.PutReferenceLinks(tx, pull.RepoAt, comment.AtUri(), pull.AtUri(), markup.FindReferences(comment.Body))
+		if err != nil {
+			l.Error("failed to record references", "err", err)
+		}
+		if err := db.MarkAttachmentsUsed(tx, comment.OwnerDid, attachments.Find(comment.Body)); err != nil {
+			l.Error("failed to mark attachments used", "err", err)
+		}
+
 		// Commit the transaction
 		if err = tx.Commit(); err != nil {
-			log.Println("failed to commit transaction", err)
+			l.Error("failed to commit transaction", "err", err)
 			s.pages.Notice(w, "pull-comment", "Failed to create comment.")
 			return
 		}
@@ -808,10 +1230,12 @@ func (s *Pulls) PullComment(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) NewPull(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "NewPull")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -822,25 +1246,23 @@ func (s *Pulls) NewPull(w http.ResponseWriter, r *http.Request) {
 			scheme = "https"
 		}
 		host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-		xrpcc := &indigoxrpc.Client{
-			Host: host,
-		}
+		xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
 		repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-		xrpcBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+		xrpcBytes, err := refcache.Branches(r.Context(), s.db, xrpcc, f.RepoAt(), repo)
 		if err != nil {
 			if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-				log.Println("failed to call XRPC repo.branches", xrpcerr)
+				l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 				s.pages.Error503(w)
 				return
 			}
-			log.Println("failed to fetch branches", err)
+			l.Error("failed to fetch branches", "err", err)
 			return
 		}
 
 		var result types.RepoBranchesResponse
 		if err := json.Unmarshal(xrpcBytes, &result); err != nil {
-			log.Println("failed to decode XRPC response", err)
+			l.Error("failed to decode XRPC response", "err", err)
 			s.pages.Error503(w)
 			return
 		}
@@ -851,6 +1273,11 @@ func (s *Pulls) NewPull(w http.ResponseWriter, r *http.Request) {
 		sourceBranch := r.URL.Query().Get("sourceBranch")
 		targetBranch := r.URL.Query().Get("targetBranch")
 
+		labelPanel, err := labels.NewSubjectPanel(s.db, &f.Repo, f.RepoInfo(user), tangled.RepoPullNSID)
+		if err != nil {
+			l.Error("failed to fetch labels", "err", err)
+		}
+
 		s.pages.RepoNewPull(w, pages.RepoNewPullParams{
 			LoggedInUser: user,
 			RepoInfo:     f.RepoInfo(user),
@@ -860,6 +1287,7 @@ func (s *Pulls) NewPull(w http.ResponseWriter, r *http.Request) {
 			TargetBranch: targetBranch,
 			Title:        r.URL.Query().Get("title"),
 			Body:         r.URL.Query().Get("body"),
+			LabelPanel:   labelPanel,
 		})
 
 	case http.MethodPost:
@@ -982,31 +1410,31 @@ func (s *Pulls) handleBranchBasedPull(
 	sourceBranch string,
 	isStacked bool,
 ) {
+	l := tlog.FromContext(r.Context())
+
 	scheme := "http"
 	if !s.config.Core.Dev {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 	xrpcBytes, err := tangled.RepoCompare(r.Context(), xrpcc, repo, targetBranch, sourceBranch)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.compare", xrpcerr)
+			l.Error("failed to call XRPC repo.compare", "err", xrpcerr)
 			s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 			return
 		}
-		log.Println("failed to compare", err)
+		l.Error("failed to compare", "err", err)
 		s.pages.Notice(w, "pull", err.Error())
 		return
 	}
 
 	var comparison types.RepoFormatPatchResponse
 	if err := json.Unmarshal(xrpcBytes, &comparison); err != nil {
-		log.Println("failed to decode XRPC compare response", err)
+		l.Error("failed to decode XRPC compare response", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1016,7 +1444,7 @@ func (s *Pulls) handleBranchBasedPull(
 	combined := comparison.CombinedPatchRaw
 
 	if err := s.validator.ValidatePatch(&patch); err != nil {
-		s.logger.Error("failed to validate patch", "err", err)
+		l.Error("failed to validate patch", "err", err)
 		s.pages.Notice(w, "pull", "Invalid patch format. Please provide a valid diff.")
 		return
 	}
@@ -1034,7 +1462,7 @@ func (s *Pulls) handleBranchBasedPull(
 
 func (s *Pulls) handlePatchBasedPull(w http.ResponseWriter, r *http.Request, f *reporesolver.ResolvedRepo, user *oauth.User, title, body, targetBranch, patch string, isStacked bool) {
 	if err := s.validator.ValidatePatch(&patch); err != nil {
-		s.logger.Error("patch validation failed", "err", err)
+		tlog.FromContext(r.Context()).Error("patch validation failed", "err", err)
 		s.pages.Notice(w, "pull", "Invalid patch format. Please provide a valid diff.")
 		return
 	}
@@ -1043,6 +1471,8 @@ func (s *Pulls) handlePatchBasedPull(w http.ResponseWriter, r *http.Request, f *
 }
 
 func (s *Pulls) handleForkBasedPull(w http.ResponseWriter, r *http.Request, f *reporesolver.ResolvedRepo, user *oauth.User, forkRepo string, title, body, targetBranch, sourceBranch string, isStacked bool) {
+	l := tlog.FromContext(r.Context())
+
 	repoString := strings.SplitN(forkRepo, "/", 2)
 	forkOwnerDid := repoString[0]
 	repoName := repoString[1]
@@ -1051,7 +1481,7 @@ func (s *Pulls) handleForkBasedPull(w http.ResponseWriter, r *http.Request, f *r
 		s.pages.Notice(w, "pull", "No such fork.")
 		return
 	} else if err != nil {
-		log.Println("failed to fetch fork:", err)
+		l.Error("failed to fetch fork", "err", err)
 		s.pages.Notice(w, "pull", "Failed to fetch fork.")
 		return
 	}
@@ -1097,26 +1527,24 @@ func (s *Pulls) handleForkBasedPull(w http.ResponseWriter, r *http.Request, f *r
 		forkScheme = "https"
 	}
 	forkHost := fmt.Sprintf("%s://%s", forkScheme, fork.Knot)
-	forkXrpcc := &indigoxrpc.Client{
-		Host: forkHost,
-	}
+	forkXrpcc := xrpcclient.NewClient(s.config.KnotClient, forkHost)
 
 	forkRepoId := fmt.Sprintf("%s/%s", fork.Did, fork.Name)
 	forkXrpcBytes, err := tangled.RepoCompare(r.Context(), forkXrpcc, forkRepoId, hiddenRef, sourceBranch)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.compare for fork", xrpcerr)
+			l.Error("failed to call XRPC repo.compare for fork", "err", xrpcerr)
 			s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 			return
 		}
-		log.Println("failed to compare across branches", err)
+		l.Error("failed to compare across branches", "err", err)
 		s.pages.Notice(w, "pull", err.Error())
 		return
 	}
 
 	var comparison types.RepoFormatPatchResponse
 	if err := json.Unmarshal(forkXrpcBytes, &comparison); err != nil {
-		log.Println("failed to decode XRPC compare response for fork", err)
+		l.Error("failed to decode XRPC compare response for fork", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1126,7 +1554,7 @@ func (s *Pulls) handleForkBasedPull(w http.ResponseWriter, r *http.Request, f *r
 	combined := comparison.CombinedPatchRaw
 
 	if err := s.validator.ValidatePatch(&patch); err != nil {
-		s.logger.Error("failed to validate patch", "err", err)
+		l.Error("failed to validate patch", "err", err)
 		s.pages.Notice(w, "pull", "Invalid patch format. Please provide a valid diff.")
 		return
 	}
@@ -1160,6 +1588,8 @@ func (s *Pulls) createPullRequest(
 	recordPullSource *tangled.RepoPull_Source,
 	isStacked bool,
 ) {
+	l := tlog.FromContext(r.Context())
+
 	if isStacked {
 		// creates a series of PRs, each linking to the previous, identified by jj's change-id
 		s.createStackedPullRequest(
@@ -1177,14 +1607,14 @@ func (s *Pulls) createPullRequest(
 
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to get authorized client", err)
+		l.Error("failed to get authorized client", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
 
 	tx, err := s.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start tx")
+		l.Error("failed to start tx")
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1231,42 +1661,72 @@ func (s *Pulls) createPullRequest(
 	}
 	err = db.NewPull(tx, pull)
 	if err != nil {
-		log.Println("failed to create pull request", err)
+		l.Error("failed to create pull request", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
+	err = db.PutReferenceLinks(tx, pull.RepoAt, pull.AtUri(), pull.AtUri(), markup.FindReferences(pull.Body))
+	if err != nil {
+		l.Error("failed to record references", "err", err)
+	}
+	if err := db.MarkAttachmentsUsed(tx, pull.OwnerDid, attachments.Find(pull.Body)); err != nil {
+		l.Error("failed to mark attachments used", "err", err)
+	}
 	pullId, err := db.NextPullId(tx, f.RepoAt())
 	if err != nil {
-		log.Println("failed to get pull id", err)
+		l.Error("failed to get pull id", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
 
-	_, err = comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
-		Collection: tangled.RepoPullNSID,
-		Repo:       user.Did,
-		Rkey:       rkey,
-		Record: &lexutil.LexiconTypeDecoder{
-			Val: &tangled.RepoPull{
-				Title: title,
-				Target: &tangled.RepoPull_Target{
-					Repo:   string(f.RepoAt()),
-					Branch: targetBranch,
+	labelOps, err := labels.NewSubjectOps(s.db, s.validator, &f.Repo, user.Did, pull.AtUri(), r.Form)
+	if err != nil {
+		l.Warn("invalid labels", "err", err)
+		s.pages.Notice(w, "pull", fmt.Sprintf("Failed to create pull request: %s", err))
+		return
+	}
+	for _, op := range labelOps {
+		if _, err := db.AddLabelOp(tx, &op); err != nil {
+			l.Error("failed to add label", "err", err)
+			s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
+			return
+		}
+	}
+
+	// the picked labels are written along with the pull
+	writes := []*comatproto.RepoApplyWrites_Input_Writes_Elem{{
+		RepoApplyWrites_Create: &comatproto.RepoApplyWrites_Create{
+			Collection: tangled.RepoPullNSID,
+			Rkey:       &rkey,
+			Value: &lexutil.LexiconTypeDecoder{
+				Val: &tangled.RepoPull{
+					Title: title,
+					Target: &tangled.RepoPull_Target{
+						Repo:   string(f.RepoAt()),
+						Branch: targetBranch,
+					},
+					Patch:     patch,
+					Source:    recordPullSource,
+					CreatedAt: time.Now().Format(time.RFC3339),
 				},
-				Patch:     patch,
-				Source:    recordPullSource,
-				CreatedAt: time.Now().Format(time.RFC3339),
 			},
 		},
+	}}
+	if len(labelOps) > 0 {
+		writes = append(writes, labels.SubjectOpsWrite(labelOps))
+	}
+	_, err = comatproto.RepoApplyWrites(r.Context(), client, &comatproto.RepoApplyWrites_Input{
+		Repo:   user.Did,
+		Writes: writes,
 	})
 	if err != nil {
-		log.Println("failed to create pull request", err)
+		l.Error("failed to create pull request", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
 
 	if err = tx.Commit(); err != nil {
-		log.Println("failed to create pull request", err)
+		l.Error("failed to create pull request", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1286,25 +1746,27 @@ func (s *Pulls) createStackedPullRequest(
 	sourceRev string,
 	pullSource *models.PullSource,
 ) {
+	l := tlog.FromContext(r.Context())
+
 	// run some necessary checks for stacked-prs first
 
 	//  must be branch or fork based
 	if sourceRev == "" {
-		log.Println("stacked PR from patch-based pull")
+		l.Warn("stacked PR from patch-based pull")
 		s.pages.Notice(w, "pull", "Stacking is only supported on branch and fork based pull-requests.")
 		return
 	}
 
 	formatPatches, err := patchutil.ExtractPatches(patch)
 	if err != nil {
-		log.Println("failed to extract patches", err)
+		l.Error("failed to extract patches", "err", err)
 		s.pages.Notice(w, "pull", fmt.Sprintf("Failed to extract patches: %v", err))
 		return
 	}
 
 	//  must have atleast 1 patch to begin with
 	if len(formatPatches) == 0 {
-		log.Println("empty patches")
+		l.Warn("empty patches")
 		s.pages.Notice(w, "pull", "No patches found in the generated format-patch.")
 		return
 	}
@@ -1313,20 +1775,22 @@ func (s *Pulls) createStackedPullRequest(
 	stackId := uuid.New()
 	stack, err := newStack(f, user, targetBranch, patch, pullSource, stackId.String())
 	if err != nil {
-		log.Println("failed to create stack", err)
+		l.Error("failed to create stack", "err", err)
 		s.pages.Notice(w, "pull", fmt.Sprintf("Failed to create stack: %v", err))
 		return
 	}
 
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to get authorized client", err)
+		l.Error("failed to get authorized client", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
 
-	// apply all record creations at once
+	// apply all record creations at once, every pull of the stack gets the
+	// picked labels
 	var writes []*comatproto.RepoApplyWrites_Input_Writes_Elem
+	var labelOps []models.LabelOp
 	for _, p := range stack {
 		record := p.AsRecord()
 		write := comatproto.RepoApplyWrites_Input_Writes_Elem{
@@ -1339,13 +1803,24 @@ func (s *Pulls) createStackedPullRequest(
 			},
 		}
 		writes = append(writes, &write)
-	}
-	_, err = comatproto.RepoApplyWrites(r.Context(), client, &comatproto.RepoApplyWrites_Input{
-		Repo:   user.Did,
-		Writes: writes,
+
+		ops, err := labels.NewSubjectOps(s.db, s.validator, &f.Repo, user.Did, p.AtUri(), r.Form)
+		if err != nil {
+			l.Warn("invalid labels", "err", err)
+			s.pages.Notice(w, "pull", fmt.Sprintf("Failed to create stacked pull request: %s", err))
+			return
+		}
+		if len(ops) > 0 {
+			writes = append(writes, labels.SubjectOpsWrite(ops))
+			labelOps = append(labelOps, ops...)
+		}
+	}
+	_, err = comatproto.RepoApplyWrites(r.Context(), client, &comatproto.RepoApplyWrites_Input{
+		Repo:   user.Did,
+		Writes: writes,
 	})
 	if err != nil {
-		log.Println("failed to create stacked pull request", err)
+		l.Error("failed to create stacked pull request", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create stacked pull request. Try again later.")
 		return
 	}
@@ -1353,7 +1828,7 @@ func (s *Pulls) createStackedPullRequest(
 	// create all pulls at once
 	tx, err := s.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start tx")
+		l.Error("failed to start tx")
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1362,14 +1837,30 @@ func (s *Pulls) createStackedPullRequest(
 	for _, p := range stack {
 		err = db.NewPull(tx, p)
 		if err != nil {
-			log.Println("failed to create pull request", err)
+			l.Error("failed to create pull request", "err", err)
+			s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
+			return
+		}
+
+		err = db.PutReferenceLinks(tx, p.RepoAt, p.AtUri(), p.AtUri(), markup.FindReferences(p.Body))
+		if err != nil {
+			l.Error("failed to record references", "err", err)
+		}
+		if err := db.MarkAttachmentsUsed(tx, p.OwnerDid, attachments.Find(p.Body)); err != nil {
+			l.Error("failed to mark attachments used", "err", err)
+		}
+	}
+
+	for _, op := range labelOps {
+		if _, err := db.AddLabelOp(tx, &op); err != nil {
+			l.Error("failed to add label", "err", err)
 			s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 			return
 		}
 	}
 
 	if err = tx.Commit(); err != nil {
-		log.Println("failed to create pull request", err)
+		l.Error("failed to create pull request", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1378,9 +1869,11 @@ func (s *Pulls) createStackedPullRequest(
 }
 
 func (s *Pulls) ValidatePatch(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "ValidatePatch")
+
 	_, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -1391,7 +1884,7 @@ func (s *Pulls) ValidatePatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.validator.ValidatePatch(&patch); err != nil {
-		s.logger.Error("faield to validate patch", "err", err)
+		l.Error("failed to validate patch", "err", err)
 		s.pages.Notice(w, "patch-error", "Invalid patch format. Please provide a valid git diff or format-patch.")
 		return
 	}
@@ -1404,10 +1897,12 @@ func (s *Pulls) ValidatePatch(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) PatchUploadFragment(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "PatchUploadFragment")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -1417,10 +1912,12 @@ func (s *Pulls) PatchUploadFragment(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) CompareBranchesFragment(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "CompareBranchesFragment")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -1429,25 +1926,23 @@ func (s *Pulls) CompareBranchesFragment(w http.ResponseWriter, r *http.Request)
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-	xrpcBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+	xrpcBytes, err := refcache.Branches(r.Context(), s.db, xrpcc, f.RepoAt(), repo)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.branches", xrpcerr)
+			l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 			s.pages.Error503(w)
 			return
 		}
-		log.Println("failed to fetch branches", err)
+		l.Error("failed to fetch branches", "err", err)
 		return
 	}
 
 	var result types.RepoBranchesResponse
 	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
-		log.Println("failed to decode XRPC response", err)
+		l.Error("failed to decode XRPC response", "err", err)
 		s.pages.Error503(w)
 		return
 	}
@@ -1471,17 +1966,67 @@ func (s *Pulls) CompareBranchesFragment(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// CommitPreviewFragment lists the commits a branch-based pull would introduce,
+// so the author can check them before opening the pull.
+func (s *Pulls) CommitPreviewFragment(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "CommitPreviewFragment")
+
+	user := s.oauth.GetUser(r)
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	targetBranch := r.URL.Query().Get("targetBranch")
+	sourceBranch := r.URL.Query().Get("sourceBranch")
+	if targetBranch == "" || sourceBranch == "" || targetBranch == sourceBranch {
+		return
+	}
+
+	scheme := "http"
+	if !s.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
+
+	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+	xrpcBytes, err := tangled.RepoCompare(r.Context(), xrpcc, repo, targetBranch, sourceBranch)
+	if err != nil {
+		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+			l.Error("failed to call XRPC repo.compare", "err", xrpcerr)
+			return
+		}
+		l.Error("failed to compare", "err", err)
+		return
+	}
+
+	var comparison types.RepoFormatPatchResponse
+	if err := json.Unmarshal(xrpcBytes, &comparison); err != nil {
+		l.Error("failed to decode XRPC compare response", "err", err)
+		return
+	}
+
+	s.pages.PullCommitPreviewFragment(w, pages.PullCommitPreviewParams{
+		RepoInfo: f.RepoInfo(user),
+		Commits:  comparison.CompareCommits(),
+	})
+}
+
 func (s *Pulls) CompareForksFragment(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "CompareForksFragment")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	forks, err := db.GetForksByDid(s.db, user.Did)
 	if err != nil {
-		log.Println("failed to get forks", err)
+		l.Error("failed to get forks", "err", err)
 		return
 	}
 
@@ -1493,11 +2038,13 @@ func (s *Pulls) CompareForksFragment(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) CompareForksBranchesFragment(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "CompareForksBranchesFragment")
+
 	user := s.oauth.GetUser(r)
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -1512,7 +2059,7 @@ func (s *Pulls) CompareForksBranchesFragment(w http.ResponseWriter, r *http.Requ
 		db.FilterEq("name", forkName),
 	)
 	if err != nil {
-		log.Println("failed to get repo", "did", forkOwnerDid, "name", forkName, "err", err)
+		l.Error("failed to get repo", "did", forkOwnerDid, "name", forkName, "err", err)
 		return
 	}
 
@@ -1521,26 +2068,24 @@ func (s *Pulls) CompareForksBranchesFragment(w http.ResponseWriter, r *http.Requ
 		sourceScheme = "https"
 	}
 	sourceHost := fmt.Sprintf("%s://%s", sourceScheme, repo.Knot)
-	sourceXrpcc := &indigoxrpc.Client{
-		Host: sourceHost,
-	}
+	sourceXrpcc := xrpcclient.NewClient(s.config.KnotClient, sourceHost)
 
 	sourceRepo := fmt.Sprintf("%s/%s", forkOwnerDid, repo.Name)
-	sourceXrpcBytes, err := tangled.RepoBranches(r.Context(), sourceXrpcc, "", 0, sourceRepo)
+	sourceXrpcBytes, err := refcache.Branches(r.Context(), s.db, sourceXrpcc, repo.RepoAt(), sourceRepo)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.branches for source", xrpcerr)
+			l.Error("failed to call XRPC repo.branches for source", "err", xrpcerr)
 			s.pages.Error503(w)
 			return
 		}
-		log.Println("failed to fetch source branches", err)
+		l.Error("failed to fetch source branches", "err", err)
 		return
 	}
 
 	// Decode source branches
 	var sourceBranches types.RepoBranchesResponse
 	if err := json.Unmarshal(sourceXrpcBytes, &sourceBranches); err != nil {
-		log.Println("failed to decode source branches XRPC response", err)
+		l.Error("failed to decode source branches XRPC response", "err", err)
 		s.pages.Error503(w)
 		return
 	}
@@ -1550,26 +2095,24 @@ func (s *Pulls) CompareForksBranchesFragment(w http.ResponseWriter, r *http.Requ
 		targetScheme = "https"
 	}
 	targetHost := fmt.Sprintf("%s://%s", targetScheme, f.Knot)
-	targetXrpcc := &indigoxrpc.Client{
-		Host: targetHost,
-	}
+	targetXrpcc := xrpcclient.NewClient(s.config.KnotClient, targetHost)
 
 	targetRepo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-	targetXrpcBytes, err := tangled.RepoBranches(r.Context(), targetXrpcc, "", 0, targetRepo)
+	targetXrpcBytes, err := refcache.Branches(r.Context(), s.db, targetXrpcc, f.RepoAt(), targetRepo)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.branches for target", xrpcerr)
+			l.Error("failed to call XRPC repo.branches for target", "err", xrpcerr)
 			s.pages.Error503(w)
 			return
 		}
-		log.Println("failed to fetch target branches", err)
+		l.Error("failed to fetch target branches", "err", err)
 		return
 	}
 
 	// Decode target branches
 	var targetBranches types.RepoBranchesResponse
 	if err := json.Unmarshal(targetXrpcBytes, &targetBranches); err != nil {
-		log.Println("failed to decode target branches XRPC response", err)
+		l.Error("failed to decode target branches XRPC response", "err", err)
 		s.pages.Error503(w)
 		return
 	}
@@ -1586,16 +2129,18 @@ func (s *Pulls) CompareForksBranchesFragment(w http.ResponseWriter, r *http.Requ
 }
 
 func (s *Pulls) ResubmitPull(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "ResubmitPull")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
@@ -1622,23 +2167,25 @@ func (s *Pulls) ResubmitPull(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) resubmitPatch(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context())
+
 	user := s.oauth.GetUser(r)
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	if user.Did != pull.OwnerDid {
-		log.Println("unauthorized user")
+		l.Warn("unauthorized user")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1649,29 +2196,31 @@ func (s *Pulls) resubmitPatch(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) resubmitBranch(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context())
+
 	user := s.oauth.GetUser(r)
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "resubmit-error", "Failed to edit patch. Try again later.")
 		return
 	}
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	if user.Did != pull.OwnerDid {
-		log.Println("unauthorized user")
+		l.Warn("unauthorized user")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	if !f.RepoInfo(user).Roles.IsPushAllowed() {
-		log.Println("unauthorized user")
+		l.Warn("unauthorized user")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1681,26 +2230,24 @@ func (s *Pulls) resubmitBranch(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(s.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 	xrpcBytes, err := tangled.RepoCompare(r.Context(), xrpcc, repo, pull.TargetBranch, pull.PullSource.Branch)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.compare", xrpcerr)
+			l.Error("failed to call XRPC repo.compare", "err", xrpcerr)
 			s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 			return
 		}
-		log.Printf("compare request failed: %s", err)
+		l.Error("compare request failed", "err", err)
 		s.pages.Notice(w, "resubmit-error", err.Error())
 		return
 	}
 
 	var comparison types.RepoFormatPatchResponse
 	if err := json.Unmarshal(xrpcBytes, &comparison); err != nil {
-		log.Println("failed to decode XRPC compare response", err)
+		l.Error("failed to decode XRPC compare response", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1713,30 +2260,32 @@ func (s *Pulls) resubmitBranch(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Pulls) resubmitFork(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context())
+
 	user := s.oauth.GetUser(r)
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "resubmit-error", "Failed to edit patch. Try again later.")
 		return
 	}
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
 	if user.Did != pull.OwnerDid {
-		log.Println("unauthorized user")
+		l.Warn("unauthorized user")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	forkRepo, err := db.GetRepoByAtUri(s.db, pull.PullSource.RepoAt.String())
 	if err != nil {
-		log.Println("failed to get source repo", err)
+		l.Error("failed to get source repo", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1749,7 +2298,7 @@ func (s *Pulls) resubmitFork(w http.ResponseWriter, r *http.Request) {
 		oauth.WithDev(s.config.Core.Dev),
 	)
 	if err != nil {
-		log.Printf("failed to connect to knot server: %v", err)
+		l.Error("failed to connect to knot server", "err", err)
 		return
 	}
 
@@ -1767,7 +2316,7 @@ func (s *Pulls) resubmitFork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !resp.Success {
-		log.Println("Failed to update tracking ref.", "err", resp.Error)
+		l.Error("Failed to update tracking ref.", "err", resp.Error)
 		s.pages.Notice(w, "resubmit-error", "Failed to update tracking ref.")
 		return
 	}
@@ -1780,21 +2329,21 @@ func (s *Pulls) resubmitFork(w http.ResponseWriter, r *http.Request) {
 	}
 	forkHost := fmt.Sprintf("%s://%s", forkScheme, forkRepo.Knot)
 	forkRepoId := fmt.Sprintf("%s/%s", forkRepo.Did, forkRepo.Name)
-	forkXrpcBytes, err := tangled.RepoCompare(r.Context(), &indigoxrpc.Client{Host: forkHost}, forkRepoId, hiddenRef, pull.PullSource.Branch)
+	forkXrpcBytes, err := tangled.RepoCompare(r.Context(), xrpcclient.NewClient(s.config.KnotClient, forkHost), forkRepoId, hiddenRef, pull.PullSource.Branch)
 	if err != nil {
 		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.compare for fork", xrpcerr)
+			l.Error("failed to call XRPC repo.compare for fork", "err", xrpcerr)
 			s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 			return
 		}
-		log.Printf("failed to compare branches: %s", err)
+		l.Error("failed to compare branches", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
 
 	var forkComparison types.RepoFormatPatchResponse
 	if err := json.Unmarshal(forkXrpcBytes, &forkComparison); err != nil {
-		log.Println("failed to decode XRPC compare response for fork", err)
+		l.Error("failed to decode XRPC compare response for fork", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1809,6 +2358,77 @@ func (s *Pulls) resubmitFork(w http.ResponseWriter, r *http.Request) {
 	s.resubmitPullHelper(w, r, f, user, pull, patch, combined, sourceRev)
 }
 
+// ApplySuggestions resubmits a patch-based pull with the suggestions of the
+// selected comments spliced into its latest round.
+func (s *Pulls) ApplySuggestions(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "ApplySuggestions")
+
+	user := s.oauth.GetUser(r)
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Notice(w, "resubmit-error", "Failed to apply suggestions. Try again later.")
+		return
+	}
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	if user.Did != pull.OwnerDid {
+		l.Warn("unauthorized user")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !pull.State.IsOpen() {
+		s.pages.Notice(w, "resubmit-error", "This pull is not open.")
+		return
+	}
+	// branches have to be updated by pushing to them, and stacks are
+	// resubmitted as a whole
+	if !pull.IsPatchBased() || pull.IsStacked() {
+		s.pages.Notice(w, "resubmit-error", "Suggestions can only be applied to pulls submitted as a single patch.")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		s.pages.Notice(w, "resubmit-error", "Failed to apply suggestions. Try again later.")
+		return
+	}
+	selected := make(map[int]bool)
+	for _, id := range r.Form["comment"] {
+		if commentId, err := strconv.Atoi(id); err == nil {
+			selected[commentId] = true
+		}
+	}
+
+	// suggestions refer to the lines of the round they were made on, so
+	// only those on the latest round still make sense
+	var suggestions []patchutil.Suggestion
+	for _, c := range pull.LatestSubmission().Comments {
+		if selected[c.ID] {
+			suggestions = append(suggestions, c.Suggestions()...)
+		}
+	}
+	if len(suggestions) == 0 {
+		s.pages.Notice(w, "resubmit-error", "Select comments with suggestions on the latest round to apply.")
+		return
+	}
+
+	patch, err := patchutil.ApplySuggestions(pull.LatestPatch(), suggestions)
+	if err != nil {
+		l.Error("failed to apply suggestions", "err", err)
+		s.pages.Notice(w, "resubmit-error", fmt.Sprintf("Failed to apply suggestions: %s.", err))
+		return
+	}
+
+	s.resubmitPullHelper(w, r, f, user, pull, patch, "", "")
+}
+
 func (s *Pulls) resubmitPullHelper(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -1819,8 +2439,10 @@ func (s *Pulls) resubmitPullHelper(
 	combined string,
 	sourceRev string,
 ) {
+	l := tlog.FromContext(r.Context())
+
 	if pull.IsStacked() {
-		log.Println("resubmitting stacked PR")
+		l.Info("resubmitting stacked PR")
 		s.resubmitStackedPullHelper(w, r, f, user, pull, patch, pull.StackId)
 		return
 	}
@@ -1845,7 +2467,7 @@ func (s *Pulls) resubmitPullHelper(
 
 	tx, err := s.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start tx")
+		l.Error("failed to start tx")
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1858,13 +2480,13 @@ func (s *Pulls) resubmitPullHelper(
 	combinedPatch := combined
 	err = db.ResubmitPull(tx, pullAt, newRoundNumber, newPatch, combinedPatch, newSourceRev)
 	if err != nil {
-		log.Println("failed to create pull request", err)
+		l.Error("failed to create pull request", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to authorize client")
+		l.Error("failed to authorize client")
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -1911,13 +2533,13 @@ func (s *Pulls) resubmitPullHelper(
 		},
 	})
 	if err != nil {
-		log.Println("failed to update record", err)
+		l.Error("failed to update record", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to update pull request on the PDS. Try again later.")
 		return
 	}
 
 	if err = tx.Commit(); err != nil {
-		log.Println("failed to commit transaction", err)
+		l.Error("failed to commit transaction", "err", err)
 		s.pages.Notice(w, "resubmit-error", "Failed to resubmit pull.")
 		return
 	}
@@ -1934,12 +2556,19 @@ func (s *Pulls) resubmitStackedPullHelper(
 	patch string,
 	stackId string,
 ) {
+	l := tlog.FromContext(r.Context())
+
+	if err := s.validator.ValidatePatch(&patch); err != nil {
+		s.pages.Notice(w, "resubmit-error", err.Error())
+		return
+	}
+
 	targetBranch := pull.TargetBranch
 
 	origStack, _ := r.Context().Value("stack").(models.Stack)
 	newStack, err := newStack(f, user, targetBranch, patch, pull.PullSource, stackId)
 	if err != nil {
-		log.Println("failed to create resubmitted stack", err)
+		l.Error("failed to create resubmitted stack", "err", err)
 		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
 		return
 	}
@@ -1986,7 +2615,7 @@ func (s *Pulls) resubmitStackedPullHelper(
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		log.Println("failed to start transaction", err)
+		l.Error("failed to start transaction", "err", err)
 		s.pages.Notice(w, "pull-resubmit-error", "Failed to resubmit pull request. Try again later.")
 		return
 	}
@@ -2004,7 +2633,7 @@ func (s *Pulls) resubmitStackedPullHelper(
 
 		err := db.DeletePull(tx, p.RepoAt, p.PullId)
 		if err != nil {
-			log.Println("failed to delete pull", err, p.PullId)
+			l.Error("failed to delete pull", "err", err, "pull_id", p.PullId)
 			s.pages.Notice(w, "pull-resubmit-error", "Failed to resubmit pull request. Try again later.")
 			return
 		}
@@ -2020,7 +2649,7 @@ func (s *Pulls) resubmitStackedPullHelper(
 	for _, p := range additions {
 		err := db.NewPull(tx, p)
 		if err != nil {
-			log.Println("failed to create pull", err, p.PullId)
+			l.Error("failed to create pull", "err", err, "pull_id", p.PullId)
 			s.pages.Notice(w, "pull-resubmit-error", "Failed to resubmit pull request. Try again later.")
 			return
 		}
@@ -2055,7 +2684,7 @@ func (s *Pulls) resubmitStackedPullHelper(
 		newSourceRev := np.LatestSha()
 		err := db.ResubmitPull(tx, pullAt, newRoundNumber, newPatch, combinedPatch, newSourceRev)
 		if err != nil {
-			log.Println("failed to update pull", err, op.PullId)
+			l.Error("failed to update pull", "err", err, "pull_id", op.PullId)
 			s.pages.Notice(w, "pull-resubmit-error", "Failed to resubmit pull request. Try again later.")
 			return
 		}
@@ -2085,7 +2714,7 @@ func (s *Pulls) resubmitStackedPullHelper(
 		)
 
 		if err != nil {
-			log.Println("failed to update pull", err, p.PullId)
+			l.Error("failed to update pull", "err", err, "pull_id", p.PullId)
 			s.pages.Notice(w, "pull-resubmit-error", "Failed to resubmit pull request. Try again later.")
 			return
 		}
@@ -2093,14 +2722,14 @@ func (s *Pulls) resubmitStackedPullHelper(
 
 	err = tx.Commit()
 	if err != nil {
-		log.Println("failed to resubmit pull", err)
+		l.Error("failed to resubmit pull", "err", err)
 		s.pages.Notice(w, "pull-resubmit-error", "Failed to resubmit pull request. Try again later.")
 		return
 	}
 
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to authorize client")
+		l.Error("failed to authorize client")
 		s.pages.Notice(w, "resubmit-error", "Failed to create pull request. Try again later.")
 		return
 	}
@@ -2110,7 +2739,7 @@ func (s *Pulls) resubmitStackedPullHelper(
 		Writes: writes,
 	})
 	if err != nil {
-		log.Println("failed to create stacked pull request", err)
+		l.Error("failed to create stacked pull request", "err", err)
 		s.pages.Notice(w, "pull", "Failed to create stacked pull request. Try again later.")
 		return
 	}
@@ -2118,52 +2747,152 @@ func (s *Pulls) resubmitStackedPullHelper(
 	s.pages.HxLocation(w, fmt.Sprintf("/%s/pulls/%d", f.OwnerSlashRepo(), pull.PullId))
 }
 
+func (s *Pulls) ReorderStack(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "ReorderStack")
+
+	user := s.oauth.GetUser(r)
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Notice(w, "pull-reorder-error", "Failed to reorder stack. Try again later.")
+		return
+	}
+
+	stack, _ := r.Context().Value("stack").(models.Stack)
+	if !pull.IsStacked() || len(stack) < 2 {
+		http.Error(w, "only stacks of more than one pull can be reordered", http.StatusBadRequest)
+		return
+	}
+
+	// only the author of the stack may reorder it
+	if user.Did != pull.OwnerDid {
+		l.Warn("unauthorized user")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.pages.PullStackReorderFragment(w, pages.PullStackReorderParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Pull:         pull,
+			Stack:        stack,
+		})
+		return
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			s.pages.Notice(w, "pull-reorder-error", "Invalid form.")
+			return
+		}
+
+		newStack, err := stack.Reorder(r.Form["order"])
+		if err != nil {
+			s.pages.Notice(w, "pull-reorder-error", fmt.Sprintf("Invalid order: %s", err))
+			return
+		}
+
+		// the patches must still apply on top of each other in their new order
+		mergeCheck := s.mergeCheck(r, f, newStack[0], newStack)
+		if mergeCheck.Error != "" {
+			s.pages.Notice(w, "pull-reorder-error", fmt.Sprintf("Failed to check the new order: %s", mergeCheck.Error))
+			return
+		}
+		if mergeCheck.IsConflicted {
+			var files []string
+			for _, c := range mergeCheck.Conflicts {
+				files = append(files, c.Filename)
+			}
+			s.pages.Notice(w, "pull-reorder-error", fmt.Sprintf("This order does not apply cleanly, conflicts in: %s", strings.Join(files, ", ")))
+			return
+		}
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			l.Error("failed to start transaction", "err", err)
+			s.pages.Notice(w, "pull-reorder-error", "Failed to reorder stack. Try again later.")
+			return
+		}
+		defer tx.Rollback()
+
+		// the pull records carry no stacking information; the stack is
+		// ordered by parent-change-ids, which only live in the DB
+		for _, p := range newStack {
+			err := db.SetPullParentChangeId(
+				tx,
+				p.ParentChangeId,
+				// these should be enough filters to be unique per-stack
+				db.FilterEq("repo_at", p.RepoAt.String()),
+				db.FilterEq("owner_did", p.OwnerDid),
+				db.FilterEq("change_id", p.ChangeId),
+			)
+			if err != nil {
+				l.Error("failed to update pull", "err", err, "pull_id", p.PullId)
+				s.pages.Notice(w, "pull-reorder-error", "Failed to reorder stack. Try again later.")
+				return
+			}
+		}
+
+		if err = tx.Commit(); err != nil {
+			l.Error("failed to reorder stack", "err", err)
+			s.pages.Notice(w, "pull-reorder-error", "Failed to reorder stack. Try again later.")
+			return
+		}
+
+		s.pages.HxRefresh(w)
+		return
+	}
+}
+
 func (s *Pulls) MergePull(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "MergePull")
+
 	user := s.oauth.GetUser(r)
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to resolve repo:", err)
+		l.Error("failed to resolve repo", "err", err)
 		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-merge-error", "Failed to merge patch. Try again later.")
 		return
 	}
 
-	var pullsToMerge models.Stack
-	pullsToMerge = append(pullsToMerge, pull)
+	var stack models.Stack
 	if pull.IsStacked() {
-		stack, ok := r.Context().Value("stack").(models.Stack)
+		stack, ok = r.Context().Value("stack").(models.Stack)
 		if !ok {
-			log.Println("failed to get stack")
+			l.Error("failed to get stack")
 			s.pages.Notice(w, "pull-merge-error", "Failed to merge patch. Try again later.")
 			return
 		}
-
-		// combine patches of substack
-		subStack := stack.StrictlyBelow(pull)
-		// collect the portion of the stack that is mergeable
-		mergeable := subStack.Mergeable()
-		// add to total patch
-		pullsToMerge = append(pullsToMerge, mergeable...)
 	}
 
+	// combine the patches of the mergeable portion of the substack
+	pullsToMerge := mergeablePulls(pull, stack)
+
 	patch := pullsToMerge.CombinedPatch()
 
 	ident, err := s.idResolver.ResolveIdent(r.Context(), pull.OwnerDid)
 	if err != nil {
-		log.Printf("resolving identity: %s", err)
+		l.Error("resolving identity", "err", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
 	email, err := db.GetPrimaryEmail(s.db, pull.OwnerDid)
 	if err != nil {
-		log.Printf("failed to get primary email: %s", err)
+		l.Error("failed to get primary email", "err", err)
 	}
 
 	authorName := ident.Handle.String()
@@ -2184,14 +2913,75 @@ func (s *Pulls) MergePull(w http.ResponseWriter, r *http.Request) {
 		mergeInput.AuthorEmail = &email.Address
 	}
 
+	// fuzz is opt-in, the merge check tells whether it is needed
+	if r.FormValue("fuzz") == "true" {
+		mergeInput.Fuzz = &mergeFuzz
+	}
+
+	// a knot that was never verified, or was since dropped, can't be
+	// trusted with the merge
+	registrations, err := db.GetRegistrations(s.db, db.FilterEq("domain", f.Knot))
+	if err != nil {
+		l.Error("failed to get knot registration", "err", err)
+		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
+		return
+	}
+	if !slices.ContainsFunc(registrations, func(reg models.Registration) bool { return !reg.IsPending() }) {
+		s.pages.Notice(w, "pull-merge-error", fmt.Sprintf("The knot %s is not registered, so it can't merge pull requests.", f.Knot))
+		return
+	}
+
+	blocked, err := s.blockedBy(f, pullsToMerge)
+	if err != nil {
+		l.Error("failed to get pull dependencies", "err", err)
+		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
+		return
+	}
+	if blocked != "" {
+		s.pages.Notice(w, "pull-merge-error", fmt.Sprintf("This pull is %s.", blocked))
+		return
+	}
+
+	for _, p := range pullsToMerge {
+		missing, err := s.missingChecks(f, p)
+		if err != nil {
+			l.Error("failed to check required checks", "err", err)
+			s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
+			return
+		}
+		if len(missing) > 0 {
+			s.pages.Notice(w, "pull-merge-error", fmt.Sprintf("Required checks have not passed: %s.", strings.Join(missing, ", ")))
+			return
+		}
+	}
+
+	pipelineBlockers, err := s.pipelineBlockers(f, pullsToMerge)
+	if err != nil {
+		l.Error("failed to check pull pipelines", "err", err)
+		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
+		return
+	}
+	if len(pipelineBlockers) > 0 {
+		// owners may merge anyway, but have to ask for it
+		if !f.RepoInfo(user).Roles.IsOwner() {
+			s.pages.Notice(w, "pull-merge-error", fmt.Sprintf("Pipelines must pass before merging: %s.", strings.Join(pipelineBlockers, ", ")))
+			return
+		}
+		if r.FormValue("override") != "true" {
+			s.pages.Notice(w, "pull-merge-error", fmt.Sprintf("Pipelines have not passed: %s. Confirm to merge anyway.", strings.Join(pipelineBlockers, ", ")))
+			return
+		}
+	}
+
 	client, err := s.oauth.ServiceClient(
 		r,
 		oauth.WithService(f.Knot),
 		oauth.WithLxm(tangled.RepoMergeNSID),
+		oauth.WithoutRetry(),
 		oauth.WithDev(s.config.Core.Dev),
 	)
 	if err != nil {
-		log.Printf("failed to connect to knot server: %v", err)
+		l.Error("failed to connect to knot server", "err", err)
 		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
 		return
 	}
@@ -2204,7 +2994,7 @@ func (s *Pulls) MergePull(w http.ResponseWriter, r *http.Request) {
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		log.Println("failed to start transcation", err)
+		l.Error("failed to start transcation", "err", err)
 		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
 		return
 	}
@@ -2213,17 +3003,25 @@ func (s *Pulls) MergePull(w http.ResponseWriter, r *http.Request) {
 	for _, p := range pullsToMerge {
 		err := db.MergePull(tx, f.RepoAt(), p.PullId)
 		if err != nil {
-			log.Printf("failed to update pull request status in database: %s", err)
+			l.Error("failed to update pull request status in database", "err", err)
 			s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
 			return
 		}
+		err = db.AddThreadEvent(tx, models.ThreadEvent{
+			ThreadAt: p.AtUri(),
+			Kind:     models.ThreadEventMerged,
+			Did:      user.Did,
+		})
+		if err != nil {
+			l.Error("failed to record pull event", "err", err)
+		}
 		p.State = models.PullMerged
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		// TODO: this is unsound, we should also revert the merge from the knotserver here
-		log.Printf("failed to update pull request status in database: %s", err)
+		l.Error("failed to update pull request status in database", "err", err)
 		s.pages.Notice(w, "pull-merge-error", "Failed to merge pull request. Try again later.")
 		return
 	}
@@ -2233,33 +3031,45 @@ func (s *Pulls) MergePull(w http.ResponseWriter, r *http.Request) {
 		s.notifier.NewPullState(r.Context(), syntax.DID(user.Did), p)
 	}
 
+	for _, p := range pullsToMerge {
+		s.closeReferencedIssues(r.Context(), f, syntax.DID(user.Did), p)
+	}
+	s.deleteHiddenRefs(pullsToMerge)
+
+	// the pulls of a stack share their source branch, which may still
+	// carry the ones above this pull
+	if f.DeleteBranchOnMerge && !pull.IsStacked() {
+		if status := s.branchDeleteStatus(r, f, pull); status != nil && len(status.TargetOf) == 0 {
+			s.deleteSourceBranch(r, user, pull, status)
+		}
+	}
+
 	s.pages.HxLocation(w, fmt.Sprintf("/@%s/%s/pulls/%d", f.OwnerHandle(), f.Name, pull.PullId))
 }
 
 func (s *Pulls) ClosePull(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "ClosePull")
+
 	user := s.oauth.GetUser(r)
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("malformed middleware")
+		l.Error("malformed middleware")
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
 
-	// auth filter: only owner or collaborators can close
-	roles := f.RolesInRepo(user)
-	isOwner := roles.IsOwner()
-	isCollaborator := roles.IsCollaborator()
+	// auth filter: only triagers or the author can close
 	isPullAuthor := user.Did == pull.OwnerDid
-	isCloseAllowed := isOwner || isCollaborator || isPullAuthor
+	isCloseAllowed := f.RolesInRepo(user).IsTriageAllowed() || isPullAuthor
 	if !isCloseAllowed {
-		log.Println("failed to close pull")
+		l.Error("failed to close pull")
 		s.pages.Notice(w, "pull-close", "You are unauthorized to close this pull.")
 		return
 	}
@@ -2267,7 +3077,7 @@ func (s *Pulls) ClosePull(w http.ResponseWriter, r *http.Request) {
 	// Start a transaction
 	tx, err := s.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start transaction", err)
+		l.Error("failed to start transaction", "err", err)
 		s.pages.Notice(w, "pull-close", "Failed to close pull.")
 		return
 	}
@@ -2287,16 +3097,24 @@ func (s *Pulls) ClosePull(w http.ResponseWriter, r *http.Request) {
 		// Close the pull in the database
 		err = db.ClosePull(tx, f.RepoAt(), p.PullId)
 		if err != nil {
-			log.Println("failed to close pull", err)
+			l.Error("failed to close pull", "err", err)
 			s.pages.Notice(w, "pull-close", "Failed to close pull.")
 			return
 		}
+		err = db.AddThreadEvent(tx, models.ThreadEvent{
+			ThreadAt: p.AtUri(),
+			Kind:     models.ThreadEventClosed,
+			Did:      user.Did,
+		})
+		if err != nil {
+			l.Error("failed to record pull event", "err", err)
+		}
 		p.State = models.PullClosed
 	}
 
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
-		log.Println("failed to commit transaction", err)
+		l.Error("failed to commit transaction", "err", err)
 		s.pages.Notice(w, "pull-close", "Failed to close pull.")
 		return
 	}
@@ -2304,35 +3122,35 @@ func (s *Pulls) ClosePull(w http.ResponseWriter, r *http.Request) {
 	for _, p := range pullsToClose {
 		s.notifier.NewPullState(r.Context(), syntax.DID(user.Did), p)
 	}
+	s.deleteHiddenRefs(pullsToClose)
 
 	s.pages.HxLocation(w, fmt.Sprintf("/%s/pulls/%d", f.OwnerSlashRepo(), pull.PullId))
 }
 
 func (s *Pulls) ReopenPull(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "ReopenPull")
+
 	user := s.oauth.GetUser(r)
 
 	f, err := s.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to resolve repo", err)
+		l.Error("failed to resolve repo", "err", err)
 		s.pages.Notice(w, "pull-reopen", "Failed to reopen pull.")
 		return
 	}
 
 	pull, ok := r.Context().Value("pull").(*models.Pull)
 	if !ok {
-		log.Println("failed to get pull")
+		l.Error("failed to get pull")
 		s.pages.Notice(w, "pull-error", "Failed to edit patch. Try again later.")
 		return
 	}
 
-	// auth filter: only owner or collaborators can close
-	roles := f.RolesInRepo(user)
-	isOwner := roles.IsOwner()
-	isCollaborator := roles.IsCollaborator()
+	// auth filter: only triagers or the author can close
 	isPullAuthor := user.Did == pull.OwnerDid
-	isCloseAllowed := isOwner || isCollaborator || isPullAuthor
+	isCloseAllowed := f.RolesInRepo(user).IsTriageAllowed() || isPullAuthor
 	if !isCloseAllowed {
-		log.Println("failed to close pull")
+		l.Error("failed to close pull")
 		s.pages.Notice(w, "pull-close", "You are unauthorized to close this pull.")
 		return
 	}
@@ -2340,7 +3158,7 @@ func (s *Pulls) ReopenPull(w http.ResponseWriter, r *http.Request) {
 	// Start a transaction
 	tx, err := s.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start transaction", err)
+		l.Error("failed to start transaction", "err", err)
 		s.pages.Notice(w, "pull-reopen", "Failed to reopen pull.")
 		return
 	}
@@ -2360,16 +3178,24 @@ func (s *Pulls) ReopenPull(w http.ResponseWriter, r *http.Request) {
 		// Close the pull in the database
 		err = db.ReopenPull(tx, f.RepoAt(), p.PullId)
 		if err != nil {
-			log.Println("failed to close pull", err)
+			l.Error("failed to close pull", "err", err)
 			s.pages.Notice(w, "pull-close", "Failed to close pull.")
 			return
 		}
+		err = db.AddThreadEvent(tx, models.ThreadEvent{
+			ThreadAt: p.AtUri(),
+			Kind:     models.ThreadEventReopened,
+			Did:      user.Did,
+		})
+		if err != nil {
+			l.Error("failed to record pull event", "err", err)
+		}
 		p.State = models.PullOpen
 	}
 
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
-		log.Println("failed to commit transaction", err)
+		l.Error("failed to commit transaction", "err", err)
 		s.pages.Notice(w, "pull-reopen", "Failed to reopen pull.")
 		return
 	}
@@ -2381,6 +3207,140 @@ func (s *Pulls) ReopenPull(w http.ResponseWriter, r *http.Request) {
 	s.pages.HxLocation(w, fmt.Sprintf("/%s/pulls/%d", f.OwnerSlashRepo(), pull.PullId))
 }
 
+// TogglePullTask checks or unchecks a single task list item in the pull
+// description, and writes it back to the record on the author's PDS. Only
+// the author can do so, as the record is theirs.
+func (s *Pulls) TogglePullTask(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "TogglePullTask")
+
+	user := s.oauth.GetUser(r)
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		http.Error(w, "repo not found", http.StatusNotFound)
+		return
+	}
+
+	pull, ok := r.Context().Value("pull").(*models.Pull)
+	if !ok {
+		l.Error("failed to get pull")
+		s.pages.Error404(w)
+		return
+	}
+
+	// the description lives in the record of the author, which nobody else
+	// can write to
+	if user.Did != pull.OwnerDid {
+		l.Error("unauthorized task toggle", "pullDid", pull.OwnerDid, "gotDid", user.Did)
+		http.Error(w, "you cannot edit this pull", http.StatusForbidden)
+		return
+	}
+
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		http.Error(w, "invalid task index", http.StatusBadRequest)
+		return
+	}
+	checked := r.FormValue("checked") == "true"
+
+	// the description was edited since it was rendered, the index may now
+	// point at a different task; reload to show the latest one instead
+	if r.FormValue("hash") != markup.ContentHash(pull.Body) {
+		l.Info("stale task toggle", "pull", pull.AtUri())
+		s.pages.HxRefresh(w)
+		return
+	}
+
+	newBody, err := markup.ToggleTaskListItem(pull.Body, index, checked)
+	if err != nil {
+		l.Error("failed to toggle task", "err", err)
+		http.Error(w, "invalid task index", http.StatusBadRequest)
+		return
+	}
+
+	client, err := s.oauth.AuthorizedClient(r)
+	if err != nil {
+		l.Error("failed to get authorized client", "err", err)
+		http.Error(w, "failed to update pull", http.StatusInternalServerError)
+		return
+	}
+
+	// the rest of the record is kept as it is on the PDS
+	ex, err := comatproto.RepoGetRecord(r.Context(), client, "", tangled.RepoPullNSID, user.Did, pull.Rkey)
+	if err != nil {
+		l.Error("failed to get record", "err", err)
+		http.Error(w, "failed to update pull, no record found on PDS", http.StatusInternalServerError)
+		return
+	}
+	record, ok := ex.Value.Val.(*tangled.RepoPull)
+	if !ok {
+		l.Error("unexpected record type", "type", fmt.Sprintf("%T", ex.Value.Val))
+		http.Error(w, "failed to update pull", http.StatusInternalServerError)
+		return
+	}
+	record.Body = &newBody
+
+	_, err = comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoPullNSID,
+		Repo:       user.Did,
+		Rkey:       pull.Rkey,
+		SwapRecord: ex.Cid,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: record,
+		},
+	})
+	if err != nil {
+		l.Error("failed to edit record on PDS", "err", err)
+		http.Error(w, "failed to update pull on PDS", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.SetPullBody(s.db, f.RepoAt(), pull.PullId, newBody); err != nil {
+		l.Error("failed to edit pull", "err", err)
+		http.Error(w, "failed to update pull", http.StatusInternalServerError)
+		return
+	}
+
+	newPull := *pull
+	newPull.Body = newBody
+	s.resolveReferences(&newPull, s.readableRepos(user))
+	s.pages.PullBodyFragment(w, pages.PullBodyParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Pull:         &newPull,
+	})
+}
+
+// readableRepos returns the private repos user may read, for leaving the
+// others out of references. None are when they can't be listed.
+func (s *Pulls) readableRepos(user *oauth.User) []string {
+	readable, err := s.repoResolver.ReadableRepos(user)
+	if err != nil {
+		s.logger.Error("failed to list private repos", "err", err)
+	}
+	return readable
+}
+
+// resolveReferences links up the issue and pull references written in the
+// body of the pull and its comments, for display. Only those to public repos
+// and the private ones in readable are.
+func (s *Pulls) resolveReferences(pull *models.Pull, readable []string) {
+	refs := markup.FindReferences(pull.Body)
+	for _, submission := range pull.Submissions {
+		for _, c := range submission.Comments {
+			refs = append(refs, markup.FindReferences(c.Body)...)
+		}
+	}
+
+	resolved, err := db.ResolveReferences(s.db, pull.RepoAt, refs, readable)
+	if err != nil {
+		s.logger.Error("failed to resolve references", "err", err)
+		return
+	}
+	pull.References = resolved
+}
+
 func newStack(f *reporesolver.ResolvedRepo, user *oauth.User, targetBranch, patch string, pullSource *models.PullSource, stackId string) (models.Stack, error) {
 	formatPatches, err := patchutil.ExtractPatches(patch)
 	if err != nil {
@@ -2436,3 +3396,163 @@ func newStack(f *reporesolver.ResolvedRepo, user *oauth.User, targetBranch, patc
 
 	return stack, nil
 }
+
+// closeReferencedIssues closes the open issues of the repo that the body of
+// the merged pull refers to with a closing keyword, as in 'fixes #12'.
+func (s *Pulls) closeReferencedIssues(ctx context.Context, f *reporesolver.ResolvedRepo, actor syntax.DID, pull *models.Pull) {
+	l := tlog.FromContext(ctx)
+
+	refs := markup.FindClosingReferences(pull.Body)
+	if len(refs) == 0 {
+		return
+	}
+
+	// only issues of this repo are closed, which the merger can read
+	resolved, err := db.ResolveReferences(s.db, f.RepoAt(), refs, []string{f.RepoAt().String()})
+	if err != nil {
+		l.Error("failed to resolve closing references", "err", err)
+		return
+	}
+
+	var issueAts []string
+	for _, ref := range resolved {
+		if ref.Kind == models.ReferenceKindIssue {
+			issueAts = append(issueAts, ref.AtUri.String())
+		}
+	}
+	if len(issueAts) == 0 {
+		return
+	}
+
+	// only issues of this repo, a pull can't close issues elsewhere
+	issues, err := db.GetIssues(
+		s.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterIn("at_uri", issueAts),
+		db.FilterEq("open", 1),
+	)
+	if err != nil {
+		l.Error("failed to get closing references", "err", err)
+		return
+	}
+
+	for _, issue := range issues {
+		if err := db.CloseIssues(s.db, db.FilterEq("id", issue.Id)); err != nil {
+			l.Error("failed to close referenced issue", "err", err)
+			continue
+		}
+		issue.Open = false
+
+		err = db.AddThreadEvent(s.db, models.ThreadEvent{
+			ThreadAt: issue.AtUri(),
+			Kind:     models.ThreadEventClosed,
+			Did:      actor.String(),
+		})
+		if err != nil {
+			l.Error("failed to record issue event", "err", err)
+		}
+
+		s.notifier.NewIssueState(ctx, actor, &issue)
+	}
+}
+
+// missingChecks returns the checks required on the pull's target branch that
+// have not passed on its latest submission.
+func (s *Pulls) missingChecks(f *reporesolver.ResolvedRepo, pull *models.Pull) ([]string, error) {
+	required, err := db.GetRequiredChecks(
+		s.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("branch", pull.TargetBranch),
+	)
+	if err != nil || len(required) == 0 {
+		return nil, err
+	}
+
+	sha := pull.LatestSha()
+	if sha == "" {
+		return models.MissingChecks(required, nil, nil), nil
+	}
+
+	statuses, err := db.GetCommitStatuses(
+		s.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("sha", sha),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline, err := s.latestPipeline(f, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.MissingChecks(required, statuses, pipeline), nil
+}
+
+// latestPipeline returns the newest pipeline run on sha, or nil if there is
+// none.
+func (s *Pulls) latestPipeline(f *reporesolver.ResolvedRepo, sha string) (*models.Pipeline, error) {
+	if sha == "" {
+		return nil, nil
+	}
+
+	pipelines, err := db.GetPipelineStatuses(
+		s.db,
+		1,
+		db.FilterEq("repo_owner", f.OwnerDid()),
+		db.FilterEq("repo_name", f.Name),
+		db.FilterEq("knot", f.Knot),
+		db.FilterEq("sha", sha),
+	)
+	if err != nil || len(pipelines) == 0 {
+		return nil, err
+	}
+
+	return &pipelines[0], nil
+}
+
+// pipelineBlockers explains, for each of pulls, why it can't merge yet in a
+// repo that requires passing pipelines. It is empty once every pull's head
+// passed, or if the repo doesn't require it.
+func (s *Pulls) pipelineBlockers(f *reporesolver.ResolvedRepo, pulls models.Stack) ([]string, error) {
+	if !f.RequiresPassingPipeline() {
+		return nil, nil
+	}
+
+	var blockers []string
+	for _, p := range pulls {
+		pipeline, err := s.latestPipeline(f, p.LatestSha())
+		if err != nil {
+			return nil, err
+		}
+		if reason := models.PipelineBlocker(pipeline); reason != "" {
+			blockers = append(blockers, fmt.Sprintf("#%d %s", p.PullId, reason))
+		}
+	}
+	return blockers, nil
+}
+
+// mergeablePulls returns pull and the part of the stack below it that would
+// be merged along with it.
+func mergeablePulls(pull *models.Pull, stack models.Stack) models.Stack {
+	pulls := models.Stack{pull}
+	if pull.IsStacked() {
+		pulls = append(pulls, stack.StrictlyBelow(pull).Mergeable()...)
+	}
+	return pulls
+}
+
+// pipelineStatus lists what keeps an open pull from merging until its
+// pipelines pass, for display.
+func (s *Pulls) pipelineStatus(f *reporesolver.ResolvedRepo, pull *models.Pull, stack models.Stack) []string {
+	if pull.State != models.PullOpen {
+		return nil
+	}
+
+	blockers, err := s.pipelineBlockers(f, mergeablePulls(pull, stack))
+	if err != nil {
+		s.logger.Error("failed to check pull pipelines", "err", err)
+	}
+	return blockers
+}
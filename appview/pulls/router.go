@@ -9,12 +9,13 @@ import (
 
 func (s *Pulls) Router(mw *middleware.Middleware) http.Handler {
 	r := chi.NewRouter()
-	r.Get("/", s.RepoPulls)
+	r.With(middleware.Paginate).Get("/", s.RepoPulls)
 	r.With(middleware.AuthMiddleware(s.oauth)).Route("/new", func(r chi.Router) {
 		r.Get("/", s.NewPull)
 		r.Get("/patch-upload", s.PatchUploadFragment)
 		r.Post("/validate-patch", s.ValidatePatch)
 		r.Get("/compare-branches", s.CompareBranchesFragment)
+		r.Get("/commit-preview", s.CommitPreviewFragment)
 		r.Get("/compare-forks", s.CompareForksFragment)
 		r.Get("/fork-branches", s.CompareForksBranchesFragment)
 		r.Post("/", s.NewPull)
@@ -45,13 +46,28 @@ func (s *Pulls) Router(mw *middleware.Middleware) http.Handler {
 				r.Get("/", s.ResubmitPull)
 				r.Post("/", s.ResubmitPull)
 			})
+			r.Post("/suggestions/apply", s.ApplySuggestions)
+			// the author or those who can push, checked within
+			r.Post("/tasks", s.TogglePullTask)
+			// the author or triagers, checked within
+			r.Route("/dependencies", func(r chi.Router) {
+				r.Post("/", s.AddPullDependency)
+				r.Delete("/{id}", s.RemovePullDependency)
+			})
+			r.Route("/reorder", func(r chi.Router) {
+				r.Get("/", s.ReorderStack)
+				r.Post("/", s.ReorderStack)
+			})
 			// permissions here require us to know pull author
 			// it is handled within the route
 			r.Post("/close", s.ClosePull)
 			r.Post("/reopen", s.ReopenPull)
-			// collaborators only
+			r.Post("/recheck", s.RecheckMerge)
+			// needs push access to the source repo, checked within
+			r.Post("/branch/delete", s.DeletePullBranch)
+			// mergers only
 			r.Group(func(r chi.Router) {
-				r.Use(mw.RepoPermissionMiddleware("repo:push"))
+				r.Use(mw.RepoPermissionMiddleware("repo:merge"))
 				r.Post("/merge", s.MergePull)
 				// maybe lock, etc.
 			})
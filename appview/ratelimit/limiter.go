@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter hands out a token bucket per key, like a user or an address.
+// Buckets that have sat idle for a while are dropped, a full bucket is no
+// different from a new one.
+type Limiter struct {
+	mu      sync.Mutex
+	every   time.Duration
+	burst   int
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	*rate.Limiter
+	seen time.Time
+}
+
+// New makes a Limiter whose buckets hold burst tokens and gain one back
+// every interval.
+func New(every time.Duration, burst int) *Limiter {
+	return &Limiter{
+		every:   every,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of key, and reports whether there was
+// one to take.
+func (l *Limiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.bucket(key, now).AllowN(now, 1)
+}
+
+// Exhausted reports whether the bucket of key is out of tokens, without
+// taking one. Together with Allow, it limits failures rather than attempts.
+func (l *Limiter) Exhausted(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.bucket(key, now).TokensAt(now) < 1
+}
+
+// bucket must be called with mu held.
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	idle := l.every * time.Duration(l.burst)
+	if now.Sub(l.pruned) > idle {
+		for k, b := range l.buckets {
+			if now.Sub(b.seen) > idle {
+				delete(l.buckets, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{Limiter: rate.NewLimiter(rate.Every(l.every), l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+
+	return b
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := New(time.Second, 2)
+	now := time.Now()
+
+	if !l.Allow("alice", now) || !l.Allow("alice", now) {
+		t.Fatal("expected the burst to be allowed")
+	}
+	if l.Allow("alice", now) {
+		t.Fatal("expected the third request to be limited")
+	}
+	if !l.Allow("bob", now) {
+		t.Fatal("expected other users to have their own bucket")
+	}
+	if !l.Allow("alice", now.Add(time.Second)) {
+		t.Fatal("expected the bucket to refill")
+	}
+
+	if l.Exhausted("dave", now) {
+		t.Fatal("expected a new bucket to be full")
+	}
+	l.Allow("dave", now)
+	l.Allow("dave", now)
+	if !l.Exhausted("dave", now) || l.Exhausted("dave", now.Add(time.Second)) {
+		t.Fatal("expected dave to be exhausted until the bucket refills")
+	}
+
+	l.Allow("carol", now.Add(10*time.Second))
+	if _, ok := l.buckets["alice"]; ok {
+		t.Fatal("expected idle buckets to be pruned")
+	}
+}
@@ -0,0 +1,81 @@
+// Package refcache answers branch and tag listings from the appview's
+// database, falling back to the knot when they aren't cached. Listings are
+// dropped as soon as the knot reports an update to one of their refs.
+package refcache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"github.com/go-git/go-git/v5/plumbing"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+)
+
+// bounds how stale a listing can get if ref updates from its knot are missed
+const maxAge = 30 * time.Minute
+
+// Branches is tangled.RepoBranches for every branch of a repo.
+func Branches(ctx context.Context, e db.Execer, xrpcc *indigoxrpc.Client, repoAt syntax.ATURI, repo string) ([]byte, error) {
+	return cached(e, repoAt, models.RefCacheBranches, func() ([]byte, error) {
+		return tangled.RepoBranches(ctx, xrpcc, "", 0, repo)
+	})
+}
+
+// Tags is tangled.RepoTags for every tag of a repo.
+func Tags(ctx context.Context, e db.Execer, xrpcc *indigoxrpc.Client, repoAt syntax.ATURI, repo string) ([]byte, error) {
+	return cached(e, repoAt, models.RefCacheTags, func() ([]byte, error) {
+		return tangled.RepoTags(ctx, xrpcc, "", 0, repo)
+	})
+}
+
+// Contributors is tangled.RepoContributors for the default branch of a repo,
+// as JSON.
+func Contributors(ctx context.Context, e db.Execer, xrpcc *indigoxrpc.Client, repoAt syntax.ATURI, repo string) ([]byte, error) {
+	return cached(e, repoAt, models.RefCacheContributors, func() ([]byte, error) {
+		out, err := tangled.RepoContributors(ctx, xrpcc, "", repo)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(out)
+	})
+}
+
+func cached(e db.Execer, repoAt syntax.ATURI, kind models.RefCacheKind, fetch func() ([]byte, error)) ([]byte, error) {
+	if data, ok, err := db.GetRefCache(e, repoAt, kind, maxAge); err == nil && ok {
+		return data, nil
+	}
+
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	// failing to cache a listing doesn't fail the listing
+	db.PutRefCache(e, repoAt, kind, data)
+
+	return data, nil
+}
+
+// Invalidate drops the cached listings an update to ref affects, refs that
+// are neither branches nor tags drop all of them. Any branch update drops
+// contributors, since the default branch can't be told apart here.
+func Invalidate(e db.Execer, repoAt syntax.ATURI, ref string) error {
+	name := plumbing.ReferenceName(ref)
+	switch {
+	case name.IsBranch():
+		return db.DeleteRefCache(
+			e,
+			db.FilterEq("repo_at", repoAt),
+			db.FilterIn("kind", []models.RefCacheKind{models.RefCacheBranches, models.RefCacheContributors}),
+		)
+	case name.IsTag():
+		return db.DeleteRefCache(e, db.FilterEq("repo_at", repoAt), db.FilterEq("kind", models.RefCacheTags))
+	default:
+		return db.DeleteRefCache(e, db.FilterEq("repo_at", repoAt))
+	}
+}
@@ -0,0 +1,166 @@
+// Package releasenotes drafts release notes from the changes between two
+// tags, for the author to edit before publishing.
+package releasenotes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Category string
+
+const (
+	Features      Category = "Features"
+	Fixes         Category = "Fixes"
+	Documentation Category = "Documentation"
+	Other         Category = "Other changes"
+)
+
+// categories in the order they are listed in
+var categories = []Category{Features, Fixes, Documentation, Other}
+
+// Commit is a commit between the two tags.
+type Commit struct {
+	Hash    string
+	Subject string
+	Author  string
+}
+
+// Pull is a merged pull. It stands in for the commit it landed as, which is
+// found by its title.
+type Pull struct {
+	PullId int
+	Title  string
+	Labels []string
+}
+
+// Range is what the notes cover.
+type Range struct {
+	// base path of the repo, like /did:plc:foo/repo
+	RepoPath string
+	// empty for the first release
+	PreviousTag string
+	Tag         string
+}
+
+// conventional commit subjects, like "feat(ui)!: add a button"
+var conventional = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?!?:\s*(.+)$`)
+
+var prefixCategories = map[string]Category{
+	"feat":    Features,
+	"feature": Features,
+	"fix":     Fixes,
+	"bugfix":  Fixes,
+	"docs":    Documentation,
+	"doc":     Documentation,
+}
+
+var labelCategories = map[string]Category{
+	"feature":       Features,
+	"enhancement":   Features,
+	"bug":           Fixes,
+	"fix":           Fixes,
+	"documentation": Documentation,
+	"docs":          Documentation,
+}
+
+// Categorize files a change by its labels, or failing that by the
+// conventional commit prefix of its title. It returns the title without the
+// prefix.
+func Categorize(title string, labels []string) (Category, string) {
+	category := Other
+	for _, l := range labels {
+		if c, ok := labelCategories[strings.ToLower(l)]; ok {
+			category = c
+			break
+		}
+	}
+
+	m := conventional.FindStringSubmatch(title)
+	if m == nil {
+		return category, title
+	}
+
+	c, ok := prefixCategories[strings.ToLower(m[1])]
+	if !ok {
+		return category, title
+	}
+	if category == Other {
+		category = c
+	}
+
+	if m[2] != "" {
+		return category, fmt.Sprintf("**%s**: %s", m[2], m[3])
+	}
+	return category, m[3]
+}
+
+// Generate drafts markdown notes for the commits in r. Commits that landed a
+// merged pull are listed as that pull.
+func Generate(r Range, commits []Commit, pulls []Pull) string {
+	pullsByTitle := make(map[string]Pull)
+	for _, p := range pulls {
+		pullsByTitle[p.Title] = p
+	}
+
+	entries := make(map[Category][]string)
+	seenPulls := make(map[int]bool)
+	for _, c := range commits {
+		var labels []string
+		var ref string
+
+		if p, ok := pullsByTitle[c.Subject]; ok {
+			if seenPulls[p.PullId] {
+				continue
+			}
+			seenPulls[p.PullId] = true
+			labels = p.Labels
+			ref = fmt.Sprintf("[#%d](%s/pulls/%d)", p.PullId, r.RepoPath, p.PullId)
+		} else {
+			ref = fmt.Sprintf("[`%s`](%s/commit/%s)", short(c.Hash), r.RepoPath, c.Hash)
+		}
+
+		category, title := Categorize(c.Subject, labels)
+
+		entry := fmt.Sprintf("- %s (%s)", title, ref)
+		if c.Author != "" {
+			entry = fmt.Sprintf("- %s (%s, %s)", title, ref, c.Author)
+		}
+		entries[category] = append(entries[category], entry)
+	}
+
+	var sb strings.Builder
+	for _, c := range categories {
+		if len(entries[c]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "## %s\n\n", c)
+		for _, e := range entries[c] {
+			sb.WriteString(e)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(entries) == 0 {
+		sb.WriteString("No changes.\n\n")
+	}
+
+	if r.PreviousTag != "" {
+		fmt.Fprintf(
+			&sb,
+			"**Full changelog**: [%s...%s](%s/compare/%s...%s)\n",
+			r.PreviousTag, r.Tag, r.RepoPath, r.PreviousTag, r.Tag,
+		)
+	}
+
+	return sb.String()
+}
+
+func short(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
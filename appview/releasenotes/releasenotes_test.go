@@ -0,0 +1,54 @@
+package releasenotes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		title    string
+		labels   []string
+		category Category
+		cleaned  string
+	}{
+		{"feat: add a button", nil, Features, "add a button"},
+		{"fix(ui)!: stop the flicker", nil, Fixes, "**ui**: stop the flicker"},
+		{"docs: explain setup", nil, Documentation, "explain setup"},
+		{"bump dependencies", nil, Other, "bump dependencies"},
+		{"chore: tidy up", nil, Other, "chore: tidy up"},
+		{"feat: add a button", []string{"bug"}, Fixes, "add a button"},
+		{"make it faster", []string{"wontfix", "Enhancement"}, Features, "make it faster"},
+	}
+
+	for _, tt := range tests {
+		category, cleaned := Categorize(tt.title, tt.labels)
+		if category != tt.category || cleaned != tt.cleaned {
+			t.Errorf("Categorize(%q, %v) = %q, %q, want %q, %q", tt.title, tt.labels, category, cleaned, tt.category, tt.cleaned)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	r := Range{RepoPath: "/alice/project", PreviousTag: "v1.0.0", Tag: "v1.1.0"}
+	commits := []Commit{
+		{Hash: "1111111111", Subject: "feat: add a button", Author: "alice"},
+		{Hash: "2222222222", Subject: "fix the flicker", Author: "bob"},
+		{Hash: "3333333333", Subject: "fix the flicker", Author: "bob"},
+	}
+	pulls := []Pull{{PullId: 7, Title: "fix the flicker", Labels: []string{"bug"}}}
+
+	got := Generate(r, commits, pulls)
+	want := "## Features\n\n" +
+		"- add a button ([`11111111`](/alice/project/commit/1111111111), alice)\n\n" +
+		"## Fixes\n\n" +
+		"- fix the flicker ([#7](/alice/project/pulls/7), bob)\n\n" +
+		"**Full changelog**: [v1.0.0...v1.1.0](/alice/project/compare/v1.0.0...v1.1.0)\n"
+	if got != want {
+		t.Errorf("Generate() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := Generate(Range{Tag: "v0.1.0"}, nil, nil); !strings.HasPrefix(got, "No changes.") {
+		t.Errorf("Generate() with no commits = %q", got)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"tangled.org/core/api/tangled"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 )
@@ -28,9 +27,7 @@ func (rp *Repo) DownloadArchive(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 	archiveBytes, err := tangled.RepoArchive(r.Context(), xrpcc, "tar.gz", "", ref, repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
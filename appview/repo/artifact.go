@@ -2,10 +2,8 @@ package repo
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"time"
@@ -15,13 +13,11 @@ import (
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages"
 	"tangled.org/core/appview/reporesolver"
-	"tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/tid"
 	"tangled.org/core/types"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	lexutil "github.com/bluesky-social/indigo/lex/util"
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/dustin/go-humanize"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -30,25 +26,26 @@ import (
 
 // TODO: proper statuses here on early exit
 func (rp *Repo) AttachArtifact(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "AttachArtifact")
 	user := rp.oauth.GetUser(r)
 	tagParam := chi.URLParam(r, "tag")
 	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		rp.pages.Notice(w, "upload", "failed to upload artifact, error in repo resolution")
 		return
 	}
 
 	tag, err := rp.resolveTag(r.Context(), f, tagParam)
 	if err != nil {
-		log.Println("failed to resolve tag", err)
+		l.Error("failed to resolve tag", "err", err)
 		rp.pages.Notice(w, "upload", "failed to upload artifact, error in tag resolution")
 		return
 	}
 
 	file, handler, err := r.FormFile("artifact")
 	if err != nil {
-		log.Println("failed to upload artifact", err)
+		l.Error("failed to upload artifact", "err", err)
 		rp.pages.Notice(w, "upload", "failed to upload artifact")
 		return
 	}
@@ -56,19 +53,19 @@ func (rp *Repo) AttachArtifact(w http.ResponseWriter, r *http.Request) {
 
 	client, err := rp.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to get authorized client", err)
+		l.Error("failed to get authorized client", "err", err)
 		rp.pages.Notice(w, "upload", "failed to get authorized client")
 		return
 	}
 
 	uploadBlobResp, err := comatproto.RepoUploadBlob(r.Context(), client, file)
 	if err != nil {
-		log.Println("failed to upload blob", err)
+		l.Error("failed to upload blob", "err", err)
 		rp.pages.Notice(w, "upload", "Failed to upload blob to your PDS. Try again later.")
 		return
 	}
 
-	log.Println("uploaded blob", humanize.Bytes(uint64(uploadBlobResp.Blob.Size)), uploadBlobResp.Blob.Ref.String())
+	l.Info("uploaded blob", "size", humanize.Bytes(uint64(uploadBlobResp.Blob.Size)), "ref", uploadBlobResp.Blob.Ref.String())
 
 	rkey := tid.TID()
 	createdAt := time.Now()
@@ -88,16 +85,16 @@ func (rp *Repo) AttachArtifact(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 	if err != nil {
-		log.Println("failed to create record", err)
+		l.Error("failed to create record", "err", err)
 		rp.pages.Notice(w, "upload", "Failed to create artifact record. Try again later.")
 		return
 	}
 
-	log.Println(putRecordResp.Uri)
+	l.Info("created artifact record", "uri", putRecordResp.Uri)
 
 	tx, err := rp.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start tx")
+		l.Error("failed to start tx")
 		rp.pages.Notice(w, "upload", "Failed to create artifact. Try again later.")
 		return
 	}
@@ -117,14 +114,14 @@ func (rp *Repo) AttachArtifact(w http.ResponseWriter, r *http.Request) {
 
 	err = db.AddArtifact(tx, artifact)
 	if err != nil {
-		log.Println("failed to add artifact record to db", err)
+		l.Error("failed to add artifact record to db", "err", err)
 		rp.pages.Notice(w, "upload", "Failed to create artifact. Try again later.")
 		return
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		log.Println("failed to add artifact record to db")
+		l.Error("failed to add artifact record to db")
 		rp.pages.Notice(w, "upload", "Failed to create artifact. Try again later.")
 		return
 	}
@@ -137,9 +134,10 @@ func (rp *Repo) AttachArtifact(w http.ResponseWriter, r *http.Request) {
 }
 
 func (rp *Repo) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "DownloadArtifact")
 	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		http.Error(w, "failed to resolve repo", http.StatusInternalServerError)
 		return
 	}
@@ -149,7 +147,7 @@ func (rp *Repo) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
 
 	tag, err := rp.resolveTag(r.Context(), f, tagParam)
 	if err != nil {
-		log.Println("failed to resolve tag", err)
+		l.Error("failed to resolve tag", "err", err)
 		rp.pages.Notice(w, "upload", "failed to upload artifact, error in tag resolution")
 		return
 	}
@@ -161,13 +159,13 @@ func (rp *Repo) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
 		db.FilterEq("name", filename),
 	)
 	if err != nil {
-		log.Println("failed to get artifacts", err)
+		l.Error("failed to get artifacts", "err", err)
 		http.Error(w, "failed to get artifact", http.StatusInternalServerError)
 		return
 	}
 
 	if len(artifacts) != 1 {
-		log.Printf("too many or too few artifacts found")
+		l.Error("too many or too few artifacts found", "count", len(artifacts))
 		http.Error(w, "artifact not found", http.StatusNotFound)
 		return
 	}
@@ -183,7 +181,7 @@ func (rp *Repo) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
 
 	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
 	if err != nil {
-		log.Println("failed to create request", err)
+		l.Error("failed to create request", "err", err)
 		http.Error(w, "failed to create request", http.StatusInternalServerError)
 		return
 	}
@@ -191,7 +189,7 @@ func (rp *Repo) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log.Println("failed to make request", err)
+		l.Error("failed to make request", "err", err)
 		http.Error(w, "failed to make request to PDS", http.StatusInternalServerError)
 		return
 	}
@@ -207,18 +205,19 @@ func (rp *Repo) DownloadArtifact(w http.ResponseWriter, r *http.Request) {
 
 	// stream the body directly to the client
 	if _, err := io.Copy(w, resp.Body); err != nil {
-		log.Println("error streaming response to client:", err)
+		l.Error("error streaming response to client", "err", err)
 	}
 }
 
 // TODO: proper statuses here on early exit
 func (rp *Repo) DeleteArtifact(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "DeleteArtifact")
 	user := rp.oauth.GetUser(r)
 	tagParam := chi.URLParam(r, "tag")
 	filename := chi.URLParam(r, "file")
 	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -233,7 +232,7 @@ func (rp *Repo) DeleteArtifact(w http.ResponseWriter, r *http.Request) {
 		db.FilterEq("name", filename),
 	)
 	if err != nil {
-		log.Println("failed to get artifacts", err)
+		l.Error("failed to get artifacts", "err", err)
 		rp.pages.Notice(w, "remove", "Failed to delete artifact. Try again later.")
 		return
 	}
@@ -245,7 +244,7 @@ func (rp *Repo) DeleteArtifact(w http.ResponseWriter, r *http.Request) {
 	artifact := artifacts[0]
 
 	if user.Did != artifact.Did {
-		log.Println("user not authorized to delete artifact", err)
+		l.Error("user not authorized to delete artifact", "err", err)
 		rp.pages.Notice(w, "remove", "Unauthorized deletion of artifact.")
 		return
 	}
@@ -256,14 +255,14 @@ func (rp *Repo) DeleteArtifact(w http.ResponseWriter, r *http.Request) {
 		Rkey:       artifact.Rkey,
 	})
 	if err != nil {
-		log.Println("failed to get blob from pds", err)
+		l.Error("failed to get blob from pds", "err", err)
 		rp.pages.Notice(w, "remove", "Failed to remove blob from PDS.")
 		return
 	}
 
 	tx, err := rp.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start tx")
+		l.Error("failed to start tx")
 		rp.pages.Notice(w, "remove", "Failed to delete artifact. Try again later.")
 		return
 	}
@@ -275,14 +274,14 @@ func (rp *Repo) DeleteArtifact(w http.ResponseWriter, r *http.Request) {
 		db.FilterEq("name", filename),
 	)
 	if err != nil {
-		log.Println("failed to remove artifact record from db", err)
+		l.Error("failed to remove artifact record from db", "err", err)
 		rp.pages.Notice(w, "remove", "Failed to delete artifact. Try again later.")
 		return
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		log.Println("failed to remove artifact record from db")
+		l.Error("failed to remove artifact record from db")
 		rp.pages.Notice(w, "remove", "Failed to delete artifact. Try again later.")
 		return
 	}
@@ -291,34 +290,15 @@ func (rp *Repo) DeleteArtifact(w http.ResponseWriter, r *http.Request) {
 }
 
 func (rp *Repo) resolveTag(ctx context.Context, f *reporesolver.ResolvedRepo, tagParam string) (*types.TagReference, error) {
+	l := rp.logger.With("handler", "resolveTag")
 	tagParam, err := url.QueryUnescape(tagParam)
 	if err != nil {
 		return nil, err
 	}
 
-	scheme := "http"
-	if !rp.config.Core.Dev {
-		scheme = "https"
-	}
-	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
-
-	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-	xrpcBytes, err := tangled.RepoTags(ctx, xrpcc, "", 0, repo)
+	result, err := rp.fetchTags(ctx, f)
 	if err != nil {
-		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
-			log.Println("failed to call XRPC repo.tags", xrpcerr)
-			return nil, xrpcerr
-		}
-		log.Println("failed to reach knotserver", err)
-		return nil, err
-	}
-
-	var result types.RepoTagsResponse
-	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
-		log.Println("failed to decode XRPC tags response", err)
+		l.Error("failed to call XRPC repo.tags", "err", err)
 		return nil, err
 	}
 
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"path/filepath"
@@ -15,10 +16,10 @@ import (
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages"
 	"tangled.org/core/appview/pages/markup"
+	"tangled.org/core/appview/pagination"
 	"tangled.org/core/appview/reporesolver"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -26,11 +27,14 @@ import (
 //
 // - code      : text |          | raw
 // - markup    : text | rendered | raw
+// - notebook  : text | rendered | raw
+// - table     : text | rendered | raw
 // - svg       : text | rendered | raw
 // - png       :      | rendered | raw
 // - video     :      | rendered | raw
+// - pdf       :      | rendered | raw
 // - submodule :      | rendered |
-// - rest      :      |          |
+// - rest      :      |          | download
 func (rp *Repo) Blob(w http.ResponseWriter, r *http.Request) {
 	l := rp.logger.With("handler", "RepoBlob")
 
@@ -51,9 +55,7 @@ func (rp *Repo) Blob(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Repo.Name)
 	resp, err := tangled.RepoBlob(r.Context(), xrpcc, filePath, false, ref, repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
@@ -76,13 +78,43 @@ func (rp *Repo) Blob(w http.ResponseWriter, r *http.Request) {
 
 	user := rp.oauth.GetUser(r)
 
-	rp.pages.RepoBlob(w, pages.RepoBlobParams{
+	params := pages.RepoBlobParams{
 		LoggedInUser:    user,
 		RepoInfo:        f.RepoInfo(user),
 		BreadCrumbs:     breadcrumbs,
 		BlobView:        blobView,
 		RepoBlob_Output: resp,
-	})
+	}
+
+	if user != nil && resp.Submodule == nil && rp.isBranch(r, f, ref) {
+		isBinary := resp.IsBinary != nil && *resp.IsBinary
+		params.CanEdit = blobView.HasTextView && !isBinary
+		params.CanDelete = true
+	}
+
+	// tables are paginated, unlike any other rendered view
+	if blobView.ContentType.IsTable() && blobView.ShowingRendered {
+		delimiter := '\t'
+		if !strings.EqualFold(filepath.Ext(filePath), ".tsv") {
+			delimiter = markup.DetectDelimiter(blobView.Contents)
+		}
+
+		table, err := markup.ParseTable(blobView.Contents, delimiter)
+		if err != nil {
+			// leave it to the code view
+			l.Warn("failed to parse table", "err", err)
+		} else {
+			page := pagination.FromContext(r.Context())
+			start := min(max(page.Offset, 0), len(table.Rows))
+			end := min(start+max(page.Limit, 1), len(table.Rows))
+
+			params.Table = table
+			params.TableRows = table.Rows[start:end]
+			params.Page = page
+		}
+	}
+
+	rp.pages.RepoBlob(w, params)
 }
 
 func (rp *Repo) RepoBlobRaw(w http.ResponseWriter, r *http.Request) {
@@ -168,13 +200,29 @@ func (rp *Repo) RepoBlobRaw(w http.ResponseWriter, r *http.Request) {
 		// serve images and videos with their original content type
 		w.Header().Set("Content-Type", contentType)
 		w.Write(body)
+	} else if contentType == pdfMimeType {
+		// embedded by the blob view
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Write(body)
 	} else {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
-		w.Write([]byte("unsupported content type"))
-		return
+		// everything else is only ever downloaded, never displayed
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(filePath)}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Write(body)
 	}
 }
 
+const pdfMimeType = "application/pdf"
+
+// maxEmbedSize caps the documents that are embedded in the blob view,
+// larger ones are offered as downloads.
+const maxEmbedSize = 20 << 20
+
 // NewBlobView creates a BlobView from the XRPC response
 func NewBlobView(resp *tangled.RepoBlob_Output, config *config.Config, f *reporesolver.ResolvedRepo, ref, filePath string, queryParams url.Values) models.BlobView {
 	view := models.BlobView{
@@ -201,6 +249,18 @@ func NewBlobView(resp *tangled.RepoBlob_Output, config *config.Config, f *repore
 		view.ContentSrc = generateBlobURL(config, f, ref, filePath)
 		ext := strings.ToLower(filepath.Ext(resp.Path))
 
+		// pdfs go by what the knot detected rather than the extension, and
+		// are embedded from the appview's raw endpoint, which serves them
+		// with their content type
+		if resp.MimeType != nil && *resp.MimeType == pdfMimeType && view.SizeHint <= maxEmbedSize {
+			view.ContentType = models.BlobContentTypePdf
+			view.ContentSrc = fmt.Sprintf("/%s/raw/%s/%s", f.OwnerSlashRepo(), url.PathEscape(ref), filePath)
+			view.HasRawView = true
+			view.HasRenderedView = true
+			view.ShowingRendered = true
+			return view
+		}
+
 		switch ext {
 		case ".jpg", ".jpeg", ".png", ".gif", ".webp":
 			view.ContentType = models.BlobContentTypeImage
@@ -239,12 +299,22 @@ func NewBlobView(resp *tangled.RepoBlob_Output, config *config.Config, f *repore
 		view.Lines = strings.Count(view.Contents, "\n") + 1
 	}
 
-	// with text, we may be dealing with markdown
-	format := markup.GetFormat(resp.Path)
-	if format == markup.FormatMarkdown {
+	// with text, we may be dealing with markdown, a notebook or a table
+	switch markup.GetFormat(resp.Path) {
+	case markup.FormatMarkdown:
 		view.ContentType = models.BlobContentTypeMarkup
 		view.HasRenderedView = true
 		view.ShowingRendered = queryParams.Get("code") != "true"
+
+	case markup.FormatNotebook:
+		view.ContentType = models.BlobContentTypeNotebook
+		view.HasRenderedView = true
+		view.ShowingRendered = queryParams.Get("code") != "true"
+
+	case markup.FormatTable:
+		view.ContentType = models.BlobContentTypeTable
+		view.HasRenderedView = true
+		view.ShowingRendered = queryParams.Get("code") != "true"
 	}
 
 	return view
@@ -4,14 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/refcache"
+	"tangled.org/core/appview/reporesolver"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/types"
 
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 func (rp *Repo) Branches(w http.ResponseWriter, r *http.Request) {
@@ -26,11 +31,9 @@ func (rp *Repo) Branches(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-	xrpcBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+	xrpcBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -68,10 +71,41 @@ func (rp *Repo) DeleteBranch(w http.ResponseWriter, r *http.Request) {
 		fail("No branch provided.", nil)
 		return
 	}
+
+	defaultBranch, err := rp.defaultBranch(r, f)
+	if err != nil {
+		fail("Failed to find the default branch. Try again later.", err)
+		return
+	}
+	if branch == defaultBranch {
+		fail(fmt.Sprintf("%s is the default branch and cannot be deleted.", branch), nil)
+		return
+	}
+
+	// open pulls into the branch would lose their target
+	targetOf, err := db.GetPulls(
+		rp.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("target_branch", branch),
+		db.FilterEq("state", models.PullOpen),
+	)
+	if err != nil {
+		fail("Failed to delete branch. Try again later.", err)
+		return
+	}
+	if len(targetOf) > 0 && r.FormValue("confirm") != "true" {
+		var ids []string
+		for _, p := range targetOf {
+			ids = append(ids, fmt.Sprintf("#%d", p.PullId))
+		}
+		fail(fmt.Sprintf("%s is the target of open pulls %s, confirm to delete it anyway.", branch, strings.Join(ids, ", ")), nil)
+		return
+	}
 	client, err := rp.oauth.ServiceClient(
 		r,
 		oauth.WithService(f.Knot),
 		oauth.WithLxm(tangled.RepoDeleteBranchNSID),
+		oauth.WithoutRetry(),
 		oauth.WithDev(rp.config.Core.Dev),
 	)
 	if err != nil {
@@ -91,5 +125,39 @@ func (rp *Repo) DeleteBranch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	l.Error("deleted branch from knot", "branch", branch, "repo", f.RepoAt())
+
+	// don't wait for the knot to report the deletion
+	if err := refcache.Invalidate(rp.db, f.RepoAt(), plumbing.NewBranchReferenceName(branch).String()); err != nil {
+		l.Error("failed to invalidate ref cache", "err", err)
+	}
+
 	rp.pages.HxRefresh(w)
 }
+
+// defaultBranch is the branch the knot reports as the default of the repo.
+func (rp *Repo) defaultBranch(r *http.Request, f *reporesolver.ResolvedRepo) (string, error) {
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
+	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+	xrpcBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return "", xrpcerr
+	}
+
+	var result types.RepoBranchesResponse
+	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
+		return "", err
+	}
+
+	for _, b := range result.Branches {
+		if b.IsDefault {
+			return b.Name, nil
+		}
+	}
+
+	return "", nil
+}
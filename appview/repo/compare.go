@@ -9,11 +9,11 @@ import (
 
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/refcache"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/patchutil"
 	"tangled.org/core/types"
 
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -32,12 +32,10 @@ func (rp *Repo) CompareNew(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-	branchBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+	branchBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -74,7 +72,7 @@ func (rp *Repo) CompareNew(w http.ResponseWriter, r *http.Request) {
 		head = queryHead
 	}
 
-	tagBytes, err := tangled.RepoTags(r.Context(), xrpcc, "", 0, repo)
+	tagBytes, err := refcache.Tags(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.tags", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -147,13 +145,11 @@ func (rp *Repo) Compare(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 
-	branchBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+	branchBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -167,7 +163,7 @@ func (rp *Repo) Compare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tagBytes, err := tangled.RepoTags(r.Context(), xrpcc, "", 0, repo)
+	tagBytes, err := refcache.Tags(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.tags", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -213,6 +209,7 @@ func (rp *Repo) Compare(w http.ResponseWriter, r *http.Request) {
 		Head:         head,
 		Diff:         &diff,
 		DiffOpts:     diffOpts,
+		Commits:      formatPatch.CompareCommits(),
 	})
 
 }
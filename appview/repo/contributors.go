@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+	"tangled.org/core/appview/refcache"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+)
+
+// Contributors lists who authored commits on the default branch, most
+// commits first. Authors with a verified email are linked to their profile.
+func (rp *Repo) Contributors(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "Contributors")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		rp.pages.Error404(w)
+		return
+	}
+
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
+	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+
+	xrpcBytes, err := refcache.Contributors(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		l.Error("failed to call XRPC repo.contributors", "err", xrpcerr)
+		rp.pages.Error503(w)
+		return
+	}
+
+	var result tangled.RepoContributors_Output
+	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
+		l.Error("failed to decode XRPC response", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	page := pagination.FromContext(r.Context())
+	total := len(result.Contributors)
+	start := min(page.Offset, total)
+	end := min(page.Offset+page.Limit, total)
+	onPage := result.Contributors[start:end]
+
+	emails := make([]string, len(onPage))
+	for i, c := range onPage {
+		emails[i] = c.Email
+	}
+	emailToDid, err := db.GetEmailToDid(rp.db, emails, true)
+	if err != nil {
+		l.Error("failed to resolve emails", "err", err)
+	}
+
+	contributors := make([]pages.Contributor, len(onPage))
+	for i, c := range onPage {
+		contributors[i] = pages.Contributor{
+			Name:      c.Name,
+			Did:       emailToDid[c.Email],
+			Commits:   c.Commits,
+			Additions: c.Additions,
+			Deletions: c.Deletions,
+		}
+		if t, err := time.Parse(time.RFC3339, c.FirstCommit); err == nil {
+			contributors[i].FirstCommit = t
+		}
+		if t, err := time.Parse(time.RFC3339, c.LastCommit); err == nil {
+			contributors[i].LastCommit = t
+		}
+	}
+
+	user := rp.oauth.GetUser(r)
+	rp.pages.RepoContributors(w, pages.RepoContributorsParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Contributors: contributors,
+		Page:         page,
+		Total:        total,
+	})
+}
@@ -0,0 +1,267 @@
+package repo
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/reporesolver"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NewFile creates a file in the directory given in the path, on the branch
+// given as ref.
+func (rp *Repo) NewFile(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "NewFile")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+	user := rp.oauth.GetUser(r)
+
+	ref := chi.URLParam(r, "ref")
+	ref, _ = url.PathUnescape(ref)
+
+	dir := chi.URLParam(r, "*")
+	dir, _ = url.PathUnescape(dir)
+	dir = strings.Trim(dir, "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		if !rp.isBranch(r, f, ref) {
+			rp.pages.Error404(w)
+			return
+		}
+
+		filePath := r.URL.Query().Get("path")
+		if filePath == "" && dir != "" {
+			filePath = dir + "/"
+		}
+
+		rp.pages.RepoEditFile(w, pages.RepoEditFileParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Ref:          ref,
+			Path:         filePath,
+			IsNew:        true,
+			CanCommit:    rp.mayCommitTo(f, user, ref),
+		})
+
+	case http.MethodPost:
+		filePath, content, ok := rp.fileFromForm(w, r)
+		if !ok {
+			return
+		}
+
+		file := &tangled.RepoCommitFiles_File{
+			Path:    filePath,
+			Content: base64.StdEncoding.EncodeToString([]byte(content)),
+		}
+		if r.FormValue("executable") == "on" {
+			executable := true
+			file.Executable = &executable
+		}
+
+		message := commitMessage(r, fmt.Sprintf("Create %s", filePath))
+		rp.commitFiles(w, r, f, user, ref, message, []*tangled.RepoCommitFiles_File{file}, blobLink(f, ref, filePath))
+	}
+}
+
+// EditFile changes a text file, and optionally moves it elsewhere.
+func (rp *Repo) EditFile(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "EditFile")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+	user := rp.oauth.GetUser(r)
+
+	ref := chi.URLParam(r, "ref")
+	ref, _ = url.PathUnescape(ref)
+
+	filePath := chi.URLParam(r, "*")
+	filePath, _ = url.PathUnescape(filePath)
+
+	switch r.Method {
+	case http.MethodGet:
+		if !rp.isBranch(r, f, ref) {
+			rp.pages.Error404(w)
+			return
+		}
+
+		resp, err := rp.fetchBlob(r, f, ref, filePath)
+		if err != nil {
+			l.Error("failed to call XRPC repo.blob", "err", err)
+			rp.pages.Error404(w)
+			return
+		}
+
+		// binary files can be replaced, but not edited as text
+		if (resp.IsBinary != nil && *resp.IsBinary) || resp.Submodule != nil {
+			rp.pages.Error404(w)
+			return
+		}
+
+		var content string
+		if resp.Content != nil {
+			content = *resp.Content
+		}
+		var blob string
+		if resp.Hash != nil {
+			blob = *resp.Hash
+		}
+
+		rp.pages.RepoEditFile(w, pages.RepoEditFileParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Ref:          ref,
+			Path:         filePath,
+			Content:      content,
+			Blob:         blob,
+			CanCommit:    rp.mayCommitTo(f, user, ref),
+		})
+
+	case http.MethodPost:
+		newPath, content, ok := rp.fileFromForm(w, r)
+		if !ok {
+			return
+		}
+
+		file := &tangled.RepoCommitFiles_File{
+			Path:    newPath,
+			Content: base64.StdEncoding.EncodeToString([]byte(content)),
+		}
+		if newPath != filePath {
+			file.From = &filePath
+		}
+		if blob := r.FormValue("blob"); blob != "" {
+			file.ExpectedBlob = &blob
+		}
+
+		message := commitMessage(r, fmt.Sprintf("Update %s", newPath))
+		rp.commitFiles(w, r, f, user, ref, message, []*tangled.RepoCommitFiles_File{file}, blobLink(f, ref, newPath))
+	}
+}
+
+// DeleteFile removes a file, once the user has confirmed it.
+func (rp *Repo) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "DeleteFile")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+	user := rp.oauth.GetUser(r)
+
+	ref := chi.URLParam(r, "ref")
+	ref, _ = url.PathUnescape(ref)
+
+	filePath := chi.URLParam(r, "*")
+	filePath, _ = url.PathUnescape(filePath)
+
+	switch r.Method {
+	case http.MethodGet:
+		if !rp.isBranch(r, f, ref) {
+			rp.pages.Error404(w)
+			return
+		}
+
+		resp, err := rp.fetchBlob(r, f, ref, filePath)
+		if err != nil || resp.Submodule != nil {
+			l.Error("failed to call XRPC repo.blob", "err", err)
+			rp.pages.Error404(w)
+			return
+		}
+
+		var blob string
+		if resp.Hash != nil {
+			blob = *resp.Hash
+		}
+
+		rp.pages.RepoDeleteFile(w, pages.RepoDeleteFileParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Ref:          ref,
+			Path:         filePath,
+			Blob:         blob,
+			CanCommit:    rp.mayCommitTo(f, user, ref),
+		})
+
+	case http.MethodPost:
+		remove := true
+		file := &tangled.RepoCommitFiles_File{
+			Path:   filePath,
+			Delete: &remove,
+		}
+		if blob := r.FormValue("blob"); blob != "" {
+			file.ExpectedBlob = &blob
+		}
+
+		// back to the directory the file was in
+		next := fmt.Sprintf("/%s/tree/%s", f.OwnerSlashRepo(), url.PathEscape(ref))
+		if dir := path.Dir(filePath); dir != "." {
+			next = next + "/" + dir
+		}
+
+		message := commitMessage(r, fmt.Sprintf("Delete %s", filePath))
+		rp.commitFiles(w, r, f, user, ref, message, []*tangled.RepoCommitFiles_File{file}, next)
+	}
+}
+
+// fetchBlob gets a file from the knot of the repo.
+func (rp *Repo) fetchBlob(r *http.Request, f *reporesolver.ResolvedRepo, ref, filePath string) (*tangled.RepoBlob_Output, error) {
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
+	resp, err := tangled.RepoBlob(r.Context(), xrpcc, filePath, false, ref, f.DidSlashRepo())
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return nil, xrpcerr
+	}
+	return resp, nil
+}
+
+// fileFromForm reads the path and content of a file from the editor.
+func (rp *Repo) fileFromForm(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	filePath := strings.TrimSpace(r.FormValue("path"))
+	if filePath == "" || strings.HasSuffix(filePath, "/") {
+		rp.pages.Notice(w, "commit-files", "Give the file a name.")
+		return "", "", false
+	}
+	if clean := path.Clean(filePath); clean != filePath || path.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		rp.pages.Notice(w, "commit-files", fmt.Sprintf("%s is not a valid path.", filePath))
+		return "", "", false
+	}
+
+	// browsers send the text of textareas with CRLF line endings
+	content := strings.ReplaceAll(r.FormValue("content"), "\r\n", "\n")
+
+	return filePath, content, true
+}
+
+// commitMessage is the message given in the commit form, with the extended
+// description as its body.
+func commitMessage(r *http.Request, fallback string) string {
+	message := strings.TrimSpace(r.FormValue("message"))
+	if message == "" {
+		message = fallback
+	}
+	if description := strings.TrimSpace(r.FormValue("description")); description != "" {
+		message = message + "\n\n" + description
+	}
+	return message
+}
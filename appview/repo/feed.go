@@ -3,7 +3,6 @@ package repo
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"slices"
 	"time"
@@ -147,15 +146,16 @@ func (rp *Repo) buildPullDescription(handle syntax.Handle, state string, pull *m
 }
 
 func (rp *Repo) AtomFeed(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "AtomFeed")
 	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to fully resolve repo:", err)
+		l.Error("failed to fully resolve repo", "err", err)
 		return
 	}
 
 	feed, err := rp.getRepoFeed(r.Context(), f)
 	if err != nil {
-		log.Println("failed to get repo feed:", err)
+		l.Error("failed to get repo feed", "err", err)
 		rp.pages.Error500(w)
 		return
 	}
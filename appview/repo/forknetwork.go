@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+)
+
+// bounds on the fork network, each fork in it costs a few knot queries
+const (
+	maxForkDepth    = 3
+	maxForkChildren = 10
+	maxForkNodes    = 100
+)
+
+// ForkNetwork shows the forks of the repo as a tree with the forks of those
+// forks, each compared with the repo it was forked from.
+func (rp *Repo) ForkNetwork(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "ForkNetwork")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		rp.pages.Error404(w)
+		return
+	}
+
+	user := rp.oauth.GetUser(r)
+
+	tree, err := db.GetForkTree(rp.db, f.RepoAt(), maxForkDepth, maxForkNodes)
+	if err != nil {
+		l.Error("failed to get fork tree", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	network, hidden := forkNodes(tree, user)
+
+	var all []*pages.ForkNode
+	var walk func(nodes []*pages.ForkNode)
+	walk = func(nodes []*pages.ForkNode) {
+		for _, n := range nodes {
+			all = append(all, n)
+			walk(n.Children)
+		}
+	}
+	walk(network)
+
+	dids := make([]string, len(all))
+	for i, n := range all {
+		dids[i] = n.Repo.Did
+	}
+	idents := rp.idResolver.ResolveIdents(r.Context(), dids)
+	for i, n := range all {
+		if ident := idents[i]; ident != nil && !ident.Handle.IsInvalidHandle() {
+			n.Handle = ident.Handle.String()
+		}
+	}
+
+	sourceClient := rp.knotClient(f.Knot)
+	sourceRepo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+	var sourceHead string
+	if head, err := tangled.RepoGetDefaultBranch(r.Context(), sourceClient, sourceRepo); err != nil {
+		l.Error("failed to get default branch", "err", err)
+	} else {
+		sourceHead = head.Hash
+	}
+	rp.compareForkNodes(r.Context(), sourceClient, sourceRepo, sourceHead, network)
+
+	rp.pages.RepoForkNetwork(w, pages.RepoForkNetworkParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Network:      network,
+		Hidden:       hidden,
+		Total:        len(all),
+	})
+}
+
+// forkNodes drops the forks user can't see along with their own forks, and
+// keeps at most maxForkChildren forks of each repo. It returns how many of
+// the forks in tree that can be seen were left out.
+func forkNodes(tree []*models.ForkNode, user *oauth.User) ([]*pages.ForkNode, int) {
+	var nodes []*pages.ForkNode
+	hidden := 0
+	for _, t := range tree {
+		if t.Repo.Visibility != models.RepoVisibilityPublic && (user == nil || t.Repo.Did != user.Did) {
+			continue
+		}
+
+		if len(nodes) == maxForkChildren {
+			hidden++
+			continue
+		}
+
+		children, childrenHidden := forkNodes(t.Children, user)
+		nodes = append(nodes, &pages.ForkNode{
+			Fork:     pages.Fork{Repo: t.Repo},
+			Children: children,
+			Hidden:   childrenHidden,
+		})
+	}
+
+	return nodes, hidden
+}
+
+// compareForkNodes compares each fork with the repo it was forked from, a
+// level of the tree at a time.
+func (rp *Repo) compareForkNodes(ctx context.Context, sourceClient *indigoxrpc.Client, sourceRepo, sourceHead string, nodes []*pages.ForkNode) {
+	heads := make([]string, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			heads[i] = rp.compareFork(ctx, sourceClient, sourceRepo, sourceHead, &n.Fork)
+		}()
+	}
+	wg.Wait()
+
+	for i, n := range nodes {
+		if len(n.Children) == 0 {
+			continue
+		}
+		client := rp.knotClient(n.Repo.Knot)
+		repo := fmt.Sprintf("%s/%s", n.Repo.Did, n.Repo.Name)
+		rp.compareForkNodes(ctx, client, repo, heads[i], n.Children)
+	}
+}
@@ -0,0 +1,150 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/types"
+)
+
+// Forks lists the forks of the repo, newest first, with how each one's
+// default branch compares to ours. Private forks are only listed for their
+// owner.
+func (rp *Repo) Forks(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "Forks")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		rp.pages.Error404(w)
+		return
+	}
+
+	user := rp.oauth.GetUser(r)
+
+	repos, err := db.GetRepos(rp.db, 0, db.FilterEq("source", f.RepoAt().String()))
+	if err != nil {
+		l.Error("failed to get forks", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	var visible []models.Repo
+	for _, repo := range repos {
+		if repo.Visibility == models.RepoVisibilityPublic || (user != nil && repo.Did == user.Did) {
+			visible = append(visible, repo)
+		}
+	}
+
+	page := pagination.FromContext(r.Context())
+	total := len(visible)
+	start := min(page.Offset, total)
+	end := min(page.Offset+page.Limit, total)
+	onPage := visible[start:end]
+
+	dids := make([]string, len(onPage))
+	for i, repo := range onPage {
+		dids[i] = repo.Did
+	}
+	idents := rp.idResolver.ResolveIdents(r.Context(), dids)
+
+	forks := make([]pages.Fork, len(onPage))
+	for i, repo := range onPage {
+		forks[i] = pages.Fork{Repo: repo}
+		if ident := idents[i]; ident != nil && !ident.Handle.IsInvalidHandle() {
+			forks[i].Handle = ident.Handle.String()
+		}
+	}
+
+	sourceClient := rp.knotClient(f.Knot)
+	sourceRepo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+	if head, err := tangled.RepoGetDefaultBranch(r.Context(), sourceClient, sourceRepo); err != nil {
+		l.Error("failed to get default branch", "err", err)
+	} else {
+		var wg sync.WaitGroup
+		for i := range forks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rp.compareFork(r.Context(), sourceClient, sourceRepo, head.Hash, &forks[i])
+			}()
+		}
+		wg.Wait()
+	}
+
+	rp.pages.RepoForks(w, pages.RepoForksParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Forks:        forks,
+		Page:         page,
+		Total:        total,
+	})
+}
+
+// compareFork fills in how the default branch of fork compares to the
+// source's head, and returns the fork's head. Each knot only has the commits
+// it was pushed, so the fork is ahead if its knot knows our head, behind if
+// ours knows its head, and has diverged otherwise.
+func (rp *Repo) compareFork(ctx context.Context, sourceClient *indigoxrpc.Client, sourceRepo, sourceHead string, fork *pages.Fork) string {
+	forkClient := rp.knotClient(fork.Repo.Knot)
+	forkRepo := fmt.Sprintf("%s/%s", fork.Repo.Did, fork.Repo.Name)
+
+	head, err := tangled.RepoGetDefaultBranch(ctx, forkClient, forkRepo)
+	if err != nil {
+		// empty, or its knot is unreachable
+		return ""
+	}
+
+	switch {
+	case sourceHead == "":
+		// nothing to compare against
+	case head.Hash == sourceHead:
+		fork.Status = pages.ForkUpToDate
+	default:
+		if n, err := countCommits(ctx, forkClient, forkRepo, sourceHead, head.Hash); err == nil {
+			fork.Status = pages.ForkAhead
+			fork.Ahead = n
+		} else if n, err := countCommits(ctx, sourceClient, sourceRepo, head.Hash, sourceHead); err == nil {
+			fork.Status = pages.ForkBehind
+			fork.Behind = n
+		} else {
+			fork.Status = pages.ForkDiverged
+		}
+	}
+
+	return head.Hash
+}
+
+// countCommits counts the commits in rev2 that are not in rev1.
+func countCommits(ctx context.Context, xrpcc *indigoxrpc.Client, repo, rev1, rev2 string) (int, error) {
+	compareBytes, err := tangled.RepoCompare(ctx, xrpcc, repo, rev1, rev2)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return 0, xrpcerr
+	}
+
+	var formatPatch types.RepoFormatPatchResponse
+	if err := json.Unmarshal(compareBytes, &formatPatch); err != nil {
+		return 0, err
+	}
+
+	return len(formatPatch.FormatPatch), nil
+}
+
+func (rp *Repo) knotClient(knot string) *indigoxrpc.Client {
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, knot)
+	return xrpcclient.NewClient(rp.config.KnotClient, host)
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -22,6 +23,7 @@ import (
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/refcache"
 	"tangled.org/core/appview/reporesolver"
 	"tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/types"
@@ -47,9 +49,7 @@ func (rp *Repo) Index(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 
 	user := rp.oauth.GetUser(r)
 	repoInfo := f.RepoInfo(user)
@@ -146,9 +146,31 @@ func (rp *Repo) Index(w http.ResponseWriter, r *http.Request) {
 		// non-fatal
 	}
 
+	latestRelease, err := db.GetLatestRelease(rp.db, f.RepoAt())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("failed to get latest release", "err", err)
+		// non-fatal
+	}
+
+	var setup *pages.RepoSetup
+	if result.IsEmpty && repoInfo.Roles.IsPushAllowed() {
+		setup = rp.emptyRepoSetup(r.Context(), xrpcc, f, repoInfo)
+	}
+
+	canAddFiles := setup != nil
+	if user != nil {
+		for _, b := range result.Branches {
+			if b.Name == result.Ref {
+				canAddFiles = true
+			}
+		}
+	}
+
 	rp.pages.RepoIndexPage(w, pages.RepoIndexParams{
 		LoggedInUser:      user,
 		RepoInfo:          repoInfo,
+		Setup:             setup,
+		CanAddFiles:       canAddFiles,
 		TagMap:            tagMap,
 		RepoIndexResponse: *result,
 		CommitsTrunc:      commitsTrunc,
@@ -159,6 +181,7 @@ func (rp *Repo) Index(w http.ResponseWriter, r *http.Request) {
 		VerifiedCommits: vc,
 		Languages:       languageInfo,
 		Pipelines:       pipelines,
+		LatestRelease:   latestRelease,
 	})
 }
 
@@ -259,7 +282,7 @@ func (rp *Repo) buildIndexResponse(ctx context.Context, xrpcc *indigoxrpc.Client
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 
 	// first get branches to determine the ref if not specified
-	branchesBytes, err := tangled.RepoBranches(ctx, xrpcc, "", 0, repo)
+	branchesBytes, err := refcache.Branches(ctx, rp.db, xrpcc, f.RepoAt(), repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call repoBranches: %w", err)
 	}
@@ -303,7 +326,7 @@ func (rp *Repo) buildIndexResponse(ctx context.Context, xrpcc *indigoxrpc.Client
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		tagsBytes, err := tangled.RepoTags(ctx, xrpcc, "", 0, repo)
+		tagsBytes, err := refcache.Tags(ctx, rp.db, xrpcc, f.RepoAt(), repo)
 		if err != nil {
 			errs = errors.Join(errs, fmt.Errorf("failed to call repoTags: %w", err))
 			return
@@ -364,6 +387,9 @@ func (rp *Repo) buildIndexResponse(ctx context.Context, xrpcc *indigoxrpc.Client
 					When:    when,
 				}
 			}
+			if file.Submodule != nil {
+				niceFile.Submodule = rp.submoduleInfo(ctx, f, file.Submodule)
+			}
 			files = append(files, niceFile)
 		}
 	}
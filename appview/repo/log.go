@@ -12,10 +12,10 @@ import (
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/refcache"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/types"
 
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 )
@@ -45,9 +45,7 @@ func (rp *Repo) Log(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 
 	limit := int64(60)
 	cursor := ""
@@ -72,7 +70,7 @@ func (rp *Repo) Log(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tagBytes, err := tangled.RepoTags(r.Context(), xrpcc, "", 0, repo)
+	tagBytes, err := refcache.Tags(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.tags", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -93,7 +91,7 @@ func (rp *Repo) Log(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	branchBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+	branchBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -170,9 +168,7 @@ func (rp *Repo) Commit(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 	xrpcBytes, err := tangled.RepoDiff(r.Context(), xrpcc, ref, repo)
@@ -211,6 +207,16 @@ func (rp *Repo) Commit(w http.ResponseWriter, r *http.Request) {
 		pipeline = &p
 	}
 
+	statuses, err := db.GetCommitStatuses(
+		rp.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("sha", result.Diff.Commit.This),
+	)
+	if err != nil {
+		l.Error("failed to get commit statuses", "err", err)
+		// non-fatal
+	}
+
 	rp.pages.RepoCommit(w, pages.RepoCommitParams{
 		LoggedInUser:       user,
 		RepoInfo:           f.RepoInfo(user),
@@ -218,6 +224,7 @@ func (rp *Repo) Commit(w http.ResponseWriter, r *http.Request) {
 		EmailToDid:         emailToDidMap,
 		VerifiedCommit:     vc,
 		Pipeline:           pipeline,
+		CommitStatuses:     statuses,
 		DiffOpts:           diffOpts,
 	})
 }
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"image/color"
 	"image/png"
-	"log"
 	"net/http"
 	"sort"
 	"strings"
@@ -20,6 +19,7 @@ import (
 )
 
 func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.RepoLanguageDetails) (*ogcard.Card, error) {
+	l := rp.logger.With("handler", "drawRepoSummaryCard")
 	width, height := ogcard.DefaultSize()
 	mainCard, err := ogcard.NewCard(width, height)
 	if err != nil {
@@ -112,7 +112,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 
 				_, err = descriptionCard.DrawText(description, color.RGBA{88, 96, 105, 255}, 36, ogcard.Top, ogcard.Left)
 				if err != nil {
-					log.Printf("failed to draw description: %v", err)
+					l.Error("failed to draw description", "err", err)
 				}
 			}
 		}
@@ -131,7 +131,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	avatarURL := rp.pages.AvatarUrl(ownerHandle, "256")
 	err = avatarArea.DrawCircularExternalImage(avatarURL, avatarX, avatarY, avatarSize)
 	if err != nil {
-		log.Printf("failed to draw avatar (non-fatal): %v", err)
+		l.Warn("failed to draw avatar (non-fatal)", "err", err)
 	}
 
 	// Split bottom area: icons area (65%) and language bar (35%)
@@ -160,7 +160,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	iconBaselineOffset := int(textSize) / 2
 	err = statsArea.DrawLucideIcon("star", currentX, statsY+iconBaselineOffset-iconSize/2+5, iconSize, iconColor)
 	if err != nil {
-		log.Printf("failed to draw star icon: %v", err)
+		l.Error("failed to draw star icon", "err", err)
 	}
 	starIconX := currentX
 	currentX += iconSize + 15
@@ -168,7 +168,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	starText := fmt.Sprintf("%d", starsText)
 	err = statsArea.DrawTextAt(starText, currentX, statsY+iconBaselineOffset, iconColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw star text: %v", err)
+		l.Error("failed to draw star text", "err", err)
 	}
 	starTextWidth := len(starText) * 20
 	starGroupWidth := iconSize + 15 + starTextWidth
@@ -178,7 +178,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	labelX := starIconX + starGroupWidth/2
 	err = iconsArea.DrawTextAt("stars", labelX, labelY, iconColor, labelSize, ogcard.Top, ogcard.Center)
 	if err != nil {
-		log.Printf("failed to draw stars label: %v", err)
+		l.Error("failed to draw stars label", "err", err)
 	}
 
 	currentX += starTextWidth + 50
@@ -187,14 +187,14 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	issueStartX := currentX
 	err = statsArea.DrawLucideIcon("circle-dot", currentX, statsY+iconBaselineOffset-iconSize/2+5, iconSize, iconColor)
 	if err != nil {
-		log.Printf("failed to draw circle-dot icon: %v", err)
+		l.Error("failed to draw circle-dot icon", "err", err)
 	}
 	currentX += iconSize + 15
 
 	issueText := fmt.Sprintf("%d", issuesText)
 	err = statsArea.DrawTextAt(issueText, currentX, statsY+iconBaselineOffset, iconColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw issue text: %v", err)
+		l.Error("failed to draw issue text", "err", err)
 	}
 	issueTextWidth := len(issueText) * 20
 	issueGroupWidth := iconSize + 15 + issueTextWidth
@@ -203,7 +203,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	labelX = issueStartX + issueGroupWidth/2
 	err = iconsArea.DrawTextAt("issues", labelX, labelY, iconColor, labelSize, ogcard.Top, ogcard.Center)
 	if err != nil {
-		log.Printf("failed to draw issues label: %v", err)
+		l.Error("failed to draw issues label", "err", err)
 	}
 
 	currentX += issueTextWidth + 50
@@ -212,14 +212,14 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	prStartX := currentX
 	err = statsArea.DrawLucideIcon("git-pull-request", currentX, statsY+iconBaselineOffset-iconSize/2+5, iconSize, iconColor)
 	if err != nil {
-		log.Printf("failed to draw git-pull-request icon: %v", err)
+		l.Error("failed to draw git-pull-request icon", "err", err)
 	}
 	currentX += iconSize + 15
 
 	prText := fmt.Sprintf("%d", pullRequestsText)
 	err = statsArea.DrawTextAt(prText, currentX, statsY+iconBaselineOffset, iconColor, textSize, ogcard.Middle, ogcard.Left)
 	if err != nil {
-		log.Printf("failed to draw PR text: %v", err)
+		l.Error("failed to draw PR text", "err", err)
 	}
 	prTextWidth := len(prText) * 20
 	prGroupWidth := iconSize + 15 + prTextWidth
@@ -228,7 +228,7 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	labelX = prStartX + prGroupWidth/2
 	err = iconsArea.DrawTextAt("pulls", labelX, labelY, iconColor, labelSize, ogcard.Top, ogcard.Center)
 	if err != nil {
-		log.Printf("failed to draw pulls label: %v", err)
+		l.Error("failed to draw pulls label", "err", err)
 	}
 
 	dollyBounds := dollyArea.Img.Bounds()
@@ -238,13 +238,13 @@ func (rp *Repo) drawRepoSummaryCard(repo *models.Repo, languageStats []types.Rep
 	dollyColor := color.RGBA{180, 180, 180, 255} // light gray
 	err = dollyArea.DrawDollySilhouette(dollyX, dollyY, dollySize, dollyColor)
 	if err != nil {
-		log.Printf("dolly silhouette not available (this is ok): %v", err)
+		l.Info("dolly silhouette not available (this is ok)", "err", err)
 	}
 
 	// Draw language bar at bottom
 	err = drawLanguagesCard(languageBarCard, languageStats)
 	if err != nil {
-		log.Printf("failed to draw language bar: %v", err)
+		l.Error("failed to draw language bar", "err", err)
 		return nil, err
 	}
 
@@ -328,9 +328,10 @@ func drawLanguagesCard(card *ogcard.Card, languageStats []types.RepoLanguageDeta
 }
 
 func (rp *Repo) Opengraph(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "Opengraph")
 	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
-		log.Println("failed to get repo and knot", err)
+		l.Error("failed to get repo and knot", "err", err)
 		return
 	}
 
@@ -342,7 +343,7 @@ func (rp *Repo) Opengraph(w http.ResponseWriter, r *http.Request) {
 		db.FilterEq("is_default_ref", 1),
 	)
 	if err != nil {
-		log.Printf("failed to get language stats from db: %v", err)
+		l.Error("failed to get language stats from db", "err", err)
 		// non-fatal, continue without language stats
 	} else if len(langs) > 0 {
 		var total int64
@@ -376,7 +377,7 @@ func (rp *Repo) Opengraph(w http.ResponseWriter, r *http.Request) {
 
 	card, err := rp.drawRepoSummaryCard(&f.Repo, languageStats)
 	if err != nil {
-		log.Println("failed to draw repo summary card", err)
+		l.Error("failed to draw repo summary card", "err", err)
 		http.Error(w, "failed to draw repo summary card", http.StatusInternalServerError)
 		return
 	}
@@ -384,7 +385,7 @@ func (rp *Repo) Opengraph(w http.ResponseWriter, r *http.Request) {
 	var imageBuffer bytes.Buffer
 	err = png.Encode(&imageBuffer, card.Img)
 	if err != nil {
-		log.Println("failed to encode repo summary card", err)
+		l.Error("failed to encode repo summary card", "err", err)
 		http.Error(w, "failed to encode repo summary card", http.StatusInternalServerError)
 		return
 	}
@@ -396,7 +397,7 @@ func (rp *Repo) Opengraph(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write(imageBytes)
 	if err != nil {
-		log.Println("failed to write repo summary card", err)
+		l.Error("failed to write repo summary card", "err", err)
 		return
 	}
 }
@@ -0,0 +1,562 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/refcache"
+	"tangled.org/core/appview/releasenotes"
+	"tangled.org/core/appview/reporesolver"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/tid"
+	"tangled.org/core/types"
+)
+
+// the history listed in the notes of a first release is cut off here
+const maxReleaseCommits = 100
+
+func (rp *Repo) Releases(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "RepoReleases")
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	tags, err := rp.fetchTags(r.Context(), f)
+	if err != nil {
+		l.Error("failed to fetch tags", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	releases, err := db.GetReleases(rp.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err != nil {
+		l.Error("failed to get releases", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	dangling, err := rp.attachArtifacts(f, tags, releases)
+	if err != nil {
+		l.Error("failed to get artifacts", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	user := rp.oauth.GetUser(r)
+	rp.pages.RepoReleases(w, pages.RepoReleasesParams{
+		LoggedInUser:      user,
+		RepoInfo:          f.RepoInfo(user),
+		Releases:          releases,
+		Latest:            latestRelease(releases),
+		DanglingArtifacts: dangling,
+	})
+}
+
+func (rp *Repo) Release(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "RepoRelease")
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	release, err := rp.releaseFromRequest(r, f)
+	if err != nil {
+		l.Error("failed to get release", "err", err)
+		rp.pages.Error404(w)
+		return
+	}
+
+	tags, err := rp.fetchTags(r.Context(), f)
+	if err != nil {
+		l.Error("failed to fetch tags", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	releases := []models.Release{*release}
+	if _, err := rp.attachArtifacts(f, tags, releases); err != nil {
+		l.Error("failed to get artifacts", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	latest, err := db.GetLatestRelease(rp.db, f.RepoAt())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("failed to get latest release", "err", err)
+	}
+
+	user := rp.oauth.GetUser(r)
+	rp.pages.RepoRelease(w, pages.RepoReleaseParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Release:      releases[0],
+		Latest:       latest,
+	})
+}
+
+// LatestRelease redirects to the newest release that is not a pre-release.
+func (rp *Repo) LatestRelease(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "LatestRelease")
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	latest, err := db.GetLatestRelease(rp.db, f.RepoAt())
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			l.Error("failed to get latest release", "err", err)
+		}
+		rp.pages.Error404(w)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/%s/releases/%s", f.OwnerSlashRepo(), url.PathEscape(latest.Tag)), http.StatusFound)
+}
+
+func (rp *Repo) NewRelease(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "NewRelease")
+	user := rp.oauth.GetUser(r)
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	tags, err := rp.fetchTags(r.Context(), f)
+	if err != nil {
+		l.Error("failed to fetch tags", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	releases, err := db.GetReleases(rp.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err != nil {
+		l.Error("failed to get releases", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	released := make(map[string]bool)
+	for _, release := range releases {
+		released[release.Tag] = true
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// only tags without a release can get one
+		var available []string
+		for _, t := range tags.Tags {
+			if !released[t.Name] {
+				available = append(available, t.Name)
+			}
+		}
+
+		rp.pages.RepoPutRelease(w, pages.RepoPutReleaseParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Action:       "create",
+			Tags:         available,
+			SelectedTag:  r.URL.Query().Get("tag"),
+		})
+	case http.MethodPost:
+		release := &models.Release{
+			Did:        user.Did,
+			Rkey:       tid.TID(),
+			RepoAt:     f.RepoAt(),
+			Tag:        r.FormValue("tag"),
+			Title:      strings.TrimSpace(r.FormValue("title")),
+			Body:       r.FormValue("body"),
+			Prerelease: r.FormValue("prerelease") == "on",
+			Created:    time.Now(),
+		}
+
+		if findTag(tags, release.Tag) == nil {
+			rp.pages.Notice(w, "releases", "Pick a tag to release.")
+			return
+		}
+		if released[release.Tag] {
+			rp.pages.Notice(w, "releases", fmt.Sprintf("Tag %s already has a release.", release.Tag))
+			return
+		}
+		if release.Title == "" {
+			release.Title = release.Tag
+		}
+
+		if err := rp.putRelease(r, release); err != nil {
+			l.Error("failed to create release", "err", err)
+			rp.pages.Notice(w, "releases", "Failed to create release, try again later.")
+			return
+		}
+
+		rp.pages.HxLocation(w, fmt.Sprintf("/%s/releases/%s", f.OwnerSlashRepo(), url.PathEscape(release.Tag)))
+	}
+}
+
+// ReleaseNotes drafts notes for a new release of a tag from the commits and
+// merged pulls since the tag before it. The author edits them before
+// publishing.
+func (rp *Repo) ReleaseNotes(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "ReleaseNotes")
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	tags, err := rp.fetchTags(r.Context(), f)
+	if err != nil {
+		l.Error("failed to fetch tags", "err", err)
+		rp.pages.Notice(w, "releases", "Failed to generate notes, try again later.")
+		return
+	}
+
+	tag := r.FormValue("tag")
+	idx := slices.IndexFunc(tags.Tags, func(t *types.TagReference) bool {
+		return t.Name == tag
+	})
+	if idx < 0 {
+		rp.pages.Notice(w, "releases", "Pick a tag to generate notes for.")
+		return
+	}
+
+	// tags are listed newest first
+	var previousTag string
+	if idx+1 < len(tags.Tags) {
+		previousTag = tags.Tags[idx+1].Name
+	}
+
+	commits, err := rp.releaseCommits(r.Context(), f, previousTag, tag)
+	if err != nil {
+		l.Error("failed to list commits", "err", err)
+		rp.pages.Notice(w, "releases", "Failed to generate notes, try again later.")
+		return
+	}
+
+	pulls, err := rp.mergedPulls(f)
+	if err != nil {
+		l.Error("failed to get merged pulls", "err", err)
+		rp.pages.Notice(w, "releases", "Failed to generate notes, try again later.")
+		return
+	}
+
+	notes := releasenotes.Generate(releasenotes.Range{
+		RepoPath:    "/" + f.OwnerSlashRepo(),
+		PreviousTag: previousTag,
+		Tag:         tag,
+	}, commits, pulls)
+
+	rp.pages.ReleaseNotesFragment(w, pages.ReleaseNotesParams{
+		Body: notes,
+	})
+}
+
+// releaseCommits lists the commits between previousTag and tag, oldest
+// first. Without a previous tag, it lists the history of tag.
+func (rp *Repo) releaseCommits(ctx context.Context, f *reporesolver.ResolvedRepo, previousTag, tag string) ([]releasenotes.Commit, error) {
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
+
+	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+
+	var commits []releasenotes.Commit
+	if previousTag != "" {
+		compareBytes, err := tangled.RepoCompare(ctx, xrpcc, repo, previousTag, tag)
+		if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+			return nil, xrpcerr
+		}
+
+		var formatPatch types.RepoFormatPatchResponse
+		if err := json.Unmarshal(compareBytes, &formatPatch); err != nil {
+			return nil, err
+		}
+
+		for _, p := range formatPatch.FormatPatch {
+			if p.PatchHeader == nil {
+				continue
+			}
+			c := releasenotes.Commit{
+				Hash:    p.SHA,
+				Subject: p.Title,
+			}
+			if p.Author != nil {
+				c.Author = p.Author.Name
+			}
+			commits = append(commits, c)
+		}
+
+		return commits, nil
+	}
+
+	logBytes, err := tangled.RepoLog(ctx, xrpcc, "", maxReleaseCommits, "", tag, repo)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return nil, xrpcerr
+	}
+
+	var logResp types.RepoLogResponse
+	if err := json.Unmarshal(logBytes, &logResp); err != nil {
+		return nil, err
+	}
+
+	// the log is newest first
+	for _, c := range slices.Backward(logResp.Commits) {
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		commits = append(commits, releasenotes.Commit{
+			Hash:    c.Hash.String(),
+			Subject: subject,
+			Author:  c.Author.Name,
+		})
+	}
+
+	return commits, nil
+}
+
+// mergedPulls returns the merged pulls of the repo along with the names of
+// their labels.
+func (rp *Repo) mergedPulls(f *reporesolver.ResolvedRepo) ([]releasenotes.Pull, error) {
+	pulls, err := db.GetPulls(
+		rp.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("state", models.PullMerged),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var labelAts []string
+	for _, p := range pulls {
+		for labelAt := range p.Labels.Inner() {
+			labelAts = append(labelAts, labelAt)
+		}
+	}
+
+	labelNames := make(map[string]string)
+	if len(labelAts) > 0 {
+		defs, err := db.GetLabelDefinitions(rp.db, db.FilterIn("at_uri", labelAts))
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range defs {
+			labelNames[d.AtUri().String()] = d.Name
+		}
+	}
+
+	var merged []releasenotes.Pull
+	for _, p := range pulls {
+		pull := releasenotes.Pull{
+			PullId: p.PullId,
+			Title:  p.Title,
+		}
+		for labelAt := range p.Labels.Inner() {
+			if name, ok := labelNames[labelAt]; ok {
+				pull.Labels = append(pull.Labels, name)
+			}
+		}
+		merged = append(merged, pull)
+	}
+
+	return merged, nil
+}
+
+// EditRelease updates the notes of a release. The record lives on the PDS of
+// whoever cut the release, so only they can edit it.
+func (rp *Repo) EditRelease(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "EditRelease")
+	user := rp.oauth.GetUser(r)
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	release, err := rp.releaseFromRequest(r, f)
+	if err != nil {
+		l.Error("failed to get release", "err", err)
+		rp.pages.Error404(w)
+		return
+	}
+
+	if release.Did != user.Did {
+		http.Error(w, "only the author of a release can edit it", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rp.pages.RepoPutRelease(w, pages.RepoPutReleaseParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Action:       "edit",
+			Release:      release,
+		})
+	case http.MethodPost:
+		release.Title = strings.TrimSpace(r.FormValue("title"))
+		release.Body = r.FormValue("body")
+		release.Prerelease = r.FormValue("prerelease") == "on"
+		if release.Title == "" {
+			release.Title = release.Tag
+		}
+
+		now := time.Now()
+		release.Edited = &now
+
+		if err := rp.putRelease(r, release); err != nil {
+			l.Error("failed to edit release", "err", err)
+			rp.pages.Notice(w, "releases", "Failed to edit release, try again later.")
+			return
+		}
+
+		rp.pages.HxLocation(w, fmt.Sprintf("/%s/releases/%s", f.OwnerSlashRepo(), url.PathEscape(release.Tag)))
+	}
+}
+
+// putRelease writes release to the PDS of the user and to the database.
+func (rp *Repo) putRelease(r *http.Request, release *models.Release) error {
+	client, err := rp.oauth.AuthorizedClient(r)
+	if err != nil {
+		return err
+	}
+
+	record := release.AsRecord()
+	input := &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoReleaseNSID,
+		Repo:       release.Did,
+		Rkey:       release.Rkey,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &record,
+		},
+	}
+
+	// editing replaces the record as it is now
+	if release.Edited != nil {
+		ex, err := comatproto.RepoGetRecord(r.Context(), client, "", tangled.RepoReleaseNSID, release.Did, release.Rkey)
+		if err != nil {
+			return err
+		}
+		input.SwapRecord = ex.Cid
+	}
+
+	if _, err := comatproto.RepoPutRecord(r.Context(), client, input); err != nil {
+		return err
+	}
+
+	return db.PutRelease(rp.db, release)
+}
+
+func (rp *Repo) releaseFromRequest(r *http.Request, f *reporesolver.ResolvedRepo) (*models.Release, error) {
+	tag, err := url.QueryUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetRelease(rp.db, db.FilterEq("repo_at", f.RepoAt()), db.FilterEq("tag", tag))
+}
+
+// attachArtifacts fills in the artifacts of each release from the ones
+// uploaded to its tag, and returns the artifacts whose tag is gone.
+func (rp *Repo) attachArtifacts(f *reporesolver.ResolvedRepo, tags *types.RepoTagsResponse, releases []models.Release) ([]models.Artifact, error) {
+	artifacts, err := db.GetArtifact(rp.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err != nil {
+		return nil, err
+	}
+
+	artifactMap, dangling := groupArtifacts(tags, artifacts)
+	for i := range releases {
+		tag := findTag(tags, releases[i].Tag)
+		if tag == nil {
+			releases[i].TagMissing = true
+			continue
+		}
+		if tag.Tag != nil {
+			releases[i].Artifacts = artifactMap[tag.Tag.Hash]
+		}
+	}
+
+	return dangling, nil
+}
+
+func (rp *Repo) fetchTags(ctx context.Context, f *reporesolver.ResolvedRepo) (*types.RepoTagsResponse, error) {
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
+
+	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
+	xrpcBytes, err := refcache.Tags(ctx, rp.db, xrpcc, f.RepoAt(), repo)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return nil, xrpcerr
+	}
+
+	var result types.RepoTagsResponse
+	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// groupArtifacts maps artifacts to the hash of the tag they are attached to.
+// Artifacts whose tag was deleted are returned separately.
+func groupArtifacts(tags *types.RepoTagsResponse, artifacts []models.Artifact) (map[plumbing.Hash][]models.Artifact, []models.Artifact) {
+	known := make(map[plumbing.Hash]bool)
+	for _, t := range tags.Tags {
+		if t.Tag != nil {
+			known[t.Tag.Hash] = true
+		}
+	}
+
+	artifactMap := make(map[plumbing.Hash][]models.Artifact)
+	var dangling []models.Artifact
+	for _, a := range artifacts {
+		if !known[a.Tag] {
+			dangling = append(dangling, a)
+			continue
+		}
+		artifactMap[a.Tag] = append(artifactMap[a.Tag], a)
+	}
+
+	return artifactMap, dangling
+}
+
+func findTag(tags *types.RepoTagsResponse, name string) *types.TagReference {
+	for _, t := range tags.Tags {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func latestRelease(releases []models.Release) *models.Release {
+	for i := range releases {
+		if !releases[i].Prerelease {
+			return &releases[i]
+		}
+	}
+	return nil
+}
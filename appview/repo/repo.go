@@ -105,8 +105,15 @@ func (rp *Repo) EditSpindle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !removingSpindle {
-		// ensure that this is a valid spindle for this user
-		validSpindles, err := rp.enforcer.GetSpindlesForUser(user.Did)
+		// ensure that this is a valid spindle for the repo owner
+		memberOf, err := rp.enforcer.GetSpindlesForUser(f.OwnerDid())
+		if err != nil {
+			fail("Failed to find spindles. Try again later.", err)
+			return
+		}
+
+		// and that an admin let it serve
+		validSpindles, err := db.ApprovedSpindles(rp.db, memberOf)
 		if err != nil {
 			fail("Failed to find spindles. Try again later.", err)
 			return
@@ -120,13 +127,13 @@ func (rp *Repo) EditSpindle(w http.ResponseWriter, r *http.Request) {
 
 	newRepo := f.Repo
 	newRepo.Spindle = newSpindle
-	record := newRepo.AsRecord()
 
 	spindlePtr := &newSpindle
 	if removingSpindle {
 		spindlePtr = nil
 		newRepo.Spindle = ""
 	}
+	record := newRepo.AsRecord()
 
 	// optimistic update
 	err = db.UpdateSpindle(rp.db, newRepo.RepoAt().String(), spindlePtr)
@@ -155,7 +162,7 @@ func (rp *Repo) EditSpindle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !removingSpindle {
+	if !removingSpindle && !newRepo.PipelinesDisabled {
 		// add this spindle to spindle stream
 		rp.spindlestream.AddSource(
 			context.Background(),
@@ -185,6 +192,7 @@ func (rp *Repo) AddLabelDef(w http.ResponseWriter, r *http.Request) {
 
 	// get form values for label definition
 	name := r.FormValue("name")
+	description := r.FormValue("description")
 	concreteType := r.FormValue("valueType")
 	valueFormat := r.FormValue("valueFormat")
 	enumValues := r.FormValue("enumValues")
@@ -215,19 +223,24 @@ func (rp *Repo) AddLabelDef(w http.ResponseWriter, r *http.Request) {
 	}
 
 	label := models.LabelDefinition{
-		Did:       user.Did,
-		Rkey:      tid.TID(),
-		Name:      name,
-		ValueType: valueType,
-		Scope:     scope,
-		Color:     &color,
-		Multiple:  multiple,
-		Created:   time.Now(),
+		Did:         user.Did,
+		Rkey:        tid.TID(),
+		Name:        name,
+		Description: &description,
+		ValueType:   valueType,
+		Scope:       scope,
+		Color:       &color,
+		Multiple:    multiple,
+		Created:     time.Now(),
 	}
 	if err := rp.validator.ValidateLabelDefinition(&label); err != nil {
 		fail(err.Error(), err)
 		return
 	}
+	if err := rp.checkLabelName(f.Repo, &label); err != nil {
+		fail(err.Error(), err)
+		return
+	}
 
 	// announce this relation into the firehose, store into owners' pds
 	client, err := rp.oauth.AuthorizedClient(r)
@@ -325,6 +338,113 @@ func (rp *Repo) AddLabelDef(w http.ResponseWriter, r *http.Request) {
 	rp.pages.HxRefresh(w)
 }
 
+// EditLabelDef updates the name, description, color and scope of a label
+// definition. The value type is left alone, as existing label ops depend on
+// it.
+func (rp *Repo) EditLabelDef(w http.ResponseWriter, r *http.Request) {
+	user := rp.oauth.GetUser(r)
+	l := rp.logger.With("handler", "EditLabel")
+	l = l.With("did", user.Did)
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		l.Error("invalid form", "err", err)
+		return
+	}
+
+	labelId := r.FormValue("label-id")
+	errorId := fmt.Sprintf("edit-label-error-%s", labelId)
+	fail := func(msg string, err error) {
+		l.Error(msg, "err", err)
+		rp.pages.Notice(w, errorId, msg)
+	}
+
+	label, err := db.GetLabelDefinition(rp.db, db.FilterEq("id", labelId))
+	if err != nil {
+		fail("Failed to find label definition.", err)
+		return
+	}
+	if !slices.Contains(f.Repo.Labels, label.AtUri().String()) {
+		fail("This label is not used by this repository.", nil)
+		return
+	}
+	if label.Did != user.Did {
+		fail("Only the creator of a label can edit it.", nil)
+		return
+	}
+
+	description := r.FormValue("description")
+	color := r.FormValue("color")
+	label.Name = r.FormValue("name")
+	label.Description = &description
+	label.Color = &color
+	label.Scope = r.Form["scope"]
+
+	if err := rp.validator.ValidateLabelDefinition(label); err != nil {
+		fail(err.Error(), err)
+		return
+	}
+	if err := rp.checkLabelName(f.Repo, label); err != nil {
+		fail(err.Error(), err)
+		return
+	}
+
+	client, err := rp.oauth.AuthorizedClient(r)
+	if err != nil {
+		fail(err.Error(), err)
+		return
+	}
+
+	ex, err := comatproto.RepoGetRecord(r.Context(), client, "", tangled.LabelDefinitionNSID, label.Did, label.Rkey)
+	if err != nil {
+		fail("Failed to update label, no record found on PDS.", err)
+		return
+	}
+	labelRecord := label.AsRecord()
+	_, err = comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.LabelDefinitionNSID,
+		Repo:       label.Did,
+		Rkey:       label.Rkey,
+		SwapRecord: ex.Cid,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &labelRecord,
+		},
+	})
+	if err != nil {
+		fail("Failed to write record to PDS.", err)
+		return
+	}
+
+	if _, err := db.AddLabelDefinition(rp.db, label); err != nil {
+		fail("Failed to update label.", err)
+		return
+	}
+
+	rp.pages.HxRefresh(w)
+}
+
+// checkLabelName makes sure no other label of the repo goes by the same
+// name, so that labels stay distinguishable in listings and filters.
+func (rp *Repo) checkLabelName(repo models.Repo, label *models.LabelDefinition) error {
+	existing, err := db.GetLabelDefinitions(rp.db, db.FilterIn("at_uri", repo.Labels))
+	if err != nil {
+		return fmt.Errorf("failed to fetch labels: %w", err)
+	}
+
+	for _, e := range existing {
+		if e.AtUri() != label.AtUri() && strings.EqualFold(e.Name, label.Name) {
+			return fmt.Errorf("this repository already has a label named %q", e.Name)
+		}
+	}
+
+	return nil
+}
+
 func (rp *Repo) DeleteLabelDef(w http.ResponseWriter, r *http.Request) {
 	user := rp.oauth.GetUser(r)
 	l := rp.logger.With("handler", "DeleteLabel")
@@ -456,11 +576,17 @@ func (rp *Repo) SubscribeLabel(w http.ResponseWriter, r *http.Request) {
 	}
 
 	labelAts := r.Form["label"]
-	_, err = db.GetLabelDefinitions(rp.db, db.FilterIn("at_uri", labelAts))
+	subscribing, err := db.GetLabelDefinitions(rp.db, db.FilterIn("at_uri", labelAts))
 	if err != nil {
 		fail("Failed to subscribe to label.", err)
 		return
 	}
+	for _, label := range subscribing {
+		if err := rp.checkLabelName(f.Repo, &label); err != nil {
+			fail(err.Error(), err)
+			return
+		}
+	}
 
 	newRepo := f.Repo
 	newRepo.Labels = append(newRepo.Labels, labelAts...)
@@ -726,8 +852,30 @@ func (rp *Repo) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 		fail("You seem to be adding yourself as a collaborator.", nil)
 		return
 	}
+
+	role := r.FormValue("role")
+	if role == "" {
+		role = rbac.RoleCollaborator
+	}
+	if !rbac.IsRepoRole(role) {
+		fail(fmt.Sprintf("'%s' is not a valid role.", role), nil)
+		return
+	}
+
 	l = l.With("collaborator", collaboratorIdent.Handle)
 	l = l.With("knot", f.Knot)
+	l = l.With("role", role)
+
+	// adding an existing collaborator changes their role
+	existing, err := db.GetCollaborators(
+		rp.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("subject_did", collaboratorIdent.DID),
+	)
+	if err != nil {
+		fail("Failed to add collaborator.", err)
+		return
+	}
 
 	// announce this relation into the firehose, store into owners' pds
 	client, err := rp.oauth.AuthorizedClient(r)
@@ -740,6 +888,10 @@ func (rp *Repo) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 	currentUser := rp.oauth.GetUser(r)
 	rkey := tid.TID()
 	createdAt := time.Now()
+	if len(existing) > 0 {
+		rkey = existing[0].Rkey
+		createdAt = existing[0].Created
+	}
 	resp, err := comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
 		Collection: tangled.RepoCollaboratorNSID,
 		Repo:       currentUser.Did,
@@ -748,6 +900,7 @@ func (rp *Repo) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 			Val: &tangled.RepoCollaborator{
 				Subject:   collaboratorIdent.DID.String(),
 				Repo:      string(f.RepoAt()),
+				Role:      &role,
 				CreatedAt: createdAt.Format(time.RFC3339),
 			}},
 	})
@@ -757,10 +910,15 @@ func (rp *Repo) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	aturi := resp.Uri
-	l = l.With("at-uri", aturi)
+	l = l.With("at-uri", resp.Uri)
 	l.Info("wrote record to PDS")
 
+	// an updated record is not rolled back, it was valid before
+	aturi := resp.Uri
+	if len(existing) > 0 {
+		aturi = ""
+	}
+
 	tx, err := rp.db.BeginTx(r.Context(), nil)
 	if err != nil {
 		fail("Failed to add collaborator.", err)
@@ -784,19 +942,24 @@ func (rp *Repo) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rollback()
 
-	err = rp.enforcer.AddCollaborator(collaboratorIdent.DID.String(), f.Knot, f.DidSlashRepo())
+	err = rp.enforcer.SetRepoRole(collaboratorIdent.DID.String(), f.Knot, f.DidSlashRepo(), role)
 	if err != nil {
 		fail("Failed to add collaborator permissions.", err)
 		return
 	}
 
-	err = db.AddCollaborator(tx, models.Collaborator{
-		Did:        syntax.DID(currentUser.Did),
-		Rkey:       rkey,
-		SubjectDid: collaboratorIdent.DID,
-		RepoAt:     f.RepoAt(),
-		Created:    createdAt,
-	})
+	if len(existing) > 0 {
+		err = db.SetCollaboratorRole(tx, existing[0].Id, role)
+	} else {
+		err = db.AddCollaborator(tx, models.Collaborator{
+			Did:        syntax.DID(currentUser.Did),
+			Rkey:       rkey,
+			SubjectDid: collaboratorIdent.DID,
+			RepoAt:     f.RepoAt(),
+			Role:       role,
+			Created:    createdAt,
+		})
+	}
 	if err != nil {
 		fail("Failed to add collaborator.", err)
 		return
@@ -831,6 +994,11 @@ func (rp *Repo) DeleteRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := rp.oauth.ConfirmAction(r, f.Name); err != nil {
+		rp.pages.Notice(w, noticeId, err.Error())
+		return
+	}
+
 	// remove record from pds
 	atpClient, err := rp.oauth.AuthorizedClient(r)
 	if err != nil {
@@ -853,6 +1021,7 @@ func (rp *Repo) DeleteRepo(w http.ResponseWriter, r *http.Request) {
 		r,
 		oauth.WithService(f.Knot),
 		oauth.WithLxm(tangled.RepoDeleteNSID),
+		oauth.WithoutRetry(),
 		oauth.WithDev(rp.config.Core.Dev),
 	)
 	if err != nil {
@@ -952,6 +1121,7 @@ func (rp *Repo) SyncRepoFork(w http.ResponseWriter, r *http.Request) {
 			r,
 			oauth.WithService(f.Knot),
 			oauth.WithLxm(tangled.RepoForkSyncNSID),
+			oauth.WithoutRetry(),
 			oauth.WithDev(rp.config.Core.Dev),
 		)
 		if err != nil {
@@ -1013,6 +1183,12 @@ func (rp *Repo) ForkRepo(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		l := rp.logger.With("handler", "ForkRepo")
 
+		// knots clone forks anonymously, they can't read private repos
+		if f.Repo.IsPrivate() {
+			rp.pages.Notice(w, "repo", "Private repositories cannot be forked.")
+			return
+		}
+
 		targetKnot := r.FormValue("knot")
 		if targetKnot == "" {
 			rp.pages.Notice(w, "repo", "Invalid form submission&mdash;missing knot domain.")
@@ -1135,6 +1311,7 @@ func (rp *Repo) ForkRepo(w http.ResponseWriter, r *http.Request) {
 			r,
 			oauth.WithService(targetKnot),
 			oauth.WithLxm(tangled.RepoCreateNSID),
+			oauth.WithoutRetry(),
 			oauth.WithDev(rp.config.Core.Dev),
 			oauth.WithTimeout(time.Second*20), // big repos take time to clone
 		)
@@ -102,9 +102,8 @@ func getPipelineStatuses(
 		return m, nil
 	}
 
-	ps, err := db.GetPipelineStatuses(
+	ps, err := db.GetLatestPipelineStatuses(
 		d,
-		len(shas),
 		db.FilterEq("repo_owner", repoInfo.OwnerDid),
 		db.FilterEq("repo_name", repoInfo.Name),
 		db.FilterEq("knot", repoInfo.Knot),
@@ -19,6 +19,12 @@ func (rp *Repo) Router(mw *middleware.Middleware) http.Handler {
 	})
 	r.Get("/commit/{ref}", rp.Commit)
 	r.Get("/branches", rp.Branches)
+	r.With(middleware.Paginate).Get("/stargazers", rp.Stargazers)
+	r.With(middleware.Paginate).Get("/contributors", rp.Contributors)
+	r.Route("/forks", func(r chi.Router) {
+		r.With(middleware.Paginate).Get("/", rp.Forks)
+		r.Get("/network", rp.ForkNetwork)
+	})
 	r.Delete("/branches", rp.DeleteBranch)
 	r.Route("/tags", func(r chi.Router) {
 		r.Get("/", rp.Tags)
@@ -37,9 +43,44 @@ func (rp *Repo) Router(mw *middleware.Middleware) http.Handler {
 			})
 		})
 	})
-	r.Get("/blob/{ref}/*", rp.Blob)
+	r.Route("/releases", func(r chi.Router) {
+		r.Get("/", rp.Releases)
+		r.Get("/latest", rp.LatestRelease)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(rp.oauth))
+			r.Use(mw.RepoPermissionMiddleware("repo:push"))
+			r.Get("/new", rp.NewRelease)
+			r.Post("/new", rp.NewRelease)
+			r.Post("/new/notes", rp.ReleaseNotes)
+		})
+		r.Route("/{tag}", func(r chi.Router) {
+			r.Get("/", rp.Release)
+
+			// only the author can edit, their PDS holds the record
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(rp.oauth))
+				r.Use(mw.RepoPermissionMiddleware("repo:push"))
+				r.Get("/edit", rp.EditRelease)
+				r.Post("/edit", rp.EditRelease)
+			})
+		})
+	})
+	r.With(middleware.Paginate).Get("/blob/{ref}/*", rp.Blob)
 	r.Get("/raw/{ref}/*", rp.RepoBlobRaw)
 
+	// writing files in the browser; those who can't push propose a pull
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(rp.oauth))
+		r.Get("/new/{ref}/*", rp.NewFile)
+		r.Post("/new/{ref}/*", rp.NewFile)
+		r.Get("/edit/{ref}/*", rp.EditFile)
+		r.Post("/edit/{ref}/*", rp.EditFile)
+		r.Get("/delete/{ref}/*", rp.DeleteFile)
+		r.Post("/delete/{ref}/*", rp.DeleteFile)
+		r.Get("/upload/{ref}/*", rp.UploadFiles)
+		r.Post("/upload/{ref}/*", rp.UploadFiles)
+	})
+
 	// intentionally doesn't use /* as this isn't
 	// a file path
 	r.Get("/archive/{ref}", rp.DownloadArchive)
@@ -77,15 +118,22 @@ func (rp *Repo) Router(mw *middleware.Middleware) http.Handler {
 			r.Get("/", rp.Settings)
 			r.With(mw.RepoPermissionMiddleware("repo:owner")).Put("/base", rp.EditBaseSettings)
 			r.With(mw.RepoPermissionMiddleware("repo:owner")).Post("/spindle", rp.EditSpindle)
+			r.With(mw.RepoPermissionMiddleware("repo:owner")).Put("/pipelines", rp.EditPipelineSettings)
 			r.With(mw.RepoPermissionMiddleware("repo:owner")).Put("/label", rp.AddLabelDef)
+			r.With(mw.RepoPermissionMiddleware("repo:owner")).Post("/label", rp.EditLabelDef)
 			r.With(mw.RepoPermissionMiddleware("repo:owner")).Delete("/label", rp.DeleteLabelDef)
 			r.With(mw.RepoPermissionMiddleware("repo:owner")).Post("/label/subscribe", rp.SubscribeLabel)
 			r.With(mw.RepoPermissionMiddleware("repo:owner")).Post("/label/unsubscribe", rp.UnsubscribeLabel)
 			r.With(mw.RepoPermissionMiddleware("repo:invite")).Put("/collaborator", rp.AddCollaborator)
 			r.With(mw.RepoPermissionMiddleware("repo:delete")).Delete("/delete", rp.DeleteRepo)
 			r.Put("/branches/default", rp.SetDefaultBranch)
+			r.With(mw.RepoPermissionMiddleware("repo:owner")).Put("/merge", rp.EditMergeSettings)
+			r.With(mw.RepoPermissionMiddleware("repo:owner")).Put("/template", rp.EditTemplateSettings)
 			r.Put("/secrets", rp.Secrets)
+			r.Patch("/secrets", rp.Secrets)
 			r.Delete("/secrets", rp.Secrets)
+			r.With(mw.RepoPermissionMiddleware("repo:owner")).Put("/checks", rp.RequiredChecks)
+			r.With(mw.RepoPermissionMiddleware("repo:owner")).Delete("/checks", rp.RequiredChecks)
 		})
 	})
 
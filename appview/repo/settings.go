@@ -1,23 +1,31 @@
 package repo
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/refcache"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/eventconsumer"
 	"tangled.org/core/types"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	lexutil "github.com/bluesky-social/indigo/lex/util"
 	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 type tab = map[string]any
@@ -27,7 +35,9 @@ var (
 	settingsTabs []tab = []tab{
 		{"Name": "general", "Icon": "sliders-horizontal"},
 		{"Name": "access", "Icon": "users"},
+		{"Name": "labels", "Icon": "tag"},
 		{"Name": "pipelines", "Icon": "layers-2"},
+		{"Name": "webhooks", "Icon": "webhook"},
 	}
 )
 
@@ -73,13 +83,18 @@ func (rp *Repo) SetDefaultBranch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the default branch is flagged in the cached branches
+	if err := refcache.Invalidate(rp.db, f.RepoAt(), plumbing.NewBranchReferenceName(branch).String()); err != nil {
+		l.Error("failed to invalidate ref cache", "err", err)
+	}
+
 	rp.pages.HxRefresh(w)
 }
 
-func (rp *Repo) Secrets(w http.ResponseWriter, r *http.Request) {
-	user := rp.oauth.GetUser(r)
-	l := rp.logger.With("handler", "Secrets")
-	l = l.With("did", user.Did)
+// EditMergeSettings toggles whether the source branches of pulls are
+// deleted once they are merged. This is kept by the appview alone.
+func (rp *Repo) EditMergeSettings(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "EditMergeSettings")
 
 	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
@@ -87,25 +102,145 @@ func (rp *Repo) Secrets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if f.Spindle == "" {
-		l.Error("empty spindle cannot add/rm secret", "err", err)
+	noticeId := "merge-settings-error"
+	enabled := r.FormValue("deleteBranchOnMerge") == "on"
+	if err := db.UpdateDeleteBranchOnMerge(rp.db, f.RepoAt().String(), enabled); err != nil {
+		l.Error("failed to update merge settings", "err", err)
+		rp.pages.Notice(w, noticeId, "Failed to save merge settings. Try again later.")
 		return
 	}
 
-	lxm := tangled.RepoAddSecretNSID
-	if r.Method == http.MethodDelete {
-		lxm = tangled.RepoRemoveSecretNSID
+	rp.pages.HxRefresh(w)
+}
+
+// EditTemplateSettings marks a repo as a template, or stops it being one.
+// Like forks, templates are fetched anonymously, so private repos can't be
+// templates.
+func (rp *Repo) EditTemplateSettings(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "EditTemplateSettings")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
 	}
 
-	spindleClient, err := rp.oauth.ServiceClient(
-		r,
-		oauth.WithService(f.Spindle),
-		oauth.WithLxm(lxm),
-		oauth.WithExp(60),
-		oauth.WithDev(rp.config.Core.Dev),
-	)
+	noticeId := "template-settings-error"
+	isTemplate := r.FormValue("isTemplate") == "on"
+	if isTemplate && f.IsPrivate() {
+		rp.pages.Notice(w, noticeId, "Private repositories cannot be templates.")
+		return
+	}
+
+	if err := db.UpdateIsTemplate(rp.db, f.RepoAt().String(), isTemplate); err != nil {
+		l.Error("failed to update template settings", "err", err)
+		rp.pages.Notice(w, noticeId, "Failed to save template settings. Try again later.")
+		return
+	}
+
+	rp.pages.HxRefresh(w)
+}
+
+// EditPipelineSettings turns the pipelines of a repo on or off, keeping its
+// spindle. While they are off the spindle is left out of the repo record,
+// so the spindle no longer runs pipelines for its pushes.
+func (rp *Repo) EditPipelineSettings(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "EditPipelineSettings")
+
+	f, err := rp.repoResolver.Resolve(r)
 	if err != nil {
-		l.Error("failed to create spindle client", "err", err)
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	noticeId := "pipeline-settings-error"
+	fail := func(msg string, err error) {
+		l.Error(msg, "err", err)
+		rp.pages.Notice(w, noticeId, msg)
+	}
+
+	disabled := r.FormValue("pipelinesEnabled") != "on"
+	cancelSuperseded := r.FormValue("cancelSuperseded") == "on"
+	requirePassing := r.FormValue("requirePassing") == "on"
+
+	// empty leaves the limit to the spindle
+	concurrency := 0
+	if v := strings.TrimSpace(r.FormValue("concurrency")); v != "" {
+		concurrency, err = strconv.Atoi(v)
+		if err != nil || concurrency < 1 {
+			rp.pages.Notice(w, noticeId, "Concurrent pipelines must be a positive number.")
+			return
+		}
+	}
+
+	client, err := rp.oauth.AuthorizedClient(r)
+	if err != nil {
+		fail("Failed to authorize. Try again later.", err)
+		return
+	}
+
+	// optimistic update
+	if err := db.UpdatePipelinesDisabled(rp.db, f.RepoAt().String(), disabled); err != nil {
+		fail("Failed to save pipeline settings. Try again later.", err)
+		return
+	}
+	if err := db.UpdatePipelineQueue(rp.db, f.RepoAt().String(), concurrency, cancelSuperseded); err != nil {
+		fail("Failed to save pipeline settings. Try again later.", err)
+		return
+	}
+	if err := db.UpdateRequirePassingPipeline(rp.db, f.RepoAt().String(), requirePassing); err != nil {
+		fail("Failed to save pipeline settings. Try again later.", err)
+		return
+	}
+
+	newRepo := f.Repo
+	newRepo.PipelinesDisabled = disabled
+	newRepo.PipelineConcurrency = concurrency
+	newRepo.CancelSupersededPipelines = cancelSuperseded
+	record := newRepo.AsRecord()
+
+	ex, err := comatproto.RepoGetRecord(r.Context(), client, "", tangled.RepoNSID, newRepo.Did, newRepo.Rkey)
+	if err != nil {
+		fail("Failed to save pipeline settings, no record found on PDS.", err)
+		return
+	}
+	_, err = comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoNSID,
+		Repo:       newRepo.Did,
+		Rkey:       newRepo.Rkey,
+		SwapRecord: ex.Cid,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &record,
+		},
+	})
+	if err != nil {
+		fail("Failed to save pipeline settings, unable to save to PDS.", err)
+		return
+	}
+
+	if !disabled && newRepo.Spindle != "" {
+		rp.spindlestream.AddSource(
+			context.Background(),
+			eventconsumer.NewSpindleSource(newRepo.Spindle),
+		)
+	}
+
+	rp.pages.HxRefresh(w)
+}
+
+func (rp *Repo) Secrets(w http.ResponseWriter, r *http.Request) {
+	user := rp.oauth.GetUser(r)
+	l := rp.logger.With("handler", "Secrets")
+	l = l.With("did", user.Did)
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	if f.Spindle == "" {
+		l.Error("empty spindle cannot add/rm secret", "err", err)
 		return
 	}
 
@@ -125,33 +260,38 @@ func (rp *Repo) Secrets(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = tangled.RepoAddSecret(
-			r.Context(),
-			spindleClient,
-			&tangled.RepoAddSecret_Input{
-				Repo:  f.RepoAt().String(),
-				Key:   key,
-				Value: value,
-			},
-		)
-		if err != nil {
+		if err := rp.addSecret(r, f.Spindle, f.RepoAt().String(), key, value); err != nil {
 			l.Error("Failed to add secret.", "err", err)
 			rp.pages.Notice(w, errorId, "Failed to add secret.")
 			return
 		}
 
+	case http.MethodPatch:
+		// secrets cannot be read back, so updating one replaces its value
+		errorId := fmt.Sprintf("update-secret-error-%s", key)
+
+		value := r.FormValue("value")
+		if value == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := rp.removeSecret(r, f.Spindle, f.RepoAt().String(), key); err != nil {
+			l.Error("Failed to remove secret before update.", "err", err)
+			rp.pages.Notice(w, errorId, "Failed to update secret.")
+			return
+		}
+
+		if err := rp.addSecret(r, f.Spindle, f.RepoAt().String(), key, value); err != nil {
+			l.Error("Failed to add secret during update.", "err", err)
+			rp.pages.Notice(w, errorId, "Failed to update secret, it has been removed. Add it again.")
+			return
+		}
+
 	case http.MethodDelete:
 		errorId := "operation-error"
 
-		err = tangled.RepoRemoveSecret(
-			r.Context(),
-			spindleClient,
-			&tangled.RepoRemoveSecret_Input{
-				Repo: f.RepoAt().String(),
-				Key:  key,
-			},
-		)
-		if err != nil {
+		if err := rp.removeSecret(r, f.Spindle, f.RepoAt().String(), key); err != nil {
 			l.Error("Failed to delete secret.", "err", err)
 			rp.pages.Notice(w, errorId, "Failed to delete secret.")
 			return
@@ -161,6 +301,50 @@ func (rp *Repo) Secrets(w http.ResponseWriter, r *http.Request) {
 	rp.pages.HxRefresh(w)
 }
 
+func (rp *Repo) spindleClient(r *http.Request, spindle, lxm string) (*indigoxrpc.Client, error) {
+	return rp.oauth.ServiceClient(
+		r,
+		oauth.WithService(spindle),
+		oauth.WithLxm(lxm),
+		oauth.WithoutRetry(),
+		oauth.WithExp(60),
+		oauth.WithDev(rp.config.Core.Dev),
+	)
+}
+
+func (rp *Repo) addSecret(r *http.Request, spindle, repoAt, key, value string) error {
+	client, err := rp.spindleClient(r, spindle, tangled.RepoAddSecretNSID)
+	if err != nil {
+		return fmt.Errorf("failed to create spindle client: %w", err)
+	}
+
+	return tangled.RepoAddSecret(
+		r.Context(),
+		client,
+		&tangled.RepoAddSecret_Input{
+			Repo:  repoAt,
+			Key:   key,
+			Value: value,
+		},
+	)
+}
+
+func (rp *Repo) removeSecret(r *http.Request, spindle, repoAt, key string) error {
+	client, err := rp.spindleClient(r, spindle, tangled.RepoRemoveSecretNSID)
+	if err != nil {
+		return fmt.Errorf("failed to create spindle client: %w", err)
+	}
+
+	return tangled.RepoRemoveSecret(
+		r.Context(),
+		client,
+		&tangled.RepoRemoveSecret_Input{
+			Repo: repoAt,
+			Key:  key,
+		},
+	)
+}
+
 func (rp *Repo) Settings(w http.ResponseWriter, r *http.Request) {
 	tabVal := r.URL.Query().Get("tab")
 	if tabVal == "" {
@@ -174,8 +358,14 @@ func (rp *Repo) Settings(w http.ResponseWriter, r *http.Request) {
 	case "access":
 		rp.accessSettings(w, r)
 
+	case "labels":
+		rp.labelSettings(w, r)
+
 	case "pipelines":
 		rp.pipelineSettings(w, r)
+
+	case "webhooks":
+		rp.webhookSettings(w, r)
 	}
 }
 
@@ -190,12 +380,10 @@ func (rp *Repo) generalSettings(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
-	xrpcBytes, err := tangled.RepoBranches(r.Context(), xrpcc, "", 0, repo)
+	xrpcBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
 		l.Error("failed to call XRPC repo.branches", "err", xrpcerr)
 		rp.pages.Error503(w)
@@ -209,6 +397,74 @@ func (rp *Repo) generalSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	transfer, err := db.GetRepoTransfer(rp.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("failed to fetch transfer", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	// knots that predate sizes don't know them, the page goes without
+	var repoSize uint64
+	size, err := tangled.RepoSize(r.Context(), xrpcc, repo)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		l.Warn("failed to call XRPC repo.size", "err", xrpcerr)
+	} else if size.Size > 0 {
+		repoSize = uint64(size.Size)
+	}
+
+	var storage *models.StorageUsage
+	if user.Did == f.OwnerDid() {
+		storage, err = rp.storageUsage(r, f.Knot, user.Did)
+		if err != nil {
+			l.Warn("failed to get storage usage", "err", err)
+		}
+	}
+
+	rp.pages.RepoGeneralSettings(w, pages.RepoGeneralSettingsParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Branches:     result.Branches,
+		Tabs:         settingsTabs,
+		Tab:          "general",
+		Transfer:     transfer,
+		TotpEnrolled: rp.oauth.TotpEnrolled(user.Did),
+
+		DeleteBranchOnMerge: f.DeleteBranchOnMerge,
+
+		RepoSize: repoSize,
+		Storage:  storage,
+	})
+}
+
+// storageUsage asks knot how much of its disk the repos of did take up.
+// Only did and the knot owner may ask.
+func (rp *Repo) storageUsage(r *http.Request, knot, did string) (*models.StorageUsage, error) {
+	client, err := rp.oauth.ServiceClient(
+		r,
+		oauth.WithService(knot),
+		oauth.WithLxm(tangled.KnotUsageNSID),
+		oauth.WithDev(rp.config.Core.Dev),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := tangled.KnotUsage(r.Context(), client, did)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return nil, xrpcerr
+	}
+
+	usage := models.StorageUsageFromKnot(knot, out)
+	return &usage, nil
+}
+
+func (rp *Repo) labelSettings(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "labelSettings")
+
+	f, err := rp.repoResolver.Resolve(r)
+	user := rp.oauth.GetUser(r)
+
 	defaultLabels, err := db.GetLabelDefinitions(rp.db, db.FilterIn("at_uri", rp.config.Label.DefaultLabelDefs))
 	if err != nil {
 		l.Error("failed to fetch labels", "err", err)
@@ -252,16 +508,15 @@ func (rp *Repo) generalSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rp.pages.RepoGeneralSettings(w, pages.RepoGeneralSettingsParams{
+	rp.pages.RepoLabelSettings(w, pages.RepoLabelSettingsParams{
 		LoggedInUser:       user,
 		RepoInfo:           f.RepoInfo(user),
-		Branches:           result.Branches,
 		Labels:             labels,
 		DefaultLabels:      defaultLabels,
 		SubscribedLabels:   subscribedLabels,
 		ShouldSubscribeAll: shouldSubscribeAll,
 		Tabs:               settingsTabs,
-		Tab:                "general",
+		Tab:                "labels",
 	})
 }
 
@@ -292,11 +547,19 @@ func (rp *Repo) pipelineSettings(w http.ResponseWriter, r *http.Request) {
 	user := rp.oauth.GetUser(r)
 
 	// all spindles that the repo owner is a member of
-	spindles, err := rp.enforcer.GetSpindlesForUser(f.OwnerDid())
+	memberOf, err := rp.enforcer.GetSpindlesForUser(f.OwnerDid())
+	if err != nil {
+		l.Error("failed to fetch spindles", "err", err)
+		return
+	}
+
+	// of those, the ones an admin let serve
+	spindles, err := db.ApprovedSpindles(rp.db, memberOf)
 	if err != nil {
 		l.Error("failed to fetch spindles", "err", err)
 		return
 	}
+	slices.Sort(spindles)
 
 	var secrets []*tangled.RepoListSecrets_Secret
 	if f.Spindle != "" {
@@ -337,17 +600,122 @@ func (rp *Repo) pipelineSettings(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	requiredChecks, err := db.GetRequiredChecks(rp.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err != nil {
+		l.Error("failed to fetch required checks", "err", err)
+	}
+
 	rp.pages.RepoPipelineSettings(w, pages.RepoPipelineSettingsParams{
-		LoggedInUser:   user,
-		RepoInfo:       f.RepoInfo(user),
-		Tabs:           settingsTabs,
-		Tab:            "pipelines",
-		Spindles:       spindles,
-		CurrentSpindle: f.Spindle,
-		Secrets:        niceSecret,
+		LoggedInUser:     user,
+		RepoInfo:         f.RepoInfo(user),
+		Tabs:             settingsTabs,
+		Tab:              "pipelines",
+		Spindles:         spindles,
+		CurrentSpindle:   f.Spindle,
+		PipelinesOn:      !f.PipelinesDisabled,
+		Concurrency:      f.PipelineConcurrency,
+		CancelSuperseded: f.CancelSupersededPipelines,
+		RequirePassing:   f.RequirePassingPipeline,
+		Secrets:          niceSecret,
+		RequiredChecks:   requiredChecks,
 	})
 }
 
+// webhookSettings lists the repo's webhooks, and the latest deliveries of
+// the one picked with the hook query parameter.
+func (rp *Repo) webhookSettings(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "webhookSettings")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+	user := rp.oauth.GetUser(r)
+
+	hooks, err := db.GetWebhooks(rp.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err != nil {
+		l.Error("failed to fetch webhooks", "err", err)
+	}
+
+	var current *models.Webhook
+	var deliveries []models.WebhookDelivery
+	if id, err := strconv.ParseInt(r.URL.Query().Get("hook"), 10, 64); err == nil {
+		for i := range hooks {
+			if hooks[i].Id == id {
+				current = &hooks[i]
+			}
+		}
+	}
+	if current != nil {
+		deliveries, err = db.GetWebhookDeliveries(rp.db, rp.config.Webhook.KeepDeliveries, db.FilterEq("webhook_id", current.Id))
+		if err != nil {
+			l.Error("failed to fetch webhook deliveries", "err", err)
+		}
+	}
+
+	rp.pages.RepoWebhookSettings(w, pages.RepoWebhookSettingsParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Tabs:         settingsTabs,
+		Tab:          "webhooks",
+		Webhooks:     hooks,
+		Webhook:      current,
+		Deliveries:   deliveries,
+	})
+}
+
+// RequiredChecks adds and removes the checks that pulls into a branch must
+// pass before they can be merged.
+func (rp *Repo) RequiredChecks(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "RequiredChecks")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		errorId := "add-required-check-error"
+
+		branch := strings.TrimSpace(r.FormValue("branch"))
+		check := strings.TrimSpace(r.FormValue("context"))
+		if branch == "" || check == "" {
+			rp.pages.Notice(w, errorId, "Both a branch and a check are needed.")
+			return
+		}
+
+		err = db.AddRequiredCheck(rp.db, models.RequiredCheck{
+			RepoAt:  f.RepoAt(),
+			Branch:  branch,
+			Context: check,
+		})
+		if err != nil {
+			l.Error("failed to add required check", "err", err)
+			rp.pages.Notice(w, errorId, "Failed to add required check.")
+			return
+		}
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = db.DeleteRequiredChecks(rp.db, db.FilterEq("repo_at", f.RepoAt()), db.FilterEq("id", id))
+		if err != nil {
+			l.Error("failed to delete required check", "err", err)
+			rp.pages.Notice(w, "operation-error", "Failed to delete required check.")
+			return
+		}
+	}
+
+	rp.pages.HxRefresh(w)
+}
+
 func (rp *Repo) EditBaseSettings(w http.ResponseWriter, r *http.Request) {
 	l := rp.logger.With("handler", "EditBaseSettings")
 
@@ -371,8 +739,18 @@ func (rp *Repo) EditBaseSettings(w http.ResponseWriter, r *http.Request) {
 		description = r.FormValue("description")
 		website     = r.FormValue("website")
 		topicStr    = r.FormValue("topics")
+		visibility  = models.RepoVisibility(r.FormValue("visibility"))
 	)
 
+	switch visibility {
+	case "":
+		visibility = f.Visibility
+	case models.RepoVisibilityPublic, models.RepoVisibilityPrivate:
+	default:
+		rp.pages.Notice(w, noticeId, "Invalid repository visibility.")
+		return
+	}
+
 	err = rp.validator.ValidateURI(website)
 	if website != "" && err != nil {
 		l.Error("invalid uri", "err", err)
@@ -392,6 +770,7 @@ func (rp *Repo) EditBaseSettings(w http.ResponseWriter, r *http.Request) {
 	newRepo.Description = description
 	newRepo.Website = website
 	newRepo.Topics = topics
+	newRepo.Visibility = visibility
 	record := newRepo.AsRecord()
 
 	tx, err := rp.db.BeginTx(r.Context(), nil)
@@ -409,6 +788,15 @@ func (rp *Repo) EditBaseSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// private repos can't be fetched as templates
+	if newRepo.IsPrivate() && newRepo.IsTemplate {
+		if err := db.UpdateIsTemplate(tx, newRepo.RepoAt().String(), false); err != nil {
+			l.Error("failed to unmark template", "err", err)
+			rp.pages.Notice(w, noticeId, "Failed to save repository information.")
+			return
+		}
+	}
+
 	ex, err := comatproto.RepoGetRecord(r.Context(), client, "", tangled.RepoNSID, newRepo.Did, newRepo.Rkey)
 	if err != nil {
 		// failed to get record
@@ -433,6 +821,33 @@ func (rp *Repo) EditBaseSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if newRepo.Visibility != f.Visibility {
+		knotClient, err := rp.oauth.ServiceClient(
+			r,
+			oauth.WithService(f.Knot),
+			oauth.WithLxm(tangled.RepoSetVisibilityNSID),
+			oauth.WithDev(rp.config.Core.Dev),
+		)
+		if err != nil {
+			l.Error("failed to connect to knot server", "err", err)
+			rp.pages.Notice(w, noticeId, "Failed to connect to knot server.")
+			return
+		}
+
+		xe := tangled.RepoSetVisibility(
+			r.Context(),
+			knotClient,
+			&tangled.RepoSetVisibility_Input{
+				Repo: f.RepoAt().String(),
+			},
+		)
+		if err := xrpcclient.HandleXrpcErr(xe); err != nil {
+			l.Error("xrpc failed", "err", xe)
+			rp.pages.Notice(w, noticeId, err.Error())
+			return
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		l.Error("failed to commit", "err", err)
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pages/repoinfo"
+	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/appview/xrpcclient"
+)
+
+// emptyRepoSetup is what someone who can push to an empty repo needs to
+// get their first commits into it.
+func (rp *Repo) emptyRepoSetup(ctx context.Context, xrpcc *indigoxrpc.Client, f *reporesolver.ResolvedRepo, repoInfo repoinfo.RepoInfo) *pages.RepoSetup {
+	setup := &pages.RepoSetup{Branch: rp.config.Core.DefaultBranch}
+
+	// HEAD of an empty repo still names the branch it was created with
+	out, err := tangled.RepoGetDefaultBranch(ctx, xrpcc, f.DidSlashRepo())
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		rp.logger.Warn("failed to get default branch of empty repo", "err", xrpcerr)
+	} else if out.Name != "" {
+		setup.Branch = out.Name
+	}
+
+	setup.HTTPSUrl, setup.SSHUrl = rp.cloneUrls(repoInfo.Knot, repoInfo.OwnerHandle, repoInfo.Name)
+	if repoInfo.Source != nil {
+		setup.UpstreamUrl, _ = rp.cloneUrls(repoInfo.Source.Knot, repoInfo.SourceHandle, repoInfo.Source.Name)
+	}
+
+	return setup
+}
+
+// cloneUrls are the remotes of a repo: HTTPS goes through the appview,
+// which proxies to the knot, and SSH goes straight to the knot.
+func (rp *Repo) cloneUrls(knot, owner, name string) (httpsUrl, sshUrl string) {
+	host := strings.TrimSuffix(rp.config.Core.AppviewHost, "/")
+	if !strings.Contains(host, "://") {
+		scheme := "http"
+		if !rp.config.Core.Dev {
+			scheme = "https"
+		}
+		host = fmt.Sprintf("%s://%s", scheme, host)
+	}
+	httpsUrl = fmt.Sprintf("%s/%s/%s", host, owner, name)
+
+	if knot == "knot1.tangled.sh" {
+		knot = "tangled.org"
+	}
+	knot, _, _ = strings.Cut(knot, ":")
+	sshUrl = fmt.Sprintf("git@%s:%s/%s", knot, owner, name)
+
+	return httpsUrl, sshUrl
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"net/http"
+
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+)
+
+// Stargazers lists who starred the repo, newest first.
+func (rp *Repo) Stargazers(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "Stargazers")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		rp.pages.Error404(w)
+		return
+	}
+
+	page := pagination.FromContext(r.Context())
+
+	stars, err := db.GetStars(rp.db, page, db.FilterEq("subject_at", f.RepoAt()))
+	if err != nil {
+		l.Error("failed to get stars", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	total, err := db.GetStarCount(rp.db, f.RepoAt())
+	if err != nil {
+		l.Error("failed to get star count", "err", err)
+		rp.pages.Error503(w)
+		return
+	}
+
+	dids := make([]string, len(stars))
+	for i, star := range stars {
+		dids[i] = star.Did
+	}
+	idents := rp.idResolver.ResolveIdents(r.Context(), dids)
+
+	stargazers := make([]pages.Stargazer, len(stars))
+	for i, star := range stars {
+		stargazers[i] = pages.Stargazer{
+			Did:     star.Did,
+			Starred: star.Created,
+		}
+		if ident := idents[i]; ident != nil && !ident.Handle.IsInvalidHandle() {
+			stargazers[i].Handle = ident.Handle.String()
+		}
+	}
+
+	user := rp.oauth.GetUser(r)
+	rp.pages.RepoStargazers(w, pages.RepoStargazersParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Stargazers:   stargazers,
+		Page:         page,
+		Total:        total,
+	})
+}
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/types"
+)
+
+// submoduleInfo converts the submodule of a tree entry, and works out where
+// its entry should link to: the pinned commit when the submodule lives on a
+// knot we know of, or else its url when that can be browsed.
+func (rp *Repo) submoduleInfo(ctx context.Context, f *reporesolver.ResolvedRepo, sm *tangled.RepoTree_Submodule) *types.SubmoduleInfo {
+	info := &types.SubmoduleInfo{
+		Name:   sm.Name,
+		Url:    sm.Url,
+		Commit: sm.Commit,
+	}
+	if sm.Branch != nil {
+		info.Branch = *sm.Branch
+	}
+
+	if info.Url == "" {
+		return info
+	}
+
+	scheme := "https"
+	if rp.config.Core.Dev {
+		scheme = "http"
+	}
+	superproject := fmt.Sprintf("%s://%s/%s/%s", scheme, f.Knot, f.OwnerDid(), f.Name)
+
+	u, err := resolveSubmoduleUrl(superproject, info.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		// ssh and scp-like urls can't be browsed
+		return info
+	}
+	info.Url = u.String()
+	info.Link = info.Url
+
+	if repo := rp.knownRepo(ctx, u); repo != nil {
+		info.Link = fmt.Sprintf("/%s/%s/tree/%s", repo.Did, repo.Name, url.PathEscape(info.Commit))
+	}
+
+	return info
+}
+
+// resolveSubmoduleUrl resolves a submodule url against the url of its
+// superproject. Like git, relative urls start with ./ or ../, and are
+// relative to the superproject itself rather than to its parent, so
+// ../lib next to example.com/alice/app is example.com/alice/lib.
+func resolveSubmoduleUrl(superproject, raw string) (*url.URL, error) {
+	if !strings.HasPrefix(raw, "./") && !strings.HasPrefix(raw, "../") {
+		return url.Parse(raw)
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(superproject, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return base.ResolveReference(ref), nil
+}
+
+// knownRepo looks up the repo behind a clone url, either on one of the
+// registered knots or on the appview itself, where the owner may also be a
+// handle.
+func (rp *Repo) knownRepo(ctx context.Context, u *url.URL) *models.Repo {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 {
+		return nil
+	}
+
+	onAppview := false
+	if appview, err := url.Parse(rp.config.Core.AppviewHost); err == nil {
+		onAppview = u.Host == appview.Host
+	}
+
+	// skip resolving identities for hosts we know nothing about
+	if !onAppview {
+		registrations, err := db.GetRegistrations(rp.db, db.FilterEq("domain", u.Host))
+		if err != nil || len(registrations) == 0 {
+			return nil
+		}
+	}
+
+	ident, err := rp.idResolver.ResolveIdent(ctx, strings.TrimPrefix(parts[0], "@"))
+	if err != nil {
+		return nil
+	}
+	did := ident.DID.String()
+	name := strings.TrimSuffix(parts[1], ".git")
+
+	var repo *models.Repo
+	if onAppview {
+		repo, err = db.GetRepo(rp.db, db.FilterEq("did", did), db.FilterEq("name", name))
+	} else {
+		repo, err = db.GetRepo(rp.db, db.FilterEq("did", did), db.FilterEq("name", name), db.FilterEq("knot", u.Host))
+	}
+	if err != nil {
+		return nil
+	}
+
+	return repo
+}
@@ -12,7 +12,6 @@ import (
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/types"
 
-	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 )
@@ -36,9 +35,7 @@ func (rp *Repo) Tree(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
-	xrpcc := &indigoxrpc.Client{
-		Host: host,
-	}
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
 	repo := fmt.Sprintf("%s/%s", f.OwnerDid(), f.Name)
 	xrpcResp, err := tangled.RepoTree(r.Context(), xrpcc, treePath, ref, repo)
 	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
@@ -63,6 +60,9 @@ func (rp *Repo) Tree(w http.ResponseWriter, r *http.Request) {
 				When:    commitWhen,
 			}
 		}
+		if xrpcFile.Submodule != nil {
+			file.Submodule = rp.submoduleInfo(r.Context(), f, xrpcFile.Submodule)
+		}
 		files[i] = file
 	}
 	result := types.RepoTreeResponse{
@@ -102,5 +102,6 @@ func (rp *Repo) Tree(w http.ResponseWriter, r *http.Request) {
 		TreePath:         treePath,
 		RepoInfo:         f.RepoInfo(user),
 		RepoTreeResponse: result,
+		CanAddFiles:      user != nil && rp.isBranch(r, f, ref),
 	})
 }
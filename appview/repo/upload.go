@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/pages"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-chi/chi/v5"
+)
+
+// UploadFiles commits files uploaded from the browser to the directory
+// given in the path, all in one commit.
+func (rp *Repo) UploadFiles(w http.ResponseWriter, r *http.Request) {
+	l := rp.logger.With("handler", "UploadFiles")
+
+	f, err := rp.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+	user := rp.oauth.GetUser(r)
+	limits := rp.config.Upload
+
+	ref := chi.URLParam(r, "ref")
+	ref, _ = url.PathUnescape(ref)
+
+	dir := chi.URLParam(r, "*")
+	dir, _ = url.PathUnescape(dir)
+	dir = strings.Trim(dir, "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		if !rp.isBranch(r, f, ref) {
+			rp.pages.Error404(w)
+			return
+		}
+
+		rp.pages.RepoUploadFiles(w, pages.RepoUploadFilesParams{
+			LoggedInUser: user,
+			RepoInfo:     f.RepoInfo(user),
+			Ref:          ref,
+			Dir:          dir,
+			CanCommit:    rp.mayCommitTo(f, user, ref),
+			MaxFileBytes: uint64(limits.MaxFileBytes),
+			MaxBytes:     uint64(limits.MaxBytes),
+			MaxFiles:     limits.MaxFiles,
+		})
+
+	case http.MethodPost:
+		noticeId := "commit-files"
+		tooLarge := fmt.Sprintf("Uploads can be at most %s in all.", humanize.Bytes(uint64(limits.MaxBytes)))
+
+		// leave some room for the multipart framing and the message
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBytes+1<<20)
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			var maxBytes *http.MaxBytesError
+			if errors.As(err, &maxBytes) {
+				rp.pages.Notice(w, noticeId, tooLarge)
+				return
+			}
+			l.Error("failed to parse upload", "err", err)
+			rp.pages.Notice(w, noticeId, "Failed to read the upload.")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		headers := r.MultipartForm.File["files"]
+		if len(headers) == 0 {
+			rp.pages.Notice(w, noticeId, "Choose some files to upload.")
+			return
+		}
+		if len(headers) > limits.MaxFiles {
+			rp.pages.Notice(w, noticeId, fmt.Sprintf("At most %d files can be uploaded at once.", limits.MaxFiles))
+			return
+		}
+
+		var total int64
+		seen := make(map[string]bool)
+		files := make([]*tangled.RepoCommitFiles_File, 0, len(headers))
+		for _, header := range headers {
+			if header.Size > limits.MaxFileBytes {
+				rp.pages.Notice(w, noticeId, fmt.Sprintf("%s is larger than %s, the most a file can be.", header.Filename, humanize.Bytes(uint64(limits.MaxFileBytes))))
+				return
+			}
+			total += header.Size
+			if total > limits.MaxBytes {
+				rp.pages.Notice(w, noticeId, tooLarge)
+				return
+			}
+
+			filePath := path.Join(dir, header.Filename)
+			if seen[filePath] {
+				rp.pages.Notice(w, noticeId, fmt.Sprintf("%s was chosen twice.", header.Filename))
+				return
+			}
+			seen[filePath] = true
+
+			content, err := readUpload(header)
+			if err != nil {
+				l.Error("failed to read upload", "err", err, "file", header.Filename)
+				rp.pages.Notice(w, noticeId, "Failed to read the upload.")
+				return
+			}
+
+			files = append(files, &tangled.RepoCommitFiles_File{
+				Path:    filePath,
+				Content: base64.StdEncoding.EncodeToString(content),
+			})
+		}
+
+		fallback := fmt.Sprintf("Add %d files", len(files))
+		if len(files) == 1 {
+			fallback = fmt.Sprintf("Add %s", files[0].Path)
+		}
+		message := commitMessage(r, fallback)
+
+		next := fmt.Sprintf("/%s/tree/%s", f.OwnerSlashRepo(), url.PathEscape(ref))
+		if dir != "" {
+			next = next + "/" + dir
+		}
+
+		rp.commitFiles(w, r, f, user, ref, message, files, next)
+	}
+}
+
+func readUpload(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
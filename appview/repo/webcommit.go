@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages/markup"
+	"tangled.org/core/appview/refcache"
+	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/tid"
+	"tangled.org/core/types"
+)
+
+// isBranch reports whether ref is a branch of the repo, which is all that
+// files can be committed to from the web. The branch of an empty repo has
+// no commits yet, and counts as well.
+func (rp *Repo) isBranch(r *http.Request, f *reporesolver.ResolvedRepo, ref string) bool {
+	scheme := "http"
+	if !rp.config.Core.Dev {
+		scheme = "https"
+	}
+	host := fmt.Sprintf("%s://%s", scheme, f.Knot)
+	xrpcc := xrpcclient.NewClient(rp.config.KnotClient, host)
+
+	xrpcBytes, err := refcache.Branches(r.Context(), rp.db, xrpcc, f.RepoAt(), f.DidSlashRepo())
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return false
+	}
+
+	var result types.RepoBranchesResponse
+	if err := json.Unmarshal(xrpcBytes, &result); err != nil {
+		return false
+	}
+
+	for _, b := range result.Branches {
+		if b.Name == ref {
+			return true
+		}
+	}
+
+	if len(result.Branches) == 0 {
+		out, err := tangled.RepoGetDefaultBranch(r.Context(), xrpcc, f.DidSlashRepo())
+		return err == nil && out.Name == ref
+	}
+
+	return false
+}
+
+// mayCommitTo reports whether user may commit straight to branch. Branches
+// that need checks to pass take changes through pulls only.
+func (rp *Repo) mayCommitTo(f *reporesolver.ResolvedRepo, user *oauth.User, branch string) bool {
+	if !f.RolesInRepo(user).IsPushAllowed() {
+		return false
+	}
+
+	checks, err := db.GetRequiredChecks(
+		rp.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("branch", branch),
+	)
+	if err != nil {
+		rp.logger.Error("failed to get required checks", "err", err)
+		return false
+	}
+
+	return len(checks) == 0
+}
+
+// commitFiles commits files written in the web UI to branch, and sends the
+// browser to next. Users who may not commit to the branch get a pull with
+// the change instead.
+func (rp *Repo) commitFiles(
+	w http.ResponseWriter,
+	r *http.Request,
+	f *reporesolver.ResolvedRepo,
+	user *oauth.User,
+	branch, message string,
+	files []*tangled.RepoCommitFiles_File,
+	next string,
+) {
+	l := rp.logger.With("handler", "commitFiles", "repo", f.DidSlashRepo(), "branch", branch)
+	noticeId := "commit-files"
+
+	if !rp.isBranch(r, f, branch) {
+		rp.pages.Notice(w, noticeId, fmt.Sprintf("%s is not a branch, changes can only be committed to branches.", branch))
+		return
+	}
+
+	ident, err := rp.idResolver.ResolveIdent(r.Context(), user.Did)
+	if err != nil {
+		l.Error("failed to resolve identity", "err", err)
+		rp.pages.Notice(w, noticeId, "Failed to commit changes. Try again later.")
+		return
+	}
+	authorName := ident.Handle.String()
+
+	input := &tangled.RepoCommitFiles_Input{
+		Repo:       f.RepoAt().String(),
+		Branch:     branch,
+		Message:    message,
+		Files:      files,
+		AuthorName: &authorName,
+	}
+
+	email, err := db.GetPrimaryEmail(rp.db, user.Did)
+	if err != nil {
+		l.Warn("failed to get primary email", "err", err)
+	}
+	if email.Address != "" {
+		input.AuthorEmail = &email.Address
+	}
+
+	direct := rp.mayCommitTo(f, user, branch)
+	if !direct {
+		patchOnly := true
+		input.PatchOnly = &patchOnly
+	}
+
+	client, err := rp.oauth.ServiceClient(
+		r,
+		oauth.WithService(f.Knot),
+		oauth.WithLxm(tangled.RepoCommitFilesNSID),
+		oauth.WithoutRetry(),
+		oauth.WithDev(rp.config.Core.Dev),
+	)
+	if err != nil {
+		l.Error("failed to get service client", "err", err)
+		rp.pages.Notice(w, noticeId, "Failed to connect to knotserver.")
+		return
+	}
+
+	out, err := tangled.RepoCommitFiles(r.Context(), client, input)
+	if err != nil {
+		l.Error("failed to commit files", "err", err)
+		if msg, ok := xrpcclient.ErrorMessage(err); ok {
+			rp.pages.Notice(w, noticeId, fmt.Sprintf("Failed to commit changes: %s.", msg))
+		} else {
+			rp.pages.Notice(w, noticeId, "Failed to commit changes. Try again later.")
+		}
+		return
+	}
+
+	if direct {
+		// don't wait for the knot to report the push
+		if err := refcache.Invalidate(rp.db, f.RepoAt(), plumbing.NewBranchReferenceName(branch).String()); err != nil {
+			l.Error("failed to invalidate ref cache", "err", err)
+		}
+		rp.pages.HxLocation(w, next)
+		return
+	}
+
+	if out.Patch == nil {
+		l.Error("knot returned no patch")
+		rp.pages.Notice(w, noticeId, "Failed to propose changes. Try again later.")
+		return
+	}
+
+	pullId, err := rp.proposeChange(r, f, user, branch, message, *out.Patch)
+	if err != nil {
+		l.Error("failed to open pull", "err", err)
+		rp.pages.Notice(w, noticeId, "Failed to propose changes. Try again later.")
+		return
+	}
+
+	rp.pages.HxLocation(w, fmt.Sprintf("/%s/pulls/%d", f.OwnerSlashRepo(), pullId))
+}
+
+// proposeChange opens a pull for a patch made from the web UI, by a user
+// who may not commit it themselves.
+func (rp *Repo) proposeChange(r *http.Request, f *reporesolver.ResolvedRepo, user *oauth.User, branch, message, patch string) (int, error) {
+	if err := rp.validator.ValidatePatch(&patch); err != nil {
+		return 0, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	title, body, _ := strings.Cut(message, "\n")
+	body = strings.TrimSpace(body)
+
+	client, err := rp.oauth.AuthorizedClient(r)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := rp.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rkey := tid.TID()
+	pull := &models.Pull{
+		Title:        title,
+		Body:         body,
+		TargetBranch: branch,
+		OwnerDid:     user.Did,
+		RepoAt:       f.RepoAt(),
+		Rkey:         rkey,
+		Submissions: []*models.PullSubmission{
+			{Patch: patch},
+		},
+	}
+	if err := db.NewPull(tx, pull); err != nil {
+		return 0, fmt.Errorf("failed to create pull: %w", err)
+	}
+	if err := db.PutReferenceLinks(tx, pull.RepoAt, pull.AtUri(), pull.AtUri(), markup.FindReferences(pull.Body)); err != nil {
+		rp.logger.Error("failed to record references", "err", err)
+	}
+
+	_, err = comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoPullNSID,
+		Repo:       user.Did,
+		Rkey:       rkey,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &tangled.RepoPull{
+				Title: title,
+				Body:  &body,
+				Target: &tangled.RepoPull_Target{
+					Repo:   f.RepoAt().String(),
+					Branch: branch,
+				},
+				Patch:     patch,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to write pull record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	rp.notifier.NewPull(r.Context(), pull)
+	return pull.PullId, nil
+}
+
+// blobLink is where a file committed to branch is shown.
+func blobLink(f *reporesolver.ResolvedRepo, branch, path string) string {
+	return fmt.Sprintf("/%s/blob/%s/%s", f.OwnerSlashRepo(), url.PathEscape(branch), path)
+}
@@ -98,14 +98,9 @@ func (f *ResolvedRepo) Collaborators(ctx context.Context) ([]pages.Collaborator,
 
 	var collaborators []pages.Collaborator
 	for _, item := range repoCollaborators {
-		// currently only two roles: owner and member
-		var role string
-		switch item[3] {
-		case "repo:owner":
-			role = "owner"
-		case "repo:collaborator":
-			role = "collaborator"
-		default:
+		// every member holds a permission naming their role
+		role, ok := strings.CutPrefix(item[3], "repo:")
+		if !ok || (role != "owner" && !rbac.IsRepoRole(role)) {
 			continue
 		}
 
@@ -140,14 +135,20 @@ func (f *ResolvedRepo) Collaborators(ctx context.Context) ([]pages.Collaborator,
 func (f *ResolvedRepo) RepoInfo(user *oauth.User) repoinfo.RepoInfo {
 	repoAt := f.RepoAt()
 	isStarred := false
+	isWatching := false
 	if user != nil {
 		isStarred = db.GetStarStatus(f.rr.execer, user.Did, repoAt)
+		isWatching = db.GetWatchStatus(f.rr.execer, user.Did, repoAt)
 	}
 
 	starCount, err := db.GetStarCount(f.rr.execer, repoAt)
 	if err != nil {
 		log.Println("failed to get star count for ", repoAt)
 	}
+	forkCount, err := db.GetForkCount(f.rr.execer, repoAt)
+	if err != nil {
+		log.Println("failed to get fork count for ", repoAt)
+	}
 	issueCount, err := db.GetIssueCount(f.rr.execer, repoAt)
 	if err != nil {
 		log.Println("failed to get issue count for ", repoAt)
@@ -190,12 +191,16 @@ func (f *ResolvedRepo) RepoInfo(user *oauth.User) repoinfo.RepoInfo {
 		Description: f.Description,
 		Website:     f.Website,
 		Topics:      f.Topics,
+		IsPrivate:   f.IsPrivate(),
+		IsTemplate:  f.IsTemplate,
 		IsStarred:   isStarred,
+		IsWatching:  isWatching,
 		Knot:        knot,
 		Spindle:     f.Spindle,
 		Roles:       f.RolesInRepo(user),
 		Stats: models.RepoStats{
 			StarCount:  starCount,
+			ForkCount:  forkCount,
 			IssueCount: issueCount,
 			PullCount:  pullCount,
 		},
@@ -220,6 +225,45 @@ func (f *ResolvedRepo) RolesInRepo(u *oauth.User) repoinfo.RolesInRepo {
 	}
 }
 
+// PrivateRepos splits the private repos into those viewer may read and
+// those hidden from them, by at-uri. Anonymous viewers, with an empty did,
+// read none of them.
+func PrivateRepos(e db.Execer, enforcer *rbac.Enforcer, viewer string) (readable, hidden []string, err error) {
+	repos, err := db.GetRepos(e, 0, db.FilterEq("visibility", models.RepoVisibilityPrivate))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, repo := range repos {
+		ok := false
+		if viewer != "" {
+			ok, err = enforcer.IsRepoReadAllowed(viewer, repo.Knot, repo.DidSlashRepo())
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if ok {
+			readable = append(readable, repo.RepoAt().String())
+		} else {
+			hidden = append(hidden, repo.RepoAt().String())
+		}
+	}
+
+	return readable, hidden, nil
+}
+
+// ReadableRepos returns the private repos user may read, see PrivateRepos.
+func (rr *RepoResolver) ReadableRepos(user *oauth.User) ([]string, error) {
+	var viewer string
+	if user != nil {
+		viewer = user.Did
+	}
+
+	readable, _, err := PrivateRepos(rr.execer, rr.enforcer, viewer)
+	return readable, err
+}
+
 // extractPathAfterRef gets the actual repository path
 // after the ref. for example:
 //
@@ -0,0 +1,242 @@
+// Package search looks for repos, issues, pulls and users across the whole
+// appview, leaving out whatever the viewer is not allowed to see.
+package search
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/indexer"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+	"tangled.org/core/appview/reporesolver"
+	"tangled.org/core/idresolver"
+	"tangled.org/core/rbac"
+)
+
+// how many results of each kind are shown when searching all of them
+const previewLimit = 5
+
+type Search struct {
+	db         *db.DB
+	oauth      *oauth.OAuth
+	enforcer   *rbac.Enforcer
+	indexer    *indexer.Indexer
+	idResolver *idresolver.Resolver
+	pages      *pages.Pages
+	logger     *slog.Logger
+}
+
+func New(
+	database *db.DB,
+	oauthHandler *oauth.OAuth,
+	enforcer *rbac.Enforcer,
+	indexer *indexer.Indexer,
+	idResolver *idresolver.Resolver,
+	pagesHandler *pages.Pages,
+	logger *slog.Logger,
+) *Search {
+	return &Search{
+		db:         database,
+		oauth:      oauthHandler,
+		enforcer:   enforcer,
+		indexer:    indexer,
+		idResolver: idResolver,
+		pages:      pagesHandler,
+		logger:     logger,
+	}
+}
+
+func (s *Search) Router() http.Handler {
+	r := chi.NewRouter()
+	r.With(middleware.Paginate).Get("/", s.searchPage)
+	return r
+}
+
+func (s *Search) searchPage(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "searchPage")
+	user := s.oauth.GetUser(r)
+
+	params := pages.SearchParams{
+		LoggedInUser: user,
+		Query:        strings.TrimSpace(r.URL.Query().Get("q")),
+		Kind:         pages.SearchKind(r.URL.Query().Get("type")),
+		Page:         pagination.FromContext(r.Context()),
+	}
+	if !slices.Contains(pages.SearchKinds, params.Kind) {
+		params.Kind = ""
+	}
+
+	if params.Query == "" {
+		s.pages.Search(w, params)
+		return
+	}
+
+	// a page of each kind, or pages of the one asked for
+	page := params.Page
+	if params.Kind == "" {
+		page = pagination.Page{Limit: previewLimit}
+	}
+	wants := func(kind pages.SearchKind) bool {
+		return params.Kind == "" || params.Kind == kind
+	}
+
+	var viewer string
+	if user != nil {
+		viewer = user.Did
+	}
+	readable, hidden, err := reporesolver.PrivateRepos(s.db, s.enforcer, viewer)
+	if err != nil {
+		l.Error("failed to list private repos", "err", err)
+		s.pages.Error500(w)
+		return
+	}
+
+	if wants(pages.SearchRepos) {
+		params.Repos, params.RepoCount, err = db.SearchRepos(s.db, params.Query, readable, page)
+		if err != nil {
+			l.Error("failed to search repos", "err", err)
+			s.pages.Error500(w)
+			return
+		}
+	}
+
+	if wants(pages.SearchIssues) {
+		params.Issues, params.IssueCount, err = s.issues(r.Context(), params.Query, hidden, page)
+		if err != nil {
+			l.Error("failed to search issues", "err", err)
+			s.pages.Error500(w)
+			return
+		}
+	}
+
+	if wants(pages.SearchPulls) {
+		params.Pulls, params.PullCount, err = s.pulls(r.Context(), params.Query, hidden, page)
+		if err != nil {
+			l.Error("failed to search pulls", "err", err)
+			s.pages.Error500(w)
+			return
+		}
+	}
+
+	if wants(pages.SearchUsers) {
+		params.Users, params.UserCount, err = s.users(r.Context(), params.Query, page)
+		if err != nil {
+			l.Error("failed to search users", "err", err)
+			s.pages.Error500(w)
+			return
+		}
+	}
+
+	s.pages.Search(w, params)
+}
+
+func (s *Search) issues(ctx context.Context, keyword string, hidden []string, page pagination.Page) ([]models.Issue, int, error) {
+	res, err := s.indexer.Issues.Search(ctx, models.IssueSearchOptions{
+		Keyword:        keyword,
+		AnyState:       true,
+		ExcludeRepoAts: hidden,
+		Page:           page,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if res == nil || len(res.Hits) == 0 {
+		return nil, 0, nil
+	}
+
+	issues, err := db.GetIssues(s.db, db.FilterIn("id", res.Hits))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// back into the order of relevance
+	slices.SortFunc(issues, func(a, b models.Issue) int {
+		return slices.Index(res.Hits, a.Id) - slices.Index(res.Hits, b.Id)
+	})
+
+	return issues, int(res.Total), nil
+}
+
+func (s *Search) pulls(ctx context.Context, keyword string, hidden []string, page pagination.Page) ([]*models.Pull, int, error) {
+	res, err := s.indexer.Pulls.Search(ctx, models.PullSearchOptions{
+		Keyword:        keyword,
+		AnyState:       true,
+		ExcludeRepoAts: hidden,
+		Page:           page,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if res == nil || len(res.Hits) == 0 {
+		return nil, 0, nil
+	}
+
+	pulls, err := db.GetPulls(s.db, db.FilterIn("id", res.Hits))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var repoAts []string
+	for _, pull := range pulls {
+		repoAts = append(repoAts, pull.RepoAt.String())
+	}
+	repos, err := db.GetRepos(s.db, 0, db.FilterIn("at_uri", repoAts))
+	if err != nil {
+		return nil, 0, err
+	}
+	repoMap := make(map[syntax.ATURI]*models.Repo)
+	for i := range repos {
+		repoMap[repos[i].RepoAt()] = &repos[i]
+	}
+	for _, pull := range pulls {
+		pull.Repo = repoMap[pull.RepoAt]
+	}
+
+	// back into the order of relevance
+	slices.SortFunc(pulls, func(a, b *models.Pull) int {
+		return slices.Index(res.Hits, int64(a.ID)) - slices.Index(res.Hits, int64(b.ID))
+	})
+
+	return pulls, int(res.Total), nil
+}
+
+// users finds the dids behind handles like keyword. A keyword that is itself
+// a handle or did is resolved, so that exact matches are found even for
+// users the appview has never recorded a handle for.
+func (s *Search) users(ctx context.Context, keyword string, page pagination.Page) ([]string, int, error) {
+	keyword = strings.TrimPrefix(keyword, "@")
+
+	dids, total, err := db.SearchHandles(s.db, keyword, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := syntax.ParseAtIdentifier(keyword); err != nil {
+		return dids, total, nil
+	}
+
+	ident, err := s.idResolver.ResolveIdent(ctx, keyword)
+	if err != nil {
+		s.logger.Debug("failed to resolve search keyword", "keyword", keyword, "err", err)
+		return dids, total, nil
+	}
+
+	did := ident.DID.String()
+	if slices.Contains(dids, did) {
+		return dids, total, nil
+	}
+	if page.Offset == 0 {
+		dids = append([]string{did}, dids...)
+	}
+	return dids, total + 1, nil
+}
@@ -62,7 +62,8 @@ func RunVerification(ctx context.Context, domain, expectedOwner string, dev bool
 	return nil
 }
 
-// MarkSpindleVerified marks a spindle as verified in the DB and adds the user as its owner
+// MarkSpindleVerified marks a spindle as verified in the DB and, once it is
+// approved, adds the user as its owner
 func MarkSpindleVerified(d *db.DB, e *rbac.Enforcer, instance, owner string) (int64, error) {
 	tx, err := d.Begin()
 	if err != nil {
@@ -83,9 +84,21 @@ func MarkSpindleVerified(d *db.DB, e *rbac.Enforcer, instance, owner string) (in
 		return 0, fmt.Errorf("failed to write to DB: %w", err)
 	}
 
-	err = e.AddSpindleOwner(instance, owner)
+	spindles, err := db.GetSpindles(
+		tx,
+		db.FilterEq("owner", owner),
+		db.FilterEq("instance", instance),
+	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to update ACL: %w", err)
+		return 0, fmt.Errorf("failed to get spindle: %w", err)
+	}
+
+	// spindles awaiting an admin are verified, but nobody can use them yet
+	if len(spindles) == 1 && spindles[0].Approval.IsApproved() {
+		err = EnableSpindle(e, instance, owner)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	err = tx.Commit()
@@ -101,7 +114,8 @@ func MarkSpindleVerified(d *db.DB, e *rbac.Enforcer, instance, owner string) (in
 	return rowId, nil
 }
 
-// MarkKnotVerified marks a knot as verified and sets up ownership/permissions
+// MarkKnotVerified marks a knot as verified and, once it is approved, sets up
+// ownership/permissions
 func MarkKnotVerified(d *db.DB, e *rbac.Enforcer, domain, owner string) error {
 	tx, err := d.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -122,16 +136,21 @@ func MarkKnotVerified(d *db.DB, e *rbac.Enforcer, domain, owner string) error {
 		return fmt.Errorf("failed to register domain: %w", err)
 	}
 
-	// add basic acls for this domain
-	err = e.AddKnot(domain)
+	registrations, err := db.GetRegistrations(
+		tx,
+		db.FilterEq("did", owner),
+		db.FilterEq("domain", domain),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to add knot to enforcer: %w", err)
+		return fmt.Errorf("failed to get registration: %w", err)
 	}
 
-	// add this did as owner of this domain
-	err = e.AddKnotOwner(domain, owner)
-	if err != nil {
-		return fmt.Errorf("failed to add knot owner to enforcer: %w", err)
+	// knots awaiting an admin are verified, but nobody can use them yet
+	if len(registrations) == 1 && registrations[0].Approval.IsApproved() {
+		err = EnableKnot(e, domain, owner)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = tx.Commit()
@@ -146,3 +165,32 @@ func MarkKnotVerified(d *db.DB, e *rbac.Enforcer, domain, owner string) error {
 
 	return nil
 }
+
+// EnableKnot sets up the ownership/permissions of a verified, approved knot.
+// The caller saves the policy.
+func EnableKnot(e *rbac.Enforcer, domain, owner string) error {
+	// add basic acls for this domain
+	err := e.AddKnot(domain)
+	if err != nil {
+		return fmt.Errorf("failed to add knot to enforcer: %w", err)
+	}
+
+	// add this did as owner of this domain
+	err = e.AddKnotOwner(domain, owner)
+	if err != nil {
+		return fmt.Errorf("failed to add knot owner to enforcer: %w", err)
+	}
+
+	return nil
+}
+
+// EnableSpindle adds the owner of a verified, approved spindle to the ACL.
+// The caller saves the policy.
+func EnableSpindle(e *rbac.Enforcer, instance, owner string) error {
+	err := e.AddSpindleOwner(instance, owner)
+	if err != nil {
+		return fmt.Errorf("failed to update ACL: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package settings
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/deletions"
+	"tangled.org/core/appview/pages"
+	tlog "tangled.org/core/log"
+)
+
+// deletionPhrase is typed to confirm deleting an account, by those without
+// an authenticator app.
+const deletionPhrase = "delete my account"
+
+func (s *Settings) accountSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "accountSettings")
+	user := s.OAuth.GetUser(r)
+
+	deletion, err := db.GetPendingAccountDeletion(s.Db, user.Did)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("failed to get pending account deletion", "err", err)
+	}
+
+	s.Pages.UserAccountSettings(w, pages.UserAccountSettingsParams{
+		LoggedInUser:   user,
+		Deletion:       deletion,
+		DeletionPhrase: deletionPhrase,
+		TotpEnrolled:   s.OAuth.TotpEnrolled(user.Did),
+		Tabs:           settingsTabs,
+		Tab:            "account",
+	})
+}
+
+// scheduleDeletion asks for the account of the user to be deleted once the
+// grace period is over. Until then, they can cancel it.
+func (s *Settings) scheduleDeletion(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "scheduleDeletion")
+	user := s.OAuth.GetUser(r)
+	noticeId := "settings-account-error"
+
+	if err := s.OAuth.ConfirmAction(r, deletionPhrase); err != nil {
+		s.Pages.Notice(w, noticeId, err.Error())
+		return
+	}
+
+	_, err := s.Deleter.Schedule(user.Did)
+	if errors.Is(err, deletions.ErrAlreadyScheduled) {
+		s.Pages.Notice(w, noticeId, "Your account is already being deleted.")
+		return
+	}
+	if err != nil {
+		l.Error("failed to schedule account deletion", "err", err)
+		s.Pages.Notice(w, noticeId, "Unable to delete your account, try again later.")
+		return
+	}
+
+	s.Pages.HxRefresh(w)
+}
+
+func (s *Settings) cancelDeletion(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "cancelDeletion")
+	user := s.OAuth.GetUser(r)
+	noticeId := "settings-account-error"
+
+	err := s.Deleter.Cancel(user.Did)
+	if errors.Is(err, deletions.ErrAlreadyScheduled) {
+		s.Pages.Notice(w, noticeId, "Your account is being deleted, it is too late to cancel.")
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("failed to cancel account deletion", "err", err)
+		s.Pages.Notice(w, noticeId, "Unable to cancel, try again later.")
+		return
+	}
+
+	s.Pages.HxRefresh(w)
+}
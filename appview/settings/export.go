@@ -0,0 +1,88 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/exports"
+	"tangled.org/core/appview/pages"
+	tlog "tangled.org/core/log"
+)
+
+func (s *Settings) exportSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "exportSettings")
+	user := s.OAuth.GetUser(r)
+
+	list, err := db.GetExports(s.Db, db.FilterEq("did", user.Did))
+	if err != nil {
+		l.Error("failed to get exports", "err", err)
+	}
+
+	s.Pages.UserExportSettings(w, pages.UserExportSettingsParams{
+		LoggedInUser: user,
+		Exports:      list,
+		Tabs:         settingsTabs,
+		Tab:          "export",
+	})
+}
+
+// requestExport asks for an archive of everything the user has here. It is
+// built in the background, and they are mailed once it is ready.
+func (s *Settings) requestExport(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "requestExport")
+	user := s.OAuth.GetUser(r)
+	noticeId := "settings-export-error"
+
+	_, err := s.Exporter.Request(user.Did, r.FormValue("include_repos") == "on")
+	if errors.Is(err, exports.ErrInProgress) {
+		s.Pages.Notice(w, noticeId, "Your last export is still being built.")
+		return
+	}
+	if err != nil {
+		l.Error("failed to request export", "err", err)
+		s.Pages.Notice(w, noticeId, "Unable to start the export, try again later.")
+		return
+	}
+
+	s.Pages.HxRefresh(w)
+}
+
+func (s *Settings) downloadExport(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "downloadExport")
+	user := s.OAuth.GetUser(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.Pages.Error404(w)
+		return
+	}
+
+	// only ever the user's own
+	list, err := db.GetExports(s.Db, db.FilterEq("id", id), db.FilterEq("did", user.Did))
+	if err != nil || len(list) == 0 || !list[0].IsDownloadable() {
+		s.Pages.Error404(w)
+		return
+	}
+	export := list[0]
+
+	f, err := os.Open(s.Exporter.Path(&export))
+	if err != nil {
+		l.Error("failed to open export", "id", export.Id, "err", err)
+		s.Pages.Error404(w)
+		return
+	}
+	defer f.Close()
+
+	filename := fmt.Sprintf("tangled-export-%s.zip", export.Created.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	http.ServeContent(w, r, "", *export.Finished, f)
+}
@@ -1,25 +1,37 @@
 package settings
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"log"
+	"html/template"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/go-chi/chi/v5"
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/deletions"
 	"tangled.org/core/appview/email"
+	"tangled.org/core/appview/exports"
 	"tangled.org/core/appview/middleware"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/totp"
+	"tangled.org/core/appview/validator"
+	tlog "tangled.org/core/log"
 	"tangled.org/core/tid"
+	"tangled.org/core/xrpc/serviceauth"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -29,10 +41,13 @@ import (
 )
 
 type Settings struct {
-	Db     *db.DB
-	OAuth  *oauth.OAuth
-	Pages  *pages.Pages
-	Config *config.Config
+	Db        *db.DB
+	OAuth     *oauth.OAuth
+	Pages     *pages.Pages
+	Config    *config.Config
+	Validator *validator.Validator
+	Exporter  *exports.Exporter
+	Deleter   *deletions.Deleter
 }
 
 type tab = map[string]any
@@ -41,8 +56,13 @@ var (
 	settingsTabs []tab = []tab{
 		{"Name": "profile", "Icon": "user"},
 		{"Name": "keys", "Icon": "key"},
+		{"Name": "app-passwords", "Icon": "lock"},
+		{"Name": "security", "Icon": "shield"},
 		{"Name": "emails", "Icon": "mail"},
 		{"Name": "notifications", "Icon": "bell"},
+		{"Name": "watching", "Icon": "eye"},
+		{"Name": "export", "Icon": "download"},
+		{"Name": "account", "Icon": "user-x"},
 	}
 )
 
@@ -54,6 +74,7 @@ func (s *Settings) Router() http.Handler {
 	// settings pages
 	r.Get("/", s.profileSettings)
 	r.Get("/profile", s.profileSettings)
+	r.Put("/default-branch", s.updateDefaultBranch)
 
 	r.Route("/keys", func(r chi.Router) {
 		r.Get("/", s.keysSettings)
@@ -61,6 +82,19 @@ func (s *Settings) Router() http.Handler {
 		r.Delete("/", s.keys)
 	})
 
+	r.Route("/app-passwords", func(r chi.Router) {
+		r.Get("/", s.appPasswordsSettings)
+		r.Put("/", s.appPasswords)
+		r.Delete("/", s.appPasswords)
+	})
+
+	r.Route("/security", func(r chi.Router) {
+		r.Get("/", s.securitySettings)
+		r.Post("/totp", s.enrollTotp)
+		r.Put("/totp", s.confirmTotp)
+		r.Delete("/totp", s.removeTotp)
+	})
+
 	r.Route("/emails", func(r chi.Router) {
 		r.Get("/", s.emailsSettings)
 		r.Put("/", s.emails)
@@ -75,26 +109,72 @@ func (s *Settings) Router() http.Handler {
 		r.Put("/", s.updateNotificationPreferences)
 	})
 
+	r.Get("/watching", s.watchingSettings)
+
+	r.Route("/export", func(r chi.Router) {
+		r.Get("/", s.exportSettings)
+		r.Post("/", s.requestExport)
+		r.Get("/{id}", s.downloadExport)
+	})
+
+	r.Route("/account", func(r chi.Router) {
+		r.Get("/", s.accountSettings)
+		r.Post("/delete", s.scheduleDeletion)
+		r.Delete("/delete", s.cancelDeletion)
+	})
+
 	return r
 }
 
 func (s *Settings) profileSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "profileSettings")
 	user := s.OAuth.GetUser(r)
 
+	defaultBranch, err := db.GetDefaultBranch(s.Db, user.Did)
+	if err != nil {
+		l.Error("failed to get default branch", "err", err)
+	}
+
 	s.Pages.UserProfileSettings(w, pages.UserProfileSettingsParams{
-		LoggedInUser: user,
-		Tabs:         settingsTabs,
-		Tab:          "profile",
+		LoggedInUser:          user,
+		Tabs:                  settingsTabs,
+		Tab:                   "profile",
+		DefaultBranch:         defaultBranch,
+		InstanceDefaultBranch: s.Config.Core.DefaultBranch,
 	})
 }
 
+// updateDefaultBranch sets the branch the user's new repos start with. An
+// empty one goes back to the instance default.
+func (s *Settings) updateDefaultBranch(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "updateDefaultBranch")
+	did := s.OAuth.GetDid(r)
+
+	branch := strings.TrimSpace(r.FormValue("branch"))
+	if branch != "" {
+		if err := s.Validator.ValidateBranchName(branch); err != nil {
+			s.Pages.Notice(w, "settings-default-branch-error", err.Error())
+			return
+		}
+	}
+
+	if err := db.SetDefaultBranch(s.Db, did, branch); err != nil {
+		l.Error("failed to set default branch", "err", err)
+		s.Pages.Notice(w, "settings-default-branch-error", "Unable to save the default branch.")
+		return
+	}
+
+	s.Pages.Notice(w, "settings-default-branch-success", "Default branch saved successfully.")
+}
+
 func (s *Settings) notificationsSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "notificationsSettings")
 	user := s.OAuth.GetUser(r)
 	did := s.OAuth.GetDid(r)
 
 	prefs, err := db.GetNotificationPreference(s.Db, did)
 	if err != nil {
-		log.Printf("failed to get notification preferences: %s", err)
+		l.Error("failed to get notification preferences", "err", err)
 		s.Pages.Notice(w, "settings-notifications-error", "Unable to load notification preferences.")
 		return
 	}
@@ -108,6 +188,7 @@ func (s *Settings) notificationsSettings(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Settings) updateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "updateNotificationPreferences")
 	did := s.OAuth.GetDid(r)
 
 	prefs := &models.NotificationPreferences{
@@ -126,7 +207,7 @@ func (s *Settings) updateNotificationPreferences(w http.ResponseWriter, r *http.
 
 	err := s.Db.UpdateNotificationPreferences(r.Context(), prefs)
 	if err != nil {
-		log.Printf("failed to update notification preferences: %s", err)
+		l.Error("failed to update notification preferences", "err", err)
 		s.Pages.Notice(w, "settings-notifications-error", "Unable to save notification preferences.")
 		return
 	}
@@ -135,10 +216,11 @@ func (s *Settings) updateNotificationPreferences(w http.ResponseWriter, r *http.
 }
 
 func (s *Settings) keysSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "keysSettings")
 	user := s.OAuth.GetUser(r)
 	pubKeys, err := db.GetPublicKeysForDid(s.Db, user.Did)
 	if err != nil {
-		log.Println(err)
+		l.Error("failed to get public keys", "err", err)
 	}
 
 	s.Pages.UserKeysSettings(w, pages.UserKeysSettingsParams{
@@ -149,11 +231,140 @@ func (s *Settings) keysSettings(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Settings) appPasswordsSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "appPasswordsSettings")
+	user := s.OAuth.GetUser(r)
+	passwords, err := db.GetAppPasswords(s.Db, db.FilterEq("did", user.Did))
+	if err != nil {
+		l.Error("failed to get app passwords", "err", err)
+	}
+
+	s.Pages.UserAppPasswordsSettings(w, pages.UserAppPasswordsSettingsParams{
+		LoggedInUser: user,
+		AppPasswords: passwords,
+		Scopes:       serviceauth.Scopes,
+		Tabs:         settingsTabs,
+		Tab:          "app-passwords",
+	})
+}
+
+func (s *Settings) watchingSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "watchingSettings")
+	user := s.OAuth.GetUser(r)
+	watches, err := db.GetRepoWatches(s.Db, db.FilterEq("did", user.Did))
+	if err != nil {
+		l.Error("failed to get watched repos", "err", err)
+	}
+
+	s.Pages.UserWatchingSettings(w, pages.UserWatchingSettingsParams{
+		LoggedInUser: user,
+		Watches:      watches,
+		Tabs:         settingsTabs,
+		Tab:          "watching",
+	})
+}
+
+func (s *Settings) securitySettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "securitySettings")
+	user := s.OAuth.GetUser(r)
+
+	secret, err := s.OAuth.TotpSecret(user.Did)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("failed to get totp secret", "err", err)
+	}
+
+	params := pages.UserSecuritySettingsParams{
+		LoggedInUser: user,
+		Totp:         secret,
+		Tabs:         settingsTabs,
+		Tab:          "security",
+	}
+	if secret != nil && !secret.Confirmed {
+		params.TotpUri = totp.URI(secret.Secret, "Tangled", user.Did)
+	}
+
+	s.Pages.UserSecuritySettings(w, params)
+}
+
+// enrollTotp gives the user a new secret for their authenticator app. It is
+// not asked for until they confirm it with a code.
+func (s *Settings) enrollTotp(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "enrollTotp")
+	did := s.OAuth.GetDid(r)
+
+	// replacing an app that is in use needs a code from it
+	if s.OAuth.TotpEnrolled(did) {
+		s.Pages.Notice(w, "settings-security", "Remove your authenticator app first.")
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		l.Error("generating totp secret", "err", err)
+		s.Pages.Notice(w, "settings-security", "Unable to set up an authenticator app at this moment, try again later.")
+		return
+	}
+
+	if err := s.OAuth.SetTotpSecret(did, secret); err != nil {
+		l.Error("adding totp secret", "err", err)
+		s.Pages.Notice(w, "settings-security", "Unable to set up an authenticator app at this moment, try again later.")
+		return
+	}
+
+	s.Pages.HxLocation(w, "/settings/security")
+}
+
+func (s *Settings) confirmTotp(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "confirmTotp")
+	did := s.OAuth.GetDid(r)
+
+	secret, err := s.OAuth.TotpSecret(did)
+	if err != nil {
+		s.Pages.Notice(w, "settings-security", "Set up an authenticator app first.")
+		return
+	}
+
+	if err := s.OAuth.CheckTotp(secret, r.FormValue("totp")); err != nil {
+		s.Pages.Notice(w, "settings-security", err.Error())
+		return
+	}
+
+	if err := db.ConfirmTotpSecret(s.Db, did); err != nil {
+		l.Error("confirming totp secret", "err", err)
+		s.Pages.Notice(w, "settings-security", "Unable to confirm your authenticator app at this moment, try again later.")
+		return
+	}
+
+	s.Pages.HxLocation(w, "/settings/security")
+}
+
+func (s *Settings) removeTotp(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "removeTotp")
+	did := s.OAuth.GetDid(r)
+
+	// a confirmed app has to be used one last time to remove it
+	if s.OAuth.TotpEnrolled(did) {
+		if err := s.OAuth.ConfirmAction(r, ""); err != nil {
+			s.Pages.Notice(w, "settings-security", err.Error())
+			return
+		}
+	}
+
+	if err := db.DeleteTotpSecret(s.Db, did); err != nil {
+		l.Error("removing totp secret", "err", err)
+		s.Pages.Notice(w, "settings-security", "Unable to remove your authenticator app at this moment, try again later.")
+		return
+	}
+
+	s.Pages.HxLocation(w, "/settings/security")
+}
+
 func (s *Settings) emailsSettings(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "emailsSettings")
 	user := s.OAuth.GetUser(r)
 	emails, err := db.GetAllEmails(s.Db, user.Did)
 	if err != nil {
-		log.Println(err)
+		l.Error("failed to get emails", "err", err)
 	}
 
 	s.Pages.UserEmailsSettings(w, pages.UserEmailsSettingsParams{
@@ -181,12 +392,12 @@ func (s *Settings) buildVerificationEmail(emailAddr, did, code string) email.Ema
 }
 
 // sendVerificationEmail handles the common logic for sending verification emails
-func (s *Settings) sendVerificationEmail(w http.ResponseWriter, did, emailAddr, code string, errorContext string) error {
+func (s *Settings) sendVerificationEmail(l *slog.Logger, w http.ResponseWriter, did, emailAddr, code string, errorContext string) error {
 	emailToSend := s.buildVerificationEmail(emailAddr, did, code)
 
 	err := email.SendEmail(emailToSend)
 	if err != nil {
-		log.Printf("sending email: %s", err)
+		l.Error("sending email", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", fmt.Sprintf("Unable to send verification email at this moment, try again later. %s", errorContext))
 		return err
 	}
@@ -195,10 +406,11 @@ func (s *Settings) sendVerificationEmail(w http.ResponseWriter, did, emailAddr,
 }
 
 func (s *Settings) emails(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "emails")
 	switch r.Method {
 	case http.MethodGet:
 		s.Pages.Notice(w, "settings-emails", "Unimplemented.")
-		log.Println("unimplemented")
+		l.Info("unimplemented")
 		return
 	case http.MethodPut:
 		did := s.OAuth.GetDid(r)
@@ -213,7 +425,7 @@ func (s *Settings) emails(w http.ResponseWriter, r *http.Request) {
 		// check if email already exists in database
 		existingEmail, err := db.GetEmail(s.Db, did, emAddr)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			log.Printf("checking for existing email: %s", err)
+			l.Error("checking for existing email", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to add email at this moment, try again later.")
 			return
 		}
@@ -228,12 +440,22 @@ func (s *Settings) emails(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// unverified claims by others don't count, verified ones do
+		if owner, err := db.GetVerifiedDidForEmail(s.Db, emAddr); err == nil && owner != did {
+			s.Pages.Notice(w, "settings-emails-error", "This email is already verified by another account.")
+			return
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			l.Error("checking for verified email", "err", err)
+			s.Pages.Notice(w, "settings-emails-error", "Unable to add email at this moment, try again later.")
+			return
+		}
+
 		code := uuid.New().String()
 
 		// Begin transaction
 		tx, err := s.Db.Begin()
 		if err != nil {
-			log.Printf("failed to start transaction: %s", err)
+			l.Error("failed to start transaction", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to add email at this moment, try again later.")
 			return
 		}
@@ -245,18 +467,18 @@ func (s *Settings) emails(w http.ResponseWriter, r *http.Request) {
 			Verified:         false,
 			VerificationCode: code,
 		}); err != nil {
-			log.Printf("adding email: %s", err)
+			l.Error("adding email", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to add email at this moment, try again later.")
 			return
 		}
 
-		if err := s.sendVerificationEmail(w, did, emAddr, code, ""); err != nil {
+		if err := s.sendVerificationEmail(l, w, did, emAddr, code, ""); err != nil {
 			return
 		}
 
 		// Commit transaction
 		if err := tx.Commit(); err != nil {
-			log.Printf("failed to commit transaction: %s", err)
+			l.Error("failed to commit transaction", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to add email at this moment, try again later.")
 			return
 		}
@@ -271,21 +493,21 @@ func (s *Settings) emails(w http.ResponseWriter, r *http.Request) {
 		// Begin transaction
 		tx, err := s.Db.Begin()
 		if err != nil {
-			log.Printf("failed to start transaction: %s", err)
+			l.Error("failed to start transaction", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to delete email at this moment, try again later.")
 			return
 		}
 		defer tx.Rollback()
 
 		if err := db.DeleteEmail(tx, did, emailAddr); err != nil {
-			log.Printf("deleting email: %s", err)
+			l.Error("deleting email", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to delete email at this moment, try again later.")
 			return
 		}
 
 		// Commit transaction
 		if err := tx.Commit(); err != nil {
-			log.Printf("failed to commit transaction: %s", err)
+			l.Error("failed to commit transaction", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to delete email at this moment, try again later.")
 			return
 		}
@@ -307,6 +529,7 @@ func (s *Settings) verifyUrl(did string, email string, code string) string {
 }
 
 func (s *Settings) emailsVerify(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "emailsVerify")
 	q := r.URL.Query()
 
 	// Get the parameters directly from the query
@@ -316,7 +539,7 @@ func (s *Settings) emailsVerify(w http.ResponseWriter, r *http.Request) {
 
 	valid, err := db.CheckValidVerificationCode(s.Db, did, emailAddr, code)
 	if err != nil {
-		log.Printf("checking email verification: %s", err)
+		l.Error("checking email verification", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", "Error verifying email. Please try again later.")
 		return
 	}
@@ -326,9 +549,15 @@ func (s *Settings) emailsVerify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// someone else may have verified the address since it was added here
+	if owner, err := db.GetVerifiedDidForEmail(s.Db, emailAddr); err == nil && owner != did {
+		s.Pages.Notice(w, "settings-emails-error", "This email is already verified by another account.")
+		return
+	}
+
 	// Mark email as verified in the database
 	if err := db.MarkEmailVerified(s.Db, did, emailAddr); err != nil {
-		log.Printf("marking email as verified: %s", err)
+		l.Error("marking email as verified", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", "Error updating email verification status. Please try again later.")
 		return
 	}
@@ -337,6 +566,7 @@ func (s *Settings) emailsVerify(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Settings) emailsVerifyResend(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "emailsVerifyResend")
 	if r.Method != http.MethodPost {
 		s.Pages.Notice(w, "settings-emails-error", "Invalid request method.")
 		return
@@ -357,7 +587,7 @@ func (s *Settings) emailsVerifyResend(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.Pages.Notice(w, "settings-emails-error", "Email not found. Please add it first.")
 		} else {
-			log.Printf("checking for existing email: %s", err)
+			l.Error("checking for existing email", "err", err)
 			s.Pages.Notice(w, "settings-emails-error", "Unable to resend verification email at this moment, try again later.")
 		}
 		return
@@ -384,7 +614,7 @@ func (s *Settings) emailsVerifyResend(w http.ResponseWriter, r *http.Request) {
 	// Begin transaction
 	tx, err := s.Db.Begin()
 	if err != nil {
-		log.Printf("failed to start transaction: %s", err)
+		l.Error("failed to start transaction", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", "Unable to resend verification email at this moment, try again later.")
 		return
 	}
@@ -392,19 +622,19 @@ func (s *Settings) emailsVerifyResend(w http.ResponseWriter, r *http.Request) {
 
 	// Update the verification code and last sent time
 	if err := db.UpdateVerificationCode(tx, did, emAddr, code); err != nil {
-		log.Printf("updating email verification: %s", err)
+		l.Error("updating email verification", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", "Unable to resend verification email at this moment, try again later.")
 		return
 	}
 
 	// Send verification email
-	if err := s.sendVerificationEmail(w, did, emAddr, code, ""); err != nil {
+	if err := s.sendVerificationEmail(l, w, did, emAddr, code, ""); err != nil {
 		return
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		log.Printf("failed to commit transaction: %s", err)
+		l.Error("failed to commit transaction", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", "Unable to resend verification email at this moment, try again later.")
 		return
 	}
@@ -413,6 +643,7 @@ func (s *Settings) emailsVerifyResend(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Settings) emailsPrimary(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "emailsPrimary")
 	did := s.OAuth.GetDid(r)
 	emailAddr := r.FormValue("email")
 	emailAddr = strings.TrimSpace(emailAddr)
@@ -423,7 +654,7 @@ func (s *Settings) emailsPrimary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := db.MakeEmailPrimary(s.Db, did, emailAddr); err != nil {
-		log.Printf("setting primary email: %s", err)
+		l.Error("setting primary email", "err", err)
 		s.Pages.Notice(w, "settings-emails-error", "Error setting primary email. Please try again later.")
 		return
 	}
@@ -432,10 +663,11 @@ func (s *Settings) emailsPrimary(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Settings) keys(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "keys")
 	switch r.Method {
 	case http.MethodGet:
 		s.Pages.Notice(w, "settings-keys", "Unimplemented.")
-		log.Println("unimplemented")
+		l.Info("unimplemented")
 		return
 	case http.MethodPut:
 		did := s.OAuth.GetDid(r)
@@ -450,7 +682,7 @@ func (s *Settings) keys(w http.ResponseWriter, r *http.Request) {
 
 		_, _, _, _, err = ssh.ParseAuthorizedKey([]byte(key))
 		if err != nil {
-			log.Printf("parsing public key: %s", err)
+			l.Error("parsing public key", "err", err)
 			s.Pages.Notice(w, "settings-keys", "That doesn't look like a valid public key. Make sure it's a <strong>public</strong> key.")
 			return
 		}
@@ -459,14 +691,14 @@ func (s *Settings) keys(w http.ResponseWriter, r *http.Request) {
 
 		tx, err := s.Db.Begin()
 		if err != nil {
-			log.Printf("failed to start tx; adding public key: %s", err)
+			l.Error("failed to start tx; adding public key", "err", err)
 			s.Pages.Notice(w, "settings-keys", "Unable to add public key at this moment, try again later.")
 			return
 		}
 		defer tx.Rollback()
 
 		if err := db.AddPublicKey(tx, did, name, key, rkey); err != nil {
-			log.Printf("adding public key: %s", err)
+			l.Error("adding public key", "err", err)
 			s.Pages.Notice(w, "settings-keys", "Failed to add public key.")
 			return
 		}
@@ -485,16 +717,16 @@ func (s *Settings) keys(w http.ResponseWriter, r *http.Request) {
 		})
 		// invalid record
 		if err != nil {
-			log.Printf("failed to create record: %s", err)
+			l.Error("failed to create record", "err", err)
 			s.Pages.Notice(w, "settings-keys", "Failed to create record.")
 			return
 		}
 
-		log.Println("created atproto record: ", resp.Uri)
+		l.Info("created atproto record", "uri", resp.Uri)
 
 		err = tx.Commit()
 		if err != nil {
-			log.Printf("failed to commit tx; adding public key: %s", err)
+			l.Error("failed to commit tx; adding public key", "err", err)
 			s.Pages.Notice(w, "settings-keys", "Unable to add public key at this moment, try again later.")
 			return
 		}
@@ -510,19 +742,17 @@ func (s *Settings) keys(w http.ResponseWriter, r *http.Request) {
 		rkey := q.Get("rkey")
 		key := q.Get("key")
 
-		log.Println(name)
-		log.Println(rkey)
-		log.Println(key)
+		l.Info("deleting key", "name", name, "rkey", rkey, "key", key)
 
 		client, err := s.OAuth.AuthorizedClient(r)
 		if err != nil {
-			log.Printf("failed to authorize client: %s", err)
+			l.Error("failed to authorize client", "err", err)
 			s.Pages.Notice(w, "settings-keys", "Failed to authorize client.")
 			return
 		}
 
-		if err := db.DeletePublicKey(s.Db, did, name, key); err != nil {
-			log.Printf("removing public key: %s", err)
+		if err := db.RevokePublicKey(s.Db, did, name, key); err != nil {
+			l.Error("removing public key", "err", err)
 			s.Pages.Notice(w, "settings-keys", "Failed to remove public key.")
 			return
 		}
@@ -537,14 +767,116 @@ func (s *Settings) keys(w http.ResponseWriter, r *http.Request) {
 
 			// invalid record
 			if err != nil {
-				log.Printf("failed to delete record from PDS: %s", err)
+				l.Error("failed to delete record from PDS", "err", err)
 				s.Pages.Notice(w, "settings-keys", "Failed to remove key from PDS.")
 				return
 			}
 		}
-		log.Println("deleted successfully")
+		l.Info("deleted successfully")
 
 		s.Pages.HxLocation(w, "/settings/keys")
 		return
 	}
 }
+
+func (s *Settings) appPasswords(w http.ResponseWriter, r *http.Request) {
+	l := tlog.FromContext(r.Context()).With("handler", "appPasswords")
+	did := s.OAuth.GetDid(r)
+
+	switch r.Method {
+	case http.MethodPut:
+		name := strings.TrimSpace(r.FormValue("name"))
+		if name == "" {
+			s.Pages.Notice(w, "settings-app-passwords", "App passwords need a name.")
+			return
+		}
+
+		scopes := r.Form["scope"]
+		if len(scopes) == 0 {
+			s.Pages.Notice(w, "settings-app-passwords", "Pick at least one scope.")
+			return
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(serviceauth.Scopes, scope) {
+				s.Pages.Notice(w, "settings-app-passwords", fmt.Sprintf("Unknown scope %q.", scope))
+				return
+			}
+		}
+
+		knots, err := parseKnots(r.FormValue("knots"))
+		if err != nil {
+			s.Pages.Notice(w, "settings-app-passwords", err.Error())
+			return
+		}
+		if len(knots) == 0 && slices.ContainsFunc(scopes, isKnotScope) {
+			s.Pages.Notice(w, "settings-app-passwords", "Name the knots this password is for.")
+			return
+		}
+
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			l.Error("generating app password", "err", err)
+			s.Pages.Notice(w, "settings-app-passwords", "Unable to create app password at this moment, try again later.")
+			return
+		}
+		password := serviceauth.AppPasswordPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+		err = db.AddAppPassword(s.Db, &models.AppPassword{
+			Did:          did,
+			Name:         name,
+			PasswordHash: models.HashAppPassword(password),
+			Scopes:       scopes,
+			Knots:        knots,
+		})
+		if err != nil {
+			l.Error("adding app password", "err", err)
+			s.Pages.Notice(w, "settings-app-passwords", "Failed to create app password, is the name already taken?")
+			return
+		}
+
+		// this is the only time the password is ever shown
+		s.Pages.Notice(w, "settings-app-passwords-created", fmt.Sprintf(
+			"Created app password <strong>%s</strong>. Copy it now, it will not be shown again: <code class=\"select-all break-all\">%s</code>",
+			template.HTMLEscapeString(name), password,
+		))
+		return
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			s.Pages.Notice(w, "settings-app-passwords-created", "Invalid app password.")
+			return
+		}
+
+		if err := db.DeleteAppPassword(s.Db, db.FilterEq("did", did), db.FilterEq("id", id)); err != nil {
+			l.Error("revoking app password", "err", err)
+			s.Pages.Notice(w, "settings-app-passwords-created", "Failed to revoke app password.")
+			return
+		}
+
+		s.Pages.HxLocation(w, "/settings/app-passwords")
+		return
+	}
+}
+
+// isKnotScope reports whether scope is used on knots, rather than on the
+// appview.
+func isKnotScope(scope string) bool {
+	return scope != serviceauth.ScopeRepoStatus
+}
+
+// parseKnots reads the hostnames of knots, separated by spaces or commas.
+func parseKnots(s string) ([]string, error) {
+	var knots []string
+	for _, knot := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		knot = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(knot, "https://"), "http://"))
+		knot = strings.TrimSuffix(knot, "/")
+		if u, err := url.Parse("//" + knot); err != nil || u.Host != knot || knot == "" {
+			return nil, fmt.Errorf("%q is not the hostname of a knot.", knot)
+		}
+		if !slices.Contains(knots, knot) {
+			knots = append(knots, knot)
+		}
+	}
+	return knots, nil
+}
@@ -20,6 +20,7 @@ import (
 	"tangled.org/core/appview/email"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pow"
 	"tangled.org/core/appview/state/userutil"
 	"tangled.org/core/idresolver"
 )
@@ -33,9 +34,11 @@ type Signup struct {
 	pages               *pages.Pages
 	l                   *slog.Logger
 	disallowedNicknames map[string]bool
+	// set when the anti-abuse challenge is a proof-of-work
+	pow *pow.Pow
 }
 
-func New(cfg *config.Config, database *db.DB, pc posthog.Client, idResolver *idresolver.Resolver, pages *pages.Pages, l *slog.Logger) *Signup {
+func New(cfg *config.Config, database *db.DB, pc posthog.Client, idResolver *idresolver.Resolver, pages *pages.Pages, pow *pow.Pow, l *slog.Logger) *Signup {
 	var cf *dns.Cloudflare
 	if cfg.Cloudflare.ApiToken != "" && cfg.Cloudflare.ZoneId != "" {
 		var err error
@@ -56,6 +59,7 @@ func New(cfg *config.Config, database *db.DB, pc posthog.Client, idResolver *idr
 		pages:               pages,
 		l:                   l,
 		disallowedNicknames: disallowedNicknames,
+		pow:                 pow,
 	}
 }
 
@@ -118,9 +122,21 @@ func (s *Signup) Router() http.Handler {
 func (s *Signup) signup(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		s.pages.Signup(w, pages.SignupParams{
-			CloudflareSiteKey: s.config.Cloudflare.TurnstileSiteKey,
-		})
+		params := pages.SignupParams{}
+		switch s.config.AntiAbuse.Challenge {
+		case config.ChallengeTurnstile:
+			params.CloudflareSiteKey = s.config.Cloudflare.TurnstileSiteKey
+		case config.ChallengePow:
+			challenge, err := s.pow.Challenge()
+			if err != nil {
+				s.l.Error("failed to issue challenge", "error", err)
+				http.Error(w, "failed to issue challenge", http.StatusInternalServerError)
+				return
+			}
+			params.Pow = challenge
+		}
+
+		s.pages.Signup(w, params)
 	case http.MethodPost:
 		if s.cf == nil {
 			http.Error(w, "signup is disabled", http.StatusFailedDependency)
@@ -131,10 +147,19 @@ func (s *Signup) signup(w http.ResponseWriter, r *http.Request) {
 
 		noticeId := "signup-msg"
 
-		if err := s.validateCaptcha(cfToken, r); err != nil {
-			s.l.Warn("turnstile validation failed", "error", err, "email", emailId)
-			s.pages.Notice(w, noticeId, "Captcha validation failed.")
-			return
+		switch s.config.AntiAbuse.Challenge {
+		case config.ChallengeTurnstile:
+			if err := s.validateCaptcha(cfToken, r); err != nil {
+				s.l.Warn("turnstile validation failed", "error", err, "email", emailId)
+				s.pages.Notice(w, noticeId, "Captcha validation failed.")
+				return
+			}
+		case config.ChallengePow:
+			if err := s.pow.Verify(r.FormValue("pow-challenge"), r.FormValue("pow-nonce")); err != nil {
+				s.l.Warn("proof-of-work validation failed", "error", err, "email", emailId)
+				s.pages.Notice(w, noticeId, "Verification failed, reload the page and try again.")
+				return
+			}
 		}
 
 		if !email.IsValidEmail(emailId) {
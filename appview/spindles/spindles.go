@@ -171,9 +171,15 @@ func (s *Spindles) register(w http.ResponseWriter, r *http.Request) {
 		s.Enforcer.E.LoadPolicy()
 	}()
 
+	approval := models.ApprovalApproved
+	if s.Config.Admin.ReviewsRegistrations() {
+		approval = models.ApprovalPending
+	}
+
 	err = db.AddSpindle(tx, models.Spindle{
 		Owner:    syntax.DID(user.Did),
 		Instance: instance,
+		Approval: approval,
 	})
 	if err != nil {
 		l.Error("failed to insert", "err", err)
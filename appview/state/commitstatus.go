@@ -0,0 +1,142 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	xrpcerr "tangled.org/core/xrpc/errors"
+	"tangled.org/core/xrpc/serviceauth"
+)
+
+// SetCommitStatus lets CI systems other than spindle report on commits. It
+// takes an app password with the repo:status scope, whose owner must be
+// allowed to push to the repo.
+func (s *State) SetCommitStatus(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "SetCommitStatus")
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !serviceauth.IsAppPassword(token) {
+		writeXrpcError(w, xrpcerr.AuthError(errors.New("an app password is required")), http.StatusUnauthorized)
+		return
+	}
+
+	password, err := db.GetAppPassword(s.db, db.FilterEq("password_hash", models.HashAppPassword(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeXrpcError(w, xrpcerr.AuthError(errors.New("invalid app password")), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		l.Error("failed to get app password", "err", err)
+		writeXrpcError(w, xrpcerr.GenericError(errors.New("failed to verify app password")), http.StatusInternalServerError)
+		return
+	}
+	if !password.HasScope(serviceauth.ScopeRepoStatus) {
+		writeXrpcError(w, xrpcerr.AuthError(fmt.Errorf("app password lacks the %s scope", serviceauth.ScopeRepoStatus)), http.StatusForbidden)
+		return
+	}
+	if err := db.TouchAppPassword(s.db, password.Id); err != nil {
+		l.Error("failed to update app password last use", "err", err)
+	}
+
+	var input tangled.RepoCommitStatus_Input
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&input); err != nil {
+		writeXrpcError(w, xrpcerr.GenericError(fmt.Errorf("invalid request: %w", err)), http.StatusBadRequest)
+		return
+	}
+
+	status, err := commitStatusFromInput(input)
+	if err != nil {
+		writeXrpcError(w, xrpcerr.NewXrpcError(xrpcerr.WithTag("InvalidRequest"), xrpcerr.WithError(err)), http.StatusBadRequest)
+		return
+	}
+	status.Did = password.Did
+
+	repo, err := db.GetRepoByAtUri(s.db, input.Repo)
+	if err != nil {
+		writeXrpcError(w, xrpcerr.RepoNotFoundError, http.StatusNotFound)
+		return
+	}
+
+	ok, err := s.enforcer.IsPushAllowed(password.Did, repo.Knot, repo.DidSlashRepo())
+	if err != nil || !ok {
+		writeXrpcError(w, xrpcerr.AccessControlError(password.Did), http.StatusForbidden)
+		return
+	}
+
+	if err := db.SetCommitStatus(s.db, status); err != nil {
+		l.Error("failed to set commit status", "err", err)
+		writeXrpcError(w, xrpcerr.GenericError(errors.New("failed to set commit status")), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func commitStatusFromInput(input tangled.RepoCommitStatus_Input) (models.CommitStatus, error) {
+	status := models.CommitStatus{
+		Sha:     strings.ToLower(input.Sha),
+		Context: strings.TrimSpace(input.Context),
+		State:   models.CommitStatusState(input.State),
+	}
+
+	repoAt, err := syntax.ParseATURI(input.Repo)
+	if err != nil {
+		return status, fmt.Errorf("repo must be an at-uri")
+	}
+	status.RepoAt = repoAt
+
+	if !isFullSha(status.Sha) {
+		return status, fmt.Errorf("sha must be a full commit hash")
+	}
+	if status.Context == "" || utf8.RuneCountInString(status.Context) > 255 {
+		return status, fmt.Errorf("context must be between 1 and 255 characters")
+	}
+	if !status.State.IsValid() {
+		return status, fmt.Errorf("unknown state %q", input.State)
+	}
+
+	if input.TargetUrl != nil && *input.TargetUrl != "" {
+		u, err := url.Parse(*input.TargetUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return status, fmt.Errorf("targetUrl must be an http or https url")
+		}
+		status.TargetUrl = u.String()
+	}
+
+	if input.Description != nil {
+		status.Description = strings.TrimSpace(*input.Description)
+		if utf8.RuneCountInString(status.Description) > 1000 {
+			return status, fmt.Errorf("description must be at most 1000 characters")
+		}
+	}
+
+	return status, nil
+}
+
+func isFullSha(sha string) bool {
+	if len(sha) != 40 {
+		return false
+	}
+	for _, c := range sha {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeXrpcError(w http.ResponseWriter, e xrpcerr.XrpcError, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,41 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+
+	"tangled.org/core/appview/db"
+	ec "tangled.org/core/eventconsumer"
+)
+
+// replayDeadLetters gives the messages that a stream's consumer gave up on
+// another go, those that succeed are forgotten and the rest are kept.
+func replayDeadLetters(ctx context.Context, logger *slog.Logger, d *db.DB, consumer *ec.Consumer, stream string) {
+	deadLetters, err := db.GetDeadLetters(d, db.FilterEq("stream", stream))
+	if err != nil {
+		logger.Error("failed to get dead letters", "stream", stream, "err", err)
+		return
+	}
+
+	replayed := 0
+	for _, dl := range deadLetters {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := consumer.Replay(ctx, dl); err != nil {
+			logger.Warn("dead letter failed again", "stream", stream, "id", dl.Id, "source", dl.Source, "nsid", dl.Message.Nsid, "rkey", dl.Message.Rkey, "err", err)
+			continue
+		}
+
+		if err := db.DeleteDeadLetters(d, db.FilterEq("id", dl.Id)); err != nil {
+			logger.Error("failed to delete dead letter", "stream", stream, "id", dl.Id, "err", err)
+			continue
+		}
+		replayed++
+	}
+
+	if len(deadLetters) > 0 {
+		logger.Info("replayed dead letters", "stream", stream, "replayed", replayed, "remaining", len(deadLetters)-replayed)
+	}
+}
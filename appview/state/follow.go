@@ -1,7 +1,6 @@
 package state
 
 import (
-	"log"
 	"net/http"
 	"time"
 
@@ -15,28 +14,29 @@ import (
 )
 
 func (s *State) Follow(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "Follow")
 	currentUser := s.oauth.GetUser(r)
 
 	subject := r.URL.Query().Get("subject")
 	if subject == "" {
-		log.Println("invalid form")
+		l.Error("invalid form")
 		return
 	}
 
 	subjectIdent, err := s.idResolver.ResolveIdent(r.Context(), subject)
 	if err != nil {
-		log.Println("failed to follow, invalid did")
+		l.Error("failed to follow, invalid did")
 		return
 	}
 
 	if currentUser.Did == subjectIdent.DID.String() {
-		log.Println("cant follow or unfollow yourself")
+		l.Info("cant follow or unfollow yourself")
 		return
 	}
 
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to authorize client")
+		l.Error("failed to authorize client")
 		return
 	}
 
@@ -55,11 +55,11 @@ func (s *State) Follow(w http.ResponseWriter, r *http.Request) {
 				}},
 		})
 		if err != nil {
-			log.Println("failed to create atproto record", err)
+			l.Error("failed to create atproto record", "err", err)
 			return
 		}
 
-		log.Println("created atproto record: ", resp.Uri)
+		l.Info("created atproto record", "uri", resp.Uri)
 
 		follow := &models.Follow{
 			UserDid:    currentUser.Did,
@@ -69,7 +69,7 @@ func (s *State) Follow(w http.ResponseWriter, r *http.Request) {
 
 		err = db.AddFollow(s.db, follow)
 		if err != nil {
-			log.Println("failed to follow", err)
+			l.Error("failed to follow", "err", err)
 			return
 		}
 
@@ -85,7 +85,7 @@ func (s *State) Follow(w http.ResponseWriter, r *http.Request) {
 		// find the record in the db
 		follow, err := db.GetFollow(s.db, currentUser.Did, subjectIdent.DID.String())
 		if err != nil {
-			log.Println("failed to get follow relationship")
+			l.Error("failed to get follow relationship")
 			return
 		}
 
@@ -96,13 +96,13 @@ func (s *State) Follow(w http.ResponseWriter, r *http.Request) {
 		})
 
 		if err != nil {
-			log.Println("failed to unfollow")
+			l.Error("failed to unfollow")
 			return
 		}
 
 		err = db.DeleteFollowByRkey(s.db, currentUser.Did, follow.Rkey)
 		if err != nil {
-			log.Println("failed to delete follow from DB")
+			l.Error("failed to delete follow from DB")
 			// this is not an issue, the firehose event might have already done this
 		}
 
@@ -1,7 +1,6 @@
 package state
 
 import (
-	"log"
 	"net/http"
 	"sort"
 
@@ -14,6 +13,7 @@ import (
 )
 
 func (s *State) GoodFirstIssues(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "GoodFirstIssues")
 	user := s.oauth.GetUser(r)
 
 	page := pagination.FromContext(r.Context())
@@ -22,14 +22,14 @@ func (s *State) GoodFirstIssues(w http.ResponseWriter, r *http.Request) {
 
 	gfiLabelDef, err := db.GetLabelDefinition(s.db, db.FilterEq("at_uri", goodFirstIssueLabel))
 	if err != nil {
-		log.Println("failed to get gfi label def", err)
+		l.Error("failed to get gfi label def", "err", err)
 		s.pages.Error500(w)
 		return
 	}
 
 	repoLabels, err := db.GetRepoLabels(s.db, db.FilterEq("label_at", goodFirstIssueLabel))
 	if err != nil {
-		log.Println("failed to get repo labels", err)
+		l.Error("failed to get repo labels", "err", err)
 		s.pages.Error503(w)
 		return
 	}
@@ -59,14 +59,14 @@ func (s *State) GoodFirstIssues(w http.ResponseWriter, r *http.Request) {
 		db.FilterEq("open", 1),
 	)
 	if err != nil {
-		log.Println("failed to get issues", err)
+		l.Error("failed to get issues", "err", err)
 		s.pages.Error503(w)
 		return
 	}
 
 	var goodFirstIssues []models.Issue
 	for _, issue := range allIssues {
-		if issue.Labels.ContainsLabel(goodFirstIssueLabel) {
+		if issue.Labels.ContainsLabel(goodFirstIssueLabel) && !issue.Repo.IsPrivate() {
 			goodFirstIssues = append(goodFirstIssues, issue)
 		}
 	}
@@ -134,7 +134,7 @@ func (s *State) GoodFirstIssues(w http.ResponseWriter, r *http.Request) {
 		if len(uriList) > 0 {
 			allLabelDefs, err = db.GetLabelDefinitions(s.db, db.FilterIn("at_uri", uriList))
 			if err != nil {
-				log.Println("failed to fetch labels", err)
+				l.Error("failed to fetch labels", "err", err)
 			}
 		}
 	}
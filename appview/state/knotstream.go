@@ -13,6 +13,7 @@ import (
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/refcache"
 	ec "tangled.org/core/eventconsumer"
 	"tangled.org/core/eventconsumer/cursor"
 	"tangled.org/core/log"
@@ -31,6 +32,7 @@ func Knotstream(ctx context.Context, c *config.Config, d *db.DB, enforcer *rbac.
 	knots, err := db.GetRegistrations(
 		d,
 		db.FilterIsNot("registered", "null"),
+		db.FilterEq("approval", models.ApprovalApproved),
 	)
 	if err != nil {
 		return nil, err
@@ -56,6 +58,8 @@ func Knotstream(ctx context.Context, c *config.Config, d *db.DB, enforcer *rbac.
 		Logger:            logger,
 		Dev:               c.Core.Dev,
 		CursorStore:       &cursorStore,
+		MaxAttempts:       c.Knotstream.MaxAttempts,
+		DeadLetters:       db.DeadLetterStore{Execer: d, Stream: "knotstream"},
 	}
 
 	return ec.NewConsumer(cfg), nil
@@ -91,16 +95,17 @@ func ingestRefUpdate(d *db.DB, enforcer *rbac.Enforcer, pc posthog.Client, dev b
 
 	err1 := populatePunchcard(d, record)
 	err2 := updateRepoLanguages(d, record)
+	err3 := invalidateRefCache(d, record)
 
-	var err3 error
+	var err4 error
 	if !dev {
-		err3 = pc.Enqueue(posthog.Capture{
+		err4 = pc.Enqueue(posthog.Capture{
 			DistinctId: record.CommitterDid,
 			Event:      "git_ref_update",
 		})
 	}
 
-	return errors.Join(err1, err2, err3)
+	return errors.Join(err1, err2, err3, err4)
 }
 
 func populatePunchcard(d *db.DB, record tangled.GitRefUpdate) error {
@@ -129,13 +134,19 @@ func populatePunchcard(d *db.DB, record tangled.GitRefUpdate) error {
 
 	punch := models.Punch{
 		Did:   record.CommitterDid,
-		Date:  time.Now(),
+		Date:  time.Now().UTC(),
 		Count: count,
 	}
 	return db.AddPunch(d, punch)
 }
 
 func updateRepoLanguages(d *db.DB, record tangled.GitRefUpdate) error {
+	// only branches have a language breakdown, tags are not a failure
+	ref := plumbing.ReferenceName(record.Ref)
+	if !ref.IsBranch() {
+		return nil
+	}
+
 	if record.Meta == nil || record.Meta.LangBreakdown == nil || record.Meta.LangBreakdown.Inputs == nil {
 		return fmt.Errorf("empty language data for repo: %s/%s", record.RepoDid, record.RepoName)
 	}
@@ -154,11 +165,6 @@ func updateRepoLanguages(d *db.DB, record tangled.GitRefUpdate) error {
 	}
 	repo := repos[0]
 
-	ref := plumbing.ReferenceName(record.Ref)
-	if !ref.IsBranch() {
-		return fmt.Errorf("%s is not a valid reference name", ref)
-	}
-
 	var langs []models.RepoLanguage
 	for _, l := range record.Meta.LangBreakdown.Inputs {
 		if l == nil {
@@ -190,6 +196,35 @@ func updateRepoLanguages(d *db.DB, record tangled.GitRefUpdate) error {
 	return tx.Commit()
 }
 
+func invalidateRefCache(d *db.DB, record tangled.GitRefUpdate) error {
+	repos, err := db.GetRepos(
+		d,
+		0,
+		db.FilterEq("did", record.RepoDid),
+		db.FilterEq("name", record.RepoName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to look for repo in DB (%s/%s): %w", record.RepoDid, record.RepoName, err)
+	}
+	if len(repos) != 1 {
+		return fmt.Errorf("incorrect number of repos returned: %d (expected 1)", len(repos))
+	}
+
+	// merge checks against a branch that moved are stale
+	if ref := plumbing.ReferenceName(record.Ref); ref.IsBranch() {
+		err = db.DeleteMergeChecks(
+			d,
+			db.FilterEq("repo_at", repos[0].RepoAt()),
+			db.FilterEq("target_branch", ref.Short()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return refcache.Invalidate(d, repos[0].RepoAt(), record.Ref)
+}
+
 func ingestPipeline(d *db.DB, source ec.Source, msg ec.Message) error {
 	var record tangled.Pipeline
 	err := json.Unmarshal(msg.EventJson, &record)
@@ -221,6 +256,9 @@ func ingestPipeline(d *db.DB, source ec.Source, msg ec.Message) error {
 	if repos[0].Spindle == "" {
 		return fmt.Errorf("repo does not have a spindle configured yet: nsid %s, rkey %s", msg.Nsid, msg.Rkey)
 	}
+	if repos[0].PipelinesDisabled {
+		return fmt.Errorf("repo has pipelines turned off: nsid %s, rkey %s", msg.Nsid, msg.Rkey)
+	}
 
 	// trigger info
 	var trigger models.Trigger
@@ -51,6 +51,12 @@ func (s *State) Login(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := s.oauth.StartLogin(w, r, r.FormValue("remember") == "on"); err != nil {
+			l.Error("failed to start login", "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		s.pages.HxRedirect(w, redirectURL)
 	}
 }
@@ -3,10 +3,9 @@ package state
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"slices"
-	"strings"
+	"sync"
 	"time"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
@@ -18,7 +17,10 @@ import (
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
+	"tangled.org/core/appview/xrpcclient"
 )
 
 func (s *State) Profile(w http.ResponseWriter, r *http.Request) {
@@ -56,7 +58,11 @@ func (s *State) profile(r *http.Request) (*pages.ProfileCard, error) {
 		return nil, fmt.Errorf("failed to get profile: %w", err)
 	}
 
-	repoCount, err := db.CountRepos(s.db, db.FilterEq("did", did))
+	repoCount, err := db.CountRepos(
+		s.db,
+		db.FilterEq("did", did),
+		db.FilterIn("visibility", s.visibleRepos(r, did)),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repo count: %w", err)
 	}
@@ -82,13 +88,13 @@ func (s *State) profile(r *http.Request) (*pages.ProfileCard, error) {
 		followStatus = db.GetFollowStatus(s.db, loggedInUser.Did, did)
 	}
 
-	now := time.Now()
-	startOfYear := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	// the last 12 months, in UTC days like the punches themselves
+	now := time.Now().UTC()
 	punchcard, err := db.MakePunchcard(
 		s.db,
+		now.AddDate(-1, 0, 1),
+		now,
 		db.FilterEq("did", did),
-		db.FilterGte("date", startOfYear.Format(time.DateOnly)),
-		db.FilterLte("date", now.Format(time.DateOnly)),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get punchcard for %s: %w", did, err)
@@ -110,6 +116,15 @@ func (s *State) profile(r *http.Request) (*pages.ProfileCard, error) {
 	}, nil
 }
 
+// visibleRepos returns the visibilities of the repos that are listed on the
+// profile of did, private repos are only listed for their owner.
+func (s *State) visibleRepos(r *http.Request, did string) []models.RepoVisibility {
+	if user := s.oauth.GetUser(r); user != nil && user.Did == did {
+		return []models.RepoVisibility{models.RepoVisibilityPublic, models.RepoVisibilityPrivate}
+	}
+	return []models.RepoVisibility{models.RepoVisibilityPublic}
+}
+
 func (s *State) profileOverview(w http.ResponseWriter, r *http.Request) {
 	l := s.logger.With("handler", "profileHomePage")
 
@@ -121,39 +136,26 @@ func (s *State) profileOverview(w http.ResponseWriter, r *http.Request) {
 	}
 	l = l.With("profileDid", profile.UserDid, "profileHandle", profile.UserHandle)
 
-	repos, err := db.GetRepos(
-		s.db,
-		0,
-		db.FilterEq("did", profile.UserDid),
-	)
-	if err != nil {
-		l.Error("failed to fetch repos", "err", err)
-	}
-
-	// filter out ones that are pinned
-	pinnedRepos := []models.Repo{}
-	for i, r := range repos {
-		// if this is a pinned repo, add it
-		if slices.Contains(profile.Profile.PinnedRepos[:], r.RepoAt()) {
-			pinnedRepos = append(pinnedRepos, r)
-		}
-
-		// if there are no saved pins, add the first 4 repos
-		if profile.Profile.IsPinnedReposEmpty() && i < 4 {
-			pinnedRepos = append(pinnedRepos, r)
-		}
-	}
+	visible := s.visibleRepos(r, profile.UserDid)
 
-	collaboratingRepos, err := db.CollaboratingIn(s.db, profile.UserDid)
+	pins, err := db.GetPins(s.db, profile.UserDid, profile.Profile.Pins[:], visible)
 	if err != nil {
-		l.Error("failed to fetch collaborating repos", "err", err)
+		l.Error("failed to fetch pins", "err", err)
 	}
 
-	pinnedCollaboratingRepos := []models.Repo{}
-	for _, r := range collaboratingRepos {
-		// if this is a pinned repo, add it
-		if slices.Contains(profile.Profile.PinnedRepos[:], r.RepoAt()) {
-			pinnedCollaboratingRepos = append(pinnedCollaboratingRepos, r)
+	// if there are no saved pins, show the first 4 repos
+	if profile.Profile.IsPinsEmpty() {
+		repos, err := db.GetRepos(
+			s.db,
+			4,
+			db.FilterEq("did", profile.UserDid),
+			db.FilterIn("visibility", visible),
+		)
+		if err != nil {
+			l.Error("failed to fetch repos", "err", err)
+		}
+		for _, repo := range repos {
+			pins = append(pins, models.Pin{Kind: models.PinKindRepo, Uri: repo.RepoAt(), Repo: &repo})
 		}
 	}
 
@@ -163,11 +165,10 @@ func (s *State) profileOverview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.pages.ProfileOverview(w, pages.ProfileOverviewParams{
-		LoggedInUser:       s.oauth.GetUser(r),
-		Card:               profile,
-		Repos:              pinnedRepos,
-		CollaboratingRepos: pinnedCollaboratingRepos,
-		ProfileTimeline:    timeline,
+		LoggedInUser:    s.oauth.GetUser(r),
+		Card:            profile,
+		Pins:            pins,
+		ProfileTimeline: timeline,
 	})
 }
 
@@ -186,6 +187,7 @@ func (s *State) reposPage(w http.ResponseWriter, r *http.Request) {
 		s.db,
 		0,
 		db.FilterEq("did", profile.UserDid),
+		db.FilterIn("visibility", s.visibleRepos(r, profile.UserDid)),
 	)
 	if err != nil {
 		l.Error("failed to get repos", "err", err)
@@ -217,9 +219,13 @@ func (s *State) starredPage(w http.ResponseWriter, r *http.Request) {
 		s.pages.Error500(w)
 		return
 	}
+	visible := s.visibleRepos(r, profile.UserDid)
+
 	var repos []models.Repo
-	for _, s := range stars {
-		repos = append(repos, *s.Repo)
+	for _, star := range stars {
+		if slices.Contains(visible, star.Repo.Visibility) {
+			repos = append(repos, *star.Repo)
+		}
 	}
 
 	err = s.pages.ProfileStarred(w, pages.ProfileStarredParams{
@@ -300,7 +306,7 @@ func (s *State) followPage(
 
 	followStatsMap, err := db.GetFollowerFollowingCounts(s.db, followDids)
 	if err != nil {
-		log.Printf("getting follow counts for %s: %s", followDids, err)
+		l.Error("getting follow counts", "dids", followDids, "err", err)
 	}
 
 	loggedInUserFollowing := make(map[string]struct{})
@@ -519,18 +525,19 @@ func (s *State) createRepoItem(ctx context.Context, repo models.RepoEvent, autho
 }
 
 func (s *State) UpdateProfileBio(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "UpdateProfileBio")
 	user := s.oauth.GetUser(r)
 
 	err := r.ParseForm()
 	if err != nil {
-		log.Println("invalid profile update form", err)
+		l.Error("invalid profile update form", "err", err)
 		s.pages.Notice(w, "update-profile", "Invalid form.")
 		return
 	}
 
 	profile, err := db.GetProfile(s.db, user.Did)
 	if err != nil {
-		log.Printf("getting profile data for %s: %s", user.Did, err)
+		l.Error("getting profile data", "did", user.Did, "err", err)
 	}
 
 	profile.Description = r.FormValue("description")
@@ -558,7 +565,7 @@ func (s *State) UpdateProfileBio(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := db.ValidateProfile(s.db, profile); err != nil {
-		log.Println("invalid profile", err)
+		l.Error("invalid profile", "err", err)
 		s.pages.Notice(w, "update-profile", err.Error())
 		return
 	}
@@ -567,56 +574,63 @@ func (s *State) UpdateProfileBio(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *State) UpdateProfilePins(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "UpdateProfilePins")
 	user := s.oauth.GetUser(r)
 
 	err := r.ParseForm()
 	if err != nil {
-		log.Println("invalid profile update form", err)
+		l.Error("invalid profile update form", "err", err)
 		s.pages.Notice(w, "update-profile", "Invalid form.")
 		return
 	}
 
 	profile, err := db.GetProfile(s.db, user.Did)
 	if err != nil {
-		log.Printf("getting profile data for %s: %s", user.Did, err)
+		l.Error("getting profile data", "did", user.Did, "err", err)
 	}
 
-	i := 0
-	var pinnedRepos [6]syntax.ATURI
-	for key, values := range r.Form {
-		if i >= 6 {
-			log.Println("invalid pin update form", err)
-			s.pages.Notice(w, "update-profile", "Only 6 repositories can be pinned at a time.")
+	// pins are kept in the order they were sent in
+	values := slices.DeleteFunc(r.Form["pin"], func(v string) bool { return v == "" })
+	if len(values) > 6 {
+		l.Error("invalid pin update form", "count", len(values))
+		s.pages.Notice(w, "update-profile", "Only 6 items can be pinned at a time.")
+		return
+	}
+
+	var pins [6]syntax.ATURI
+	for i, v := range values {
+		aturi, err := syntax.ParseATURI(v)
+		if err != nil {
+			l.Error("invalid profile update form", "err", err)
+			s.pages.Notice(w, "update-profile", "Invalid form.")
 			return
 		}
-		if strings.HasPrefix(key, "pinnedRepo") && len(values) > 0 && values[0] != "" && i < 6 {
-			aturi, err := syntax.ParseATURI(values[0])
-			if err != nil {
-				log.Println("invalid profile update form", err)
-				s.pages.Notice(w, "update-profile", "Invalid form.")
-				return
-			}
-			pinnedRepos[i] = aturi
-			i++
-		}
+		pins[i] = aturi
+	}
+	profile.Pins = pins
+
+	if err := db.ValidateProfile(s.db, profile); err != nil {
+		l.Error("invalid profile", "err", err)
+		s.pages.Notice(w, "update-profile", err.Error())
+		return
 	}
-	profile.PinnedRepos = pinnedRepos
 
 	s.updateProfile(profile, w, r)
 }
 
 func (s *State) updateProfile(profile *models.Profile, w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "updateProfile")
 	user := s.oauth.GetUser(r)
 	tx, err := s.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Println("failed to start transaction", err)
+		l.Error("failed to start transaction", "err", err)
 		s.pages.Notice(w, "update-profile", "Failed to update profile, try again later.")
 		return
 	}
 
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to get authorized client", err)
+		l.Error("failed to get authorized client", "err", err)
 		s.pages.Notice(w, "update-profile", "Failed to update profile, try again later.")
 		return
 	}
@@ -624,7 +638,7 @@ func (s *State) updateProfile(profile *models.Profile, w http.ResponseWriter, r
 	// yeah... lexgen dose not support syntax.ATURI in the record for some reason,
 	// nor does it support exact size arrays
 	var pinnedRepoStrings []string
-	for _, r := range profile.PinnedRepos {
+	for _, r := range profile.Pins {
 		pinnedRepoStrings = append(pinnedRepoStrings, r.String())
 	}
 
@@ -656,14 +670,14 @@ func (s *State) updateProfile(profile *models.Profile, w http.ResponseWriter, r
 		SwapRecord: cid,
 	})
 	if err != nil {
-		log.Println("failed to update profile", err)
+		l.Error("failed to update profile", "err", err)
 		s.pages.Notice(w, "update-profile", "Failed to update PDS, try again later.")
 		return
 	}
 
 	err = db.UpsertProfile(tx, profile)
 	if err != nil {
-		log.Println("failed to update profile", err)
+		l.Error("failed to update profile", "err", err)
 		s.pages.Notice(w, "update-profile", "Failed to update profile, try again later.")
 		return
 	}
@@ -674,11 +688,12 @@ func (s *State) updateProfile(profile *models.Profile, w http.ResponseWriter, r
 }
 
 func (s *State) EditBioFragment(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "EditBioFragment")
 	user := s.oauth.GetUser(r)
 
 	profile, err := db.GetProfile(s.db, user.Did)
 	if err != nil {
-		log.Printf("getting profile data for %s: %s", user.Did, err)
+		l.Error("getting profile data", "did", user.Did, "err", err)
 	}
 
 	s.pages.EditBioFragment(w, pages.EditBioParams{
@@ -687,44 +702,149 @@ func (s *State) EditBioFragment(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// StorageFragment shows the logged in user how much of each knot their
+// repos take up. It is loaded lazily since every knot is asked.
+func (s *State) StorageFragment(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "StorageFragment")
+	user := s.oauth.GetUser(r)
+
+	repos, err := db.GetRepos(s.db, 0, db.FilterEq("did", user.Did))
+	if err != nil {
+		l.Error("getting repos", "did", user.Did, "err", err)
+	}
+
+	var knots []string
+	for _, repo := range repos {
+		if !slices.Contains(knots, repo.Knot) {
+			knots = append(knots, repo.Knot)
+		}
+	}
+
+	usage := make([]*models.StorageUsage, len(knots))
+	var wg sync.WaitGroup
+	for i, knot := range knots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := s.storageUsage(r, knot, user.Did)
+			if err != nil {
+				l.Error("getting storage usage", "knot", knot, "did", user.Did, "err", err)
+				return
+			}
+			usage[i] = u
+		}()
+	}
+	wg.Wait()
+
+	// knots that could not say are left out
+	var known []models.StorageUsage
+	for _, u := range usage {
+		if u != nil {
+			known = append(known, *u)
+		}
+	}
+
+	s.pages.StorageFragment(w, known)
+}
+
+func (s *State) storageUsage(r *http.Request, knot, did string) (*models.StorageUsage, error) {
+	client, err := s.oauth.ServiceClient(
+		r,
+		oauth.WithService(knot),
+		oauth.WithLxm(tangled.KnotUsageNSID),
+		oauth.WithDev(s.config.Core.Dev),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := tangled.KnotUsage(r.Context(), client, did)
+	if xrpcerr := xrpcclient.HandleXrpcErr(err); xrpcerr != nil {
+		return nil, xrpcerr
+	}
+
+	usage := models.StorageUsageFromKnot(knot, out)
+	return &usage, nil
+}
+
 func (s *State) EditPinsFragment(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "EditPinsFragment")
 	user := s.oauth.GetUser(r)
 
 	profile, err := db.GetProfile(s.db, user.Did)
 	if err != nil {
-		log.Printf("getting profile data for %s: %s", user.Did, err)
+		l.Error("getting profile data", "did", user.Did, "err", err)
 	}
 
+	// what is already pinned comes first, in order, followed by everything
+	// else that could be
+	uris := slices.Clone(profile.Pins[:])
+
 	repos, err := db.GetRepos(s.db, 0, db.FilterEq("did", user.Did))
 	if err != nil {
-		log.Printf("getting repos for %s: %s", user.Did, err)
+		l.Error("getting repos", "did", user.Did, "err", err)
+	}
+	for _, r := range repos {
+		uris = append(uris, r.RepoAt())
 	}
 
 	collaboratingRepos, err := db.CollaboratingIn(s.db, user.Did)
 	if err != nil {
-		log.Printf("getting collaborating repos for %s: %s", user.Did, err)
+		l.Error("getting collaborating repos", "did", user.Did, "err", err)
+	}
+	for _, r := range collaboratingRepos {
+		uris = append(uris, r.RepoAt())
 	}
 
-	allRepos := []pages.PinnedRepo{}
+	strs, err := db.GetStrings(s.db, 0, db.FilterEq("did", user.Did))
+	if err != nil {
+		l.Error("getting strings", "did", user.Did, "err", err)
+	}
+	for _, str := range strs {
+		uris = append(uris, str.AtUri())
+	}
 
-	for _, r := range repos {
-		isPinned := slices.Contains(profile.PinnedRepos[:], r.RepoAt())
-		allRepos = append(allRepos, pages.PinnedRepo{
-			IsPinned: isPinned,
-			Repo:     r,
-		})
+	issues, err := db.GetIssuesPaginated(s.db, pagination.Page{Limit: 20}, db.FilterEq("did", user.Did))
+	if err != nil {
+		l.Error("getting issues", "did", user.Did, "err", err)
 	}
-	for _, r := range collaboratingRepos {
-		isPinned := slices.Contains(profile.PinnedRepos[:], r.RepoAt())
-		allRepos = append(allRepos, pages.PinnedRepo{
-			IsPinned: isPinned,
-			Repo:     r,
+	for _, i := range issues {
+		uris = append(uris, i.AtUri())
+	}
+
+	pulls, err := db.GetPullsWithLimit(s.db, 20, db.FilterEq("owner_did", user.Did))
+	if err != nil {
+		l.Error("getting pulls", "did", user.Did, "err", err)
+	}
+	for _, p := range pulls {
+		uris = append(uris, p.AtUri())
+	}
+
+	seen := make(map[syntax.ATURI]bool)
+	uris = slices.DeleteFunc(uris, func(uri syntax.ATURI) bool {
+		if uri == "" || seen[uri] {
+			return true
+		}
+		seen[uri] = true
+		return false
+	})
+
+	pins, err := db.GetPins(s.db, user.Did, uris, s.visibleRepos(r, user.Did))
+	if err != nil {
+		l.Error("getting pins", "did", user.Did, "err", err)
+	}
+
+	candidates := []pages.PinCandidate{}
+	for _, pin := range pins {
+		candidates = append(candidates, pages.PinCandidate{
+			IsPinned: slices.Contains(profile.Pins[:], pin.Uri),
+			Pin:      pin,
 		})
 	}
 
 	s.pages.EditPinsFragment(w, pages.EditPinsParams{
 		LoggedInUser: user,
 		Profile:      profile,
-		AllRepos:     allRepos,
+		Candidates:   candidates,
 	})
 }
@@ -5,14 +5,19 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/admin"
 	"tangled.org/core/appview/issues"
 	"tangled.org/core/appview/knots"
 	"tangled.org/core/appview/labels"
 	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/moderation"
 	"tangled.org/core/appview/notifications"
 	"tangled.org/core/appview/pipelines"
+	"tangled.org/core/appview/preview"
 	"tangled.org/core/appview/pulls"
 	"tangled.org/core/appview/repo"
+	"tangled.org/core/appview/search"
 	"tangled.org/core/appview/settings"
 	"tangled.org/core/appview/signup"
 	"tangled.org/core/appview/spindles"
@@ -32,10 +37,14 @@ func (s *State) Router() http.Handler {
 		s.pages,
 	)
 
+	router.Use(middleware.WithLogger(s.logger))
+	router.Use(middleware.AccessLog(log.SubLogger(s.logger, "http"), s.config.AccessLog))
+
 	router.Get("/favicon.svg", s.Favicon)
 	router.Get("/favicon.ico", s.Favicon)
 	router.Get("/pwa-manifest.json", s.PWAManifest)
 	router.Get("/robots.txt", s.RobotsTxt)
+	router.Get("/health", s.Health)
 
 	userRouter := s.UserRouter(&middleware)
 	standardRouter := s.StandardRouter(&middleware)
@@ -96,8 +105,22 @@ func (s *State) UserRouter(mw *middleware.Middleware) http.Handler {
 			r.Mount("/", s.RepoRouter(mw))
 			r.Mount("/issues", s.IssuesRouter(mw))
 			r.Mount("/pulls", s.PullsRouter(mw))
-			r.Mount("/pipelines", s.PipelinesRouter())
+			r.Mount("/pipelines", s.PipelinesRouter(mw))
 			r.Mount("/labels", s.LabelsRouter())
+			r.Mount("/hooks", s.WebhooksRouter(mw))
+
+			r.With(middleware.AuthMiddleware(s.oauth)).Route("/template", func(r chi.Router) {
+				r.Get("/", s.CreateFromTemplate)
+				r.Post("/", s.CreateFromTemplate)
+			})
+
+			r.With(middleware.AuthMiddleware(s.oauth)).Route("/transfer", func(r chi.Router) {
+				r.Get("/", s.TransferPage)
+				r.Post("/", s.TransferRepo)
+				r.Delete("/", s.CancelTransfer)
+				r.Post("/accept", s.AcceptTransfer)
+				r.Post("/decline", s.DeclineTransfer)
+			})
 
 			// These routes get proxied to the knot
 			r.Get("/info/refs", s.InfoRefs)
@@ -151,6 +174,11 @@ func (s *State) StandardRouter(mw *middleware.Middleware) http.Handler {
 		r.Delete("/", s.Star)
 	})
 
+	r.With(middleware.AuthMiddleware(s.oauth)).Route("/watch", func(r chi.Router) {
+		r.Post("/", s.Watch)
+		r.Delete("/", s.Watch)
+	})
+
 	r.With(middleware.AuthMiddleware(s.oauth)).Route("/react", func(r chi.Router) {
 		r.Post("/", s.React)
 		r.Delete("/", s.React)
@@ -160,6 +188,7 @@ func (s *State) StandardRouter(mw *middleware.Middleware) http.Handler {
 		r.Use(middleware.AuthMiddleware(s.oauth))
 		r.Get("/edit-bio", s.EditBioFragment)
 		r.Get("/edit-pins", s.EditPinsFragment)
+		r.Get("/storage", s.StorageFragment)
 		r.Post("/bio", s.UpdateProfileBio)
 		r.Post("/pins", s.UpdateProfilePins)
 	})
@@ -169,11 +198,21 @@ func (s *State) StandardRouter(mw *middleware.Middleware) http.Handler {
 	r.Mount("/knots", s.KnotsRouter())
 	r.Mount("/spindles", s.SpindlesRouter())
 	r.Mount("/notifications", s.NotificationsRouter(mw))
+	r.Mount("/search", s.SearchRouter())
+	r.Mount("/markup", s.PreviewRouter())
+	r.Mount("/attachments", s.AttachmentsRouter())
+	r.Mount("/mail", s.MailRouter())
+	r.Mount("/moderation", s.ModerationRouter(mw))
+	if len(s.config.Admin.Dids) > 0 {
+		r.Mount("/admin", s.AdminRouter())
+	}
 
 	r.Mount("/signup", s.SignupRouter())
 	r.Mount("/", s.oauth.Router())
 
 	r.Get("/keys/{user}", s.Keys)
+	r.Post("/app-passwords/verify", s.VerifyAppPassword)
+	r.Post("/xrpc/"+tangled.RepoCommitStatusNSID, s.SetCommitStatus)
 	r.Get("/terms", s.TermsOfService)
 	r.Get("/privacy", s.PrivacyPolicy)
 	r.Get("/brand", s.Brand)
@@ -200,10 +239,13 @@ func (s *State) Core() http.HandlerFunc {
 
 func (s *State) SettingsRouter() http.Handler {
 	settings := &settings.Settings{
-		Db:     s.db,
-		OAuth:  s.oauth,
-		Pages:  s.pages,
-		Config: s.config,
+		Db:        s.db,
+		OAuth:     s.oauth,
+		Pages:     s.pages,
+		Config:    s.config,
+		Validator: s.validator,
+		Exporter:  s.exporter,
+		Deleter:   s.deleter,
 	}
 
 	return settings.Router()
@@ -236,6 +278,7 @@ func (s *State) KnotsRouter() http.Handler {
 		Enforcer:   s.enforcer,
 		IdResolver: s.idResolver,
 		Knotstream: s.knotstream,
+		Health:     s.knotHealth,
 		Logger:     logger,
 	}
 
@@ -274,7 +317,16 @@ func (s *State) IssuesRouter(mw *middleware.Middleware) http.Handler {
 }
 
 func (s *State) PullsRouter(mw *middleware.Middleware) http.Handler {
-	pulls := pulls.New(
+	return s.pulls().Router(mw)
+}
+
+// MailRouter takes patches and replies to them over email.
+func (s *State) MailRouter() http.Handler {
+	return s.pulls().MailRouter()
+}
+
+func (s *State) pulls() *pulls.Pulls {
+	return pulls.New(
 		s.oauth,
 		s.repoResolver,
 		s.pages,
@@ -287,7 +339,6 @@ func (s *State) PullsRouter(mw *middleware.Middleware) http.Handler {
 		s.indexer.Pulls,
 		log.SubLogger(s.logger, "pulls"),
 	)
-	return pulls.Router(mw)
 }
 
 func (s *State) RepoRouter(mw *middleware.Middleware) http.Handler {
@@ -307,7 +358,7 @@ func (s *State) RepoRouter(mw *middleware.Middleware) http.Handler {
 	return repo.Router(mw)
 }
 
-func (s *State) PipelinesRouter() http.Handler {
+func (s *State) PipelinesRouter(mw *middleware.Middleware) http.Handler {
 	pipes := pipelines.New(
 		s.oauth,
 		s.repoResolver,
@@ -319,7 +370,7 @@ func (s *State) PipelinesRouter() http.Handler {
 		s.enforcer,
 		log.SubLogger(s.logger, "pipelines"),
 	)
-	return pipes.Router()
+	return pipes.Router(mw)
 }
 
 func (s *State) LabelsRouter() http.Handler {
@@ -339,7 +390,45 @@ func (s *State) NotificationsRouter(mw *middleware.Middleware) http.Handler {
 	return notifs.Router(mw)
 }
 
+func (s *State) ModerationRouter(mw *middleware.Middleware) http.Handler {
+	mod := moderation.New(s.db, s.oauth, s.pages, s.config, s.idResolver, log.SubLogger(s.logger, "moderation"))
+	return mod.Router(mw)
+}
+
+func (s *State) AdminRouter() http.Handler {
+	admin := admin.New(
+		s.db,
+		s.oauth,
+		s.pages,
+		s.config,
+		s.idResolver,
+		s.enforcer,
+		s.knotstream,
+		s.notifier,
+		log.SubLogger(s.logger, "admin"),
+	)
+	return admin.Router()
+}
+
+func (s *State) SearchRouter() http.Handler {
+	search := search.New(s.db, s.oauth, s.enforcer, s.indexer, s.idResolver, s.pages, log.SubLogger(s.logger, "search"))
+	return search.Router()
+}
+
+func (s *State) PreviewRouter() http.Handler {
+	preview := preview.New(s.db, s.oauth, s.enforcer, s.idResolver, s.pages, log.SubLogger(s.logger, "preview"))
+	return preview.Router()
+}
+
+func (s *State) AttachmentsRouter() http.Handler {
+	return s.attachments.Router()
+}
+
+func (s *State) WebhooksRouter(mw *middleware.Middleware) http.Handler {
+	return s.webhooks.Router(mw)
+}
+
 func (s *State) SignupRouter() http.Handler {
-	sig := signup.New(s.config, s.db, s.posthog, s.idResolver, s.pages, log.SubLogger(s.logger, "signup"))
+	sig := signup.New(s.config, s.db, s.posthog, s.idResolver, s.pages, s.pow, log.SubLogger(s.logger, "signup"))
 	return sig.Router()
 }
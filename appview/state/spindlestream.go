@@ -28,6 +28,7 @@ func Spindlestream(ctx context.Context, c *config.Config, d *db.DB, enforcer *rb
 	spindles, err := db.GetSpindles(
 		d,
 		db.FilterIsNot("verified", "null"),
+		db.FilterEq("approval", models.ApprovalApproved),
 	)
 	if err != nil {
 		return nil, err
@@ -53,6 +54,8 @@ func Spindlestream(ctx context.Context, c *config.Config, d *db.DB, enforcer *rb
 		Logger:            logger,
 		Dev:               c.Core.Dev,
 		CursorStore:       &cursorStore,
+		MaxAttempts:       c.Spindlestream.MaxAttempts,
+		DeadLetters:       db.DeadLetterStore{Execer: d, Stream: "spindlestream"},
 	}
 
 	return ec.NewConsumer(cfg), nil
@@ -63,6 +66,8 @@ func spindleIngester(ctx context.Context, logger *slog.Logger, d *db.DB) ec.Proc
 		switch msg.Nsid {
 		case tangled.PipelineStatusNSID:
 			return ingestPipelineStatus(ctx, logger, d, source, msg)
+		case spindle.ArtifactNSID:
+			return ingestPipelineArtifact(ctx, logger, d, source, msg)
 		}
 
 		return nil
@@ -111,3 +116,38 @@ func ingestPipelineStatus(ctx context.Context, logger *slog.Logger, d *db.DB, so
 
 	return nil
 }
+
+func ingestPipelineArtifact(ctx context.Context, logger *slog.Logger, d *db.DB, source ec.Source, msg ec.Message) error {
+	var record spindle.ArtifactEvent
+	err := json.Unmarshal(msg.EventJson, &record)
+	if err != nil {
+		return err
+	}
+
+	pipelineUri, err := syntax.ParseATURI(record.Pipeline)
+	if err != nil {
+		return err
+	}
+
+	created := time.Now()
+	if t, err := time.Parse(time.RFC3339, record.CreatedAt); err == nil && created.After(t) {
+		created = t
+	}
+
+	artifact := models.PipelineArtifact{
+		Spindle:      source.Key(),
+		PipelineKnot: strings.TrimPrefix(pipelineUri.Authority().String(), "did:web:"),
+		PipelineRkey: pipelineUri.RecordKey().String(),
+		Workflow:     record.Workflow,
+		Name:         record.Name,
+		Size:         uint64(record.Size),
+		Created:      created,
+	}
+
+	err = db.AddPipelineArtifact(d, artifact)
+	if err != nil {
+		return fmt.Errorf("failed to add pipeline artifact: %w", err)
+	}
+
+	return nil
+}
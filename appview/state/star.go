@@ -1,7 +1,8 @@
 package state
 
 import (
-	"log"
+	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
@@ -12,32 +13,48 @@ import (
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pagination"
 	"tangled.org/core/tid"
 )
 
+// Star stars or unstars a subject and answers with the button in its new
+// state. Both are idempotent, so two tabs toggling at once agree on the
+// outcome, and the count is read in the same transaction as the change.
 func (s *State) Star(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "Star")
 	currentUser := s.oauth.GetUser(r)
 
 	subject := r.URL.Query().Get("subject")
 	if subject == "" {
-		log.Println("invalid form")
+		l.Error("invalid form")
 		return
 	}
 
 	subjectUri, err := syntax.ParseATURI(subject)
 	if err != nil {
-		log.Println("invalid form")
+		l.Error("invalid form")
 		return
 	}
 
 	client, err := s.oauth.AuthorizedClient(r)
 	if err != nil {
-		log.Println("failed to authorize client", err)
+		l.Error("failed to authorize client", "err", err)
 		return
 	}
 
 	switch r.Method {
 	case http.MethodPost:
+		// starred already, from another tab
+		_, err := db.GetStar(s.db, currentUser.Did, subjectUri)
+		if err == nil {
+			s.starBtn(w, subjectUri, true)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			l.Error("failed to get star relationship", "err", err)
+			return
+		}
+
 		createdAt := time.Now().Format(time.RFC3339)
 		rkey := tid.TID()
 		resp, err := comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
@@ -51,10 +68,10 @@ func (s *State) Star(w http.ResponseWriter, r *http.Request) {
 				}},
 		})
 		if err != nil {
-			log.Println("failed to create atproto record", err)
+			l.Error("failed to create atproto record", "err", err)
 			return
 		}
-		log.Println("created atproto record: ", resp.Uri)
+		l.Info("created atproto record", "uri", resp.Uri)
 
 		star := &models.Star{
 			Did:    currentUser.Did,
@@ -62,15 +79,27 @@ func (s *State) Star(w http.ResponseWriter, r *http.Request) {
 			Rkey:   rkey,
 		}
 
-		err = db.AddStar(s.db, star)
+		tx, err := s.db.BeginTx(r.Context(), nil)
 		if err != nil {
-			log.Println("failed to star", err)
+			l.Error("failed to star", "err", err)
+			return
+		}
+		defer tx.Rollback()
+
+		if err := db.AddStar(tx, star); err != nil {
+			l.Error("failed to star", "err", err)
 			return
 		}
 
-		starCount, err := db.GetStarCount(s.db, subjectUri)
+		starCount, err := db.GetStarCount(tx, subjectUri)
 		if err != nil {
-			log.Println("failed to get star count for ", subjectUri)
+			l.Error("failed to get star count", "subject", subjectUri, "err", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			l.Error("failed to star", "err", err)
+			return
 		}
 
 		s.notifier.NewStar(r.Context(), star)
@@ -83,37 +112,61 @@ func (s *State) Star(w http.ResponseWriter, r *http.Request) {
 
 		return
 	case http.MethodDelete:
-		// find the record in the db
-		star, err := db.GetStar(s.db, currentUser.Did, subjectUri)
+		// there can be more than one record if several tabs starred at once
+		stars, err := db.GetStars(
+			s.db,
+			pagination.Page{},
+			db.FilterEq("did", currentUser.Did),
+			db.FilterEq("subject_at", subjectUri),
+		)
 		if err != nil {
-			log.Println("failed to get star relationship")
+			l.Error("failed to get star relationship", "err", err)
 			return
 		}
 
-		_, err = comatproto.RepoDeleteRecord(r.Context(), client, &comatproto.RepoDeleteRecord_Input{
-			Collection: tangled.FeedStarNSID,
-			Repo:       currentUser.Did,
-			Rkey:       star.Rkey,
-		})
+		// unstarred already, from another tab
+		if len(stars) == 0 {
+			s.starBtn(w, subjectUri, false)
+			return
+		}
+
+		for _, star := range stars {
+			_, err = comatproto.RepoDeleteRecord(r.Context(), client, &comatproto.RepoDeleteRecord_Input{
+				Collection: tangled.FeedStarNSID,
+				Repo:       currentUser.Did,
+				Rkey:       star.Rkey,
+			})
+			if err != nil {
+				l.Error("failed to unstar")
+				return
+			}
+		}
 
+		tx, err := s.db.BeginTx(r.Context(), nil)
 		if err != nil {
-			log.Println("failed to unstar")
+			l.Error("failed to unstar", "err", err)
 			return
 		}
+		defer tx.Rollback()
 
-		err = db.DeleteStarByRkey(s.db, currentUser.Did, star.Rkey)
-		if err != nil {
-			log.Println("failed to delete star from DB")
-			// this is not an issue, the firehose event might have already done this
+		// the firehose event might have already done this
+		if err := db.DeleteStar(tx, currentUser.Did, subjectUri); err != nil {
+			l.Error("failed to delete star from DB")
+			return
 		}
 
-		starCount, err := db.GetStarCount(s.db, subjectUri)
+		starCount, err := db.GetStarCount(tx, subjectUri)
 		if err != nil {
-			log.Println("failed to get star count for ", subjectUri)
+			l.Error("failed to get star count", "subject", subjectUri, "err", err)
 			return
 		}
 
-		s.notifier.DeleteStar(r.Context(), star)
+		if err := tx.Commit(); err != nil {
+			l.Error("failed to unstar", "err", err)
+			return
+		}
+
+		s.notifier.DeleteStar(r.Context(), &stars[0])
 
 		s.pages.StarBtnFragment(w, pages.StarBtnFragmentParams{
 			IsStarred: false,
@@ -125,3 +178,20 @@ func (s *State) Star(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// starBtn answers with the star button as it is, for when there was nothing
+// to change.
+func (s *State) starBtn(w http.ResponseWriter, subjectUri syntax.ATURI, isStarred bool) {
+	l := s.logger.With("handler", "starBtn")
+	starCount, err := db.GetStarCount(s.db, subjectUri)
+	if err != nil {
+		l.Error("failed to get star count", "subject", subjectUri, "err", err)
+		return
+	}
+
+	s.pages.StarBtnFragment(w, pages.StarBtnFragmentParams{
+		IsStarred: isStarred,
+		SubjectAt: subjectUri,
+		StarCount: starCount,
+	})
+}
@@ -3,34 +3,47 @@ package state
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/appview"
+	"tangled.org/core/appview/attachments"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/deletions"
+	"tangled.org/core/appview/exports"
 	"tangled.org/core/appview/indexer"
+	"tangled.org/core/appview/knothealth"
 	"tangled.org/core/appview/models"
 	"tangled.org/core/appview/notify"
 	dbnotify "tangled.org/core/appview/notify/db"
 	phnotify "tangled.org/core/appview/notify/posthog"
+	pcnotify "tangled.org/core/appview/notify/punchcard"
 	"tangled.org/core/appview/oauth"
 	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/pow"
+	"tangled.org/core/appview/ratelimit"
 	"tangled.org/core/appview/reporesolver"
 	"tangled.org/core/appview/validator"
+	"tangled.org/core/appview/webhooks"
 	xrpcclient "tangled.org/core/appview/xrpcclient"
 	"tangled.org/core/eventconsumer"
 	"tangled.org/core/idresolver"
 	"tangled.org/core/jetstream"
 	"tangled.org/core/log"
 	tlog "tangled.org/core/log"
+	"tangled.org/core/patchutil"
 	"tangled.org/core/rbac"
 	"tangled.org/core/tid"
+	"tangled.org/core/xrpc/serviceauth"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	atpclient "github.com/bluesky-social/indigo/atproto/client"
@@ -57,12 +70,26 @@ type State struct {
 	spindlestream *eventconsumer.Consumer
 	logger        *slog.Logger
 	validator     *validator.Validator
+	webhooks      *webhooks.Webhooks
+	// set when signups and new repos need a proof-of-work
+	pow         *pow.Pow
+	attachments *attachments.Attachments
+	knotHealth  *knothealth.Checker
+	exporter    *exports.Exporter
+	deleter     *deletions.Deleter
+	// failed app password verifications, by address
+	appPasswordFailures *ratelimit.Limiter
 }
 
 func Make(ctx context.Context, config *config.Config) (*State, error) {
 	logger := tlog.FromContext(ctx)
 
-	d, err := db.Make(ctx, config.Core.DbPath)
+	d, err := db.Make(ctx, config.Core.DbPath, db.Options{
+		BusyTimeout:  config.Db.BusyTimeout,
+		MaxOpenConns: config.Db.MaxOpenConns,
+		MaxIdleConns: config.Db.MaxIdleConns,
+		BusyRetries:  config.Db.BusyRetries,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create db: %w", err)
 	}
@@ -78,6 +105,14 @@ func Make(ctx context.Context, config *config.Config) (*State, error) {
 		return nil, fmt.Errorf("failed to create enforcer: %w", err)
 	}
 
+	// grant the merge and triage permissions to members of existing repos
+	if err := enforcer.MigrateRepoRoles(); err != nil {
+		return nil, fmt.Errorf("failed to migrate repo roles: %w", err)
+	}
+	if err := enforcer.E.SavePolicy(); err != nil {
+		return nil, fmt.Errorf("failed to migrate repo roles: %w", err)
+	}
+
 	res, err := idresolver.RedisResolver(config.Redis.ToURL(), config.Plc.PLCURL)
 	if err != nil {
 		logger.Error("failed to create redis resolver", "err", err)
@@ -94,7 +129,8 @@ func Make(ctx context.Context, config *config.Config) (*State, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to start oauth handler: %w", err)
 	}
-	validator := validator.New(d, res, enforcer)
+	validator := validator.New(d, res, enforcer, config.Patch)
+	patchutil.RenameThreshold = config.Patch.RenameThreshold
 
 	repoResolver := reporesolver.New(config, enforcer, res, d)
 
@@ -105,8 +141,10 @@ func Make(ctx context.Context, config *config.Config) (*State, error) {
 		[]string{
 			tangled.GraphFollowNSID,
 			tangled.FeedStarNSID,
+			tangled.GraphWatchNSID,
 			tangled.PublicKeyNSID,
 			tangled.RepoArtifactNSID,
+			tangled.RepoReleaseNSID,
 			tangled.ActorProfileNSID,
 			tangled.SpindleMemberNSID,
 			tangled.SpindleNSID,
@@ -140,6 +178,7 @@ func Make(ctx context.Context, config *config.Config) (*State, error) {
 		Config:     config,
 		Logger:     log.SubLogger(logger, "ingester"),
 		Validator:  validator,
+		Indexer:    indexer,
 	}
 	err = jc.StartJetstream(ctx, ingester.Ingest())
 	if err != nil {
@@ -158,16 +197,23 @@ func Make(ctx context.Context, config *config.Config) (*State, error) {
 	}
 	spindlestream.Start(ctx)
 
+	go replayDeadLetters(ctx, logger, d, knotstream, "knotstream")
+	go replayDeadLetters(ctx, logger, d, spindlestream, "spindlestream")
+
 	var notifiers []notify.Notifier
 
 	// Always add the database notifier
 	notifiers = append(notifiers, dbnotify.NewDatabaseNotifier(d, res))
+	notifiers = append(notifiers, pcnotify.NewPunchcardNotifier(d))
 
 	// Add other notifiers in production only
 	if !config.Core.Dev {
 		notifiers = append(notifiers, phnotify.NewPosthogNotifier(posthog))
 	}
 	notifiers = append(notifiers, indexer)
+
+	webhooks := webhooks.New(d, oauth, pages, repoResolver, config, log.SubLogger(logger, "webhooks"))
+	notifiers = append(notifiers, webhooks)
 	notifier := notify.NewMergedNotifier(notifiers, tlog.SubLogger(logger, "notify"))
 
 	state := &State{
@@ -186,8 +232,34 @@ func Make(ctx context.Context, config *config.Config) (*State, error) {
 		spindlestream,
 		logger,
 		validator,
+		webhooks,
+		nil,
+		nil,
+		knothealth.New(config),
+		nil,
+		deletions.New(d, oauth, enforcer, config, log.SubLogger(logger, "deletions")),
+		ratelimit.New(appPasswordFailureEvery, appPasswordFailureBurst),
+	}
+
+	if config.AntiAbuse.UsesPow() {
+		state.pow = pow.New(config.Core.CookieSecret, config.AntiAbuse.PowDifficulty)
 	}
 
+	attachmentStore, err := attachments.NewDiskStore(config.Attachment.Dir)
+	if err != nil {
+		return nil, err
+	}
+	state.attachments = attachments.New(d, oauth, config, attachmentStore, log.SubLogger(logger, "attachments"))
+	go state.attachments.Cleanup(ctx)
+
+	state.exporter, err = exports.New(d, oauth, config, log.SubLogger(logger, "exports"))
+	if err != nil {
+		return nil, err
+	}
+	go state.exporter.Run(ctx)
+	go state.deleter.Run(ctx)
+	go state.pulls().SweepHiddenRefs(ctx)
+
 	return state, nil
 }
 
@@ -219,6 +291,22 @@ Allow: /
 	w.Write([]byte(robotsTxt))
 }
 
+// Health tells monitors how far behind the jetstream consumer is, which is
+// what keeps the appview up to date with the network.
+func (s *State) Health(w http.ResponseWriter, r *http.Request) {
+	h := s.jc.Health()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{
+		"jetstream": map[string]any{
+			"eventsPerSecond":     h.EventsPerSecond,
+			"lastEventAgeSeconds": h.LastEventAge.Seconds(),
+			"lagSeconds":          h.Lag.Seconds(),
+			"reconnects":          h.Reconnects,
+		},
+	})
+}
+
 // https://developer.mozilla.org/en-US/docs/Web/Progressive_web_apps/Manifest
 const manifestJson = `{
   "name": "tangled",
@@ -273,20 +361,36 @@ func (s *State) HomeOrTimeline(w http.ResponseWriter, r *http.Request) {
 
 func (s *State) Timeline(w http.ResponseWriter, r *http.Request) {
 	user := s.oauth.GetUser(r)
-
-	// TODO: set this flag based on the UI
-	filtered := false
+	limit := 50
 
 	var userDid string
 	if user != nil {
 		userDid = user.Did
 	}
-	timeline, err := db.MakeTimeline(s.db, 50, userDid, filtered)
+
+	// only someone logged in has people they follow
+	feed := models.TimelineGlobal
+	if user != nil && r.URL.Query().Get("feed") == string(models.TimelineFollowing) {
+		feed = models.TimelineFollowing
+	}
+
+	var before time.Time
+	if cursor := r.URL.Query().Get("before"); cursor != "" {
+		before, _ = time.Parse(time.RFC3339, cursor)
+	}
+
+	timeline, err := db.MakeTimeline(s.db, limit, userDid, feed, before)
 	if err != nil {
 		s.logger.Error("failed to make timeline", "err", err)
 		s.pages.Notice(w, "timeline", "Uh oh! Failed to load timeline.")
 	}
 
+	// a full page means there may be older events
+	var next string
+	if len(timeline) == limit {
+		next = timeline[len(timeline)-1].EventAt.UTC().Format(time.RFC3339)
+	}
+
 	repos, err := db.GetTopStarredReposLastWeek(s.db)
 	if err != nil {
 		s.logger.Error("failed to get top starred repos", "err", err)
@@ -302,6 +406,9 @@ func (s *State) Timeline(w http.ResponseWriter, r *http.Request) {
 	s.pages.Timeline(w, pages.TimelineParams{
 		LoggedInUser: user,
 		Timeline:     timeline,
+		Feed:         feed,
+		Before:       r.URL.Query().Get("before"),
+		Next:         next,
 		Repos:        repos,
 		GfiLabel:     gfiLabel,
 	})
@@ -334,21 +441,43 @@ func (s *State) UpgradeBanner(w http.ResponseWriter, r *http.Request) {
 		l.Error("non-fatal: failed to get spindles", "err", err)
 	}
 
-	if regs == nil && spindles == nil {
+	verified, err := db.GetRegistrations(
+		s.db,
+		db.FilterEq("did", user.Did),
+		db.FilterEq("needs_upgrade", 0),
+	)
+	if err != nil {
+		l.Error("non-fatal: failed to get registrations", "err", err)
+	}
+	var domains []string
+	for _, reg := range verified {
+		if reg.IsRegistered() {
+			domains = append(domains, reg.Domain)
+		}
+	}
+	var outdated []models.KnotHealth
+	for _, h := range s.knotHealth.CheckAll(r.Context(), domains) {
+		if h.NeedsUpgrade() {
+			outdated = append(outdated, h)
+		}
+	}
+	slices.SortFunc(outdated, func(a, b models.KnotHealth) int {
+		return strings.Compare(a.Domain, b.Domain)
+	})
+
+	if regs == nil && spindles == nil && outdated == nil {
 		return
 	}
 
 	s.pages.UpgradeBanner(w, pages.UpgradeBannerParams{
 		Registrations: regs,
 		Spindles:      spindles,
+		OutdatedKnots: outdated,
 	})
 }
 
 func (s *State) Home(w http.ResponseWriter, r *http.Request) {
-	// TODO: set this flag based on the UI
-	filtered := false
-
-	timeline, err := db.MakeTimeline(s.db, 5, "", filtered)
+	timeline, err := db.MakeTimeline(s.db, 5, "", models.TimelineGlobal, time.Time{})
 	if err != nil {
 		s.logger.Error("failed to make timeline", "err", err)
 		s.pages.Notice(w, "timeline", "Uh oh! Failed to load timeline.")
@@ -365,6 +494,7 @@ func (s *State) Home(w http.ResponseWriter, r *http.Request) {
 	s.pages.Home(w, pages.TimelineParams{
 		LoggedInUser: nil,
 		Timeline:     timeline,
+		Feed:         models.TimelineGlobal,
 		Repos:        repos,
 	})
 }
@@ -402,6 +532,83 @@ func (s *State) Keys(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// a caller gets this many wrong app passwords, and then one more every so
+// often
+const (
+	appPasswordFailureEvery = 10 * time.Second
+	appPasswordFailureBurst = 30
+)
+
+// VerifyAppPassword tells knots who an app password belongs to, and what it
+// may be used for. Passwords are only good on the knots they were made for,
+// the knot asking names itself in the request.
+func (s *State) VerifyAppPassword(w http.ResponseWriter, r *http.Request) {
+	addr := clientAddr(r)
+	if s.appPasswordFailures.Exhausted(addr, time.Now()) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	var req serviceauth.VerifyAppPasswordRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	unauthorized := func() {
+		s.appPasswordFailures.Allow(addr, time.Now())
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	if !serviceauth.IsAppPassword(req.Password) {
+		unauthorized()
+		return
+	}
+
+	password, err := db.GetAppPassword(s.db, db.FilterEq("password_hash", models.HashAppPassword(req.Password)))
+	if errors.Is(err, sql.ErrNoRows) {
+		unauthorized()
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to get app password", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !password.AllowsKnot(req.Knot) {
+		s.logger.Warn("app password used on another knot", "did", password.Did, "name", password.Name, "knot", req.Knot)
+		unauthorized()
+		return
+	}
+
+	if err := db.TouchAppPassword(s.db, password.Id); err != nil {
+		s.logger.Error("failed to update app password last use", "err", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serviceauth.VerifyAppPasswordResponse{
+		Did:    password.Did,
+		Scopes: password.Scopes,
+	})
+}
+
+// clientAddr is the address a request came from, as told by the proxy in
+// front of the appview if there is one. The proxy appends to
+// X-Forwarded-For, so only its last entry can't be made up by the client.
+func clientAddr(r *http.Request) string {
+	if ip := r.Header.Get("CF-Connecting-IP"); ip != "" {
+		return ip
+	}
+	if ips := r.Header.Get("X-Forwarded-For"); ips != "" {
+		return strings.TrimSpace(ips[strings.LastIndex(ips, ",")+1:])
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
 func validateRepoName(name string) error {
 	// check for path traversal attempts
 	if name == "." || name == ".." ||
@@ -438,6 +645,18 @@ func stripGitExt(name string) string {
 	return strings.TrimSuffix(name, ".git")
 }
 
+// defaultBranch is the branch that new repos of did start with.
+func (s *State) defaultBranch(did string) string {
+	branch, err := db.GetDefaultBranch(s.db, did)
+	if err != nil {
+		s.logger.Error("failed to get default branch", "did", did, "err", err)
+	}
+	if branch == "" {
+		branch = s.config.Core.DefaultBranch
+	}
+	return branch
+}
+
 func (s *State) NewRepo(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -448,9 +667,21 @@ func (s *State) NewRepo(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		var challenge *pow.Challenge
+		if s.pow != nil {
+			challenge, err = s.pow.Challenge()
+			if err != nil {
+				s.logger.Error("failed to issue challenge", "err", err)
+				s.pages.Error503(w)
+				return
+			}
+		}
+
 		s.pages.NewRepo(w, pages.NewRepoParams{
-			LoggedInUser: user,
-			Knots:        knots,
+			LoggedInUser:  user,
+			Knots:         knots,
+			Pow:           challenge,
+			DefaultBranch: s.defaultBranch(user.Did),
 		})
 
 	case http.MethodPost:
@@ -480,14 +711,28 @@ func (s *State) NewRepo(w http.ResponseWriter, r *http.Request) {
 		repoName = stripGitExt(repoName)
 		l = l.With("repoName", repoName)
 
-		defaultBranch := r.FormValue("branch")
+		defaultBranch := strings.TrimSpace(r.FormValue("branch"))
 		if defaultBranch == "" {
-			defaultBranch = "main"
+			defaultBranch = s.defaultBranch(user.Did)
+		}
+		if err := s.validator.ValidateBranchName(defaultBranch); err != nil {
+			s.pages.Notice(w, "repo", err.Error())
+			return
 		}
 		l = l.With("defaultBranch", defaultBranch)
 
 		description := r.FormValue("description")
 
+		visibility := models.RepoVisibility(r.FormValue("visibility"))
+		switch visibility {
+		case "":
+			visibility = models.RepoVisibilityPublic
+		case models.RepoVisibilityPublic, models.RepoVisibilityPrivate:
+		default:
+			s.pages.Notice(w, "repo", "Invalid repository visibility.")
+			return
+		}
+
 		// ACL validation
 		ok, err := s.enforcer.E.Enforce(user.Did, domain, domain, "repo:create")
 		if err != nil || !ok {
@@ -508,6 +753,15 @@ func (s *State) NewRepo(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// checked last, as each challenge can only be used once
+		if s.pow != nil {
+			if err := s.pow.Verify(r.FormValue("pow-challenge"), r.FormValue("pow-nonce")); err != nil {
+				l.Warn("proof-of-work failed", "err", err)
+				s.pages.Notice(w, "repo", "Verification failed, reload the page and try again.")
+				return
+			}
+		}
+
 		// create atproto record for this repo
 		rkey := tid.TID()
 		repo := &models.Repo{
@@ -518,6 +772,7 @@ func (s *State) NewRepo(w http.ResponseWriter, r *http.Request) {
 			Description: description,
 			Created:     time.Now(),
 			Labels:      s.config.Label.DefaultLabelDefs,
+			Visibility:  visibility,
 		}
 		record := repo.AsRecord()
 
@@ -578,6 +833,7 @@ func (s *State) NewRepo(w http.ResponseWriter, r *http.Request) {
 			r,
 			oauth.WithService(domain),
 			oauth.WithLxm(tangled.RepoCreateNSID),
+			oauth.WithoutRetry(),
 			oauth.WithDev(s.config.Core.Dev),
 		)
 		if err != nil {
@@ -0,0 +1,261 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/tid"
+)
+
+// CreateFromTemplate creates a new repo for the user out of a template
+// repo. The knot seeds it with a single commit holding the template's tree,
+// so the new repo has a history and a record of its own, and is not a fork.
+func (s *State) CreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "CreateFromTemplate")
+	user := s.oauth.GetUser(r)
+	l = l.With("did", user.Did)
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve template repo", "err", err)
+		s.pages.Error404(w)
+		return
+	}
+
+	// knots fetch templates anonymously, they can't read private repos
+	if !f.IsTemplate || f.IsPrivate() {
+		s.pages.Error404(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		knots, err := s.enforcer.GetKnotsForUser(user.Did)
+		if err != nil {
+			s.pages.Notice(w, "repo", "Invalid user account.")
+			return
+		}
+
+		s.pages.RepoTemplate(w, pages.RepoTemplateParams{
+			LoggedInUser:  user,
+			Knots:         knots,
+			RepoInfo:      f.RepoInfo(user),
+			DefaultBranch: s.defaultBranch(user.Did),
+		})
+
+	case http.MethodPost:
+		domain := r.FormValue("knot")
+		if domain == "" {
+			s.pages.Notice(w, "repo", "Invalid form submission&mdash;missing knot domain.")
+			return
+		}
+		l = l.With("knot", domain)
+
+		repoName := r.FormValue("name")
+		if repoName == "" {
+			s.pages.Notice(w, "repo", "Repository name cannot be empty.")
+			return
+		}
+		if err := validateRepoName(repoName); err != nil {
+			s.pages.Notice(w, "repo", err.Error())
+			return
+		}
+		repoName = stripGitExt(repoName)
+		l = l.With("repoName", repoName)
+
+		defaultBranch := strings.TrimSpace(r.FormValue("branch"))
+		if defaultBranch == "" {
+			defaultBranch = s.defaultBranch(user.Did)
+		}
+		if err := s.validator.ValidateBranchName(defaultBranch); err != nil {
+			s.pages.Notice(w, "repo", err.Error())
+			return
+		}
+
+		visibility := models.RepoVisibility(r.FormValue("visibility"))
+		switch visibility {
+		case "":
+			visibility = models.RepoVisibilityPublic
+		case models.RepoVisibilityPublic, models.RepoVisibilityPrivate:
+		default:
+			s.pages.Notice(w, "repo", "Invalid repository visibility.")
+			return
+		}
+
+		ok, err := s.enforcer.E.Enforce(user.Did, domain, domain, "repo:create")
+		if err != nil || !ok {
+			l.Info("unauthorized")
+			s.pages.Notice(w, "repo", "You do not have permission to create a repo in this knot.")
+			return
+		}
+
+		existingRepo, err := db.GetRepo(
+			s.db,
+			db.FilterEq("did", user.Did),
+			db.FilterEq("name", repoName),
+		)
+		if err == nil && existingRepo != nil {
+			s.pages.Notice(w, "repo", fmt.Sprintf("You already have a repository by this name on %s", existingRepo.Knot))
+			return
+		}
+
+		scheme := "https"
+		if s.config.Core.Dev {
+			scheme = "http"
+		}
+		templateUrl := fmt.Sprintf("%s://%s/%s/%s", scheme, f.Knot, f.OwnerDid(), f.Name)
+		l = l.With("templateUrl", templateUrl)
+
+		// the initial commit is authored by whoever starts the repo
+		authorName := user.Did
+		if ident, err := s.idResolver.ResolveIdent(r.Context(), user.Did); err == nil {
+			authorName = ident.Handle.String()
+		}
+		email, err := db.GetPrimaryEmail(s.db, user.Did)
+		if err != nil {
+			l.Warn("failed to get primary email", "err", err)
+		}
+
+		rkey := tid.TID()
+		repo := &models.Repo{
+			Did:         user.Did,
+			Name:        repoName,
+			Knot:        domain,
+			Rkey:        rkey,
+			Description: r.FormValue("description"),
+			Created:     time.Now(),
+			Labels:      s.config.Label.DefaultLabelDefs,
+			Visibility:  visibility,
+		}
+		record := repo.AsRecord()
+
+		atpClient, err := s.oauth.AuthorizedClient(r)
+		if err != nil {
+			l.Info("PDS write failed", "err", err)
+			s.pages.Notice(w, "repo", "Failed to write record to PDS.")
+			return
+		}
+
+		atresp, err := comatproto.RepoPutRecord(r.Context(), atpClient, &comatproto.RepoPutRecord_Input{
+			Collection: tangled.RepoNSID,
+			Repo:       user.Did,
+			Rkey:       rkey,
+			Record: &lexutil.LexiconTypeDecoder{
+				Val: &record,
+			},
+		})
+		if err != nil {
+			l.Info("PDS write failed", "err", err)
+			s.pages.Notice(w, "repo", "Failed to announce repository creation.")
+			return
+		}
+
+		aturi := atresp.Uri
+		l = l.With("aturi", aturi)
+
+		tx, err := s.db.BeginTx(r.Context(), nil)
+		if err != nil {
+			l.Info("txn failed", "err", err)
+			s.pages.Notice(w, "repo", "Failed to save repository information.")
+			return
+		}
+
+		// same as NewRepo, undo the txn, the ACLs and the record on failure
+		rollback := func() {
+			err1 := tx.Rollback()
+			err2 := s.enforcer.E.LoadPolicy()
+			err3 := rollbackRecord(context.Background(), aturi, atpClient)
+
+			// ignore txn complete errors, this is okay
+			if errors.Is(err1, sql.ErrTxDone) {
+				err1 = nil
+			}
+
+			if errs := errors.Join(err1, err2, err3); errs != nil {
+				l.Error("failed to rollback changes", "errs", errs)
+				return
+			}
+		}
+		defer rollback()
+
+		client, err := s.oauth.ServiceClient(
+			r,
+			oauth.WithService(domain),
+			oauth.WithLxm(tangled.RepoCreateFromTemplateNSID),
+			oauth.WithoutRetry(),
+			oauth.WithDev(s.config.Core.Dev),
+			oauth.WithTimeout(time.Second*20), // big templates take time to fetch
+		)
+		if err != nil {
+			l.Error("service auth failed", "err", err)
+			s.pages.Notice(w, "repo", "Failed to connect to knot server.")
+			return
+		}
+
+		input := &tangled.RepoCreateFromTemplate_Input{
+			Rkey:          rkey,
+			Source:        templateUrl,
+			DefaultBranch: &defaultBranch,
+			AuthorName:    &authorName,
+		}
+		if email.Address != "" {
+			input.AuthorEmail = &email.Address
+		}
+
+		xe := tangled.RepoCreateFromTemplate(r.Context(), client, input)
+		if err := xrpcclient.HandleXrpcErr(xe); err != nil {
+			l.Error("xrpc error", "xe", xe)
+			s.pages.Notice(w, "repo", err.Error())
+			return
+		}
+
+		err = db.AddRepo(tx, repo)
+		if err != nil {
+			l.Error("db write failed", "err", err)
+			s.pages.Notice(w, "repo", "Failed to save repository information.")
+			return
+		}
+
+		p, _ := securejoin.SecureJoin(user.Did, repoName)
+		err = s.enforcer.AddRepo(user.Did, domain, p)
+		if err != nil {
+			l.Error("acl setup failed", "err", err)
+			s.pages.Notice(w, "repo", "Failed to set up repository permissions.")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			l.Error("txn commit failed", "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = s.enforcer.E.SavePolicy()
+		if err != nil {
+			l.Error("acl save failed", "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// reset the ATURI because the transaction completed successfully
+		aturi = ""
+
+		s.notifier.NewRepo(r.Context(), repo)
+		s.pages.HxLocation(w, fmt.Sprintf("/%s/%s", user.Did, repoName))
+	}
+}
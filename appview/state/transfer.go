@@ -0,0 +1,407 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	atpclient "github.com/bluesky-social/indigo/atproto/client"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	xrpcclient "tangled.org/core/appview/xrpcclient"
+	"tangled.org/core/tid"
+)
+
+// TransferPage shows a pending transfer to both of its sides, the new owner
+// accepts or declines it from here.
+func (s *State) TransferPage(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "TransferPage")
+	user := s.oauth.GetUser(r)
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		s.pages.Error404(w)
+		return
+	}
+
+	transfer, err := db.GetRepoTransfer(s.db, db.FilterEq("repo_at", f.RepoAt()))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		transfer = nil
+	case err != nil:
+		l.Error("failed to get transfer", "err", err)
+		s.pages.Error503(w)
+		return
+	case user.Did != transfer.FromDid.String() && user.Did != transfer.ToDid.String():
+		transfer = nil
+	}
+
+	s.pages.RepoTransfer(w, pages.RepoTransferParams{
+		LoggedInUser: user,
+		RepoInfo:     f.RepoInfo(user),
+		Transfer:     transfer,
+	})
+}
+
+// TransferRepo offers a repo to another user. The offer is recorded in the
+// repo record, which is what the knot checks once the new owner accepts.
+func (s *State) TransferRepo(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "TransferRepo")
+	user := s.oauth.GetUser(r)
+	noticeId := "transfer-error"
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		return
+	}
+	l = l.With("repo", f.DidSlashRepo())
+
+	fail := func(msg string, err error) {
+		l.Error(msg, "err", err)
+		s.pages.Notice(w, noticeId, msg)
+	}
+
+	if !f.RolesInRepo(user).IsOwner() {
+		fail("Only the owner can transfer a repository.", nil)
+		return
+	}
+
+	if err := s.oauth.ConfirmAction(r, f.Name); err != nil {
+		fail(err.Error(), nil)
+		return
+	}
+
+	recipient := r.FormValue("recipient")
+	if recipient == "" {
+		fail("Invalid form.", nil)
+		return
+	}
+
+	// remove a single leading `@`, to make @handle work with ResolveIdent
+	recipient = strings.TrimPrefix(recipient, "@")
+
+	recipientIdent, err := s.idResolver.ResolveIdent(r.Context(), recipient)
+	if err != nil {
+		fail(fmt.Sprintf("'%s' is not a valid DID/handle.", recipient), err)
+		return
+	}
+	if recipientIdent.DID.String() == user.Did {
+		fail("You already own this repository.", nil)
+		return
+	}
+	l = l.With("to", recipientIdent.DID)
+
+	// the repo stays where it is, so its new owner has to be allowed there
+	ok, err := s.enforcer.E.Enforce(recipientIdent.DID.String(), f.Knot, f.Knot, "repo:create")
+	if err != nil || !ok {
+		fail(fmt.Sprintf("%s is not a member of %s.", recipient, f.Knot), err)
+		return
+	}
+
+	_, err = db.GetRepo(s.db, db.FilterEq("did", recipientIdent.DID), db.FilterEq("name", f.Name))
+	if err == nil {
+		fail(fmt.Sprintf("%s already has a repository named %s.", recipient, f.Name), nil)
+		return
+	}
+
+	_, err = db.GetRepoTransfer(s.db, db.FilterEq("repo_at", f.RepoAt()))
+	if err == nil {
+		fail("This repository is already being transferred.", nil)
+		return
+	}
+
+	client, err := s.oauth.AuthorizedClient(r)
+	if err != nil {
+		fail("Failed to write record to PDS.", err)
+		return
+	}
+
+	offered := f.Repo
+	offered.TransferTo = recipientIdent.DID.String()
+	if err := putRepoRecord(r.Context(), client, &offered); err != nil {
+		fail("Failed to write record to PDS.", err)
+		return
+	}
+
+	transfer := &models.RepoTransfer{
+		RepoAt:  f.RepoAt(),
+		FromDid: syntax.DID(user.Did),
+		ToDid:   recipientIdent.DID,
+	}
+	if err := db.AddRepoTransfer(s.db, transfer); err != nil {
+		fail("Failed to transfer repository.", err)
+
+		// take back the offer
+		if err := putRepoRecord(context.Background(), client, &f.Repo); err != nil {
+			l.Error("failed to rollback record", "err", err)
+		}
+		return
+	}
+	l.Info("offered repo")
+
+	s.notifier.NewRepoTransfer(r.Context(), &f.Repo, transfer)
+	s.pages.HxRefresh(w)
+}
+
+// CancelTransfer takes back an offer, whether or not it was declined.
+func (s *State) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "CancelTransfer")
+	user := s.oauth.GetUser(r)
+	noticeId := "transfer-error"
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		return
+	}
+
+	if !f.RolesInRepo(user).IsOwner() {
+		s.pages.Notice(w, noticeId, "Only the owner can cancel a transfer.")
+		return
+	}
+
+	client, err := s.oauth.AuthorizedClient(r)
+	if err != nil {
+		l.Error("failed to get authorized client", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to write record to PDS.")
+		return
+	}
+
+	kept := f.Repo
+	kept.TransferTo = ""
+	if err := putRepoRecord(r.Context(), client, &kept); err != nil {
+		l.Error("failed to update record", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to write record to PDS.")
+		return
+	}
+
+	if err := db.DeleteRepoTransfer(s.db, db.FilterEq("repo_at", f.RepoAt())); err != nil {
+		l.Error("failed to delete transfer", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to cancel transfer.")
+		return
+	}
+
+	s.pages.HxRefresh(w)
+}
+
+// DeclineTransfer turns down an offer. It stays around until the owner
+// cancels it, as only they can take it out of their repo record.
+func (s *State) DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "DeclineTransfer")
+	user := s.oauth.GetUser(r)
+	noticeId := "transfer-error"
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		return
+	}
+
+	transfer, err := db.GetRepoTransfer(
+		s.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("to_did", user.Did),
+		db.FilterEq("status", models.RepoTransferPending),
+	)
+	if err != nil {
+		l.Error("failed to get transfer", "err", err)
+		s.pages.Notice(w, noticeId, "There is no transfer for you to decline.")
+		return
+	}
+
+	if err := db.SetRepoTransferStatus(s.db, transfer.Id, models.RepoTransferDeclined); err != nil {
+		l.Error("failed to decline transfer", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to decline transfer.")
+		return
+	}
+
+	s.pages.HxRefresh(w)
+}
+
+// AcceptTransfer completes a transfer: the new owner gets their own record
+// for the repo, the knot moves it over, and everything that referred to the
+// previous record is pointed at the new one. The previous record stays
+// behind, naming the new owner.
+func (s *State) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "AcceptTransfer")
+	user := s.oauth.GetUser(r)
+	noticeId := "transfer-error"
+
+	f, err := s.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to resolve repo", "err", err)
+		return
+	}
+	l = l.With("repo", f.DidSlashRepo(), "to", user.Did)
+
+	_, err = db.GetRepoTransfer(
+		s.db,
+		db.FilterEq("repo_at", f.RepoAt()),
+		db.FilterEq("to_did", user.Did),
+		db.FilterEq("status", models.RepoTransferPending),
+	)
+	if err != nil {
+		l.Error("failed to get transfer", "err", err)
+		s.pages.Notice(w, noticeId, "There is no transfer for you to accept.")
+		return
+	}
+
+	_, err = db.GetRepo(s.db, db.FilterEq("did", user.Did), db.FilterEq("name", f.Name))
+	if err == nil {
+		s.pages.Notice(w, noticeId, fmt.Sprintf("You already have a repository named %s.", f.Name))
+		return
+	}
+
+	atpClient, err := s.oauth.AuthorizedClient(r)
+	if err != nil {
+		l.Error("failed to get authorized client", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to write record to PDS.")
+		return
+	}
+
+	accepted := f.Repo
+	accepted.Did = user.Did
+	accepted.Rkey = tid.TID()
+	accepted.TransferTo = ""
+	record := accepted.AsRecord()
+
+	atresp, err := comatproto.RepoPutRecord(r.Context(), atpClient, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoNSID,
+		Repo:       accepted.Did,
+		Rkey:       accepted.Rkey,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &record,
+		},
+	})
+	if err != nil {
+		l.Error("failed to write record", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to write record to PDS.")
+		return
+	}
+
+	aturi := atresp.Uri
+	l = l.With("aturi", aturi)
+	l.Info("wrote to PDS")
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		l.Error("txn failed", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to save repository information.")
+		return
+	}
+
+	// The rollback function reverts a few things on failure:
+	// - the pending txn
+	// - the ACLs
+	// - the atproto record created
+	rollback := func() {
+		err1 := tx.Rollback()
+		err2 := s.enforcer.E.LoadPolicy()
+		err3 := rollbackRecord(context.Background(), aturi, atpClient)
+
+		// ignore txn complete errors, this is okay
+		if errors.Is(err1, sql.ErrTxDone) {
+			err1 = nil
+		}
+
+		if errs := errors.Join(err1, err2, err3); errs != nil {
+			l.Error("failed to rollback changes", "errs", errs)
+			return
+		}
+	}
+	defer rollback()
+
+	err = db.TransferRepo(tx, &f.Repo, accepted.Did, accepted.Rkey)
+	if err != nil {
+		l.Error("db write failed", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to save repository information.")
+		return
+	}
+
+	err = s.enforcer.TransferRepo(accepted.Did, f.Knot, f.DidSlashRepo(), accepted.DidSlashRepo())
+	if err != nil {
+		l.Error("acl update failed", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to update repository permissions.")
+		return
+	}
+
+	client, err := s.oauth.ServiceClient(
+		r,
+		oauth.WithService(f.Knot),
+		oauth.WithLxm(tangled.RepoTransferNSID),
+		oauth.WithoutRetry(),
+		oauth.WithDev(s.config.Core.Dev),
+	)
+	if err != nil {
+		l.Error("service auth failed", "err", err)
+		s.pages.Notice(w, noticeId, "Failed to reach PDS.")
+		return
+	}
+
+	xe := tangled.RepoTransfer(
+		r.Context(),
+		client,
+		&tangled.RepoTransfer_Input{
+			Did:     f.Did,
+			Name:    f.Name,
+			Rkey:    f.Rkey,
+			NewRkey: accepted.Rkey,
+		},
+	)
+	if err := xrpcclient.HandleXrpcErr(xe); err != nil {
+		l.Error("xrpc error", "xe", xe)
+		s.pages.Notice(w, noticeId, err.Error())
+		return
+	}
+	l.Info("transferred repo on knot")
+
+	err = tx.Commit()
+	if err != nil {
+		l.Error("txn commit failed", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.enforcer.E.SavePolicy()
+	if err != nil {
+		l.Error("acl save failed", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// reset the ATURI because the transaction completed successfully
+	aturi = ""
+
+	s.pages.HxLocation(w, fmt.Sprintf("/%s/%s", accepted.Did, accepted.Name))
+}
+
+// putRepoRecord rewrites the record of repo on its owner's PDS.
+func putRepoRecord(ctx context.Context, client *atpclient.APIClient, repo *models.Repo) error {
+	ex, err := comatproto.RepoGetRecord(ctx, client, "", tangled.RepoNSID, repo.Did, repo.Rkey)
+	if err != nil {
+		return err
+	}
+
+	record := repo.AsRecord()
+	_, err = comatproto.RepoPutRecord(ctx, client, &comatproto.RepoPutRecord_Input{
+		Collection: tangled.RepoNSID,
+		Repo:       repo.Did,
+		Rkey:       repo.Rkey,
+		SwapRecord: ex.Cid,
+		Record: &lexutil.LexiconTypeDecoder{
+			Val: &record,
+		},
+	})
+	return err
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/tid"
+)
+
+// Watch subscribes the user to every new issue and pull in a repo, or stops
+// doing so, and answers with the button in its new state. Like starring,
+// both are idempotent.
+func (s *State) Watch(w http.ResponseWriter, r *http.Request) {
+	l := s.logger.With("handler", "Watch")
+	currentUser := s.oauth.GetUser(r)
+
+	subject := r.URL.Query().Get("subject")
+	if subject == "" {
+		l.Error("invalid form")
+		return
+	}
+
+	subjectUri, err := syntax.ParseATURI(subject)
+	if err != nil {
+		l.Error("invalid form")
+		return
+	}
+
+	client, err := s.oauth.AuthorizedClient(r)
+	if err != nil {
+		l.Error("failed to authorize client", "err", err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		// watching already, from another tab
+		_, err := db.GetWatch(s.db, currentUser.Did, subjectUri)
+		if err == nil {
+			s.watchBtn(w, subjectUri, true)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			l.Error("failed to get watch relationship", "err", err)
+			return
+		}
+
+		createdAt := time.Now().Format(time.RFC3339)
+		rkey := tid.TID()
+		resp, err := comatproto.RepoPutRecord(r.Context(), client, &comatproto.RepoPutRecord_Input{
+			Collection: tangled.GraphWatchNSID,
+			Repo:       currentUser.Did,
+			Rkey:       rkey,
+			Record: &lexutil.LexiconTypeDecoder{
+				Val: &tangled.GraphWatch{
+					Subject:   subjectUri.String(),
+					CreatedAt: createdAt,
+				}},
+		})
+		if err != nil {
+			l.Error("failed to create atproto record", "err", err)
+			return
+		}
+		l.Info("created atproto record", "uri", resp.Uri)
+
+		err = db.AddWatch(s.db, &models.Watch{
+			Did:    currentUser.Did,
+			RepoAt: subjectUri,
+			Rkey:   rkey,
+		})
+		if err != nil {
+			l.Error("failed to watch", "err", err)
+			return
+		}
+
+		s.watchBtn(w, subjectUri, true)
+		return
+	case http.MethodDelete:
+		watches, err := db.GetWatches(
+			s.db,
+			db.FilterEq("did", currentUser.Did),
+			db.FilterEq("subject_at", subjectUri),
+		)
+		if err != nil {
+			l.Error("failed to get watch relationship", "err", err)
+			return
+		}
+
+		for _, watch := range watches {
+			_, err = comatproto.RepoDeleteRecord(r.Context(), client, &comatproto.RepoDeleteRecord_Input{
+				Collection: tangled.GraphWatchNSID,
+				Repo:       currentUser.Did,
+				Rkey:       watch.Rkey,
+			})
+			if err != nil {
+				l.Error("failed to unwatch")
+				return
+			}
+		}
+
+		// the firehose event might have already done this
+		if err := db.DeleteWatch(s.db, currentUser.Did, subjectUri); err != nil {
+			l.Error("failed to delete watch from DB")
+			return
+		}
+
+		s.watchBtn(w, subjectUri, false)
+		return
+	}
+}
+
+func (s *State) watchBtn(w http.ResponseWriter, subjectUri syntax.ATURI, isWatching bool) {
+	s.pages.WatchBtnFragment(w, pages.WatchBtnFragmentParams{
+		IsWatching: isWatching,
+		SubjectAt:  subjectUri,
+	})
+}
@@ -0,0 +1,121 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size in bytes of the key secrets are sealed with.
+const KeySize = 32
+
+var ErrInvalidKey = errors.New("totp key must be 32 bytes")
+
+// sealed secrets carry this prefix, secrets stored before they were sealed
+// are plain base32 and never do
+const sealedPrefix = "sealed:"
+
+// LoadOrCreateKey reads the key at path, generating and persisting a fresh
+// one if the file does not exist yet.
+func LoadOrCreateKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%s: %w", path, ErrInvalidKey)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read totp key: %w", err)
+	}
+
+	key = make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate totp key: %w", err)
+	}
+
+	// O_EXCL so that we never clobber a key that appeared in the meantime
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create totp key: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(key); err != nil {
+		return nil, fmt.Errorf("failed to write totp key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Sealer encrypts secrets before they are stored, so that a copy of the
+// database alone does not give away the codes of its users.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+func NewSealer(key []byte) (*Sealer, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts the secret of did. The did is bound to the result, so that
+// it can't be moved to another user by editing the database.
+func (s *Sealer) Seal(did, secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), []byte(did))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret of did sealed with Seal. Secrets stored before they
+// were sealed are returned as they are.
+func (s *Sealer) Open(did, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return stored, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode totp secret: %w", err)
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return "", errors.New("totp secret is truncated")
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, []byte(did))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+// IsSealed reports whether stored was sealed, rather than stored before
+// secrets were.
+func IsSealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedPrefix)
+}
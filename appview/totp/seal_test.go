@@ -0,0 +1,41 @@
+package totp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealer(t *testing.T) {
+	s, err := NewSealer(bytes.Repeat([]byte{1}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := s.Seal("did:plc:alice", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || sealed == secret {
+		t.Fatalf("expected a sealed secret, got %q", sealed)
+	}
+
+	opened, err := s.Open("did:plc:alice", sealed)
+	if err != nil || opened != secret {
+		t.Fatalf("Open() = %q, %v, want %q", opened, err, secret)
+	}
+
+	// a secret moved to another user doesn't open
+	if _, err := s.Open("did:plc:mallory", sealed); err == nil {
+		t.Fatal("expected a secret sealed for someone else to be refused")
+	}
+
+	// secrets stored before sealing are passed through
+	if opened, err := s.Open("did:plc:alice", secret); err != nil || opened != secret {
+		t.Fatalf("Open() = %q, %v, want %q", opened, err, secret)
+	}
+}
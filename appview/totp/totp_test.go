@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	// the SHA1 test vectors of RFC 6238, which are eight digits long
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(secret, Step(time.Unix(tt.time, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.time, code, tt.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if step, ok := Validate(secret, code, now.Add(Period)); !ok || step != Step(now) {
+		t.Errorf("code from the previous step was refused")
+	}
+
+	if _, ok := Validate(secret, code, now.Add(3*Period)); ok {
+		t.Errorf("code from three steps ago was accepted")
+	}
+
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Errorf("short code was accepted")
+	}
+}
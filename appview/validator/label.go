@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"golang.org/x/exp/slices"
@@ -16,7 +17,7 @@ var (
 	// Label name should be alphanumeric with hyphens/underscores, but not start/end with them
 	labelNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_-]*[a-zA-Z0-9])?$`)
 	// Color should be a valid hex color
-	colorRegex = regexp.MustCompile(`^#[a-fA-F0-9]{6}$`)
+	colorRegex = regexp.MustCompile(`^#([a-fA-F0-9]{3}|[a-fA-F0-9]{6})$`)
 	// You can only label issues and pulls presently
 	validScopes = []string{tangled.RepoIssueNSID, tangled.RepoPullNSID}
 )
@@ -35,6 +36,17 @@ func (v *Validator) ValidateLabelDefinition(label *models.LabelDefinition) error
 		return fmt.Errorf("label name contains invalid characters (use only letters, numbers, hyphens, and underscores)")
 	}
 
+	if label.Description != nil {
+		description := strings.TrimSpace(*label.Description)
+		if description == "" {
+			label.Description = nil
+		} else if utf8.RuneCountInString(description) > 256 {
+			return fmt.Errorf("label description too long (max 256 graphemes)")
+		} else {
+			label.Description = &description
+		}
+	}
+
 	if !label.ValueType.IsConcreteType() {
 		return fmt.Errorf("invalid value type: %q (must be one of: null, boolean, integer, string)", label.ValueType.Type)
 	}
@@ -106,10 +118,8 @@ func (v *Validator) ValidateLabelOp(labelDef *models.LabelDefinition, repo *mode
 		return fmt.Errorf("label operation is required")
 	}
 
-	// validate permissions: only collaborators can apply labels currently
-	//
-	// TODO: introduce a repo:triage permission
-	ok, err := v.enforcer.IsPushAllowed(labelOp.Did, repo.Knot, repo.DidSlashRepo())
+	// validate permissions: only triagers can apply labels
+	ok, err := v.enforcer.IsTriageAllowed(labelOp.Did, repo.Knot, repo.DidSlashRepo())
 	if err != nil {
 		return fmt.Errorf("failed to enforce permissions: %w", err)
 	}
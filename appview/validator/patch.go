@@ -17,9 +17,33 @@ func (v *Validator) ValidatePatch(patch *string) error {
 		*patch = *patch + "\n"
 	}
 
+	// checked before parsing, which is what large patches make expensive
+	if limit := v.patch.MaxBytes; limit > 0 && len(*patch) > limit {
+		return fmt.Errorf("patch is too large: %d bytes, at most %d are allowed", len(*patch), limit)
+	}
+
 	if err := patchutil.IsPatchValid(*patch); err != nil {
 		return err
 	}
 
+	if v.patch.MaxFiles > 0 || v.patch.MaxHunks > 0 {
+		files, err := patchutil.AsDiff(*patch)
+		if err != nil {
+			return fmt.Errorf("%w: %w", patchutil.GenericPatchError, err)
+		}
+
+		if limit := v.patch.MaxFiles; limit > 0 && len(files) > limit {
+			return fmt.Errorf("patch changes too many files: %d, at most %d are allowed", len(files), limit)
+		}
+
+		hunks := 0
+		for _, f := range files {
+			hunks += len(f.TextFragments)
+		}
+		if limit := v.patch.MaxHunks; limit > 0 && hunks > limit {
+			return fmt.Errorf("patch has too many hunks: %d, at most %d are allowed", hunks, limit)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"tangled.org/core/appview/config"
+)
+
+// testPatch is a diff that changes files, each in hunks hunks.
+func testPatch(files, hunks int) string {
+	var b strings.Builder
+	for f := range files {
+		fmt.Fprintf(&b, "diff --git a/file%d b/file%d\n", f, f)
+		fmt.Fprintf(&b, "--- a/file%d\n+++ b/file%d\n", f, f)
+		for h := range hunks {
+			line := h*10 + 1
+			fmt.Fprintf(&b, "@@ -%d +%d @@\n-old\n+new\n", line, line)
+		}
+	}
+	return b.String()
+}
+
+func TestValidatePatch(t *testing.T) {
+	small := testPatch(1, 1)
+
+	tests := []struct {
+		name    string
+		limits  config.PatchConfig
+		patch   string
+		wantErr string
+	}{
+		{"no limits", config.PatchConfig{}, testPatch(3, 3), ""},
+		{"at the byte limit", config.PatchConfig{MaxBytes: len(small)}, small, ""},
+		{"over the byte limit", config.PatchConfig{MaxBytes: len(small) - 1}, small, "too large"},
+		{"at the file limit", config.PatchConfig{MaxFiles: 2}, testPatch(2, 1), ""},
+		{"over the file limit", config.PatchConfig{MaxFiles: 2}, testPatch(3, 1), "too many files"},
+		{"at the hunk limit", config.PatchConfig{MaxHunks: 4}, testPatch(2, 2), ""},
+		{"over the hunk limit", config.PatchConfig{MaxHunks: 4}, testPatch(1, 5), "too many hunks"},
+		{"zero turns limits off", config.PatchConfig{MaxBytes: 0, MaxFiles: 0, MaxHunks: 0}, testPatch(50, 50), ""},
+
+		// an oversized patch is turned away before it is parsed, even if it
+		// would not parse at all
+		{"size before parsing", config.PatchConfig{MaxBytes: 10}, "not a patch at all\n", "too large"},
+		{"invalid", config.PatchConfig{}, "not a patch at all\n", "patch"},
+		{"empty", config.PatchConfig{}, "", "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Validator{patch: tt.limits}
+			patch := tt.patch
+			err := v.ValidatePatch(&patch)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
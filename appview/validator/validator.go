@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/db"
 	"tangled.org/core/appview/pages/markup"
 	"tangled.org/core/idresolver"
@@ -12,13 +13,15 @@ type Validator struct {
 	sanitizer markup.Sanitizer
 	resolver  *idresolver.Resolver
 	enforcer  *rbac.Enforcer
+	patch     config.PatchConfig
 }
 
-func New(db *db.DB, res *idresolver.Resolver, enforcer *rbac.Enforcer) *Validator {
+func New(db *db.DB, res *idresolver.Resolver, enforcer *rbac.Enforcer, patch config.PatchConfig) *Validator {
 	return &Validator{
 		db:        db,
 		sanitizer: markup.NewSanitizer(),
 		resolver:  res,
 		enforcer:  enforcer,
+		patch:     patch,
 	}
 }
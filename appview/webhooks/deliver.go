@@ -0,0 +1,119 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventHeader     = "X-Tangled-Event"
+	DeliveryHeader  = "X-Tangled-Delivery"
+	SignatureHeader = "X-Tangled-Signature-256"
+)
+
+// Sign returns the signature of payload under secret, as sent in the
+// X-Tangled-Signature-256 header.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver sends payload to hook, signed with the hook's current secret, and
+// records the attempt. Failures are recorded on the delivery rather than
+// returned; the error is only for failing to record it.
+func (h *Webhooks) deliver(ctx context.Context, hook models.Webhook, event string, payload []byte, redeliveryOf *int64) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		WebhookId:    hook.Id,
+		Event:        event,
+		Guid:         uuid.New().String(),
+		Payload:      string(payload),
+		RedeliveryOf: redeliveryOf,
+	}
+
+	if max := h.config.Webhook.MaxPayloadBytes; max > 0 && len(payload) > max {
+		delivery.Payload = ""
+		delivery.Error = fmt.Sprintf("payload of %d bytes is over the limit of %d bytes", len(payload), max)
+	} else {
+		h.send(ctx, hook, delivery, payload)
+	}
+
+	if err := db.AddWebhookDelivery(h.db, delivery); err != nil {
+		return nil, err
+	}
+
+	cfg := h.config.Webhook
+	if err := db.PruneWebhookDeliveries(h.db, hook.Id, cfg.KeepDeliveries, time.Now().Add(-cfg.KeepFor)); err != nil {
+		h.logger.Error("failed to prune webhook deliveries", "webhook", hook.Id, "err", err)
+	}
+
+	return delivery, nil
+}
+
+func (h *Webhooks) send(ctx context.Context, hook models.Webhook, delivery *models.WebhookDelivery, payload []byte) {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Webhook.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(payload))
+	if err != nil {
+		delivery.Error = err.Error()
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tangled-webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.Guid)
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, payload))
+	}
+	delivery.RequestHeaders = formatHeaders(req.Header)
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	delivery.Duration = time.Since(start)
+	if err != nil {
+		delivery.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, h.config.Webhook.MaxResponseBytes))
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseHeaders = formatHeaders(resp.Header)
+	delivery.ResponseBody = string(body)
+}
+
+func formatHeaders(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		for _, v := range header[k] {
+			fmt.Fprintf(&sb, "%s: %s\n", k, v)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,27 @@
+package webhooks
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	got := Sign("It's a Secret to Everybody", []byte("Hello, World!"))
+	want := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Tangled-Event", "issue.opened")
+	header.Add("Accept", "text/plain")
+	header.Add("Accept", "application/json")
+
+	got := formatHeaders(header)
+	want := "Accept: text/plain\nAccept: application/json\nX-Tangled-Event: issue.opened\n"
+	if got != want {
+		t.Errorf("formatHeaders() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var errInternalAddress = errors.New("webhooks cannot be sent to internal addresses")
+
+// internalPrefixes are ranges that reach hosts next to the appview, which
+// netip has no predicate for.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+}
+
+// internal reports whether ip is one that webhooks must not reach: the
+// appview itself, or hosts on the network it runs in.
+func internal(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// refuseInternal is a dialer control that refuses connections to internal
+// addresses. It runs once the host was resolved, so a name that points at
+// one is refused too, however often its records change.
+func refuseInternal(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if internal(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errInternalAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// newClient returns the client webhooks are sent with. Outside of dev, it
+// only connects to public addresses.
+func newClient(dev bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !dev {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   refuseInternal,
+		}
+		transport.DialContext = dialer.DialContext
+		// a proxy would be the one dialed, and checked, in place of the
+		// host of the webhook
+		transport.Proxy = nil
+	}
+
+	return &http.Client{
+		Transport: transport,
+		// a redirect would send the payload somewhere the owner never
+		// registered
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkHost resolves host and refuses it if any of its addresses is
+// internal, so that such webhooks are turned down when they are added
+// rather than failing on every delivery.
+func checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if internal(addr) {
+			return fmt.Errorf("%w: %s", errInternalAddress, addr)
+		}
+	}
+	return nil
+}
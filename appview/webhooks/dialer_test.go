@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRefuseInternal(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1:443",
+		"[::1]:443",
+		"10.0.0.1:443",
+		"172.16.5.4:80",
+		"192.168.1.1:443",
+		"169.254.169.254:80",
+		"[fe80::1]:443",
+		"[fd00::1]:443",
+		"[::ffff:127.0.0.1]:443",
+		"0.0.0.0:443",
+		"0.1.2.3:443",
+		"100.64.0.1:443",
+		"100.127.255.254:443",
+		"[::ffff:100.64.0.1]:443",
+	} {
+		if err := refuseInternal("tcp", address, nil); !errors.Is(err, errInternalAddress) {
+			t.Errorf("refuseInternal(%q) = %v, want errInternalAddress", address, err)
+		}
+	}
+
+	for _, address := range []string{
+		"1.1.1.1:443",
+		"100.128.0.1:443",
+		"[2606:4700:4700::1111]:443",
+	} {
+		if err := refuseInternal("tcp", address, nil); err != nil {
+			t.Errorf("refuseInternal(%q) = %v, want nil", address, err)
+		}
+	}
+}
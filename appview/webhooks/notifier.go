@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/notify"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+var _ notify.Notifier = &Webhooks{}
+
+type payload struct {
+	Event   string          `json:"event"`
+	Repo    syntax.ATURI    `json:"repo"`
+	Actor   string          `json:"actor"`
+	Issue   *issuePayload   `json:"issue,omitempty"`
+	Pull    *pullPayload    `json:"pull,omitempty"`
+	Comment *commentPayload `json:"comment,omitempty"`
+	Sent    time.Time       `json:"sent"`
+}
+
+type issuePayload struct {
+	Uri    syntax.ATURI `json:"uri"`
+	Number int          `json:"number"`
+	Title  string       `json:"title"`
+	Body   string       `json:"body"`
+	Author string       `json:"author"`
+	Open   bool         `json:"open"`
+}
+
+type pullPayload struct {
+	Uri          syntax.ATURI `json:"uri"`
+	Number       int          `json:"number"`
+	Title        string       `json:"title"`
+	Body         string       `json:"body"`
+	Author       string       `json:"author"`
+	TargetBranch string       `json:"targetBranch"`
+	State        string       `json:"state"`
+}
+
+type commentPayload struct {
+	Uri    syntax.ATURI `json:"uri"`
+	Body   string       `json:"body"`
+	Author string       `json:"author"`
+}
+
+func newIssuePayload(issue *models.Issue) *issuePayload {
+	return &issuePayload{
+		Uri:    issue.AtUri(),
+		Number: issue.IssueId,
+		Title:  issue.Title,
+		Body:   issue.Body,
+		Author: issue.Did,
+		Open:   issue.Open,
+	}
+}
+
+func newPullPayload(pull *models.Pull) *pullPayload {
+	return &pullPayload{
+		Uri:          pull.AtUri(),
+		Number:       pull.PullId,
+		Title:        pull.Title,
+		Body:         pull.Body,
+		Author:       pull.OwnerDid,
+		TargetBranch: pull.TargetBranch,
+		State:        pull.State.String(),
+	}
+}
+
+func (h *Webhooks) NewIssue(ctx context.Context, issue *models.Issue, mentions []syntax.DID) {
+	h.dispatch(ctx, payload{
+		Event: "issue.opened",
+		Repo:  issue.RepoAt,
+		Actor: issue.Did,
+		Issue: newIssuePayload(issue),
+	})
+}
+
+func (h *Webhooks) NewIssueState(ctx context.Context, actor syntax.DID, issue *models.Issue) {
+	event := "issue.closed"
+	if issue.Open {
+		event = "issue.reopened"
+	}
+
+	h.dispatch(ctx, payload{
+		Event: event,
+		Repo:  issue.RepoAt,
+		Actor: actor.String(),
+		Issue: newIssuePayload(issue),
+	})
+}
+
+func (h *Webhooks) NewIssueComment(ctx context.Context, comment *models.IssueComment, mentions []syntax.DID) {
+	issues, err := db.GetIssues(h.db, db.FilterEq("at_uri", comment.IssueAt))
+	if err != nil || len(issues) == 0 {
+		h.logger.Error("failed to get issue of comment", "comment", comment.AtUri(), "err", err)
+		return
+	}
+	issue := issues[0]
+
+	h.dispatch(ctx, payload{
+		Event: "issue.comment",
+		Repo:  issue.RepoAt,
+		Actor: comment.Did,
+		Issue: newIssuePayload(&issue),
+		Comment: &commentPayload{
+			Uri:    comment.AtUri(),
+			Body:   comment.Body,
+			Author: comment.Did,
+		},
+	})
+}
+
+func (h *Webhooks) NewPull(ctx context.Context, pull *models.Pull) {
+	h.dispatch(ctx, payload{
+		Event: "pull.opened",
+		Repo:  pull.RepoAt,
+		Actor: pull.OwnerDid,
+		Pull:  newPullPayload(pull),
+	})
+}
+
+func (h *Webhooks) NewPullState(ctx context.Context, actor syntax.DID, pull *models.Pull) {
+	event := "pull." + pull.State.String()
+	if pull.State == models.PullOpen {
+		event = "pull.reopened"
+	}
+
+	h.dispatch(ctx, payload{
+		Event: event,
+		Repo:  pull.RepoAt,
+		Actor: actor.String(),
+		Pull:  newPullPayload(pull),
+	})
+}
+
+func (h *Webhooks) NewPullComment(ctx context.Context, comment *models.PullComment, mentions []syntax.DID) {
+	pull, err := db.GetPull(h.db, syntax.ATURI(comment.RepoAt), comment.PullId)
+	if err != nil {
+		h.logger.Error("failed to get pull of comment", "comment", comment.AtUri(), "err", err)
+		return
+	}
+
+	h.dispatch(ctx, payload{
+		Event: "pull.comment",
+		Repo:  pull.RepoAt,
+		Actor: comment.OwnerDid,
+		Pull:  newPullPayload(pull),
+		Comment: &commentPayload{
+			Uri:    comment.AtUri(),
+			Body:   comment.Body,
+			Author: comment.OwnerDid,
+		},
+	})
+}
+
+func (h *Webhooks) NewStar(ctx context.Context, star *models.Star) {
+	h.dispatch(ctx, payload{
+		Event: "star.created",
+		Repo:  star.RepoAt,
+		Actor: star.Did,
+	})
+}
+
+// dispatch sends p to every webhook of its repo. Deliveries happen in the
+// background, notifiers are called in line with the request.
+func (h *Webhooks) dispatch(ctx context.Context, p payload) {
+	hooks, err := db.GetWebhooks(h.db, db.FilterEq("repo_at", p.Repo))
+	if err != nil {
+		h.logger.Error("failed to get webhooks", "repo", p.Repo, "err", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	p.Sent = time.Now().UTC()
+	body, err := json.Marshal(p)
+	if err != nil {
+		h.logger.Error("failed to marshal webhook payload", "event", p.Event, "err", err)
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		go func() {
+			if _, err := h.deliver(ctx, hook, p.Event, body, nil); err != nil {
+				h.logger.Error("failed to record webhook delivery", "webhook", hook.Id, "err", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,189 @@
+// Package webhooks sends repo events to endpoints registered by the repo
+// owner, and keeps a log of each delivery so that failed ones can be looked
+// into and sent again.
+package webhooks
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+	"tangled.org/core/appview/middleware"
+	"tangled.org/core/appview/models"
+	"tangled.org/core/appview/notify"
+	"tangled.org/core/appview/oauth"
+	"tangled.org/core/appview/pages"
+	"tangled.org/core/appview/reporesolver"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Webhooks struct {
+	db           *db.DB
+	oauth        *oauth.OAuth
+	pages        *pages.Pages
+	repoResolver *reporesolver.RepoResolver
+	config       *config.Config
+	client       *http.Client
+	logger       *slog.Logger
+	notify.BaseNotifier
+}
+
+func New(
+	db *db.DB,
+	oauth *oauth.OAuth,
+	pages *pages.Pages,
+	repoResolver *reporesolver.RepoResolver,
+	config *config.Config,
+	logger *slog.Logger,
+) *Webhooks {
+	return &Webhooks{
+		db:           db,
+		oauth:        oauth,
+		pages:        pages,
+		repoResolver: repoResolver,
+		config:       config,
+		client:       newClient(config.Core.Dev),
+		logger:       logger,
+	}
+}
+
+func (h *Webhooks) Router(mw *middleware.Middleware) http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(middleware.AuthMiddleware(h.oauth))
+	r.Use(mw.RepoPermissionMiddleware("repo:owner"))
+	r.Put("/", h.AddWebhook)
+	r.Delete("/", h.DeleteWebhook)
+	r.Post("/{hook}/deliveries/{delivery}/redeliver", h.Redeliver)
+
+	return r
+}
+
+func (h *Webhooks) AddWebhook(w http.ResponseWriter, r *http.Request) {
+	l := h.logger.With("handler", "AddWebhook")
+	noticeId := "add-webhook-error"
+
+	f, err := h.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	rawUrl := strings.TrimSpace(r.FormValue("url"))
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		h.pages.Notice(w, noticeId, "The url must be an http or https url.")
+		return
+	}
+	if u.Scheme == "http" && !h.config.Core.Dev {
+		h.pages.Notice(w, noticeId, "Webhooks must use https.")
+		return
+	}
+	if !h.config.Core.Dev {
+		if err := checkHost(r.Context(), u.Hostname()); err != nil {
+			l.Info("refusing webhook", "url", u.String(), "err", err)
+			if errors.Is(err, errInternalAddress) {
+				h.pages.Notice(w, noticeId, "Webhooks cannot be sent to private or local addresses.")
+			} else {
+				h.pages.Notice(w, noticeId, "The host of the url could not be found.")
+			}
+			return
+		}
+	}
+
+	hook := models.Webhook{
+		RepoAt: f.RepoAt(),
+		Url:    u.String(),
+		Secret: r.FormValue("secret"),
+	}
+	if err := db.AddWebhook(h.db, &hook); err != nil {
+		l.Error("failed to add webhook", "err", err)
+		h.pages.Notice(w, noticeId, "Failed to add webhook.")
+		return
+	}
+
+	h.pages.HxRefresh(w)
+}
+
+func (h *Webhooks) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	l := h.logger.With("handler", "DeleteWebhook")
+
+	f, err := h.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeleteWebhooks(h.db, db.FilterEq("repo_at", f.RepoAt()), db.FilterEq("id", id)); err != nil {
+		l.Error("failed to delete webhook", "err", err)
+		h.pages.Notice(w, "operation-error", "Failed to delete webhook.")
+		return
+	}
+
+	h.pages.HxRefresh(w)
+}
+
+// Redeliver sends the payload of an earlier delivery again, signed with the
+// webhook's current secret, and records it as a new delivery.
+func (h *Webhooks) Redeliver(w http.ResponseWriter, r *http.Request) {
+	l := h.logger.With("handler", "Redeliver")
+	noticeId := "operation-error"
+
+	f, err := h.repoResolver.Resolve(r)
+	if err != nil {
+		l.Error("failed to get repo and knot", "err", err)
+		return
+	}
+
+	hookId, err := strconv.ParseInt(chi.URLParam(r, "hook"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	deliveryId, err := strconv.ParseInt(chi.URLParam(r, "delivery"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hooks, err := db.GetWebhooks(h.db, db.FilterEq("repo_at", f.RepoAt()), db.FilterEq("id", hookId))
+	if err != nil || len(hooks) == 0 {
+		l.Error("failed to get webhook", "err", err)
+		h.pages.Notice(w, noticeId, "No such webhook.")
+		return
+	}
+	hook := hooks[0]
+
+	deliveries, err := db.GetWebhookDeliveries(h.db, 1, db.FilterEq("webhook_id", hook.Id), db.FilterEq("id", deliveryId))
+	if err != nil || len(deliveries) == 0 {
+		l.Error("failed to get webhook delivery", "err", err)
+		h.pages.Notice(w, noticeId, "No such delivery.")
+		return
+	}
+	original := deliveries[0]
+
+	if original.Payload == "" {
+		h.pages.Notice(w, noticeId, "This delivery has no payload to send again.")
+		return
+	}
+
+	if _, err := h.deliver(r.Context(), hook, original.Event, []byte(original.Payload), &original.Id); err != nil {
+		l.Error("failed to record webhook delivery", "err", err)
+		h.pages.Notice(w, noticeId, "Failed to redeliver.")
+		return
+	}
+
+	h.pages.HxRefresh(w)
+}
@@ -0,0 +1,151 @@
+package xrpcclient
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	indigoxrpc "github.com/bluesky-social/indigo/xrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"tangled.org/core/appview/config"
+)
+
+// transport is shared by every client, so that connections to knots and
+// spindles are pooled instead of being opened for each call.
+var transport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = 16
+	return t
+}()
+
+// traced records a span and metrics for every attempt at a call, named after
+// the method and the xrpc method being called. Both go to the global otel
+// providers, which do nothing unless tracing was set up.
+var traced = otelhttp.NewTransport(transport,
+	otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + strings.TrimPrefix(r.URL.Path, "/xrpc/")
+	}),
+)
+
+// HTTPClient returns an http client on the shared transport. Transient
+// failures are retried unless retry is false, which non-idempotent calls
+// like merges want.
+func HTTPClient(cfg config.KnotClientConfig, timeout time.Duration, retry bool) *http.Client {
+	var rt http.RoundTripper = &viewerTransport{next: traced}
+	if retry && cfg.MaxRetries > 0 {
+		rt = &retryTransport{next: rt, cfg: cfg}
+	}
+
+	return &http.Client{
+		Transport: rt,
+		Timeout:   timeout,
+	}
+}
+
+// NewClient returns an unauthenticated xrpc client for host, retrying
+// transient failures.
+func NewClient(cfg config.KnotClientConfig, host string) *indigoxrpc.Client {
+	return &indigoxrpc.Client{
+		Host:   host,
+		Client: HTTPClient(cfg, cfg.Timeout, true),
+	}
+}
+
+type viewerKey struct{}
+
+type viewer struct {
+	host  string
+	token string
+}
+
+// WithViewer returns a context in which calls to host are authenticated
+// with the service auth token of the user viewing the page. Knots only serve
+// private repos to users that have access to them.
+func WithViewer(ctx context.Context, host, token string) context.Context {
+	return context.WithValue(ctx, viewerKey{}, viewer{host, token})
+}
+
+// viewerTransport adds the token of the viewer to calls to their host that
+// aren't authenticated otherwise.
+type viewerTransport struct {
+	next http.RoundTripper
+}
+
+func (t *viewerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	v, ok := req.Context().Value(viewerKey{}).(viewer)
+	if ok && req.URL.Host == v.host && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+v.token)
+	}
+	return t.next.RoundTrip(req)
+}
+
+type retryTransport struct {
+	next http.RoundTripper
+	cfg  config.KnotClientConfig
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.cfg.MaxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.backoff(attempt)):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// backoff doubles the wait after every attempt, and picks a random point in
+// its upper half so that clients don't retry in lockstep.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	wait := t.cfg.RetryWaitMin << attempt
+	if wait <= 0 || wait > t.cfg.RetryWaitMax {
+		wait = t.cfg.RetryWaitMax
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// retryable reports whether a request failed in a way that is likely to be
+// transient. Knots report their own errors as 500s, those are not retried.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	// the body can't be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
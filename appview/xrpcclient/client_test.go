@@ -0,0 +1,160 @@
+package xrpcclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+	"tangled.org/core/appview/config"
+)
+
+func TestRetries(t *testing.T) {
+	cfg := config.KnotClientConfig{
+		Timeout:      time.Second,
+		MaxRetries:   3,
+		RetryWaitMin: time.Millisecond,
+		RetryWaitMax: 4 * time.Millisecond,
+	}
+
+	// fails with status until it was called failures times
+	server := func(status, failures int) (*httptest.Server, *atomic.Int32) {
+		var calls atomic.Int32
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if int(calls.Add(1)) <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			w.Write(body)
+		}))
+		t.Cleanup(s.Close)
+		return s, &calls
+	}
+
+	t.Run("transient failures are retried with the same body", func(t *testing.T) {
+		s, calls := server(http.StatusServiceUnavailable, 2)
+
+		resp, err := HTTPClient(cfg, cfg.Timeout, true).Post(s.URL, "text/plain", strings.NewReader("hello"))
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello", string(body))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("retries give up eventually", func(t *testing.T) {
+		s, calls := server(http.StatusBadGateway, 10)
+
+		resp, err := HTTPClient(cfg, cfg.Timeout, true).Get(s.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("knot errors are not retried", func(t *testing.T) {
+		s, calls := server(http.StatusInternalServerError, 1)
+
+		resp, err := HTTPClient(cfg, cfg.Timeout, true).Get(s.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("retries can be turned off", func(t *testing.T) {
+		s, calls := server(http.StatusServiceUnavailable, 1)
+
+		resp, err := HTTPClient(cfg, cfg.Timeout, false).Post(s.URL, "text/plain", strings.NewReader("merge"))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestViewer(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(s.Close)
+
+	host := strings.TrimPrefix(s.URL, "http://")
+	client := HTTPClient(config.KnotClientConfig{}, time.Second, false)
+
+	get := func(ctx context.Context) string {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+		assert.NoError(t, err)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "", get(context.Background()))
+	assert.Equal(t, "Bearer token", get(WithViewer(context.Background(), host, "token")))
+	assert.Equal(t, "", get(WithViewer(context.Background(), "knot.example.com", "token")))
+}
+
+// spanRecorder is a tracer provider that only remembers the names of the
+// spans it started.
+type spanRecorder struct {
+	embedded.TracerProvider
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{r: r}
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	r *spanRecorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.r.mu.Lock()
+	t.r.names = append(t.r.names, name)
+	t.r.mu.Unlock()
+	return noop.NewTracerProvider().Tracer("").Start(ctx, name, opts...)
+}
+
+func TestTracing(t *testing.T) {
+	recorder := &spanRecorder{}
+	otel.SetTracerProvider(recorder)
+
+	var calls atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	cfg := config.KnotClientConfig{MaxRetries: 1}
+	resp, err := HTTPClient(cfg, time.Second, true).Get(s.URL + "/xrpc/sh.tangled.repo.branches")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// every attempt gets its own span
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	assert.Equal(t, []string{
+		"GET sh.tangled.repo.branches",
+		"GET sh.tangled.repo.branches",
+	}, recorder.names)
+}
@@ -34,3 +34,19 @@ func HandleXrpcErr(err error) error {
 		return ErrXrpcFailed
 	}
 }
+
+// ErrorMessage is the message the server sent along with err, for errors
+// that are worth showing to users as they are.
+func ErrorMessage(err error) (string, bool) {
+	var xrpcerr *indigoxrpc.Error
+	if !errors.As(err, &xrpcerr) {
+		return "", false
+	}
+
+	var body *indigoxrpc.XRPCError
+	if !errors.As(xrpcerr.Wrapped, &body) || body.Message == "" {
+		return "", false
+	}
+
+	return body.Message, true
+}
@@ -5,20 +5,40 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/urfave/cli/v3"
 	"tangled.org/core/appview/config"
 	"tangled.org/core/appview/state"
 	tlog "tangled.org/core/log"
 )
 
 func main() {
+	cmd := &cli.Command{
+		Name:   "appview",
+		Usage:  "run the tangled appview",
+		Action: serve,
+		Commands: []*cli.Command{
+			migrateCommand(),
+			reindexCommand(),
+		},
+	}
+
 	ctx := context.Background()
 	logger := tlog.New("appview")
 	ctx = tlog.IntoContext(ctx, logger)
 
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		logger.Error(err.Error())
+		os.Exit(-1)
+	}
+}
+
+func serve(ctx context.Context, cmd *cli.Command) error {
+	logger := tlog.FromContext(ctx)
+
 	c, err := config.LoadConfig(ctx)
 	if err != nil {
 		logger.Error("failed to load config", "error", err)
-		return
+		return nil
 	}
 
 	state, err := state.Make(ctx, c)
@@ -38,4 +58,6 @@ func main() {
 	if err := http.ListenAndServe(c.Core.ListenAddr, state.Router()); err != nil {
 		logger.Error("failed to start appview", "err", err)
 	}
+
+	return nil
 }
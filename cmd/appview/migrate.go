@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"tangled.org/core/appview/config"
+	"tangled.org/core/appview/db"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "inspect and apply migrations of the appview database",
+		Description: `
+	The appview applies pending migrations whenever it starts, these commands
+	are for looking at the database before or after that. The database is
+	found through TANGLED_DB_PATH, like the appview does.
+	`,
+		Commands: []*cli.Command{
+			{
+				Name:   "status",
+				Usage:  "list migrations and whether they were applied",
+				Action: migrateStatus,
+			},
+			{
+				Name:   "up",
+				Usage:  "apply all pending migrations",
+				Action: migrateUp,
+			},
+			{
+				Name:   "down",
+				Usage:  "undo the latest applied migration, if it can be undone",
+				Action: migrateDown,
+			},
+		},
+	}
+}
+
+func openDb(ctx context.Context) (*db.DB, error) {
+	c, err := config.LoadConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return db.Open(ctx, c.Core.DbPath, db.Options{
+		BusyTimeout: c.Db.BusyTimeout,
+		BusyRetries: c.Db.BusyRetries,
+	})
+}
+
+func migrateStatus(ctx context.Context, cmd *cli.Command) error {
+	d, err := openDb(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	statuses, err := d.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied != nil {
+			applied = s.Applied.Format(time.RFC3339)
+		} else {
+			pending++
+		}
+		fmt.Fprintf(cmd.Writer, "%4d  %-20s  %s\n", s.Version, applied, s.Name)
+	}
+	fmt.Fprintf(cmd.Writer, "%d migrations, %d pending\n", len(statuses), pending)
+
+	return nil
+}
+
+func migrateUp(ctx context.Context, cmd *cli.Command) error {
+	d, err := openDb(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Migrate(ctx)
+}
+
+func migrateDown(ctx context.Context, cmd *cli.Command) error {
+	d, err := openDb(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	undone, err := d.UndoMigration(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Writer, "undid migration %d (%s)\n", undone.Version, undone.Name)
+	return nil
+}
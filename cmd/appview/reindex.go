@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+	"tangled.org/core/appview/indexer"
+	tlog "tangled.org/core/log"
+)
+
+func reindexCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "reindex",
+		Usage: "rebuild the issue and pull search indexes from the database",
+		Description: `
+	Each index is built anew next to the current one, which it replaces once
+	every record was indexed, so running it again is always safe. The appview
+	must be stopped while it runs, as it keeps the indexes open.
+	`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "issues",
+				Usage: "only rebuild the issue index",
+			},
+			&cli.BoolFlag{
+				Name:  "pulls",
+				Usage: "only rebuild the pull index",
+			},
+		},
+		Action: reindex,
+	}
+}
+
+func reindex(ctx context.Context, cmd *cli.Command) error {
+	logger := tlog.SubLogger(tlog.FromContext(ctx), "indexer")
+	ctx = tlog.IntoContext(ctx, logger)
+
+	d, err := openDb(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// neither flag means both
+	issues, pulls := cmd.Bool("issues"), cmd.Bool("pulls")
+	if !issues && !pulls {
+		issues, pulls = true, true
+	}
+
+	ix := indexer.New(logger)
+	if issues {
+		logger.Info("rebuilding the issue index")
+		if err := ix.Issues.Rebuild(ctx, d); err != nil {
+			return fmt.Errorf("failed to rebuild issue index: %w", err)
+		}
+	}
+	if pulls {
+		logger.Info("rebuilding the pull index")
+		if err := ix.Pulls.Rebuild(ctx, d); err != nil {
+			return fmt.Errorf("failed to rebuild pull index: %w", err)
+		}
+	}
+
+	return nil
+}
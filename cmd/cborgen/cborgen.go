@@ -24,6 +24,7 @@ func main() {
 		tangled.GitRefUpdate_LangBreakdown{},
 		tangled.GitRefUpdate_Meta{},
 		tangled.GraphFollow{},
+		tangled.GraphWatch{},
 		tangled.Knot{},
 		tangled.KnotMember{},
 		tangled.LabelDefinition{},
@@ -52,6 +53,7 @@ func main() {
 		tangled.RepoPull_Source{},
 		tangled.RepoPullStatus{},
 		tangled.RepoPull_Target{},
+		tangled.RepoRelease{},
 		tangled.Spindle{},
 		tangled.SpindleMember{},
 		tangled.String{},
@@ -86,7 +86,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Println("signature")
+	fmt.Printf("signature (%s):\n", crypto.DetectSignatureType([]byte(signature)))
 	fmt.Println(signature)
 	fmt.Println()
 	fmt.Println("payload:")
@@ -4,15 +4,56 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/hiddeco/sshsig"
 	"golang.org/x/crypto/ssh"
 	"tangled.org/core/types"
 )
 
+type SignatureType string
+
+const (
+	SignatureNone    SignatureType = ""
+	SignatureSSH     SignatureType = "ssh"
+	SignatureGPG     SignatureType = "gpg"
+	SignatureX509    SignatureType = "x509"
+	SignatureUnknown SignatureType = "unknown"
+)
+
+// DetectSignatureType tells the kind of a commit signature apart by its
+// armor, the same way git does with gpg.format.
+func DetectSignatureType(signature []byte) SignatureType {
+	signature = bytes.TrimSpace(signature)
+	switch {
+	case len(signature) == 0:
+		return SignatureNone
+	case bytes.HasPrefix(signature, []byte("-----BEGIN SSH SIGNATURE-----")):
+		return SignatureSSH
+	case bytes.HasPrefix(signature, []byte("-----BEGIN PGP SIGNATURE-----")),
+		bytes.HasPrefix(signature, []byte("-----BEGIN PGP MESSAGE-----")):
+		return SignatureGPG
+	case bytes.HasPrefix(signature, []byte("-----BEGIN SIGNED MESSAGE-----")),
+		bytes.HasPrefix(signature, []byte("-----BEGIN CERTIFICATE-----")):
+		return SignatureX509
+	default:
+		return SignatureUnknown
+	}
+}
+
+// VerifySignature verifies an ssh signature over payload against an ssh
+// public key in authorized_keys format. Only ssh signatures can be verified,
+// as those are the only keys users register.
 func VerifySignature(pubKey, signature, payload []byte) (error, bool) {
+	if t := DetectSignatureType(signature); t != SignatureSSH {
+		return fmt.Errorf("unsupported signature type: %q", t), false
+	}
+
 	pub, _, _, _, err := ssh.ParseAuthorizedKey(pubKey)
 	if err != nil {
 		return fmt.Errorf("failed to parse public key: %w", err), false
@@ -24,10 +65,10 @@ func VerifySignature(pubKey, signature, payload []byte) (error, bool) {
 	}
 
 	buf := bytes.NewBuffer(payload)
-	// we use sha-512 because ed25519 keys require it internally; rsa keys support
-	// multiple algorithms but sha-512 is most secure, and git's ssh signing defaults
-	// to sha-512 for all key types anyway.
-	err = sshsig.Verify(buf, sig, pub, sshsig.HashSHA512, "git")
+	// git signs with sha-512 by default, but ssh-keygen can be asked for
+	// sha-256 too, so the signature's own algorithm is used. the key type
+	// (ed25519, rsa-sha2-*, ecdsa) is handled by the key itself.
+	err = sshsig.Verify(buf, sig, pub, sig.HashAlgorithm, "git")
 	return err, err == nil
 }
 
@@ -63,6 +104,39 @@ func VerifyCommitSignature(pubKey string, commit types.NiceDiff) (error, bool) {
 	return VerifySignature([]byte(pubKey), []byte(signature), []byte(payload.String()))
 }
 
+// VerifyTagSignature verifies a signed annotated tag against the payload
+// reported for it by the knot. The payload is only trusted once it hashes
+// back to the tag itself, so that a knot can't pass off the signature of
+// some other tag by the same tagger.
+func VerifyTagSignature(pubKey string, tag types.TagReference) (error, bool) {
+	if tag.Tag == nil || tag.Tag.PGPSignature == "" || tag.Payload == "" {
+		return errors.New("tag is not signed"), false
+	}
+
+	payload := &plumbing.MemoryObject{}
+	payload.SetType(plumbing.TagObject)
+	if _, err := payload.Write([]byte(tag.Payload)); err != nil {
+		return err, false
+	}
+
+	var decoded object.Tag
+	if err := decoded.Decode(payload); err != nil {
+		return fmt.Errorf("failed to parse tag payload: %w", err), false
+	}
+
+	decoded.PGPSignature = tag.Tag.PGPSignature
+	signed := &plumbing.MemoryObject{}
+	if err := decoded.Encode(signed); err != nil {
+		return fmt.Errorf("failed to encode tag: %w", err), false
+	}
+
+	if signed.Hash() != tag.Tag.Hash || decoded.Name != tag.Name || decoded.Tagger.Email != tag.Tag.Tagger.Email {
+		return errors.New("tag payload does not match tag"), false
+	}
+
+	return VerifySignature([]byte(pubKey), []byte(tag.Tag.PGPSignature), []byte(tag.Payload))
+}
+
 // SSHFingerprint computes the fingerprint of the supplied ssh pubkey.
 func SSHFingerprint(pubKey string) (string, error) {
 	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubKey))
@@ -73,3 +147,35 @@ func SSHFingerprint(pubKey string) (string, error) {
 	hash := sha256.Sum256(pk.Marshal())
 	return "SHA256:" + base64.StdEncoding.EncodeToString(hash[:]), nil
 }
+
+// SSHKeyExpiry returns the time set by the expiry-time option of an ssh
+// pubkey in authorized_keys format, or nil if the key does not expire.
+func SSHKeyExpiry(pubKey string) (*time.Time, error) {
+	_, _, options, _, err := ssh.ParseAuthorizedKey([]byte(pubKey))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		name, value, ok := strings.Cut(option, "=")
+		if !ok || !strings.EqualFold(name, "expiry-time") {
+			continue
+		}
+
+		// YYYYMMDD[HHMM[SS]][Z], times are taken as utc either way
+		value = strings.TrimSuffix(strings.Trim(value, `"`), "Z")
+		for _, layout := range []string{"20060102", "200601021504", "20060102150405"} {
+			if len(value) != len(layout) {
+				continue
+			}
+			t, err := time.Parse(layout, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expiry-time %q: %w", value, err)
+			}
+			return &t, nil
+		}
+		return nil, fmt.Errorf("invalid expiry-time %q", value)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,175 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"tangled.org/core/types"
+)
+
+// test vectors are empty commits and a tag made with
+//
+//	git -c gpg.format=ssh -c user.signingkey=<key> commit --allow-empty
+//	git -c gpg.format=ssh -c user.signingkey=<key> tag -s
+const (
+	ed25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGVIN1BRN3C/kwrxeSO51dThL1F15Kg2jLgFptTLWOiU alice@example.com"
+	ed25519Sig = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgZUg3UFE3cL+TCvF5I7nV1OEvUX
+XkqDaMuAWm1MtY6JQAAAADZ2l0AAAAAAAAAAZzaGE1MTIAAABTAAAAC3NzaC1lZDI1NTE5
+AAAAQDFHlWX1DE+K3kdwpFzQGJaI/Sv51OwnaLERRFofkXiWgfHV7boovMiEYRhT8GjS4F
+A43vIA8b4zC8je5j+DVA4=
+-----END SSH SIGNATURE-----`
+
+	rsaKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDGncUgZMBG9sncSi98uX+BseWaIf2NwvBTdGZa453W69G1kMfblJk32buDFM3uKLTNXLJmhfWEL4WrmhQ9LhRDMExcmstYPJ2sTeNhlPnHZXFdzj2yPVboNBiU+miYU6lBxVaSVJJQBjy21ECK2YXDuvpZTiY1Q1x45c+RR7b752Ivw5ixwgfdkrlwtm+5Al6SJp2OWfawHIVk60Nezia16QhYhaxxBogqBCxwoHZrz20q/3kN2RY3/4riJOHtNPfnXKojk1+iEd0L0QICS3x7c8wbvT/X4RqC9V20Hb4sNSvUpzIHIIa+CEXiE3RhEfpAxG2HIDvo9WyLx1u8hwH1 bob@example.com"
+	rsaSig = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAARcAAAAHc3NoLXJzYQAAAAMBAAEAAAEBAMadxSBkwEb2ydxKL3y5f4
+Gx5Zoh/Y3C8FN0Zlrjndbr0bWQx9uUmTfZu4MUze4otM1csmaF9YQvhauaFD0uFEMwTFya
+y1g8naxN42GU+cdlcV3OPbI9Vug0GJT6aJhTqUHFVpJUklAGPLbUQIrZhcO6+llOJjVDXH
+jlz5FHtvvnYi/DmLHCB92SuXC2b7kCXpImnY5Z9rAchWTrQ17OJrXpCFiFrHEGiCoELHCg
+dmvPbSr/eQ3ZFjf/iuIk4e009+dcqiOTX6IR3QvRAgJLfHtzzBu9P9fhGoL1XbQdviw1K9
+SnMgcghr4IReITdGER+kDEbYcgO+j1bIvHW7yHAfUAAAADZ2l0AAAAAAAAAAZzaGE1MTIA
+AAEUAAAADHJzYS1zaGEyLTUxMgAAAQB6eN1wD+1GSSmHny4ktZOTdl6Z49aCA+IkFW2k0C
+k6xnMoeXN5oJmtF0O+BCA5M2zXPfBprzsd96fjyUUokG8GTrN+1J4Tyuw7VRJQd4OzEIWU
+k6iIgLQywo6CKl5uWK2FqBA1uubxn+Piwck7L9XXcWmNz78G8zJh08Gj3tHP5g08obm/Gu
+HS9QB6Y0NUJttdEsYnL/19EhFChRKwxmSmSlz8SVcQjuXDQaLWzIGk5E4mXGDlwPOP9T86
+TK6QIN3U0sv+9GLJwLHpC0pf0hlLvMjf/EVwHncPdlzm56gzKHeb7bWy4hjCqaR9e9kwQq
+yYopXhsocAgoAnFfxNxHII
+-----END SSH SIGNATURE-----`
+)
+
+func signedCommit(parent, message, signature string) types.NiceDiff {
+	alice := object.Signature{
+		Name:  "Alice",
+		Email: "alice@example.com",
+		When:  time.Unix(1700000000, 0).UTC(),
+	}
+
+	var commit types.NiceDiff
+	commit.Commit.Tree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	commit.Commit.Parent = parent
+	commit.Commit.Author = alice
+	commit.Commit.Committer = alice
+	commit.Commit.Message = message
+	commit.Commit.PGPSignature = signature
+	return commit
+}
+
+func TestVerifyCommitSignature(t *testing.T) {
+	ed25519Commit := signedCommit("", "signed with ed\n", ed25519Sig)
+	rsaCommit := signedCommit("8e1e3b7cba11b48d8e9372b13c7ae71e31248265", "signed with rsa\n", rsaSig)
+
+	t.Run("ed25519", func(t *testing.T) {
+		err, ok := VerifyCommitSignature(ed25519Key, ed25519Commit)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("rsa", func(t *testing.T) {
+		err, ok := VerifyCommitSignature(rsaKey, rsaCommit)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("other keys are rejected", func(t *testing.T) {
+		_, ok := VerifyCommitSignature(rsaKey, ed25519Commit)
+		assert.False(t, ok)
+		_, ok = VerifyCommitSignature(ed25519Key, rsaCommit)
+		assert.False(t, ok)
+	})
+
+	t.Run("tampered commits are rejected", func(t *testing.T) {
+		tampered := ed25519Commit
+		tampered.Commit.Message = "signed with ed, honest\n"
+		_, ok := VerifyCommitSignature(ed25519Key, tampered)
+		assert.False(t, ok)
+	})
+
+	t.Run("gpg signatures are not verified", func(t *testing.T) {
+		gpg := signedCommit("", "signed with gpg\n", "-----BEGIN PGP SIGNATURE-----\n\niQEzBAABCAAdFiEE\n-----END PGP SIGNATURE-----")
+		err, ok := VerifyCommitSignature(ed25519Key, gpg)
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestVerifyTagSignature(t *testing.T) {
+	signed := func() types.TagReference {
+		return types.TagReference{
+			Reference: types.Reference{Name: "v1.0.0", Hash: "e136a04abe1b43f2a625e4cd4b9a9e8d5ad042ca"},
+			Tag: &object.Tag{
+				Hash:   plumbing.NewHash("e136a04abe1b43f2a625e4cd4b9a9e8d5ad042ca"),
+				Name:   "v1.0.0",
+				Tagger: object.Signature{Name: "Alice", Email: "alice@example.com"},
+				PGPSignature: `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgZUg3UFE3cL+TCvF5I7nV1OEvUX
+XkqDaMuAWm1MtY6JQAAAADZ2l0AAAAAAAAAAZzaGE1MTIAAABTAAAAC3NzaC1lZDI1NTE5
+AAAAQAs1+Z/WIyFTkPIB1nS1tMQJ83cQgtT6dN/0XzpscXZaqR3yH5RNxbb2EirJILBNJo
+s576kJL2+H07iXjAinegE=
+-----END SSH SIGNATURE-----
+`,
+			},
+			Payload: "object e4a369a4e52ffbff36bd6f22d7581c3edf5016f7\n" +
+				"type commit\n" +
+				"tag v1.0.0\n" +
+				"tagger Alice <alice@example.com> 1700000000 +0000\n" +
+				"\n" +
+				"release v1.0.0\n",
+		}
+	}
+
+	t.Run("signed tags verify", func(t *testing.T) {
+		err, ok := VerifyTagSignature(ed25519Key, signed())
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("other keys are rejected", func(t *testing.T) {
+		_, ok := VerifyTagSignature(rsaKey, signed())
+		assert.False(t, ok)
+	})
+
+	t.Run("payloads of other tags are rejected", func(t *testing.T) {
+		tag := signed()
+		tag.Name = "v2.0.0"
+		tag.Tag.Name = "v2.0.0"
+		tag.Tag.Hash = plumbing.NewHash("0000000000000000000000000000000000000001")
+		err, ok := VerifyTagSignature(ed25519Key, tag)
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("unsigned tags are not verified", func(t *testing.T) {
+		tag := signed()
+		tag.Payload = ""
+		_, ok := VerifyTagSignature(ed25519Key, tag)
+		assert.False(t, ok)
+	})
+}
+
+func TestDetectSignatureType(t *testing.T) {
+	assert.Equal(t, SignatureSSH, DetectSignatureType([]byte(ed25519Sig)))
+	assert.Equal(t, SignatureGPG, DetectSignatureType([]byte("-----BEGIN PGP SIGNATURE-----\n")))
+	assert.Equal(t, SignatureX509, DetectSignatureType([]byte("-----BEGIN SIGNED MESSAGE-----\n")))
+	assert.Equal(t, SignatureUnknown, DetectSignatureType([]byte("garbage")))
+	assert.Equal(t, SignatureNone, DetectSignatureType(nil))
+}
+
+func TestSSHKeyExpiry(t *testing.T) {
+	expires, err := SSHKeyExpiry(ed25519Key)
+	assert.NoError(t, err)
+	assert.Zero(t, expires)
+
+	expires, err = SSHKeyExpiry(`expiry-time="20250102" ` + ed25519Key)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), *expires)
+
+	expires, err = SSHKeyExpiry(`expiry-time="202501021504Z" ` + ed25519Key)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 2, 15, 4, 0, 0, time.UTC), *expires)
+
+	_, err = SSHKeyExpiry(`expiry-time="2025" ` + ed25519Key)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package eventconsumer
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"math/rand"
 	"net/url"
@@ -36,6 +37,26 @@ type ConsumerConfig struct {
 	Logger            *slog.Logger
 	Dev               bool
 	CursorStore       cursor.Store
+
+	// times ProcessFunc is tried on a message before giving up on it, and
+	// where messages that were given up on are kept, if anywhere
+	MaxAttempts int
+	DeadLetters DeadLetterStore
+}
+
+// DeadLetter is a message that could not be processed.
+type DeadLetter struct {
+	Id int64
+	// key of the source the message came from
+	Source   string
+	Message  Message
+	Error    string
+	Attempts int
+	Created  time.Time
+}
+
+type DeadLetterStore interface {
+	AddDeadLetter(DeadLetter) error
 }
 
 func NewConsumerConfig() *ConsumerConfig {
@@ -91,6 +112,9 @@ func NewConsumer(cfg ConsumerConfig) *Consumer {
 	if cfg.CursorStore == nil {
 		cfg.CursorStore = &cursor.MemoryStore{}
 	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
 	return &Consumer{
 		cfg:        cfg,
 		dialer:     websocket.DefaultDialer,
@@ -156,19 +180,77 @@ func (c *Consumer) worker(ctx context.Context) {
 			err := json.Unmarshal(j.message, &msg)
 			if err != nil {
 				c.logger.Error("error deserializing message", "source", j.source.Key(), "err", err)
-				return
+				c.deadLetter(j.source, Message{EventJson: j.message}, err, 1)
+				continue
 			}
 
 			// update cursor
 			c.cfg.CursorStore.Set(j.source.Key(), time.Now().UnixNano())
 
-			if err := c.cfg.ProcessFunc(ctx, j.source, msg); err != nil {
-				c.logger.Error("error processing message", "source", j.source, "err", err)
+			if attempts, err := c.process(ctx, j.source, msg); err != nil {
+				c.logger.Error("error processing message", "source", j.source, "nsid", msg.Nsid, "rkey", msg.Rkey, "attempts", attempts, "err", err)
+				c.deadLetter(j.source, msg, err, attempts)
 			}
 		}
 	}
 }
 
+// process tries a message up to MaxAttempts times, backing off a little
+// longer after every failure.
+func (c *Consumer) process(ctx context.Context, source Source, msg Message) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := c.cfg.ProcessFunc(ctx, source, msg)
+		if err == nil || attempt >= c.cfg.MaxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+// deadLetter keeps a message that was given up on, so that the workers can
+// move on.
+func (c *Consumer) deadLetter(source Source, msg Message, err error, attempts int) {
+	if c.cfg.DeadLetters == nil {
+		return
+	}
+
+	dl := DeadLetter{
+		Source:   source.Key(),
+		Message:  msg,
+		Error:    err.Error(),
+		Attempts: attempts,
+		Created:  time.Now(),
+	}
+	if err := c.cfg.DeadLetters.AddDeadLetter(dl); err != nil {
+		c.logger.Error("failed to store dead letter", "source", source.Key(), "nsid", msg.Nsid, "rkey", msg.Rkey, "err", err)
+	}
+}
+
+// Replay processes a dead letter again, once. It must have come from one of
+// the consumer's sources.
+func (c *Consumer) Replay(ctx context.Context, dl DeadLetter) error {
+	c.cfgMu.RLock()
+	var source Source
+	for s := range c.cfg.Sources {
+		if s.Key() == dl.Source {
+			source = s
+			break
+		}
+	}
+	c.cfgMu.RUnlock()
+
+	if source == nil {
+		return fmt.Errorf("unknown source %q", dl.Source)
+	}
+
+	return c.cfg.ProcessFunc(ctx, source, dl.Message)
+}
+
 func (c *Consumer) startConnectionLoop(ctx context.Context, source Source) {
 	defer c.wg.Done()
 
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -47,6 +48,11 @@ func Command() *cli.Command {
 			},
 		},
 		Commands: []*cli.Command{
+			{
+				Name:   "pre-receive",
+				Usage:  "asks the knot whether a push may go ahead (waits for stdin)",
+				Action: preReceive,
+			},
 			{
 				Name:   "post-recieve",
 				Usage:  "sends a post-recieve hook to the knot (waits for stdin)",
@@ -56,6 +62,51 @@ func Command() *cli.Command {
 	}
 }
 
+// preReceive refuses the push when the knot does. Git sets
+// GIT_QUARANTINE_PATH to where the pushed objects wait until the hook passes.
+func preReceive(ctx context.Context, cmd *cli.Command) error {
+	gitDir := cmd.String("git-dir")
+	userDid := cmd.String("user-did")
+	endpoint := cmd.String("internal-api")
+
+	// the refs being updated are not needed, but git expects them read
+	io.Copy(io.Discard, os.Stdin)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://"+endpoint+"/hooks/pre-receive", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("X-Git-Dir", gitDir)
+	req.Header.Set("X-Git-User-Did", userDid)
+	req.Header.Set("X-Git-Quarantine-Path", os.Getenv("GIT_QUARANTINE_PATH"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusForbidden {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var data HookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, message := range data.Messages {
+		fmt.Fprintln(os.Stderr, message)
+	}
+
+	if resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("push refused by knot")
+	}
+
+	return nil
+}
+
 func postRecieve(ctx context.Context, cmd *cli.Command) error {
 	gitDir := cmd.String("git-dir")
 	userDid := cmd.String("user-did")
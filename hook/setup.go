@@ -107,25 +107,38 @@ func SetupRepo(config config, path string) error {
 		return fmt.Errorf("%s: %w", path, ErrNoGitRepo)
 	}
 
-	preReceiveD := filepath.Join(path, "hooks", "post-receive.d")
-	if err := os.MkdirAll(preReceiveD, 0755); err != nil {
-		return fmt.Errorf("%s: %w", preReceiveD, ErrCreatingHookDir)
+	// git hook, script in its .d directory, and the hook subcommand the
+	// script runs
+	hooks := []struct {
+		name    string
+		script  string
+		command string
+	}{
+		{"pre-receive", "40-quota.sh", "pre-receive"},
+		{"post-receive", "40-notify.sh", "post-recieve"},
 	}
 
-	notify := filepath.Join(preReceiveD, "40-notify.sh")
-	if err := mkHook(config, notify); err != nil {
-		return fmt.Errorf("%s: %w", notify, ErrCreatingHook)
-	}
+	for _, h := range hooks {
+		hookD := filepath.Join(path, "hooks", h.name+".d")
+		if err := os.MkdirAll(hookD, 0755); err != nil {
+			return fmt.Errorf("%s: %w", hookD, ErrCreatingHookDir)
+		}
 
-	delegate := filepath.Join(path, "hooks", "post-receive")
-	if err := mkDelegate(delegate); err != nil {
-		return fmt.Errorf("%s: %w", delegate, ErrCreatingDelegate)
+		script := filepath.Join(hookD, h.script)
+		if err := mkHook(config, script, h.command); err != nil {
+			return fmt.Errorf("%s: %w", script, ErrCreatingHook)
+		}
+
+		delegate := filepath.Join(path, "hooks", h.name)
+		if err := mkDelegate(delegate); err != nil {
+			return fmt.Errorf("%s: %w", delegate, ErrCreatingDelegate)
+		}
 	}
 
 	return nil
 }
 
-func mkHook(config config, hookPath string) error {
+func mkHook(config config, hookPath, command string) error {
 	executablePath, err := os.Executable()
 	if err != nil {
 		return err
@@ -138,8 +151,8 @@ for ((i=0; i<GIT_PUSH_OPTION_COUNT; i++)); do
     option_var="GIT_PUSH_OPTION_$i"
     push_options+=(-push-option "${!option_var}")
 done
-%s hook -git-dir "$GIT_DIR" -user-did "$GIT_USER_DID" -user-handle "$GIT_USER_HANDLE" -internal-api "%s" "${push_options[@]}" post-recieve
-	`, executablePath, config.internalApi)
+%s hook -git-dir "$GIT_DIR" -user-did "$GIT_USER_DID" -user-handle "$GIT_USER_HANDLE" -internal-api "%s" "${push_options[@]}" %s
+	`, executablePath, config.internalApi, command)
 
 	return os.WriteFile(hookPath, []byte(hookContent), 0755)
 }
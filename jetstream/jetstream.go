@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"math/rand/v2"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -36,7 +39,51 @@ type JetstreamClient struct {
 	mu         sync.RWMutex
 
 	cancel   context.CancelFunc
+	stop     context.CancelFunc
 	cancelMu sync.Mutex
+
+	// cursor just past the last event that was processed, 0 until one is
+	lastTimeUs atomic.Int64
+
+	// health, see Health
+	events      atomic.Int64
+	reconnects  atomic.Int64
+	lastEventAt atomic.Int64
+	rate        atomic.Uint64
+}
+
+// Health is a snapshot of how the stream is doing, logged every minute and
+// served to monitors by the appview.
+type Health struct {
+	// events processed per second, over the last minute
+	EventsPerSecond float64
+	// time since the last event was processed
+	LastEventAge time.Duration
+	// how far the cursor lags behind the present
+	Lag        time.Duration
+	Reconnects int64
+}
+
+func (j *JetstreamClient) Health() Health {
+	h := Health{
+		EventsPerSecond: math.Float64frombits(j.rate.Load()),
+		Lag:             j.Lag(),
+		Reconnects:      j.reconnects.Load(),
+	}
+	if at := j.lastEventAt.Load(); at != 0 {
+		h.LastEventAge = time.Since(time.Unix(0, at)).Round(time.Second)
+	}
+	return h
+}
+
+// Lag is how far the processed events lag behind the present, 0 until an
+// event was processed.
+func (j *JetstreamClient) Lag() time.Duration {
+	lastTimeUs := j.lastTimeUs.Load()
+	if lastTimeUs == 0 {
+		return 0
+	}
+	return behind(lastTimeUs)
 }
 
 func (j *JetstreamClient) AddDid(did string) {
@@ -85,6 +132,18 @@ func (j *JetstreamClient) withDidFilter(processFunc processor) processor {
 	}
 }
 
+// withCursor keeps track of how far the stream was processed, events that
+// are filtered out count as processed too.
+func (j *JetstreamClient) withCursor(processFunc processor) processor {
+	return func(ctx context.Context, evt *models.Event) error {
+		err := processFunc(ctx, evt)
+		j.lastTimeUs.Store(evt.TimeUS + 1)
+		j.lastEventAt.Store(time.Now().UnixNano())
+		j.events.Add(1)
+		return err
+	}
+}
+
 func NewJetstreamClient(endpoint, ident string, collections []string, cfg *client.ClientConfig, logger *slog.Logger, db DB, waitForDid, logDids bool) (*JetstreamClient, error) {
 	if cfg == nil {
 		cfg = client.DefaultClientConfig()
@@ -112,7 +171,7 @@ func NewJetstreamClient(endpoint, ident string, collections []string, cfg *clien
 func (j *JetstreamClient) StartJetstream(ctx context.Context, processFunc func(context.Context, *models.Event) error) error {
 	logger := j.l
 
-	sched := sequential.NewScheduler(j.ident, logger, j.withDidFilter(processFunc))
+	sched := sequential.NewScheduler(j.ident, logger, j.withCursor(j.withDidFilter(processFunc)))
 
 	client, err := client.NewClient(j.cfg, logger, sched)
 	if err != nil {
@@ -120,6 +179,11 @@ func (j *JetstreamClient) StartJetstream(ctx context.Context, processFunc func(c
 	}
 	j.client = client
 
+	ctx, stop := context.WithCancel(ctx)
+	j.cancelMu.Lock()
+	j.stop = stop
+	j.cancelMu.Unlock()
+
 	go func() {
 		if j.waitForDid {
 			for len(j.wantedDids) == 0 {
@@ -139,6 +203,7 @@ func (j *JetstreamClient) StartJetstream(ctx context.Context, processFunc func(c
 
 func (j *JetstreamClient) connectAndRead(ctx context.Context) {
 	l := log.FromContext(ctx)
+	var b backoff
 	for {
 		cursor := j.getLastTimeUs(ctx)
 
@@ -147,38 +212,110 @@ func (j *JetstreamClient) connectAndRead(ctx context.Context) {
 		j.cancel = cancel
 		j.cancelMu.Unlock()
 
-		if err := j.client.ConnectAndRead(connCtx, cursor); err != nil {
-			l.Error("error reading jetstream", "error", err)
-			cancel()
-			continue
+		connected := time.Now()
+		err := j.client.ConnectAndRead(connCtx, cursor)
+		cancel()
+
+		if ctx.Err() != nil {
+			l.Info("context done, stopping jetstream")
+			return
 		}
 
+		wait := b.next(time.Since(connected))
+
+		l.Warn(
+			"jetstream connection dropped, reconnecting",
+			"error", err,
+			"attempt", b.attempt,
+			"reconnects", j.reconnects.Add(1),
+			"wait", wait,
+			"behind", j.Lag(),
+		)
+
 		select {
 		case <-ctx.Done():
 			l.Info("context done, stopping jetstream")
 			return
-		case <-connCtx.Done():
-			l.Info("connection context done, reconnecting")
-			continue
+		case <-time.After(wait):
 		}
 	}
 }
 
-// save cursor periodically
+// stableConnection is how long a connection has to hold up for the backoff
+// to start over once it drops.
+const stableConnection = time.Minute
+
+// backoff counts the attempts at reconnecting since the last connection that
+// held up.
+type backoff struct {
+	attempt int
+}
+
+// next is how long to wait before reconnecting, after a connection that held
+// up for held.
+func (b *backoff) next(held time.Duration) time.Duration {
+	if held > stableConnection {
+		b.attempt = 0
+	}
+	wait := reconnectBackoff(b.attempt)
+	b.attempt++
+	return wait
+}
+
+// reconnectBackoff doubles from a second up to two minutes, jittered so that
+// services don't reconnect in lockstep.
+func reconnectBackoff(attempt int) time.Duration {
+	wait := 2 * time.Minute
+	if attempt < 7 {
+		wait = min(time.Second<<attempt, wait)
+	}
+	return wait/2 + rand.N(wait/2)
+}
+
+// save the cursor periodically, so that a crash only reprocesses the last
+// minute of events at worst
 func (j *JetstreamClient) periodicLastTimeSave(ctx context.Context) {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
+	var events int64
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			j.db.SaveLastTimeUs(time.Now().UnixMicro())
+			processed := j.events.Load()
+			j.rate.Store(math.Float64bits(float64(processed-events) / time.Minute.Seconds()))
+			events = processed
+
+			// nothing processed yet, the stored cursor still stands
+			lastTimeUs := j.lastTimeUs.Load()
+			if lastTimeUs == 0 {
+				continue
+			}
+
+			if err := j.db.SaveLastTimeUs(lastTimeUs); err != nil {
+				j.l.Error("failed to save last time us", "error", err)
+				continue
+			}
+			h := j.Health()
+			j.l.Info(
+				"saved last time_us",
+				"time_us", lastTimeUs,
+				"behind", h.Lag,
+				"events_per_second", h.EventsPerSecond,
+				"last_event_age", h.LastEventAge,
+				"reconnects", h.Reconnects,
+			)
 		}
 	}
 }
 
+// behind is how far a cursor lags behind the present.
+func behind(lastTimeUs int64) time.Duration {
+	return time.Since(time.UnixMicro(lastTimeUs)).Round(time.Second)
+}
+
 func (j *JetstreamClient) getLastTimeUs(ctx context.Context) *int64 {
 	l := log.FromContext(ctx)
 	lastTimeUs, err := j.db.GetLastTimeUs()
@@ -201,10 +338,38 @@ func (j *JetstreamClient) getLastTimeUs(ctx context.Context) *int64 {
 		}
 	}
 
-	l.Info("found last time_us", "time_us", lastTimeUs)
+	// when reconnecting, the events processed since the last save are not
+	// worth processing again
+	if processed := j.lastTimeUs.Load(); processed > lastTimeUs {
+		lastTimeUs = processed
+	}
+
+	l.Info("resuming from last time_us", "time_us", lastTimeUs, "behind", behind(lastTimeUs))
 	return &lastTimeUs
 }
 
+// Shutdown saves the cursor and stops the client, also while it waits to
+// reconnect.
+func (j *JetstreamClient) Shutdown() {
+	// events that arrived but weren't processed yet are picked up on the
+	// next start
+	if lastTimeUs := j.lastTimeUs.Load(); lastTimeUs != 0 {
+		if err := j.db.SaveLastTimeUs(lastTimeUs); err != nil {
+			j.l.Error("Failed to save last time during shutdown", "error", err)
+		}
+		j.l.Info("Saved lastTimeUs before shutdown", "lastTimeUs", lastTimeUs)
+	}
+
+	j.cancelMu.Lock()
+	defer j.cancelMu.Unlock()
+	if j.cancel != nil {
+		j.cancel()
+	}
+	if j.stop != nil {
+		j.stop()
+	}
+}
+
 func (j *JetstreamClient) saveIfKilled(ctx context.Context) context.Context {
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 
@@ -223,18 +388,7 @@ func (j *JetstreamClient) saveIfKilled(ctx context.Context) context.Context {
 		sig := <-sigChan
 		j.l.Info("Received signal, initiating graceful shutdown", "signal", sig)
 
-		lastTimeUs := time.Now().UnixMicro()
-		if err := j.db.SaveLastTimeUs(lastTimeUs); err != nil {
-			j.l.Error("Failed to save last time during shutdown", "error", err)
-		}
-		j.l.Info("Saved lastTimeUs before shutdown", "lastTimeUs", lastTimeUs)
-
-		j.cancelMu.Lock()
-		if j.cancel != nil {
-			j.cancel()
-		}
-		j.cancelMu.Unlock()
-
+		j.Shutdown()
 		cancel()
 
 		os.Exit(0)
@@ -0,0 +1,89 @@
+package jetstream
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/jetstream/pkg/client"
+	"github.com/bluesky-social/jetstream/pkg/client/schedulers/sequential"
+	"github.com/bluesky-social/jetstream/pkg/models"
+)
+
+func TestBackoff(t *testing.T) {
+	var b backoff
+
+	// every wait is jittered within the upper half of its bound
+	bound := time.Second
+	for range 10 {
+		wait := b.next(time.Second)
+		if wait < bound/2 || wait >= bound {
+			t.Fatalf("attempt %d: expected a wait in [%s, %s), got %s", b.attempt, bound/2, bound, wait)
+		}
+		bound = min(bound*2, 2*time.Minute)
+	}
+
+	// a connection that held up starts over from a second
+	if wait := b.next(2 * stableConnection); wait >= time.Second {
+		t.Fatalf("expected the backoff to start over, got %s", wait)
+	}
+	if b.attempt != 1 {
+		t.Fatalf("expected the first attempt, got %d", b.attempt)
+	}
+}
+
+type memDB struct {
+	lastTimeUs atomic.Int64
+}
+
+func (d *memDB) GetLastTimeUs() (int64, error) { return d.lastTimeUs.Load(), nil }
+func (d *memDB) SaveLastTimeUs(t int64) error  { d.lastTimeUs.Store(t); return nil }
+
+func TestShutdownWhileReconnecting(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	// nothing listens there, so every connection drops right away
+	cfg := client.DefaultClientConfig()
+	cfg.WebsocketURL = "ws://127.0.0.1:1/subscribe"
+
+	j, err := NewJetstreamClient("", "test", nil, cfg, logger, &memDB{}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := sequential.NewScheduler("test", logger, func(context.Context, *models.Event) error { return nil })
+	j.client, err = client.NewClient(cfg, logger, sched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	j.stop = stop
+
+	done := make(chan struct{})
+	go func() {
+		j.connectAndRead(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for j.reconnects.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the connection to drop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the first wait is at least half a second
+	start := time.Now()
+	j.Shutdown()
+	select {
+	case <-done:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected the client to stop without waiting to reconnect")
+	}
+	if took := time.Since(start); took > 200*time.Millisecond {
+		t.Fatalf("expected shutdown to be prompt, took %s", took)
+	}
+}
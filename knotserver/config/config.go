@@ -12,6 +12,14 @@ type Repo struct {
 	ScanPath   string   `env:"SCAN_PATH, default=/home/git"`
 	Readme     []string `env:"README"`
 	MainBranch string   `env:"MAIN_BRANCH, default=main"`
+
+	// bytes each user's repos may take up on disk, pushes past it are
+	// refused; 0 means no quota
+	QuotaBytes int64 `env:"QUOTA_BYTES, default=0"`
+
+	// bytes a request to commit files from the web may take, with the
+	// files in it base64 encoded
+	MaxWebCommitBytes int64 `env:"MAX_WEB_COMMIT_BYTES, default=67108864"`
 }
 
 type Server struct {
@@ -32,6 +40,10 @@ type Git struct {
 	// user name & email used as committer
 	UserName  string `env:"USER_NAME, default=Tangled"`
 	UserEmail string `env:"USER_EMAIL, default=noreply@tangled.sh"`
+
+	// how similar, in percent, a deleted and an added file must be to be
+	// shown as a rename in commit diffs; 0 turns detection off
+	RenameThreshold int `env:"RENAME_THRESHOLD, default=50"`
 }
 
 func (s Server) Did() syntax.DID {
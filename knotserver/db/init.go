@@ -55,6 +55,10 @@ func Setup(dbPath string) (*DB, error) {
 			created integer not null default (strftime('%s', 'now')),
 			primary key (rkey, nsid)
 		);
+
+		create table if not exists private_repos (
+			repo text primary key -- did/name
+		);
 	`)
 	if err != nil {
 		return nil, err
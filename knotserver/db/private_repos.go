@@ -0,0 +1,21 @@
+package db
+
+// SetRepoPrivate marks repo, in did/name form, as private or public.
+func (d *DB) SetRepoPrivate(repo string, private bool) error {
+	if !private {
+		_, err := d.db.Exec(`delete from private_repos where repo = ?`, repo)
+		return err
+	}
+
+	_, err := d.db.Exec(`insert or ignore into private_repos (repo) values (?)`, repo)
+	return err
+}
+
+func (d *DB) IsRepoPrivate(repo string) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`select count(1) from private_repos where repo = ?`, repo).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
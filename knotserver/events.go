@@ -7,8 +7,14 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/gorilla/websocket"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/db"
 	"tangled.org/core/log"
+	"tangled.org/core/rbac"
+	"tangled.org/core/xrpc/serviceauth"
 )
 
 var upgrader = websocket.Upgrader{
@@ -56,7 +62,7 @@ func (h *Knot) Events(w http.ResponseWriter, r *http.Request) {
 
 	// complete backfill first before going to live data
 	l.Debug("going through backfill", "cursor", cursor)
-	if err := h.streamOps(conn, &cursor); err != nil {
+	if err := h.streamOps(r, conn, &cursor); err != nil {
 		l.Error("failed to backfill", "err", err)
 		return
 	}
@@ -70,7 +76,7 @@ func (h *Knot) Events(w http.ResponseWriter, r *http.Request) {
 		case <-ch:
 			// we have been notified of new data
 			l.Debug("going through live data", "cursor", cursor)
-			if err := h.streamOps(conn, &cursor); err != nil {
+			if err := h.streamOps(r, conn, &cursor); err != nil {
 				l.Error("failed to stream", "err", err)
 				return
 			}
@@ -83,14 +89,35 @@ func (h *Knot) Events(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Knot) streamOps(conn *websocket.Conn, cursor *int64) error {
+// streamOps writes the events after cursor to conn. Those of private repos
+// are left out unless the caller of r can read the repo, the cursor moves
+// past them all the same.
+func (h *Knot) streamOps(r *http.Request, conn *websocket.Conn, cursor *int64) error {
 	events, err := h.db.GetEvents(*cursor)
 	if err != nil {
 		h.l.Error("failed to fetch events from db", "err", err, "cursor", cursor)
 		return err
 	}
 
+	readable := make(map[string]bool)
+
 	for _, event := range events {
+		if repo, ok := eventRepo(event); ok {
+			canRead, seen := readable[repo]
+			if !seen {
+				canRead, err = h.canStreamRepo(r, repo)
+				if err != nil {
+					h.l.Error("failed to check repo access", "repo", repo, "err", err)
+					return err
+				}
+				readable[repo] = canRead
+			}
+			if !canRead {
+				*cursor = event.Created
+				continue
+			}
+		}
+
 		// first extract the inner json into a map
 		var eventJson map[string]any
 		err := json.Unmarshal([]byte(event.EventJson), &eventJson)
@@ -118,3 +145,53 @@ func (h *Knot) streamOps(conn *websocket.Conn, cursor *int64) error {
 
 	return nil
 }
+
+// canStreamRepo reports whether the caller of r can see the events of repo,
+// in did/name form. Those of private repos need a service auth token from
+// someone with a role in the repo.
+func (h *Knot) canStreamRepo(r *http.Request, repo string) (bool, error) {
+	private, err := h.db.IsRepoPrivate(repo)
+	if err != nil {
+		return false, err
+	}
+	if !private {
+		return true, nil
+	}
+
+	actorDid, ok := r.Context().Value(serviceauth.ActorDid).(syntax.DID)
+	if !ok || !serviceauth.HasScope(r.Context(), serviceauth.ScopeApi) {
+		return false, nil
+	}
+
+	return h.e.IsRepoReadAllowed(actorDid.String(), rbac.ThisServer, repo)
+}
+
+// eventRepo returns the repo an event is about, in did/name form.
+func eventRepo(event db.Event) (string, bool) {
+	var did, name string
+	switch event.Nsid {
+	case tangled.GitRefUpdateNSID:
+		var refUpdate tangled.GitRefUpdate
+		if err := json.Unmarshal([]byte(event.EventJson), &refUpdate); err != nil {
+			return "", false
+		}
+		did, name = refUpdate.RepoDid, refUpdate.RepoName
+	case tangled.PipelineNSID:
+		var pipeline tangled.Pipeline
+		if err := json.Unmarshal([]byte(event.EventJson), &pipeline); err != nil {
+			return "", false
+		}
+		if pipeline.TriggerMetadata == nil || pipeline.TriggerMetadata.Repo == nil {
+			return "", false
+		}
+		did, name = pipeline.TriggerMetadata.Repo.Did, pipeline.TriggerMetadata.Repo.Repo
+	default:
+		return "", false
+	}
+
+	repo, err := securejoin.SecureJoin(did, name)
+	if err != nil {
+		return "", false
+	}
+	return repo, true
+}
@@ -0,0 +1,109 @@
+package knotserver
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/gorilla/websocket"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/db"
+	"tangled.org/core/notifier"
+	"tangled.org/core/rbac"
+	"tangled.org/core/xrpc/serviceauth"
+)
+
+func TestEventsOfPrivateRepos(t *testing.T) {
+	dir := t.TempDir()
+	d, err := db.Setup(filepath.Join(dir, "knot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := rbac.NewEnforcer(filepath.Join(dir, "acl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddKnot(rbac.ThisServer); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddRepo("did:plc:alice", rbac.ThisServer, "did:plc:alice/secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetRepoPrivate("did:plc:alice/secret", true); err != nil {
+		t.Fatal(err)
+	}
+
+	n := notifier.New()
+	h := &Knot{db: d, e: e, l: slog.Default(), n: &n}
+
+	insert := func(rkey, nsid string, record any) {
+		eventJson, err := json.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.InsertEvent(db.Event{Rkey: rkey, Nsid: nsid, EventJson: string(eventJson)}, h.n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refUpdate := func(name string) tangled.GitRefUpdate {
+		return tangled.GitRefUpdate{RepoDid: "did:plc:alice", RepoName: name, Ref: "refs/heads/main"}
+	}
+	pipeline := func(name string) tangled.Pipeline {
+		return tangled.Pipeline{TriggerMetadata: &tangled.Pipeline_TriggerMetadata{
+			Repo: &tangled.Pipeline_TriggerRepo{Did: "did:plc:alice", Repo: name},
+		}}
+	}
+	insert("public-push", tangled.GitRefUpdateNSID, refUpdate("public"))
+	insert("secret-push", tangled.GitRefUpdateNSID, refUpdate("secret"))
+	insert("secret-pipeline", tangled.PipelineNSID, pipeline("secret"))
+	insert("public-pipeline", tangled.PipelineNSID, pipeline("public"))
+
+	// rkeys of the events streamed to a subscriber, until there are none
+	subscribe := func(handler http.Handler) []string {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?cursor=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		var rkeys []string
+		for {
+			conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return rkeys
+			}
+			var event struct {
+				Rkey string `json:"rkey"`
+			}
+			if err := json.Unmarshal(msg, &event); err != nil {
+				t.Fatal(err)
+			}
+			rkeys = append(rkeys, event.Rkey)
+		}
+	}
+
+	anonymous := subscribe(http.HandlerFunc(h.Events))
+	if want := []string{"public-push", "public-pipeline"}; !slices.Equal(anonymous, want) {
+		t.Errorf("expected %v for an anonymous subscriber, got %v", want, anonymous)
+	}
+
+	owner := subscribe(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), serviceauth.ActorDid, syntax.DID("did:plc:alice"))
+		h.Events(w, r.WithContext(ctx))
+	}))
+	if want := []string{"public-push", "secret-push", "secret-pipeline", "public-pipeline"}; !slices.Equal(owner, want) {
+		t.Errorf("expected %v for the owner, got %v", want, owner)
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/go-chi/chi/v5"
 	"tangled.org/core/knotserver/git/service"
+	"tangled.org/core/rbac"
+	"tangled.org/core/xrpc/serviceauth"
 )
 
 func (h *Knot) InfoRefs(w http.ResponseWriter, r *http.Request) {
@@ -39,6 +43,11 @@ func (h *Knot) InfoRefs(w http.ResponseWriter, r *http.Request) {
 	serviceName := r.URL.Query().Get("service")
 	switch serviceName {
 	case "git-upload-pack":
+		if !h.canReadRepo(r, repoName) {
+			h.denyRead(w, r)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
 		w.Header().Set("Connection", "Keep-Alive")
 		w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
@@ -50,7 +59,19 @@ func (h *Knot) InfoRefs(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	case "git-receive-pack":
-		h.RejectPush(w, r, name)
+		if !h.canPushRepo(w, r, repoName, name) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-git-receive-pack-advertisement")
+		w.Header().Set("Connection", "Keep-Alive")
+		w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+		w.WriteHeader(http.StatusOK)
+
+		if err := cmd.ReceivePackInfoRefs(); err != nil {
+			h.l.Error("git: process failed", "handler", "InfoRefs", "service", serviceName, "error", err)
+			return
+		}
 	default:
 		gitError(w, fmt.Sprintf("service unsupported: '%s'", serviceName), http.StatusForbidden)
 	}
@@ -66,6 +87,11 @@ func (h *Knot) UploadPack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.canReadRepo(r, filepath.Join(did, name)) {
+		h.denyRead(w, r)
+		return
+	}
+
 	const expectedContentType = "application/x-git-upload-pack-request"
 	contentType := r.Header.Get("Content-Type")
 	if contentType != expectedContentType {
@@ -108,14 +134,60 @@ func (h *Knot) UploadPack(w http.ResponseWriter, r *http.Request) {
 func (h *Knot) ReceivePack(w http.ResponseWriter, r *http.Request) {
 	did := chi.URLParam(r, "did")
 	name := chi.URLParam(r, "name")
-	_, err := securejoin.SecureJoin(h.c.Repo.ScanPath, filepath.Join(did, name))
+	repo, err := securejoin.SecureJoin(h.c.Repo.ScanPath, filepath.Join(did, name))
 	if err != nil {
 		gitError(w, err.Error(), http.StatusForbidden)
 		h.l.Error("git: failed to secure join repo path", "handler", "ReceivePack", "error", err)
 		return
 	}
 
-	h.RejectPush(w, r, name)
+	if !h.canPushRepo(w, r, filepath.Join(did, name), name) {
+		return
+	}
+
+	const expectedContentType = "application/x-git-receive-pack-request"
+	contentType := r.Header.Get("Content-Type")
+	if contentType != expectedContentType {
+		gitError(w, fmt.Sprintf("Expected Content-Type: '%s', but received '%s'.", expectedContentType, contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var bodyReader io.ReadCloser = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			gitError(w, err.Error(), http.StatusInternalServerError)
+			h.l.Error("git: failed to create gzip reader", "handler", "ReceivePack", "error", err)
+			return
+		}
+		defer gzipReader.Close()
+		bodyReader = gzipReader
+	}
+
+	actorDid := r.Context().Value(serviceauth.ActorDid).(syntax.DID)
+
+	w.Header().Set("Content-Type", "application/x-git-receive-pack-result")
+	w.Header().Set("Connection", "Keep-Alive")
+	w.Header().Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+
+	h.l.Info("git: executing git-receive-pack", "handler", "ReceivePack", "repo", repo, "user", actorDid)
+
+	cmd := service.ServiceCommand{
+		GitProtocol: r.Header.Get("Git-Protocol"),
+		Dir:         repo,
+		Stdout:      w,
+		Stdin:       bodyReader,
+		// the post-receive hook reads the pusher from here, as it does for
+		// pushes over ssh
+		Env: append(os.Environ(), fmt.Sprintf("GIT_USER_DID=%s", actorDid)),
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if err := cmd.ReceivePack(); err != nil {
+		h.l.Error("git: failed to execute git-receive-pack", "handler", "ReceivePack", "error", err)
+		return
+	}
 }
 
 func (h *Knot) RejectPush(w http.ResponseWriter, r *http.Request, unqualifiedRepoName string) {
@@ -124,7 +196,7 @@ func (h *Knot) RejectPush(w http.ResponseWriter, r *http.Request, unqualifiedRep
 	w.Header().Set("content-type", "text/plain; charset=UTF-8")
 	w.WriteHeader(http.StatusForbidden)
 
-	fmt.Fprintf(w, "Pushes are only supported over SSH.")
+	fmt.Fprintf(w, "You are not allowed to push to this repository. Pushes over HTTPS need an app password with the %s scope, or push over SSH.", serviceauth.ScopeGitWrite)
 
 	// If the appview gave us the repository owner's handle we can attempt to
 	// construct the correct ssh url.
@@ -150,3 +222,64 @@ func gitError(w http.ResponseWriter, msg string, status int) {
 	w.WriteHeader(status)
 	fmt.Fprintf(w, "%s\n", msg)
 }
+
+// challenge asks git for credentials, which it answers with basic auth.
+func (h *Knot) challenge(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", h.c.Server.Hostname))
+	gitError(w, "authentication required", http.StatusUnauthorized)
+}
+
+// denyRead answers a fetch of a repo the caller can't read. Anonymous
+// callers are asked to log in, everyone else can't see the repo at all.
+func (h *Knot) denyRead(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(serviceauth.ActorDid).(syntax.DID); !ok {
+		h.challenge(w)
+		return
+	}
+	gitError(w, "repository not found", http.StatusNotFound)
+}
+
+// canPushRepo reports whether the caller of r can push to repo, in did/name
+// form, and answers the request when they can't.
+func (h *Knot) canPushRepo(w http.ResponseWriter, r *http.Request, repo, unqualifiedRepoName string) bool {
+	actorDid, ok := r.Context().Value(serviceauth.ActorDid).(syntax.DID)
+	if !ok {
+		h.challenge(w)
+		return false
+	}
+
+	if !serviceauth.HasScope(r.Context(), serviceauth.ScopeGitWrite) {
+		h.RejectPush(w, r, unqualifiedRepoName)
+		return false
+	}
+
+	ok, err := h.e.IsPushAllowed(actorDid.String(), rbac.ThisServer, repo)
+	if err != nil || !ok {
+		h.RejectPush(w, r, unqualifiedRepoName)
+		return false
+	}
+
+	return true
+}
+
+// canReadRepo reports whether the caller of r can fetch repo, in did/name
+// form. Private repos need a service auth token or an app password of
+// someone with a role in the repo.
+func (h *Knot) canReadRepo(r *http.Request, repo string) bool {
+	private, err := h.db.IsRepoPrivate(repo)
+	if err != nil {
+		h.l.Error("git: failed to check repo visibility", "repo", repo, "error", err)
+		return false
+	}
+	if !private {
+		return true
+	}
+
+	actorDid, ok := r.Context().Value(serviceauth.ActorDid).(syntax.DID)
+	if !ok || !serviceauth.HasScope(r.Context(), serviceauth.ScopeGitRead) {
+		return false
+	}
+
+	ok, err = h.e.IsRepoReadAllowed(actorDid.String(), rbac.ThisServer, repo)
+	return err == nil && ok
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -111,7 +112,33 @@ func (g *GitRepo) Branches() ([]types.Branch, error) {
 	return branches, nil
 }
 
+// ErrDefaultBranch is returned when deleting the branch that HEAD points
+// at, which would leave the repository without a default branch.
+var ErrDefaultBranch = errors.New("cannot delete the default branch")
+
 func (g *GitRepo) DeleteBranch(branch string) error {
+	defaultBranch, err := g.headBranch()
+	if err != nil {
+		return err
+	}
+	if branch == defaultBranch {
+		return ErrDefaultBranch
+	}
+
 	ref := plumbing.NewBranchReferenceName(branch)
 	return g.r.Storer.RemoveReference(ref)
 }
+
+// headBranch is the branch HEAD points at, read straight from the ref
+// store, so it works before the branch has any commits.
+func (g *GitRepo) headBranch() (string, error) {
+	head, err := g.r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", fmt.Errorf("reading HEAD: %w", err)
+	}
+	if head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return "", nil
+	}
+
+	return head.Target().Short(), nil
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestDeleteBranchKeepsDefault(t *testing.T) {
+	dir := t.TempDir()
+	r, err := gogit.PlainInit(dir, true)
+	assert.NoError(t, err)
+
+	// HEAD of a fresh repo points at a branch that has no commits yet
+	g, err := PlainOpen(dir)
+	assert.NoError(t, err)
+	unborn, err := g.headBranch()
+	assert.NoError(t, err)
+	assert.Equal(t, "master", unborn)
+	unborn, err = g.FindMainBranch()
+	assert.NoError(t, err)
+	assert.Equal(t, "master", unborn)
+
+	tree, err := r.Storer.SetEncodedObject(func() plumbing.EncodedObject {
+		obj := r.Storer.NewEncodedObject()
+		assert.NoError(t, (&object.Tree{}).Encode(obj))
+		return obj
+	}())
+	assert.NoError(t, err)
+
+	sig := object.Signature{Name: "a", Email: "a@example.com", When: time.Now()}
+	obj := r.Storer.NewEncodedObject()
+	assert.NoError(t, (&object.Commit{Author: sig, Committer: sig, Message: "init", TreeHash: tree}).Encode(obj))
+	commit, err := r.Storer.SetEncodedObject(obj)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"main", "feature", "topic"} {
+		ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), commit)
+		assert.NoError(t, r.Storer.SetReference(ref))
+	}
+	assert.NoError(t, g.SetDefaultBranch("main"))
+
+	main, err := g.FindMainBranch()
+	assert.NoError(t, err)
+	assert.Equal(t, "main", main)
+
+	assert.IsError(t, g.DeleteBranch("main"), ErrDefaultBranch)
+	assert.NoError(t, g.DeleteBranch("topic"))
+
+	// the guard follows the default branch around
+	assert.NoError(t, g.SetDefaultBranch("feature"))
+	head, err := g.headBranch()
+	assert.NoError(t, err)
+	assert.Equal(t, "feature", head)
+	assert.IsError(t, g.DeleteBranch("feature"), ErrDefaultBranch)
+	assert.NoError(t, g.DeleteBranch("main"))
+}
@@ -0,0 +1,309 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNoChanges    = errors.New("the commit changes nothing")
+	ErrInvalidPath  = errors.New("invalid file path")
+	ErrBranchMoved  = errors.New("the branch was updated in the meantime")
+	ErrPathConflict = errors.New("a file and a directory would share a path")
+	ErrFileChanged  = errors.New("the file was changed in the meantime")
+)
+
+// FileWrite is a file put in place, or removed, by a commit made with
+// CommitFiles.
+type FileWrite struct {
+	Path    string
+	Content []byte
+	// From is where the file was before, if it is being renamed. The file
+	// there is removed.
+	From string
+	// Executable only applies to new files; existing ones keep their mode.
+	Executable bool
+	// Delete removes the file at Path, and ignores the rest.
+	Delete bool
+	// ExpectedBlob, if set, is the blob the file (at From, when renaming)
+	// must still have on the branch; the commit is refused otherwise.
+	ExpectedBlob string
+}
+
+type CommitFilesOptions struct {
+	Branch        string
+	CommitMessage string
+
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+
+	// PusherDid is who the commit is pushed as, for the hooks
+	PusherDid string
+}
+
+// CommitFiles writes files to a branch as a single commit on top of its tip,
+// or as its first commit if it has none. The commit is pushed to the repo
+// like any other, so its hooks run as they would for a push. Its objects
+// are written elsewhere until then, so that they arrive with the push and
+// count towards the quota of the owner.
+func (g *GitRepo) CommitFiles(files []FileWrite, opts CommitFilesOptions) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "git-commit-files-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	objects := filepath.Join(tmpDir, "objects")
+	if err := os.Mkdir(objects, 0755); err != nil {
+		return "", err
+	}
+
+	env := []string{
+		"GIT_OBJECT_DIRECTORY=" + objects,
+		"GIT_ALTERNATE_OBJECT_DIRECTORIES=" + filepath.Join(g.path, "objects"),
+	}
+	c := g.commitCmd(tmpDir, env)
+	commit, err := c.commit(files, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// git leaves the object directories of the sending side out of the
+	// environment of the receiving one
+	var stderr bytes.Buffer
+	push := exec.Command("git", "-C", g.path, "push", "--quiet", g.path, commit+":refs/heads/"+opts.Branch)
+	push.Env = append(append(os.Environ(), env...), "GIT_USER_DID="+opts.PusherDid)
+	push.Stderr = &stderr
+	if err := push.Run(); err != nil {
+		if strings.Contains(stderr.String(), "[rejected]") {
+			return "", ErrBranchMoved
+		}
+		return "", fmt.Errorf("pushing commit: %w: %s", err, stderr.String())
+	}
+
+	return commit, nil
+}
+
+// FilesPatch makes the commit that CommitFiles would, and returns it as a
+// patch in the format of git format-patch. The repo is left as it was: the
+// objects of the commit are written elsewhere and thrown away.
+func (g *GitRepo) FilesPatch(files []FileWrite, opts CommitFilesOptions) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "git-files-patch-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	objects := filepath.Join(tmpDir, "objects")
+	if err := os.Mkdir(objects, 0755); err != nil {
+		return "", err
+	}
+
+	c := g.commitCmd(tmpDir, []string{
+		"GIT_OBJECT_DIRECTORY=" + objects,
+		"GIT_ALTERNATE_OBJECT_DIRECTORIES=" + filepath.Join(g.path, "objects"),
+	})
+	commit, err := c.commit(files, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// binary files are kept whole, so that the patch applies
+	return c.run(nil, "format-patch", "-1", "--root", "--binary", "--stdout", commit)
+}
+
+// commitCmd runs git on a repo with an index of its own, so that the
+// index of the repo, if it has one, is never touched.
+type commitCmd struct {
+	path string
+	env  []string
+}
+
+func (g *GitRepo) commitCmd(tmpDir string, env []string) *commitCmd {
+	return &commitCmd{
+		path: g.path,
+		env:  append(env, "GIT_INDEX_FILE="+filepath.Join(tmpDir, "index")),
+	}
+}
+
+func (c *commitCmd) run(stdin io.Reader, args ...string) (string, error) {
+	return c.runEnv(nil, stdin, args...)
+}
+
+func (c *commitCmd) runEnv(env []string, stdin io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", c.path}, args...)...)
+	cmd.Env = append(append(os.Environ(), c.env...), env...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+func (c *commitCmd) commit(files []FileWrite, opts CommitFilesOptions) (string, error) {
+	// an unborn branch has no tip, and the commit becomes its first
+	parent, _ := c.run(nil, "rev-parse", "--verify", "--quiet", "refs/heads/"+opts.Branch+"^{commit}")
+	parent = strings.TrimSpace(parent)
+	if parent != "" {
+		if _, err := c.run(nil, "read-tree", parent); err != nil {
+			return "", err
+		}
+	}
+
+	for _, f := range files {
+		p, err := cleanPath(f.Path)
+		if err != nil {
+			return "", err
+		}
+
+		src := p
+		if f.From != "" && !f.Delete {
+			if src, err = cleanPath(f.From); err != nil {
+				return "", err
+			}
+		}
+
+		mode, object, err := c.entry(src)
+		if err != nil {
+			return "", err
+		}
+		if f.ExpectedBlob != "" && object != f.ExpectedBlob {
+			return "", fmt.Errorf("%w: %s", ErrFileChanged, src)
+		}
+		if mode == "" {
+			if f.From != "" || f.Delete {
+				return "", fmt.Errorf("%w: %s does not exist", ErrInvalidPath, src)
+			}
+			mode = "100644"
+			if f.Executable {
+				mode = "100755"
+			}
+		}
+
+		if f.Delete {
+			if err := c.remove(p); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		if src != p {
+			if err := c.remove(src); err != nil {
+				return "", err
+			}
+		}
+
+		blob, err := c.run(bytes.NewReader(f.Content), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return "", err
+		}
+
+		cacheInfo := fmt.Sprintf("%s,%s,%s", mode, strings.TrimSpace(blob), p)
+		if _, err := c.run(nil, "update-index", "--add", "--cacheinfo", cacheInfo); err != nil {
+			if strings.Contains(err.Error(), "appears as both a file and as a directory") {
+				return "", fmt.Errorf("%w: %s", ErrPathConflict, p)
+			}
+			return "", err
+		}
+	}
+
+	tree, err := c.run(nil, "write-tree")
+	if err != nil {
+		if strings.Contains(err.Error(), "appears as both a file and as a directory") {
+			return "", ErrPathConflict
+		}
+		return "", err
+	}
+	tree = strings.TrimSpace(tree)
+
+	args := []string{"commit-tree", tree, "-m", opts.CommitMessage}
+	if parent != "" {
+		parentTree, err := c.run(nil, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(parentTree) == tree {
+			return "", ErrNoChanges
+		}
+		args = append(args, "-p", parent)
+	}
+
+	authorName, authorEmail := opts.AuthorName, opts.AuthorEmail
+	if authorName == "" || authorEmail == "" {
+		authorName, authorEmail = opts.CommitterName, opts.CommitterEmail
+	}
+
+	commit, err := c.runEnv(
+		[]string{
+			"GIT_AUTHOR_NAME=" + authorName,
+			"GIT_AUTHOR_EMAIL=" + authorEmail,
+			"GIT_COMMITTER_NAME=" + opts.CommitterName,
+			"GIT_COMMITTER_EMAIL=" + opts.CommitterEmail,
+		},
+		nil, args...,
+	)
+	if err != nil {
+		return "", fmt.Errorf("creating commit: %w", err)
+	}
+
+	return strings.TrimSpace(commit), nil
+}
+
+// entry is the mode and object of the file at p in the index, or empty
+// strings if there is none.
+func (c *commitCmd) entry(p string) (mode, object string, err error) {
+	out, err := c.run(nil, "ls-files", "--stage", "-z", "--", p)
+	if err != nil {
+		return "", "", err
+	}
+
+	// <mode> <object> <stage>\t<path>, for each match; a directory
+	// matches all of the files in it
+	for _, line := range strings.Split(out, "\x00") {
+		meta, name, ok := strings.Cut(line, "\t")
+		if !ok || name != p {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) < 2 {
+			continue
+		}
+		return fields[0], fields[1], nil
+	}
+
+	return "", "", nil
+}
+
+// remove takes the file at p out of the index.
+func (c *commitCmd) remove(p string) error {
+	// a zero mode takes the file out of the index
+	line := fmt.Sprintf("0 %s\t%s\n", strings.Repeat("0", 40), p)
+	_, err := c.run(strings.NewReader(line), "update-index", "--index-info")
+	return err
+}
+
+// cleanPath checks that p is a path to a file inside a repo.
+func cleanPath(p string) (string, error) {
+	clean := path.Clean(strings.TrimSpace(p))
+	if p == "" || clean != p || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPath, p)
+	}
+	for _, part := range strings.Split(clean, "/") {
+		if strings.EqualFold(part, ".git") {
+			return "", fmt.Errorf("%w: %q", ErrInvalidPath, p)
+		}
+	}
+	return clean, nil
+}
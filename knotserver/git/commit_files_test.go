@@ -0,0 +1,104 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestCommitFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "repo")
+	assert.NoError(t, InitBare(dir, "main"))
+	g, err := PlainOpen(dir)
+	assert.NoError(t, err)
+
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+		assert.NoError(t, err)
+		return strings.TrimSpace(string(out))
+	}
+
+	opts := CommitFilesOptions{
+		Branch:         "main",
+		CommitMessage:  "add files",
+		CommitterName:  "knot",
+		CommitterEmail: "knot@example.com",
+	}
+
+	// the objects of a commit arrive with its push, where the hooks see them
+	quarantined := filepath.Join(t.TempDir(), "quarantined")
+	hook := "#!/bin/sh\nfind \"$GIT_QUARANTINE_PATH\" -type f | wc -l > " + quarantined + "\n"
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "hooks"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "hooks", "pre-receive"), []byte(hook), 0755))
+
+	// the first commit of an empty repo, with a file in a new directory
+	first, err := g.CommitFiles([]FileWrite{
+		{Path: "run.sh", Content: []byte("#!/bin/sh\n"), Executable: true},
+		{Path: "docs/guide/intro.md", Content: []byte("# intro\n")},
+	}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, first, git("rev-parse", "main"))
+	assert.Equal(t, "# intro", git("show", "main:docs/guide/intro.md"))
+	count, err := os.ReadFile(quarantined)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "0", strings.TrimSpace(string(count)))
+
+	// edits keep the mode of the file, and renames move it
+	opts.CommitMessage = "edit files"
+	second, err := g.CommitFiles([]FileWrite{
+		{Path: "scripts/run.sh", From: "run.sh", Content: []byte("#!/bin/sh\necho hi\n")},
+	}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, first, git("rev-parse", "main^"))
+	assert.True(t, strings.HasPrefix(git("ls-tree", "main", "scripts/run.sh"), "100755 "))
+	assert.Equal(t, "", git("ls-tree", "main", "run.sh"))
+	assert.Equal(t, "edit files", git("log", "-1", "--format=%s", "main"))
+
+	_, err = g.CommitFiles([]FileWrite{{Path: "scripts/run.sh", Content: []byte("#!/bin/sh\necho hi\n")}}, opts)
+	assert.IsError(t, err, ErrNoChanges)
+
+	for _, p := range []string{"docs", "scripts/run.sh/x"} {
+		_, err = g.CommitFiles([]FileWrite{{Path: p, Content: []byte("x")}}, opts)
+		assert.IsError(t, err, ErrPathConflict, p)
+	}
+
+	for _, p := range []string{"", "/etc/passwd", "../x", "a/../../x", ".git/config", "a//b", "a/"} {
+		_, err = g.CommitFiles([]FileWrite{{Path: p, Content: []byte("x")}}, opts)
+		assert.IsError(t, err, ErrInvalidPath, p)
+	}
+
+	// deletes only go through if the file is as it was expected to be
+	opts.CommitMessage = "delete files"
+	_, err = g.CommitFiles([]FileWrite{{Path: "docs/guide/intro.md", Delete: true, ExpectedBlob: first}}, opts)
+	assert.IsError(t, err, ErrFileChanged)
+	_, err = g.CommitFiles([]FileWrite{{Path: "docs/missing.md", Delete: true}}, opts)
+	assert.IsError(t, err, ErrInvalidPath)
+
+	third, err := g.CommitFiles([]FileWrite{
+		{Path: "docs/guide/intro.md", Delete: true, ExpectedBlob: git("rev-parse", "main:docs/guide/intro.md")},
+	}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, second, git("rev-parse", "main^"))
+	assert.Equal(t, "", git("ls-tree", "-r", "main", "docs"))
+
+	// a patch leaves the branch and the object store alone
+	patch, err := g.FilesPatch([]FileWrite{{Path: "README.md", Content: []byte("hello\n")}}, opts)
+	assert.NoError(t, err)
+	assert.Contains(t, patch, "Subject: [PATCH] delete files")
+	assert.Contains(t, patch, "+++ b/README.md")
+	assert.Equal(t, third, git("rev-parse", "main"))
+	assert.Equal(t, "", git("ls-tree", "main", "README.md"))
+	hash := exec.Command("git", "-C", dir, "hash-object", "--stdin")
+	hash.Stdin = strings.NewReader("hello\n")
+	blob, err := hash.Output()
+	assert.NoError(t, err)
+	assert.Error(t, exec.Command("git", "-C", dir, "cat-file", "-e", strings.TrimSpace(string(blob))).Run())
+
+	patch, err = g.FilesPatch([]FileWrite{{Path: "logo.png", Content: []byte("\x89PNG\r\n\x1a\n\x00\x00")}}, opts)
+	assert.NoError(t, err)
+	assert.Contains(t, patch, "GIT binary patch")
+}
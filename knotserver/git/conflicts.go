@@ -0,0 +1,116 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/patchutil"
+	"tangled.org/core/types"
+)
+
+// at most this many hunks are shown for each conflicted file
+const maxConflictHunks = 10
+
+// addConflictHunks fills in the regions of each conflicted file that the
+// patch does not apply to, reading the target branch from the checkout in
+// dir. The first conflict reported for a file carries its hunks.
+func addConflictHunks(dir, patchData string, conflicts []ConflictInfo) {
+	diffs, err := patchutil.AsDiff(patchData)
+	if err != nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i := range conflicts {
+		name := conflicts[i].Filename
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		idx := slices.IndexFunc(diffs, func(d *gitdiff.File) bool {
+			return d.OldName == name || d.NewName == name
+		})
+		if idx < 0 || diffs[idx].IsBinary {
+			continue
+		}
+
+		conflicts[i].Hunks = conflictHunks(dir, diffs[idx])
+	}
+}
+
+func conflictHunks(dir string, d *gitdiff.File) []types.ConflictHunk {
+	name := d.OldName
+	if d.IsNew {
+		name = d.NewName
+	}
+
+	var ours []string
+	if path, err := securejoin.SecureJoin(dir, name); err == nil {
+		if content, err := os.ReadFile(path); err == nil {
+			ours = splitLines(content)
+		}
+	}
+
+	var hunks []types.ConflictHunk
+	for _, tf := range d.TextFragments {
+		var base, theirs []string
+		for _, l := range tf.Lines {
+			if l.Op != gitdiff.OpAdd {
+				base = append(base, l.Line)
+			}
+			if l.Op != gitdiff.OpDelete {
+				theirs = append(theirs, l.Line)
+			}
+		}
+
+		// git applies hunks that moved around, only the ones it can't
+		// find anymore conflict
+		if len(base) > 0 && containsRun(ours, base) {
+			continue
+		}
+
+		start := min(max(tf.OldPosition-1, 0), int64(len(ours)))
+		end := min(start+int64(len(base)), int64(len(ours)))
+
+		hunks = append(hunks, types.ConflictHunk{
+			Line:   start + 1,
+			Base:   strings.Join(base, ""),
+			Ours:   strings.Join(ours[start:end], ""),
+			Theirs: strings.Join(theirs, ""),
+		})
+		if len(hunks) == maxConflictHunks {
+			break
+		}
+	}
+
+	return hunks
+}
+
+// splitLines splits content into lines, keeping their line endings.
+func splitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n') + 1
+		if i == 0 {
+			i = len(content)
+		}
+		lines = append(lines, string(content[:i]))
+		content = content[i:]
+	}
+	return lines
+}
+
+// containsRun reports whether run appears in lines as consecutive lines.
+func containsRun(lines, run []string) bool {
+	for i := 0; i+len(run) <= len(lines); i++ {
+		if slices.Equal(lines[i:i+len(run)], run) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestAddConflictHunks(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "greet.txt"), []byte("hello\nbonjour\nbye\n"), 0644)
+	assert.NoError(t, err)
+
+	patch := `diff --git a/greet.txt b/greet.txt
+index 1111111..2222222 100644
+--- a/greet.txt
++++ b/greet.txt
+@@ -1,3 +1,3 @@
+ hello
+-hi
++hey
+ bye
+`
+	conflicts := []ConflictInfo{
+		{Filename: "greet.txt", Reason: "patch does not apply"},
+		{Filename: "greet.txt", Reason: "patch failed"},
+	}
+	addConflictHunks(dir, patch, conflicts)
+
+	assert.Equal(t, 1, len(conflicts[0].Hunks))
+	assert.Zero(t, conflicts[1].Hunks)
+
+	h := conflicts[0].Hunks[0]
+	assert.Equal(t, int64(1), h.Line)
+	assert.Equal(t, "hello\nhi\nbye\n", h.Base)
+	assert.Equal(t, "hello\nbonjour\nbye\n", h.Ours)
+	assert.Equal(t, "hello\nhey\nbye\n", h.Theirs)
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Contributor struct {
+	Name        string
+	Email       string
+	Commits     int64
+	Additions   int64
+	Deletions   int64
+	FirstCommit time.Time
+	LastCommit  time.Time
+}
+
+// Contributors aggregates the non-merge commits reachable from the repo's
+// ref by author email, most commits first.
+func (g *GitRepo) Contributors(ctx context.Context) ([]Contributor, error) {
+	output, err := g.streamingGitLog(
+		ctx,
+		"--no-merges",
+		"--numstat",
+		"--format="+recordSeparator+"%an"+fieldSeparator+"%ae"+fieldSeparator+"%aI",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close() // Ensure the git process is properly cleaned up
+
+	return parseContributors(output)
+}
+
+// parseContributors reads `git log --numstat` output, newest commit first,
+// whose headers are separated as in Contributors.
+func parseContributors(r io.Reader) ([]Contributor, error) {
+	byEmail := make(map[string]*Contributor)
+	var current *Contributor
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if header, ok := strings.CutPrefix(line, recordSeparator); ok {
+			parts := strings.SplitN(header, fieldSeparator, 3)
+			if len(parts) != 3 {
+				current = nil
+				continue
+			}
+
+			when, err := time.Parse(time.RFC3339, parts[2])
+			if err != nil {
+				current = nil
+				continue
+			}
+
+			key := strings.ToLower(parts[1])
+			c, ok := byEmail[key]
+			if !ok {
+				// the log is newest first, so this is their latest name
+				c = &Contributor{Name: parts[0], Email: parts[1], LastCommit: when}
+				byEmail[key] = c
+			}
+			c.Commits++
+			c.FirstCommit = when
+			current = c
+			continue
+		}
+
+		if current == nil || line == "" {
+			continue
+		}
+
+		// added, deleted and path; binary files count as "-"
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			current.Additions += n
+		}
+		if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			current.Deletions += n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	contributors := make([]Contributor, 0, len(byEmail))
+	for _, c := range byEmail {
+		contributors = append(contributors, *c)
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].Commits != contributors[j].Commits {
+			return contributors[i].Commits > contributors[j].Commits
+		}
+		return contributors[i].FirstCommit.Before(contributors[j].FirstCommit)
+	})
+
+	return contributors, nil
+}
@@ -0,0 +1,37 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestParseContributors(t *testing.T) {
+	header := func(name, email, date string) string {
+		return recordSeparator + name + fieldSeparator + email + fieldSeparator + date + "\n"
+	}
+
+	log := header("Alice", "alice@example.com", "2024-03-01T10:00:00Z") +
+		"\n3\t1\tmain.go\n-\t-\tlogo.png\n" +
+		header("Bob", "bob@example.com", "2024-02-01T10:00:00Z") +
+		"\n10\t0\tREADME.md\n" +
+		header("alice", "Alice@example.com", "2024-01-01T10:00:00Z") +
+		"\n1\t2\tmain.go\n"
+
+	contributors, err := parseContributors(strings.NewReader(log))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(contributors))
+
+	alice := contributors[0]
+	assert.Equal(t, "Alice", alice.Name)
+	assert.Equal(t, int64(2), alice.Commits)
+	assert.Equal(t, int64(4), alice.Additions)
+	assert.Equal(t, int64(3), alice.Deletions)
+	assert.Equal(t, "2024-01-01", alice.FirstCommit.Format("2006-01-02"))
+	assert.Equal(t, "2024-03-01", alice.LastCommit.Format("2006-01-02"))
+
+	bob := contributors[1]
+	assert.Equal(t, int64(1), bob.Commits)
+	assert.Equal(t, int64(10), bob.Additions)
+}
@@ -50,6 +50,7 @@ func (g *GitRepo) Diff() (*types.NiceDiff, error) {
 	if err != nil {
 		log.Println(err)
 	}
+	diffs = patchutil.DetectRenames(diffs, patchutil.RenameThreshold)
 
 	nd := types.NiceDiff{}
 	for _, d := range diffs {
@@ -61,6 +62,9 @@ func (g *GitRepo) Diff() (*types.NiceDiff, error) {
 		ndiff.IsDelete = d.IsDelete
 		ndiff.IsCopy = d.IsCopy
 		ndiff.IsRename = d.IsRename
+		if d.IsRename || d.IsCopy {
+			ndiff.Similarity = d.Score
+		}
 
 		for _, tf := range d.TextFragments {
 			ndiff.TextFragments = append(ndiff.TextFragments, *tf)
@@ -4,11 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"slices"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
+const hiddenRefPrefix = "refs/hidden/"
+
 func Fork(repoPath, source string) error {
 	cloneCmd := exec.Command("git", "clone", "--bare", source, repoPath)
 	if err := cloneCmd.Run(); err != nil {
@@ -62,3 +67,52 @@ func (g *GitRepo) TrackHiddenRemoteRef(forkRef, remoteRef string) error {
 	}
 	return nil
 }
+
+// DeleteHiddenRef removes the ref TrackHiddenRemoteRef made for forkRef and
+// remoteRef. Removing one that is already gone is not an error.
+func (g *GitRepo) DeleteHiddenRef(forkRef, remoteRef string) error {
+	ref := plumbing.ReferenceName(fmt.Sprintf("%s%s/%s", hiddenRefPrefix, forkRef, remoteRef))
+	if err := g.r.Storer.RemoveReference(ref); err != nil {
+		return fmt.Errorf("failed to delete hidden ref: %s: %w", ref, err)
+	}
+	return nil
+}
+
+// HiddenRefs lists the hidden refs of the repository as <forkRef>/<remoteRef>.
+func (g *GitRepo) HiddenRefs() ([]string, error) {
+	iter, err := g.r.References()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var refs []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if name, ok := strings.CutPrefix(ref.Name().String(), hiddenRefPrefix); ok {
+			refs = append(refs, name)
+		}
+		return nil
+	})
+	return refs, err
+}
+
+// PruneHiddenRefs removes every hidden ref that is not in keep, given as
+// <forkRef>/<remoteRef>, and returns the ones it removed.
+func (g *GitRepo) PruneHiddenRefs(keep []string) ([]string, error) {
+	refs, err := g.HiddenRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, name := range refs {
+		if slices.Contains(keep, name) {
+			continue
+		}
+		if err := g.r.Storer.RemoveReference(plumbing.ReferenceName(hiddenRefPrefix + name)); err != nil {
+			return pruned, fmt.Errorf("failed to delete hidden ref: %s: %w", name, err)
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, nil
+}
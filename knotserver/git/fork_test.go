@@ -0,0 +1,50 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestHiddenRefs(t *testing.T) {
+	dir := t.TempDir()
+	r, err := gogit.PlainInit(dir, true)
+	assert.NoError(t, err)
+
+	sig := object.Signature{Name: "a", Email: "a@example.com", When: time.Now()}
+	obj := r.Storer.NewEncodedObject()
+	assert.NoError(t, (&object.Commit{Author: sig, Committer: sig, Message: "init", TreeHash: plumbing.ZeroHash}).Encode(obj))
+	commit, err := r.Storer.SetEncodedObject(obj)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"refs/heads/main", "refs/hidden/feature/main", "refs/hidden/fix/x/main", "refs/hidden/old/main"} {
+		assert.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), commit)))
+	}
+
+	g, err := PlainOpen(dir)
+	assert.NoError(t, err)
+
+	refs, err := g.HiddenRefs()
+	assert.NoError(t, err)
+	assert.SliceContains(t, refs, "fix/x/main")
+	assert.Equal(t, 3, len(refs))
+
+	// deleting is idempotent
+	assert.NoError(t, g.DeleteHiddenRef("old", "main"))
+	assert.NoError(t, g.DeleteHiddenRef("old", "main"))
+
+	pruned, err := g.PruneHiddenRefs([]string{"feature/main"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fix/x/main"}, pruned)
+
+	refs, err = g.HiddenRefs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"feature/main"}, refs)
+
+	_, err = g.Branch("main")
+	assert.NoError(t, err)
+}
@@ -266,13 +266,22 @@ func (g *GitRepo) SetDefaultBranch(branch string) error {
 	return g.r.Storer.SetReference(ref)
 }
 
+// FindMainBranch returns the branch HEAD points at, including one that has
+// no commits yet, as in an empty repo.
 func (g *GitRepo) FindMainBranch() (string, error) {
 	output, err := g.revParse("--abbrev-ref", "HEAD")
+	branch := strings.TrimSpace(string(output))
+	if err != nil || branch == "HEAD" {
+		// rev-parse cannot resolve an unborn branch
+		if unborn, headErr := g.headBranch(); headErr == nil && unborn != "" {
+			return unborn, nil
+		}
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to find main branch: %w", err)
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return branch, nil
 }
 
 // WriteTar writes itself from a tree into a binary tar file format.
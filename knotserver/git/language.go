@@ -11,23 +11,63 @@ import (
 
 type LangBreakdown map[string]int64
 
-func (g *GitRepo) AnalyzeLanguages(ctx context.Context) (LangBreakdown, error) {
+// LangExclusions counts the files that .gitattributes overrides or linguist's
+// own detection kept out of a breakdown, and the files whose language was
+// set by linguist-language.
+type LangExclusions struct {
+	Vendored      int64
+	Generated     int64
+	Documentation int64
+	Overridden    int64
+}
+
+func (g *GitRepo) AnalyzeLanguages(ctx context.Context) (LangBreakdown, LangExclusions, error) {
+	var excluded LangExclusions
+
+	overrides, err := g.linguistOverrides()
+	if err != nil {
+		return nil, excluded, err
+	}
+
 	sizes := make(map[string]int64)
-	err := g.Walk(ctx, "", func(node object.TreeEntry, parent *object.Tree, root string) error {
+	err = g.Walk(ctx, "", func(node object.TreeEntry, parent *object.Tree, root string) error {
 		filepath := path.Join(root, node.Name)
+		po := overrides.match(filepath)
+
+		if orDetected(po.vendored, func() bool { return enry.IsVendor(filepath) }) {
+			excluded.Vendored++
+			return nil
+		}
+		if orDetected(po.documentation, func() bool { return enry.IsDocumentation(filepath) }) {
+			excluded.Documentation++
+			return nil
+		}
 
 		content, err := g.FileContentN(filepath, 16*1024) // 16KB
 		if err != nil {
 			return nil
 		}
 
-		if enry.IsGenerated(filepath, content) ||
-			enry.IsBinary(content) ||
+		if orDetected(po.generated, func() bool { return enry.IsGenerated(filepath, content) }) {
+			excluded.Generated++
+			return nil
+		}
+
+		if enry.IsBinary(content) ||
 			strings.HasSuffix(filepath, "bun.lock") {
 			return nil
 		}
 
-		language := analyzeLanguage(node, content)
+		var language string
+		if po.language != "" {
+			excluded.Overridden++
+			language = po.language
+			if name, ok := enry.GetLanguageByAlias(po.language); ok {
+				language = name
+			}
+		} else {
+			language = analyzeLanguage(node, content)
+		}
 		if group := enry.GetLanguageGroup(language); group != "" {
 			language = group
 		}
@@ -44,10 +84,10 @@ func (g *GitRepo) AnalyzeLanguages(ctx context.Context) (LangBreakdown, error) {
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, excluded, err
 	}
 
-	return sizes, nil
+	return sizes, excluded, nil
 }
 
 func analyzeLanguage(node object.TreeEntry, content []byte) string {
@@ -0,0 +1,154 @@
+package git
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// the .gitattributes that override linguist's detection, see
+// https://github.com/github-linguist/linguist/blob/main/docs/overrides.md
+const (
+	attrVendored      = "linguist-vendored"
+	attrGenerated     = "linguist-generated"
+	attrDocumentation = "linguist-documentation"
+	attrLanguage      = "linguist-language"
+)
+
+// linguistOverrides are the linguist attributes of every .gitattributes in
+// a tree, ordered so that later ones take precedence.
+type linguistOverrides []gitattributes.MatchAttribute
+
+// pathOverrides are the overrides that apply to a single path. A nil flag
+// leaves the decision to detection.
+type pathOverrides struct {
+	vendored      *bool
+	generated     *bool
+	documentation *bool
+	language      string
+}
+
+// linguistOverrides reads the .gitattributes files in the tree of the
+// current ref. Files closer to the root come first, as deeper ones take
+// precedence over them. Malformed lines are skipped, like git does.
+func (g *GitRepo) linguistOverrides() (linguistOverrides, error) {
+	c, err := g.r.CommitObject(g.h)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*object.File
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if path.Base(f.Name) == ".gitattributes" {
+			files = append(files, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		di, dj := strings.Count(files[i].Name, "/"), strings.Count(files[j].Name, "/")
+		if di != dj {
+			return di < dj
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	var overrides linguistOverrides
+	for _, f := range files {
+		contents, err := f.Contents()
+		if err != nil {
+			continue
+		}
+
+		var domain []string
+		if dir := path.Dir(f.Name); dir != "." {
+			domain = strings.Split(dir, "/")
+		}
+
+		for line := range strings.SplitSeq(contents, "\n") {
+			// macros are only allowed at the root
+			attr, err := gitattributes.ParseAttributesLine(line, domain, domain == nil)
+			if err != nil || attr.Pattern == nil || !hasLinguistAttr(attr) {
+				continue
+			}
+			overrides = append(overrides, attr)
+		}
+	}
+
+	return overrides, nil
+}
+
+func hasLinguistAttr(attr gitattributes.MatchAttribute) bool {
+	for _, a := range attr.Attributes {
+		if strings.HasPrefix(a.Name(), "linguist-") {
+			return true
+		}
+	}
+	return false
+}
+
+// match applies every line matching filepath in order, so that the last
+// one to mention an attribute wins.
+func (o linguistOverrides) match(filepath string) pathOverrides {
+	var po pathOverrides
+	parts := strings.Split(filepath, "/")
+
+	for _, line := range o {
+		if !line.Pattern.Match(parts) {
+			continue
+		}
+
+		for _, a := range line.Attributes {
+			switch a.Name() {
+			case attrVendored:
+				po.vendored = attrBool(a, po.vendored)
+			case attrGenerated:
+				po.generated = attrBool(a, po.generated)
+			case attrDocumentation:
+				po.documentation = attrBool(a, po.documentation)
+			case attrLanguage:
+				if a.IsValueSet() {
+					po.language = a.Value()
+				} else if a.IsUnset() || a.IsUnspecified() {
+					po.language = ""
+				}
+			}
+		}
+	}
+
+	return po
+}
+
+// attrBool reads a boolean attribute; besides being set or unset, linguist
+// also takes the values true and false.
+func attrBool(a gitattributes.Attribute, prev *bool) *bool {
+	yes, no := true, false
+	switch {
+	case a.IsSet(), a.IsValueSet() && a.Value() == "true":
+		return &yes
+	case a.IsUnset(), a.IsValueSet() && a.Value() == "false":
+		return &no
+	case a.IsUnspecified():
+		return nil
+	}
+	return prev
+}
+
+// orDetected is the override if there is one, else what detection says.
+func orDetected(override *bool, detect func() bool) bool {
+	if override != nil {
+		return *override
+	}
+	return detect()
+}
@@ -0,0 +1,62 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestAnalyzeLanguagesOverrides(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitattributes": "*.gen.go linguist-generated\n" +
+			"docs/** -linguist-documentation\n" +
+			"lib3p/** linguist-vendored\n" +
+			"*.h linguist-language=C++\n",
+		"main.go":         "package main\n\nfunc main() {}\n",
+		"api.gen.go":      "package main\n\nvar x = 1\n",
+		"docs/guide.go":   "package docs\n",
+		"lib3p/lib.py":    "print('hi')\n",
+		"vendor/dep/x.go": "package dep\n",
+		"foo.h":           "int foo(void);\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "."},
+		{"commit", "-m", "init"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	g, err := Open(dir, "")
+	assert.NoError(t, err)
+
+	sizes, excluded, err := g.AnalyzeLanguages(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, LangBreakdown{
+		"Go":  int64(len(files["main.go"]) + len(files["docs/guide.go"])),
+		"C++": int64(len(files["foo.h"])),
+	}, sizes)
+	// linguist also counts dotfiles, .gitattributes here, as vendored
+	assert.Equal(t, LangExclusions{
+		Vendored:   3,
+		Generated:  1,
+		Overridden: 1,
+	}, excluded)
+}
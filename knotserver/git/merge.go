@@ -35,43 +35,35 @@ func init() {
 	mergeCheckCache = MergeCheckCache{cache}
 }
 
-func (m *MergeCheckCache) cacheKey(g *GitRepo, patch string, targetBranch string) string {
+func (m *MergeCheckCache) cacheKey(g *GitRepo, patch string, targetBranch string, fuzz int) string {
 	sep := byte(':')
-	hash := sha256.Sum256(fmt.Append([]byte{}, g.path, sep, g.h.String(), sep, patch, sep, targetBranch))
+	hash := sha256.Sum256(fmt.Append([]byte{}, g.path, sep, g.h.String(), sep, patch, sep, targetBranch, sep, fuzz))
 	return fmt.Sprintf("%x", hash)
 }
 
 // we can't cache "mergeable" in risetto, nil is not cacheable
 //
-// we use the sentinel value instead
-func (m *MergeCheckCache) cacheVal(check error) any {
-	if check == nil {
-		return struct{}{}
-	} else {
-		return check
-	}
+// we cache the whole result instead
+type mergeCheckResult struct {
+	fuzzed bool
+	err    error
 }
 
-func (m *MergeCheckCache) Set(g *GitRepo, patch string, targetBranch string, mergeCheck error) {
-	key := m.cacheKey(g, patch, targetBranch)
-	val := m.cacheVal(mergeCheck)
-	m.cache.Set(key, val, 0)
+func (m *MergeCheckCache) Set(g *GitRepo, patch string, targetBranch string, fuzz int, fuzzed bool, mergeCheck error) {
+	key := m.cacheKey(g, patch, targetBranch, fuzz)
+	m.cache.Set(key, mergeCheckResult{fuzzed, mergeCheck}, 0)
 }
 
-func (m *MergeCheckCache) Get(g *GitRepo, patch string, targetBranch string) (error, bool) {
-	key := m.cacheKey(g, patch, targetBranch)
+func (m *MergeCheckCache) Get(g *GitRepo, patch string, targetBranch string, fuzz int) (bool, error, bool) {
+	key := m.cacheKey(g, patch, targetBranch, fuzz)
 	if val, ok := m.cache.Get(key); ok {
-		if val == struct{}{} {
-			// cache hit for mergeable
-			return nil, true
-		} else if e, ok := val.(error); ok {
-			// cache hit for merge conflict
-			return e, true
+		if res, ok := val.(mergeCheckResult); ok {
+			return res.fuzzed, res.err, true
 		}
 	}
 
 	// cache miss
-	return nil, false
+	return false, nil, false
 }
 
 type ErrMerge struct {
@@ -84,6 +76,7 @@ type ErrMerge struct {
 type ConflictInfo struct {
 	Filename string
 	Reason   string
+	Hunks    []types.ConflictHunk
 }
 
 // MergeOptions specifies the configuration for a merge operation
@@ -95,6 +88,27 @@ type MergeOptions struct {
 	CommitterName  string
 	CommitterEmail string
 	FormatPatch    bool
+	// Fuzz is how many lines of context around each change may be ignored
+	// when the patch doesn't apply as is, like GNU patch's fuzz factor. It
+	// is clamped to MaxFuzz.
+	Fuzz int
+}
+
+// patches are expected to carry this many lines of context, which is what
+// git produces by default
+const patchContext = 3
+
+// MaxFuzz is the most context that can be ignored, at least one line of
+// context always has to match.
+const MaxFuzz = patchContext - 1
+
+// fuzzArgs returns the flags that make git apply and git am ignore fuzz
+// lines of context.
+func fuzzArgs(fuzz int) []string {
+	if fuzz <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("-C%d", patchContext-min(fuzz, MaxFuzz))}
 }
 
 func (e ErrMerge) Error() string {
@@ -147,22 +161,34 @@ func (g *GitRepo) cloneRepository(targetBranch string) (string, error) {
 	return tmpDir, nil
 }
 
-func (g *GitRepo) checkPatch(tmpDir, patchFile string) error {
+// checkPatch reports whether the patch only applies after ignoring fuzz lines
+// of context, if it doesn't apply as is.
+func (g *GitRepo) checkPatch(tmpDir, patchData, patchFile string, fuzz int) (bool, error) {
 	var stderr bytes.Buffer
 
 	cmd := exec.Command("git", "-C", tmpDir, "apply", "--check", "-v", patchFile)
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		conflicts := parseGitApplyErrors(stderr.String())
-		return &ErrMerge{
-			Message:     "patch cannot be applied cleanly",
-			Conflicts:   conflicts,
-			HasConflict: len(conflicts) > 0,
-			OtherError:  err,
+	err := cmd.Run()
+	if err == nil {
+		return false, nil
+	}
+
+	if fuzz > 0 {
+		args := append([]string{"-C", tmpDir, "apply", "--check"}, fuzzArgs(fuzz)...)
+		if exec.Command("git", append(args, patchFile)...).Run() == nil {
+			return true, nil
 		}
 	}
-	return nil
+
+	conflicts := parseGitApplyErrors(stderr.String())
+	addConflictHunks(tmpDir, patchData, conflicts)
+	return false, &ErrMerge{
+		Message:     "patch cannot be applied cleanly",
+		Conflicts:   conflicts,
+		HasConflict: len(conflicts) > 0,
+		OtherError:  err,
+	}
 }
 
 func (g *GitRepo) applyPatch(patchData, patchFile string, opts MergeOptions) error {
@@ -176,11 +202,12 @@ func (g *GitRepo) applyPatch(patchData, patchFile string, opts MergeOptions) err
 
 	// if patch is a format-patch, apply using 'git am'
 	if opts.FormatPatch {
-		return g.applyMailbox(patchData)
+		return g.applyMailbox(patchData, opts.Fuzz)
 	}
 
 	// else, apply using 'git apply' and commit it manually
-	applyCmd := exec.Command("git", "-C", g.path, "apply", patchFile)
+	applyArgs := append([]string{"-C", g.path, "apply"}, fuzzArgs(opts.Fuzz)...)
+	applyCmd := exec.Command("git", append(applyArgs, patchFile)...)
 	applyCmd.Stderr = &stderr
 	if err := applyCmd.Run(); err != nil {
 		return fmt.Errorf("patch application failed: %s", stderr.String())
@@ -219,7 +246,7 @@ func (g *GitRepo) applyPatch(patchData, patchFile string, opts MergeOptions) err
 	return nil
 }
 
-func (g *GitRepo) applyMailbox(patchData string) error {
+func (g *GitRepo) applyMailbox(patchData string, fuzz int) error {
 	fps, err := patchutil.ExtractPatches(patchData)
 	if err != nil {
 		return fmt.Errorf("failed to extract patches: %w", err)
@@ -229,7 +256,7 @@ func (g *GitRepo) applyMailbox(patchData string) error {
 	// update the newly created commit object to add the change-id header
 	total := len(fps)
 	for i, p := range fps {
-		newCommit, err := g.applySingleMailbox(p)
+		newCommit, err := g.applySingleMailbox(p, fuzz)
 		if err != nil {
 			return err
 		}
@@ -240,14 +267,15 @@ func (g *GitRepo) applyMailbox(patchData string) error {
 	return nil
 }
 
-func (g *GitRepo) applySingleMailbox(singlePatch types.FormatPatch) (plumbing.Hash, error) {
+func (g *GitRepo) applySingleMailbox(singlePatch types.FormatPatch, fuzz int) (plumbing.Hash, error) {
 	tmpPatch, err := g.createTempFileWithPatch(singlePatch.Raw)
 	if err != nil {
 		return plumbing.ZeroHash, fmt.Errorf("failed to create temporary patch file for singluar mailbox patch: %w", err)
 	}
 
 	var stderr bytes.Buffer
-	cmd := exec.Command("git", "-C", g.path, "am", tmpPatch)
+	args := append([]string{"-C", g.path, "am"}, fuzzArgs(fuzz)...)
+	cmd := exec.Command("git", append(args, tmpPatch)...)
 	cmd.Stderr = &stderr
 
 	head, err := g.r.Head()
@@ -324,14 +352,17 @@ func (g *GitRepo) setChangeId(hash plumbing.Hash, changeId string) (plumbing.Has
 	return newHash, nil
 }
 
-func (g *GitRepo) MergeCheck(patchData string, targetBranch string) error {
-	if val, ok := mergeCheckCache.Get(g, patchData, targetBranch); ok {
-		return val
+// MergeCheck checks that the patch applies to targetBranch, ignoring up to
+// fuzz lines of context if it doesn't apply as is. It reports whether the
+// context had to be ignored.
+func (g *GitRepo) MergeCheck(patchData string, targetBranch string, fuzz int) (bool, error) {
+	if fuzzed, val, ok := mergeCheckCache.Get(g, patchData, targetBranch, fuzz); ok {
+		return fuzzed, val
 	}
 
 	patchFile, err := g.createTempFileWithPatch(patchData)
 	if err != nil {
-		return &ErrMerge{
+		return false, &ErrMerge{
 			Message:    err.Error(),
 			OtherError: err,
 		}
@@ -340,16 +371,16 @@ func (g *GitRepo) MergeCheck(patchData string, targetBranch string) error {
 
 	tmpDir, err := g.cloneRepository(targetBranch)
 	if err != nil {
-		return &ErrMerge{
+		return false, &ErrMerge{
 			Message:    err.Error(),
 			OtherError: err,
 		}
 	}
 	defer os.RemoveAll(tmpDir)
 
-	result := g.checkPatch(tmpDir, patchFile)
-	mergeCheckCache.Set(g, patchData, targetBranch, result)
-	return result
+	fuzzed, result := g.checkPatch(tmpDir, patchData, patchFile, fuzz)
+	mergeCheckCache.Set(g, patchData, targetBranch, fuzz, fuzzed, result)
+	return fuzzed, result
 }
 
 func (g *GitRepo) MergeWithOptions(patchData string, targetBranch string, opts MergeOptions) error {
@@ -69,7 +69,7 @@ func (g *GitRepo) RefUpdateMeta(line PostReceiveLine) (RefUpdateMeta, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
-	breakdown, err := g.AnalyzeLanguages(ctx)
+	breakdown, _, err := g.AnalyzeLanguages(ctx)
 	errors.Join(errs, err)
 
 	return RefUpdateMeta{
@@ -19,11 +19,14 @@ type ServiceCommand struct {
 	Dir         string
 	Stdin       io.Reader
 	Stdout      http.ResponseWriter
+	// extra environment for the git process, hooks read the pusher from it
+	Env []string
 }
 
 func (c *ServiceCommand) RunService(cmd *exec.Cmd) error {
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	cmd.Dir = c.Dir
+	cmd.Env = append(cmd.Env, c.Env...)
 	cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_PROTOCOL=%s", c.GitProtocol))
 
 	var stderr bytes.Buffer
@@ -73,15 +76,24 @@ func (c *ServiceCommand) RunService(cmd *exec.Cmd) error {
 }
 
 func (c *ServiceCommand) InfoRefs() error {
+	return c.infoRefs("upload-pack")
+}
+
+func (c *ServiceCommand) ReceivePackInfoRefs() error {
+	return c.infoRefs("receive-pack")
+}
+
+func (c *ServiceCommand) infoRefs(service string) error {
 	cmd := exec.Command("git", []string{
-		"upload-pack",
+		service,
 		"--stateless-rpc",
 		"--http-backend-info-refs",
 		".",
 	}...)
 
-	if !strings.Contains(c.GitProtocol, "version=2") {
-		if err := packLine(c.Stdout, "# service=git-upload-pack\n"); err != nil {
+	// receive-pack has no protocol v2, it always gets the service line
+	if service == "receive-pack" || !strings.Contains(c.GitProtocol, "version=2") {
+		if err := packLine(c.Stdout, fmt.Sprintf("# service=git-%s\n", service)); err != nil {
 			log.Printf("git: failed to write pack line: %s", err)
 			return err
 		}
@@ -110,6 +122,16 @@ func (c *ServiceCommand) UploadPack() error {
 	return c.RunService(cmd)
 }
 
+func (c *ServiceCommand) ReceivePack() error {
+	cmd := exec.Command("git", []string{
+		"receive-pack",
+		"--stateless-rpc",
+		".",
+	}...)
+
+	return c.RunService(cmd)
+}
+
 func packLine(w io.Writer, s string) error {
 	_, err := fmt.Fprintf(w, "%04x%s", len(s)+4, s)
 	return err
@@ -0,0 +1,35 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// DirSize returns the bytes taken up by the regular files under path. Files
+// that go away while it walks, as git's temporary files do, are skipped.
+func DirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
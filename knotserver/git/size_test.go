@@ -0,0 +1,25 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "objects", "pack"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "objects", "pack", "b"), make([]byte, 23), 0644))
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link")))
+
+	size, err := DirSize(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 123, size)
+
+	size, err = DirSize(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, size)
+}
@@ -2,6 +2,7 @@ package git
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
@@ -95,3 +96,34 @@ func (g *GitRepo) Tags() ([]object.Tag, error) {
 
 	return tags, nil
 }
+
+// TagSignature returns the signature of an annotated tag and the payload it
+// signs, which is the tag object without the signature. Both are empty if
+// the tag is not signed.
+func (g *GitRepo) TagSignature(hash plumbing.Hash) (payload, signature string, err error) {
+	tag, err := g.r.TagObject(hash)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read tag object: %w", err)
+	}
+	if tag.PGPSignature == "" {
+		return "", "", nil
+	}
+
+	o := &plumbing.MemoryObject{}
+	if err := tag.EncodeWithoutSignature(o); err != nil {
+		return "", "", fmt.Errorf("failed to encode tag object: %w", err)
+	}
+
+	r, err := o.Reader()
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(b), tag.PGPSignature, nil
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type TemplateOptions struct {
+	// ref of the template to take the tree from, HEAD if empty
+	Ref           string
+	DefaultBranch string
+	CommitMessage string
+
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+}
+
+// FromTemplate creates a bare repository at repoPath whose default branch
+// holds a single commit, with the tree of source at opts.Ref. None of the
+// template's history, refs or config is carried over; only the objects of
+// the tree are kept.
+func FromTemplate(repoPath, source string, opts TemplateOptions) error {
+	if err := InitBare(repoPath, opts.DefaultBranch); err != nil {
+		return err
+	}
+
+	if err := seedFromTemplate(repoPath, source, opts); err != nil {
+		os.RemoveAll(repoPath)
+		return err
+	}
+
+	return nil
+}
+
+func seedFromTemplate(repoPath, source string, opts TemplateOptions) error {
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	authorName, authorEmail := opts.AuthorName, opts.AuthorEmail
+	if authorName == "" || authorEmail == "" {
+		authorName, authorEmail = opts.CommitterName, opts.CommitterEmail
+	}
+
+	run := func(env []string, args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, stderr.String())
+		}
+		return strings.TrimSpace(stdout.String()), nil
+	}
+
+	// only the tip is needed, its history is dropped anyway
+	if _, err := run(nil, "fetch", "--depth=1", "--no-tags", source, ref); err != nil {
+		return fmt.Errorf("fetching template: %w", err)
+	}
+
+	tree, err := run(nil, "rev-parse", "FETCH_HEAD^{tree}")
+	if err != nil {
+		return fmt.Errorf("reading template tree: %w", err)
+	}
+
+	commit, err := run(
+		[]string{
+			"GIT_AUTHOR_NAME=" + authorName,
+			"GIT_AUTHOR_EMAIL=" + authorEmail,
+			"GIT_COMMITTER_NAME=" + opts.CommitterName,
+			"GIT_COMMITTER_EMAIL=" + opts.CommitterEmail,
+		},
+		"commit-tree", tree, "-m", opts.CommitMessage,
+	)
+	if err != nil {
+		return fmt.Errorf("creating initial commit: %w", err)
+	}
+
+	if _, err := run(nil, "update-ref", "refs/heads/"+opts.DefaultBranch, commit); err != nil {
+		return fmt.Errorf("updating %s: %w", opts.DefaultBranch, err)
+	}
+
+	// forget the template's commit, the new one has no parents so the
+	// repository is no longer shallow
+	os.Remove(filepath.Join(repoPath, "shallow"))
+	os.Remove(filepath.Join(repoPath, "FETCH_HEAD"))
+	if _, err := run(nil, "prune", "--expire=now"); err != nil {
+		return fmt.Errorf("pruning template objects: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestFromTemplate(t *testing.T) {
+	src := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", src}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	git("init", "-b", "trunk")
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "README"), []byte("one"), 0644))
+	git("add", ".")
+	git("commit", "-m", "first")
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "README"), []byte("two"), 0644))
+	git("commit", "-am", "second")
+
+	template, err := Open(src, "")
+	assert.NoError(t, err)
+	templateHead, err := template.Commit(template.h)
+	assert.NoError(t, err)
+
+	dst := filepath.Join(t.TempDir(), "did:plc:foo", "bar")
+	err = FromTemplate(dst, "file://"+src, TemplateOptions{
+		DefaultBranch:  "main",
+		CommitMessage:  "Initial commit",
+		AuthorName:     "b",
+		AuthorEmail:    "b@example.com",
+		CommitterName:  "Tangled",
+		CommitterEmail: "noreply@tangled.sh",
+	})
+	assert.NoError(t, err)
+
+	g, err := Open(dst, "")
+	assert.NoError(t, err)
+	main, err := g.FindMainBranch()
+	assert.NoError(t, err)
+	assert.Equal(t, "main", main)
+
+	commits, err := g.Commits(0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(commits))
+	assert.Equal(t, 0, commits[0].NumParents())
+	assert.Equal(t, templateHead.TreeHash, commits[0].TreeHash)
+	assert.Equal(t, "b", commits[0].Author.Name)
+	assert.Equal(t, "Tangled", commits[0].Committer.Name)
+
+	// nothing of the template's history is left behind
+	_, err = os.Stat(filepath.Join(dst, "shallow"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = g.r.CommitObject(templateHead.Hash)
+	assert.IsError(t, err, plumbing.ErrObjectNotFound)
+}
@@ -7,6 +7,8 @@ import (
 	"path"
 	"time"
 
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"tangled.org/core/types"
@@ -53,6 +55,10 @@ func (g *GitRepo) makeNiceTree(ctx context.Context, subtree *object.Tree, parent
 		return nts
 	}
 
+	// .gitmodules is only read once there's a gitlink to describe
+	var modules *config.Modules
+	var modulesRead bool
+
 	for _, e := range subtree.Entries {
 		sz, _ := subtree.Size(e.Name)
 		fpath := path.Join(parent, e.Name)
@@ -66,11 +72,21 @@ func (g *GitRepo) makeNiceTree(ctx context.Context, subtree *object.Tree, parent
 			}
 		}
 
+		var submodule *types.SubmoduleInfo
+		if e.Mode == filemode.Submodule {
+			if !modulesRead {
+				modules, _ = g.Submodules()
+				modulesRead = true
+			}
+			submodule = submoduleInfo(modules, fpath, e.Hash)
+		}
+
 		nts = append(nts, types.NiceTree{
 			Name:       e.Name,
 			Mode:       e.Mode.String(),
 			Size:       sz,
 			LastCommit: lastCommit,
+			Submodule:  submodule,
 		})
 
 	}
@@ -78,6 +94,30 @@ func (g *GitRepo) makeNiceTree(ctx context.Context, subtree *object.Tree, parent
 	return nts
 }
 
+// submoduleInfo describes the gitlink at fpath. A gitlink missing from
+// .gitmodules still has its pinned commit, but no url.
+func submoduleInfo(modules *config.Modules, fpath string, commit plumbing.Hash) *types.SubmoduleInfo {
+	info := &types.SubmoduleInfo{
+		Name:   fpath,
+		Commit: commit.String(),
+	}
+
+	if modules == nil {
+		return info
+	}
+
+	for _, sm := range modules.Submodules {
+		if sm.Path == fpath {
+			info.Name = sm.Name
+			info.Url = sm.URL
+			info.Branch = sm.Branch
+			break
+		}
+	}
+
+	return info
+}
+
 var (
 	TerminateWalk error = errors.New("terminate walk")
 )
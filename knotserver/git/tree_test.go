@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"tangled.org/core/types"
+)
+
+func TestFileTreeSubmodules(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	const (
+		pinned   = "0123456789abcdef0123456789abcdef01234567"
+		unlisted = "89abcdef0123456789abcdef0123456789abcdef"
+	)
+	gitmodules := "[submodule \"lib\"]\n" +
+		"\tpath = deps/lib\n" +
+		"\turl = ../lib.git\n" +
+		"\tbranch = stable\n"
+
+	git("init")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".gitmodules"), []byte(gitmodules), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "deps"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "deps", "README"), []byte("deps"), 0644))
+	git("add", ".")
+	git("update-index", "--add", "--cacheinfo", "160000,"+pinned+",deps/lib")
+	git("update-index", "--add", "--cacheinfo", "160000,"+unlisted+",deps/other")
+	git("commit", "-m", "init")
+
+	g, err := Open(dir, "")
+	assert.NoError(t, err)
+
+	files, err := g.FileTree(context.Background(), "deps")
+	assert.NoError(t, err)
+
+	submodules := map[string]*types.SubmoduleInfo{}
+	for _, f := range files {
+		assert.Equal(t, f.IsSubmodule(), f.Submodule != nil, f.Name)
+		if f.Submodule != nil {
+			submodules[f.Name] = f.Submodule
+		}
+	}
+
+	assert.Equal(t, map[string]*types.SubmoduleInfo{
+		"lib": {
+			Name:   "lib",
+			Url:    "../lib.git",
+			Branch: "stable",
+			Commit: pinned,
+		},
+		"other": {
+			Name:   "deps/other",
+			Commit: unlisted,
+		},
+	}, submodules)
+}
@@ -269,7 +269,12 @@ func (h *Knot) processCollaborator(ctx context.Context, event *models.Event) err
 	}
 	h.jc.AddDid(subjectId.DID.String())
 
-	if err := h.e.AddCollaborator(subjectId.DID.String(), rbac.ThisServer, didSlashRepo); err != nil {
+	role, err := rbac.RecordRole(record.Role)
+	if err != nil {
+		return err
+	}
+
+	if err := h.e.SetRepoRole(subjectId.DID.String(), rbac.ThisServer, didSlashRepo, role); err != nil {
 		return err
 	}
 
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/dustin/go-humanize"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -122,6 +123,23 @@ func (h *InternalHandle) Guard(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprint(w, repo)
 			return
 		}
+	} else {
+		// fetching from private repos is limited to those with a role in them
+		private, err := h.db.IsRepoPrivate(qualifiedRepo)
+		if err != nil {
+			l.Error("failed to check repo visibility", "repo", qualifiedRepo, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "error checking repo visibility\n")
+			return
+		}
+		if private {
+			ok, err := h.e.IsRepoReadAllowed(incomingUser, rbac.ThisServer, qualifiedRepo)
+			if err != nil || !ok {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, repo)
+				return
+			}
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -198,6 +216,90 @@ func (h *InternalHandle) PostReceiveHook(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, resp)
 }
 
+// PreReceiveHook refuses pushes that would take the repo owner's repos past
+// the storage quota. Git keeps the objects of a push in a quarantine
+// directory until every pre-receive hook has passed, so their size is that
+// of the directory.
+func (h *InternalHandle) PreReceiveHook(w http.ResponseWriter, r *http.Request) {
+	l := h.l.With("handler", "PreReceiveHook")
+
+	resp := hook.HookResponse{
+		Messages: make([]string, 0),
+	}
+
+	quota := h.c.Repo.QuotaBytes
+	if quota <= 0 {
+		writeJSON(w, resp)
+		return
+	}
+
+	gitAbsoluteDir := r.Header.Get("X-Git-Dir")
+	gitRelativeDir, err := filepath.Rel(h.c.Repo.ScanPath, gitAbsoluteDir)
+	if err != nil {
+		l.Error("failed to calculate relative git dir", "scanPath", h.c.Repo.ScanPath, "gitAbsoluteDir", gitAbsoluteDir)
+		writeError(w, "invalid git dir", http.StatusBadRequest)
+		return
+	}
+
+	repoDid, _, ok := strings.Cut(gitRelativeDir, "/")
+	if !ok {
+		l.Error("invalid git dir", "gitRelativeDir", gitRelativeDir)
+		writeError(w, "invalid git dir", http.StatusBadRequest)
+		return
+	}
+
+	// the quarantine sits under the repo's objects, anywhere else is not
+	// this push's doing
+	var incoming int64
+	if quarantine := r.Header.Get("X-Git-Quarantine-Path"); quarantine != "" {
+		if rel, err := filepath.Rel(gitAbsoluteDir, quarantine); err == nil && !strings.HasPrefix(rel, "..") {
+			incoming, err = git.DirSize(quarantine)
+			if err != nil {
+				l.Error("failed to measure push", "quarantine", quarantine, "err", err)
+			}
+		}
+	}
+
+	// pushes that bring no objects, like deleting a branch, only ever free
+	// up space
+	if incoming == 0 {
+		writeJSON(w, resp)
+		return
+	}
+
+	userPath, err := securejoin.SecureJoin(h.c.Repo.ScanPath, repoDid)
+	if err != nil {
+		writeError(w, "invalid git dir", http.StatusBadRequest)
+		return
+	}
+
+	// the quarantine is counted in here as well
+	usage, err := git.DirSize(userPath)
+	if err != nil {
+		l.Error("failed to measure repos", "did", repoDid, "err", err)
+		writeError(w, "failed to measure repos", http.StatusInternalServerError)
+		return
+	}
+
+	if usage > quota {
+		l.Info("push over quota", "did", repoDid, "usage", usage-incoming, "incoming", incoming, "quota", quota)
+		resp.Messages = append(resp.Messages,
+			"error: push refused, it would take the repositories of this user over their storage quota",
+			fmt.Sprintf("error: %s used of %s, this push adds %s",
+				humanize.IBytes(uint64(usage-incoming)),
+				humanize.IBytes(uint64(quota)),
+				humanize.IBytes(uint64(incoming)),
+			),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	writeJSON(w, resp)
+}
+
 func (h *InternalHandle) insertRefUpdate(line git.PostReceiveLine, gitUserDid, repoDid, repoName string) error {
 	didSlashRepo, err := securejoin.SecureJoin(repoDid, repoName)
 	if err != nil {
@@ -427,6 +529,7 @@ func Internal(ctx context.Context, c *config.Config, db *db.DB, e *rbac.Enforcer
 	r.Get("/push-allowed", h.PushAllowed)
 	r.Get("/keys", h.InternalKeys)
 	r.Get("/guard", h.Guard)
+	r.Post("/hooks/pre-receive", h.PreReceiveHook)
 	r.Post("/hooks/post-receive", h.PostReceiveHook)
 	r.Mount("/debug", middleware.Profiler())
 
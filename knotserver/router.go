@@ -71,6 +71,9 @@ func Setup(ctx context.Context, c *config.Config, db *db.DB, e *rbac.Enforcer, j
 func (h *Knot) Router() http.Handler {
 	r := chi.NewRouter()
 
+	serviceAuth := serviceauth.NewServiceAuth(h.l, h.resolver, h.c.Server.Did().String())
+	serviceAuth.AcceptAppPasswords(h.c.AppViewEndpoint)
+
 	r.Use(h.CORS)
 	r.Use(h.RequestLogger)
 
@@ -80,7 +83,9 @@ func (h *Knot) Router() http.Handler {
 
 	r.Route("/{did}", func(r chi.Router) {
 		r.Route("/{name}", func(r chi.Router) {
-			// routes for git operations
+			// routes for git operations, private repos and pushes need service
+			// auth or an app password
+			r.Use(serviceAuth.OptionalServiceAuth)
 			r.Get("/info/refs", h.InfoRefs)
 			r.Post("/git-upload-pack", h.UploadPack)
 			r.Post("/git-receive-pack", h.ReceivePack)
@@ -88,17 +93,16 @@ func (h *Knot) Router() http.Handler {
 	})
 
 	// xrpc apis
-	r.Mount("/xrpc", h.XrpcRouter())
+	r.Mount("/xrpc", h.XrpcRouter(serviceAuth))
 
-	// Socket that streams git oplogs
-	r.Get("/events", h.Events)
+	// Socket that streams git oplogs, those of private repos only to
+	// subscribers with a role in them
+	r.With(serviceAuth.OptionalServiceAuth).Get("/events", h.Events)
 
 	return r
 }
 
-func (h *Knot) XrpcRouter() http.Handler {
-	serviceAuth := serviceauth.NewServiceAuth(h.l, h.resolver, h.c.Server.Did().String())
-
+func (h *Knot) XrpcRouter(serviceAuth *serviceauth.ServiceAuth) http.Handler {
 	l := log.SubLogger(h.l, "xrpc")
 
 	xrpc := &xrpc.Xrpc{
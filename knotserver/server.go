@@ -13,6 +13,7 @@ import (
 	"tangled.org/core/knotserver/db"
 	"tangled.org/core/log"
 	"tangled.org/core/notifier"
+	"tangled.org/core/patchutil"
 	"tangled.org/core/rbac"
 )
 
@@ -34,8 +35,11 @@ func Command() *cli.Command {
 		KNOT_REPO_SCAN_PATH              (default: /home/git)
 		KNOT_REPO_README                 (comma-separated list)
 		KNOT_REPO_MAIN_BRANCH            (default: main)
+		KNOT_REPO_QUOTA_BYTES            (default: 0, no quota)
+		KNOT_REPO_MAX_WEB_COMMIT_BYTES   (default: 67108864)
 		KNOT_GIT_USER_NAME               (default: Tangled)
 		KNOT_GIT_USER_EMAIL              (default: noreply@tangled.sh)
+		KNOT_GIT_RENAME_THRESHOLD        (default: 50)
 		APPVIEW_ENDPOINT                 (default: https://tangled.sh)
 	`,
 	}
@@ -50,6 +54,7 @@ func Run(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	patchutil.RenameThreshold = c.Git.RenameThreshold
 
 	err = hook.Setup(hook.Config(
 		hook.WithScanPath(c.Repo.ScanPath),
@@ -0,0 +1,147 @@
+package xrpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/git"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+// CommitFiles makes a commit out of files written or deleted in the web UI. Anyone who
+// can read the repo may have it made as a patch, to propose it in a pull.
+func (x *Xrpc) CommitFiles(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger.With("handler", "CommitFiles")
+	fail := func(e xrpcerr.XrpcError, status int) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, status)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError, http.StatusBadRequest)
+		return
+	}
+
+	var data tangled.RepoCommitFiles_Input
+	body := r.Body
+	if limit := x.Config.Repo.MaxWebCommitBytes; limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	if err := json.NewDecoder(body).Decode(&data); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(xrpcerr.NewXrpcError(
+				xrpcerr.WithTag("TooLarge"),
+				xrpcerr.WithMessage(fmt.Sprintf("the files can take at most %d bytes", tooLarge.Limit)),
+			), http.StatusRequestEntityTooLarge)
+			return
+		}
+		fail(xrpcerr.GenericError(err), http.StatusBadRequest)
+		return
+	}
+
+	if data.Repo == "" || data.Branch == "" || data.Message == "" || len(data.Files) == 0 {
+		fail(xrpcerr.GenericError(fmt.Errorf("repo, branch, message and files are required")), http.StatusBadRequest)
+		return
+	}
+	if err := validateBranchName(data.Branch); err != nil {
+		fail(xrpcerr.GenericError(err), http.StatusBadRequest)
+		return
+	}
+
+	files := make([]git.FileWrite, 0, len(data.Files))
+	for _, f := range data.Files {
+		content, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			fail(xrpcerr.GenericError(fmt.Errorf("content of %s is not base64: %w", f.Path, err)), http.StatusBadRequest)
+			return
+		}
+		file := git.FileWrite{Path: f.Path, Content: content}
+		if f.From != nil {
+			file.From = *f.From
+		}
+		if f.Executable != nil {
+			file.Executable = *f.Executable
+		}
+		if f.Delete != nil {
+			file.Delete = *f.Delete
+		}
+		if f.ExpectedBlob != nil {
+			file.ExpectedBlob = *f.ExpectedBlob
+		}
+		files = append(files, file)
+	}
+
+	opts := git.CommitFilesOptions{
+		Branch:         data.Branch,
+		CommitMessage:  data.Message,
+		CommitterName:  x.Config.Git.UserName,
+		CommitterEmail: x.Config.Git.UserEmail,
+		PusherDid:      actorDid.String(),
+	}
+	if data.AuthorName != nil {
+		opts.AuthorName = *data.AuthorName
+	}
+	if data.AuthorEmail != nil {
+		opts.AuthorEmail = *data.AuthorEmail
+	}
+
+	patchOnly := data.PatchOnly != nil && *data.PatchOnly
+
+	var gr *git.GitRepo
+	var xerr *xrpcerr.XrpcError
+	var status int
+	if patchOnly {
+		gr, xerr, status = x.openReadableRepo(r, data.Repo)
+	} else {
+		gr, xerr, status = x.openPushableRepo(r.Context(), actorDid, data.Repo)
+	}
+	if xerr != nil {
+		fail(*xerr, status)
+		return
+	}
+
+	var out tangled.RepoCommitFiles_Output
+	var err error
+	if patchOnly {
+		var patch string
+		patch, err = gr.FilesPatch(files, opts)
+		out.Patch = &patch
+	} else {
+		var commit string
+		commit, err = gr.CommitFiles(files, opts)
+		out.Commit = &commit
+	}
+
+	switch {
+	case errors.Is(err, git.ErrInvalidPath), errors.Is(err, git.ErrPathConflict), errors.Is(err, git.ErrNoChanges):
+		fail(xrpcerr.NewXrpcError(
+			xrpcerr.WithTag("InvalidRequest"),
+			xrpcerr.WithMessage(err.Error()),
+		), http.StatusBadRequest)
+		return
+	case errors.Is(err, git.ErrBranchMoved):
+		fail(xrpcerr.NewXrpcError(
+			xrpcerr.WithTag("BranchMoved"),
+			xrpcerr.WithMessage(err.Error()),
+		), http.StatusConflict)
+		return
+	case errors.Is(err, git.ErrFileChanged):
+		fail(xrpcerr.NewXrpcError(
+			xrpcerr.WithTag("FileChanged"),
+			xrpcerr.WithMessage(err.Error()),
+		), http.StatusConflict)
+		return
+	case err != nil:
+		fail(xrpcerr.GitError(err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, out)
+}
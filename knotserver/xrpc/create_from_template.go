@@ -0,0 +1,108 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	gogit "github.com/go-git/go-git/v5"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/git"
+	"tangled.org/core/rbac"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+// CreateFromTemplate creates a repo like CreateRepo does, except that its
+// default branch starts out with a single commit holding the tree of the
+// template. Unlike a fork, it shares no history with the template.
+func (h *Xrpc) CreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	l := h.Logger.With("handler", "CreateFromTemplate")
+	fail := func(e xrpcerr.XrpcError) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, http.StatusBadRequest)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError)
+		return
+	}
+
+	isMember, err := h.Enforcer.IsRepoCreateAllowed(actorDid.String(), rbac.ThisServer)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+	if !isMember {
+		fail(xrpcerr.AccessControlError(actorDid.String()))
+		return
+	}
+
+	var data tangled.RepoCreateFromTemplate_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	// templates are fetched like any other remote, never off the disk
+	source, err := url.Parse(data.Source)
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") {
+		fail(xrpcerr.GenericError(errors.New("template source must be an http(s) URL")))
+		return
+	}
+
+	repo, err := h.newRepoRecord(r.Context(), actorDid, data.Rkey)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	opts := git.TemplateOptions{
+		DefaultBranch:  h.Config.Repo.MainBranch,
+		CommitMessage:  "Initial commit",
+		CommitterName:  h.Config.Git.UserName,
+		CommitterEmail: h.Config.Git.UserEmail,
+	}
+	if data.DefaultBranch != nil && *data.DefaultBranch != "" {
+		opts.DefaultBranch = *data.DefaultBranch
+	}
+	if err := validateBranchName(opts.DefaultBranch); err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+	if data.Ref != nil {
+		opts.Ref = *data.Ref
+	}
+	if data.AuthorName != nil {
+		opts.AuthorName = *data.AuthorName
+	}
+	if data.AuthorEmail != nil {
+		opts.AuthorEmail = *data.AuthorEmail
+	}
+
+	relativeRepoPath := filepath.Join(actorDid.String(), repo.Name)
+	repoPath, _ := securejoin.SecureJoin(h.Config.Repo.ScanPath, relativeRepoPath)
+
+	err = git.FromTemplate(repoPath, data.Source, opts)
+	if err != nil {
+		l.Error("creating repo from template", "error", err.Error())
+		if errors.Is(err, gogit.ErrRepositoryAlreadyExists) {
+			fail(xrpcerr.RepoExistsError("repository already exists"))
+			return
+		}
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.setupRepo(actorDid, relativeRepoPath, repoPath, repo); err != nil {
+		l.Error("setting up repo", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
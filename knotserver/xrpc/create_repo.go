@@ -1,6 +1,7 @@
 package xrpc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"github.com/bluesky-social/indigo/xrpc"
 	securejoin "github.com/cyphar/filepath-securejoin"
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/hook"
 	"tangled.org/core/knotserver/git"
@@ -49,33 +51,17 @@ func (h *Xrpc) CreateRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rkey := data.Rkey
-
-	ident, err := h.Resolver.ResolveIdent(r.Context(), actorDid.String())
-	if err != nil || ident.Handle.IsInvalidHandle() {
-		fail(xrpcerr.GenericError(err))
-		return
-	}
-
-	xrpcc := xrpc.Client{
-		Host: ident.PDSEndpoint(),
-	}
-
-	resp, err := comatproto.RepoGetRecord(r.Context(), &xrpcc, "", tangled.RepoNSID, actorDid.String(), rkey)
+	repo, err := h.newRepoRecord(r.Context(), actorDid, data.Rkey)
 	if err != nil {
 		fail(xrpcerr.GenericError(err))
 		return
 	}
 
-	repo := resp.Value.Val.(*tangled.Repo)
-
 	defaultBranch := h.Config.Repo.MainBranch
 	if data.DefaultBranch != nil && *data.DefaultBranch != "" {
 		defaultBranch = *data.DefaultBranch
 	}
-
-	if err := validateRepoName(repo.Name); err != nil {
-		l.Error("creating repo", "error", err.Error())
+	if err := validateBranchName(defaultBranch); err != nil {
 		fail(xrpcerr.GenericError(err))
 		return
 	}
@@ -104,14 +90,61 @@ func (h *Xrpc) CreateRepo(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// add perms for this user to access the repo
-	err = h.Enforcer.AddRepo(actorDid.String(), rbac.ThisServer, relativeRepoPath)
-	if err != nil {
-		l.Error("adding repo permissions", "error", err.Error())
+	if err := h.setupRepo(actorDid, relativeRepoPath, repoPath, repo); err != nil {
+		l.Error("setting up repo", "error", err.Error())
 		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
 		return
 	}
 
+	w.WriteHeader(http.StatusOK)
+}
+
+// newRepoRecord fetches the record of a repo that actor is creating, and
+// checks that its name is usable as a path.
+func (h *Xrpc) newRepoRecord(ctx context.Context, actorDid syntax.DID, rkey string) (*tangled.Repo, error) {
+	ident, err := h.Resolver.ResolveIdent(ctx, actorDid.String())
+	if err != nil {
+		return nil, err
+	}
+	if ident.Handle.IsInvalidHandle() {
+		return nil, fmt.Errorf("invalid handle for %s", actorDid)
+	}
+
+	xrpcc := xrpc.Client{
+		Host: ident.PDSEndpoint(),
+	}
+
+	resp, err := comatproto.RepoGetRecord(ctx, &xrpcc, "", tangled.RepoNSID, actorDid.String(), rkey)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, ok := resp.Value.Val.(*tangled.Repo)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a repo record", rkey)
+	}
+
+	if err := validateRepoName(repo.Name); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// setupRepo registers a freshly created repo: its owner's permissions, its
+// visibility and its hooks.
+func (h *Xrpc) setupRepo(actorDid syntax.DID, relativeRepoPath, repoPath string, repo *tangled.Repo) error {
+	// add perms for this user to access the repo
+	err := h.Enforcer.AddRepo(actorDid.String(), rbac.ThisServer, relativeRepoPath)
+	if err != nil {
+		return fmt.Errorf("adding repo permissions: %w", err)
+	}
+
+	err = h.Db.SetRepoPrivate(relativeRepoPath, isPrivate(repo))
+	if err != nil {
+		return fmt.Errorf("setting repo visibility: %w", err)
+	}
+
 	hook.SetupRepo(
 		hook.Config(
 			hook.WithScanPath(h.Config.Repo.ScanPath),
@@ -120,7 +153,16 @@ func (h *Xrpc) CreateRepo(w http.ResponseWriter, r *http.Request) {
 		repoPath,
 	)
 
-	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// validateBranchName checks a branch name against git's rules for ref
+// names, see git-check-ref-format(1).
+func validateBranchName(name string) error {
+	if err := plumbing.NewBranchReferenceName(name).Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
 }
 
 func validateRepoName(name string) error {
@@ -2,6 +2,7 @@ package xrpc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -77,6 +78,13 @@ func (x *Xrpc) DeleteBranch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = gr.DeleteBranch(data.Branch)
+	if errors.Is(err, git.ErrDefaultBranch) {
+		fail(xrpcerr.NewXrpcError(
+			xrpcerr.WithTag("DefaultBranch"),
+			xrpcerr.WithMessage(fmt.Sprintf("%s is the default branch and cannot be deleted", data.Branch)),
+		))
+		return
+	}
 	if err != nil {
 		l.Error("deleting branch", "error", err.Error(), "branch", data.Branch)
 		writeError(w, xrpcerr.GitError(err), http.StatusInternalServerError)
@@ -0,0 +1,176 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/xrpc"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/git"
+	"tangled.org/core/rbac"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+// DeleteHiddenRef removes the hidden ref of a pull that is merged or closed.
+func (x *Xrpc) DeleteHiddenRef(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger.With("handler", "DeleteHiddenRef")
+	fail := func(e xrpcerr.XrpcError, status int) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, status)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError, http.StatusBadRequest)
+		return
+	}
+
+	var data tangled.RepoDeleteHiddenRef_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err), http.StatusBadRequest)
+		return
+	}
+
+	if data.ForkRef == "" || data.RemoteRef == "" || data.Repo == "" {
+		fail(xrpcerr.GenericError(fmt.Errorf("forkRef, remoteRef, and repo are required")), http.StatusBadRequest)
+		return
+	}
+
+	gr, xerr, status := x.openPushableRepo(r.Context(), actorDid, data.Repo)
+	if xerr != nil {
+		fail(*xerr, status)
+		return
+	}
+
+	if err := gr.DeleteHiddenRef(data.ForkRef, data.RemoteRef); err != nil {
+		fail(xrpcerr.GitError(err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// PruneHiddenRefs removes the hidden refs that no open pull needs anymore.
+func (x *Xrpc) PruneHiddenRefs(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger.With("handler", "PruneHiddenRefs")
+	fail := func(e xrpcerr.XrpcError, status int) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, status)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError, http.StatusBadRequest)
+		return
+	}
+
+	var data tangled.RepoPruneHiddenRefs_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err), http.StatusBadRequest)
+		return
+	}
+
+	gr, xerr, status := x.openPushableRepo(r.Context(), actorDid, data.Repo)
+	if xerr != nil {
+		fail(*xerr, status)
+		return
+	}
+
+	pruned, err := gr.PruneHiddenRefs(data.Keep)
+	if err != nil {
+		fail(xrpcerr.GitError(err), http.StatusInternalServerError)
+		return
+	}
+	if len(pruned) > 0 {
+		l.Info("pruned hidden refs", "repo", data.Repo, "refs", pruned)
+	}
+
+	writeJson(w, tangled.RepoPruneHiddenRefs_Output{
+		Pruned: append([]string{}, pruned...),
+	})
+}
+
+// openPushableRepo opens the repo at repoAtUri, if actor may push to it.
+func (x *Xrpc) openPushableRepo(ctx context.Context, actor syntax.DID, repoAtUri string) (*git.GitRepo, *xrpcerr.XrpcError, int) {
+	didPath, xerr, status := x.resolveRepoPath(ctx, repoAtUri)
+	if xerr != nil {
+		return nil, xerr, status
+	}
+
+	if ok, err := x.Enforcer.IsPushAllowed(actor.String(), rbac.ThisServer, didPath); !ok || err != nil {
+		e := xrpcerr.AccessControlError(actor.String())
+		return nil, &e, http.StatusUnauthorized
+	}
+
+	return x.openRepo(didPath)
+}
+
+// openReadableRepo opens the repo at repoAtUri, if the caller of r may see
+// it.
+func (x *Xrpc) openReadableRepo(r *http.Request, repoAtUri string) (*git.GitRepo, *xrpcerr.XrpcError, int) {
+	didPath, xerr, status := x.resolveRepoPath(r.Context(), repoAtUri)
+	if xerr != nil {
+		return nil, xerr, status
+	}
+
+	if ok, err := x.canReadRepo(r, didPath); !ok || err != nil {
+		e := xrpcerr.RepoNotFoundError
+		return nil, &e, http.StatusNotFound
+	}
+
+	return x.openRepo(didPath)
+}
+
+// resolveRepoPath finds the repo at repoAtUri, and returns it in did/name
+// form.
+func (x *Xrpc) resolveRepoPath(ctx context.Context, repoAtUri string) (string, *xrpcerr.XrpcError, int) {
+	failed := func(e xrpcerr.XrpcError, status int) (string, *xrpcerr.XrpcError, int) {
+		return "", &e, status
+	}
+
+	repoAt, err := syntax.ParseATURI(repoAtUri)
+	if err != nil {
+		return failed(xrpcerr.InvalidRepoError(repoAtUri), http.StatusBadRequest)
+	}
+
+	ident, err := x.Resolver.ResolveIdent(ctx, repoAt.Authority().String())
+	if err != nil || ident.Handle.IsInvalidHandle() {
+		return failed(xrpcerr.GenericError(fmt.Errorf("failed to resolve handle: %w", err)), http.StatusBadRequest)
+	}
+
+	xrpcc := xrpc.Client{Host: ident.PDSEndpoint()}
+	resp, err := comatproto.RepoGetRecord(ctx, &xrpcc, "", tangled.RepoNSID, ident.DID.String(), repoAt.RecordKey().String())
+	if err != nil {
+		return failed(xrpcerr.GenericError(err), http.StatusBadRequest)
+	}
+
+	repo := resp.Value.Val.(*tangled.Repo)
+	didPath, err := securejoin.SecureJoin(ident.DID.String(), repo.Name)
+	if err != nil {
+		return failed(xrpcerr.GenericError(err), http.StatusBadRequest)
+	}
+
+	return didPath, nil, 0
+}
+
+// openRepo opens the repo at didPath, in did/name form.
+func (x *Xrpc) openRepo(didPath string) (*git.GitRepo, *xrpcerr.XrpcError, int) {
+	repoPath, err := securejoin.SecureJoin(x.Config.Repo.ScanPath, didPath)
+	if err != nil {
+		e := xrpcerr.GenericError(err)
+		return nil, &e, http.StatusBadRequest
+	}
+
+	gr, err := git.PlainOpen(repoPath)
+	if err != nil {
+		e := xrpcerr.GenericError(fmt.Errorf("failed to open repository: %w", err))
+		return nil, &e, http.StatusNotFound
+	}
+
+	return gr, nil, 0
+}
@@ -92,5 +92,12 @@ func (x *Xrpc) DeleteRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = x.Db.SetRepoPrivate(relativeRepoPath, false)
+	if err != nil {
+		l.Error("failed to forget repo visibility", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,52 @@
+package xrpc
+
+import (
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"tangled.org/core/api/tangled"
+)
+
+var startedAt = time.Now()
+
+func (x *Xrpc) Health(w http.ResponseWriter, r *http.Request) {
+	v, err := knotVersion()
+	if err != nil {
+		v = "unknown"
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	response := tangled.KnotHealth_Output{
+		Version:      v,
+		StartedAt:    startedAt.UTC().Format(time.RFC3339),
+		Uptime:       int64(time.Since(startedAt).Seconds()),
+		Capabilities: x.capabilities,
+		Load: &tangled.KnotHealth_Load{
+			Goroutines: int64(runtime.NumGoroutine()),
+			HeapBytes:  int64(mem.HeapInuse),
+			Cpus:       int64(runtime.NumCPU()),
+		},
+	}
+
+	writeJson(w, response)
+}
+
+// methods lists the NSIDs of the XRPC methods served by r.
+func methods(r chi.Routes) []string {
+	var nsids []string
+	chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		nsid := strings.TrimPrefix(route, "/")
+		if !slices.Contains(nsids, nsid) {
+			nsids = append(nsids, nsid)
+		}
+		return nil
+	})
+	slices.Sort(nsids)
+	return nsids
+}
@@ -0,0 +1,60 @@
+package xrpc
+
+import (
+	"net/http"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/git"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+// KnotUsage reports the storage used by a user's repos, which counts
+// against the knot's quota. Only the user and the knot owner may see it.
+func (x *Xrpc) KnotUsage(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger.With("handler", "KnotUsage")
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		writeError(w, xrpcerr.MissingActorDidError, http.StatusBadRequest)
+		return
+	}
+
+	did, err := syntax.ParseDID(r.URL.Query().Get("did"))
+	if err != nil {
+		writeError(w, xrpcerr.NewXrpcError(
+			xrpcerr.WithTag("InvalidRequest"),
+			xrpcerr.WithMessage("invalid did"),
+		), http.StatusBadRequest)
+		return
+	}
+
+	if actorDid != did && actorDid.String() != x.Config.Server.Owner {
+		writeError(w, xrpcerr.AccessControlError(actorDid.String()), http.StatusForbidden)
+		return
+	}
+
+	userPath, err := securejoin.SecureJoin(x.Config.Repo.ScanPath, did.String())
+	if err != nil {
+		writeError(w, xrpcerr.GenericError(err), http.StatusBadRequest)
+		return
+	}
+
+	usage, err := git.DirSize(userPath)
+	if err != nil {
+		l.Error("failed to measure repos", "did", did, "err", err)
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	out := tangled.KnotUsage_Output{
+		Did:   did.String(),
+		Usage: usage,
+	}
+	if quota := x.Config.Repo.QuotaBytes; quota > 0 {
+		out.Quota = &quota
+	}
+
+	writeJson(w, out)
+}
@@ -84,6 +84,9 @@ func (x *Xrpc) Merge(w http.ResponseWriter, r *http.Request) {
 	mo.CommitterName = x.Config.Git.UserName
 	mo.CommitterEmail = x.Config.Git.UserEmail
 	mo.FormatPatch = patchutil.IsFormatPatch(data.Patch)
+	if data.Fuzz != nil {
+		mo.Fuzz = int(*data.Fuzz)
+	}
 
 	err = gr.MergeWithOptions(data.Patch, data.Branch, mo)
 	if err != nil {
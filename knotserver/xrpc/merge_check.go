@@ -39,6 +39,12 @@ func (x *Xrpc) MergeCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the repo is in the body, so RepoAccess can't see it
+	if ok, err := x.canReadRepo(r, relativeRepoPath); err != nil || !ok {
+		writeError(w, xrpcerr.RepoNotFoundError, http.StatusNotFound)
+		return
+	}
+
 	repoPath, err := securejoin.SecureJoin(x.Config.Repo.ScanPath, relativeRepoPath)
 	if err != nil {
 		fail(xrpcerr.GenericError(err))
@@ -51,11 +57,19 @@ func (x *Xrpc) MergeCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = gr.MergeCheck(data.Patch, data.Branch)
+	var fuzz int
+	if data.Fuzz != nil {
+		fuzz = int(*data.Fuzz)
+	}
+
+	fuzzed, err := gr.MergeCheck(data.Patch, data.Branch, fuzz)
 
 	response := tangled.RepoMergeCheck_Output{
 		Is_conflicted: false,
 	}
+	if fuzzed {
+		response.Applied_with_fuzz = &fuzzed
+	}
 
 	if err != nil {
 		var mergeErr *git.ErrMerge
@@ -68,6 +82,14 @@ func (x *Xrpc) MergeCheck(w http.ResponseWriter, r *http.Request) {
 					Filename: conflict.Filename,
 					Reason:   conflict.Reason,
 				}
+				for _, h := range conflict.Hunks {
+					conflicts[i].Hunks = append(conflicts[i].Hunks, &tangled.RepoMergeCheck_ConflictHunk{
+						Line:   h.Line,
+						Base:   h.Base,
+						Ours:   h.Ours,
+						Theirs: h.Theirs,
+					})
+				}
 			}
 			response.Conflicts = conflicts
 
@@ -0,0 +1,72 @@
+package xrpc
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/rbac"
+	xrpcerr "tangled.org/core/xrpc/errors"
+	"tangled.org/core/xrpc/serviceauth"
+)
+
+// RepoAccess hides private repos from the query endpoints. Callers have to
+// authenticate with service auth and hold a role in the repo to see them.
+func (x *Xrpc) RepoAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.URL.Query().Get("repo")
+		if repo == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		parts := strings.SplitN(repo, "/", 2)
+		if len(parts) != 2 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		didRepoPath, err := securejoin.SecureJoin(parts[0], parts[1])
+		if err != nil {
+			writeError(w, xrpcerr.RepoNotFoundError, http.StatusNotFound)
+			return
+		}
+
+		ok, err := x.canReadRepo(r, didRepoPath)
+		if err != nil {
+			x.Logger.Error("failed to check repo access", "repo", didRepoPath, "err", err)
+			writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			writeError(w, xrpcerr.RepoNotFoundError, http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// canReadRepo reports whether the caller of r can see repo, in did/name form.
+func (x *Xrpc) canReadRepo(r *http.Request, repo string) (bool, error) {
+	private, err := x.Db.IsRepoPrivate(repo)
+	if err != nil {
+		return false, err
+	}
+	if !private {
+		return true, nil
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok || !serviceauth.HasScope(r.Context(), serviceauth.ScopeApi) {
+		return false, nil
+	}
+
+	return x.Enforcer.IsRepoReadAllowed(actorDid.String(), rbac.ThisServer, repo)
+}
+
+func isPrivate(repo *tangled.Repo) bool {
+	return repo.Visibility != nil && *repo.Visibility == "private"
+}
@@ -4,11 +4,13 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"mime"
 	"net/http"
 	"path/filepath"
 	"slices"
 	"strings"
 
+	"github.com/go-git/go-git/v5/plumbing"
 	"tangled.org/core/api/tangled"
 	"tangled.org/core/knotserver/git"
 	xrpcerr "tangled.org/core/xrpc/errors"
@@ -81,7 +83,7 @@ func (x *Xrpc) RepoBlob(w http.ResponseWriter, r *http.Request) {
 		eTag := fmt.Sprintf("\"%x\"", contentHash)
 
 		switch {
-		case strings.HasPrefix(mimeType, "image/"), strings.HasPrefix(mimeType, "video/"):
+		case strings.HasPrefix(mimeType, "image/"), strings.HasPrefix(mimeType, "video/"), mimeType == "application/pdf":
 			if clientETag := r.Header.Get("If-None-Match"); clientETag == eTag {
 				w.WriteHeader(http.StatusNotModified)
 				return
@@ -100,12 +102,10 @@ func (x *Xrpc) RepoBlob(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 
 		default:
-			x.Logger.Error("attempted to serve disallowed file type", "mimetype", mimeType)
-			writeError(w, xrpcerr.NewXrpcError(
-				xrpcerr.WithTag("InvalidRequest"),
-				xrpcerr.WithMessage("only image, video, and text files can be accessed directly"),
-			), http.StatusForbidden)
-			return
+			// everything else is only ever downloaded, never displayed
+			w.Header().Set("Content-Type", mimeType)
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(treePath)}))
+			w.Header().Set("X-Content-Type-Options", "nosniff")
 		}
 		w.Write(contents)
 		return
@@ -129,6 +129,8 @@ func (x *Xrpc) RepoBlob(w http.ResponseWriter, r *http.Request) {
 		encoding = "utf-8"
 	}
 
+	hash := plumbing.ComputeHash(plumbing.BlobObject, contents).String()
+
 	response := tangled.RepoBlob_Output{
 		Ref:      ref,
 		Path:     treePath,
@@ -136,6 +138,7 @@ func (x *Xrpc) RepoBlob(w http.ResponseWriter, r *http.Request) {
 		Encoding: &encoding,
 		Size:     &size,
 		IsBinary: &isBinary,
+		Hash:     &hash,
 	}
 
 	if mimeType != "" {
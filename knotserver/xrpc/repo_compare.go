@@ -92,6 +92,7 @@ func (x *Xrpc) RepoCompare(w http.ResponseWriter, r *http.Request) {
 		FormatPatchRaw:   rawPatch,
 		CombinedPatch:    combinedPatch,
 		CombinedPatchRaw: combinedPatchRaw,
+		Commits:          types.CompareCommitsFromPatches(formatPatch),
 	}
 
 	writeJson(w, response)
@@ -0,0 +1,61 @@
+package xrpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/git"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+func (x *Xrpc) RepoContributors(w http.ResponseWriter, r *http.Request) {
+	repo := r.URL.Query().Get("repo")
+	repoPath, err := x.parseRepoParam(repo)
+	if err != nil {
+		writeError(w, err.(xrpcerr.XrpcError), http.StatusBadRequest)
+		return
+	}
+
+	ref := r.URL.Query().Get("ref")
+
+	gr, err := git.Open(repoPath, ref)
+	if err != nil {
+		x.Logger.Error("opening repo", "error", err.Error())
+		writeError(w, xrpcerr.RefNotFoundError, http.StatusNotFound)
+		return
+	}
+
+	// walks the whole history, callers are expected to cache the result
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	contributors, err := gr.Contributors(ctx)
+	if err != nil {
+		x.Logger.Error("failed to aggregate contributors", "error", err.Error())
+		writeError(w, xrpcerr.NewXrpcError(
+			xrpcerr.WithTag("InvalidRequest"),
+			xrpcerr.WithMessage("failed to aggregate repository contributors"),
+		), http.StatusInternalServerError)
+		return
+	}
+
+	apiContributors := make([]*tangled.RepoContributors_Contributor, len(contributors))
+	for i, c := range contributors {
+		apiContributors[i] = &tangled.RepoContributors_Contributor{
+			Name:        c.Name,
+			Email:       c.Email,
+			Commits:     c.Commits,
+			Additions:   c.Additions,
+			Deletions:   c.Deletions,
+			FirstCommit: c.FirstCommit.Format(time.RFC3339),
+			LastCommit:  c.LastCommit.Format(time.RFC3339),
+		}
+	}
+
+	writeJson(w, tangled.RepoContributors_Output{
+		Ref:          ref,
+		Contributors: apiContributors,
+	})
+}
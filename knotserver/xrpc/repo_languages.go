@@ -31,7 +31,7 @@ func (x *Xrpc) RepoLanguages(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
 	defer cancel()
 
-	sizes, err := gr.AnalyzeLanguages(ctx)
+	sizes, excluded, err := gr.AnalyzeLanguages(ctx)
 	if err != nil {
 		x.Logger.Error("failed to analyze languages", "error", err.Error())
 		writeError(w, xrpcerr.NewXrpcError(
@@ -64,6 +64,12 @@ func (x *Xrpc) RepoLanguages(w http.ResponseWriter, r *http.Request) {
 	response := tangled.RepoLanguages_Output{
 		Ref:       ref,
 		Languages: apiLanguages,
+		Excluded: &tangled.RepoLanguages_Excluded{
+			Vendored:      excluded.Vendored,
+			Generated:     excluded.Generated,
+			Documentation: excluded.Documentation,
+			Overridden:    excluded.Overridden,
+		},
 	}
 
 	if totalSize > 0 {
@@ -0,0 +1,27 @@
+package xrpc
+
+import (
+	"net/http"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/knotserver/git"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+func (x *Xrpc) RepoSize(w http.ResponseWriter, r *http.Request) {
+	repo := r.URL.Query().Get("repo")
+	repoPath, err := x.parseRepoParam(repo)
+	if err != nil {
+		writeError(w, err.(xrpcerr.XrpcError), http.StatusBadRequest)
+		return
+	}
+
+	size, err := git.DirSize(repoPath)
+	if err != nil {
+		x.Logger.Error("failed to measure repo", "repo", repo, "err", err)
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, tangled.RepoSize_Output{Size: size})
+}
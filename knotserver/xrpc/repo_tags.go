@@ -3,6 +3,7 @@ package xrpc
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -77,6 +78,28 @@ func (x *Xrpc) RepoTags(w http.ResponseWriter, r *http.Request) {
 
 	paginatedTags := rtags[offset:end]
 
+	// signed tags carry their payload so that the appview can verify them
+	for _, tr := range paginatedTags {
+		if tr.Tag == nil {
+			continue
+		}
+
+		payload, signature, err := gr.TagSignature(tr.Tag.Hash)
+		if err != nil {
+			x.Logger.Warn("getting tag signature", "tag", tr.Name, "error", err.Error())
+			continue
+		}
+		if signature == "" {
+			continue
+		}
+
+		// for-each-ref reports the signature as part of the message
+		tr.Tag.Message = strings.TrimSuffix(tr.Tag.Message, strings.TrimSpace(signature))
+		tr.Tag.PGPSignature = signature
+		tr.Message = tr.Tag.Message
+		tr.Payload = payload
+	}
+
 	// Create response using existing types.RepoTagsResponse
 	response := types.RepoTagsResponse{
 		Tags: paginatedTags,
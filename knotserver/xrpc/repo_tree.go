@@ -80,6 +80,17 @@ func (x *Xrpc) RepoTree(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if sm := file.Submodule; sm != nil {
+			entry.Submodule = &tangled.RepoTree_Submodule{
+				Name:   sm.Name,
+				Url:    sm.Url,
+				Commit: sm.Commit,
+			}
+			if sm.Branch != "" {
+				entry.Submodule.Branch = &sm.Branch
+			}
+		}
+
 		treeEntries[i] = entry
 	}
 
@@ -0,0 +1,64 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+// SetVisibility makes a repo private or public, as its record says. Only the
+// owner of the repo can change it.
+func (x *Xrpc) SetVisibility(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger.With("handler", "SetVisibility")
+	fail := func(e xrpcerr.XrpcError) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, http.StatusBadRequest)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError)
+		return
+	}
+
+	var data tangled.RepoSetVisibility_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	repoAt, err := syntax.ParseATURI(data.Repo)
+	if err != nil {
+		fail(xrpcerr.InvalidRepoError(data.Repo))
+		return
+	}
+
+	if repoAt.Authority().String() != actorDid.String() {
+		writeError(w, xrpcerr.AccessControlError(actorDid.String()), http.StatusUnauthorized)
+		return
+	}
+
+	repo, err := x.getRepoRecord(r.Context(), actorDid.String(), repoAt.RecordKey().String())
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	didPath, err := securejoin.SecureJoin(actorDid.String(), repo.Name)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	if err := x.Db.SetRepoPrivate(didPath, isPrivate(repo)); err != nil {
+		l.Error("setting visibility", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,161 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/xrpc"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/rbac"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+// TransferRepo hands a repo over to the caller. The current owner agrees to
+// the transfer by naming the caller in their repo record, and the caller
+// accepts it by creating their own record for the repo.
+func (x *Xrpc) TransferRepo(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger.With("handler", "TransferRepo")
+	fail := func(e xrpcerr.XrpcError) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, http.StatusBadRequest)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError)
+		return
+	}
+
+	var data tangled.RepoTransfer_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	did := data.Did
+	name := data.Name
+	l = l.With("did", did, "name", name, "to", actorDid)
+
+	if did == "" || name == "" || data.Rkey == "" || data.NewRkey == "" {
+		fail(xrpcerr.GenericError(fmt.Errorf("did, name, rkey and newRkey are required")))
+		return
+	}
+
+	// only members may keep repos on this knot
+	isMember, err := x.Enforcer.IsRepoCreateAllowed(actorDid.String(), rbac.ThisServer)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+	if !isMember {
+		fail(xrpcerr.AccessControlError(actorDid.String()))
+		return
+	}
+
+	oldRecord, err := x.getRepoRecord(r.Context(), did, data.Rkey)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+	if oldRecord.Name != name || oldRecord.TransferTo == nil || *oldRecord.TransferTo != actorDid.String() {
+		fail(xrpcerr.AccessControlError(actorDid.String()))
+		return
+	}
+
+	newRecord, err := x.getRepoRecord(r.Context(), actorDid.String(), data.NewRkey)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+	if newRecord.Name != name || newRecord.Knot != oldRecord.Knot {
+		fail(xrpcerr.GenericError(fmt.Errorf("new repo record does not match %s/%s", did, name)))
+		return
+	}
+
+	oldRelativePath := filepath.Join(did, name)
+	newRelativePath := filepath.Join(actorDid.String(), name)
+
+	oldPath, err := securejoin.SecureJoin(x.Config.Repo.ScanPath, oldRelativePath)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+	newPath, err := securejoin.SecureJoin(x.Config.Repo.ScanPath, newRelativePath)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	if _, err := os.Stat(oldPath); err != nil {
+		fail(xrpcerr.RepoNotFoundError)
+		return
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		fail(xrpcerr.RepoExistsError(newRelativePath))
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		l.Error("creating owner directory", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		l.Error("moving repo", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = x.Enforcer.TransferRepo(actorDid.String(), rbac.ThisServer, oldRelativePath, newRelativePath)
+	if err != nil {
+		l.Error("failed to transfer repo permissions", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = errors.Join(
+		x.Db.SetRepoPrivate(oldRelativePath, false),
+		x.Db.SetRepoPrivate(newRelativePath, isPrivate(newRecord)),
+	)
+	if err != nil {
+		l.Error("failed to transfer repo visibility", "error", err.Error())
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	l.Info("transferred repo")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (x *Xrpc) getRepoRecord(ctx context.Context, did, rkey string) (*tangled.Repo, error) {
+	ident, err := x.Resolver.ResolveIdent(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if ident.Handle.IsInvalidHandle() {
+		return nil, fmt.Errorf("invalid handle for %s", did)
+	}
+
+	xrpcc := xrpc.Client{
+		Host: ident.PDSEndpoint(),
+	}
+
+	resp, err := comatproto.RepoGetRecord(ctx, &xrpcc, "", tangled.RepoNSID, did, rkey)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := resp.Value.Val.(*tangled.Repo)
+	if !ok {
+		return nil, fmt.Errorf("not a repo record: %s/%s", did, rkey)
+	}
+	return record, nil
+}
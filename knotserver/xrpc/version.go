@@ -12,49 +12,60 @@ import (
 var version string
 
 func (x *Xrpc) Version(w http.ResponseWriter, r *http.Request) {
-	if version == "" {
-		info, ok := debug.ReadBuildInfo()
-		if !ok {
-			http.Error(w, "failed to read build info", http.StatusInternalServerError)
-			return
-		}
+	v, err := knotVersion()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		var modVer string
-		var sha string
-		var modified bool
+	response := tangled.KnotVersion_Output{
+		Version: v,
+	}
 
-		for _, mod := range info.Deps {
-			if mod.Path == "tangled.org/tangled.org/knotserver/xrpc" {
-				modVer = mod.Version
-				break
-			}
-		}
+	writeJson(w, response)
+}
 
-		for _, setting := range info.Settings {
-			switch setting.Key {
-			case "vcs.revision":
-				sha = setting.Value
-			case "vcs.modified":
-				modified = setting.Value == "true"
-			}
-		}
+func knotVersion() (string, error) {
+	if version != "" {
+		return version, nil
+	}
 
-		if modVer == "" {
-			modVer = "unknown"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", fmt.Errorf("failed to read build info")
+	}
+
+	var modVer string
+	var sha string
+	var modified bool
+
+	for _, mod := range info.Deps {
+		if mod.Path == "tangled.org/tangled.org/knotserver/xrpc" {
+			modVer = mod.Version
+			break
 		}
+	}
 
-		if sha == "" {
-			version = modVer
-		} else if modified {
-			version = fmt.Sprintf("%s (%s with modifications)", modVer, sha)
-		} else {
-			version = fmt.Sprintf("%s (%s)", modVer, sha)
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			sha = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
 		}
 	}
 
-	response := tangled.KnotVersion_Output{
-		Version: version,
+	if modVer == "" {
+		modVer = "unknown"
 	}
 
-	writeJson(w, response)
+	if sha == "" {
+		version = modVer
+	} else if modified {
+		version = fmt.Sprintf("%s (%s with modifications)", modVer, sha)
+	} else {
+		version = fmt.Sprintf("%s (%s)", modVer, sha)
+	}
+
+	return version, nil
 }
@@ -29,6 +29,9 @@ type Xrpc struct {
 	Notifier    *notifier.Notifier
 	Resolver    *idresolver.Resolver
 	ServiceAuth *serviceauth.ServiceAuth
+
+	// the methods served, reported by the health endpoint
+	capabilities []string
 }
 
 func (x *Xrpc) Router() http.Handler {
@@ -40,40 +43,58 @@ func (x *Xrpc) Router() http.Handler {
 		r.Post("/"+tangled.RepoSetDefaultBranchNSID, x.SetDefaultBranch)
 		r.Post("/"+tangled.RepoDeleteBranchNSID, x.DeleteBranch)
 		r.Post("/"+tangled.RepoCreateNSID, x.CreateRepo)
+		r.Post("/"+tangled.RepoCreateFromTemplateNSID, x.CreateFromTemplate)
 		r.Post("/"+tangled.RepoDeleteNSID, x.DeleteRepo)
+		r.Post("/"+tangled.RepoTransferNSID, x.TransferRepo)
+		r.Post("/"+tangled.RepoSetVisibilityNSID, x.SetVisibility)
 		r.Post("/"+tangled.RepoForkStatusNSID, x.ForkStatus)
 		r.Post("/"+tangled.RepoForkSyncNSID, x.ForkSync)
 		r.Post("/"+tangled.RepoHiddenRefNSID, x.HiddenRef)
+		r.Post("/"+tangled.RepoDeleteHiddenRefNSID, x.DeleteHiddenRef)
+		r.Post("/"+tangled.RepoPruneHiddenRefsNSID, x.PruneHiddenRefs)
 		r.Post("/"+tangled.RepoMergeNSID, x.Merge)
+		r.Post("/"+tangled.RepoCommitFilesNSID, x.CommitFiles)
+
+		r.Get("/"+tangled.KnotUsageNSID, x.KnotUsage)
 	})
 
-	// merge check is an open endpoint
-	//
-	// TODO: should we constrain this more?
-	// - we can calculate on PR submit/resubmit/gitRefUpdate etc.
-	// - use ETags on clients to keep requests to a minimum
-	r.Post("/"+tangled.RepoMergeCheckNSID, x.MergeCheck)
-
-	// repo query endpoints (no auth required)
-	r.Get("/"+tangled.RepoTreeNSID, x.RepoTree)
-	r.Get("/"+tangled.RepoLogNSID, x.RepoLog)
-	r.Get("/"+tangled.RepoBranchesNSID, x.RepoBranches)
-	r.Get("/"+tangled.RepoTagsNSID, x.RepoTags)
-	r.Get("/"+tangled.RepoBlobNSID, x.RepoBlob)
-	r.Get("/"+tangled.RepoDiffNSID, x.RepoDiff)
-	r.Get("/"+tangled.RepoCompareNSID, x.RepoCompare)
-	r.Get("/"+tangled.RepoGetDefaultBranchNSID, x.RepoGetDefaultBranch)
-	r.Get("/"+tangled.RepoBranchNSID, x.RepoBranch)
-	r.Get("/"+tangled.RepoArchiveNSID, x.RepoArchive)
-	r.Get("/"+tangled.RepoLanguagesNSID, x.RepoLanguages)
+	// repo query endpoints, private repos need service auth
+	r.Group(func(r chi.Router) {
+		r.Use(x.ServiceAuth.OptionalServiceAuth)
+		r.Use(x.RepoAccess)
+
+		// merge check is an open endpoint
+		//
+		// TODO: should we constrain this more?
+		// - we can calculate on PR submit/resubmit/gitRefUpdate etc.
+		// - use ETags on clients to keep requests to a minimum
+		r.Post("/"+tangled.RepoMergeCheckNSID, x.MergeCheck)
+
+		r.Get("/"+tangled.RepoTreeNSID, x.RepoTree)
+		r.Get("/"+tangled.RepoLogNSID, x.RepoLog)
+		r.Get("/"+tangled.RepoBranchesNSID, x.RepoBranches)
+		r.Get("/"+tangled.RepoTagsNSID, x.RepoTags)
+		r.Get("/"+tangled.RepoBlobNSID, x.RepoBlob)
+		r.Get("/"+tangled.RepoDiffNSID, x.RepoDiff)
+		r.Get("/"+tangled.RepoCompareNSID, x.RepoCompare)
+		r.Get("/"+tangled.RepoGetDefaultBranchNSID, x.RepoGetDefaultBranch)
+		r.Get("/"+tangled.RepoBranchNSID, x.RepoBranch)
+		r.Get("/"+tangled.RepoArchiveNSID, x.RepoArchive)
+		r.Get("/"+tangled.RepoLanguagesNSID, x.RepoLanguages)
+		r.Get("/"+tangled.RepoContributorsNSID, x.RepoContributors)
+		r.Get("/"+tangled.RepoSizeNSID, x.RepoSize)
+	})
 
 	// knot query endpoints (no auth required)
 	r.Get("/"+tangled.KnotListKeysNSID, x.ListKeys)
 	r.Get("/"+tangled.KnotVersionNSID, x.Version)
+	r.Get("/"+tangled.KnotHealthNSID, x.Health)
 
 	// service query endpoints (no auth required)
 	r.Get("/"+tangled.OwnerNSID, x.Owner)
 
+	x.capabilities = methods(r)
+
 	return r
 }
 
@@ -77,6 +77,18 @@ in
             default = "main";
             description = "Default branch name for repositories";
           };
+
+          quotaBytes = mkOption {
+            type = types.int;
+            default = 0;
+            description = "Bytes each user's repositories may take up on disk, 0 for no quota";
+          };
+
+          maxWebCommitBytes = mkOption {
+            type = types.int;
+            default = 67108864;
+            description = "Bytes a request to commit files from the web may take";
+          };
         };
 
         git = {
@@ -250,6 +262,8 @@ in
             "KNOT_REPO_SCAN_PATH=${cfg.repo.scanPath}"
             "KNOT_REPO_README=${concatStringsSep "," cfg.repo.readme}"
             "KNOT_REPO_MAIN_BRANCH=${cfg.repo.mainBranch}"
+            "KNOT_REPO_QUOTA_BYTES=${toString cfg.repo.quotaBytes}"
+            "KNOT_REPO_MAX_WEB_COMMIT_BYTES=${toString cfg.repo.maxWebCommitBytes}"
             "KNOT_GIT_USER_NAME=${cfg.git.userName}"
             "KNOT_GIT_USER_EMAIL=${cfg.git.userEmail}"
             "APPVIEW_ENDPOINT=${cfg.appviewEndpoint}"
@@ -68,6 +68,13 @@ in
             description = "Maximum number of concurrent jobs to run";
           };
 
+          maxRepoJobCount = mkOption {
+            type = types.int;
+            default = 1;
+            example = 2;
+            description = "Default maximum number of concurrent pipelines of a single repo, further pipelines are queued";
+          };
+
           queueSize = mkOption {
             type = types.int;
             default = 100;
@@ -82,6 +89,14 @@ in
               description = "Backend to use for secret management, valid options are 'sqlite', and 'openbao'.";
             };
 
+            sqlite = {
+              keyFile = mkOption {
+                type = types.path;
+                default = "/var/lib/spindle/secrets.key";
+                description = "Key used to encrypt secrets at rest, generated on first start if missing";
+              };
+            };
+
             openbao = {
               proxyAddr = mkOption {
                 type = types.str;
@@ -130,8 +145,10 @@ in
             "SPINDLE_SERVER_DEV=${lib.boolToString cfg.server.dev}"
             "SPINDLE_SERVER_OWNER=${cfg.server.owner}"
             "SPINDLE_SERVER_MAX_JOB_COUNT=${toString cfg.server.maxJobCount}"
+            "SPINDLE_SERVER_MAX_REPO_JOB_COUNT=${toString cfg.server.maxRepoJobCount}"
             "SPINDLE_SERVER_QUEUE_SIZE=${toString cfg.server.queueSize}"
             "SPINDLE_SERVER_SECRETS_PROVIDER=${cfg.server.secrets.provider}"
+            "SPINDLE_SERVER_SECRETS_SQLITE_KEY_FILE=${cfg.server.secrets.sqlite.keyFile}"
             "SPINDLE_SERVER_SECRETS_OPENBAO_PROXY_ADDR=${cfg.server.secrets.openbao.proxyAddr}"
             "SPINDLE_SERVER_SECRETS_OPENBAO_MOUNT=${cfg.server.secrets.openbao.mount}"
             "SPINDLE_NIXERY_PIPELINES_NIXERY=${cfg.pipelines.nixery}"
@@ -16,6 +16,10 @@ import (
 // rev2' <- apply(patch2, merged)
 // combineddiff <- diff(rev2', original1)
 func combineFiles(file1, file2 *gitdiff.File) (*gitdiff.File, error) {
+	if file1.IsBinary || file2.IsBinary {
+		return combineBinaryFiles(file1, file2), nil
+	}
+
 	fileName := bestName(file1)
 
 	o1 := CreatePreImage(file1)
@@ -48,6 +52,38 @@ func combineFiles(file1, file2 *gitdiff.File) (*gitdiff.File, error) {
 	return parsed[0], nil
 }
 
+// binary changes have no lines to merge, so the combined change goes from
+// the first file's original to the second file's result. only literal
+// fragments carry over, deltas are relative to the intermediate file; a
+// binary file without fragments still shows up as changed.
+func combineBinaryFiles(file1, file2 *gitdiff.File) *gitdiff.File {
+	// added by one commit and removed by the next
+	if file1.IsNew && file2.IsDelete {
+		return nil
+	}
+
+	combined := &gitdiff.File{
+		OldName:  file1.OldName,
+		NewName:  file2.NewName,
+		IsNew:    file1.IsNew,
+		IsDelete: file2.IsDelete,
+		IsCopy:   file1.IsCopy,
+		IsRename: file1.IsRename || file2.IsRename,
+		OldMode:  file1.OldMode,
+		NewMode:  file2.NewMode,
+		IsBinary: true,
+	}
+
+	if f := file2.BinaryFragment; f != nil && f.Method == gitdiff.BinaryPatchLiteral {
+		combined.BinaryFragment = f
+	}
+	if f := file1.ReverseBinaryFragment; f != nil && f.Method == gitdiff.BinaryPatchLiteral {
+		combined.ReverseBinaryFragment = f
+	}
+
+	return combined
+}
+
 // use empty lines for lines we are unaware of
 //
 // this raises an error only if the two patches were invalid or non-contiguous
@@ -1,6 +1,7 @@
 package patchutil
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
@@ -30,6 +31,24 @@ func (i *InterdiffResult) String() string {
 	return b.String()
 }
 
+// Comparable reports whether the two patches shared enough context to be
+// interdiffed: false when every file they both touch failed to line up,
+// which happens when the base moved a lot between them.
+func (i *InterdiffResult) Comparable() bool {
+	shared := 0
+	for _, f := range i.Files {
+		switch f.Status.StatusKind {
+		case StatusOnlyInOne, StatusOnlyInTwo:
+			continue
+		case StatusRebased, StatusError:
+			shared++
+		default:
+			return true
+		}
+	}
+	return shared == 0
+}
+
 type InterdiffFile struct {
 	*gitdiff.File
 	Name   string
@@ -55,6 +74,12 @@ func (s *InterdiffFile) Split() *types.SplitDiff {
 	}
 }
 
+// Binary reports whether the file is a binary one, which has no lines to
+// show.
+func (s *InterdiffFile) Binary() bool {
+	return s.File != nil && s.File.IsBinary
+}
+
 // used by html elements as a unique ID for hrefs
 func (s *InterdiffFile) Id() string {
 	return s.Name
@@ -141,6 +166,10 @@ const (
 )
 
 func interdiffFiles(f1, f2 *gitdiff.File) *InterdiffFile {
+	if f1.IsBinary || f2.IsBinary {
+		return interdiffBinaryFiles(f1, f2)
+	}
+
 	re1 := CreatePreImage(f1)
 	re2 := CreatePreImage(f2)
 
@@ -208,6 +237,34 @@ func interdiffFiles(f1, f2 *gitdiff.File) *InterdiffFile {
 	return &interdiffFile
 }
 
+// binary files can't be diffed line by line, so the interdiff only tells
+// whether the change is the same in both patches. when either patch leaves
+// out the binary data there is no telling, and it is reported as changed.
+func interdiffBinaryFiles(f1, f2 *gitdiff.File) *InterdiffFile {
+	interdiffFile := InterdiffFile{
+		File: f2,
+		Name: bestName(f1),
+	}
+
+	if f1.IsBinary && f2.IsBinary &&
+		f1.IsDelete == f2.IsDelete &&
+		f1.NewMode == f2.NewMode &&
+		sameBinaryFragment(f1.BinaryFragment, f2.BinaryFragment) {
+		interdiffFile.Status = InterdiffFileStatus{
+			StatusKind: StatusUnchanged,
+		}
+	}
+
+	return &interdiffFile
+}
+
+func sameBinaryFragment(a, b *gitdiff.BinaryFragment) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.Method == b.Method && a.Size == b.Size && bytes.Equal(a.Data, b.Data)
+}
+
 func Interdiff(patch1, patch2 []*gitdiff.File) *InterdiffResult {
 	fileToIdx1 := make(map[string]int)
 	fileToIdx2 := make(map[string]int)
@@ -294,6 +294,7 @@ func AsNiceDiff(patch, targetBranch string) types.NiceDiff {
 	if err != nil {
 		log.Println(err)
 	}
+	diffs = DetectRenames(diffs, RenameThreshold)
 
 	nd := types.NiceDiff{}
 	nd.Commit.Parent = targetBranch
@@ -307,6 +308,9 @@ func AsNiceDiff(patch, targetBranch string) types.NiceDiff {
 		ndiff.IsDelete = d.IsDelete
 		ndiff.IsCopy = d.IsCopy
 		ndiff.IsRename = d.IsRename
+		if d.IsRename || d.IsCopy {
+			ndiff.Similarity = d.Score
+		}
 
 		for _, tf := range d.TextFragments {
 			ndiff.TextFragments = append(ndiff.TextFragments, *tf)
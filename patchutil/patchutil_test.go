@@ -3,7 +3,10 @@ package patchutil
 import (
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
 )
 
 func TestIsPatchValid(t *testing.T) {
@@ -323,3 +326,390 @@ content
 		})
 	}
 }
+
+func TestInterdiffComparable(t *testing.T) {
+	file := func(kind StatusKind) *InterdiffFile {
+		return &InterdiffFile{Status: InterdiffFileStatus{StatusKind: kind}}
+	}
+
+	tests := []struct {
+		name     string
+		files    []*InterdiffFile
+		expected bool
+	}{
+		{
+			name:     `no shared files`,
+			files:    []*InterdiffFile{file(StatusOnlyInOne), file(StatusOnlyInTwo)},
+			expected: true,
+		},
+		{
+			name:     `some shared files line up`,
+			files:    []*InterdiffFile{file(StatusRebased), file(StatusOk)},
+			expected: true,
+		},
+		{
+			name:     `no shared files line up`,
+			files:    []*InterdiffFile{file(StatusRebased), file(StatusError), file(StatusOnlyInTwo)},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &InterdiffResult{Files: tt.files}
+			if got := result.Comparable(); got != tt.expected {
+				t.Errorf("Comparable() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// made with git diff --binary
+const (
+	addLogoPatch = `diff --git a/a.txt b/a.txt
+index ce01362..94954ab 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1,2 @@
+ hello
++world
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000000000000000000000000000000000000..8352675d67aed6625ece79af41c27fdb4ee2e867
+GIT binary patch
+literal 3
+KcmZQzWC8#H2LJ>B
+
+literal 0
+HcmV?d00001
+
+`
+	addOtherLogoPatch = `diff --git a/a.txt b/a.txt
+index ce01362..94954ab 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1,2 @@
+ hello
++world
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000000000000000000000000000000000000..ef2caffcda6e1bd757164a29c6f81be03d172fd5
+GIT binary patch
+literal 4
+LcmZQzWM%;X01*HQ
+
+literal 0
+HcmV?d00001
+
+`
+	changeLogoPatch = `diff --git a/logo.png b/logo.png
+index 8352675d67aed6625ece79af41c27fdb4ee2e867..ef2caffcda6e1bd757164a29c6f81be03d172fd5 100644
+GIT binary patch
+literal 4
+LcmZQzWM%;X01*HQ
+
+literal 3
+KcmZQzWC8#H2LJ>B
+
+`
+	deleteLogoPatch = `diff --git a/logo.png b/logo.png
+deleted file mode 100644
+index 8352675..0000000
+Binary files a/logo.png and /dev/null differ
+`
+)
+
+func parsePatch(t *testing.T, patch string) []*gitdiff.File {
+	t.Helper()
+	files, _, err := gitdiff.Parse(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("failed to parse patch: %v", err)
+	}
+	return files
+}
+
+func TestCombineDiffBinary(t *testing.T) {
+	t.Run("binary changes are kept", func(t *testing.T) {
+		combined := CombineDiff(parsePatch(t, addLogoPatch), parsePatch(t, changeLogoPatch))
+		if len(combined) != 2 {
+			t.Fatalf("expected 2 files, got %d", len(combined))
+		}
+
+		logo := combined[1]
+		if !logo.IsBinary || !logo.IsNew || bestName(logo) != "logo.png" {
+			t.Errorf("expected a new binary logo.png, got %+v", logo)
+		}
+		if logo.BinaryFragment == nil || logo.BinaryFragment.Size != 4 {
+			t.Errorf("expected the literal contents of the second patch, got %+v", logo.BinaryFragment)
+		}
+	})
+
+	t.Run("binary files added and deleted cancel out", func(t *testing.T) {
+		combined := CombineDiff(parsePatch(t, addLogoPatch), parsePatch(t, deleteLogoPatch))
+		if len(combined) != 1 || bestName(combined[0]) != "a.txt" {
+			t.Errorf("expected only a.txt, got %v", combined)
+		}
+	})
+}
+
+func TestInterdiffBinary(t *testing.T) {
+	logo := func(result *InterdiffResult) *InterdiffFile {
+		for _, f := range result.Files {
+			if f.Name == "logo.png" {
+				return f
+			}
+		}
+		t.Fatal("logo.png is missing from the interdiff")
+		return nil
+	}
+
+	t.Run("identical binary changes are unchanged", func(t *testing.T) {
+		result := Interdiff(parsePatch(t, addLogoPatch), parsePatch(t, addLogoPatch))
+		if f := logo(result); !f.Status.IsUnchanged() {
+			t.Errorf("expected logo.png to be unchanged, got %s", f.Status.String())
+		}
+	})
+
+	t.Run("different binary changes are changed", func(t *testing.T) {
+		result := Interdiff(parsePatch(t, addLogoPatch), parsePatch(t, addOtherLogoPatch))
+		f := logo(result)
+		if !f.Status.IsOk() || !f.Binary() {
+			t.Errorf("expected logo.png to be a changed binary file, got %s", f.Status.String())
+		}
+	})
+}
+
+func TestDetectRenames(t *testing.T) {
+	files := parsePatch(t, `diff --git a/old.go b/old.go
+deleted file mode 100644
+index 1111111..0000000
+--- a/old.go
++++ /dev/null
+@@ -1,4 +0,0 @@
+-package main
+-
+-func main() {
+-}
+diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..2222222
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,4 @@
++package main
++
++func main() {
++	println("hi")
+diff --git a/other.txt b/other.txt
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/other.txt
+@@ -0,0 +1 @@
++unrelated
+`)
+
+	t.Run("similar files are renamed", func(t *testing.T) {
+		got := DetectRenames(files, 50)
+		if len(got) != 2 {
+			t.Fatalf("expected 2 files, got %d", len(got))
+		}
+
+		r := got[0]
+		if !r.IsRename || r.OldName != "old.go" || r.NewName != "new.go" {
+			t.Fatalf("expected old.go to be renamed to new.go, got %+v", r)
+		}
+		if r.Score != 75 {
+			t.Errorf("expected a similarity of 75, got %d", r.Score)
+		}
+
+		var added, deleted int64
+		for _, tf := range r.TextFragments {
+			added += tf.LinesAdded
+			deleted += tf.LinesDeleted
+		}
+		if added != 1 || deleted != 1 {
+			t.Errorf("expected only the edited line, got +%d -%d", added, deleted)
+		}
+
+		if got[1].NewName != "other.txt" || !got[1].IsNew {
+			t.Errorf("expected other.txt to stay added")
+		}
+	})
+
+	t.Run("files below the threshold are left alone", func(t *testing.T) {
+		if got := DetectRenames(files, 80); len(got) != 3 {
+			t.Errorf("expected 3 files, got %d", len(got))
+		}
+	})
+
+	t.Run("a threshold of 0 turns detection off", func(t *testing.T) {
+		if got := DetectRenames(files, 0); len(got) != 3 {
+			t.Errorf("expected 3 files, got %d", len(got))
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	patch := `From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001
+From: Alice <alice@example.com>
+Date: Mon, 1 Jan 2024 00:00:00 +0000
+Subject: [PATCH 1/2] one
+
+---
+diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/logo.png differ
+-- 
+2.40.0
+
+From 2222222222222222222222222222222222222222 Mon Sep 17 00:00:00 2001
+From: Alice <alice@example.com>
+Date: Mon, 1 Jan 2024 00:00:00 +0000
+Subject: [PATCH 2/2] two
+
+---
+diff --git a/a.txt b/a.txt
+index 2222222..4444444 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,3 @@
+ uno
+ two
++three
+-- 
+2.40.0
+`
+
+	stat, err := Stats(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.FilesChanged != 2 || stat.BinaryFiles != 1 {
+		t.Errorf("expected 2 files with 1 binary, got %d with %d", stat.FilesChanged, stat.BinaryFiles)
+	}
+	if stat.Insertions != 2 || stat.Deletions != 1 {
+		t.Errorf("expected +2 -1, got +%d -%d", stat.Insertions, stat.Deletions)
+	}
+	if stat.Files[0].Name != "a.txt" || stat.Files[0].Insertions != 2 {
+		t.Errorf("expected a.txt to sum both commits, got %+v", stat.Files[0])
+	}
+}
+
+func TestParseSuggestions(t *testing.T) {
+	body := "looks good, but:\n\n" +
+		"```suggestion main.go:3-4\nfmt.Println(\"hi\")\n```\n\n" +
+		"~~~~suggestion dir/a b.go:7\n~~~\n~~~~\n\n" +
+		"```suggestion\nno location\n```\n\n" +
+		"```go\nfmt.Println(\"not a suggestion\")\n```\n"
+
+	got := ParseSuggestions(body)
+	want := []Suggestion{
+		{Path: "main.go", Start: 3, End: 4, Lines: []string{`fmt.Println("hi")`}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got = ParseSuggestions("````suggestion a.go:2\n```\n````\n```suggestion a.go:5-5\n```")
+	want = []Suggestion{
+		{Path: "a.go", Start: 2, End: 2, Lines: []string{"```"}},
+		{Path: "a.go", Start: 5, End: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestApplySuggestions(t *testing.T) {
+	patch := `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,4 @@
+ one
+-two
++dos
++deux
+ three
+@@ -10,2 +11,2 @@
+ ten
+-eleven
++once
+`
+
+	t.Run("context and added lines are replaced", func(t *testing.T) {
+		got, err := ApplySuggestions(patch, []Suggestion{
+			{Path: "a.txt", Start: 3, End: 4, Lines: []string{"zwei", "drei"}},
+			{Path: "a.txt", Start: 12, End: 12, Lines: []string{"onze", "douze"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,4 @@
+ one
+-two
++dos
+-three
++zwei
++drei
+@@ -10,2 +11,3 @@
+ ten
+-eleven
++onze
++douze
+`
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("an empty suggestion deletes the lines", func(t *testing.T) {
+		got, err := ApplySuggestions(patch, []Suggestion{{Path: "a.txt", Start: 3, End: 3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := parsePatch(t, got)
+		if tf := files[0].TextFragments[0]; tf.LinesAdded != 1 || tf.NewLines != 3 {
+			t.Errorf("expected one line added, got %s", tf)
+		}
+		if tf := files[0].TextFragments[1]; tf.NewPosition != 10 {
+			t.Errorf("expected the next hunk to move up, got %s", tf.Header())
+		}
+	})
+
+	errs := []struct {
+		name        string
+		patch       string
+		suggestions []Suggestion
+		want        error
+	}{
+		{"file not in patch", patch, []Suggestion{{Path: "b.txt", Start: 1, End: 1}}, ErrSuggestionNotInPatch},
+		{"lines outside of hunks", patch, []Suggestion{{Path: "a.txt", Start: 6, End: 6}}, ErrSuggestionNotInPatch},
+		{"lines across hunks", patch, []Suggestion{{Path: "a.txt", Start: 4, End: 11}}, ErrSuggestionNotInPatch},
+		{"overlapping", patch, []Suggestion{{Path: "a.txt", Start: 1, End: 2}, {Path: "a.txt", Start: 2, End: 3}}, ErrSuggestionsOverlap},
+		{"format-patch", "From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001\n" + patch, []Suggestion{{Path: "a.txt", Start: 1, End: 1}}, ErrSuggestionFormatPatch},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ApplySuggestions(tt.patch, tt.suggestions); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package patchutil
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// RenameThreshold is the similarity, in percent, above which a deleted file
+// and an added file are shown as a rename. 0 turns rename detection off.
+var RenameThreshold = 50
+
+// DetectRenames pairs up files that were deleted with files that were added
+// and are at least threshold percent similar, and replaces each pair with a
+// rename that only carries the changes between the two. Files already
+// marked as renames or copies are left alone.
+//
+// The returned slice keeps the order of files, with a rename taking the
+// place of the added file.
+func DetectRenames(files []*gitdiff.File, threshold int) []*gitdiff.File {
+	if threshold <= 0 {
+		return files
+	}
+
+	var deleted, added []int
+	for i, f := range files {
+		switch {
+		case f.IsBinary:
+		case f.IsDelete:
+			deleted = append(deleted, i)
+		case f.IsNew:
+			added = append(added, i)
+		}
+	}
+	if len(deleted) == 0 || len(added) == 0 {
+		return files
+	}
+
+	contents := make(map[int][]string, len(deleted)+len(added))
+	for _, i := range append(deleted, added...) {
+		contents[i] = fileLines(files[i])
+	}
+
+	// empty files are all alike, pairing them up says nothing
+	empty := func(i int) bool { return len(contents[i]) == 0 }
+	deleted = slices.DeleteFunc(deleted, empty)
+	added = slices.DeleteFunc(added, empty)
+
+	renamed := make(map[int]*gitdiff.File)
+	dropped := make(map[int]bool)
+	for _, a := range added {
+		best, bestScore := -1, 0
+		for _, d := range deleted {
+			if dropped[d] {
+				continue
+			}
+			score := similarity(contents[d], contents[a])
+			if score > bestScore || (score == bestScore && best >= 0 && sameBase(files[d], files[a])) {
+				best, bestScore = d, score
+			}
+		}
+		if best < 0 || bestScore < threshold {
+			continue
+		}
+
+		r, err := asRename(files[best], files[a], contents[best], contents[a], bestScore)
+		if err != nil {
+			log.Println("detecting renames:", err)
+			continue
+		}
+		renamed[a] = r
+		dropped[best] = true
+	}
+
+	if len(renamed) == 0 {
+		return files
+	}
+
+	result := make([]*gitdiff.File, 0, len(files)-len(dropped))
+	for i, f := range files {
+		if dropped[i] {
+			continue
+		}
+		if r, ok := renamed[i]; ok {
+			f = r
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func asRename(from, to *gitdiff.File, oldLines, newLines []string, score int) (*gitdiff.File, error) {
+	r := &gitdiff.File{
+		OldName:      from.OldName,
+		NewName:      to.NewName,
+		OldMode:      from.OldMode,
+		NewMode:      to.NewMode,
+		OldOIDPrefix: from.OldOIDPrefix,
+		NewOIDPrefix: to.NewOIDPrefix,
+		IsRename:     true,
+		Score:        score,
+	}
+
+	oldText, newText := strings.Join(oldLines, ""), strings.Join(newLines, "")
+	if oldText == newText {
+		return r, nil
+	}
+
+	unified, err := Unified(oldText, "a/"+r.OldName, newText, "b/"+r.NewName)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, _, err := gitdiff.Parse(strings.NewReader(unified))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parsed {
+		r.TextFragments = append(r.TextFragments, p.TextFragments...)
+	}
+
+	return r, nil
+}
+
+// fileLines returns the contents of a file that was added or deleted
+// outright, as the only side of its diff.
+func fileLines(f *gitdiff.File) []string {
+	var lines []string
+	for _, tf := range f.TextFragments {
+		for _, l := range tf.Lines {
+			lines = append(lines, l.Line)
+		}
+	}
+	return lines
+}
+
+// similarity is the share of lines the two files have in common, relative
+// to the longer of the two.
+func similarity(a, b []string) int {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, l := range a {
+		counts[l]++
+	}
+
+	common := 0
+	for _, l := range b {
+		if counts[l] > 0 {
+			counts[l]--
+			common++
+		}
+	}
+
+	return common * 100 / longest
+}
+
+func sameBase(a, b *gitdiff.File) bool {
+	base := func(name string) string {
+		return name[strings.LastIndex(name, "/")+1:]
+	}
+	return base(a.OldName) == base(b.NewName)
+}
@@ -0,0 +1,67 @@
+package patchutil
+
+import (
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"tangled.org/core/types"
+)
+
+// Stats counts the lines and files changed by a patch. The commits of a
+// format-patch are summed, so a file changed by two commits is counted once
+// with the lines of both.
+func Stats(patch string) (types.PatchStat, error) {
+	var stat types.PatchStat
+
+	var series [][]*gitdiff.File
+	if IsFormatPatch(patch) {
+		patches, err := ExtractPatches(patch)
+		if err != nil {
+			return stat, err
+		}
+		for _, p := range patches {
+			series = append(series, p.Files)
+		}
+	} else {
+		files, _, err := gitdiff.Parse(strings.NewReader(patch))
+		if err != nil {
+			return stat, err
+		}
+		series = append(series, files)
+	}
+
+	index := make(map[string]int)
+	for _, files := range series {
+		for _, f := range files {
+			name := f.NewName
+			if f.IsDelete {
+				name = f.OldName
+			}
+
+			i, ok := index[name]
+			if !ok {
+				i = len(stat.Files)
+				index[name] = i
+				stat.Files = append(stat.Files, types.FileStat{Name: name})
+			}
+
+			fs := &stat.Files[i]
+			fs.IsBinary = fs.IsBinary || f.IsBinary
+			for _, tf := range f.TextFragments {
+				fs.Insertions += tf.LinesAdded
+				fs.Deletions += tf.LinesDeleted
+			}
+		}
+	}
+
+	for _, fs := range stat.Files {
+		stat.Insertions += fs.Insertions
+		stat.Deletions += fs.Deletions
+		if fs.IsBinary {
+			stat.BinaryFiles++
+		}
+	}
+	stat.FilesChanged = len(stat.Files)
+
+	return stat, nil
+}
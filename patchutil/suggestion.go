@@ -0,0 +1,254 @@
+package patchutil
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// Suggestion is a change proposed in a review comment, as a fenced block:
+//
+//	```suggestion path/to/file.go:12-14
+//	replacement lines
+//	```
+//
+// Start and End are the first and last line it replaces, inclusive and on
+// the new side of the patch. An empty block suggests deleting the lines.
+type Suggestion struct {
+	Path  string
+	Start int64
+	End   int64
+	Lines []string
+}
+
+var (
+	ErrSuggestionFormatPatch = errors.New("suggestions can only be applied to plain diffs")
+	ErrSuggestionNotInPatch  = errors.New("suggestion does not apply to the patch")
+	ErrSuggestionsOverlap    = errors.New("suggestions overlap")
+)
+
+// ParseSuggestions finds the suggestions in a comment body. Blocks without a
+// valid location are left out.
+func ParseSuggestions(body string) []Suggestion {
+	var suggestions []Suggestion
+
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		var content []string
+		for i++; i < len(lines); i++ {
+			if isClosingFence(lines[i], fence) {
+				break
+			}
+			content = append(content, lines[i])
+		}
+
+		if s, ok := parseSuggestionInfo(info); ok {
+			s.Lines = content
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return suggestions
+}
+
+func openingFence(line string) (fence, info string, ok bool) {
+	line = strings.TrimLeft(line, " ")
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n], strings.TrimSpace(line[n:]), true
+		}
+	}
+	return "", "", false
+}
+
+func isClosingFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// parseSuggestionInfo parses the info string of a suggestion block, as in
+// "suggestion path:start-end" or "suggestion path:line".
+func parseSuggestionInfo(info string) (Suggestion, bool) {
+	fields := strings.Fields(info)
+	if len(fields) != 2 || fields[0] != "suggestion" {
+		return Suggestion{}, false
+	}
+
+	i := strings.LastIndex(fields[1], ":")
+	if i <= 0 {
+		return Suggestion{}, false
+	}
+	path, lines := fields[1][:i], fields[1][i+1:]
+
+	first, last, isRange := strings.Cut(lines, "-")
+	if !isRange {
+		last = first
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return Suggestion{}, false
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || start < 1 || end < start {
+		return Suggestion{}, false
+	}
+
+	return Suggestion{Path: path, Start: start, End: end}, true
+}
+
+// ApplySuggestions splices suggestions into a patch, as if its author had
+// made the changes themselves. Every suggestion must replace lines the
+// patch shows, within a single hunk, and suggestions on the same file must
+// not overlap.
+func ApplySuggestions(patch string, suggestions []Suggestion) (string, error) {
+	if IsFormatPatch(patch) {
+		return "", ErrSuggestionFormatPatch
+	}
+
+	files, _, err := gitdiff.Parse(strings.NewReader(patch))
+	if err != nil {
+		return "", err
+	}
+
+	byPath := make(map[string][]Suggestion)
+	for _, s := range suggestions {
+		byPath[s.Path] = append(byPath[s.Path], s)
+	}
+
+	for path, ss := range byPath {
+		idx := slices.IndexFunc(files, func(f *gitdiff.File) bool {
+			return !f.IsDelete && !f.IsBinary && f.NewName == path
+		})
+		if idx < 0 {
+			return "", fmt.Errorf("%w: %s is not changed by the patch", ErrSuggestionNotInPatch, path)
+		}
+
+		// later lines first, so that the line numbers of the earlier ones
+		// still hold
+		slices.SortFunc(ss, func(a, b Suggestion) int { return int(b.Start - a.Start) })
+		for i, s := range ss {
+			if i > 0 && s.End >= ss[i-1].Start {
+				return "", fmt.Errorf("%w: %s:%d-%d", ErrSuggestionsOverlap, path, s.Start, s.End)
+			}
+			if err := applySuggestion(files[idx], s); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f.String())
+	}
+	return b.String(), nil
+}
+
+func applySuggestion(file *gitdiff.File, s Suggestion) error {
+	frag := -1
+	for i, tf := range file.TextFragments {
+		if s.Start >= tf.NewPosition && s.End < tf.NewPosition+tf.NewLines {
+			frag = i
+			break
+		}
+	}
+	if frag < 0 {
+		return fmt.Errorf("%w: %s:%d-%d is outside of the changed lines", ErrSuggestionNotInPatch, s.Path, s.Start, s.End)
+	}
+
+	tf := file.TextFragments[frag]
+	oldNewLines := tf.NewLines
+
+	var lines []gitdiff.Line
+	newLine := tf.NewPosition
+	inserted, noEOL := false, false
+	for _, l := range tf.Lines {
+		if l.New() && newLine > s.End && !inserted {
+			lines = append(lines, suggestedLines(s, noEOL)...)
+			inserted = true
+		}
+
+		replaced := l.New() && newLine >= s.Start && newLine <= s.End
+		if replaced {
+			noEOL = l.NoEOL()
+		}
+		if l.New() {
+			newLine++
+		}
+
+		switch {
+		case !replaced:
+			lines = append(lines, l)
+		case l.Op == gitdiff.OpContext:
+			// the line is kept by the patch, drop it instead
+			lines = append(lines, gitdiff.Line{Op: gitdiff.OpDelete, Line: l.Line})
+		}
+	}
+	if !inserted {
+		lines = append(lines, suggestedLines(s, noEOL)...)
+	}
+
+	tf.Lines = lines
+	recount(tf)
+	if err := tf.Validate(); err != nil {
+		return fmt.Errorf("%w: %s:%d-%d: %w", ErrSuggestionNotInPatch, s.Path, s.Start, s.End, err)
+	}
+
+	delta := tf.NewLines - oldNewLines
+	for _, later := range file.TextFragments[frag+1:] {
+		later.NewPosition += delta
+	}
+
+	return nil
+}
+
+// suggestedLines are the lines of a suggestion as additions. The last one
+// goes without a newline if the lines it replaces ended the file without
+// one.
+func suggestedLines(s Suggestion, noEOL bool) []gitdiff.Line {
+	lines := make([]gitdiff.Line, 0, len(s.Lines))
+	for i, line := range s.Lines {
+		if i < len(s.Lines)-1 || !noEOL {
+			line += "\n"
+		}
+		lines = append(lines, gitdiff.Line{Op: gitdiff.OpAdd, Line: line})
+	}
+	return lines
+}
+
+// recount updates the counts of a fragment after its lines have changed.
+func recount(tf *gitdiff.TextFragment) {
+	tf.OldLines, tf.NewLines = 0, 0
+	tf.LinesAdded, tf.LinesDeleted = 0, 0
+	tf.LeadingContext, tf.TrailingContext = 0, 0
+
+	for _, l := range tf.Lines {
+		switch l.Op {
+		case gitdiff.OpContext:
+			tf.OldLines++
+			tf.NewLines++
+			if tf.LinesAdded == 0 && tf.LinesDeleted == 0 {
+				tf.LeadingContext++
+			} else {
+				tf.TrailingContext++
+			}
+		case gitdiff.OpAdd:
+			tf.NewLines++
+			tf.LinesAdded++
+			tf.TrailingContext = 0
+		case gitdiff.OpDelete:
+			tf.OldLines++
+			tf.LinesDeleted++
+			tf.TrailingContext = 0
+		}
+	}
+}
@@ -2,6 +2,7 @@ package rbac
 
 import (
 	"database/sql"
+	"fmt"
 	"slices"
 	"strings"
 
@@ -153,6 +154,8 @@ func repoPolicies(member, domain, repo string) [][]string {
 	return [][]string{
 		{member, domain, repo, "repo:settings"},
 		{member, domain, repo, "repo:push"},
+		{member, domain, repo, "repo:merge"},
+		{member, domain, repo, "repo:triage"},
 		{member, domain, repo, "repo:owner"},
 		{member, domain, repo, "repo:invite"},
 		{member, domain, repo, "repo:delete"},
@@ -174,40 +177,168 @@ func (e *Enforcer) RemoveRepo(member, domain, repo string) error {
 		return err
 	}
 
-	_, err = e.E.RemovePolicies(repoPolicies(member, domain, repo))
-	return err
+	// repos created before some permissions existed don't have them, remove
+	// what is there
+	for _, p := range repoPolicies(member, domain, repo) {
+		if _, err := e.E.RemovePolicy(p); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-var (
-	collaboratorPolicies = func(collaborator, domain, repo string) [][]string {
-		return [][]string{
-			{collaborator, domain, repo, "repo:collaborator"},
-			{collaborator, domain, repo, "repo:settings"},
-			{collaborator, domain, repo, "repo:push"},
-		}
+// TransferRepo makes newOwner the owner of oldRepo, which now lives at
+// newRepo. Everybody else loses their access to it, including its previous
+// owner and collaborators.
+func (e *Enforcer) TransferRepo(newOwner, domain, oldRepo, newRepo string) error {
+	if err := checkRepoFormat(oldRepo); err != nil {
+		return err
+	}
+	if err := checkRepoFormat(newRepo); err != nil {
+		return err
 	}
+
+	if _, err := e.E.RemoveFilteredPolicy(1, domain, oldRepo); err != nil {
+		return err
+	}
+
+	_, err := e.E.AddPolicies(repoPolicies(newOwner, domain, newRepo))
+	return err
+}
+
+// roles that the owner of a repo can give to others
+const (
+	RoleCollaborator = "collaborator"
+	RoleMaintainer   = "maintainer"
+	RoleTriager      = "triager"
 )
 
+// RepoRoles maps each role to the permissions it grants. Holders of a role
+// are also given "repo:<role>", which records the role itself.
+//
+//   - repo:push lets users push and manage branches and pipelines
+//   - repo:merge lets users merge pulls
+//   - repo:triage lets users label, close and reopen issues and pulls
+//   - repo:settings lets users change the settings of a repo
+var RepoRoles = map[string][]string{
+	RoleCollaborator: {"repo:settings", "repo:push", "repo:merge", "repo:triage"},
+	RoleMaintainer:   {"repo:push", "repo:merge", "repo:triage"},
+	RoleTriager:      {"repo:triage"},
+}
+
+// IsRepoRole reports whether role is one of RepoRoles.
+func IsRepoRole(role string) bool {
+	_, ok := RepoRoles[role]
+	return ok
+}
+
+// RecordRole returns the role a collaborator record gives, records without
+// one predate roles and give the collaborator role.
+func RecordRole(role *string) (string, error) {
+	if role == nil || *role == "" {
+		return RoleCollaborator, nil
+	}
+	if !IsRepoRole(*role) {
+		return "", fmt.Errorf("unknown role: %s", *role)
+	}
+	return *role, nil
+}
+
+func rolePolicies(user, domain, repo, role string) [][]string {
+	policies := [][]string{{user, domain, repo, "repo:" + role}}
+	for _, perm := range RepoRoles[role] {
+		policies = append(policies, []string{user, domain, repo, perm})
+	}
+	return policies
+}
+
+// AddCollaborator gives the collaborator role, which is what collaborators
+// had before other roles existed.
 func (e *Enforcer) AddCollaborator(collaborator, domain, repo string) error {
+	return e.SetRepoRole(collaborator, domain, repo, RoleCollaborator)
+}
+
+// SetRepoRole gives user role in repo, replacing any role they had.
+func (e *Enforcer) SetRepoRole(user, domain, repo, role string) error {
 	err := checkRepoFormat(repo)
 	if err != nil {
 		return err
 	}
 
-	_, err = e.E.AddPolicies(collaboratorPolicies(collaborator, domain, repo))
+	if !IsRepoRole(role) {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+
+	if ok, err := e.E.Enforce(user, domain, repo, "repo:owner"); err != nil {
+		return err
+	} else if ok {
+		return fmt.Errorf("%s owns %s", user, repo)
+	}
+
+	if err := e.RemoveCollaborator(user, domain, repo); err != nil {
+		return err
+	}
+
+	_, err = e.E.AddPolicies(rolePolicies(user, domain, repo, role))
 	return err
 }
 
+// RemoveCollaborator takes away whatever role user has in repo.
 func (e *Enforcer) RemoveCollaborator(collaborator, domain, repo string) error {
 	err := checkRepoFormat(repo)
 	if err != nil {
 		return err
 	}
 
-	_, err = e.E.RemovePolicies(collaboratorPolicies(collaborator, domain, repo))
+	if ok, err := e.E.Enforce(collaborator, domain, repo, "repo:owner"); err != nil || ok {
+		return err
+	}
+
+	_, err = e.E.RemoveFilteredPolicy(0, collaborator, domain, repo)
 	return err
 }
 
+// GetRepoRole returns the role user has in repo, or "" if they have none.
+// The owner of a repo has no role.
+func (e *Enforcer) GetRepoRole(user, domain, repo string) string {
+	perms := e.GetPermissionsInRepo(user, domain, repo)
+	for role := range RepoRoles {
+		if slices.Contains(perms, "repo:"+role) {
+			return role
+		}
+	}
+	return ""
+}
+
+// MigrateRepoRoles grants the permissions that were added after repos and
+// collaborators were created: owners and collaborators can now merge and
+// triage. It is safe to run repeatedly.
+func (e *Enforcer) MigrateRepoRoles() error {
+	owners, err := e.E.GetFilteredPolicy(3, "repo:owner")
+	if err != nil {
+		return err
+	}
+	collaborators, err := e.E.GetFilteredPolicy(3, "repo:"+RoleCollaborator)
+	if err != nil {
+		return err
+	}
+
+	var missing [][]string
+	for _, p := range owners {
+		missing = append(missing, repoPolicies(p[0], p[1], p[2])...)
+	}
+	for _, p := range collaborators {
+		missing = append(missing, rolePolicies(p[0], p[1], p[2], RoleCollaborator)...)
+	}
+
+	for _, p := range missing {
+		if _, err := e.E.AddPolicy(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *Enforcer) GetUserByRole(role, domain string) ([]string, error) {
 	var membersWithoutRoles []string
 
@@ -297,6 +428,20 @@ func (e *Enforcer) IsCollaboratorInviteAllowed(user, domain, repo string) (bool,
 	return e.E.Enforce(user, domain, repo, "repo:invite")
 }
 
+func (e *Enforcer) IsMergeAllowed(user, domain, repo string) (bool, error) {
+	return e.E.Enforce(user, domain, repo, "repo:merge")
+}
+
+func (e *Enforcer) IsTriageAllowed(user, domain, repo string) (bool, error) {
+	return e.E.Enforce(user, domain, repo, "repo:triage")
+}
+
+// IsRepoReadAllowed reports whether user can see a private repo, which is
+// the case for its owner and anyone with a role in it.
+func (e *Enforcer) IsRepoReadAllowed(user, domain, repo string) (bool, error) {
+	return len(e.GetPermissionsInRepo(user, domain, repo)) > 0, nil
+}
+
 // given a repo, what permissions does this user have? repo:owner? repo:invite? etc.
 func (e *Enforcer) GetPermissionsInRepo(user, domain, repo string) []string {
 	var permissions []string
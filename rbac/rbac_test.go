@@ -140,7 +140,7 @@ func TestCollaboratorPermissions(t *testing.T) {
 	// all collaborator permissions granted
 	perms := e.GetPermissionsInRepo(collaborator, knot, repo)
 	assert.ElementsMatch(t, []string{
-		"repo:settings", "repo:push", "repo:collaborator",
+		"repo:settings", "repo:push", "repo:merge", "repo:triage", "repo:collaborator",
 	}, perms)
 
 	err = e.RemoveCollaborator(collaborator, knot, repo)
@@ -151,6 +151,78 @@ func TestCollaboratorPermissions(t *testing.T) {
 	assert.ElementsMatch(t, []string{}, perms)
 }
 
+func TestRepoRoles(t *testing.T) {
+	e := setup(t)
+
+	knot := "example.com"
+	repo := "did:plc:foo/my-repo"
+	owner := "did:plc:foo"
+	user := "did:plc:bar"
+
+	_ = e.AddKnot(knot)
+	_ = e.AddRepo(owner, knot, repo)
+
+	err := e.SetRepoRole(user, knot, repo, rbac.RoleTriager)
+	assert.NoError(t, err)
+	assert.Equal(t, rbac.RoleTriager, e.GetRepoRole(user, knot, repo))
+
+	canTriage, _ := e.IsTriageAllowed(user, knot, repo)
+	canPush, _ := e.IsPushAllowed(user, knot, repo)
+	assert.True(t, canTriage)
+	assert.False(t, canPush)
+
+	// roles replace each other
+	err = e.SetRepoRole(user, knot, repo, rbac.RoleMaintainer)
+	assert.NoError(t, err)
+	assert.Equal(t, rbac.RoleMaintainer, e.GetRepoRole(user, knot, repo))
+	assert.ElementsMatch(t, []string{
+		"repo:push", "repo:merge", "repo:triage", "repo:maintainer",
+	}, e.GetPermissionsInRepo(user, knot, repo))
+
+	err = e.SetRepoRole(user, knot, repo, "admin")
+	assert.Error(t, err)
+
+	// owners keep their permissions
+	err = e.SetRepoRole(owner, knot, repo, rbac.RoleTriager)
+	assert.Error(t, err)
+	_ = e.RemoveCollaborator(owner, knot, repo)
+	canPush, _ = e.IsPushAllowed(owner, knot, repo)
+	assert.True(t, canPush)
+}
+
+func TestMigrateRepoRoles(t *testing.T) {
+	e := setup(t)
+
+	knot := "example.com"
+	repo := "did:plc:foo/my-repo"
+	owner := "did:plc:foo"
+	collaborator := "did:plc:bar"
+
+	// policies as they were before roles
+	_, err := e.E.AddPolicies([][]string{
+		{owner, knot, repo, "repo:settings"},
+		{owner, knot, repo, "repo:push"},
+		{owner, knot, repo, "repo:owner"},
+		{owner, knot, repo, "repo:invite"},
+		{owner, knot, repo, "repo:delete"},
+		{collaborator, knot, repo, "repo:collaborator"},
+		{collaborator, knot, repo, "repo:settings"},
+		{collaborator, knot, repo, "repo:push"},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.MigrateRepoRoles())
+	assert.NoError(t, e.MigrateRepoRoles())
+
+	for _, user := range []string{owner, collaborator} {
+		canMerge, _ := e.IsMergeAllowed(user, knot, repo)
+		canTriage, _ := e.IsTriageAllowed(user, knot, repo)
+		assert.True(t, canMerge)
+		assert.True(t, canTriage)
+	}
+	assert.Equal(t, rbac.RoleCollaborator, e.GetRepoRole(collaborator, knot, repo))
+}
+
 func TestGetByRole(t *testing.T) {
 	e := setup(t)
 
@@ -190,7 +262,7 @@ func TestGetPermissionsInRepo(t *testing.T) {
 
 	perms := e.GetPermissionsInRepo(user, knot, repo)
 	assert.ElementsMatch(t, []string{
-		"repo:settings", "repo:push", "repo:owner", "repo:invite", "repo:delete",
+		"repo:settings", "repo:push", "repo:merge", "repo:triage", "repo:owner", "repo:invite", "repo:delete",
 	}, perms)
 }
 
@@ -267,6 +339,46 @@ func TestRemoveRepo(t *testing.T) {
 	assert.False(t, allowed)
 }
 
+func TestRepoReadAllowed(t *testing.T) {
+	e := setup(t)
+	knot := "example.com"
+	repo := "did:plc:foo/repo"
+	_ = e.AddKnot(knot)
+	_ = e.AddRepo("did:plc:foo", knot, repo)
+	_ = e.SetRepoRole("did:plc:bar", knot, repo, rbac.RoleTriager)
+
+	for user, want := range map[string]bool{
+		"did:plc:foo": true,
+		"did:plc:bar": true,
+		"did:plc:baz": false,
+	} {
+		allowed, err := e.IsRepoReadAllowed(user, knot, repo)
+		assert.NoError(t, err)
+		assert.Equal(t, want, allowed, user)
+	}
+}
+
+func TestTransferRepo(t *testing.T) {
+	e := setup(t)
+	knot := "example.com"
+	oldRepo := "did:plc:foo/repo"
+	newRepo := "did:plc:baz/repo"
+	_ = e.AddKnot(knot)
+	_ = e.AddRepo("did:plc:foo", knot, oldRepo)
+	_ = e.AddCollaborator("did:plc:bar", knot, oldRepo)
+
+	err := e.TransferRepo("did:plc:baz", knot, oldRepo, newRepo)
+	assert.NoError(t, err)
+
+	for _, user := range []string{"did:plc:foo", "did:plc:bar"} {
+		assert.Empty(t, e.GetPermissionsInRepo(user, knot, oldRepo))
+		assert.Empty(t, e.GetPermissionsInRepo(user, knot, newRepo))
+	}
+
+	allowed, _ := e.IsRepoDeleteAllowed("did:plc:baz", knot, newRepo)
+	assert.True(t, allowed)
+}
+
 func TestAddKnotAndSpindle(t *testing.T) {
 	e := setup(t)
 
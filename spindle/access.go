@@ -0,0 +1,63 @@
+package spindle
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/rbac"
+	"tangled.org/core/spindle/models"
+	"tangled.org/core/xrpc/serviceauth"
+)
+
+// canReadPipeline reports whether the caller of r can see the statuses, logs
+// and artifacts of a pipeline. Pipelines of private repos need a service auth
+// token from someone with a role in the repo.
+func (s *Spindle) canReadPipeline(r *http.Request, id models.PipelineId) (bool, error) {
+	owner, name, err := s.db.GetPipelineRepo(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		// nothing is known about pipelines that were never received
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	private, err := s.db.IsRepoPrivate(owner, name)
+	if err != nil {
+		return false, err
+	}
+	if !private {
+		return true, nil
+	}
+
+	actorDid, ok := r.Context().Value(serviceauth.ActorDid).(syntax.DID)
+	if !ok || !serviceauth.HasScope(r.Context(), serviceauth.ScopeApi) {
+		return false, nil
+	}
+
+	didSlashRepo, err := securejoin.SecureJoin(owner, name)
+	if err != nil {
+		return false, err
+	}
+
+	return s.e.IsRepoReadAllowed(actorDid.String(), rbac.ThisServer, didSlashRepo)
+}
+
+// eventPipeline returns the pipeline a status or artifact event is about.
+func eventPipeline(eventJson map[string]any) (models.PipelineId, bool) {
+	uri, _ := eventJson["pipeline"].(string)
+	pipelineAt, err := syntax.ParseATURI(uri)
+	if err != nil || pipelineAt.Collection() != tangled.PipelineNSID {
+		return models.PipelineId{}, false
+	}
+
+	return models.PipelineId{
+		Knot: strings.TrimPrefix(pipelineAt.Authority().String(), "did:web:"),
+		Rkey: pipelineAt.RecordKey().String(),
+	}, true
+}
@@ -0,0 +1,79 @@
+package spindle
+
+import (
+	"database/sql"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/go-chi/chi/v5"
+)
+
+// Artifact serves a file kept from a successful workflow. Like logs, those
+// of private repos are only served to the people with a role in them.
+func (s *Spindle) Artifact(w http.ResponseWriter, r *http.Request) {
+	wid, err := getWorkflowID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l := s.l.With("handler", "Artifact", "wid", wid)
+
+	ok, err := s.canReadPipeline(r, wid.PipelineId)
+	if err != nil {
+		l.Error("failed to check pipeline access", "err", err)
+		http.Error(w, "failed to check access", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	name, err := url.PathUnescape(chi.URLParam(r, "*"))
+	if err != nil || name == "" {
+		http.Error(w, "invalid artifact name", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetArtifact(wid, name); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			l.Error("failed to get artifact", "name", name, "err", err)
+		}
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	dir, err := securejoin.SecureJoin(s.cfg.Server.ArtifactDir, wid.String())
+	if err != nil {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	filePath, err := securejoin.SecureJoin(dir, name)
+	if err != nil {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		l.Error("failed to open artifact", "name", name, "err", err)
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(name)}))
+	http.ServeContent(w, r, path.Base(name), stat.ModTime(), f)
+}
@@ -18,8 +18,11 @@ type Server struct {
 	Owner             string  `env:"OWNER, required"`
 	Secrets           Secrets `env:",prefix=SECRETS_"`
 	LogDir            string  `env:"LOG_DIR, default=/var/log/spindle"`
+	ArtifactDir       string  `env:"ARTIFACT_DIR, default=/var/lib/spindle/artifacts"`
+	ArtifactQuota     int64   `env:"ARTIFACT_QUOTA, default=1073741824"` // max bytes of artifacts kept per repo
 	QueueSize         int     `env:"QUEUE_SIZE, default=100"`
-	MaxJobCount       int     `env:"MAX_JOB_COUNT, default=2"` // max number of jobs that run at a time
+	MaxJobCount       int     `env:"MAX_JOB_COUNT, default=2"`      // max number of jobs that run at a time
+	MaxRepoJobCount   int     `env:"MAX_REPO_JOB_COUNT, default=1"` // default max number of pipelines of one repo that run at a time
 }
 
 func (s Server) Did() syntax.DID {
@@ -28,9 +31,14 @@ func (s Server) Did() syntax.DID {
 
 type Secrets struct {
 	Provider string        `env:"PROVIDER, default=sqlite"`
+	Sqlite   SqliteConfig  `env:",prefix=SQLITE_"`
 	OpenBao  OpenBaoConfig `env:",prefix=OPENBAO_"`
 }
 
+type SqliteConfig struct {
+	KeyFile string `env:"KEY_FILE, default=secrets.key"` // generated on first start if missing
+}
+
 type OpenBaoConfig struct {
 	ProxyAddr string `env:"PROXY_ADDR, default=http://127.0.0.1:8200"`
 	Mount     string `env:"MOUNT, default=spindle"`
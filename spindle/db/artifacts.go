@@ -0,0 +1,66 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"tangled.org/core/notifier"
+	"tangled.org/core/spindle/models"
+	"tangled.org/core/tid"
+)
+
+type Artifact struct {
+	Workflow models.WorkflowId
+	Repo     string
+	Name     string
+	Size     int64
+}
+
+func (d *DB) AddArtifact(a Artifact) error {
+	_, err := d.Exec(
+		`insert or replace into artifacts (knot, rkey, workflow, repo, name, size) values (?, ?, ?, ?, ?, ?)`,
+		a.Workflow.Knot,
+		a.Workflow.Rkey,
+		a.Workflow.Name,
+		a.Repo,
+		a.Name,
+		a.Size,
+	)
+	return err
+}
+
+func (d *DB) GetArtifact(wid models.WorkflowId, name string) (*Artifact, error) {
+	a := Artifact{Workflow: wid, Name: name}
+	err := d.QueryRow(
+		`select repo, size from artifacts where knot = ? and rkey = ? and workflow = ? and name = ?`,
+		wid.Knot,
+		wid.Rkey,
+		wid.Name,
+		name,
+	).Scan(&a.Repo, &a.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ArtifactsSize returns the total size of the artifacts kept for repo.
+func (d *DB) ArtifactsSize(repo string) (int64, error) {
+	var size int64
+	err := d.QueryRow(`select coalesce(sum(size), 0) from artifacts where repo = ?`, repo).Scan(&size)
+	return size, err
+}
+
+func (d *DB) CreateArtifactEvent(a models.ArtifactEvent, n *notifier.Notifier) error {
+	eventJson, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return d.InsertEvent(Event{
+		Rkey:      tid.TID(),
+		Nsid:      models.ArtifactNSID,
+		Created:   time.Now().UnixNano(),
+		EventJson: string(eventJson),
+	}, n)
+}
@@ -49,6 +49,25 @@ func Make(dbPath string) (*DB, error) {
 			unique(owner, name)
 		);
 
+		-- repos whose record makes them private, their pipelines are only
+		-- shown to those with a role in them
+		create table if not exists private_repos (
+			owner text not null,
+			name text not null,
+
+			unique(owner, name)
+		);
+
+		-- pipeline settings from a repo's record
+		create table if not exists repo_pipeline_settings (
+			owner text not null,
+			name text not null,
+			max_concurrent integer not null default 0, -- 0 uses the spindle's default
+			cancel_superseded integer not null default 0,
+
+			unique(owner, name)
+		);
+
 		create table if not exists spindle_members (
 			-- identifiers for the record
 			id integer primary key autoincrement,
@@ -64,6 +83,29 @@ func Make(dbPath string) (*DB, error) {
 			unique (did, instance, subject)
 		);
 
+		-- pipeline records as received from knots, kept around to run them again
+		create table if not exists pipelines (
+			knot text not null,
+			rkey text not null,
+			record text not null, -- json
+			created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+			unique(knot, rkey)
+		);
+
+		-- files kept from a successful workflow
+		create table if not exists artifacts (
+			knot text not null,
+			rkey text not null,
+			workflow text not null,
+			repo text not null, -- did/name, for quotas
+			name text not null,
+			size integer not null,
+			created text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+
+			unique(knot, rkey, workflow, name)
+		);
+
 		-- status event for a single workflow
 		create table if not exists events (
 			rkey text not null,
@@ -158,6 +158,10 @@ func (d *DB) StatusPending(workflowId models.WorkflowId, n *notifier.Notifier) e
 	return d.createStatusEvent(workflowId, models.StatusKindPending, nil, nil, n)
 }
 
+func (d *DB) StatusQueued(workflowId models.WorkflowId, n *notifier.Notifier) error {
+	return d.createStatusEvent(workflowId, models.StatusKindQueued, nil, nil, n)
+}
+
 func (d *DB) StatusRunning(workflowId models.WorkflowId, n *notifier.Notifier) error {
 	return d.createStatusEvent(workflowId, models.StatusKindRunning, nil, nil, n)
 }
@@ -170,6 +174,10 @@ func (d *DB) StatusSuccess(workflowId models.WorkflowId, n *notifier.Notifier) e
 	return d.createStatusEvent(workflowId, models.StatusKindSuccess, nil, nil, n)
 }
 
+func (d *DB) StatusCancelled(workflowId models.WorkflowId, n *notifier.Notifier) error {
+	return d.createStatusEvent(workflowId, models.StatusKindCancelled, nil, nil, n)
+}
+
 func (d *DB) StatusTimeout(workflowId models.WorkflowId, n *notifier.Notifier) error {
 	return d.createStatusEvent(workflowId, models.StatusKindTimeout, nil, nil, n)
 }
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"encoding/json"
+
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/spindle/models"
+)
+
+func (d *DB) AddPipeline(id models.PipelineId, record tangled.Pipeline) error {
+	recordJson, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.Exec(
+		`insert or ignore into pipelines (knot, rkey, record) values (?, ?, ?)`,
+		id.Knot,
+		id.Rkey,
+		string(recordJson),
+	)
+	return err
+}
+
+func (d *DB) GetPipeline(id models.PipelineId) (*tangled.Pipeline, error) {
+	var recordJson string
+	err := d.QueryRow(
+		`select record from pipelines where knot = ? and rkey = ?`,
+		id.Knot,
+		id.Rkey,
+	).Scan(&recordJson)
+	if err != nil {
+		return nil, err
+	}
+
+	var record tangled.Pipeline
+	if err := json.Unmarshal([]byte(recordJson), &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// GetPipelineRepo returns the owner and name of the repo a pipeline was
+// triggered for.
+func (d *DB) GetPipelineRepo(id models.PipelineId) (string, string, error) {
+	var owner, name string
+	err := d.QueryRow(
+		`select
+			json_extract(record, '$.triggerMetadata.repo.did'),
+			json_extract(record, '$.triggerMetadata.repo.repo')
+		from pipelines where knot = ? and rkey = ?`,
+		id.Knot,
+		id.Rkey,
+	).Scan(&owner, &name)
+	return owner, name, err
+}
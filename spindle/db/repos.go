@@ -1,5 +1,10 @@
 package db
 
+import (
+	"database/sql"
+	"errors"
+)
+
 type Repo struct {
 	Knot  string
 	Owner string
@@ -11,6 +16,79 @@ func (d *DB) AddRepo(knot, owner, name string) error {
 	return err
 }
 
+// RemoveRepo stops watching a repo, for instance once its record names
+// another spindle.
+func (d *DB) RemoveRepo(owner, name string) error {
+	_, err := d.Exec(`delete from repos where owner = ? and name = ?`, owner, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.Exec(`delete from repo_pipeline_settings where owner = ? and name = ?`, owner, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.Exec(`delete from private_repos where owner = ? and name = ?`, owner, name)
+	return err
+}
+
+// SetRepoPrivate marks a repo as private or public, as its record says.
+func (d *DB) SetRepoPrivate(owner, name string, private bool) error {
+	if !private {
+		_, err := d.Exec(`delete from private_repos where owner = ? and name = ?`, owner, name)
+		return err
+	}
+
+	_, err := d.Exec(`insert or ignore into private_repos (owner, name) values (?, ?)`, owner, name)
+	return err
+}
+
+func (d *DB) IsRepoPrivate(owner, name string) (bool, error) {
+	var private bool
+	err := d.QueryRow(
+		`select exists(select 1 from private_repos where owner = ? and name = ?)`,
+		owner, name,
+	).Scan(&private)
+	return private, err
+}
+
+// RepoPipelineSettings are the pipeline preferences a repo sets in its record.
+type RepoPipelineSettings struct {
+	// MaxConcurrent is the number of pipelines of the repo that may run at
+	// once, 0 uses the spindle's default
+	MaxConcurrent int
+	// CancelSuperseded drops queued pipelines of a branch once a newer one
+	// is triggered
+	CancelSuperseded bool
+}
+
+func (d *DB) SetRepoPipelineSettings(owner, name string, s RepoPipelineSettings) error {
+	_, err := d.Exec(
+		`insert into repo_pipeline_settings (owner, name, max_concurrent, cancel_superseded)
+		values (?, ?, ?, ?)
+		on conflict(owner, name) do update set
+			max_concurrent = excluded.max_concurrent,
+			cancel_superseded = excluded.cancel_superseded`,
+		owner, name, s.MaxConcurrent, s.CancelSuperseded,
+	)
+	return err
+}
+
+// GetRepoPipelineSettings returns the zero settings for repos that never set
+// any.
+func (d *DB) GetRepoPipelineSettings(owner, name string) (RepoPipelineSettings, error) {
+	var s RepoPipelineSettings
+	err := d.QueryRow(
+		`select max_concurrent, cancel_superseded from repo_pipeline_settings where owner = ? and name = ?`,
+		owner, name,
+	).Scan(&s.MaxConcurrent, &s.CancelSuperseded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RepoPipelineSettings{}, nil
+	}
+	return s, err
+}
+
 func (d *DB) Knots() ([]string, error) {
 	rows, err := d.Query(`select knot from repos`)
 	if err != nil {
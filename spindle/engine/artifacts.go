@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"tangled.org/core/notifier"
+	"tangled.org/core/spindle/config"
+	"tangled.org/core/spindle/db"
+	"tangled.org/core/spindle/models"
+)
+
+// collectArtifacts keeps the artifacts of a successful workflow on disk, as
+// long as repo stays within its quota. Artifacts that don't fit are skipped
+// and noted in the workflow logs.
+func collectArtifacts(
+	ctx context.Context,
+	l *slog.Logger,
+	cfg *config.Config,
+	d *db.DB,
+	n *notifier.Notifier,
+	eng models.Engine,
+	wid models.WorkflowId,
+	w *models.Workflow,
+	repo string,
+	logs io.Writer,
+) error {
+	if len(w.Artifacts) == 0 {
+		return nil
+	}
+
+	collector, ok := eng.(models.ArtifactCollector)
+	if !ok {
+		fmt.Fprintln(logs, "artifacts are not supported by this engine, skipping")
+		return nil
+	}
+
+	used, err := d.ArtifactsSize(repo)
+	if err != nil {
+		return fmt.Errorf("getting artifacts size: %w", err)
+	}
+
+	dir, err := securejoin.SecureJoin(cfg.Server.ArtifactDir, wid.String())
+	if err != nil {
+		return err
+	}
+
+	return collector.CollectArtifacts(ctx, wid, w, func(name string, size int64, r io.Reader) error {
+		if used+size > cfg.Server.ArtifactQuota {
+			l.Warn("artifact quota exceeded", "wid", wid, "name", name, "size", size, "used", used)
+			fmt.Fprintf(logs, "artifact quota exceeded, skipping %s (%d bytes)\n", name, size)
+			return nil
+		}
+
+		path, err := securejoin.SecureJoin(dir, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("creating artifact dir: %w", err)
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("creating artifact: %w", err)
+		}
+		written, err := io.Copy(f, io.LimitReader(r, size))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("writing artifact: %w", err)
+		}
+		used += written
+
+		err = d.AddArtifact(db.Artifact{
+			Workflow: wid,
+			Repo:     repo,
+			Name:     name,
+			Size:     written,
+		})
+		if err != nil {
+			return fmt.Errorf("adding artifact: %w", err)
+		}
+
+		fmt.Fprintf(logs, "kept artifact %s (%d bytes)\n", name, written)
+
+		return d.CreateArtifactEvent(models.ArtifactEvent{
+			Pipeline:  string(wid.PipelineId.AtUri()),
+			Workflow:  wid.Name,
+			Name:      name,
+			Size:      written,
+			CreatedAt: time.Now().Format(time.RFC3339),
+		}, n)
+	})
+}
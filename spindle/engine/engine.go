@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"golang.org/x/sync/errgroup"
@@ -18,6 +23,7 @@ import (
 var (
 	ErrTimedOut       = errors.New("timed out")
 	ErrWorkflowFailed = errors.New("workflow failed")
+	ErrCancelled      = errors.New("cancelled")
 )
 
 func StartWorkflows(l *slog.Logger, vault secrets.Manager, cfg *config.Config, db *db.DB, n *notifier.Notifier, ctx context.Context, pipeline *models.Pipeline, pipelineId models.PipelineId) {
@@ -31,100 +37,240 @@ func StartWorkflows(l *slog.Logger, vault secrets.Manager, cfg *config.Config, d
 		}
 	}
 
+	r := &workflowRunner{
+		l:        l,
+		cfg:      cfg,
+		db:       db,
+		n:        n,
+		pipeline: pipeline,
+		id:       pipelineId,
+		secrets:  allSecrets,
+	}
+
 	eg, ctx := errgroup.WithContext(ctx)
 	for eng, wfs := range pipeline.Workflows {
 		workflowTimeout := eng.WorkflowTimeout()
 		l.Info("using workflow timeout", "timeout", workflowTimeout)
 
+		// the jobs of a matrix run together, under their parent
+		matrices := make(map[string][]models.Workflow)
 		for _, w := range wfs {
+			if w.Matrix != nil {
+				matrices[w.Parent] = append(matrices[w.Parent], w)
+				continue
+			}
+
 			eg.Go(func() error {
-				wid := models.WorkflowId{
-					PipelineId: pipelineId,
-					Name:       w.Name,
-				}
+				return r.run(ctx, eng, workflowTimeout, w)
+			})
+		}
 
-				err := db.StatusRunning(wid, n)
-				if err != nil {
-					return err
-				}
+		for parent, jobs := range matrices {
+			eg.Go(func() error {
+				return r.runMatrix(ctx, eng, workflowTimeout, parent, jobs)
+			})
+		}
+	}
+
+	if err := eg.Wait(); err != nil {
+		l.Error("failed to run one or more workflows", "err", err)
+	} else {
+		l.Info("successfully ran full pipeline")
+	}
+}
+
+type workflowRunner struct {
+	l        *slog.Logger
+	cfg      *config.Config
+	db       *db.DB
+	n        *notifier.Notifier
+	pipeline *models.Pipeline
+	id       models.PipelineId
+	secrets  []secrets.UnlockedSecret
+}
+
+// run runs a single workflow to completion, reporting its status along the
+// way. The error wraps ErrCancelled if the workflow was cancelled.
+func (r *workflowRunner) run(ctx context.Context, eng models.Engine, workflowTimeout time.Duration, w models.Workflow) error {
+	l, cfg, db, n := r.l, r.cfg, r.db, r.n
+
+	wid := models.WorkflowId{
+		PipelineId: r.id,
+		Name:       w.Name,
+	}
+
+	// cancelled while still in the queue
+	if errors.Is(context.Cause(ctx), ErrCancelled) {
+		if err := db.StatusCancelled(wid, n); err != nil {
+			return err
+		}
+		return ErrCancelled
+	}
+
+	err := db.StatusRunning(wid, n)
+	if err != nil {
+		return err
+	}
+
+	err = eng.SetupWorkflow(ctx, wid, &w)
+	if err != nil {
+		// TODO(winter): Should this always set StatusFailed?
+		// In the original, we only do in a subset of cases.
+		l.Error("setting up worklow", "wid", wid, "err", err)
+
+		destroyErr := eng.DestroyWorkflow(context.WithoutCancel(ctx), wid)
+		if destroyErr != nil {
+			l.Error("failed to destroy workflow after setup failure", "error", destroyErr)
+		}
+
+		if errors.Is(context.Cause(ctx), ErrCancelled) {
+			if dbErr := db.StatusCancelled(wid, n); dbErr != nil {
+				return dbErr
+			}
+			return ErrCancelled
+		}
+		if dbErr := db.StatusFailed(wid, err.Error(), -1, n); dbErr != nil {
+			return dbErr
+		}
+		return err
+	}
+	// clean up even when the run was cancelled
+	defer eng.DestroyWorkflow(context.WithoutCancel(ctx), wid)
 
-				err = eng.SetupWorkflow(ctx, wid, &w)
-				if err != nil {
-					// TODO(winter): Should this always set StatusFailed?
-					// In the original, we only do in a subset of cases.
-					l.Error("setting up worklow", "wid", wid, "err", err)
-
-					destroyErr := eng.DestroyWorkflow(ctx, wid)
-					if destroyErr != nil {
-						l.Error("failed to destroy workflow after setup failure", "error", destroyErr)
-					}
-
-					dbErr := db.StatusFailed(wid, err.Error(), -1, n)
-					if dbErr != nil {
-						return dbErr
-					}
-					return err
+	wfLogger, err := models.NewWorkflowLogger(cfg.Server.LogDir, wid)
+	if err != nil {
+		l.Warn("failed to setup step logger; logs will not be persisted", "error", err)
+		wfLogger = nil
+	} else {
+		defer wfLogger.Close()
+
+		values := make([]string, 0, len(r.secrets))
+		for _, s := range r.secrets {
+			values = append(values, s.Value)
+		}
+		wfLogger.Redact(values)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, workflowTimeout)
+	defer cancel()
+
+	for stepIdx, step := range w.Steps {
+		// log start of step
+		if wfLogger != nil {
+			wfLogger.
+				ControlWriter(stepIdx, step, models.StepStatusStart).
+				Write([]byte{0})
+		}
+
+		err = eng.RunStep(ctx, wid, &w, stepIdx, r.secrets, wfLogger)
+
+		// log end of step
+		if wfLogger != nil {
+			wfLogger.
+				ControlWriter(stepIdx, step, models.StepStatusEnd).
+				Write([]byte{0})
+		}
+
+		if err != nil {
+			if errors.Is(context.Cause(ctx), ErrCancelled) {
+				if dbErr := db.StatusCancelled(wid, n); dbErr != nil {
+					return dbErr
 				}
-				defer eng.DestroyWorkflow(ctx, wid)
-
-				wfLogger, err := models.NewWorkflowLogger(cfg.Server.LogDir, wid)
-				if err != nil {
-					l.Warn("failed to setup step logger; logs will not be persisted", "error", err)
-					wfLogger = nil
-				} else {
-					defer wfLogger.Close()
+				return ErrCancelled
+			} else if errors.Is(err, ErrTimedOut) {
+				dbErr := db.StatusTimeout(wid, n)
+				if dbErr != nil {
+					return dbErr
 				}
-
-				ctx, cancel := context.WithTimeout(ctx, workflowTimeout)
-				defer cancel()
-
-				for stepIdx, step := range w.Steps {
-					// log start of step
-					if wfLogger != nil {
-						wfLogger.
-							ControlWriter(stepIdx, step, models.StepStatusStart).
-							Write([]byte{0})
-					}
-
-					err = eng.RunStep(ctx, wid, &w, stepIdx, allSecrets, wfLogger)
-
-					// log end of step
-					if wfLogger != nil {
-						wfLogger.
-							ControlWriter(stepIdx, step, models.StepStatusEnd).
-							Write([]byte{0})
-					}
-
-					if err != nil {
-						if errors.Is(err, ErrTimedOut) {
-							dbErr := db.StatusTimeout(wid, n)
-							if dbErr != nil {
-								return dbErr
-							}
-						} else {
-							dbErr := db.StatusFailed(wid, err.Error(), -1, n)
-							if dbErr != nil {
-								return dbErr
-							}
-						}
-
-						return fmt.Errorf("starting steps image: %w", err)
-					}
+			} else {
+				dbErr := db.StatusFailed(wid, err.Error(), -1, n)
+				if dbErr != nil {
+					return dbErr
 				}
+			}
 
-				err = db.StatusSuccess(wid, n)
-				if err != nil {
-					return err
-				}
+			return fmt.Errorf("starting steps image: %w", err)
+		}
+	}
 
-				return nil
-			})
+	logs := io.Discard
+	if wfLogger != nil && len(w.Steps) > 0 {
+		logs = wfLogger.DataWriter(len(w.Steps)-1, "stdout")
+	}
+	if didSlashRepo, err := securejoin.SecureJoin(r.pipeline.RepoOwner, r.pipeline.RepoName); err == nil {
+		err = collectArtifacts(ctx, l, cfg, db, n, eng, wid, &w, didSlashRepo, logs)
+		if err != nil {
+			// the workflow itself succeeded, so this isn't fatal
+			l.Error("failed to collect artifacts", "wid", wid, "err", err)
 		}
 	}
 
-	if err := eg.Wait(); err != nil {
-		l.Error("failed to run one or more workflows", "err", err)
-	} else {
-		l.Info("successfully ran full pipeline")
+	return db.StatusSuccess(wid, n)
+}
+
+// runMatrix runs the jobs of a matrix in parallel and reports their overall
+// status under the name of the workflow they were expanded from.
+func (r *workflowRunner) runMatrix(ctx context.Context, eng models.Engine, workflowTimeout time.Duration, parent string, jobs []models.Workflow) error {
+	matrix := jobs[0].Matrix
+	wid := models.WorkflowId{
+		PipelineId: r.id,
+		Name:       parent,
+	}
+
+	err := r.db.StatusRunning(wid, r.n)
+	if err != nil {
+		return err
+	}
+
+	// failing fast cancels the remaining jobs as if the pipeline was
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		failed    []string
+		cancelled bool
+	)
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := r.run(ctx, eng, workflowTimeout, job)
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if errors.Is(err, ErrCancelled) {
+				cancelled = true
+				return
+			}
+			failed = append(failed, job.Name)
+			if !matrix.ContinueOnError && matrix.IsFailFast() {
+				cancel(ErrCancelled)
+			}
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case len(failed) > 0 && !matrix.ContinueOnError:
+		slices.Sort(failed)
+		err := fmt.Errorf("%s: %w", strings.Join(failed, ", "), ErrWorkflowFailed)
+		if dbErr := r.db.StatusFailed(wid, err.Error(), -1, r.n); dbErr != nil {
+			return dbErr
+		}
+		return err
+	case cancelled:
+		if dbErr := r.db.StatusCancelled(wid, r.n); dbErr != nil {
+			return dbErr
+		}
+		return ErrCancelled
+	default:
+		return r.db.StatusSuccess(wid, r.n)
 	}
 }
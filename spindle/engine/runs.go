@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"sync"
+
+	"tangled.org/core/spindle/models"
+)
+
+// Runs keeps track of the pipelines that are queued or running, so that they
+// can be cancelled.
+type Runs struct {
+	mu      sync.Mutex
+	cancels map[models.PipelineId]context.CancelCauseFunc
+}
+
+func NewRuns() *Runs {
+	return &Runs{
+		cancels: make(map[models.PipelineId]context.CancelCauseFunc),
+	}
+}
+
+// Track derives a context for running pipelineId that is cancelled with
+// ErrCancelled by Cancel. The returned func must be called once the pipeline
+// is done.
+func (r *Runs) Track(ctx context.Context, pipelineId models.PipelineId) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	r.cancels[pipelineId] = cancel
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.cancels, pipelineId)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel signals the workflows of pipelineId to stop, and reports whether
+// the pipeline was queued or running at all.
+func (r *Runs) Cancel(pipelineId models.PipelineId) bool {
+	r.mu.Lock()
+	cancel, ok := r.cancels[pipelineId]
+	r.mu.Unlock()
+
+	if ok {
+		cancel(ErrCancelled)
+	}
+	return ok
+}
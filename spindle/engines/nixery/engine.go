@@ -1,6 +1,7 @@
 package nixery
 
 import (
+	"archive/tar"
 	"context"
 	"errors"
 	"fmt"
@@ -9,9 +10,11 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
@@ -88,6 +91,7 @@ func (e *Engine) InitWorkflow(twf tangled.Pipeline_Workflow, tpl tangled.Pipelin
 		} `yaml:"steps"`
 		Dependencies map[string][]string `yaml:"dependencies"`
 		Environment  map[string]string   `yaml:"environment"`
+		Artifacts    []string            `yaml:"artifacts"`
 	}{}
 	err := yaml.Unmarshal([]byte(twf.Raw), &dwf)
 	if err != nil {
@@ -103,6 +107,7 @@ func (e *Engine) InitWorkflow(twf tangled.Pipeline_Workflow, tpl tangled.Pipelin
 		swf.Steps = append(swf.Steps, sstep)
 	}
 	swf.Name = twf.Name
+	swf.Artifacts = dwf.Artifacts
 	addl.env = dwf.Environment
 	addl.image = workflowImage(dwf.Dependencies, e.cfg.NixeryPipelines.Nixery)
 
@@ -368,6 +373,48 @@ func (e *Engine) RunStep(ctx context.Context, wid models.WorkflowId, w *models.W
 	return nil
 }
 
+// CollectArtifacts copies the workspace out of the workflow's container and
+// hands over the files matching its artifact patterns.
+func (e *Engine) CollectArtifacts(ctx context.Context, wid models.WorkflowId, w *models.Workflow, fn func(name string, size int64, r io.Reader) error) error {
+	addl := w.Data.(addlFields)
+
+	rc, _, err := e.docker.CopyFromContainer(ctx, addl.container, workspaceDir)
+	if err != nil {
+		return fmt.Errorf("copying workspace: %w", err)
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading workspace: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// entries are prefixed with the name of the copied directory
+		_, name, ok := strings.Cut(hdr.Name, "/")
+		if !ok {
+			continue
+		}
+
+		for _, pattern := range w.Artifacts {
+			if matched, _ := doublestar.Match(strings.TrimPrefix(pattern, "./"), name); matched {
+				if err := fn(name, hdr.Size, tr); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+}
+
 func (e *Engine) tailStep(ctx context.Context, wfLogger *models.WorkflowLogger, execID string, wid models.WorkflowId, stepIdx int, step models.Step) error {
 	if wfLogger == nil {
 		return nil
@@ -156,18 +156,24 @@ func (s *Spindle) ingestRepo(ctx context.Context, e *models.Event) error {
 			return err
 		}
 
+		// transferred repos are picked up from their new owner's record
+		if record.TransferTo != nil {
+			l.Info("repo is being transferred", "name", record.Name)
+			return nil
+		}
+
 		domain := s.cfg.Server.Hostname
 
-		// no spindle configured for this repo
+		// no spindle configured for this repo, or its pipelines are off
 		if record.Spindle == nil {
 			l.Info("no spindle configured", "name", record.Name)
-			return nil
+			return s.db.RemoveRepo(did, record.Name)
 		}
 
 		// this repo did not want this spindle
 		if *record.Spindle != domain {
 			l.Info("different spindle configured", "name", record.Name, "spindle", *record.Spindle, "domain", domain)
-			return nil
+			return s.db.RemoveRepo(did, record.Name)
 		}
 
 		// add this repo to the watch list
@@ -176,6 +182,24 @@ func (s *Spindle) ingestRepo(ctx context.Context, e *models.Event) error {
 			return fmt.Errorf("failed to add repo: %w", err)
 		}
 
+		settings := db.RepoPipelineSettings{}
+		if record.PipelineConcurrency != nil {
+			settings.MaxConcurrent = int(*record.PipelineConcurrency)
+		}
+		if record.CancelSupersededPipelines != nil {
+			settings.CancelSuperseded = *record.CancelSupersededPipelines
+		}
+		if err := s.db.SetRepoPipelineSettings(did, record.Name, settings); err != nil {
+			l.Error("failed to save pipeline settings", "error", err)
+			return fmt.Errorf("failed to add repo: %w", err)
+		}
+
+		private := record.Visibility != nil && *record.Visibility == "private"
+		if err := s.db.SetRepoPrivate(did, record.Name, private); err != nil {
+			l.Error("failed to save repo visibility", "error", err)
+			return fmt.Errorf("failed to add repo: %w", err)
+		}
+
 		didSlashRepo, err := securejoin.SecureJoin(did, record.Name)
 		if err != nil {
 			return err
@@ -258,8 +282,14 @@ func (s *Spindle) ingestCollaborator(ctx context.Context, e *models.Event) error
 			return fmt.Errorf("insufficient permissions: %w", err)
 		}
 
+		role, err := rbac.RecordRole(record.Role)
+		if err != nil {
+			l.Info("rejecting record", "error", err)
+			return nil
+		}
+
 		// add collaborator to rbac
-		if err := s.e.AddCollaborator(record.Subject, rbac.ThisServer, didSlashRepo); err != nil {
+		if err := s.e.SetRepoRole(record.Subject, rbac.ThisServer, didSlashRepo, role); err != nil {
 			l.Error("failed to add repo to enforcer", "error", err)
 			return fmt.Errorf("failed to add repo: %w", err)
 		}
@@ -290,7 +320,13 @@ func (s *Spindle) fetchAndAddCollaborators(ctx context.Context, owner *identity.
 		}
 		record := r.Value.Val.(*tangled.RepoCollaborator)
 
-		if err := s.e.AddCollaborator(record.Subject, rbac.ThisServer, didSlashRepo); err != nil {
+		role, err := rbac.RecordRole(record.Role)
+		if err != nil {
+			l.Info("skipping record", "error", err)
+			continue
+		}
+
+		if err := s.e.SetRepoRole(record.Subject, rbac.ThisServer, didSlashRepo, role); err != nil {
 			l.Error("failed to add repo to enforcer", "error", err)
 			errors.Join(errs, fmt.Errorf("failed to add repo: %w", err))
 		}
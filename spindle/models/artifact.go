@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ArtifactNSID is the nsid of the events announcing the artifacts kept for
+// a workflow, alongside the status events.
+const ArtifactNSID = "sh.tangled.pipeline.artifact"
+
+// ArtifactEvent is the event emitted once an artifact is stored.
+type ArtifactEvent struct {
+	// ATURI of the pipeline
+	Pipeline string `json:"pipeline"`
+	// name of the workflow within this pipeline
+	Workflow string `json:"workflow"`
+	// path of the artifact relative to the workspace
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// ArtifactPath is the path, on the spindle, an artifact is served from.
+func ArtifactPath(wid WorkflowId, name string) string {
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return fmt.Sprintf("/artifacts/%s/%s/%s/%s", wid.Knot, wid.Rkey, url.PathEscape(wid.Name), strings.Join(segments, "/"))
+}
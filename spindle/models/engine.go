@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"tangled.org/core/api/tangled"
@@ -15,3 +16,12 @@ type Engine interface {
 	DestroyWorkflow(ctx context.Context, wid WorkflowId) error
 	RunStep(ctx context.Context, wid WorkflowId, w *Workflow, idx int, secrets []secrets.UnlockedSecret, wfLogger *WorkflowLogger) error
 }
+
+// ArtifactCollector is implemented by engines that can copy files out of the
+// environment a workflow ran in.
+type ArtifactCollector interface {
+	// CollectArtifacts calls fn with every regular file in the workspace
+	// that matches one of the workflow's artifact patterns. name is the
+	// path of the file relative to the workspace.
+	CollectArtifacts(ctx context.Context, wid WorkflowId, w *Workflow, fn func(name string, size int64, r io.Reader) error) error
+}
@@ -6,12 +6,17 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
+// replaces secret values in step output
+const redactedValue = "***"
+
 type WorkflowLogger struct {
-	file    *os.File
-	encoder *json.Encoder
+	file     *os.File
+	encoder  *json.Encoder
+	redactor *strings.Replacer
 }
 
 func NewWorkflowLogger(baseDir string, wid WorkflowId) (*WorkflowLogger, error) {
@@ -37,6 +42,43 @@ func (l *WorkflowLogger) Close() error {
 	return l.file.Close()
 }
 
+// Redact masks every occurrence of the given values in step output written
+// after this call. Multi-line values are also masked line by line, since
+// output may be written a line at a time.
+func (l *WorkflowLogger) Redact(values []string) {
+	var needles []string
+	for _, v := range values {
+		needles = append(needles, v)
+		if strings.ContainsAny(v, "\r\n") {
+			needles = append(needles, strings.FieldsFunc(v, func(r rune) bool {
+				return r == '\r' || r == '\n'
+			})...)
+		}
+	}
+
+	needles = slices.DeleteFunc(needles, func(n string) bool {
+		return strings.TrimSpace(n) == ""
+	})
+	if len(needles) == 0 {
+		return
+	}
+
+	// longest first, so that a value is masked whole rather than around a
+	// shorter value it happens to contain
+	slices.SortFunc(needles, func(a, b string) int {
+		if d := len(b) - len(a); d != 0 {
+			return d
+		}
+		return strings.Compare(a, b)
+	})
+
+	var oldnew []string
+	for _, n := range slices.Compact(needles) {
+		oldnew = append(oldnew, n, redactedValue)
+	}
+	l.redactor = strings.NewReplacer(oldnew...)
+}
+
 func (l *WorkflowLogger) DataWriter(idx int, stream string) io.Writer {
 	return &dataWriter{
 		logger: l,
@@ -62,6 +104,9 @@ type dataWriter struct {
 
 func (w *dataWriter) Write(p []byte) (int, error) {
 	line := strings.TrimRight(string(p), "\r\n")
+	if w.logger.redactor != nil {
+		line = w.logger.redactor.Replace(line)
+	}
 	entry := NewDataLogLine(w.idx, line, w.stream)
 	if err := w.logger.encoder.Encode(entry); err != nil {
 		return 0, err
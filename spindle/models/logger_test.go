@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWorkflowLogger_Redact(t *testing.T) {
+	dir := t.TempDir()
+	wid := WorkflowId{
+		PipelineId: PipelineId{Knot: "example.com", Rkey: "abc"},
+		Name:       "test.yml",
+	}
+
+	l, err := NewWorkflowLogger(dir, wid)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	l.Redact([]string{"hunter2", "", "hunter22", "line one\nline two"})
+
+	lines := []string{
+		"token is hunter22\n",
+		"password=hunter2 again hunter2\n",
+		"line two is here\n",
+		"nothing to see\n",
+	}
+	for _, line := range lines {
+		if _, err := l.DataWriter(0, "stdout").Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	l.Close()
+
+	data, err := os.ReadFile(LogFilePath(dir, wid))
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	var got []string
+	for _, raw := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry LogLine
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			t.Fatalf("failed to decode log line: %v", err)
+		}
+		got = append(got, entry.Content)
+	}
+
+	expected := []string{
+		"token is ***",
+		"password=*** again ***",
+		"*** is here",
+		"nothing to see",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
@@ -42,14 +42,16 @@ type StatusKind string
 
 var (
 	StatusKindPending   StatusKind = "pending"
+	StatusKindQueued    StatusKind = "queued" // waiting for earlier runs of its repo
 	StatusKindRunning   StatusKind = "running"
 	StatusKindFailed    StatusKind = "failed"
 	StatusKindTimeout   StatusKind = "timeout"
 	StatusKindCancelled StatusKind = "cancelled"
 	StatusKindSuccess   StatusKind = "success"
 
-	StartStates [2]StatusKind = [2]StatusKind{
+	StartStates [3]StatusKind = [3]StatusKind{
 		StatusKindPending,
+		StatusKindQueued,
 		StatusKindRunning,
 	}
 	FinishStates [4]StatusKind = [4]StatusKind{
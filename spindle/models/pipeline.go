@@ -1,5 +1,7 @@
 package models
 
+import "tangled.org/core/workflow"
+
 type Pipeline struct {
 	RepoOwner string
 	RepoName  string
@@ -25,4 +27,13 @@ type Workflow struct {
 	Steps []Step
 	Name  string
 	Data  any
+
+	// glob patterns, relative to the workspace, of the files to keep once
+	// the workflow succeeds
+	Artifacts []string
+
+	// set on the jobs a matrix fans out into, Parent is the name of the
+	// workflow they were expanded from
+	Parent string
+	Matrix *workflow.Matrix
 }
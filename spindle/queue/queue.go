@@ -7,12 +7,20 @@ import (
 type Job struct {
 	Run    func() error
 	OnFail func(error)
+	// OnWait is called when a Scheduler holds the job back for its repo
+	OnWait func()
+	// Cancel is called when a Scheduler drops the job before it ran
+	Cancel func()
 }
 
 type Queue struct {
 	jobs    chan Job
 	workers int
 	wg      sync.WaitGroup
+
+	// guards against enqueueing onto a stopped queue
+	mu     sync.RWMutex
+	closed bool
 }
 
 func NewQueue(queueSize, numWorkers int) *Queue {
@@ -23,6 +31,13 @@ func NewQueue(queueSize, numWorkers int) *Queue {
 }
 
 func (q *Queue) Enqueue(job Job) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
 	select {
 	case q.jobs <- job:
 		return true
@@ -41,15 +56,23 @@ func (q *Queue) Start() {
 func (q *Queue) worker() {
 	defer q.wg.Done()
 	for job := range q.jobs {
-		if err := job.Run(); err != nil {
-			if job.OnFail != nil {
-				job.OnFail(err)
-			}
+		run(job)
+	}
+}
+
+func run(job Job) {
+	if err := job.Run(); err != nil {
+		if job.OnFail != nil {
+			job.OnFail(err)
 		}
 	}
 }
 
 func (q *Queue) Stop() {
+	q.mu.Lock()
+	q.closed = true
 	close(q.jobs)
+	q.mu.Unlock()
+
 	q.wg.Wait()
 }
@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"slices"
+	"sync"
+)
+
+// Scheduler holds jobs back until their repo has fewer than its limit of jobs
+// running, then hands them to a Queue in the order they were scheduled.
+type Scheduler struct {
+	mu      sync.Mutex
+	q       *Queue
+	size    int
+	running map[string]int
+	waiting []waitingJob
+	seq     uint64
+}
+
+type waitingJob struct {
+	seq   uint64
+	repo  string
+	group string
+	limit int
+	job   Job
+}
+
+// NewScheduler schedules jobs onto q, keeping at most size jobs waiting.
+func NewScheduler(q *Queue, size int) *Scheduler {
+	return &Scheduler{
+		q:       q,
+		size:    size,
+		running: make(map[string]int),
+	}
+}
+
+// Schedule runs job once fewer than limit jobs of repo are running, calling
+// its OnWait func if it has to wait. Jobs that share a group can later be
+// superseded while they wait. It reports whether the job was accepted.
+func (s *Scheduler) Schedule(repo, group string, limit int, job Job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.waiting) >= s.size {
+		return false
+	}
+
+	s.seq++
+	seq := s.seq
+	s.waiting = append(s.waiting, waitingJob{
+		seq:   seq,
+		repo:  repo,
+		group: group,
+		limit: max(limit, 1),
+		job:   job,
+	})
+	s.dispatch()
+
+	// still holding mu, so the job can't start before OnWait returns
+	waiting := slices.ContainsFunc(s.waiting, func(w waitingJob) bool {
+		return w.seq == seq
+	})
+	if waiting && job.OnWait != nil {
+		job.OnWait()
+	}
+
+	return true
+}
+
+// Supersede drops the jobs of repo in group that are still waiting. Their
+// Cancel func is called and they are then run right away, outside of any
+// limit, so that they can report their cancellation. It returns the number
+// of dropped jobs.
+func (s *Scheduler) Supersede(repo, group string) int {
+	s.mu.Lock()
+	var dropped []Job
+	s.waiting = slices.DeleteFunc(s.waiting, func(w waitingJob) bool {
+		if w.repo == repo && w.group == group {
+			dropped = append(dropped, w.job)
+			return true
+		}
+		return false
+	})
+	s.mu.Unlock()
+
+	for _, job := range dropped {
+		if job.Cancel != nil {
+			job.Cancel()
+		}
+		go run(job)
+	}
+
+	return len(dropped)
+}
+
+// dispatch hands every waiting job whose repo has a free slot to the queue,
+// oldest first. It must be called with mu held.
+func (s *Scheduler) dispatch() {
+	i := 0
+	for i < len(s.waiting) {
+		w := s.waiting[i]
+		if s.running[w.repo] >= w.limit {
+			i++
+			continue
+		}
+
+		if !s.q.Enqueue(s.wrap(w)) {
+			// the queue is full, a worker finishing a job will call us again
+			return
+		}
+
+		s.running[w.repo]++
+		s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+	}
+}
+
+// wrap releases the slot of w once its job has run.
+func (s *Scheduler) wrap(w waitingJob) Job {
+	job := w.job
+	run := job.Run
+	job.Run = func() error {
+		defer s.release(w.repo)
+		return run()
+	}
+	return job
+}
+
+func (s *Scheduler) release(repo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running[repo]--
+	if s.running[repo] <= 0 {
+		delete(s.running, repo)
+	}
+	s.dispatch()
+}
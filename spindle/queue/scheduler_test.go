@@ -0,0 +1,163 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// job returns a Job that records its name once it runs and then blocks until
+// release is closed.
+func job(name string, ran chan<- string, release <-chan struct{}) Job {
+	return Job{
+		Run: func() error {
+			ran <- name
+			<-release
+			return nil
+		},
+	}
+}
+
+func expectRun(t *testing.T, ran <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ran:
+		if got != want {
+			t.Fatalf("expected %q to run, got %q", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected %q to run", want)
+	}
+}
+
+func expectIdle(t *testing.T, ran <-chan string) {
+	t.Helper()
+	select {
+	case got := <-ran:
+		t.Fatalf("expected nothing to run, got %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestScheduler_RepoLimit(t *testing.T) {
+	q := NewQueue(10, 4)
+	q.Start()
+	defer q.Stop()
+	s := NewScheduler(q, 10)
+
+	ran := make(chan string, 10)
+	releaseA1, releaseA2, releaseB := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	defer close(releaseA2)
+	defer close(releaseB)
+
+	var waited []string
+	schedule := func(repo, name string, release chan struct{}) {
+		j := job(name, ran, release)
+		j.OnWait = func() { waited = append(waited, name) }
+		if !s.Schedule(repo, "push:main", 1, j) {
+			t.Fatalf("expected %s to be accepted", name)
+		}
+	}
+
+	schedule("a", "a1", releaseA1)
+	expectRun(t, ran, "a1")
+
+	schedule("a", "a2", releaseA2)
+
+	// other repos are not held back by a
+	schedule("b", "b1", releaseB)
+	expectRun(t, ran, "b1")
+
+	if len(waited) != 1 || waited[0] != "a2" {
+		t.Fatalf("expected only a2 to wait, got %v", waited)
+	}
+	expectIdle(t, ran)
+
+	close(releaseA1)
+	expectRun(t, ran, "a2")
+}
+
+func TestScheduler_FIFO(t *testing.T) {
+	q := NewQueue(10, 4)
+	q.Start()
+	defer q.Stop()
+	s := NewScheduler(q, 10)
+
+	ran := make(chan string, 10)
+	releases := make([]chan struct{}, 4)
+	for i := range releases {
+		releases[i] = make(chan struct{})
+	}
+
+	for i, name := range []string{"1", "2", "3", "4"} {
+		s.Schedule("a", "push:main", 1, job(name, ran, releases[i]))
+	}
+
+	for i, name := range []string{"1", "2", "3", "4"} {
+		expectRun(t, ran, name)
+		expectIdle(t, ran)
+		close(releases[i])
+	}
+}
+
+func TestScheduler_Supersede(t *testing.T) {
+	q := NewQueue(10, 4)
+	q.Start()
+	defer q.Stop()
+	s := NewScheduler(q, 10)
+
+	ran := make(chan string, 10)
+	release := make(chan struct{})
+	defer close(release)
+
+	s.Schedule("a", "push:main", 1, job("running", ran, release))
+	expectRun(t, ran, "running")
+
+	var mu sync.Mutex
+	var cancelled []string
+	queued := func(name, group string) {
+		j := job(name, ran, release)
+		j.Cancel = func() {
+			mu.Lock()
+			cancelled = append(cancelled, name)
+			mu.Unlock()
+		}
+		s.Schedule("a", group, 1, j)
+	}
+	queued("old", "push:main")
+	queued("other", "push:dev")
+
+	if n := s.Supersede("a", "push:main"); n != 1 {
+		t.Fatalf("expected 1 superseded job, got %d", n)
+	}
+
+	// superseded jobs run right away to report their cancellation
+	expectRun(t, ran, "old")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cancelled) != 1 || cancelled[0] != "old" {
+		t.Fatalf("expected only old to be cancelled, got %v", cancelled)
+	}
+}
+
+func TestScheduler_Full(t *testing.T) {
+	q := NewQueue(10, 1)
+	q.Start()
+	defer q.Stop()
+	s := NewScheduler(q, 1)
+
+	ran := make(chan string, 10)
+	release := make(chan struct{})
+	defer close(release)
+
+	s.Schedule("a", "", 1, job("1", ran, release))
+	expectRun(t, ran, "1")
+
+	if !s.Schedule("a", "", 1, job("2", ran, release)) {
+		t.Fatal("expected 2 to be accepted")
+	}
+	if s.Schedule("a", "", 1, job("3", ran, release)) {
+		t.Fatal("expected 3 to be rejected")
+	}
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeySize is the size in bytes of the key used to encrypt secrets at rest
+const KeySize = 32
+
+var ErrInvalidEncryptionKey = errors.New("encryption key must be 32 bytes")
+
+// LoadOrCreateKey reads the encryption key at path, generating and persisting
+// a fresh one if the file does not exist yet.
+func LoadOrCreateKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%s: %w", path, ErrInvalidEncryptionKey)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+
+	key = make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+	}
+
+	// O_EXCL so that we never clobber a key that appeared in the meantime
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption key: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(key); err != nil {
+		return nil, fmt.Errorf("failed to write encryption key: %w", err)
+	}
+
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// the repo and key are bound to the ciphertext as additional data, so a value
+// cannot be moved to another repo or renamed by editing the database
+func additionalData(repo DidSlashRepo, key string) []byte {
+	return []byte(string(repo) + "\x00" + key)
+}
+
+func seal(aead cipher.AEAD, repo DidSlashRepo, key, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), additionalData(repo, key))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func open(aead cipher.AEAD, repo DidSlashRepo, key, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret %s: %w", key, err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("secret %s is truncated", key)
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(repo, key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret %s: %w", key, err)
+	}
+
+	return string(plaintext), nil
+}
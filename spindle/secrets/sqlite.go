@@ -3,7 +3,9 @@ package secrets
 
 import (
 	"context"
+	"crypto/cipher"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 type SqliteManager struct {
 	db        *sql.DB
 	tableName string
+	key       []byte
+	aead      cipher.AEAD
 }
 
 type SqliteManagerOpt func(*SqliteManager)
@@ -23,6 +27,14 @@ func WithTableName(name string) SqliteManagerOpt {
 	}
 }
 
+// WithEncryptionKey encrypts secret values at rest with the given 32 byte key.
+// Values stored before a key was configured are encrypted on startup.
+func WithEncryptionKey(key []byte) SqliteManagerOpt {
+	return func(s *SqliteManager) {
+		s.key = key
+	}
+}
+
 func NewSQLiteManager(dbPath string, opts ...SqliteManagerOpt) (*SqliteManager, error) {
 	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=1")
 	if err != nil {
@@ -38,6 +50,14 @@ func NewSQLiteManager(dbPath string, opts ...SqliteManagerOpt) (*SqliteManager,
 		o(manager)
 	}
 
+	if manager.key != nil {
+		aead, err := newAEAD(manager.key)
+		if err != nil {
+			return nil, err
+		}
+		manager.aead = aead
+	}
+
 	if err := manager.init(); err != nil {
 		return nil, err
 	}
@@ -55,20 +75,106 @@ func (s *SqliteManager) init() error {
 			value text not null,
 			created_at text not null default (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
 			created_by text not null,
+			encrypted integer not null default 0,
 
 			unique(repo, key)
 		);`
-	_, err := s.db.Exec(createTable)
-	return err
+	if _, err := s.db.Exec(createTable); err != nil {
+		return err
+	}
+
+	// tables created before values were encrypted lack this column
+	var hasEncrypted bool
+	err := s.db.QueryRow(
+		`select count(*) > 0 from pragma_table_info(?) where name = 'encrypted'`,
+		s.tableName,
+	).Scan(&hasEncrypted)
+	if err != nil {
+		return err
+	}
+	if !hasEncrypted {
+		_, err := s.db.Exec(`alter table ` + s.tableName + ` add column encrypted integer not null default 0`)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.aead == nil {
+		return nil
+	}
+
+	return s.encryptPlaintext()
+}
+
+// encrypts every value that was stored in plaintext
+func (s *SqliteManager) encryptPlaintext() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`select id, repo, key, value from ` + s.tableName + ` where encrypted = 0`)
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		id    int64
+		value string
+	}
+	var sealed []row
+	for rows.Next() {
+		var r row
+		var repo DidSlashRepo
+		var key, value string
+		if err := rows.Scan(&r.id, &repo, &key, &value); err != nil {
+			rows.Close()
+			return err
+		}
+
+		r.value, err = seal(s.aead, repo, key, value)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		sealed = append(sealed, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range sealed {
+		_, err := tx.Exec(`update `+s.tableName+` set value = ?, encrypted = 1 where id = ?`, r.value, r.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
+var ErrMissingEncryptionKey = errors.New("secret is encrypted but no encryption key is configured")
+
 func (s *SqliteManager) AddSecret(ctx context.Context, secret UnlockedSecret) error {
 	query := fmt.Sprintf(`
-		insert or ignore into %s (repo, key, value, created_by)
-		values (?, ?, ?, ?);
+		insert or ignore into %s (repo, key, value, created_by, encrypted)
+		values (?, ?, ?, ?, ?);
 	`, s.tableName)
 
-	res, err := s.db.ExecContext(ctx, query, secret.Repo, secret.Key, secret.Value, secret.CreatedBy)
+	value, encrypted := secret.Value, false
+	if s.aead != nil {
+		var err error
+		value, err = seal(s.aead, secret.Repo, secret.Key, secret.Value)
+		if err != nil {
+			return err
+		}
+		encrypted = true
+	}
+
+	res, err := s.db.ExecContext(ctx, query, secret.Repo, secret.Key, value, secret.CreatedBy, encrypted)
 	if err != nil {
 		return err
 	}
@@ -141,7 +247,7 @@ func (s *SqliteManager) GetSecretsLocked(ctx context.Context, didSlashRepo DidSl
 
 func (s *SqliteManager) GetSecretsUnlocked(ctx context.Context, didSlashRepo DidSlashRepo) ([]UnlockedSecret, error) {
 	query := fmt.Sprintf(`
-		select repo, key, value, created_at, created_by from %s where repo = ?;
+		select repo, key, value, encrypted, created_at, created_by from %s where repo = ?;
 	`, s.tableName)
 
 	rows, err := s.db.QueryContext(ctx, query, didSlashRepo)
@@ -153,10 +259,20 @@ func (s *SqliteManager) GetSecretsUnlocked(ctx context.Context, didSlashRepo Did
 	for rows.Next() {
 		var l UnlockedSecret
 		var createdAt string
-		if err = rows.Scan(&l.Repo, &l.Key, &l.Value, &createdAt, &l.CreatedBy); err != nil {
+		var encrypted bool
+		if err = rows.Scan(&l.Repo, &l.Key, &l.Value, &encrypted, &createdAt, &l.CreatedBy); err != nil {
 			return nil, err
 		}
 
+		if encrypted {
+			if s.aead == nil {
+				return nil, ErrMissingEncryptionKey
+			}
+			if l.Value, err = open(s.aead, l.Repo, l.Key, l.Value); err != nil {
+				return nil, err
+			}
+		}
+
 		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 			l.CreatedAt = t
 		}
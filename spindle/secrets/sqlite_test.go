@@ -1,7 +1,10 @@
 package secrets
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -588,3 +591,91 @@ func TestSqliteManager_StopperInterface(t *testing.T) {
 	_, ok := interface{}(manager).(Stopper)
 	assert.False(t, ok, "SqliteManager should NOT implement Stopper interface")
 }
+
+func TestSqliteManager_Encryption(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "spindle.db")
+	key := bytes.Repeat([]byte{0x42}, KeySize)
+
+	manager, err := NewSQLiteManager(dbPath, WithEncryptionKey(key))
+	assert.NoError(t, err)
+
+	secret := createTestSecret("did:plc:foo/repo", "API_KEY", "hunter2", "did:plc:user")
+	assert.NoError(t, manager.AddSecret(ctx, secret))
+
+	// the stored value must not be the plaintext
+	var stored string
+	err = manager.db.QueryRow(`select value from secrets where key = ?`, "API_KEY").Scan(&stored)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "hunter2", stored)
+
+	unlocked, err := manager.GetSecretsUnlocked(ctx, secret.Repo)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(unlocked))
+	assert.Equal(t, "hunter2", unlocked[0].Value)
+
+	// moving the ciphertext to another repo must not decrypt
+	_, err = manager.db.Exec(`update secrets set repo = ?`, "did:plc:bar/repo")
+	assert.NoError(t, err)
+	_, err = manager.GetSecretsUnlocked(ctx, DidSlashRepo("did:plc:bar/repo"))
+	assert.Error(t, err)
+	assert.NoError(t, manager.db.Close())
+
+	// without the key, encrypted values cannot be read
+	manager, err = NewSQLiteManager(dbPath)
+	assert.NoError(t, err)
+	_, err = manager.GetSecretsUnlocked(ctx, DidSlashRepo("did:plc:bar/repo"))
+	assert.IsError(t, err, ErrMissingEncryptionKey)
+	assert.NoError(t, manager.db.Close())
+
+	// a key of the wrong size is rejected
+	_, err = NewSQLiteManager(dbPath, WithEncryptionKey([]byte("short")))
+	assert.IsError(t, err, ErrInvalidEncryptionKey)
+}
+
+func TestSqliteManager_EncryptsPlaintext(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "spindle.db")
+
+	plain, err := NewSQLiteManager(dbPath)
+	assert.NoError(t, err)
+	secret := createTestSecret("did:plc:foo/repo", "TOKEN", "s3cret", "did:plc:user")
+	assert.NoError(t, plain.AddSecret(ctx, secret))
+	assert.NoError(t, plain.db.Close())
+
+	manager, err := NewSQLiteManager(dbPath, WithEncryptionKey(bytes.Repeat([]byte{0x07}, KeySize)))
+	assert.NoError(t, err)
+	defer manager.db.Close()
+
+	var stored string
+	var encrypted bool
+	err = manager.db.QueryRow(`select value, encrypted from secrets where key = ?`, "TOKEN").Scan(&stored, &encrypted)
+	assert.NoError(t, err)
+	assert.True(t, encrypted)
+	assert.NotEqual(t, "s3cret", stored)
+
+	unlocked, err := manager.GetSecretsUnlocked(ctx, secret.Repo)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(unlocked))
+	assert.Equal(t, "s3cret", unlocked[0].Value)
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.key")
+
+	key, err := LoadOrCreateKey(path)
+	assert.NoError(t, err)
+	assert.Equal(t, KeySize, len(key))
+
+	again, err := LoadOrCreateKey(path)
+	assert.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.NoError(t, os.WriteFile(path, []byte("short"), 0600))
+	_, err = LoadOrCreateKey(path)
+	assert.IsError(t, err, ErrInvalidEncryptionKey)
+}
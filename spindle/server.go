@@ -25,6 +25,7 @@ import (
 	"tangled.org/core/spindle/queue"
 	"tangled.org/core/spindle/secrets"
 	"tangled.org/core/spindle/xrpc"
+	"tangled.org/core/workflow"
 	"tangled.org/core/xrpc/serviceauth"
 )
 
@@ -43,6 +44,8 @@ type Spindle struct {
 	n     *notifier.Notifier
 	engs  map[string]models.Engine
 	jq    *queue.Queue
+	sched *queue.Scheduler
+	runs  *engine.Runs
 	cfg   *config.Config
 	ks    *eventconsumer.Consumer
 	res   *idresolver.Resolver
@@ -82,11 +85,19 @@ func New(ctx context.Context, cfg *config.Config, engines map[string]models.Engi
 		}
 		logger.Info("using openbao secrets provider", "proxy_address", cfg.Server.Secrets.OpenBao.ProxyAddr, "mount", cfg.Server.Secrets.OpenBao.Mount)
 	case "sqlite", "":
-		vault, err = secrets.NewSQLiteManager(cfg.Server.DBPath, secrets.WithTableName("secrets"))
+		key, err := secrets.LoadOrCreateKey(cfg.Server.Secrets.Sqlite.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load secrets encryption key: %w", err)
+		}
+		vault, err = secrets.NewSQLiteManager(
+			cfg.Server.DBPath,
+			secrets.WithTableName("secrets"),
+			secrets.WithEncryptionKey(key),
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to setup sqlite secrets provider: %w", err)
 		}
-		logger.Info("using sqlite secrets provider", "path", cfg.Server.DBPath)
+		logger.Info("using sqlite secrets provider", "path", cfg.Server.DBPath, "key_file", cfg.Server.Secrets.Sqlite.KeyFile)
 	default:
 		return nil, fmt.Errorf("unknown secrets provider: %s", cfg.Server.Secrets.Provider)
 	}
@@ -124,6 +135,8 @@ func New(ctx context.Context, cfg *config.Config, engines map[string]models.Engi
 		n:     &n,
 		engs:  engines,
 		jq:    jq,
+		sched: queue.NewScheduler(jq, cfg.Server.QueueSize),
+		runs:  engine.NewRuns(),
 		cfg:   cfg,
 		res:   resolver,
 		vault: vault,
@@ -247,16 +260,21 @@ func (s *Spindle) Router() http.Handler {
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write(motd)
 	})
-	mux.HandleFunc("/events", s.Events)
-	mux.HandleFunc("/logs/{knot}/{rkey}/{name}", s.Logs)
+	serviceAuth := serviceauth.NewServiceAuth(s.l, s.res, s.cfg.Server.Did().String())
+
+	// pipelines of private repos need service auth
+	mux.Group(func(r chi.Router) {
+		r.Use(serviceAuth.OptionalServiceAuth)
+		r.HandleFunc("/events", s.Events)
+		r.HandleFunc("/logs/{knot}/{rkey}/{name}", s.Logs)
+		r.Get("/artifacts/{knot}/{rkey}/{name}/*", s.Artifact)
+	})
 
-	mux.Mount("/xrpc", s.XrpcRouter())
+	mux.Mount("/xrpc", s.XrpcRouter(serviceAuth))
 	return mux
 }
 
-func (s *Spindle) XrpcRouter() http.Handler {
-	serviceAuth := serviceauth.NewServiceAuth(s.l, s.res, s.cfg.Server.Did().String())
-
+func (s *Spindle) XrpcRouter(serviceAuth *serviceauth.ServiceAuth) http.Handler {
 	l := log.SubLogger(s.l, "xrpc")
 
 	x := xrpc.Xrpc{
@@ -267,6 +285,7 @@ func (s *Spindle) XrpcRouter() http.Handler {
 		Config:      s.cfg,
 		Resolver:    s.res,
 		Vault:       s.vault,
+		Runner:      s,
 		ServiceAuth: serviceAuth,
 	}
 
@@ -309,15 +328,56 @@ func (s *Spindle) processPipeline(ctx context.Context, src eventconsumer.Source,
 			Rkey: msg.Rkey,
 		}
 
-		workflows := make(map[models.Engine][]models.Workflow)
+		return s.RunPipeline(ctx, pipelineId, &tpl)
+	}
+
+	return nil
+}
 
-		for _, w := range tpl.Workflows {
-			if w != nil {
-				if _, ok := s.engs[w.Engine]; !ok {
-					err = s.db.StatusFailed(models.WorkflowId{
+// RunPipeline enqueues the workflows of a pipeline record, reporting their
+// status under pipelineId. The record is kept so the pipeline can be run
+// again later.
+func (s *Spindle) RunPipeline(ctx context.Context, pipelineId models.PipelineId, tpl *tangled.Pipeline) error {
+	if err := s.db.AddPipeline(pipelineId, *tpl); err != nil {
+		return fmt.Errorf("failed to store pipeline: %w", err)
+	}
+
+	workflows := make(map[models.Engine][]models.Workflow)
+
+	for _, w := range tpl.Workflows {
+		if w != nil {
+			if _, ok := s.engs[w.Engine]; !ok {
+				err := s.db.StatusFailed(models.WorkflowId{
+					PipelineId: pipelineId,
+					Name:       w.Name,
+				}, fmt.Sprintf("unknown engine %#v", w.Engine), -1, s.n)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			eng := s.engs[w.Engine]
+
+			if _, ok := workflows[eng]; !ok {
+				workflows[eng] = []models.Workflow{}
+			}
+
+			def, err := workflow.FromFile(w.Name, []byte(w.Raw))
+			if err != nil {
+				return err
+			}
+
+			// a matrix fans out into one job per combination, the workflow
+			// itself only reports their overall status
+			jobs := []tangled.Pipeline_Workflow{*w}
+			if def.Matrix != nil {
+				if err := def.Matrix.Validate(); err != nil {
+					err := s.db.StatusFailed(models.WorkflowId{
 						PipelineId: pipelineId,
 						Name:       w.Name,
-					}, fmt.Sprintf("unknown engine %#v", w.Engine), -1, s.n)
+					}, err.Error(), -1, s.n)
 					if err != nil {
 						return err
 					}
@@ -325,19 +385,37 @@ func (s *Spindle) processPipeline(ctx context.Context, src eventconsumer.Source,
 					continue
 				}
 
-				eng := s.engs[w.Engine]
-
-				if _, ok := workflows[eng]; !ok {
-					workflows[eng] = []models.Workflow{}
+				jobs = nil
+				for _, values := range def.Matrix.Combinations() {
+					job := *w
+					job.Name = workflow.MatrixJobName(w.Name, values)
+					job.Raw = workflow.ExpandMatrix(w.Raw, values)
+					jobs = append(jobs, job)
 				}
+			}
 
-				ewf, err := s.engs[w.Engine].InitWorkflow(*w, tpl)
+			for _, job := range jobs {
+				ewf, err := eng.InitWorkflow(job, *tpl)
 				if err != nil {
 					return err
 				}
+				if def.Matrix != nil {
+					ewf.Parent = w.Name
+					ewf.Matrix = def.Matrix
+				}
 
 				workflows[eng] = append(workflows[eng], *ewf)
 
+				err = s.db.StatusPending(models.WorkflowId{
+					PipelineId: pipelineId,
+					Name:       job.Name,
+				}, s.n)
+				if err != nil {
+					return err
+				}
+			}
+
+			if def.Matrix != nil {
 				err = s.db.StatusPending(models.WorkflowId{
 					PipelineId: pipelineId,
 					Name:       w.Name,
@@ -347,30 +425,102 @@ func (s *Spindle) processPipeline(ctx context.Context, src eventconsumer.Source,
 				}
 			}
 		}
+	}
+
+	repo := tpl.TriggerMetadata.Repo
+	settings, err := s.db.GetRepoPipelineSettings(repo.Did, repo.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to get pipeline settings: %w", err)
+	}
 
-		ok := s.jq.Enqueue(queue.Job{
-			Run: func() error {
-				engine.StartWorkflows(log.SubLogger(s.l, "engine"), s.vault, s.cfg, s.db, s.n, ctx, &models.Pipeline{
-					RepoOwner: tpl.TriggerMetadata.Repo.Did,
-					RepoName:  tpl.TriggerMetadata.Repo.Repo,
-					Workflows: workflows,
-				}, pipelineId)
-				return nil
-			},
-			OnFail: func(jobError error) {
-				s.l.Error("pipeline run failed", "error", jobError)
-			},
-		})
-		if ok {
-			s.l.Info("pipeline enqueued successfully", "id", msg.Rkey)
-		} else {
-			s.l.Error("failed to enqueue pipeline: queue is full")
+	limit := s.cfg.Server.MaxRepoJobCount
+	if settings.MaxConcurrent > 0 {
+		limit = settings.MaxConcurrent
+	}
+	// a repo can't take up more than the whole spindle
+	limit = min(limit, s.cfg.Server.MaxJobCount)
+
+	didSlashRepo := repo.Did + "/" + repo.Repo
+	group := triggerGroup(tpl.TriggerMetadata)
+	if settings.CancelSuperseded && group != "" {
+		if n := s.sched.Supersede(didSlashRepo, group); n > 0 {
+			s.l.Info("cancelled superseded pipelines", "repo", didSlashRepo, "group", group, "count", n)
 		}
 	}
 
+	ctx, done := s.runs.Track(ctx, pipelineId)
+	ok := s.sched.Schedule(didSlashRepo, group, limit, queue.Job{
+		Run: func() error {
+			defer done()
+			engine.StartWorkflows(log.SubLogger(s.l, "engine"), s.vault, s.cfg, s.db, s.n, ctx, &models.Pipeline{
+				RepoOwner: tpl.TriggerMetadata.Repo.Did,
+				RepoName:  tpl.TriggerMetadata.Repo.Repo,
+				Workflows: workflows,
+			}, pipelineId)
+			return nil
+		},
+		OnFail: func(jobError error) {
+			s.l.Error("pipeline run failed", "error", jobError)
+		},
+		OnWait: func() {
+			s.l.Info("pipeline queued behind earlier runs of its repo", "id", pipelineId.Rkey, "limit", limit)
+			for _, wid := range workflowIds(pipelineId, workflows) {
+				if err := s.db.StatusQueued(wid, s.n); err != nil {
+					s.l.Error("failed to mark workflow queued", "wid", wid, "err", err)
+				}
+			}
+		},
+		Cancel: func() {
+			s.runs.Cancel(pipelineId)
+		},
+	})
+	if !ok {
+		done()
+		s.l.Error("failed to enqueue pipeline: queue is full")
+		return fmt.Errorf("failed to enqueue pipeline: queue is full")
+	}
+
+	s.l.Info("pipeline enqueued successfully", "id", pipelineId.Rkey)
 	return nil
 }
 
+// triggerGroup names the branch a pipeline was triggered for, newer
+// pipelines of the same group supersede queued ones. Manual runs are never
+// superseded.
+func triggerGroup(t *tangled.Pipeline_TriggerMetadata) string {
+	switch {
+	case t.Push != nil:
+		return "push:" + t.Push.Ref
+	case t.PullRequest != nil:
+		return "pull:" + t.PullRequest.SourceBranch
+	default:
+		return ""
+	}
+}
+
+// workflowIds lists every workflow of a pipeline that reports a status,
+// including the parents of matrix jobs.
+func workflowIds(pipelineId models.PipelineId, workflows map[models.Engine][]models.Workflow) []models.WorkflowId {
+	var ids []models.WorkflowId
+	parents := make(map[string]bool)
+	for _, wfs := range workflows {
+		for _, w := range wfs {
+			ids = append(ids, models.WorkflowId{PipelineId: pipelineId, Name: w.Name})
+			if w.Parent != "" && !parents[w.Parent] {
+				parents[w.Parent] = true
+				ids = append(ids, models.WorkflowId{PipelineId: pipelineId, Name: w.Parent})
+			}
+		}
+	}
+	return ids
+}
+
+// CancelPipeline stops the workflows of a queued or running pipeline, and
+// reports whether there was anything to stop.
+func (s *Spindle) CancelPipeline(pipelineId models.PipelineId) bool {
+	return s.runs.Cancel(pipelineId)
+}
+
 func (s *Spindle) configureOwner() error {
 	cfgOwner := s.cfg.Server.Owner
 
@@ -64,7 +64,7 @@ func (s *Spindle) Events(w http.ResponseWriter, r *http.Request) {
 
 	// complete backfill first before going to live data
 	l.Debug("going through backfill", "cursor", cursor)
-	if err := s.streamPipelines(conn, &cursor); err != nil {
+	if err := s.streamPipelines(r, conn, &cursor); err != nil {
 		l.Error("failed to backfill", "err", err)
 		return
 	}
@@ -78,7 +78,7 @@ func (s *Spindle) Events(w http.ResponseWriter, r *http.Request) {
 		case <-ch:
 			// we have been notified of new data
 			l.Debug("going through live data", "cursor", cursor)
-			if err := s.streamPipelines(conn, &cursor); err != nil {
+			if err := s.streamPipelines(r, conn, &cursor); err != nil {
 				l.Error("failed to stream", "err", err)
 				return
 			}
@@ -101,6 +101,17 @@ func (s *Spindle) Logs(w http.ResponseWriter, r *http.Request) {
 	l := s.l.With("handler", "Logs")
 	l = s.l.With("wid", wid)
 
+	ok, err := s.canReadPipeline(r, wid.PipelineId)
+	if err != nil {
+		l.Error("failed to check pipeline access", "err", err)
+		http.Error(w, "failed to check access", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "workflow not found", http.StatusNotFound)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		l.Error("websocket upgrade failed", "err", err)
@@ -193,10 +204,28 @@ func (s *Spindle) streamLogsFromDisk(ctx context.Context, conn *websocket.Conn,
 	}
 	defer t.Stop()
 
+	// status changes are announced through the notifier, once the workflow
+	// finishes (or is cancelled) the rest of the file is sent and the stream
+	// ends
+	ch := s.n.Subscribe()
+	defer s.n.Unsubscribe(ch)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-ch:
+			if isFinished {
+				continue
+			}
+			status, err := s.db.GetStatus(wid)
+			if err != nil {
+				return err
+			}
+			if models.StatusKind(status.Status).IsFinish() {
+				isFinished = true
+				go t.StopAtEOF()
+			}
 		case line := <-t.Lines:
 			if line == nil && isFinished {
 				return fmt.Errorf("tail completed")
@@ -222,13 +251,17 @@ func (s *Spindle) streamLogsFromDisk(ctx context.Context, conn *websocket.Conn,
 	}
 }
 
-func (s *Spindle) streamPipelines(conn *websocket.Conn, cursor *int64) error {
+// streamPipelines sends the events after cursor, leaving out those of
+// pipelines the caller of r cannot see.
+func (s *Spindle) streamPipelines(r *http.Request, conn *websocket.Conn, cursor *int64) error {
 	events, err := s.db.GetEvents(*cursor)
 	if err != nil {
 		s.l.Debug("err", "err", err)
 		return err
 	}
 
+	readable := make(map[models.PipelineId]bool)
+
 	for _, event := range events {
 		// first extract the inner json into a map
 		var eventJson map[string]any
@@ -238,6 +271,22 @@ func (s *Spindle) streamPipelines(conn *websocket.Conn, cursor *int64) error {
 			return err
 		}
 
+		if id, ok := eventPipeline(eventJson); ok {
+			canRead, seen := readable[id]
+			if !seen {
+				canRead, err = s.canReadPipeline(r, id)
+				if err != nil {
+					s.l.Error("failed to check pipeline access", "pipeline", id.AtUri(), "err", err)
+					return err
+				}
+				readable[id] = canRead
+			}
+			if !canRead {
+				*cursor = event.Created
+				continue
+			}
+		}
+
 		jsonMsg, err := json.Marshal(map[string]any{
 			"rkey":  event.Rkey,
 			"nsid":  event.Nsid,
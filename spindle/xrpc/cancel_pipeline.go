@@ -0,0 +1,44 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+func (x *Xrpc) CancelPipeline(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger
+	fail := func(e xrpcerr.XrpcError) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, http.StatusBadRequest)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError)
+		return
+	}
+
+	var data tangled.PipelineCancel_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	pipelineId, _, ok := x.pipelineForActor(w, actorDid, data.Pipeline)
+	if !ok {
+		return
+	}
+
+	if !x.Runner.CancelPipeline(pipelineId) {
+		fail(xrpcerr.GenericError(fmt.Errorf("pipeline is not running: %s", data.Pipeline)))
+		return
+	}
+
+	l.Info("cancelled pipeline", "pipeline", data.Pipeline, "did", actorDid.String())
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,62 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"tangled.org/core/api/tangled"
+	"tangled.org/core/spindle/models"
+	xrpcerr "tangled.org/core/xrpc/errors"
+)
+
+func (x *Xrpc) RerunPipeline(w http.ResponseWriter, r *http.Request) {
+	l := x.Logger
+	fail := func(e xrpcerr.XrpcError) {
+		l.Error("failed", "kind", e.Tag, "error", e.Message)
+		writeError(w, e, http.StatusBadRequest)
+	}
+
+	actorDid, ok := r.Context().Value(ActorDid).(syntax.DID)
+	if !ok {
+		fail(xrpcerr.MissingActorDidError)
+		return
+	}
+
+	var data tangled.PipelineRerun_Input
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	rkey, err := syntax.ParseRecordKey(data.Rkey)
+	if err != nil {
+		fail(xrpcerr.GenericError(err))
+		return
+	}
+
+	pipelineId, tpl, ok := x.pipelineForActor(w, actorDid, data.Pipeline)
+	if !ok {
+		return
+	}
+
+	rerunId := models.PipelineId{
+		Knot: pipelineId.Knot,
+		Rkey: rkey.String(),
+	}
+	if _, err := x.Db.GetPipeline(rerunId); err == nil {
+		fail(xrpcerr.RecordExistsError(rerunId.AtUri().String()))
+		return
+	}
+
+	// the workflows outlive this request
+	err = x.Runner.RunPipeline(context.WithoutCancel(r.Context()), rerunId, tpl)
+	if err != nil {
+		l.Error("failed to run pipeline again", "pipeline", data.Pipeline, "err", err)
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
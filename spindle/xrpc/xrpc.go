@@ -1,11 +1,18 @@
 package xrpc
 
 import (
+	"context"
+	"database/sql"
 	_ "embed"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/go-chi/chi/v5"
 
 	"tangled.org/core/api/tangled"
@@ -21,6 +28,12 @@ import (
 
 const ActorDid string = "ActorDid"
 
+// PipelineRunner enqueues and cancels the workflows of a pipeline record.
+type PipelineRunner interface {
+	RunPipeline(ctx context.Context, pipelineId models.PipelineId, tpl *tangled.Pipeline) error
+	CancelPipeline(pipelineId models.PipelineId) bool
+}
+
 type Xrpc struct {
 	Logger      *slog.Logger
 	Db          *db.DB
@@ -29,6 +42,7 @@ type Xrpc struct {
 	Config      *config.Config
 	Resolver    *idresolver.Resolver
 	Vault       secrets.Manager
+	Runner      PipelineRunner
 	ServiceAuth *serviceauth.ServiceAuth
 }
 
@@ -41,6 +55,8 @@ func (x *Xrpc) Router() http.Handler {
 		r.Post("/"+tangled.RepoAddSecretNSID, x.AddSecret)
 		r.Post("/"+tangled.RepoRemoveSecretNSID, x.RemoveSecret)
 		r.Get("/"+tangled.RepoListSecretsNSID, x.ListSecrets)
+		r.Post("/"+tangled.PipelineRerunNSID, x.RerunPipeline)
+		r.Post("/"+tangled.PipelineCancelNSID, x.CancelPipeline)
 	})
 
 	// service query endpoints (no auth required)
@@ -57,3 +73,51 @@ func writeError(w http.ResponseWriter, e xrpcerr.XrpcError, status int) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(e)
 }
+
+// pipelineForActor looks up the pipeline at pipelineUri, and checks that
+// actorDid may push to the repo it was run for. Errors are written to w.
+func (x *Xrpc) pipelineForActor(w http.ResponseWriter, actorDid syntax.DID, pipelineUri string) (models.PipelineId, *tangled.Pipeline, bool) {
+	l := x.Logger
+
+	pipelineAt, err := syntax.ParseATURI(pipelineUri)
+	if err != nil || pipelineAt.Collection() != tangled.PipelineNSID {
+		writeError(w, xrpcerr.GenericError(fmt.Errorf("supplied at-uri is not a pipeline: %s", pipelineUri)), http.StatusBadRequest)
+		return models.PipelineId{}, nil, false
+	}
+
+	pipelineId := models.PipelineId{
+		Knot: strings.TrimPrefix(pipelineAt.Authority().String(), "did:web:"),
+		Rkey: pipelineAt.RecordKey().String(),
+	}
+
+	tpl, err := x.Db.GetPipeline(pipelineId)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, xrpcerr.PipelineNotFoundError, http.StatusNotFound)
+		return models.PipelineId{}, nil, false
+	}
+	if err != nil {
+		l.Error("failed to get pipeline", "pipeline", pipelineUri, "err", err)
+		writeError(w, xrpcerr.GenericError(err), http.StatusInternalServerError)
+		return models.PipelineId{}, nil, false
+	}
+
+	if tpl.TriggerMetadata == nil || tpl.TriggerMetadata.Repo == nil {
+		writeError(w, xrpcerr.GenericError(fmt.Errorf("no repo data found")), http.StatusBadRequest)
+		return models.PipelineId{}, nil, false
+	}
+
+	didPath, err := securejoin.SecureJoin(tpl.TriggerMetadata.Repo.Did, tpl.TriggerMetadata.Repo.Repo)
+	if err != nil {
+		writeError(w, xrpcerr.GenericError(err), http.StatusBadRequest)
+		return models.PipelineId{}, nil, false
+	}
+
+	// anyone who can push to the repo can manage its pipelines
+	if ok, err := x.Enforcer.IsPushAllowed(actorDid.String(), rbac.ThisServer, didPath); !ok || err != nil {
+		l.Error("insufficent permissions", "did", actorDid.String())
+		writeError(w, xrpcerr.AccessControlError(actorDid.String()), http.StatusUnauthorized)
+		return models.PipelineId{}, nil, false
+	}
+
+	return pipelineId, tpl, true
+}
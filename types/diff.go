@@ -25,6 +25,9 @@ type Diff struct {
 	IsDelete      bool                   `json:"is_delete"`
 	IsCopy        bool                   `json:"is_copy"`
 	IsRename      bool                   `json:"is_rename"`
+	// Similarity is how alike, in percent, the old and new file of a rename
+	// or copy are.
+	Similarity int `json:"similarity,omitempty"`
 }
 
 type DiffStat struct {
@@ -41,6 +44,23 @@ func (d *Diff) Stats() DiffStat {
 	return stats
 }
 
+// PatchStat summarizes a patch, summing over the commits of a format-patch.
+type PatchStat struct {
+	Files        []FileStat
+	FilesChanged int
+	Insertions   int64
+	Deletions    int64
+	// binary files count towards FilesChanged, but have no lines
+	BinaryFiles int
+}
+
+type FileStat struct {
+	Name       string
+	IsBinary   bool
+	Insertions int64
+	Deletions  int64
+}
+
 // A nicer git diff representation.
 type NiceDiff struct {
 	Commit struct {
@@ -82,8 +102,36 @@ func (d *NiceDiff) ChangedFiles() []string {
 	return files
 }
 
+// PatchStat summarizes the diff the same way patchutil.Stats summarizes a
+// patch.
+func (d *NiceDiff) PatchStat() PatchStat {
+	var stat PatchStat
+	for i := range d.Diff {
+		f := &d.Diff[i]
+		fs := FileStat{Name: f.Name.New, IsBinary: f.IsBinary}
+		if f.IsDelete {
+			fs.Name = f.Name.Old
+		}
+		st := f.Stats()
+		fs.Insertions, fs.Deletions = st.Insertions, st.Deletions
+
+		stat.Files = append(stat.Files, fs)
+		stat.Insertions += fs.Insertions
+		stat.Deletions += fs.Deletions
+		if fs.IsBinary {
+			stat.BinaryFiles++
+		}
+	}
+	stat.FilesChanged = len(stat.Files)
+	return stat
+}
+
 // used by html elements as a unique ID for hrefs
 func (d *Diff) Id() string {
+	// deleted files have no new name
+	if d.IsDelete {
+		return d.Name.Old
+	}
 	return d.Name.New
 }
 
@@ -1,8 +1,19 @@
 package types
 
 type ConflictInfo struct {
-	Filename string `json:"filename"`
-	Reason   string `json:"reason"`
+	Filename string         `json:"filename"`
+	Reason   string         `json:"reason"`
+	Hunks    []ConflictHunk `json:"hunks,omitempty"`
+}
+
+// ConflictHunk is a region of a file that a patch could not be applied to,
+// as the patch expected it (base), as the target branch has it (ours) and
+// as the patch would have left it (theirs).
+type ConflictHunk struct {
+	Line   int64  `json:"line"`
+	Base   string `json:"base"`
+	Ours   string `json:"ours"`
+	Theirs string `json:"theirs"`
 }
 
 type MergeCheckResponse struct {
@@ -10,6 +21,9 @@ type MergeCheckResponse struct {
 	Conflicts    []ConflictInfo `json:"conflicts"`
 	Message      string         `json:"message"`
 	Error        string         `json:"error"`
+	// AppliedWithFuzz is set when the patch only applies after ignoring
+	// some of its context, and has to be merged with fuzz.
+	AppliedWithFuzz bool `json:"applied_with_fuzz"`
 }
 
 type MergeRequest struct {
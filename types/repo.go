@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -42,6 +43,46 @@ type RepoFormatPatchResponse struct {
 	FormatPatchRaw   string          `json:"patch,omitempty"`
 	CombinedPatch    []*gitdiff.File `json:"combined_patch,omitempty"`
 	CombinedPatchRaw string          `json:"combined_patch_raw,omitempty"`
+	Commits          []CompareCommit `json:"commits,omitempty"`
+}
+
+// CompareCommit is a short summary of a commit introduced by a comparison.
+type CompareCommit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author,omitempty"`
+	Email   string    `json:"email,omitempty"`
+	When    time.Time `json:"when,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// CompareCommits returns the commits introduced by the comparison. Knots that
+// predate the commits field only send format patches, so the list is derived
+// from their headers instead.
+func (r RepoFormatPatchResponse) CompareCommits() []CompareCommit {
+	if len(r.Commits) > 0 {
+		return r.Commits
+	}
+	return CompareCommitsFromPatches(r.FormatPatch)
+}
+
+func CompareCommitsFromPatches(patches []FormatPatch) []CompareCommit {
+	var commits []CompareCommit
+	for _, p := range patches {
+		if p.PatchHeader == nil {
+			continue
+		}
+		c := CompareCommit{
+			Hash:    p.SHA,
+			When:    p.AuthorDate,
+			Message: p.Title,
+		}
+		if p.Author != nil {
+			c.Author = p.Author.Name
+			c.Email = p.Author.Email
+		}
+		commits = append(commits, c)
+	}
+	return commits
 }
 
 type RepoTreeResponse struct {
@@ -58,6 +99,9 @@ type TagReference struct {
 	Reference
 	Tag     *object.Tag `json:"tag,omitempty"`
 	Message string      `json:"message,omitempty"`
+	// Payload is the raw tag object that Tag.PGPSignature signs, set for
+	// signed tags only
+	Payload string `json:"payload,omitempty"`
 }
 
 type Reference struct {
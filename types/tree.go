@@ -17,6 +17,9 @@ type NiceTree struct {
 	Size int64  `json:"size"`
 
 	LastCommit *LastCommitInfo `json:"last_commit,omitempty"`
+
+	// Set for gitlink entries only
+	Submodule *SubmoduleInfo `json:"submodule,omitempty"`
 }
 
 func (t *NiceTree) FileMode() (filemode.FileMode, error) {
@@ -106,3 +109,18 @@ type LastCommitInfo struct {
 	Message string
 	When    time.Time
 }
+
+// SubmoduleInfo describes a gitlink entry, as declared in .gitmodules.
+type SubmoduleInfo struct {
+	Name string `json:"name"`
+	// Url is as written in .gitmodules, it may be relative to the
+	// superproject's own url
+	Url    string `json:"url"`
+	Branch string `json:"branch,omitempty"`
+	// Commit that the superproject pins the submodule to
+	Commit string `json:"commit"`
+
+	// Link is where the appview sends the entry to, empty if the url can't
+	// be browsed
+	Link string `json:"-"`
+}
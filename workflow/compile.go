@@ -140,6 +140,13 @@ func (compiler *Compiler) compileWorkflow(w Workflow) *tangled.Pipeline_Workflow
 		return nil
 	}
 
+	if w.Matrix != nil {
+		if err := w.Matrix.Validate(); err != nil {
+			compiler.Diagnostics.AddError(w.Name, err)
+			return nil
+		}
+	}
+
 	cw.Engine = w.Engine
 	cw.Raw = w.Raw
 
@@ -29,6 +29,7 @@ type (
 		Engine    string       `yaml:"engine"`
 		When      []Constraint `yaml:"when"`
 		CloneOpts CloneOpts    `yaml:"clone"`
+		Matrix    *Matrix      `yaml:"matrix"`
 		Raw       string       `yaml:"-"`
 	}
 
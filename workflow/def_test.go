@@ -321,3 +321,50 @@ func TestConstraintMatchTag_GlobPatterns(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalWorkflowWithMatrix(t *testing.T) {
+	yamlData := `
+matrix:
+  axes:
+    os: linux
+    go: ["1.22", "1.23"]
+  fail_fast: false
+
+environment:
+  GO_VERSION: ${{ matrix.go }}`
+
+	wf, err := FromFile("test.yml", []byte(yamlData))
+	assert.NoError(t, err)
+	assert.NotNil(t, wf.Matrix)
+	assert.NoError(t, wf.Matrix.Validate())
+	assert.False(t, wf.Matrix.IsFailFast())
+	assert.False(t, wf.Matrix.ContinueOnError)
+
+	combinations := wf.Matrix.Combinations()
+	assert.Equal(t, []map[string]string{
+		{"go": "1.22", "os": "linux"},
+		{"go": "1.23", "os": "linux"},
+	}, combinations)
+
+	name := MatrixJobName(wf.Name, combinations[1])
+	assert.Equal(t, "test.yml[go=1.23,os=linux]", name)
+
+	parent, values, ok := SplitMatrixJobName(name)
+	assert.True(t, ok)
+	assert.Equal(t, "test.yml", parent)
+	assert.Equal(t, "go=1.23,os=linux", values)
+
+	_, _, ok = SplitMatrixJobName("test.yml")
+	assert.False(t, ok)
+
+	expanded := ExpandMatrix(wf.Raw, combinations[0])
+	assert.Contains(t, expanded, "GO_VERSION: 1.22")
+}
+
+func TestInvalidMatrix(t *testing.T) {
+	m := Matrix{Axes: map[string]StringList{"go": nil}}
+	assert.Error(t, m.Validate())
+
+	m = Matrix{Axes: map[string]StringList{"go-version": {"1.22"}}}
+	assert.Error(t, m.Validate())
+}
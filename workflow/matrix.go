@@ -0,0 +1,117 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// a matrix fans a workflow out into one job per combination of its axes,
+// these run in parallel and their parent reports their overall status:
+//
+//	matrix:
+//	  axes:
+//	    go: ["1.22", "1.23"]
+//	    os: [linux]
+//	  fail_fast: false
+//
+// values are substituted wherever ${{ matrix.<axis> }} appears in the
+// workflow.
+type Matrix struct {
+	Axes map[string]StringList `yaml:"axes"`
+
+	// cancel the remaining jobs as soon as one fails, defaults to true
+	FailFast *bool `yaml:"fail_fast"`
+
+	// failing jobs do not fail the matrix as a whole
+	ContinueOnError bool `yaml:"continue_on_error"`
+}
+
+const MaxMatrixJobs = 64
+
+var (
+	matrixAxisRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	matrixValueRegexp = regexp.MustCompile(`\$\{\{\s*matrix\.([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+)
+
+func (m *Matrix) IsFailFast() bool {
+	return m.FailFast == nil || *m.FailFast
+}
+
+func (m *Matrix) Validate() error {
+	if len(m.Axes) == 0 {
+		return errors.New("matrix has no axes")
+	}
+
+	jobs := 1
+	for axis, values := range m.Axes {
+		if !matrixAxisRegexp.MatchString(axis) {
+			return fmt.Errorf("invalid matrix axis %q", axis)
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("matrix axis %q has no values", axis)
+		}
+		jobs *= len(values)
+		if jobs > MaxMatrixJobs {
+			return fmt.Errorf("matrix expands to more than %d jobs", MaxMatrixJobs)
+		}
+	}
+
+	return nil
+}
+
+// Combinations returns every combination of values across the axes, in a
+// stable order.
+func (m *Matrix) Combinations() []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, axis := range slices.Sorted(maps.Keys(m.Axes)) {
+		var next []map[string]string
+		for _, c := range combinations {
+			for _, v := range m.Axes[axis] {
+				nc := maps.Clone(c)
+				nc[axis] = v
+				next = append(next, nc)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// MatrixJobName names the job of workflow for one combination of values,
+// e.g. 'test.yml[go=1.22,os=linux]'.
+func MatrixJobName(workflow string, values map[string]string) string {
+	var parts []string
+	for _, axis := range slices.Sorted(maps.Keys(values)) {
+		parts = append(parts, fmt.Sprintf("%s=%s", axis, values[axis]))
+	}
+	return fmt.Sprintf("%s[%s]", workflow, strings.Join(parts, ","))
+}
+
+// SplitMatrixJobName is the reverse of MatrixJobName, ok is false if name
+// is not that of a matrix job.
+func SplitMatrixJobName(name string) (workflow, values string, ok bool) {
+	if !strings.HasSuffix(name, "]") {
+		return "", "", false
+	}
+	i := strings.Index(name, "[")
+	if i <= 0 {
+		return "", "", false
+	}
+	return name[:i], name[i+1 : len(name)-1], true
+}
+
+// ExpandMatrix substitutes the values of a combination into a raw workflow,
+// references to unknown axes are left as they are.
+func ExpandMatrix(raw string, values map[string]string) string {
+	return matrixValueRegexp.ReplaceAllStringFunc(raw, func(s string) string {
+		axis := matrixValueRegexp.FindStringSubmatch(s)[1]
+		if v, ok := values[axis]; ok {
+			return v
+		}
+		return s
+	})
+}
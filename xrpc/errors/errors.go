@@ -66,6 +66,11 @@ var RefNotFoundError = NewXrpcError(
 	WithMessage("failed to access ref"),
 )
 
+var PipelineNotFoundError = NewXrpcError(
+	WithTag("PipelineNotFound"),
+	WithMessage("failed to find pipeline"),
+)
+
 var AuthError = func(err error) XrpcError {
 	return NewXrpcError(
 		WithTag("Auth"),
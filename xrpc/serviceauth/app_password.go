@@ -0,0 +1,116 @@
+package serviceauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// scopes that app passwords can be given. Service auth tokens are signed by
+// the PDS of their user and are allowed everything.
+const (
+	ScopeGitRead  = "git:read"
+	ScopeGitWrite = "git:write"
+	ScopeApi      = "api"
+
+	// ScopeRepoStatus lets CI systems post commit statuses to the appview
+	ScopeRepoStatus = "repo:status"
+)
+
+var Scopes = []string{ScopeGitRead, ScopeGitWrite, ScopeApi, ScopeRepoStatus}
+
+// AppPasswordPrefix starts every app password, which tells them apart from
+// service auth tokens.
+const AppPasswordPrefix = "tgl_"
+
+func IsAppPassword(token string) bool {
+	return strings.HasPrefix(token, AppPasswordPrefix)
+}
+
+type scopesKey struct{}
+
+// HasScope reports whether the credential a request was made with allows
+// scope. Only app passwords are limited to some scopes.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ctx.Value(scopesKey{}).([]string)
+	if !ok {
+		return true
+	}
+	return slices.Contains(scopes, scope)
+}
+
+// AcceptAppPasswords lets users authenticate with app passwords, which are
+// verified by the appview at endpoint.
+func (sa *ServiceAuth) AcceptAppPasswords(endpoint string) {
+	sa.appPasswordEndpoint = endpoint
+}
+
+// VerifyAppPasswordRequest is sent by a knot along with its own did, since
+// app passwords are only good on the knots they were made for. Otherwise a
+// knot could replay the passwords it is sent on every other knot.
+type VerifyAppPasswordRequest struct {
+	Password string `json:"password"`
+	Knot     string `json:"knot"`
+}
+
+type VerifyAppPasswordResponse struct {
+	Did    string   `json:"did"`
+	Scopes []string `json:"scopes"`
+}
+
+var appPasswordClient = &http.Client{Timeout: 10 * time.Second}
+
+func (sa *ServiceAuth) verifyAppPassword(ctx context.Context, password string) (syntax.DID, []string, error) {
+	if sa.appPasswordEndpoint == "" {
+		return "", nil, fmt.Errorf("app passwords are not accepted")
+	}
+
+	endpoint, err := url.JoinPath(sa.appPasswordEndpoint, "app-passwords", "verify")
+	if err != nil {
+		return "", nil, err
+	}
+
+	body, err := json.Marshal(VerifyAppPasswordRequest{
+		Password: password,
+		Knot:     sa.audienceDid,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := appPasswordClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("verifying app password: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("invalid app password")
+	}
+
+	var verified VerifyAppPasswordResponse
+	if err := json.NewDecoder(resp.Body).Decode(&verified); err != nil {
+		return "", nil, err
+	}
+
+	did, err := syntax.ParseDID(verified.Did)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return did, verified.Scopes, nil
+}
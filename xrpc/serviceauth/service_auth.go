@@ -3,6 +3,7 @@ package serviceauth
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -19,6 +20,9 @@ type ServiceAuth struct {
 	logger      *slog.Logger
 	resolver    *idresolver.Resolver
 	audienceDid string
+
+	// where app passwords are verified, they are refused when empty
+	appPasswordEndpoint string
 }
 
 func NewServiceAuth(logger *slog.Logger, resolver *idresolver.Resolver, audienceDid string) *ServiceAuth {
@@ -31,31 +35,84 @@ func NewServiceAuth(logger *slog.Logger, resolver *idresolver.Resolver, audience
 
 func (sa *ServiceAuth) VerifyServiceAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.Header.Get("Authorization")
-		token = strings.TrimPrefix(token, "Bearer ")
+		r, err := sa.authenticate(r)
+		if err != nil {
+			sa.logger.Error("signature verification failed", "err", err)
+			writeError(w, xrpcerr.AuthError(err), http.StatusForbidden)
+			return
+		}
 
-		s := auth.ServiceAuthValidator{
-			Audience: sa.audienceDid,
-			Dir:      sa.resolver.Directory(),
+		// app passwords only make api calls when they are allowed to
+		if !HasScope(r.Context(), ScopeApi) {
+			writeError(w, xrpcerr.AuthError(fmt.Errorf("app password lacks the %s scope", ScopeApi)), http.StatusForbidden)
+			return
 		}
 
-		did, err := s.Validate(r.Context(), token, nil)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OptionalServiceAuth verifies the service auth token of requests that carry
+// one, requests without one are passed along anonymously.
+func (sa *ServiceAuth) OptionalServiceAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r, err := sa.authenticate(r)
 		if err != nil {
 			sa.logger.Error("signature verification failed", "err", err)
 			writeError(w, xrpcerr.AuthError(err), http.StatusForbidden)
 			return
 		}
 
-		sa.logger.Debug("valid signature", ActorDid, did)
-
-		r = r.WithContext(
-			context.WithValue(r.Context(), ActorDid, did),
-		)
-
 		next.ServeHTTP(w, r)
 	})
 }
 
+// authenticate checks the credential of r, which is either a service auth
+// token or an app password, and puts its owner in the context.
+func (sa *ServiceAuth) authenticate(r *http.Request) (*http.Request, error) {
+	token := r.Header.Get("Authorization")
+	token = strings.TrimPrefix(token, "Bearer ")
+
+	// git sends credentials with basic auth
+	if _, password, ok := r.BasicAuth(); ok {
+		token = password
+	}
+
+	if IsAppPassword(token) {
+		did, scopes, err := sa.verifyAppPassword(r.Context(), token)
+		if err != nil {
+			return nil, err
+		}
+
+		sa.logger.Debug("valid app password", ActorDid, did, "scopes", scopes)
+
+		ctx := context.WithValue(r.Context(), ActorDid, did)
+		ctx = context.WithValue(ctx, scopesKey{}, scopes)
+		return r.WithContext(ctx), nil
+	}
+
+	s := auth.ServiceAuthValidator{
+		Audience: sa.audienceDid,
+		Dir:      sa.resolver.Directory(),
+	}
+
+	did, err := s.Validate(r.Context(), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sa.logger.Debug("valid signature", ActorDid, did)
+
+	return r.WithContext(
+		context.WithValue(r.Context(), ActorDid, did),
+	), nil
+}
+
 // this is slightly different from http_util::write_error to follow the spec:
 //
 // the json object returned must include an "error" and a "message"